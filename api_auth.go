@@ -0,0 +1,116 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// apiKey is a named key granting access to the HTTP API
+type apiKey struct {
+	name string
+	key  string
+}
+
+// parseAPIKeys parses API_KEYS, a comma-separated list of name:key pairs, e.g. "home-assistant:s3cr3t,backup:0th3r".
+// The names identify the clients in the logs.
+func parseAPIKeys(text string) ([]apiKey, error) {
+	var keys []apiKey
+	for i, entry := range splitAndTrim(text) {
+		name, key, found := strings.Cut(entry, ":")
+		name, key = strings.TrimSpace(name), strings.TrimSpace(key)
+		if !found || name == "" || key == "" {
+			// The entry isn't part of the error, so keys don't end up in the logs
+			return nil, fmt.Errorf("entry %d is not of the form name:key", i+1)
+		}
+		for _, other := range keys {
+			if other.name == name {
+				return nil, fmt.Errorf("duplicate name %q", name)
+			}
+			if other.key == key {
+				return nil, fmt.Errorf("%q and %q use the same key", other.name, name)
+			}
+		}
+		keys = append(keys, apiKey{name: name, key: key})
+	}
+	return keys, nil
+}
+
+// requestAPIKey returns the key sent with the request as bearer token, in the X-API-Key header or as password of
+// HTTP basic authentication, which lets browsers ask for the key when opening the web UI
+func requestAPIKey(r *http.Request) string {
+	if token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found {
+		return strings.TrimSpace(token)
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	if _, password, ok := r.BasicAuth(); ok {
+		return password
+	}
+	return ""
+}
+
+// matchAPIKey returns the name of the key, comparing the keys in constant time
+func matchAPIKey(keys []apiKey, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+	name, matched := "", false
+	for _, candidate := range keys {
+		if subtle.ConstantTimeCompare([]byte(candidate.key), []byte(key)) == 1 {
+			name, matched = candidate.name, true
+		}
+	}
+	return name, matched
+}
+
+// apiAuth rejects requests that aren't authenticated with one of the API keys, an API token of a user or, with OIDC,
+// with the session of the web UI or a bearer token issued by the OpenID Connect provider. Without keys, tokens and
+// OIDC, all requests are allowed. The user is added to the request context, so the work done is attributed to them.
+func apiAuth(keys []apiKey, db *gorm.DB, provider *oidcProvider) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(keys) == 0 && provider == nil && !hasActiveAPITokens(db) {
+			c.Next()
+			return
+		}
+
+		key := requestAPIKey(c.Request)
+		if name, ok := matchAPIKey(keys, key); ok {
+			log.Debugf("Request %s %s authenticated with API key %s", c.Request.Method, c.Request.URL.Path, name)
+			c.Set("api_key_name", name)
+			c.Request = c.Request.WithContext(withRequestUser(c.Request.Context(), name))
+			c.Next()
+			return
+		}
+		if token, ok := authenticateAPIToken(db, key); ok {
+			log.Debugf("Request %s %s authenticated with API token %d of user %s", c.Request.Method, c.Request.URL.Path, token.ID, token.User)
+			c.Set("user", token.User)
+			c.Set("api_token_id", token.ID)
+			c.Request = c.Request.WithContext(withRequestUser(c.Request.Context(), token.User))
+			c.Next()
+			return
+		}
+		if provider != nil {
+			if user, ok := provider.authenticate(c.Request); ok {
+				log.Debugf("Request %s %s authenticated as user %s", c.Request.Method, c.Request.URL.Path, user)
+				c.Set("user", user)
+				c.Request = c.Request.WithContext(withRequestUser(c.Request.Context(), user))
+				c.Next()
+				return
+			}
+		}
+
+		// Users of the web UI log in with OIDC, otherwise the browser asks for an API key
+		if provider != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="paperless-gpt"`)
+		} else {
+			c.Header("WWW-Authenticate", `Basic realm="paperless-gpt"`)
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid credentials"})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAPIKeys(t *testing.T) {
+	keys, err := parseAPIKeys("home-assistant:s3cr3t, backup : 0th3r")
+	require.NoError(t, err)
+	assert.Equal(t, []apiKey{{name: "home-assistant", key: "s3cr3t"}, {name: "backup", key: "0th3r"}}, keys)
+
+	for _, invalid := range []string{"s3cr3t", ":s3cr3t", "backup:", "a:one,a:two", "a:same,b:same"} {
+		_, err := parseAPIKeys(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	_, err = parseAPIKeys("valid:key,:leaked")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "leaked", "keys must not be part of errors")
+}
+
+func TestAPIKeyAuth(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	newRouter := func(keys []apiKey) *gin.Engine {
+		router := gin.New()
+		router.Group("/api", apiAuth(keys, nil, nil)).GET("/tags", func(c *gin.Context) {
+			c.String(http.StatusOK, c.GetString("api_key_name"))
+		})
+		return router
+	}
+	router := newRouter([]apiKey{{name: "home-assistant", key: "s3cr3t"}, {name: "backup", key: "0th3r"}})
+
+	testCases := []struct {
+		name     string
+		prepare  func(r *http.Request)
+		wantCode int
+		wantName string
+	}{
+		{"bearer token", func(r *http.Request) { r.Header.Set("Authorization", "Bearer s3cr3t") }, http.StatusOK, "home-assistant"},
+		{"header", func(r *http.Request) { r.Header.Set("X-API-Key", "0th3r") }, http.StatusOK, "backup"},
+		{"basic authentication", func(r *http.Request) { r.SetBasicAuth("anyone", "s3cr3t") }, http.StatusOK, "home-assistant"},
+		{"missing key", func(r *http.Request) {}, http.StatusUnauthorized, ""},
+		{"wrong key", func(r *http.Request) { r.Header.Set("Authorization", "Bearer guess") }, http.StatusUnauthorized, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/api/tags", nil)
+			tc.prepare(request)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, request)
+			assert.Equal(t, tc.wantCode, w.Code)
+			if tc.wantCode == http.StatusOK {
+				assert.Equal(t, tc.wantName, w.Body.String())
+			} else {
+				assert.NotEmpty(t, w.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+
+	// Without keys the API stays open
+	w := httptest.NewRecorder()
+	newRouter(nil).ServeHTTP(w, httptest.NewRequest("GET", "/api/tags", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// apiRateLimiterIdleTimeout is how long the limiter of a client is kept after its last request
+const apiRateLimiterIdleTimeout = 10 * time.Minute
+
+// apiRateLimiter limits the requests per second of each API client. Clients are told apart by their API key or
+// OIDC user, anonymous clients by their IP address.
+type apiRateLimiter struct {
+	limit rate.Limit
+	burst int
+	now   func() time.Time
+
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	lastSweep time.Time
+}
+
+// clientLimiter is the rate limiter of a single client
+type clientLimiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newAPIRateLimiter allows each client requestsPerSecond requests per second with bursts of up to burst requests,
+// 0 requests per second disables rate limiting
+func newAPIRateLimiter(requestsPerSecond float64, burst int) *apiRateLimiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return &apiRateLimiter{
+		limit:   rate.Limit(requestsPerSecond),
+		burst:   max(burst, 1),
+		now:     time.Now,
+		clients: make(map[string]*clientLimiter),
+	}
+}
+
+// reserve takes a request of the client from its limiter. If the client exceeded its limit, it returns how long
+// to wait before the next request is allowed.
+func (l *apiRateLimiter) reserve(client string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > apiRateLimiterIdleTimeout {
+		for key, entry := range l.clients {
+			if now.Sub(entry.lastSeen) > apiRateLimiterIdleTimeout {
+				delete(l.clients, key)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	entry, ok := l.clients[client]
+	if !ok {
+		entry = &clientLimiter{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.clients[client] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay, false
+	}
+	return 0, true
+}
+
+// middleware rejects requests of clients exceeding their rate limit, it must run after apiAuth to tell authenticated
+// clients apart. Without rate limit, it allows all requests.
+func (l *apiRateLimiter) middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+		client := "ip:" + c.ClientIP()
+		if name := c.GetString("api_key_name"); name != "" {
+			client = "key:" + name
+		} else if user := c.GetString("user"); user != "" {
+			client = "user:" + user
+		}
+
+		if delay, ok := l.reserve(client); !ok {
+			log.Warnf("Rate limit of API client %s exceeded on %s %s", client, c.Request.Method, c.Request.URL.Path)
+			rejectTooManyRequests(c, delay, "Rate limit exceeded")
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitConcurrentRequests rejects requests while all slots are taken instead of queueing them, so scripts can't pile
+// up requests. Without slots, it allows all requests.
+func limitConcurrentRequests(slots concurrencySlots) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slots == nil {
+			c.Next()
+			return
+		}
+		release, ok := slots.tryAcquire()
+		if !ok {
+			rejectTooManyRequests(c, time.Second, "Too many concurrent requests")
+			return
+		}
+		defer release()
+		c.Next()
+	}
+}
+
+// rejectTooManyRequests answers with 429 Too Many Requests and when to try again
+func rejectTooManyRequests(c *gin.Context, retryAfter time.Duration, message string) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": message})
+}
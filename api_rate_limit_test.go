@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRateLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := newAPIRateLimiter(1, 2)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	router := gin.New()
+	router.Group("/api", apiAuth([]apiKey{{name: "backup", key: "s3cr3t"}}, nil, nil), limiter.middleware()).GET("/tags", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	request := func(key string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/api/tags", nil)
+		r.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+
+	// Bursts are allowed up to the limit
+	assert.Equal(t, http.StatusOK, request("s3cr3t").Code)
+	assert.Equal(t, http.StatusOK, request("s3cr3t").Code)
+	w := request("s3cr3t")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	// Unauthenticated requests don't use up the limit of the key
+	assert.Equal(t, http.StatusUnauthorized, request("guess").Code)
+
+	// Rejected requests don't count, the limit refills over time
+	now = now.Add(time.Second)
+	assert.Equal(t, http.StatusOK, request("s3cr3t").Code)
+	assert.Equal(t, http.StatusTooManyRequests, request("s3cr3t").Code)
+
+	// Idle clients are forgotten
+	now = now.Add(2 * apiRateLimiterIdleTimeout)
+	limiter.reserve("ip:192.0.2.1")
+	assert.Len(t, limiter.clients, 1)
+}
+
+func TestAPIRateLimiterPerClient(t *testing.T) {
+	limiter := newAPIRateLimiter(0.1, 1)
+	_, ok := limiter.reserve("key:backup")
+	assert.True(t, ok)
+	_, ok = limiter.reserve("key:backup")
+	assert.False(t, ok)
+	_, ok = limiter.reserve("user:jane")
+	assert.True(t, ok, "clients have separate limits")
+
+	// Without rate limit, all requests are allowed
+	assert.Nil(t, newAPIRateLimiter(0, 10))
+}
+
+func TestLimitConcurrentRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	router := gin.New()
+	router.POST("/api/generate-suggestions", limitConcurrentRequests(newConcurrencySlots(1)), func(c *gin.Context) {
+		started <- struct{}{}
+		<-finish
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan int)
+	go func() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions", nil))
+		done <- w.Code
+	}()
+	<-started
+
+	// Requests beyond the cap are rejected instead of queued
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	close(finish)
+	assert.Equal(t, http.StatusOK, <-done)
+
+	// The slot is free again
+	go func() { <-started }()
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// apiTokenPrefix starts every API token, so they are told apart from API keys and OIDC access tokens
+const apiTokenPrefix = "pgpt_"
+
+// apiTokenDisplayLength is the number of characters of a token kept to recognize it in listings
+const apiTokenDisplayLength = 12
+
+// NewAPITokenRequest is the request payload of POST /api/tokens
+type NewAPITokenRequest struct {
+	User string `json:"user"` // Defaults to the user creating the token
+	Name string `json:"name"`
+}
+
+// CreatedAPIToken is the response of POST /api/tokens, the only time the token itself is returned
+type CreatedAPIToken struct {
+	APIToken
+	Token string `json:"token"`
+}
+
+// generateAPIToken returns a new random token and the hash it is stored with
+func generateAPIToken() (token string, hash string, err error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", "", err
+	}
+	token = apiTokenPrefix + base64.RawURLEncoding.EncodeToString(data)
+	return token, hashAPIToken(token), nil
+}
+
+// hashAPIToken returns the hex encoded SHA-256 hash of a token
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// authenticateAPIToken returns the token matching the key sent with a request, if it isn't revoked
+func authenticateAPIToken(db *gorm.DB, key string) (*APIToken, bool) {
+	if db == nil || !strings.HasPrefix(key, apiTokenPrefix) {
+		return nil, false
+	}
+	token, err := FindActiveAPIToken(db, hashAPIToken(key))
+	if err != nil {
+		return nil, false
+	}
+	if err := TouchAPIToken(db, token); err != nil {
+		log.Warnf("Failed to record the use of API token %d: %v", token.ID, err)
+	}
+	return token, true
+}
+
+// hasActiveAPITokens reports whether any token was issued and not revoked, which protects the API
+func hasActiveAPITokens(db *gorm.DB) bool {
+	if db == nil {
+		return false
+	}
+	count, err := CountActiveAPITokens(db)
+	if err != nil {
+		// Better to reject requests than to leave the API open
+		log.Errorf("Failed to count API tokens: %v", err)
+		return true
+	}
+	return count > 0
+}
+
+// requestUserKey is the context key of the user a request was authenticated as
+type requestUserKey struct{}
+
+// withRequestUser attributes the work done with the context, like modifications, jobs and LLM requests, to the user
+func withRequestUser(ctx context.Context, user string) context.Context {
+	if user == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestUserKey{}, user)
+}
+
+// requestUser returns the user work is done for, empty for background processing and an unprotected API
+func requestUser(ctx context.Context) string {
+	user, _ := ctx.Value(requestUserKey{}).(string)
+	return user
+}
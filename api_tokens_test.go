@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestAPITokens(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	clear := func() {
+		for _, model := range []any{&APIToken{}, &LLMUsage{}} {
+			require.NoError(t, db.Where("1 = 1").Delete(model).Error)
+		}
+	}
+	clear()
+	defer clear()
+
+	gin.SetMode(gin.TestMode)
+	app := &App{Database: db}
+	router := gin.New()
+	api := router.Group("/api", apiAuth([]apiKey{{name: "admin", key: "s3cr3t"}}, db, nil))
+	api.GET("/tokens", app.getAPITokensHandler)
+	api.POST("/tokens", app.createAPITokenHandler)
+	api.DELETE("/tokens/:id", app.revokeAPITokenHandler)
+	api.GET("/whoami", func(c *gin.Context) { c.String(http.StatusOK, requestUser(c.Request.Context())) })
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("Authorization", "Bearer "+key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w
+	}
+	create := func(key, body string) CreatedAPIToken {
+		w := request("POST", "/api/tokens", key, body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created CreatedAPIToken
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		return created
+	}
+
+	// API keys manage the tokens of all users
+	alice := create("s3cr3t", `{"user": "alice", "name": "laptop"}`)
+	assert.True(t, strings.HasPrefix(alice.Token, apiTokenPrefix))
+	assert.Equal(t, alice.Token[:apiTokenDisplayLength], alice.Prefix)
+	bob := create("s3cr3t", `{"user": "bob", "name": "scripts"}`)
+	assert.NotContains(t, request("GET", "/api/tokens", "s3cr3t", "").Body.String(), alice.Token, "tokens are only returned when created")
+
+	// Requests with a token are attributed to its user
+	w := request("GET", "/api/whoami", alice.Token, "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "alice", w.Body.String())
+	stored, err := GetAPIToken(db, alice.ID)
+	require.NoError(t, err)
+	assert.NotEmpty(t, stored.LastUsedAt)
+	assert.NotEqual(t, alice.Token, stored.TokenHash)
+
+	// Users manage only their own tokens
+	assert.Equal(t, http.StatusForbidden, request("POST", "/api/tokens", alice.Token, `{"user": "bob", "name": "mine now"}`).Code)
+	second := create(alice.Token, `{"name": "phone"}`)
+	assert.Equal(t, "alice", second.User)
+	var tokens []APIToken
+	require.NoError(t, json.Unmarshal(request("GET", "/api/tokens", alice.Token, "").Body.Bytes(), &tokens))
+	assert.Len(t, tokens, 2)
+	require.NoError(t, json.Unmarshal(request("GET", "/api/tokens", "s3cr3t", "").Body.Bytes(), &tokens))
+	assert.Len(t, tokens, 3)
+	assert.Equal(t, http.StatusNotFound, request("DELETE", "/api/tokens/"+strconv.Itoa(int(bob.ID)), alice.Token, "").Code)
+	assert.Equal(t, http.StatusBadRequest, request("POST", "/api/tokens", "s3cr3t", `{"name": "nobody"}`).Code)
+
+	// Revoked tokens are rejected
+	assert.Equal(t, http.StatusOK, request("DELETE", "/api/tokens/"+strconv.Itoa(int(second.ID)), alice.Token, "").Code)
+	assert.Equal(t, http.StatusUnauthorized, request("GET", "/api/whoami", second.Token, "").Code)
+	assert.Equal(t, http.StatusOK, request("GET", "/api/whoami", alice.Token, "").Code)
+
+	// Active tokens protect an API without keys
+	openRouter := gin.New()
+	openRouter.Group("/api", apiAuth(nil, db, nil)).GET("/tags", func(c *gin.Context) { c.Status(http.StatusOK) })
+	w = httptest.NewRecorder()
+	openRouter.ServeHTTP(w, httptest.NewRequest("GET", "/api/tags", nil))
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	require.NoError(t, db.Where("1 = 1").Delete(&APIToken{}).Error)
+	w = httptest.NewRecorder()
+	openRouter.ServeHTTP(w, httptest.NewRequest("GET", "/api/tags", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The tokens spent are attributed to the user
+	model := recordLLMUsage(&usageReportingLLM{}, db, "openai", "gpt-4o")
+	_, err = model.GenerateContent(withRequestUser(context.Background(), "alice"), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Title?")})
+	require.NoError(t, err)
+	_, err = model.GenerateContent(context.Background(), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Title?")})
+	require.NoError(t, err)
+	stats, err := collectStats(db, time.Now().AddDate(0, 0, -1))
+	require.NoError(t, err)
+	require.Len(t, stats.Users, 2)
+	assert.Equal(t, "", stats.Users[0].User)
+	assert.Equal(t, "alice", stats.Users[1].User)
+	assert.EqualValues(t, 1000, stats.Users[1].PromptTokens)
+}
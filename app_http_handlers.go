@@ -1,15 +1,26 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"text/template"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/oauth2"
+	"gorm.io/gorm"
 )
 
 // getPromptsHandler handles the GET /api/prompts endpoint
@@ -80,6 +91,274 @@ func updatePromptsHandler(c *gin.Context) {
 	c.Status(http.StatusOK)
 }
 
+// exportPromptsHandler handles the GET /api/prompts/export endpoint.
+// It returns all prompt templates as a JSON bundle, or as a zip archive of the template files with ?format=zip.
+func exportPromptsHandler(c *gin.Context) {
+	templateMutex.RLock()
+	bundle, err := exportPrompts()
+	templateMutex.RUnlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error reading prompts"})
+		log.Errorf("Failed to export prompts: %v", err)
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.Header("Content-Disposition", `attachment; filename="paperless-gpt-prompts.json"`)
+		c.JSON(http.StatusOK, bundle)
+	case "zip":
+		var archive bytes.Buffer
+		if err := writePromptsZip(&archive, bundle); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error packing prompts"})
+			log.Errorf("Failed to pack prompts: %v", err)
+			return
+		}
+		c.Header("Content-Disposition", `attachment; filename="paperless-gpt-prompts.zip"`)
+		c.Data(http.StatusOK, "application/zip", archive.Bytes())
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or zip"})
+	}
+}
+
+// importPromptsHandler handles the POST /api/prompts/import endpoint.
+// It takes a bundle of the export endpoint, as JSON or zip archive, and replaces the prompts it contains after parsing
+// and rendering each of them with sample data. With ?dry_run=true, only the rendered prompts are returned.
+func importPromptsHandler(c *gin.Context) {
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxPromptBundleSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error reading prompt bundle: %v", err)})
+		return
+	}
+
+	var bundle PromptBundle
+	if c.ContentType() == "application/zip" || bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		bundle, err = readPromptsZip(data)
+	} else if err = json.Unmarshal(data, &bundle); err != nil {
+		err = fmt.Errorf("invalid JSON: %w", err)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid prompt bundle: %v", err)})
+		return
+	}
+
+	templates, previews, problems := parsePromptBundle(bundle)
+	if len(problems) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid prompt bundle", "problems": problems})
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		imported := make([]string, 0, len(templates))
+		for _, prompt := range promptTemplates {
+			if _, ok := templates[prompt.name]; ok {
+				imported = append(imported, prompt.name)
+			}
+		}
+		c.JSON(http.StatusOK, PromptImportResult{DryRun: true, Imported: imported, Previews: previews})
+		return
+	}
+
+	imported, err := importPrompts(bundle, templates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing prompts"})
+		log.Errorf("Failed to import prompts: %v", err)
+		return
+	}
+	log.Infof("Imported prompts: %s", strings.Join(imported, ", "))
+	c.JSON(http.StatusOK, PromptImportResult{Imported: imported})
+}
+
+// getPromptPresetsHandler handles the GET /api/prompts/presets endpoint.
+// It lists the built-in prompt presets with the prompts they have templates for.
+func getPromptPresetsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, availablePromptPresets())
+}
+
+// applyPromptPresetHandler handles the POST /api/prompts/presets/:name/apply endpoint.
+// It replaces the templates of the prompts directory with the ones of the preset, all of them or the prompts of the
+// optional request body. With ?dry_run=true the templates are only rendered with sample data.
+func applyPromptPresetHandler(c *gin.Context) {
+	var req struct {
+		Prompts []string `json:"prompts"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	name := c.Param("name")
+	if _, ok := findPromptPreset(name); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prompt preset not found"})
+		return
+	}
+	bundle, err := presetBundle(name, req.Prompts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	templates, previews, problems := parsePromptBundle(bundle)
+	if len(problems) > 0 {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid prompt preset", "problems": problems})
+		log.Errorf("Prompt preset %s has invalid templates: %v", name, problems)
+		return
+	}
+
+	if c.Query("dry_run") == "true" {
+		applied := make([]string, 0, len(templates))
+		for _, prompt := range promptTemplates {
+			if _, ok := templates[prompt.name]; ok {
+				applied = append(applied, prompt.name)
+			}
+		}
+		c.JSON(http.StatusOK, PromptImportResult{DryRun: true, Imported: applied, Previews: previews})
+		return
+	}
+
+	applied, err := importPrompts(bundle, templates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error writing prompts"})
+		log.Errorf("Failed to apply prompt preset %s: %v", name, err)
+		return
+	}
+	log.Infof("Applied the prompts of the %s preset: %s", name, strings.Join(applied, ", "))
+	c.JSON(http.StatusOK, PromptImportResult{Imported: applied})
+}
+
+// validatePromptHandler handles the POST /api/prompts/validate endpoint.
+// It parses a template of a prompt and renders it with sample data or the given document, without saving it.
+func (app *App) validatePromptHandler(c *gin.Context) {
+	var req PromptValidationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	prompt, ok := findPromptTemplate(req.Name)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown prompt %q", req.Name)})
+		return
+	}
+
+	data := samplePromptData()
+	if req.DocumentID != 0 {
+		var err error
+		data, err = app.documentValidationData(c.Request.Context(), req.DocumentID)
+		if app.skipDeletedDocument(req.DocumentID, err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document was deleted in paperless-ngx"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error fetching document"})
+			log.Errorf("Error fetching document %d to validate the %s prompt: %v", req.DocumentID, prompt.name, err)
+			return
+		}
+	}
+
+	result := validatePrompt(c.Request.Context(), prompt.name, req.Template, data)
+	result.DocumentID = req.DocumentID
+	c.JSON(http.StatusOK, result)
+}
+
+// getConfigHandler handles the GET /api/config endpoint.
+// It returns the effective configuration with the secrets redacted.
+func (app *App) getConfigHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, app.currentRuntimeConfig())
+}
+
+// updateConfigHandler handles the PATCH /api/config endpoint.
+// It changes the reloadable settings, keyed by the name of their environment variable, until the next restart.
+func (app *App) updateConfigHandler(c *gin.Context) {
+	var req map[string]interface{}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	settings := make(map[string]string, len(req))
+	for name, value := range req {
+		switch value := value.(type) {
+		case string:
+			settings[name] = value
+		case bool:
+			settings[name] = strconv.FormatBool(value)
+		case float64:
+			settings[name] = strconv.FormatFloat(value, 'f', -1, 64)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s must be a string, number or boolean", name)})
+			return
+		}
+	}
+	if err := app.applySettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Infof("Changed settings through the API: %s", strings.Join(slices.Sorted(maps.Keys(settings)), ", "))
+	c.JSON(http.StatusOK, app.currentRuntimeConfig())
+}
+
+// getStatsHandler handles the GET /api/stats endpoint.
+// It aggregates the processed documents, LLM requests and jobs of the last days (?days=, 30 by default).
+func (app *App) getStatsHandler(c *gin.Context) {
+	days := defaultStatsDays
+	if value := c.Query("days"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxStatsDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("days must be a number between 1 and %d", maxStatsDays)})
+			return
+		}
+		days = parsed
+	}
+
+	stats, err := collectStats(app.Database, statsSince(time.Now(), days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error collecting statistics"})
+		log.Errorf("Failed to collect statistics: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// graphQLHandler handles the GET and POST /graphql endpoint (GRAPHQL_ENABLED).
+// Queries are sent as JSON body or, with GET, as query, variables and operationName query parameters. Errors of the
+// query are part of the response like GraphQL servers do, only invalid requests are rejected with 400.
+func (app *App) graphQLHandler(c *gin.Context) {
+	var request GraphQLRequest
+	if c.Request.Method == http.MethodGet {
+		request.Query, request.OperationName = c.Query("query"), c.Query("operationName")
+		if variables := c.Query("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &request.Variables); err != nil {
+				c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "variables must be a JSON object"}}})
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "Invalid request payload"}}})
+		return
+	}
+	if strings.TrimSpace(request.Query) == "" {
+		c.JSON(http.StatusBadRequest, GraphQLResponse{Errors: []GraphQLError{{Message: "query is required"}}})
+		return
+	}
+
+	response, err := executeGraphQL(c.Request.Context(), app.graphQLSchema(), request)
+	if err != nil {
+		response = GraphQLResponse{Errors: []GraphQLError{{Message: err.Error()}}}
+	}
+	for _, graphQLError := range response.Errors {
+		log.Debugf("GraphQL query failed at %v: %s", graphQLError.Path, graphQLError.Message)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// graphQLSchemaHandler handles the GET /graphql/schema endpoint, it describes the types and fields of the GraphQL API
+func (app *App) graphQLSchemaHandler(c *gin.Context) {
+	c.String(http.StatusOK, graphQLSchemaText(app.graphQLSchema()))
+}
+
 // getAllTagsHandler handles the GET /api/tags endpoint
 func (app *App) getAllTagsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -94,21 +373,96 @@ func (app *App) getAllTagsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, tags)
 }
 
-// documentsHandler handles the GET /api/documents endpoint
+// documentsHandler handles the GET /api/documents endpoint.
+// Documents are selected by the manual tag, or by a saved view (?saved_view=<name or id>) and/or filter query (?query=<filter>).
+// The selection is paginated, sorted and narrowed down with the options of parseDocumentListOptions, the number of all
+// matching documents is returned in the X-Total-Count header.
 func (app *App) documentsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	documents, err := app.Client.GetDocumentsByTags(ctx, []string{manualTag}, 25)
+	options, err := parseDocumentListOptions(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Documents tagged with MANUAL_TAG are listed unless a saved view or filter query selects others
+	selection := "tags__name__iexact=" + urlEncode(settingsFor(ctx).ManualTag)
+	savedView, filterQuery := c.Query("saved_view"), c.Query("query")
+	if savedView != "" || filterQuery != "" {
+		selection, err = app.documentSelectionQuery(ctx, savedView, filterQuery)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid document selection: %v", err)})
+			log.Errorf("Invalid document selection: %v", err)
+			return
+		}
+	}
+
+	var tagIDs map[string]int
+	if len(options.Tags) > 0 {
+		tagIDs, err = app.Client.GetAllTags(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error fetching tags: %v", err)})
+			log.Errorf("Error fetching tags: %v", err)
+			return
+		}
+	}
+	filters, matchesAny := options.filterQuery(tagIDs)
+	if !matchesAny {
+		c.Header("X-Total-Count", "0")
+		c.JSON(http.StatusOK, []Document{})
+		return
+	}
+
+	documents, total, err := app.Client.GetDocumentsPage(ctx, joinQueries(selection, filters), options.Page, options.PageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error fetching documents: %v", err)})
 		log.Errorf("Error fetching documents: %v", err)
 		return
 	}
 
+	c.Header("X-Total-Count", strconv.Itoa(total))
 	c.JSON(http.StatusOK, documents)
 }
 
-// generateSuggestionsHandler handles the POST /api/generate-suggestions endpoint
+// searchDocumentsHandler handles the GET /api/search endpoint.
+// It runs the full-text search of paperless-ngx for ?q=<text> and adds the pending reviews, pending tags and jobs of
+// each hit. Results are paginated like GET /api/documents.
+func (app *App) searchDocumentsHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search text q"})
+		return
+	}
+	page, pageSize, err := parseDocumentPage(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	documents, hits, total, err := app.Client.SearchDocuments(ctx, text, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error searching documents: %v", err)})
+		log.Errorf("Error searching documents: %v", err)
+		return
+	}
+	results, err := enrichSearchResults(app.Database, documents, hits)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the state of the documents"})
+		log.Errorf("Failed to retrieve the state of the search results: %v", err)
+		return
+	}
+
+	c.Header("X-Total-Count", strconv.Itoa(total))
+	c.JSON(http.StatusOK, results)
+}
+
+// generateSuggestionsHandler handles the POST /api/generate-suggestions endpoint.
+// The suggestions are generated by a job in the worker pool, as runs over many documents outlast the timeouts of
+// reverse proxies. The job ID is returned right away, the result is available at /api/jobs/:job_id and pushed over
+// /api/ws. With ?wait=true, the suggestions are generated within the request instead.
 func (app *App) generateSuggestionsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -119,6 +473,24 @@ func (app *App) generateSuggestionsHandler(c *gin.Context) {
 		return
 	}
 
+	if c.Query("wait") != "true" {
+		// Queued jobs count towards the cap of concurrent requests, so scripts can't queue an unbounded number of runs
+		if suggestionsConcurrencyLimit > 0 && jobStore.countUnfinishedJobs(JobKindGenerate) >= suggestionsConcurrencyLimit {
+			rejectTooManyRequests(c, time.Second, "Too many unfinished suggestion jobs")
+			return
+		}
+		job, err := newGenerateJob(suggestionRequest)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error queueing suggestions: %v", err)})
+			log.Errorf("Error queueing suggestions: %v", err)
+			return
+		}
+		job.User = requestUser(c.Request.Context())
+		enqueueJob(job)
+		c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID})
+		return
+	}
+
 	results, err := app.generateDocumentSuggestions(ctx, suggestionRequest, log.WithContext(ctx))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error processing documents: %v", err)})
@@ -129,6 +501,48 @@ func (app *App) generateSuggestionsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, results)
 }
 
+// generateSuggestionsBatchHandler handles the POST /api/generate-suggestions/batch endpoint.
+// It queues a generate job for each document matching the tag, correspondent, saved view, filter query and date range
+// of the request, so callers don't have to list the documents first.
+func (app *App) generateSuggestionsBatchHandler(c *gin.Context) {
+	var batchRequest GenerateSuggestionsBatchRequest
+	if err := c.ShouldBindJSON(&batchRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request payload: %v", err)})
+		return
+	}
+	if len(batchRequest.Documents) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Documents are selected by the filters, use /api/generate-suggestions to list them"})
+		return
+	}
+	if suggestionsConcurrencyLimit > 0 && jobStore.countUnfinishedJobs(JobKindGenerate) >= suggestionsConcurrencyLimit {
+		rejectTooManyRequests(c, time.Second, "Too many unfinished suggestion jobs")
+		return
+	}
+
+	ctx := c.Request.Context()
+	query, matchesAny, err := app.batchDocumentQuery(ctx, batchRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid document selection: %v", err)})
+		return
+	}
+	jobs := []*Job{}
+	if matchesAny {
+		jobs, err = app.queueBatchSuggestions(ctx, query, batchRequest.GenerateSuggestionsRequest)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error queuing suggestions"})
+			log.Errorf("Failed to queue batch of suggestions: %v", err)
+			return
+		}
+	}
+
+	jobIDs := make([]string, len(jobs))
+	for i, job := range jobs {
+		jobIDs[i] = job.ID
+	}
+	log.Infof("Queued suggestions for %d documents", len(jobs))
+	c.JSON(http.StatusAccepted, gin.H{"count": len(jobs), "job_ids": jobIDs})
+}
+
 // updateDocumentsHandler handles the PATCH /api/update-documents endpoint
 func (app *App) updateDocumentsHandler(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -157,70 +571,105 @@ func (app *App) submitOCRJobHandler(c *gin.Context) {
 		return
 	}
 
-	// Create a new job
-	jobID := generateJobID() // Implement a function to generate unique job IDs
-	job := &Job{
-		ID:         jobID,
-		DocumentID: documentID,
-		Status:     "pending",
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
-	}
-
-	// Add job to store and queue
-	jobStore.addJob(job)
-	jobQueue <- job
+	// Add a new job to the store and queue
+	job := newJob(JobKindOCR, documentID)
+	job.User = requestUser(c.Request.Context())
+	enqueueJob(job)
 
 	// Return the job ID to the client
-	c.JSON(http.StatusAccepted, gin.H{"job_id": jobID})
+	c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID})
 }
 
-func (app *App) getJobStatusHandler(c *gin.Context) {
-	jobID := c.Param("job_id")
-
-	job, exists := jobStore.getJob(jobID)
-	if !exists {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+// submitJobHandler queues a job of any kind for a document
+func (app *App) submitJobHandler(c *gin.Context) {
+	var req struct {
+		Kind       string `json:"kind"`
+		DocumentID int    `json:"document_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	if !slices.Contains(jobKinds, req.Kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid job kind %q, expected one of %s", req.Kind, strings.Join(jobKinds, ", "))})
+		return
+	}
+	if req.DocumentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	if req.Kind == JobKindOCR && !app.isOcrEnabled() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "OCR is not enabled"})
 		return
 	}
 
+	job := newJob(req.Kind, req.DocumentID)
+	job.User = requestUser(c.Request.Context())
+	enqueueJob(job)
+
+	c.JSON(http.StatusAccepted, gin.H{"job_id": job.ID})
+}
+
+// jobResponse describes a job for the API
+func jobResponse(job *Job) gin.H {
 	response := gin.H{
-		"job_id":     job.ID,
-		"status":     job.Status,
-		"created_at": job.CreatedAt,
-		"updated_at": job.UpdatedAt,
-		"pages_done": job.PagesDone,
+		"job_id":      job.ID,
+		"kind":        job.Kind,
+		"document_id": job.DocumentID,
+		"status":      job.Status,
+		"attempts":    job.Attempts,
+		"created_at":  job.CreatedAt,
+		"updated_at":  job.UpdatedAt,
+		"pages_done":  job.PagesDone,
+	}
+	if job.User != "" {
+		response["user"] = job.User
 	}
 
 	if job.Status == "completed" {
 		response["result"] = job.Result
+		response["junk_pages"] = job.JunkPages
 	} else if job.Status == "failed" {
 		response["error"] = job.Result
 	}
+	return response
+}
 
-	c.JSON(http.StatusOK, response)
+func (app *App) getJobStatusHandler(c *gin.Context) {
+	jobID := c.Param("job_id")
+
+	job, exists := jobStore.getJob(jobID)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, jobResponse(job))
 }
 
+// getAllJobsHandler lists the jobs, optionally filtered by the kind and status query parameters
 func (app *App) getAllJobsHandler(c *gin.Context) {
-	jobs := jobStore.GetAllJobs()
+	kind := c.Query("kind")
+	status := c.Query("status")
 
-	jobList := make([]gin.H, 0, len(jobs))
-	for _, job := range jobs {
-		response := gin.H{
-			"job_id":     job.ID,
-			"status":     job.Status,
-			"created_at": job.CreatedAt,
-			"updated_at": job.UpdatedAt,
-			"pages_done": job.PagesDone,
+	jobList := make([]gin.H, 0)
+	for _, job := range jobStore.GetAllJobs() {
+		if (kind != "" && job.Kind != kind) || (status != "" && job.Status != status) {
+			continue
 		}
+		jobList = append(jobList, jobResponse(job))
+	}
 
-		if job.Status == "completed" {
-			response["result"] = job.Result
-		} else if job.Status == "failed" {
-			response["error"] = job.Result
-		}
+	c.JSON(http.StatusOK, jobList)
+}
 
-		jobList = append(jobList, response)
+// getOCRJobsHandler lists the OCR jobs
+func (app *App) getOCRJobsHandler(c *gin.Context) {
+	jobList := make([]gin.H, 0)
+	for _, job := range jobStore.GetAllJobs() {
+		if job.Kind == JobKindOCR {
+			jobList = append(jobList, jobResponse(job))
+		}
 	}
 
 	c.JSON(http.StatusOK, jobList)
@@ -236,6 +685,10 @@ func (app *App) getDocumentHandler() gin.HandlerFunc {
 			return
 		}
 		document, err := app.Client.GetDocument(c, parsedID)
+		if app.skipDeletedDocument(parsedID, err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Document was deleted in paperless-ngx"})
+			return
+		}
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			log.Errorf("Error fetching document: %v", err)
@@ -245,6 +698,20 @@ func (app *App) getDocumentHandler() gin.HandlerFunc {
 	}
 }
 
+// paperlessStatusHandler handles the GET /api/paperless/status endpoint.
+// The status is always returned with 200, so the web UI can show what is wrong with the connection.
+func (app *App) paperlessStatusHandler(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	settings := settingsFor(ctx)
+	status := app.Client.GetStatus(ctx, []string{settings.ManualTag, settings.AutoTag, manualOcrTag, settings.AutoOCRTag})
+	if status.Error != "" {
+		log.Warnf("paperless-ngx status check failed: %s", status.Error)
+	}
+	c.JSON(http.StatusOK, status)
+}
+
 // Section for local-db actions
 
 func (app *App) getModificationHistoryHandler(c *gin.Context) {
@@ -258,9 +725,14 @@ func (app *App) getModificationHistoryHandler(c *gin.Context) {
 	if ps, err := strconv.Atoi(c.DefaultQuery("pageSize", "20")); err == nil && ps > 0 && ps <= 100 {
 		pageSize = ps
 	}
+	filter, err := parseModificationFilter(c.Request.URL.Query())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Get paginated modifications and total count
-	modifications, total, err := GetPaginatedModifications(app.Database, page, pageSize)
+	modifications, total, err := GetPaginatedModifications(app.Database, page, pageSize, filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modification history"})
 		log.Errorf("Failed to retrieve modification history: %v", err)
@@ -269,74 +741,149 @@ func (app *App) getModificationHistoryHandler(c *gin.Context) {
 
 	totalPages := (int(total) + pageSize - 1) / pageSize
 
+	// The LLM and OCR requests that generated the modifications of the page, by batch
+	var batchIDs []string
+	for _, modification := range modifications {
+		if modification.BatchID != "" && !slices.Contains(batchIDs, modification.BatchID) {
+			batchIDs = append(batchIDs, modification.BatchID)
+		}
+	}
+	usage, err := batchUsage(app.Database, batchIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modification history"})
+		log.Errorf("Failed to retrieve the LLM usage of the modification history: %v", err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"items":       modifications,
 		"totalItems":  total,
 		"totalPages":  totalPages,
 		"currentPage": page,
 		"pageSize":    pageSize,
+		"usage":       usage,
 	})
 }
 
-func (app *App) undoModificationHandler(c *gin.Context) {
-	id := c.Param("id")
-	modID, err := strconv.Atoi(id)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modification ID"})
-		log.Errorf("Invalid modification ID: %v", err)
+// exportModificationHistoryHandler handles the GET /api/modifications/export endpoint.
+// It downloads the modifications matching the filters of GET /api/modifications, oldest first, as CSV or with
+// ?format=json as JSON.
+func (app *App) exportModificationHistoryHandler(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
 		return
 	}
-
-	modification, err := GetModification(app.Database, uint(modID))
+	filter, err := parseModificationFilter(c.Request.URL.Query())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modification"})
-		log.Errorf("Failed to retrieve modification: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if modification.Undone {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Modification has already been undone"})
-		log.Errorf("Modification has already been undone: %v", id)
-		return
+	contentType := "text/csv; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json; charset=utf-8"
 	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="paperless-gpt-history.%s"`, format))
+	c.Status(http.StatusOK)
 
-	// Ok, we're actually doing the update:
-	ctx := c.Request.Context()
+	// The export is streamed, so a failure can only cut it short
+	if err := exportModifications(app.Database, filter, format, c.Writer); err != nil {
+		log.Errorf("Failed to export the modification history: %v", err)
+		c.Abort()
+	}
+}
 
-	// Make the document suggestions for UpdateDocuments
-	var suggestion DocumentSuggestion
-	suggestion.ID = int(modification.DocumentID)
-	suggestion.OriginalDocument, err = app.Client.GetDocument(ctx, int(modification.DocumentID))
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve original document"})
-		log.Errorf("Failed to retrieve original document: %v", err)
+// pruneModificationHistoryHandler handles the DELETE /api/modifications endpoint.
+// It deletes modifications older than older_than_days and all but the newest keep modifications,
+// both default to HISTORY_RETENTION_DAYS and HISTORY_MAX_ENTRIES.
+func (app *App) pruneModificationHistoryHandler(c *gin.Context) {
+	settings := settingsFor(c.Request.Context())
+	retentionDays, err := strconv.Atoi(c.DefaultQuery("older_than_days", strconv.Itoa(settings.HistoryRetentionDays)))
+	if err != nil || retentionDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "older_than_days must be a non-negative number"})
 		return
 	}
-	switch modification.ModField {
-	case "title":
-		suggestion.SuggestedTitle = modification.PreviousValue
-	case "tags":
-		var tags []string
-		err := json.Unmarshal([]byte(modification.PreviousValue), &tags)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmarshal previous tags"})
-			log.Errorf("Failed to unmarshal previous tags: %v", err)
-			return
-		}
-		suggestion.SuggestedTags = tags
-	case "content":
-		suggestion.SuggestedContent = modification.PreviousValue
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modification field"})
-		log.Errorf("Invalid modification field: %v", modification.ModField)
+	maxEntries, err := strconv.Atoi(c.DefaultQuery("keep", strconv.Itoa(settings.HistoryMaxEntries)))
+	if err != nil || maxEntries < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "keep must be a non-negative number"})
+		return
+	}
+	if retentionDays == 0 && maxEntries == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Set older_than_days or keep, no history retention is configured"})
 		return
 	}
 
-	// Update the document
-	err = app.Client.UpdateDocuments(ctx, []DocumentSuggestion{suggestion}, app.Database, true)
+	deleted, err := app.pruneHistory(retentionDays, maxEntries, time.Now())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
-		log.Errorf("Failed to update document: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prune the modification history"})
+		log.Errorf("Failed to prune the modification history: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": deleted})
+}
+
+// archiveModificationsHandler handles the POST /api/modifications/archive endpoint.
+// It hides modifications from the history without deleting them, so the audit trail is kept.
+func (app *App) archiveModificationsHandler(c *gin.Context) {
+	app.setModificationsArchived(c, true)
+}
+
+// restoreModificationsHandler handles the POST /api/modifications/restore endpoint.
+// It shows archived modifications in the history again.
+func (app *App) restoreModificationsHandler(c *gin.Context) {
+	app.setModificationsArchived(c, false)
+}
+
+func (app *App) setModificationsArchived(c *gin.Context, archived bool) {
+	var request ModificationIDsRequest
+	if err := c.ShouldBindJSON(&request); err != nil || len(request.IDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload, expected the ids of modifications"})
+		return
+	}
+
+	changed, err := SetModificationsArchived(app.Database, request.IDs, archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update the modifications"})
+		log.Errorf("Failed to update the modifications: %v", err)
+		return
+	}
+
+	if archived {
+		c.JSON(http.StatusOK, gin.H{"archived": changed})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"restored": changed})
+	}
+}
+
+func (app *App) undoModificationHandler(c *gin.Context) {
+	id := c.Param("id")
+	modID, err := strconv.Atoi(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modification ID"})
+		log.Errorf("Invalid modification ID: %v", err)
+		return
+	}
+
+	modification, err := GetModification(app.Database, uint(modID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modification"})
+		log.Errorf("Failed to retrieve modification: %v", err)
+		return
+	}
+
+	if modification.Undone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Modification has already been undone"})
+		log.Errorf("Modification has already been undone: %v", id)
+		return
+	}
+
+	// Ok, we're actually doing the update:
+	err = app.applyModificationValues(c.Request.Context(), ModificationOperationUndo, []ModificationHistory{*modification})
+	if err != nil {
+		abortModificationUpdate(c, err)
 		return
 	}
 
@@ -350,3 +897,864 @@ func (app *App) undoModificationHandler(c *gin.Context) {
 	// Else all was ok
 	c.Status(http.StatusOK)
 }
+
+// undoBatchHandler reverts the modifications applied to a document together, with a single update of the document.
+// Modifications of the batch that were undone one by one are skipped.
+func (app *App) undoBatchHandler(c *gin.Context) {
+	batchID := c.Param("id")
+	modifications, err := GetBatchModifications(app.Database, batchID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modifications"})
+		log.Errorf("Failed to retrieve the modifications of batch %s: %v", batchID, err)
+		return
+	}
+	if len(modifications) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Batch not found"})
+		return
+	}
+	modifications = slices.DeleteFunc(modifications, func(modification ModificationHistory) bool { return modification.Undone })
+	if len(modifications) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Batch has already been undone"})
+		return
+	}
+
+	if err := app.applyModificationValues(c.Request.Context(), ModificationOperationUndo, modifications); err != nil {
+		abortModificationUpdate(c, err)
+		return
+	}
+	if err := SetModificationsUndone(app.Database, modifications); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark modifications as undone"})
+		log.Errorf("Failed to mark the modifications of batch %s as undone: %v", batchID, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"undone": len(modifications)})
+}
+
+// redoModificationHandler applies an undone modification again
+func (app *App) redoModificationHandler(c *gin.Context) {
+	modID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modification ID"})
+		return
+	}
+	modification, err := GetModification(app.Database, uint(modID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Modification not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modification"})
+		log.Errorf("Failed to retrieve modification: %v", err)
+		return
+	}
+	if !modification.Undone {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Modification hasn't been undone"})
+		return
+	}
+
+	err = app.applyModificationValues(c.Request.Context(), ModificationOperationRedo, []ModificationHistory{*modification})
+	if err != nil {
+		abortModificationUpdate(c, err)
+		return
+	}
+	if err := SetModificationRedone(app.Database, modification); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark modification as redone"})
+		log.Errorf("Failed to mark modification %d as redone: %v", modification.ID, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// abortModificationUpdate responds with the error of undoing or redoing modifications
+func abortModificationUpdate(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errModifiedDocumentDeleted):
+		c.JSON(http.StatusGone, gin.H{"error": "Document was deleted in paperless-ngx"})
+	case errors.Is(err, errUnsupportedModification):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modification field"})
+		log.Errorf("Failed to update modified document: %v", err)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
+		log.Errorf("Failed to update modified document: %v", err)
+	}
+}
+
+// getPendingTagsHandler handles the GET /api/pending-tags endpoint
+func (app *App) getPendingTagsHandler(c *gin.Context) {
+	pendingTags, err := GetPendingTags(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pending tags"})
+		log.Errorf("Failed to retrieve pending tags: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, pendingTags)
+}
+
+// getOrphanedDocumentsHandler handles the GET /api/orphaned-documents endpoint.
+// It lists the documents that were deleted in paperless-ngx while local records still refer to them.
+func (app *App) getOrphanedDocumentsHandler(c *gin.Context) {
+	orphanedDocuments, err := GetOrphanedDocuments(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orphaned documents"})
+		log.Errorf("Failed to retrieve orphaned documents: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, orphanedDocuments)
+}
+
+// purgeOrphanedDocumentsHandler handles the DELETE /api/orphaned-documents endpoint.
+// History, pending tags, reports, index entries and cached files of orphaned documents are removed.
+func (app *App) purgeOrphanedDocumentsHandler(c *gin.Context) {
+	documentIDs, err := PurgeOrphanedDocuments(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to purge orphaned documents"})
+		log.Errorf("Failed to purge orphaned documents: %v", err)
+		return
+	}
+
+	for _, documentID := range documentIDs {
+		if err := app.Client.RemoveCachedDocument(int(documentID)); err != nil {
+			log.Warnf("Failed to remove cached files of document %d: %v", documentID, err)
+		}
+	}
+
+	log.Infof("Purged local data of %d orphaned documents", len(documentIDs))
+	c.JSON(http.StatusOK, gin.H{"purged_documents": documentIDs})
+}
+
+// getMigrationsHandler handles the GET /api/migrations endpoint.
+// It lists the migrations of the database schema and whether they are applied.
+func (app *App) getMigrationsHandler(c *gin.Context) {
+	migrations, err := migrationStatus(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve the database migrations"})
+		log.Errorf("Failed to retrieve the database migrations: %v", err)
+		return
+	}
+
+	pending := 0
+	for _, migration := range migrations {
+		if !migration.Applied {
+			pending++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"migrations": migrations, "pending": pending})
+}
+
+// getBackupsHandler handles the GET /api/admin/backups endpoint.
+// It lists the backups stored in BACKUP_PATH, newest first.
+func (app *App) getBackupsHandler(c *gin.Context) {
+	store, err := newBackupStore(backupPath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	backups, err := store.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list the backups"})
+		log.Errorf("Failed to list the backups: %v", err)
+		return
+	}
+
+	slices.SortFunc(backups, func(a, b BackupInfo) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// downloadBackupHandler handles the GET /api/admin/backup endpoint.
+// It returns a new backup archive of the database and the prompts without storing it.
+func (app *App) downloadBackupHandler(c *gin.Context) {
+	now := time.Now()
+	backupMutex.Lock()
+	data, err := createBackup(app.Database, now)
+	backupMutex.Unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create the backup"})
+		log.Errorf("Failed to create the backup: %v", err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, backupName(now)))
+	c.Data(http.StatusOK, "application/octet-stream", data)
+}
+
+// createBackupHandler handles the POST /api/admin/backup endpoint.
+// It stores a new backup in BACKUP_PATH and deletes all but the newest BACKUP_KEEP backups.
+func (app *App) createBackupHandler(c *gin.Context) {
+	store, err := newBackupStore(backupPath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	backup, err := app.backup(c.Request.Context(), store, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to back up the database"})
+		log.Errorf("Failed to back up the database: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, backup)
+}
+
+// restoreBackupHandler handles the POST /api/admin/restore endpoint.
+// It replaces the local database and the prompts with a backup, the stored backup named by ?name= or the archive
+// sent as request body.
+func (app *App) restoreBackupHandler(c *gin.Context) {
+	var data []byte
+	if name := c.Query("name"); name != "" {
+		if _, ok := parseBackupName(name); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup name"})
+			return
+		}
+		store, err := newBackupStore(backupPath)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if data, err = store.Get(c.Request.Context(), name); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+			log.Errorf("Failed to read backup %s: %v", name, err)
+			return
+		}
+	} else {
+		var err error
+		data, err = io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxBackupSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error reading backup: %v", err)})
+			return
+		}
+		if len(data) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Send a backup archive or the name of a stored backup"})
+			return
+		}
+	}
+
+	manifest, err := restoreBackup(app.Database, data)
+	if errors.Is(err, errInvalidBackup) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore the backup"})
+		log.Errorf("Failed to restore the backup: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"restored": manifest})
+}
+
+// feedbackHandler handles the POST /api/feedback endpoint.
+// It records whether the suggested values for a document were accepted, edited or rejected.
+func (app *App) feedbackHandler(c *gin.Context) {
+	var request FeedbackRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+
+	records, err := feedbackRecords(c.Request.Context(), request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := attributeFeedback(app.Database, records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record feedback"})
+		log.Errorf("Failed to find the prompt experiments of the feedback for document %d: %v", request.DocumentID, err)
+		return
+	}
+	if err := InsertSuggestionFeedback(app.Database, records); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record feedback"})
+		log.Errorf("Failed to record feedback for document %d: %v", request.DocumentID, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"recorded": len(records)})
+}
+
+// getExperimentsHandler handles the GET /api/experiments endpoint.
+// It compares the variants of the prompt experiments by the feedback on their suggestions.
+func (app *App) getExperimentsHandler(c *gin.Context) {
+	results, err := collectExperimentResults(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to collect the results of the prompt experiments"})
+		log.Errorf("Failed to collect the results of the prompt experiments: %v", err)
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// getAPITokensHandler handles the GET /api/tokens endpoint.
+// Users see their own tokens, requests with an API key or to an unprotected API see the tokens of all users.
+func (app *App) getAPITokensHandler(c *gin.Context) {
+	tokens, err := GetAPITokens(app.Database, c.GetString("user"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API tokens"})
+		log.Errorf("Failed to retrieve API tokens: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// createAPITokenHandler handles the POST /api/tokens endpoint.
+// Users create tokens for themselves, requests with an API key or to an unprotected API for any user.
+func (app *App) createAPITokenHandler(c *gin.Context) {
+	var request NewAPITokenRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+		return
+	}
+	request.User, request.Name = strings.TrimSpace(request.User), strings.TrimSpace(request.Name)
+
+	if owner := c.GetString("user"); owner != "" {
+		if request.User != "" && request.User != owner {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Tokens can only be created for yourself"})
+			return
+		}
+		request.User = owner
+	}
+	if request.User == "" || request.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user and name are required"})
+		return
+	}
+
+	token, hash, err := generateAPIToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate API token"})
+		log.Errorf("Failed to generate API token: %v", err)
+		return
+	}
+	record := APIToken{User: request.User, Name: request.Name, TokenHash: hash, Prefix: token[:apiTokenDisplayLength]}
+	if err := CreateAPIToken(app.Database, &record); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store API token"})
+		log.Errorf("Failed to store API token: %v", err)
+		return
+	}
+
+	log.Infof("Created API token %d (%s) for user %s", record.ID, record.Name, record.User)
+	c.JSON(http.StatusCreated, CreatedAPIToken{APIToken: record, Token: token})
+}
+
+// revokeAPITokenHandler handles the DELETE /api/tokens/:id endpoint.
+// Requests with the token are rejected from then on, the token stays listed with its revocation date.
+func (app *App) revokeAPITokenHandler(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	token, err := GetAPIToken(app.Database, uint(id))
+	// Tokens of other users are hidden like missing ones
+	if owner := c.GetString("user"); err != nil || (owner != "" && token.User != owner) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API token not found"})
+		return
+	}
+
+	if token.RevokedAt == "" {
+		if err := RevokeAPIToken(app.Database, token); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API token"})
+			log.Errorf("Failed to revoke API token %d: %v", token.ID, err)
+			return
+		}
+		log.Infof("Revoked API token %d (%s) of user %s", token.ID, token.Name, token.User)
+	}
+
+	c.JSON(http.StatusOK, token)
+}
+
+// getFailuresHandler handles the GET /api/failures endpoint.
+// It lists the documents that processing was given up for, with the reason of the last failure.
+func (app *App) getFailuresHandler(c *gin.Context) {
+	failures, err := GetDeadLetteredDocuments(app.Database)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve failures"})
+		log.Errorf("Failed to retrieve failures: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, failures)
+}
+
+// retryFailureHandler handles the POST /api/failures/:document_id/retry endpoint.
+// The ERROR_TAG is replaced by the tag that selected the document for processing, so it is processed again.
+func (app *App) retryFailureHandler(c *gin.Context) {
+	documentID, err := strconv.Atoi(c.Param("document_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	failure, err := GetDocumentFailure(app.Database, uint(documentID))
+	if err != nil || !failure.DeadLettered {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No failure recorded for the document"})
+		return
+	}
+
+	err = app.retryDeadLetteredDocument(c.Request.Context(), failure)
+	if app.skipDeletedDocument(documentID, err) {
+		c.JSON(http.StatusGone, gin.H{"error": "Document was deleted in paperless-ngx"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error retrying document: %v", err)})
+		log.Errorf("Error retrying document %d: %v", documentID, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// processingStatus is the state of the background processing and the job workers
+type processingStatus struct {
+	Paused         bool       `json:"paused"`
+	PausedSince    *time.Time `json:"paused_since,omitempty"`
+	WithinSchedule bool       `json:"within_schedule"` // Whether PROCESSING_SCHEDULE and QUIET_HOURS allow processing now
+}
+
+// currentProcessingStatus returns the state of the background processing
+func currentProcessingStatus() processingStatus {
+	status := processingStatus{WithinSchedule: processingAllowed(time.Now())}
+	if since := backgroundPause.pausedSince(); !since.IsZero() {
+		status.Paused = true
+		status.PausedSince = &since
+	}
+	return status
+}
+
+// getProcessingStatusHandler handles the GET /api/processing/status endpoint
+func getProcessingStatusHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, currentProcessingStatus())
+}
+
+// pauseProcessingHandler handles the POST /api/processing/pause endpoint.
+// Documents and jobs already being processed are finished, no new ones are started until processing is resumed.
+func pauseProcessingHandler(c *gin.Context) {
+	if backgroundPause.pause() {
+		log.Infoln("Background processing and jobs paused")
+		events.publish(EventProcessingPaused, currentProcessingStatus())
+	}
+	c.JSON(http.StatusOK, currentProcessingStatus())
+}
+
+// resumeProcessingHandler handles the POST /api/processing/resume endpoint
+func resumeProcessingHandler(c *gin.Context) {
+	if backgroundPause.resume() {
+		log.Infoln("Background processing and jobs resumed")
+		events.publish(EventProcessingResumed, currentProcessingStatus())
+	}
+	c.JSON(http.StatusOK, currentProcessingStatus())
+}
+
+// eventsWebSocketHandler handles the GET /api/ws endpoint.
+// It upgrades the connection to a WebSocket pushing the events of paperless-gpt as JSON messages,
+// ?types=job,processing limits them to the given event types or categories.
+func eventsWebSocketHandler(c *gin.Context) {
+	conn, err := eventUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// The upgrader already responded with the error
+		log.Debugf("WebSocket upgrade failed: %v", err)
+		return
+	}
+	streamEvents(c.Request.Context(), conn, splitAndTrim(c.Query("types")))
+}
+
+// approvePendingTagHandler handles the POST /api/pending-tags/:id/approve endpoint.
+// The tag is created in paperless-ngx if needed and added to the document it was proposed for.
+func (app *App) approvePendingTagHandler(c *gin.Context) {
+	pendingTag, ok := app.getUndecidedPendingTag(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	document, err := app.Client.GetDocument(ctx, int(pendingTag.DocumentID))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		log.Errorf("Failed to retrieve document %d: %v", pendingTag.DocumentID, err)
+		return
+	}
+
+	availableTags, err := app.Client.GetAllTags(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+		log.Errorf("Failed to retrieve tags: %v", err)
+		return
+	}
+
+	tagID, exists := availableTags[pendingTag.Name]
+	if !exists {
+		tagID, err = app.Client.CreateTag(ctx, pendingTag.Name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tag"})
+			log.Errorf("Failed to create tag %s: %v", pendingTag.Name, err)
+			return
+		}
+	}
+
+	err = app.Client.AddTagToDocuments(ctx, []int{document.ID}, tagID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add tag to document"})
+		log.Errorf("Failed to add tag %s to document %d: %v", pendingTag.Name, document.ID, err)
+		return
+	}
+
+	// Record the change so it can be undone like any other tag modification
+	previousTags, _ := json.Marshal(document.Tags)
+	newTags, _ := json.Marshal(append(document.Tags, pendingTag.Name))
+	err = InsertModification(app.Database, &ModificationHistory{
+		DocumentID:    pendingTag.DocumentID,
+		ModField:      "tags",
+		PreviousValue: string(previousTags),
+		NewValue:      string(newTags),
+		User:          requestUser(ctx),
+		BatchID:       uuid.NewString(),
+	})
+	if err != nil {
+		log.Errorf("Error inserting modification record for document %d: %v", document.ID, err)
+	}
+
+	err = SetPendingTagStatus(app.Database, pendingTag, PendingTagStatusApproved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark tag as approved"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// rejectPendingTagHandler handles the POST /api/pending-tags/:id/reject endpoint.
+// Rejected tags are not proposed again.
+func (app *App) rejectPendingTagHandler(c *gin.Context) {
+	pendingTag, ok := app.getUndecidedPendingTag(c)
+	if !ok {
+		return
+	}
+
+	err := SetPendingTagStatus(app.Database, pendingTag, PendingTagStatusRejected)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark tag as rejected"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// getUndecidedPendingTag loads the pending tag referenced in the request path and
+// writes an error response if it does not exist or has already been decided on
+func (app *App) getUndecidedPendingTag(c *gin.Context) (*PendingTag, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pending tag ID"})
+		return nil, false
+	}
+
+	pendingTag, err := GetPendingTag(app.Database, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pending tag not found"})
+		return nil, false
+	}
+
+	if pendingTag.Status != PendingTagStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Tag has already been %s", pendingTag.Status)})
+		return nil, false
+	}
+
+	return pendingTag, true
+}
+
+// getReviewsHandler handles the GET /api/review endpoint.
+// It lists the suggestions generated automatically that wait for review (AUTO_REVIEW).
+func (app *App) getReviewsHandler(c *gin.Context) {
+	reviews, err := app.pendingReviews(0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pending reviews"})
+		log.Errorf("Failed to retrieve pending reviews: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, reviews)
+}
+
+// approveReviewHandler handles the POST /api/review/:id/approve endpoint.
+// The suggestions are applied to the document, fields sent in the request body replace the suggested ones.
+func (app *App) approveReviewHandler(c *gin.Context) {
+	review, ok := app.getUndecidedReview(c)
+	if !ok {
+		return
+	}
+
+	suggestion, err := review.decodeSuggestion()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&suggestion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request payload"})
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+
+	// The document may have changed since the suggestions were generated
+	suggestion.ID = int(review.DocumentID)
+	suggestion.OriginalDocument, err = app.Client.GetDocument(ctx, suggestion.ID)
+	if app.skipDeletedDocument(suggestion.ID, err) {
+		c.JSON(http.StatusGone, gin.H{"error": "Document was deleted in paperless-ngx"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve document"})
+		log.Errorf("Failed to retrieve document %d: %v", suggestion.ID, err)
+		return
+	}
+
+	documentUpdateMu.Lock()
+	err = app.Client.UpdateDocuments(ctx, []DocumentSuggestion{suggestion}, app.Database, false)
+	documentUpdateMu.Unlock()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error updating document: %v", err)})
+		log.Errorf("Error updating document %d: %v", suggestion.ID, err)
+		return
+	}
+
+	err = SetPendingReviewStatus(app.Database, review, ReviewStatusApproved)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark review as approved"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// rejectReviewHandler handles the POST /api/review/:id/reject endpoint.
+// The suggestions are discarded and the document is left unchanged.
+func (app *App) rejectReviewHandler(c *gin.Context) {
+	review, ok := app.getUndecidedReview(c)
+	if !ok {
+		return
+	}
+
+	err := SetPendingReviewStatus(app.Database, review, ReviewStatusRejected)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark review as rejected"})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// getUndecidedReview loads the review referenced in the request path and
+// writes an error response if it does not exist or has already been decided on
+func (app *App) getUndecidedReview(c *gin.Context) (*PendingReview, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review ID"})
+		return nil, false
+	}
+
+	review, err := GetPendingReview(app.Database, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+
+	if review.Status != ReviewStatusPending {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Suggestions have already been %s", review.Status)})
+		return nil, false
+	}
+
+	return review, true
+}
+
+// getCorrespondentDedupeHandler handles the GET /api/correspondents/dedupe endpoint
+func (app *App) getCorrespondentDedupeHandler(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	suggestions, err := app.suggestCorrespondentMerges(ctx, log.WithContext(ctx))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error analyzing correspondents: %v", err)})
+		log.Errorf("Error analyzing correspondents: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// mergeCorrespondentsHandler handles the POST /api/correspondents/dedupe endpoint
+func (app *App) mergeCorrespondentsHandler(c *gin.Context) {
+	var request CorrespondentMergeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid request payload: %v", err)})
+		log.Errorf("Invalid request payload: %v", err)
+		return
+	}
+
+	if request.CanonicalID == 0 || len(request.DuplicateIDs) == 0 || slices.Contains(request.DuplicateIDs, request.CanonicalID) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "canonical_id and duplicate_ids are required and must differ"})
+		return
+	}
+
+	remapped, err := app.mergeCorrespondents(c.Request.Context(), request)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("Error merging correspondents: %v", err), "remapped_documents": remapped})
+		log.Errorf("Error merging correspondents: %v", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"remapped_documents": remapped})
+}
+
+// getPIIReportHandler handles the GET /api/documents/:id/pii endpoint
+func (app *App) getPIIReportHandler(c *gin.Context) {
+	documentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	report, err := GetPIIReport(app.Database, uint(documentID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No PII report found for document"})
+		return
+	}
+
+	categories := map[string]int{}
+	if err := json.Unmarshal([]byte(report.Categories), &categories); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read PII report"})
+		log.Errorf("Failed to unmarshal PII report of document %d: %v", documentID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id":  report.DocumentID,
+		"contains_pii": len(categories) > 0,
+		"categories":   categories,
+		"scanned_at":   report.ScannedAt,
+	})
+}
+
+// getDocumentLogsHandler handles the GET /api/documents/:id/logs endpoint.
+// It returns the newest lines logged while processing the document, oldest first, captured at DOCUMENT_LOG_LEVEL.
+func (app *App) getDocumentLogsHandler(c *gin.Context) {
+	documentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || documentID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+	limit := defaultDocumentLogLimit
+	if value := c.Query("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxDocumentLogLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit must be a number between 1 and %d", maxDocumentLogLimit)})
+			return
+		}
+		limit = parsed
+	}
+
+	records, err := GetDocumentLogs(app.Database, uint(documentID), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document logs"})
+		log.Errorf("Failed to read the logs of document %d: %v", documentID, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document_id": documentID,
+		"logs":        documentLogLines(records),
+	})
+}
+
+// loginHandler handles the GET /auth/login endpoint.
+// It redirects to the OpenID Connect provider, which redirects back to /auth/callback after the user logged in.
+func (provider *oidcProvider) loginHandler(c *gin.Context) {
+	state, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	login := oidcLoginState{
+		State:    state,
+		Nonce:    nonce,
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: localReturnPath(c.Query("return_to"), provider.basePath),
+		Expiry:   time.Now().Add(oidcLoginDuration).Unix(),
+	}
+	cookie, err := provider.signCookie(login)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	provider.setCookie(c, oidcLoginCookie, cookie, provider.basePath+"/auth", oidcLoginDuration)
+
+	authURL := provider.oauth2.AuthCodeURL(login.State, oauth2.S256ChallengeOption(login.Verifier), oauth2.SetAuthURLParam("nonce", login.Nonce))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// callbackHandler handles the GET /auth/callback endpoint.
+// The authorization code is exchanged for an ID token, which starts the session of the user.
+func (provider *oidcProvider) callbackHandler(c *gin.Context) {
+	if errorCode := c.Query("error"); errorCode != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": fmt.Sprintf("Login failed: %s %s", errorCode, c.Query("error_description"))})
+		return
+	}
+
+	cookie, err := c.Cookie(oidcLoginCookie)
+	var login oidcLoginState
+	if err != nil || provider.readCookie(cookie, &login) != nil || time.Now().Unix() > login.Expiry {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Login expired, please try again"})
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(c.Query("state")), []byte(login.State)) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid login state"})
+		return
+	}
+	provider.setCookie(c, oidcLoginCookie, "", provider.basePath+"/auth", 0)
+
+	ctx := provider.exchangeContext(c.Request.Context())
+	token, err := provider.oauth2.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login failed"})
+		log.Errorf("Failed to exchange the OIDC authorization code: %v", err)
+		return
+	}
+	idToken, _ := token.Extra("id_token").(string)
+	claims, err := provider.verifyToken(c.Request.Context(), idToken, provider.oauth2.ClientID)
+	if err == nil && subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(login.Nonce)) != 1 {
+		err = errors.New("nonce mismatch")
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Login failed"})
+		log.Errorf("Invalid OIDC ID token: %v", err)
+		return
+	}
+
+	session, err := provider.signCookie(oidcSession{User: claims.userName(), Expiry: time.Now().Add(oidcSessionDuration).Unix()})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start session"})
+		return
+	}
+	provider.setCookie(c, oidcSessionCookie, session, provider.basePath+"/", oidcSessionDuration)
+	log.Infof("User %s logged in", claims.userName())
+	c.Redirect(http.StatusFound, login.ReturnTo)
+}
+
+// logoutHandler handles the GET /auth/logout endpoint.
+// It ends the session and the login at the OpenID Connect provider, if the provider supports it.
+func (provider *oidcProvider) logoutHandler(c *gin.Context) {
+	provider.setCookie(c, oidcSessionCookie, "", provider.basePath+"/", 0)
+	if provider.endSessionURL != "" {
+		c.Redirect(http.StatusFound, provider.endSessionURL)
+		return
+	}
+	c.Redirect(http.StatusFound, provider.basePath+"/")
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"slices"
@@ -12,12 +11,12 @@ import (
 	_ "image/jpeg"
 
 	"github.com/sirupsen/logrus"
-	"github.com/tmc/langchaingo/llms"
 )
 
 // getSuggestedCorrespondent generates a suggested correspondent for a document using the LLM
-func (app *App) getSuggestedCorrespondent(ctx context.Context, content string, suggestedTitle string, availableCorrespondents []string, correspondentBlackList []string) (string, error) {
-	likelyLanguage := getLikelyLanguage()
+func (app *App) getSuggestedCorrespondent(ctx context.Context, content string, suggestedTitle string, availableCorrespondents []string, correspondentBlackList []string, logger *logrus.Entry) (string, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+	choices, restricted := settingsFor(ctx).correspondentChoices(availableCorrespondents, correspondentBlackList)
 
 	templateMutex.RLock()
 	defer templateMutex.RUnlock()
@@ -25,51 +24,71 @@ func (app *App) getSuggestedCorrespondent(ctx context.Context, content string, s
 	// Get available tokens for content
 	templateData := map[string]interface{}{
 		"Language":                likelyLanguage,
-		"AvailableCorrespondents": availableCorrespondents,
+		"AvailableCorrespondents": choices,
 		"BlackList":               correspondentBlackList,
+		"Restricted":              restricted,
 		"Title":                   suggestedTitle,
+		"Examples":                app.promptExamples(ctx, "correspondent"),
 	}
 
-	availableTokens, err := getAvailableTokensForContent(correspondentTemplate, templateData)
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "correspondent", correspondentTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
 	if err != nil {
 		return "", fmt.Errorf("error calculating available tokens: %v", err)
 	}
 
 	// Truncate content if needed
-	truncatedContent, err := truncateContentByTokens(content, availableTokens)
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
 	if err != nil {
 		return "", fmt.Errorf("error truncating content: %v", err)
 	}
 
 	// Execute template with truncated content
-	var promptBuffer bytes.Buffer
 	templateData["Content"] = truncatedContent
-	err = correspondentTemplate.Execute(&promptBuffer, templateData)
+	prompt, err := renderPrompt(tmpl, templateData)
 	if err != nil {
 		return "", fmt.Errorf("error executing correspondent template: %v", err)
 	}
 
-	prompt := promptBuffer.String()
-	log.Debugf("Correspondent suggestion prompt: %s", prompt)
+	logger.Debugf("Correspondent suggestion prompt: %s", prompt.text())
 
-	completion, err := app.LLM.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Parts: []llms.ContentPart{
-				llms.TextContent{
-					Text: prompt,
-				},
-			},
-			Role: llms.ChatMessageTypeHuman,
-		},
-	})
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "correspondent"), prompt.messages())
 	if err != nil {
 		return "", fmt.Errorf("error getting response from LLM: %v", err)
 	}
 
-	response := stripReasoning(strings.TrimSpace(completion.Choices[0].Content))
+	response := prompt.complete(stripReasoning(strings.TrimSpace(completion.Choices[0].Content)))
+	if restricted {
+		index := slices.IndexFunc(choices, func(choice string) bool { return strings.EqualFold(choice, strings.TrimSpace(response)) })
+		if index < 0 {
+			logger.Infof("Ignoring the suggested correspondent %q, it is not one of the allowed correspondents", response)
+			return "", nil
+		}
+		// The spelling of the list is kept
+		return choices[index], nil
+	}
 	return response, nil
 }
 
+// correspondentChoices returns the correspondents the LLM may choose from and whether it is restricted to them: the
+// correspondents of CORRESPONDENT_WHITE_LIST, which are created if they don't exist yet, or the existing ones if
+// ALLOW_NEW_CORRESPONDENTS is false. Otherwise the existing correspondents are examples. Blacklisted names are never
+// allowed.
+func (settings *runtimeSettings) correspondentChoices(availableCorrespondents []string, blackList []string) ([]string, bool) {
+	switch {
+	case len(settings.CorrespondentWhiteList) > 0:
+		return slices.DeleteFunc(slices.Clone(settings.CorrespondentWhiteList), func(name string) bool {
+			return slices.ContainsFunc(blackList, func(blocked string) bool { return strings.EqualFold(strings.TrimSpace(blocked), name) })
+		}), true
+	case !settings.AllowNewCorrespondents:
+		return slices.DeleteFunc(slices.Clone(availableCorrespondents), func(name string) bool {
+			return slices.ContainsFunc(blackList, func(blocked string) bool { return strings.EqualFold(strings.TrimSpace(blocked), name) })
+		}), true
+	}
+	return availableCorrespondents, false
+}
+
 // getSuggestedTags generates suggested tags for a document using the LLM
 func (app *App) getSuggestedTags(
 	ctx context.Context,
@@ -77,66 +96,65 @@ func (app *App) getSuggestedTags(
 	suggestedTitle string,
 	availableTags []string,
 	originalTags []string,
-	logger *logrus.Entry) ([]string, error) {
-	likelyLanguage := getLikelyLanguage()
+	logger *logrus.Entry) ([]string, []string, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+	settings := settingsFor(ctx)
 
 	templateMutex.RLock()
 	defer templateMutex.RUnlock()
 
 	// Remove all paperless-gpt related tags from available tags
-	availableTags = removeTagFromList(availableTags, manualTag)
-	availableTags = removeTagFromList(availableTags, autoTag)
-	availableTags = removeTagFromList(availableTags, autoOcrTag)
+	availableTags = removeTagFromList(availableTags, settings.ManualTag)
+	availableTags = removeTagFromList(availableTags, settings.AutoTag)
+	availableTags = removeTagFromList(availableTags, settings.AutoOCRTag)
+
+	// Only the tags of TAG_WHITE_LIST and none of TAG_BLACK_LIST are offered. The original tags of the document are kept
+	// anyway, so they are still matched against all available tags.
+	promptTags := slices.DeleteFunc(slices.Clone(availableTags), func(tag string) bool { return !settings.isAllowedTag(tag) })
 
 	// Get available tokens for content
 	templateData := map[string]interface{}{
 		"Language":      likelyLanguage,
-		"AvailableTags": availableTags,
+		"AvailableTags": promptTags,
 		"OriginalTags":  originalTags,
 		"Title":         suggestedTitle,
+		"AllowNewTags":  settings.AllowNewTags,
+		"TagGroups":     tagGroups(),
+		"Examples":      app.promptExamples(ctx, "tag"),
 	}
 
-	availableTokens, err := getAvailableTokensForContent(tagTemplate, templateData)
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "tag", tagTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
 	if err != nil {
 		logger.Errorf("Error calculating available tokens: %v", err)
-		return nil, fmt.Errorf("error calculating available tokens: %v", err)
+		return nil, nil, fmt.Errorf("error calculating available tokens: %v", err)
 	}
 
 	// Truncate content if needed
-	truncatedContent, err := truncateContentByTokens(content, availableTokens)
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
 	if err != nil {
 		logger.Errorf("Error truncating content: %v", err)
-		return nil, fmt.Errorf("error truncating content: %v", err)
+		return nil, nil, fmt.Errorf("error truncating content: %v", err)
 	}
 
 	// Execute template with truncated content
-	var promptBuffer bytes.Buffer
 	templateData["Content"] = truncatedContent
-	err = tagTemplate.Execute(&promptBuffer, templateData)
+	prompt, err := renderPrompt(tmpl, templateData)
 	if err != nil {
 		logger.Errorf("Error executing tag template: %v", err)
-		return nil, fmt.Errorf("error executing tag template: %v", err)
-	}
-
-	prompt := promptBuffer.String()
-	logger.Debugf("Tag suggestion prompt: %s", prompt)
-
-	completion, err := app.LLM.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Parts: []llms.ContentPart{
-				llms.TextContent{
-					Text: prompt,
-				},
-			},
-			Role: llms.ChatMessageTypeHuman,
-		},
-	})
+		return nil, nil, fmt.Errorf("error executing tag template: %v", err)
+	}
+
+	logger.Debugf("Tag suggestion prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "tag"), prompt.messages())
 	if err != nil {
 		logger.Errorf("Error getting response from LLM: %v", err)
-		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+		return nil, nil, fmt.Errorf("error getting response from LLM: %v", err)
 	}
 
-	response := stripReasoning(completion.Choices[0].Content)
+	response := prompt.complete(stripReasoning(completion.Choices[0].Content))
 
 	suggestedTags := strings.Split(response, ",")
 	for i, tag := range suggestedTags {
@@ -144,28 +162,44 @@ func (app *App) getSuggestedTags(
 	}
 
 	// append the original tags to the suggested tags
+	responseTags := slices.Clone(suggestedTags)
 	suggestedTags = append(suggestedTags, originalTags...)
 	// Remove duplicates
 	slices.Sort(suggestedTags)
 	suggestedTags = slices.Compact(suggestedTags)
 
-	// Filter out tags that are not in the available tags list
+	// Enforce the rules of the tag taxonomy, preferring the tags chosen by the LLM over the original ones
+	suggestedTags = tagTaxonomy.Apply(suggestedTags, responseTags, logger)
+
+	// Filter out tags that are not in the available tags list.
+	// If new tags are allowed, they are returned separately so they can be approved first.
 	filteredTags := []string{}
+	newTags := []string{}
 	for _, tag := range suggestedTags {
+		original := slices.ContainsFunc(originalTags, func(originalTag string) bool { return strings.EqualFold(originalTag, tag) })
+		if !original && !settings.isAllowedTag(tag) {
+			logger.Debugf("Ignoring the suggested tag %q, it is blacklisted or not whitelisted", tag)
+			continue
+		}
+		found := false
 		for _, availableTag := range availableTags {
 			if strings.EqualFold(tag, availableTag) {
 				filteredTags = append(filteredTags, availableTag)
+				found = true
 				break
 			}
 		}
+		if !found && settings.AllowNewTags && isValidNewTag(tag) && !slices.Contains(originalTags, tag) {
+			newTags = append(newTags, tag)
+		}
 	}
 
-	return filteredTags, nil
+	return filteredTags, newTags, nil
 }
 
 // getSuggestedTitle generates a suggested title for a document using the LLM
 func (app *App) getSuggestedTitle(ctx context.Context, content string, originalTitle string, logger *logrus.Entry) (string, error) {
-	likelyLanguage := getLikelyLanguage()
+	likelyLanguage := getDocumentLanguage(ctx)
 
 	templateMutex.RLock()
 	defer templateMutex.RUnlock()
@@ -175,53 +209,45 @@ func (app *App) getSuggestedTitle(ctx context.Context, content string, originalT
 		"Language": likelyLanguage,
 		"Content":  content,
 		"Title":    originalTitle,
+		"Examples": app.promptExamples(ctx, "title"),
 	}
 
-	availableTokens, err := getAvailableTokensForContent(titleTemplate, templateData)
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "title", titleTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
 	if err != nil {
 		logger.Errorf("Error calculating available tokens: %v", err)
 		return "", fmt.Errorf("error calculating available tokens: %v", err)
 	}
 
 	// Truncate content if needed
-	truncatedContent, err := truncateContentByTokens(content, availableTokens)
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
 	if err != nil {
 		logger.Errorf("Error truncating content: %v", err)
 		return "", fmt.Errorf("error truncating content: %v", err)
 	}
 
 	// Execute template with truncated content
-	var promptBuffer bytes.Buffer
 	templateData["Content"] = truncatedContent
-	err = titleTemplate.Execute(&promptBuffer, templateData)
+	prompt, err := renderPrompt(tmpl, templateData)
 
 	if err != nil {
 		return "", fmt.Errorf("error executing title template: %v", err)
 	}
 
-	prompt := promptBuffer.String()
-	logger.Debugf("Title suggestion prompt: %s", prompt)
+	logger.Debugf("Title suggestion prompt: %s", prompt.text())
 
-	completion, err := app.LLM.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Parts: []llms.ContentPart{
-				llms.TextContent{
-					Text: prompt,
-				},
-			},
-			Role: llms.ChatMessageTypeHuman,
-		},
-	})
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "title"), prompt.messages())
 	if err != nil {
 		return "", fmt.Errorf("error getting response from LLM: %v", err)
 	}
-	result := stripReasoning(completion.Choices[0].Content)
+	result := prompt.complete(stripReasoning(completion.Choices[0].Content))
 	return strings.TrimSpace(strings.Trim(result, "\"")), nil
 }
 
 // getSuggestedCreatedDate generates a suggested createdDate for a document using the LLM
 func (app *App) getSuggestedCreatedDate(ctx context.Context, content string, logger *logrus.Entry) (string, error) {
-	likelyLanguage := getLikelyLanguage()
+	likelyLanguage := getDocumentLanguage(ctx)
 
 	templateMutex.RLock()
 	defer templateMutex.RUnlock()
@@ -233,50 +259,45 @@ func (app *App) getSuggestedCreatedDate(ctx context.Context, content string, log
 		"Today":    getTodayDate(), // must be in YYYY-MM-DD format
 	}
 
-	availableTokens, err := getAvailableTokensForContent(createdDateTemplate, templateData)
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "created_date", createdDateTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
 	if err != nil {
 		logger.Errorf("Error calculating available tokens: %v", err)
 		return "", fmt.Errorf("error calculating available tokens: %v", err)
 	}
 
 	// Truncate content if needed
-	truncatedContent, err := truncateContentByTokens(content, availableTokens)
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
 	if err != nil {
 		logger.Errorf("Error truncating content: %v", err)
 		return "", fmt.Errorf("error truncating content: %v", err)
 	}
 
 	// Execute template with truncated content
-	var promptBuffer bytes.Buffer
 	templateData["Content"] = truncatedContent
-	err = createdDateTemplate.Execute(&promptBuffer, templateData)
+	prompt, err := renderPrompt(tmpl, templateData)
 
 	if err != nil {
 		return "", fmt.Errorf("error executing createdDate template: %v", err)
 	}
 
-	prompt := promptBuffer.String()
-	logger.Debugf("CreatedDate suggestion prompt: %s", prompt)
+	logger.Debugf("CreatedDate suggestion prompt: %s", prompt.text())
 
-	completion, err := app.LLM.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Parts: []llms.ContentPart{
-				llms.TextContent{
-					Text: prompt,
-				},
-			},
-			Role: llms.ChatMessageTypeHuman,
-		},
-	})
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "created_date"), prompt.messages())
 	if err != nil {
 		return "", fmt.Errorf("error getting response from LLM: %v", err)
 	}
-	result := stripReasoning(completion.Choices[0].Content)
+	result := prompt.complete(stripReasoning(completion.Choices[0].Content))
 	return strings.TrimSpace(strings.Trim(result, "\"")), nil
 }
 
 // generateDocumentSuggestions generates suggestions for a set of documents
 func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionRequest GenerateSuggestionsRequest, logger *logrus.Entry) ([]DocumentSuggestion, error) {
+	// The documents are processed with the settings in effect now, even if they change in the meantime
+	ctx = withSettings(ctx)
+	settings := settingsFor(ctx)
+
 	// Fetch all available tags from paperless-ngx
 	availableTagsMap, err := app.Client.GetAllTags(ctx)
 	if err != nil {
@@ -286,7 +307,7 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 	// Prepare a list of tag names
 	availableTagNames := make([]string, 0, len(availableTagsMap))
 	for tagName := range availableTagsMap {
-		if tagName == manualTag {
+		if tagName == settings.ManualTag {
 			continue
 		}
 		availableTagNames = append(availableTagNames, tagName)
@@ -304,6 +325,33 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 		availableCorrespondentNames = append(availableCorrespondentNames, correspondentName)
 	}
 
+	// Translations, the prompts by language and the choice among the languages of LLM_LANGUAGE depend on the detected
+	// language
+	shouldDetectLanguage := suggestionRequest.DetectLanguage || settings.DetectLanguage || suggestionRequest.Translate || len(languagePromptTemplates) > 0 ||
+		len(expectedLanguages(ctx)) > 1
+
+	// Prepare the custom fields the LLM should populate
+	var allCustomFields []CustomField
+	var extractCustomFields []CustomField
+	if suggestionRequest.GenerateCustomFields ||
+		(shouldDetectLanguage && languageCustomField != "") ||
+		(suggestionRequest.GenerateSummary && summaryCustomField != "") ||
+		(suggestionRequest.GenerateDueDate && dueDateCustomField != "") ||
+		(suggestionRequest.GenerateAmount && amountCustomField != "") ||
+		(suggestionRequest.Triage && actionDeadlineCustomField != "") ||
+		(suggestionRequest.LinkRelated && relatedDocumentsCustomField != "") ||
+		slices.ContainsFunc(suggestionRequest.Documents, isInvoiceDocument) ||
+		slices.ContainsFunc(suggestionRequest.Documents, isContractDocument) ||
+		slices.ContainsFunc(suggestionRequest.Documents, func(doc Document) bool { return len(doc.CustomFields) > 0 }) {
+		allCustomFields, err = app.Client.GetAllCustomFields(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch custom fields: %v", err)
+		}
+	}
+	if suggestionRequest.GenerateCustomFields {
+		extractCustomFields = extractableCustomFields(allCustomFields)
+	}
+
 	documents := suggestionRequest.Documents
 	documentSuggestions := []DocumentSuggestion{}
 
@@ -315,18 +363,67 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 		wg.Add(1)
 		go func(doc Document) {
 			defer wg.Done()
+			// The documents are processed concurrently, each with its own error
+			var err error
 			documentID := doc.ID
-			docLogger := documentLogger(documentID)
+			// The lines are logged with the document ID, so they are captured for GET /api/documents/:id/logs
+			docLogger := logger.WithField("document_id", documentID)
 			docLogger.Printf("Processing Document ID %d...", documentID)
 
 			content := doc.Content
 			suggestedTitle := doc.Title
 			var suggestedTags []string
+			var suggestedNewTags []string
 			var suggestedCorrespondent string
 			var suggestedCreatedDate string
+			var suggestedCustomFields []CustomFieldValue
+			var suggestedLanguage string
+			var suggestedSummary string
+			var suggestedDueDate string
+			var suggestedInvoiceFields []CustomFieldValue
+			var suggestedAmount float64
+			var suggestedCurrency string
+			var suggestedContractTerms contractTerms
+			var suggestedSensitivity string
+			var suggestedTranslation string
+			var suggestedTriage triageResult
+			var suggestedRelatedDocuments []RelatedDocument
+
+			// Detect the document language and use it for all following prompts, the LLM usage is recorded for the document.
+			// The prompts of PROMPT_RULES_FILE matching the tags and the document type replace the global ones, all prompts get
+			// the current values of the document.
+			docCtx := withPromptDocument(withLLMDocument(ctx, documentID), doc, allCustomFields, app.ocrConfidence(documentID))
+			if shouldDetectLanguage && strings.TrimSpace(content) != "" {
+				suggestedLanguage, err = app.detectDocumentLanguage(docCtx, content, docLogger)
+				if err != nil {
+					docLogger.Warnf("Language detection failed, using %s: %v", getLikelyLanguage(docCtx), err)
+					suggestedLanguage = ""
+				} else {
+					docLogger.Printf("Detected language for document %d: %s", documentID, suggestedLanguage)
+					language := promptLanguageFor(docCtx, suggestedLanguage)
+					if language.Code != suggestedLanguage {
+						docLogger.Printf("Language %s of document %d is not one of LLM_LANGUAGE, using %s", suggestedLanguage, documentID, language.Name)
+					}
+					docCtx = withPromptLanguage(withDocumentLanguage(docCtx, language.Name), language.Code)
+				}
+			}
+
+			// Scan for personal data
+			var piiFindings map[string]int
+			if (suggestionRequest.ScanPII || settings.PIIScan) && strings.TrimSpace(content) != "" {
+				piiFindings, err = app.scanDocumentPII(docCtx, content, docLogger)
+				if err != nil {
+					docLogger.Warnf("PII scan failed for document %d: %v", documentID, err)
+					piiFindings = nil
+				} else if app.Database != nil {
+					if err := SavePIIReport(app.Database, uint(documentID), piiFindings); err != nil {
+						docLogger.Errorf("Error storing PII report for document %d: %v", documentID, err)
+					}
+				}
+			}
 
 			if suggestionRequest.GenerateTitles {
-				suggestedTitle, err = app.getSuggestedTitle(ctx, content, suggestedTitle, docLogger)
+				suggestedTitle, err = app.getSuggestedTitle(docCtx, content, suggestedTitle, docLogger)
 				if err != nil {
 					mu.Lock()
 					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
@@ -337,34 +434,136 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 			}
 
 			if suggestionRequest.GenerateTags {
-				suggestedTags, err = app.getSuggestedTags(ctx, content, suggestedTitle, availableTagNames, doc.Tags, docLogger)
+				suggestedTags, suggestedNewTags, err = app.getSuggestedTags(docCtx, content, suggestedTitle, availableTagNames, doc.Tags, docLogger)
 				if err != nil {
 					mu.Lock()
 					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
 					mu.Unlock()
-					logger.Errorf("Error generating tags for document %d: %v", documentID, err)
+					docLogger.Errorf("Error generating tags for document %d: %v", documentID, err)
 					return
 				}
 			}
 
 			if suggestionRequest.GenerateCorrespondents {
-				suggestedCorrespondent, err = app.getSuggestedCorrespondent(ctx, content, suggestedTitle, availableCorrespondentNames, correspondentBlackList)
+				suggestedCorrespondent, err = app.getSuggestedCorrespondent(docCtx, content, suggestedTitle, availableCorrespondentNames, correspondentBlackList, docLogger)
 				if err != nil {
 					mu.Lock()
 					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
 					mu.Unlock()
-					log.Errorf("Error generating correspondents for document %d: %v", documentID, err)
+					docLogger.Errorf("Error generating correspondents for document %d: %v", documentID, err)
 					return
 				}
 			}
 
 			if suggestionRequest.GenerateCreatedDate {
-				suggestedCreatedDate, err = app.getSuggestedCreatedDate(ctx, content, docLogger)
+				suggestedCreatedDate, err = app.getSuggestedCreatedDate(docCtx, content, docLogger)
+				if err != nil {
+					mu.Lock()
+					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
+					mu.Unlock()
+					docLogger.Errorf("Error generating createdDate for document %d: %v", documentID, err)
+					return
+				}
+			}
+
+			if suggestionRequest.GenerateCustomFields {
+				suggestedCustomFields, err = app.getSuggestedCustomFields(docCtx, content, suggestedTitle, extractCustomFields, docLogger)
+				if err != nil {
+					mu.Lock()
+					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
+					mu.Unlock()
+					docLogger.Errorf("Error generating custom fields for document %d: %v", documentID, err)
+					return
+				}
+			}
+
+			if suggestionRequest.GenerateSummary {
+				suggestedSummary, err = app.getSuggestedSummary(docCtx, content, suggestedTitle, docLogger)
+				if err != nil {
+					mu.Lock()
+					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
+					mu.Unlock()
+					docLogger.Errorf("Error generating summary for document %d: %v", documentID, err)
+					return
+				}
+			}
+
+			if suggestionRequest.Translate && needsTranslation(suggestedLanguage) && !hasTranslation(content) {
+				suggestedTranslation, err = app.getSuggestedTranslation(docCtx, content, suggestedLanguage, docLogger)
+				if err != nil {
+					mu.Lock()
+					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
+					mu.Unlock()
+					docLogger.Errorf("Error translating document %d: %v", documentID, err)
+					return
+				}
+			}
+
+			if suggestionRequest.GenerateDueDate {
+				suggestedDueDate, err = app.getSuggestedDueDate(docCtx, content, suggestedTitle, doc.CreatedDate, docLogger)
+				if err != nil {
+					// An implausible due date is not worth failing the whole document for
+					docLogger.Warnf("Ignoring due date for document %d: %v", documentID, err)
+					suggestedDueDate = ""
+				}
+			}
+
+			if (suggestionRequest.ClassifySensitivity || settings.ClassifySensitivity) && strings.TrimSpace(content) != "" {
+				suggestedSensitivity, err = app.getSuggestedSensitivity(docCtx, content, suggestedTitle, docLogger)
+				if err != nil {
+					// Without a valid level the document simply keeps its current classification
+					docLogger.Warnf("Ignoring sensitivity level for document %d: %v", documentID, err)
+					suggestedSensitivity = ""
+				}
+			}
+
+			if suggestionRequest.Triage && strings.TrimSpace(content) != "" {
+				suggestedTriage, err = app.getSuggestedTriage(docCtx, content, suggestedTitle, doc.CreatedDate, docLogger)
+				if err != nil {
+					// The document simply stays untriaged
+					docLogger.Warnf("Ignoring triage for document %d: %v", documentID, err)
+					suggestedTriage = triageResult{}
+				}
+			}
+
+			if suggestionRequest.LinkRelated && app.Database != nil {
+				suggestedRelatedDocuments, err = app.findRelatedDocuments(docCtx, doc, content, docLogger)
+				if err != nil {
+					// Related documents are a convenience, the document can be processed without them
+					docLogger.Warnf("Ignoring related documents for document %d: %v", documentID, err)
+					suggestedRelatedDocuments = nil
+				}
+			}
+
+			if suggestionRequest.GenerateAmount && amountCustomField != "" {
+				suggestedAmount, suggestedCurrency, err = app.getSuggestedAmount(docCtx, content, suggestedTitle, docLogger)
+				if err != nil {
+					// An unreadable amount is not worth failing the whole document for
+					docLogger.Warnf("Ignoring amount for document %d: %v", documentID, err)
+					suggestedAmount, suggestedCurrency = 0, ""
+				}
+			}
+
+			// Invoices are processed by the invoice pipeline in addition to the requested suggestions
+			if isInvoiceDocument(doc) {
+				suggestedInvoiceFields, err = app.getSuggestedInvoiceFields(docCtx, content, suggestedTitle, allCustomFields, docLogger)
 				if err != nil {
 					mu.Lock()
 					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
 					mu.Unlock()
-					log.Errorf("Error generating createdDate for document %d: %v", documentID, err)
+					docLogger.Errorf("Error extracting invoice data for document %d: %v", documentID, err)
+					return
+				}
+			}
+
+			// Contracts and subscriptions are processed by the contract extraction
+			if isContractDocument(doc) {
+				suggestedContractTerms, err = app.getSuggestedContractTerms(docCtx, content, suggestedTitle, docLogger)
+				if err != nil {
+					mu.Lock()
+					errorsList = append(errorsList, fmt.Errorf("Document %d: %v", documentID, err))
+					mu.Unlock()
+					docLogger.Errorf("Error extracting contract terms for document %d: %v", documentID, err)
 					return
 				}
 			}
@@ -390,9 +589,20 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 				suggestion.SuggestedTags = doc.Tags
 			}
 
+			// New tags are only created in paperless-ngx once they are approved
+			if len(suggestedNewTags) > 0 {
+				docLogger.Printf("Proposed new tags for document %d: %v", documentID, suggestedNewTags)
+				suggestion.SuggestedNewTags = suggestedNewTags
+				if app.Database != nil {
+					if err := InsertPendingTags(app.Database, uint(documentID), suggestedNewTags); err != nil {
+						docLogger.Errorf("Error storing proposed tags for document %d: %v", documentID, err)
+					}
+				}
+			}
+
 			// Correspondents
 			if suggestionRequest.GenerateCorrespondents {
-				log.Printf("Suggested correspondent for document %d: %s", documentID, suggestedCorrespondent)
+				docLogger.Printf("Suggested correspondent for document %d: %s", documentID, suggestedCorrespondent)
 				suggestion.SuggestedCorrespondent = suggestedCorrespondent
 			} else {
 				suggestion.SuggestedCorrespondent = ""
@@ -400,16 +610,149 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 
 			// CreatedDate
 			if suggestionRequest.GenerateCreatedDate {
-				log.Printf("Suggested createdDate for document %d: %s", documentID, suggestedCreatedDate)
+				docLogger.Printf("Suggested createdDate for document %d: %s", documentID, suggestedCreatedDate)
 				suggestion.SuggestedCreatedDate = suggestedCreatedDate
 			} else {
 				suggestion.SuggestedCreatedDate = ""
 			}
+
+			// CustomFields
+			if suggestionRequest.GenerateCustomFields && len(suggestedCustomFields) > 0 {
+				docLogger.Printf("Suggested custom fields for document %d: %v", documentID, suggestedCustomFields)
+				suggestion.SuggestedCustomFields = suggestedCustomFields
+			}
+
+			// Language
+			if suggestedLanguage != "" {
+				suggestion.SuggestedLanguage = suggestedLanguage
+				if field, ok := languageField(extractCustomFields, allCustomFields); ok {
+					if value, err := convertCustomFieldValue(field, suggestedLanguage); err == nil {
+						suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{{Field: field.ID, Value: value}})
+					} else {
+						docLogger.Warnf("Cannot store language %s in custom field '%s': %v", suggestedLanguage, field.Name, err)
+					}
+				}
+			}
+
+			// Summary
+			if suggestionRequest.GenerateSummary && suggestedSummary != "" {
+				docLogger.Printf("Suggested summary for document %d: %s", documentID, suggestedSummary)
+				suggestion.SuggestedSummary = suggestedSummary
+				if summaryCustomField != "" {
+					if field, ok := findCustomField(allCustomFields, summaryCustomField); ok {
+						if value, err := convertCustomFieldValue(field, suggestedSummary); err == nil {
+							suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{{Field: field.ID, Value: value}})
+						} else {
+							docLogger.Warnf("Cannot store summary in custom field '%s': %v", field.Name, err)
+						}
+					} else {
+						docLogger.Warnf("Custom field '%s' configured in SUMMARY_CUSTOM_FIELD does not exist", summaryCustomField)
+					}
+				}
+			}
+
+			// DueDate
+			if suggestionRequest.GenerateDueDate && suggestedDueDate != "" {
+				docLogger.Printf("Suggested due date for document %d: %s", documentID, suggestedDueDate)
+				suggestion.SuggestedDueDate = suggestedDueDate
+				if dueDateCustomField != "" {
+					if field, ok := findCustomField(allCustomFields, dueDateCustomField); ok {
+						if value, err := convertCustomFieldValue(field, suggestedDueDate); err == nil {
+							suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{{Field: field.ID, Value: value}})
+						} else {
+							docLogger.Warnf("Cannot store due date in custom field '%s': %v", field.Name, err)
+						}
+					} else {
+						docLogger.Warnf("Custom field '%s' configured in DUE_DATE_CUSTOM_FIELD does not exist", dueDateCustomField)
+					}
+				}
+			}
+
+			// Amount
+			if suggestedAmount != 0 {
+				docLogger.Printf("Suggested amount for document %d: %.2f %s", documentID, suggestedAmount, suggestedCurrency)
+				if field, ok := findCustomField(allCustomFields, amountCustomField); ok {
+					if value, err := monetaryValue(field, suggestedAmount, suggestedCurrency); err == nil {
+						suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{{Field: field.ID, Value: value}})
+					} else {
+						docLogger.Warnf("Cannot store amount in custom field '%s': %v", field.Name, err)
+					}
+				} else {
+					docLogger.Warnf("Custom field '%s' configured in AMOUNT_CUSTOM_FIELD does not exist", amountCustomField)
+				}
+			}
+
+			// Invoice
+			if len(suggestedInvoiceFields) > 0 {
+				docLogger.Printf("Suggested invoice fields for document %d: %v", documentID, suggestedInvoiceFields)
+				suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, suggestedInvoiceFields)
+			}
+
+			// Contract
+			if suggestedContractTerms.EndDate != "" {
+				docLogger.Printf("Suggested contract terms for document %d: %+v", documentID, suggestedContractTerms)
+				suggestion.SuggestedCancellationDeadline = suggestedContractTerms.CancellationDeadline
+				suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, contractCustomFieldValues(suggestedContractTerms, allCustomFields, docLogger))
+			}
+
+			// PII
+			if len(piiFindings) > 0 {
+				docLogger.Printf("Personal data found in document %d: %v", documentID, piiFindings)
+				suggestion.SuggestedPIICategories = piiCategoryNames(piiFindings)
+			}
+
+			// Translation
+			if suggestedTranslation != "" {
+				docLogger.Printf("Translated document %d from %s to %s", documentID, suggestedLanguage, translationLanguage)
+				suggestion.SuggestedTranslation = suggestedTranslation
+			}
+
+			// Triage
+			if suggestedTriage.Action != "" {
+				docLogger.Printf("Document %d requires action: %s (deadline: %s)", documentID, suggestedTriage.Action, suggestedTriage.Deadline)
+				suggestion.SuggestedAction = suggestedTriage.Action
+				suggestion.SuggestedActionDeadline = suggestedTriage.Deadline
+				if suggestedTriage.Deadline != "" && actionDeadlineCustomField != "" {
+					if field, ok := findCustomField(allCustomFields, actionDeadlineCustomField); ok {
+						if value, err := convertCustomFieldValue(field, suggestedTriage.Deadline); err == nil {
+							suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{{Field: field.ID, Value: value}})
+						} else {
+							docLogger.Warnf("Cannot store action deadline in custom field '%s': %v", field.Name, err)
+						}
+					} else {
+						docLogger.Warnf("Custom field '%s' configured in ACTION_DEADLINE_CUSTOM_FIELD does not exist", actionDeadlineCustomField)
+					}
+				}
+			}
+
+			// Related documents
+			if len(suggestedRelatedDocuments) > 0 {
+				docLogger.Printf("Related documents for document %d: %v", documentID, suggestedRelatedDocuments)
+				suggestion.SuggestedRelatedDocuments = suggestedRelatedDocuments
+				if relatedDocumentsCustomField != "" {
+					if field, ok := findCustomField(allCustomFields, relatedDocumentsCustomField); !ok {
+						docLogger.Warnf("Custom field '%s' configured in RELATED_DOCUMENTS_CUSTOM_FIELD does not exist", relatedDocumentsCustomField)
+					} else if field.DataType != "documentlink" {
+						docLogger.Warnf("Custom field '%s' configured in RELATED_DOCUMENTS_CUSTOM_FIELD is not a document link field", field.Name)
+					} else {
+						value := relatedDocumentsFieldValue(field, doc.CustomFields, suggestedRelatedDocuments)
+						suggestion.SuggestedCustomFields = mergeCustomFields(suggestion.SuggestedCustomFields, []CustomFieldValue{value})
+					}
+				}
+			}
+
+			// Sensitivity
+			if suggestedSensitivity != "" {
+				docLogger.Printf("Suggested sensitivity level for document %d: %s", documentID, suggestedSensitivity)
+				suggestion.SuggestedSensitivity = suggestedSensitivity
+			}
+
 			// Remove manual tag from the list of suggested tags
-			suggestion.RemoveTags = []string{manualTag, autoTag}
+			suggestion.RemoveTags = []string{settings.ManualTag, settings.AutoTag}
 
 			documentSuggestions = append(documentSuggestions, suggestion)
 			mu.Unlock()
+			events.publish(EventSuggestionsGenerated, map[string]any{"document_id": documentID, "suggested_title": suggestion.SuggestedTitle})
 			docLogger.Printf("Document %d processed successfully.", documentID)
 		}(documents[i])
 	}
@@ -423,6 +766,21 @@ func (app *App) generateDocumentSuggestions(ctx context.Context, suggestionReque
 	return documentSuggestions, nil
 }
 
+// isValidNewTag filters out answers of the LLM that are unlikely to be meaningful tag names
+func isValidNewTag(tag string) bool {
+	return tag != "" && len(tag) <= 128 && !strings.ContainsAny(tag, "\n\"{}")
+}
+
+// isAllowedTag reports whether a tag may be suggested: it isn't in TAG_BLACK_LIST and, if TAG_WHITE_LIST is set, in
+// TAG_WHITE_LIST
+func (settings *runtimeSettings) isAllowedTag(tag string) bool {
+	matches := func(listed string) bool { return strings.EqualFold(listed, tag) }
+	if slices.ContainsFunc(settings.TagBlackList, matches) {
+		return false
+	}
+	return len(settings.TagWhiteList) == 0 || slices.ContainsFunc(settings.TagWhiteList, matches)
+}
+
 // getTodayDate returns the current date in YYYY-MM-DD format
 func getTodayDate() string {
 	return time.Now().Format("2006-01-02")
@@ -443,3 +801,24 @@ func stripReasoning(content string) string {
 	content = strings.TrimSpace(content)
 	return content
 }
+
+// extractJSONObject returns the outermost JSON object contained in the content.
+// LLMs tend to wrap JSON answers in markdown code blocks or add explanations around them.
+func extractJSONObject(content string) (string, error) {
+	start := strings.Index(content, "{")
+	end := strings.LastIndex(content, "}")
+	if start == -1 || end < start {
+		return "", fmt.Errorf("no JSON object found in response: %s", content)
+	}
+	return content[start : end+1], nil
+}
+
+// extractJSONArray returns the outermost JSON array contained in the content
+func extractJSONArray(content string) (string, error) {
+	start := strings.Index(content, "[")
+	end := strings.LastIndex(content, "]")
+	if start == -1 || end < start {
+		return "", fmt.Errorf("no JSON array found in response: %s", content)
+	}
+	return content[start : end+1], nil
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// currencySymbols maps common currency symbols to their ISO 4217 codes
+var currencySymbols = map[string]string{
+	"€":  "EUR",
+	"$":  "USD",
+	"£":  "GBP",
+	"¥":  "JPY",
+	"₹":  "INR",
+	"zł": "PLN",
+	"Kč": "CZK",
+	"Fr": "CHF",
+}
+
+// amountCleanupPattern matches everything that is not part of a number
+var amountCleanupPattern = regexp.MustCompile(`[^0-9.,\-]`)
+
+// getSuggestedAmount extracts the total amount and currency of a document using the LLM.
+// A zero amount and empty currency are returned if the document has no amount.
+func (app *App) getSuggestedAmount(ctx context.Context, content string, suggestedTitle string, logger *logrus.Entry) (float64, string, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "amount", amountTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return 0, "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return 0, "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return 0, "", fmt.Errorf("error executing amount template: %v", err)
+	}
+
+	logger.Debugf("Amount suggestion prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "amount"), prompt.messages())
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseAmountResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)))
+}
+
+// parseAmountResponse parses the JSON answer of the LLM into a normalized amount and currency code
+func parseAmountResponse(response string) (float64, string, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var answer struct {
+		Amount   interface{} `json:"amount"`
+		Currency string      `json:"currency"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject), &answer); err != nil {
+		return 0, "", fmt.Errorf("error parsing amount response: %v", err)
+	}
+	if answer.Amount == nil {
+		return 0, "", nil
+	}
+
+	var amount float64
+	switch value := answer.Amount.(type) {
+	case float64:
+		amount = value
+	default:
+		amount, err = normalizeAmount(fmt.Sprintf("%v", value))
+		if err != nil {
+			return 0, "", err
+		}
+	}
+
+	// The currency code takes precedence over a symbol in the amount
+	currency := strings.ToUpper(strings.TrimSpace(answer.Currency))
+	if !currencyPattern.MatchString(currency) {
+		currency = detectCurrency(fmt.Sprintf("%v", answer.Amount))
+	}
+
+	return amount, currency, nil
+}
+
+// normalizeAmount parses an amount written in a locale-specific format, e.g. "1.234,56 €", "$1,234.56" or "CHF 1'234.50"
+func normalizeAmount(text string) (float64, error) {
+	trimmed := strings.TrimSpace(text)
+	negative := strings.HasPrefix(trimmed, "(") && strings.HasSuffix(trimmed, ")")
+
+	number := amountCleanupPattern.ReplaceAllString(trimmed, "")
+	if strings.HasSuffix(number, "-") {
+		// Trailing minus sign as used in some accounting documents
+		number = "-" + strings.TrimSuffix(number, "-")
+	}
+	if strings.HasPrefix(number, "-") {
+		negative = true
+		number = strings.TrimPrefix(number, "-")
+	}
+	number = strings.Trim(number, ".,")
+	if number == "" {
+		return 0, fmt.Errorf("no amount found in %q", text)
+	}
+
+	lastDot := strings.LastIndex(number, ".")
+	lastComma := strings.LastIndex(number, ",")
+	switch {
+	case lastDot != -1 && lastComma != -1:
+		// Both separators are used: the last one is the decimal separator
+		if lastComma > lastDot {
+			number = strings.ReplaceAll(number, ".", "")
+			number = strings.Replace(number, ",", ".", 1)
+		} else {
+			number = strings.ReplaceAll(number, ",", "")
+		}
+	case lastComma != -1:
+		number = normalizeSingleSeparator(number, ",")
+	case lastDot != -1:
+		number = normalizeSingleSeparator(number, ".")
+	}
+
+	amount, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", text)
+	}
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// normalizeSingleSeparator decides whether a separator is a thousands or a decimal separator
+// when it is the only kind of separator in the number
+func normalizeSingleSeparator(number, separator string) string {
+	parts := strings.Split(number, separator)
+	last := parts[len(parts)-1]
+	if len(parts) > 2 || (len(last) == 3 && parts[0] != "0") {
+		// "1.234.567" or "1,234": thousands separator
+		return strings.Join(parts, "")
+	}
+	return strings.Join(parts, ".")
+}
+
+// detectCurrency returns the ISO 4217 code of the currency symbol or code contained in the text
+func detectCurrency(text string) string {
+	for _, word := range strings.FieldsFunc(strings.ToUpper(text), func(r rune) bool {
+		return r < 'A' || r > 'Z'
+	}) {
+		if currencyPattern.MatchString(word) {
+			return word
+		}
+	}
+	for symbol, code := range currencySymbols {
+		if strings.Contains(text, symbol) {
+			return code
+		}
+	}
+	return ""
+}
+
+// monetaryValue formats an amount for the given custom field.
+// Monetary fields get the currency prefix paperless-ngx expects, falling back to the field's default currency.
+func monetaryValue(field CustomField, amount float64, currency string) (interface{}, error) {
+	if field.DataType != "monetary" {
+		return convertCustomFieldValue(field, amount)
+	}
+	if currency == "" {
+		currency = field.ExtraData.DefaultCurrency
+	}
+	return fmt.Sprintf("%s%.2f", currency, amount), nil
+}
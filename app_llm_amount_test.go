@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAmount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.234,56 €", 1234.56},
+		{"$1,234.56", 1234.56},
+		{"1 234,56", 1234.56},
+		{"CHF 1'234.50", 1234.50},
+		{"1.234.567", 1234567},
+		{"1,234", 1234},
+		{"12,5", 12.5},
+		{"0,125", 0.125},
+		{"99.99", 99.99},
+		{"42", 42},
+		{"-15,00 EUR", -15},
+		{"15,00-", -15},
+		{"(20.00)", -20},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			amount, err := normalizeAmount(tc.input)
+			require.NoError(t, err)
+			assert.InDelta(t, tc.expected, amount, 0.0001)
+		})
+	}
+
+	_, err := normalizeAmount("n/a")
+	assert.Error(t, err)
+}
+
+func TestParseAmountResponse(t *testing.T) {
+	amount, currency, err := parseAmountResponse(`{"amount": "1.234,56 €", "currency": null}`)
+	require.NoError(t, err)
+	assert.InDelta(t, 1234.56, amount, 0.0001)
+	assert.Equal(t, "EUR", currency)
+
+	amount, currency, err = parseAmountResponse("```json\n{\"amount\": 19.99, \"currency\": \"usd\"}\n```")
+	require.NoError(t, err)
+	assert.InDelta(t, 19.99, amount, 0.0001)
+	assert.Equal(t, "USD", currency)
+
+	amount, currency, err = parseAmountResponse(`{"amount": null, "currency": null}`)
+	require.NoError(t, err)
+	assert.Zero(t, amount)
+	assert.Empty(t, currency)
+
+	_, _, err = parseAmountResponse(`{"amount": "unknown"}`)
+	assert.Error(t, err)
+}
+
+func TestMonetaryValue(t *testing.T) {
+	monetary := CustomField{ID: 1, Name: "Total", DataType: "monetary", ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}}
+
+	value, err := monetaryValue(monetary, 1234.5, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD1234.50", value)
+
+	value, err = monetaryValue(monetary, 1234.5, "")
+	require.NoError(t, err)
+	assert.Equal(t, "EUR1234.50", value)
+
+	value, err = monetaryValue(CustomField{ID: 2, Name: "Total", DataType: "float"}, 1234.5, "USD")
+	require.NoError(t, err)
+	assert.Equal(t, 1234.5, value)
+}
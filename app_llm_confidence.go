@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ratedField is a suggested field the LLM rates its confidence in
+type ratedField struct {
+	Field string // Key of the field in the answer, e.g. title
+	Value string
+}
+
+// ratedFields returns the suggested fields rated with AUTO_APPLY_CONFIDENCE. Tags already on the document aren't
+// rated, other suggestions like custom fields and summaries are applied or reviewed along with the rated ones.
+func ratedFields(suggestion DocumentSuggestion) []ratedField {
+	var fields []ratedField
+	if suggestion.SuggestedTitle != "" {
+		fields = append(fields, ratedField{"title", suggestion.SuggestedTitle})
+	}
+	tags := slices.DeleteFunc(slices.Clone(suggestion.SuggestedTags), func(tag string) bool {
+		return slices.Contains(suggestion.OriginalDocument.Tags, tag)
+	})
+	tags = append(tags, suggestion.SuggestedNewTags...)
+	if len(tags) > 0 {
+		fields = append(fields, ratedField{"tags", strings.Join(tags, ", ")})
+	}
+	if suggestion.SuggestedCorrespondent != "" {
+		fields = append(fields, ratedField{"correspondent", suggestion.SuggestedCorrespondent})
+	}
+	if suggestion.SuggestedCreatedDate != "" {
+		fields = append(fields, ratedField{"created_date", suggestion.SuggestedCreatedDate})
+	}
+	return fields
+}
+
+// getSuggestionConfidence asks the LLM how confident it is in each rated field of the suggestion, from 0 to 1
+func (app *App) getSuggestionConfidence(ctx context.Context, suggestion DocumentSuggestion, logger *logrus.Entry) (map[string]float64, error) {
+	fields := ratedFields(suggestion)
+	if len(fields) == 0 {
+		return map[string]float64{}, nil
+	}
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language":    likelyLanguage,
+		"Title":       suggestion.OriginalDocument.Title,
+		"Suggestions": fields,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "confidence", confidenceTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return nil, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	content := suggestion.SuggestedContent
+	if content == "" {
+		content = suggestion.OriginalDocument.Content
+	}
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return nil, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return nil, fmt.Errorf("error executing confidence template: %v", err)
+	}
+
+	logger.Debugf("Confidence prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "confidence"), prompt.messages())
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseConfidenceResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)), fields)
+}
+
+// parseConfidenceResponse parses the JSON answer of the LLM. Fields the LLM didn't rate get a confidence of 0, and
+// percentages are accepted as well.
+func parseConfidenceResponse(response string, fields []ratedField) (map[string]float64, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer map[string]float64
+	if err := json.Unmarshal([]byte(jsonObject), &answer); err != nil {
+		return nil, fmt.Errorf("error parsing confidence response: %v", err)
+	}
+
+	confidence := make(map[string]float64, len(fields))
+	for _, field := range fields {
+		value := answer[field.Field]
+		if value > 1 {
+			value /= 100
+		}
+		confidence[field.Field] = min(max(value, 0), 1)
+	}
+	return confidence, nil
+}
+
+// needsReview rates the suggestions with AUTO_APPLY_CONFIDENCE, storing the confidence in them, and reports whether
+// a field was rated below the threshold. Suggestions that couldn't be rated need review too.
+func (app *App) needsReview(ctx context.Context, suggestions []DocumentSuggestion, logger *logrus.Entry) bool {
+	autoApplyConfidence := settingsFor(ctx).AutoApplyConfidence
+	if autoApplyConfidence <= 0 {
+		return false
+	}
+	review := false
+	for i := range suggestions {
+		confidence, err := app.getSuggestionConfidence(ctx, suggestions[i], logger)
+		if err != nil {
+			logger.Warnf("Queueing suggestions of document %d for review, rating them failed: %v", suggestions[i].ID, err)
+			review = true
+			continue
+		}
+		suggestions[i].Confidence = confidence
+		for _, field := range ratedFields(suggestions[i]) {
+			if confidence[field.Field] < autoApplyConfidence {
+				logger.Infof("Queueing suggestions of document %d for review, the confidence in the %s is %.2f", suggestions[i].ID, field.Field, confidence[field.Field])
+				review = true
+				break
+			}
+		}
+	}
+	return review
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRatedFields(t *testing.T) {
+	suggestion := DocumentSuggestion{
+		OriginalDocument:     Document{Tags: []string{"inbox"}},
+		SuggestedTitle:       "Invoice ACME",
+		SuggestedTags:        []string{"inbox", "invoice"},
+		SuggestedNewTags:     []string{"Travel"},
+		SuggestedCreatedDate: "2024-05-01",
+		SuggestedSummary:     "An invoice",
+	}
+	assert.Equal(t, []ratedField{{"title", "Invoice ACME"}, {"tags", "invoice, Travel"}, {"created_date", "2024-05-01"}}, ratedFields(suggestion))
+	assert.Empty(t, ratedFields(DocumentSuggestion{SuggestedTags: []string{"inbox"}, OriginalDocument: Document{Tags: []string{"inbox"}}}))
+}
+
+func TestParseConfidenceResponse(t *testing.T) {
+	fields := []ratedField{{"title", "Invoice ACME"}, {"tags", "invoice"}, {"correspondent", "ACME"}}
+
+	confidence, err := parseConfidenceResponse("```json\n{\"title\": 0.9, \"tags\": 85, \"correspondent\": -1, \"summary\": 1}\n```", fields)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]float64{"title": 0.9, "tags": 0.85, "correspondent": 0}, confidence)
+
+	confidence, err = parseConfidenceResponse(`{"title": 0.9}`, fields)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, confidence["tags"], "fields that weren't rated have no confidence")
+
+	_, err = parseConfidenceResponse("I am very confident.", fields)
+	assert.Error(t, err)
+	_, err = parseConfidenceResponse(`{"title": "high"}`, fields)
+	assert.Error(t, err)
+}
+
+func TestNeedsReview(t *testing.T) {
+	var err error
+	confidenceTemplate, err = template.New("confidence").Funcs(sprig.FuncMap()).Parse(defaultConfidenceTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: `{"title": 0.95, "correspondent": 0.6}`}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "test")
+	suggestions := func() []DocumentSuggestion {
+		return []DocumentSuggestion{{ID: 1, OriginalDocument: Document{Title: "scan_001", Content: "ACME invoice"}, SuggestedTitle: "Invoice ACME", SuggestedCorrespondent: "ACME"}}
+	}
+
+	// Without a threshold the suggestions are applied without asking the LLM
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoApplyConfidence = 0 })
+	assert.False(t, app.needsReview(context.Background(), suggestions(), logger))
+	assert.Empty(t, llm.lastPrompt)
+
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoApplyConfidence = 0.8 })
+	rated := suggestions()
+	assert.True(t, app.needsReview(context.Background(), rated, logger))
+	assert.Equal(t, map[string]float64{"title": 0.95, "correspondent": 0.6}, rated[0].Confidence)
+	assert.Contains(t, llm.lastPrompt, "- title: Invoice ACME\n- correspondent: ACME\n")
+
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoApplyConfidence = 0.5 })
+	assert.False(t, app.needsReview(context.Background(), suggestions(), logger))
+
+	// Suggestions that can't be rated are reviewed
+	llm.response = "Looks good to me."
+	assert.True(t, app.needsReview(context.Background(), suggestions(), logger))
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// defaultContractCustomFields maps the contract values to the names of the custom fields they are written to
+var defaultContractCustomFields = map[string]string{
+	"end_date":              "Contract End",
+	"notice_period":         "Notice Period",
+	"cancellation_deadline": "Cancellation Deadline",
+}
+
+// contractFieldKeys lists the contract values in the order they are written
+var contractFieldKeys = []string{"end_date", "notice_period", "cancellation_deadline"}
+
+// periodPattern matches periods like "3 months", "6 weeks" or "30 days"
+var periodPattern = regexp.MustCompile(`(?i)^(\d+)\s*(day|week|month|year)s?$`)
+
+// contractTerms holds the term and cancellation conditions of a contract
+type contractTerms struct {
+	EndDate              string
+	NoticePeriod         string
+	CancellationDeadline string
+}
+
+// isContractDocument reports whether the contract extraction should run for the document,
+// based on the configured CONTRACT_TAG and CONTRACT_DOCUMENT_TYPE
+func isContractDocument(doc Document) bool {
+	return documentMatches(doc, contractTag, contractDocumentType)
+}
+
+// getSuggestedContractTerms extracts the end date and notice period of a contract using the LLM
+func (app *App) getSuggestedContractTerms(ctx context.Context, content string, suggestedTitle string, logger *logrus.Entry) (contractTerms, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+		"Today":    getTodayDate(), // must be in YYYY-MM-DD format
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "contract", contractTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return contractTerms{}, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return contractTerms{}, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return contractTerms{}, fmt.Errorf("error executing contract template: %v", err)
+	}
+
+	logger.Debugf("Contract extraction prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "contract"), prompt.messages())
+	if err != nil {
+		return contractTerms{}, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseContractResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)), time.Now(), logger)
+}
+
+// parseContractResponse parses the JSON answer of the LLM and calculates the cancellation deadline.
+// Contracts that renew automatically are rolled forward to their current term.
+func parseContractResponse(response string, now time.Time, logger *logrus.Entry) (contractTerms, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return contractTerms{}, err
+	}
+
+	var answer struct {
+		EndDate       *string `json:"end_date"`
+		NoticePeriod  *string `json:"notice_period"`
+		RenewalPeriod *string `json:"renewal_period"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject), &answer); err != nil {
+		return contractTerms{}, fmt.Errorf("error parsing contract response: %v", err)
+	}
+
+	var terms contractTerms
+	if answer.EndDate == nil {
+		return terms, nil
+	}
+	endDate, err := time.Parse("2006-01-02", strings.TrimSpace(*answer.EndDate))
+	if err != nil {
+		return contractTerms{}, fmt.Errorf("invalid end date in response: %s", *answer.EndDate)
+	}
+
+	// Roll automatically renewing contracts forward until the current term
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if answer.RenewalPeriod != nil && endDate.Before(today) {
+		years, months, days, err := parsePeriod(*answer.RenewalPeriod)
+		if err != nil {
+			logger.Warnf("Ignoring renewal period %s: %v", *answer.RenewalPeriod, err)
+		} else {
+			for i := 0; endDate.Before(today) && i < 1000; i++ {
+				endDate = addPeriod(endDate, years, months, days)
+			}
+		}
+	}
+	terms.EndDate = endDate.Format("2006-01-02")
+
+	if answer.NoticePeriod != nil {
+		years, months, days, err := parsePeriod(*answer.NoticePeriod)
+		if err != nil {
+			logger.Warnf("Ignoring notice period %s: %v", *answer.NoticePeriod, err)
+		} else {
+			terms.NoticePeriod = strings.ToLower(strings.TrimSpace(*answer.NoticePeriod))
+			terms.CancellationDeadline = addPeriod(endDate, -years, -months, -days).Format("2006-01-02")
+		}
+	}
+
+	return terms, nil
+}
+
+// parsePeriod parses a period like "3 months" into years, months and days
+func parsePeriod(period string) (int, int, int, error) {
+	matches := periodPattern.FindStringSubmatch(strings.TrimSpace(period))
+	if matches == nil {
+		return 0, 0, 0, fmt.Errorf("invalid period %q", period)
+	}
+	count, err := strconv.Atoi(matches[1])
+	if err != nil || count <= 0 {
+		return 0, 0, 0, fmt.Errorf("invalid period %q", period)
+	}
+
+	switch strings.ToLower(matches[2]) {
+	case "year":
+		return count, 0, 0, nil
+	case "month":
+		return 0, count, 0, nil
+	case "week":
+		return 0, 0, count * 7, nil
+	default:
+		return 0, 0, count, nil
+	}
+}
+
+// addPeriod adds years, months and days to a date. Unlike time.AddDate, month arithmetic is clamped
+// to the end of the month, so 3 months before 2024-12-31 is 2024-09-30 and not 2024-10-01.
+func addPeriod(date time.Time, years, months, days int) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, date.Location()).AddDate(years, months, 0)
+	lastDay := firstOfMonth.AddDate(0, 1, -1).Day()
+	day := min(date.Day(), lastDay)
+	return time.Date(firstOfMonth.Year(), firstOfMonth.Month(), day, 0, 0, 0, 0, date.Location()).AddDate(0, 0, days)
+}
+
+// contractCustomFieldValues maps the contract terms to the custom fields configured in CONTRACT_CUSTOM_FIELDS.
+// Values whose custom field does not exist in paperless-ngx are skipped.
+func contractCustomFieldValues(terms contractTerms, customFields []CustomField, logger *logrus.Entry) []CustomFieldValue {
+	values := map[string]string{
+		"end_date":              terms.EndDate,
+		"notice_period":         terms.NoticePeriod,
+		"cancellation_deadline": terms.CancellationDeadline,
+	}
+
+	result := []CustomFieldValue{}
+	for _, key := range contractFieldKeys {
+		name := contractCustomFields[key]
+		if values[key] == "" || name == "" {
+			continue
+		}
+		field, ok := findCustomField(customFields, name)
+		if !ok {
+			logger.Debugf("Custom field '%s' for contract value %s does not exist, skipping", name, key)
+			continue
+		}
+		value, err := convertCustomFieldValue(field, values[key])
+		if err != nil {
+			logger.Warnf("Cannot store contract value %s in custom field '%s': %v", values[key], field.Name, err)
+			continue
+		}
+		result = append(result, CustomFieldValue{Field: field.ID, Value: value})
+	}
+	return result
+}
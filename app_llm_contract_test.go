@@ -0,0 +1,82 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePeriod(t *testing.T) {
+	years, months, days, err := parsePeriod("3 Months")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 3, 0}, []int{years, months, days})
+
+	years, months, days, err = parsePeriod("6 weeks")
+	require.NoError(t, err)
+	assert.Equal(t, []int{0, 0, 42}, []int{years, months, days})
+
+	years, months, days, err = parsePeriod("1 year")
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0, 0}, []int{years, months, days})
+
+	_, _, _, err = parsePeriod("until end of quarter")
+	assert.Error(t, err)
+}
+
+func TestParseContractResponse(t *testing.T) {
+	logger := logrus.WithField("test", "contract")
+	now := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	t.Run("end date and notice period", func(t *testing.T) {
+		terms, err := parseContractResponse(`{"end_date": "2024-12-31", "notice_period": "3 months", "renewal_period": null}`, now, logger)
+		require.NoError(t, err)
+		assert.Equal(t, contractTerms{EndDate: "2024-12-31", NoticePeriod: "3 months", CancellationDeadline: "2024-09-30"}, terms)
+	})
+
+	t.Run("renewing contract is rolled forward", func(t *testing.T) {
+		terms, err := parseContractResponse(`{"end_date": "2022-06-30", "notice_period": "1 month", "renewal_period": "12 months"}`, now, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "2024-06-30", terms.EndDate)
+		assert.Equal(t, "2024-05-30", terms.CancellationDeadline)
+	})
+
+	t.Run("invalid notice period", func(t *testing.T) {
+		terms, err := parseContractResponse(`{"end_date": "2024-12-31", "notice_period": "unknown"}`, now, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "2024-12-31", terms.EndDate)
+		assert.Empty(t, terms.CancellationDeadline)
+	})
+
+	t.Run("no end date", func(t *testing.T) {
+		terms, err := parseContractResponse(`{"end_date": null, "notice_period": "3 months"}`, now, logger)
+		require.NoError(t, err)
+		assert.Equal(t, contractTerms{}, terms)
+	})
+
+	t.Run("invalid end date", func(t *testing.T) {
+		_, err := parseContractResponse(`{"end_date": "end of year"}`, now, logger)
+		assert.Error(t, err)
+	})
+}
+
+func TestContractCustomFieldValues(t *testing.T) {
+	originalCustomFields := contractCustomFields
+	defer func() { contractCustomFields = originalCustomFields }()
+	contractCustomFields = parseNameMapping("CONTRACT_CUSTOM_FIELDS", "notice_period=", defaultContractCustomFields)
+
+	customFields := []CustomField{
+		{ID: 1, Name: "Contract End", DataType: "date"},
+		{ID: 2, Name: "Notice Period", DataType: "string"},
+		{ID: 3, Name: "Cancellation Deadline", DataType: "date"},
+	}
+	terms := contractTerms{EndDate: "2024-12-31", NoticePeriod: "3 months", CancellationDeadline: "2024-09-30"}
+
+	values := contractCustomFieldValues(terms, customFields, logrus.WithField("test", "contract"))
+	assert.Equal(t, []CustomFieldValue{
+		{Field: 1, Value: "2024-12-31"},
+		{Field: 3, Value: "2024-09-30"},
+	}, values)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/sirupsen/logrus"
+)
+
+// legalSuffixes are ignored when comparing correspondent names
+var legalSuffixes = []string{
+	"ag", "bv", "co", "corp", "corporation", "ev", "gbr", "gmbh", "inc", "kg", "kgaa", "limited",
+	"llc", "ltd", "mbh", "nv", "ohg", "plc", "sa", "sarl", "se", "spa", "ug",
+}
+
+// minCorrespondentTokenLength is the minimum length of a name part for two correspondents to be considered similar.
+// Shorter parts like "de" or "und" would group unrelated correspondents.
+const minCorrespondentTokenLength = 4
+
+// correspondentNameTokens splits a correspondent name into lowercase words without punctuation and legal suffixes
+func correspondentNameTokens(name string) []string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	})
+
+	tokens := []string{}
+	for _, word := range words {
+		word = strings.ReplaceAll(word, ".", "")
+		if word == "" || slices.Contains(legalSuffixes, word) {
+			continue
+		}
+		tokens = append(tokens, word)
+	}
+	return tokens
+}
+
+// findCorrespondentCandidates groups correspondent names that share a significant word or are equal
+// apart from punctuation and legal suffixes. Only groups with at least two names are returned.
+func findCorrespondentCandidates(names []string) [][]string {
+	names = slices.Clone(names)
+	slices.Sort(names)
+
+	// Union-find over the names
+	parent := make([]int, len(names))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(a, b int) {
+		rootA, rootB := find(a), find(b)
+		if rootA != rootB {
+			parent[max(rootA, rootB)] = min(rootA, rootB)
+		}
+	}
+
+	firstByKey := make(map[string]int)
+	for i, name := range names {
+		tokens := correspondentNameTokens(name)
+		keys := []string{"=" + strings.Join(tokens, " ")}
+		for _, token := range tokens {
+			if len([]rune(token)) >= minCorrespondentTokenLength {
+				keys = append(keys, token)
+			}
+		}
+		for _, key := range keys {
+			if first, exists := firstByKey[key]; exists {
+				union(first, i)
+			} else {
+				firstByKey[key] = i
+			}
+		}
+	}
+
+	groupsByRoot := make(map[int][]string)
+	for i, name := range names {
+		root := find(i)
+		groupsByRoot[root] = append(groupsByRoot[root], name)
+	}
+
+	groups := [][]string{}
+	for i := range names {
+		if group, ok := groupsByRoot[i]; ok && len(group) > 1 {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// suggestCorrespondentMerges asks the LLM which of the similar looking correspondents refer to the same person or organization
+func (app *App) suggestCorrespondentMerges(ctx context.Context, logger *logrus.Entry) ([]CorrespondentMergeSuggestion, error) {
+	correspondents, err := app.Client.GetAllCorrespondents(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch correspondents: %v", err)
+	}
+
+	names := make([]string, 0, len(correspondents))
+	for name := range correspondents {
+		names = append(names, name)
+	}
+
+	groups := findCorrespondentCandidates(names)
+	if len(groups) == 0 {
+		return []CorrespondentMergeSuggestion{}, nil
+	}
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	prompt, err := renderPrompt(correspondentDedupeTemplate, map[string]interface{}{
+		"Groups": groups,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error executing correspondent dedupe template: %v", err)
+	}
+
+	logger.Debugf("Correspondent dedupe prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "correspondent_dedupe"), prompt.messages())
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseCorrespondentMerges(prompt.complete(stripReasoning(completion.Choices[0].Content)), groups, correspondents, logger)
+}
+
+// parseCorrespondentMerges parses the JSON answer of the LLM.
+// Merges are only accepted between names of the same candidate group.
+func parseCorrespondentMerges(response string, groups [][]string, correspondents map[string]int, logger *logrus.Entry) ([]CorrespondentMergeSuggestion, error) {
+	jsonArray, err := extractJSONArray(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer []struct {
+		Canonical  string   `json:"canonical"`
+		Duplicates []string `json:"duplicates"`
+	}
+	if err := json.Unmarshal([]byte(jsonArray), &answer); err != nil {
+		return nil, fmt.Errorf("error parsing correspondent dedupe response: %v", err)
+	}
+
+	groupOf := make(map[string]int)
+	for i, group := range groups {
+		for _, name := range group {
+			groupOf[name] = i
+		}
+	}
+
+	suggestions := []CorrespondentMergeSuggestion{}
+	merged := make(map[string]bool)
+	for _, entry := range answer {
+		canonicalGroup, ok := groupOf[entry.Canonical]
+		if !ok || merged[entry.Canonical] {
+			logger.Warnf("Ignoring merge suggestion with unknown canonical correspondent %q", entry.Canonical)
+			continue
+		}
+
+		suggestion := CorrespondentMergeSuggestion{
+			Canonical: CorrespondentRef{ID: correspondents[entry.Canonical], Name: entry.Canonical},
+		}
+		for _, duplicate := range entry.Duplicates {
+			group, ok := groupOf[duplicate]
+			if !ok || group != canonicalGroup || duplicate == entry.Canonical || merged[duplicate] {
+				logger.Warnf("Ignoring duplicate %q of correspondent %q", duplicate, entry.Canonical)
+				continue
+			}
+			merged[duplicate] = true
+			suggestion.Duplicates = append(suggestion.Duplicates, CorrespondentRef{ID: correspondents[duplicate], Name: duplicate})
+		}
+
+		if len(suggestion.Duplicates) > 0 {
+			merged[entry.Canonical] = true
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+
+	return suggestions, nil
+}
+
+// mergeCorrespondents assigns all documents of the duplicates to the canonical correspondent and
+// optionally deletes the duplicates afterwards. It returns the number of re-mapped documents.
+// The request is expected to be validated by the caller.
+func (app *App) mergeCorrespondents(ctx context.Context, request CorrespondentMergeRequest) (int, error) {
+	remapped := 0
+	for _, duplicateID := range request.DuplicateIDs {
+		documentIDs, err := app.Client.GetDocumentIDsByCorrespondent(ctx, duplicateID)
+		if err != nil {
+			return remapped, err
+		}
+
+		if len(documentIDs) > 0 {
+			err = app.Client.SetDocumentsCorrespondent(ctx, documentIDs, request.CanonicalID)
+			if err != nil {
+				return remapped, err
+			}
+			remapped += len(documentIDs)
+			log.Infof("Moved %d documents from correspondent %d to %d", len(documentIDs), duplicateID, request.CanonicalID)
+		}
+
+		if request.DeleteDuplicates {
+			err = app.Client.DeleteCorrespondent(ctx, duplicateID)
+			if err != nil {
+				return remapped, err
+			}
+			log.Infof("Deleted correspondent %d", duplicateID)
+		}
+	}
+
+	return remapped, nil
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCorrespondentNameTokens(t *testing.T) {
+	assert.Equal(t, []string{"telekom", "deutschland"}, correspondentNameTokens("Telekom Deutschland GmbH"))
+	assert.Equal(t, []string{"amazon", "eu"}, correspondentNameTokens("Amazon EU S.a.r.l."))
+	assert.Equal(t, []string{"acme"}, correspondentNameTokens("ACME, Inc."))
+}
+
+func TestFindCorrespondentCandidates(t *testing.T) {
+	groups := findCorrespondentCandidates([]string{
+		"Telekom Deutschland GmbH",
+		"Deutsche Telekom",
+		"ACME Inc.",
+		"Acme",
+		"Stadtwerke München",
+		"Dr. Müller",
+	})
+
+	assert.Equal(t, [][]string{
+		{"ACME Inc.", "Acme"},
+		{"Deutsche Telekom", "Telekom Deutschland GmbH"},
+	}, groups)
+}
+
+func TestParseCorrespondentMerges(t *testing.T) {
+	logger := logrus.WithField("test", "dedupe")
+	groups := [][]string{
+		{"Deutsche Telekom", "Telekom", "Telekom Deutschland GmbH"},
+		{"Acme", "ACME Inc."},
+	}
+	correspondents := map[string]int{
+		"Deutsche Telekom":         1,
+		"Telekom":                  2,
+		"Telekom Deutschland GmbH": 3,
+		"Acme":                     4,
+		"ACME Inc.":                5,
+	}
+
+	response := "```json\n" + `[
+		{"canonical": "Telekom", "duplicates": ["Deutsche Telekom", "Telekom Deutschland GmbH", "Telekom", "Acme"]},
+		{"canonical": "Unknown Corp", "duplicates": ["ACME Inc."]},
+		{"canonical": "Acme", "duplicates": ["ACME Inc."]}
+	]` + "\n```"
+
+	suggestions, err := parseCorrespondentMerges(response, groups, correspondents, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []CorrespondentMergeSuggestion{
+		{
+			Canonical:  CorrespondentRef{ID: 2, Name: "Telekom"},
+			Duplicates: []CorrespondentRef{{ID: 1, Name: "Deutsche Telekom"}, {ID: 3, Name: "Telekom Deutschland GmbH"}},
+		},
+		{
+			Canonical:  CorrespondentRef{ID: 4, Name: "Acme"},
+			Duplicates: []CorrespondentRef{{ID: 5, Name: "ACME Inc."}},
+		},
+	}, suggestions)
+
+	_, err = parseCorrespondentMerges("no duplicates", groups, correspondents, logger)
+	assert.Error(t, err)
+}
+
+func TestMergeCorrespondents(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "2", r.URL.Query().Get("correspondent__id"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count": 2, "all": [10, 11], "results": []}`))
+	})
+
+	env.setMockResponse("/api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "set_correspondent", body["method"])
+		assert.Equal(t, []interface{}{float64(10), float64(11)}, body["documents"])
+		assert.Equal(t, map[string]interface{}{"correspondent": float64(1)}, body["parameters"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	deleted := false
+	env.setMockResponse("/api/correspondents/2/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "DELETE", r.Method)
+		deleted = true
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	app := &App{Client: env.client}
+	remapped, err := app.mergeCorrespondents(context.Background(), CorrespondentMergeRequest{
+		CanonicalID:      1,
+		DuplicateIDs:     []int{2},
+		DeleteDuplicates: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, remapped)
+	assert.True(t, deleted)
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// customFieldDataTypes lists the custom field data types that can be extracted by the LLM.
+// Document links are not supported as they reference other documents.
+var customFieldDataTypes = []string{"string", "longtext", "url", "date", "boolean", "integer", "float", "monetary", "select"}
+
+// monetaryPattern matches the paperless-ngx monetary format, e.g. "EUR123.45" or "123.45"
+var monetaryPattern = regexp.MustCompile(`^([A-Z]{3})?-?\d+(\.\d{1,2})?$`)
+
+// customFieldPrompt is the representation of a custom field exposed to the custom fields template
+type customFieldPrompt struct {
+	Name     string
+	DataType string
+	Options  []string
+}
+
+// extractableCustomFields filters the custom field definitions down to the ones the LLM should populate
+func extractableCustomFields(customFields []CustomField) []CustomField {
+	var fields []CustomField
+	for _, field := range customFields {
+		if !slices.Contains(customFieldDataTypes, field.DataType) {
+			continue
+		}
+		if len(customFieldWhiteList) > 0 && !slices.ContainsFunc(customFieldWhiteList, func(name string) bool {
+			return strings.EqualFold(name, field.Name)
+		}) {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// findCustomField looks up a custom field definition by its name (case-insensitive)
+func findCustomField(customFields []CustomField, name string) (CustomField, bool) {
+	for _, field := range customFields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return CustomField{}, false
+}
+
+// parseNameMapping parses a mapping like "invoice_number=Rechnungsnummer,iban=" from the given
+// environment variable on top of the default names (of custom fields or tags). An empty name disables the entry.
+func parseNameMapping(envName string, mapping string, defaults map[string]string) map[string]string {
+	customFields := maps.Clone(defaults)
+	for _, item := range splitAndTrim(mapping) {
+		key, name, found := strings.Cut(item, "=")
+		key = strings.ToLower(strings.TrimSpace(key))
+		if _, known := defaults[key]; !found || !known {
+			log.Warnf("Ignoring invalid %s entry: %s", envName, item)
+			continue
+		}
+		customFields[key] = strings.TrimSpace(name)
+	}
+	return customFields
+}
+
+// documentMatches reports whether the document has the given tag or document type (case-insensitive).
+// Empty values never match.
+func documentMatches(doc Document, tag string, documentType string) bool {
+	if tag != "" && slices.ContainsFunc(doc.Tags, func(docTag string) bool {
+		return strings.EqualFold(docTag, tag)
+	}) {
+		return true
+	}
+	return documentType != "" && strings.EqualFold(doc.DocumentType, documentType)
+}
+
+// getSuggestedCustomFields extracts values for the given custom fields from the document content using the LLM
+func (app *App) getSuggestedCustomFields(ctx context.Context, content string, suggestedTitle string, customFields []CustomField, logger *logrus.Entry) ([]CustomFieldValue, error) {
+	if len(customFields) == 0 {
+		return nil, nil
+	}
+
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	promptFields := make([]customFieldPrompt, 0, len(customFields))
+	for _, field := range customFields {
+		options := make([]string, 0, len(field.ExtraData.SelectOptions))
+		for _, option := range field.ExtraData.SelectOptions {
+			options = append(options, option.Label)
+		}
+		promptFields = append(promptFields, customFieldPrompt{
+			Name:     field.Name,
+			DataType: field.DataType,
+			Options:  options,
+		})
+	}
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language":     likelyLanguage,
+		"Title":        suggestedTitle,
+		"CustomFields": promptFields,
+		"Today":        getTodayDate(),
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "custom_fields", customFieldsTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return nil, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return nil, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return nil, fmt.Errorf("error executing custom fields template: %v", err)
+	}
+
+	logger.Debugf("Custom fields suggestion prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "custom_fields"), prompt.messages())
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	response := prompt.complete(stripReasoning(completion.Choices[0].Content))
+	return parseCustomFieldsResponse(response, customFields, logger)
+}
+
+// parseCustomFieldsResponse converts the JSON answer of the LLM into typed custom field values.
+// Fields that are missing, null or cannot be converted to the field's data type are skipped.
+func parseCustomFieldsResponse(response string, customFields []CustomField, logger *logrus.Entry) ([]CustomFieldValue, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawValues map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonObject), &rawValues); err != nil {
+		return nil, fmt.Errorf("error parsing custom fields response: %v", err)
+	}
+
+	values := []CustomFieldValue{}
+	for _, field := range customFields {
+		var raw interface{}
+		found := false
+		for name, value := range rawValues {
+			if strings.EqualFold(strings.TrimSpace(name), field.Name) {
+				raw, found = value, true
+				break
+			}
+		}
+		if !found || raw == nil {
+			continue
+		}
+
+		value, err := convertCustomFieldValue(field, raw)
+		if err != nil {
+			logger.Warnf("Ignoring value %v for custom field '%s': %v", raw, field.Name, err)
+			continue
+		}
+		values = append(values, CustomFieldValue{Field: field.ID, Value: value})
+	}
+
+	return values, nil
+}
+
+// convertCustomFieldValue converts a value returned by the LLM into the representation paperless-ngx expects for the field's data type
+func convertCustomFieldValue(field CustomField, raw interface{}) (interface{}, error) {
+	text := strings.TrimSpace(fmt.Sprintf("%v", raw))
+	if text == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	switch field.DataType {
+	case "string":
+		if len(text) > 128 {
+			text = text[:128]
+		}
+		return text, nil
+	case "longtext", "url":
+		return text, nil
+	case "date":
+		for _, layout := range []string{"2006-01-02", time.RFC3339, "02.01.2006", "2006/01/02"} {
+			if date, err := time.Parse(layout, text); err == nil {
+				return date.Format("2006-01-02"), nil
+			}
+		}
+		return nil, fmt.Errorf("invalid date")
+	case "boolean":
+		if value, ok := raw.(bool); ok {
+			return value, nil
+		}
+		value, err := strconv.ParseBool(strings.ToLower(text))
+		if err != nil {
+			return nil, fmt.Errorf("invalid boolean")
+		}
+		return value, nil
+	case "integer":
+		if value, ok := raw.(float64); ok {
+			return int(math.Round(value)), nil
+		}
+		value, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer")
+		}
+		return value, nil
+	case "float":
+		if value, ok := raw.(float64); ok {
+			return value, nil
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float")
+		}
+		return value, nil
+	case "monetary":
+		if value, ok := raw.(float64); ok {
+			return fmt.Sprintf("%s%.2f", field.ExtraData.DefaultCurrency, value), nil
+		}
+		text = strings.ReplaceAll(text, " ", "")
+		if !monetaryPattern.MatchString(text) {
+			return nil, fmt.Errorf("invalid monetary value")
+		}
+		return text, nil
+	case "select":
+		for i, option := range field.ExtraData.SelectOptions {
+			if strings.EqualFold(option.Label, text) {
+				if option.ID != "" {
+					return option.ID, nil
+				}
+				return i, nil
+			}
+		}
+		return nil, fmt.Errorf("unknown select option")
+	default:
+		return nil, fmt.Errorf("unsupported data type %s", field.DataType)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// mockJSONLLM answers every prompt with a fixed response
+type mockJSONLLM struct {
+	response     string
+	lastPrompt   string // Text of the user message
+	lastMessages []llms.MessageContent
+}
+
+func (m *mockJSONLLM) Call(_ context.Context, prompt string, _ ...llms.CallOption) (string, error) {
+	m.lastPrompt = prompt
+	return m.response, nil
+}
+
+func (m *mockJSONLLM) GenerateContent(_ context.Context, messages []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.lastMessages = messages
+	for _, message := range messages {
+		if message.Role == llms.ChatMessageTypeHuman {
+			m.lastPrompt = message.Parts[0].(llms.TextContent).Text
+		}
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content: m.response,
+			},
+		},
+	}, nil
+}
+
+func TestParseCustomFieldsResponse(t *testing.T) {
+	testLogger := logrus.WithField("test", "test")
+
+	customFields := []CustomField{
+		{ID: 1, Name: "Invoice Number", DataType: "string"},
+		{ID: 2, Name: "Due Date", DataType: "date"},
+		{ID: 3, Name: "Amount", DataType: "monetary", ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}},
+		{ID: 4, Name: "Paid", DataType: "boolean"},
+		{ID: 5, Name: "Pages", DataType: "integer"},
+		{ID: 6, Name: "Category", DataType: "select", ExtraData: CustomFieldExtraData{
+			SelectOptions: []CustomFieldSelectOption{{Label: "Private"}, {Label: "Business"}},
+		}},
+		{ID: 7, Name: "Status", DataType: "select", ExtraData: CustomFieldExtraData{
+			SelectOptions: []CustomFieldSelectOption{{ID: "x1", Label: "Open"}},
+		}},
+		{ID: 8, Name: "Policy", DataType: "string"},
+	}
+
+	response := "```json\n" + `{
+		"invoice number": "RE-2024-001",
+		"Due Date": "31.01.2024",
+		"Amount": 123.4,
+		"Paid": "false",
+		"Pages": 3,
+		"Category": "business",
+		"Status": "Open",
+		"Policy": null
+	}` + "\n```"
+
+	values, err := parseCustomFieldsResponse(response, customFields, testLogger)
+	require.NoError(t, err)
+
+	expected := []CustomFieldValue{
+		{Field: 1, Value: "RE-2024-001"},
+		{Field: 2, Value: "2024-01-31"},
+		{Field: 3, Value: "EUR123.40"},
+		{Field: 4, Value: false},
+		{Field: 5, Value: 3},
+		{Field: 6, Value: 1},
+		{Field: 7, Value: "x1"},
+	}
+	assert.Equal(t, expected, values)
+}
+
+func TestParseCustomFieldsResponse_InvalidValues(t *testing.T) {
+	testLogger := logrus.WithField("test", "test")
+
+	customFields := []CustomField{
+		{ID: 1, Name: "Due Date", DataType: "date"},
+		{ID: 2, Name: "Amount", DataType: "monetary"},
+		{ID: 3, Name: "Category", DataType: "select", ExtraData: CustomFieldExtraData{
+			SelectOptions: []CustomFieldSelectOption{{Label: "Private"}},
+		}},
+	}
+
+	values, err := parseCustomFieldsResponse(`{"Due Date": "soon", "Amount": "a lot", "Category": "Unknown"}`, customFields, testLogger)
+	require.NoError(t, err)
+	assert.Empty(t, values)
+
+	_, err = parseCustomFieldsResponse("I could not find anything", customFields, testLogger)
+	assert.Error(t, err)
+}
+
+func TestGetSuggestedCustomFields(t *testing.T) {
+	testLogger := logrus.WithField("test", "test")
+
+	var err error
+	customFieldsTemplate, err = template.New("custom_fields").Funcs(sprig.FuncMap()).Parse(defaultCustomFieldsTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: `<think>Looking for the number</think>{"Invoice Number": "42"}`}
+	app := &App{LLM: llm}
+
+	customFields := []CustomField{
+		{ID: 1, Name: "Invoice Number", DataType: "string"},
+		{ID: 2, Name: "Category", DataType: "select", ExtraData: CustomFieldExtraData{
+			SelectOptions: []CustomFieldSelectOption{{Label: "Private"}, {Label: "Business"}},
+		}},
+	}
+
+	values, err := app.getSuggestedCustomFields(context.Background(), "Invoice 42", "Invoice", customFields, testLogger)
+	require.NoError(t, err)
+	assert.Equal(t, []CustomFieldValue{{Field: 1, Value: "42"}}, values)
+	assert.Contains(t, llm.lastPrompt, `"Category" (type: select, one of: Private, Business)`)
+}
+
+func TestExtractableCustomFields(t *testing.T) {
+	customFields := []CustomField{
+		{ID: 1, Name: "Invoice Number", DataType: "string"},
+		{ID: 2, Name: "Related", DataType: "documentlink"},
+		{ID: 3, Name: "Amount", DataType: "monetary"},
+	}
+
+	assert.Equal(t, []CustomField{customFields[0], customFields[2]}, extractableCustomFields(customFields))
+
+	originalWhiteList := customFieldWhiteList
+	defer func() { customFieldWhiteList = originalWhiteList }()
+	customFieldWhiteList = []string{"amount"}
+	assert.Equal(t, []CustomField{customFields[2]}, extractableCustomFields(customFields))
+}
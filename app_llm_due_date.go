@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dueDateMaxYearsAhead limits how far in the future a suggested due date may be.
+// Dates further ahead are most likely misread years or contract end dates.
+const dueDateMaxYearsAhead = 10
+
+// getSuggestedDueDate extracts the payment due date or deadline of a document using the LLM.
+// An empty string is returned if the document has no due date.
+func (app *App) getSuggestedDueDate(ctx context.Context, content string, suggestedTitle string, createdDate string, logger *logrus.Entry) (string, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+		"Today":    getTodayDate(), // must be in YYYY-MM-DD format
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "due_date", dueDateTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing due date template: %v", err)
+	}
+
+	logger.Debugf("Due date suggestion prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "due_date"), prompt.messages())
+	if err != nil {
+		return "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseDueDate(prompt.complete(stripReasoning(completion.Choices[0].Content)), createdDate, time.Now())
+}
+
+// parseDueDate validates the due date answered by the LLM against the document date and today.
+// Due dates before the document was created or implausibly far in the future are rejected.
+func parseDueDate(response string, createdDate string, now time.Time) (string, error) {
+	answer := strings.Trim(strings.TrimSpace(response), "\"'`.")
+	if answer == "" || strings.EqualFold(answer, "none") {
+		return "", nil
+	}
+
+	dueDate, err := time.Parse("2006-01-02", answer)
+	if err != nil {
+		return "", fmt.Errorf("invalid due date in response: %s", response)
+	}
+
+	if created, err := time.Parse("2006-01-02", createdDate); err == nil && dueDate.Before(created) {
+		return "", fmt.Errorf("due date %s is before the document date %s", answer, createdDate)
+	}
+
+	if dueDate.After(now.AddDate(dueDateMaxYearsAhead, 0, 0)) {
+		return "", fmt.Errorf("due date %s is more than %d years in the future", answer, dueDateMaxYearsAhead)
+	}
+
+	return dueDate.Format("2006-01-02"), nil
+}
+
+// isDueSoon reports whether the due date lies between today and DUE_SOON_DAYS days from now.
+// Due dates in the past are not considered due soon.
+func isDueSoon(dueDate string, now time.Time) bool {
+	return isWithinDays(dueDate, now, dueSoonDays)
+}
+
+// isWithinDays reports whether the date (YYYY-MM-DD) lies between today and the given number of days from now
+func isWithinDays(date string, now time.Time, days int) bool {
+	parsed, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return false
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return !parsed.Before(today) && !parsed.After(today.AddDate(0, 0, days))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDueDate(t *testing.T) {
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		response    string
+		createdDate string
+		expected    string
+		expectError bool
+	}{
+		{name: "valid date", response: "2024-03-24", createdDate: "2024-03-01", expected: "2024-03-24"},
+		{name: "quoted date", response: "\"2024-03-24\".", createdDate: "", expected: "2024-03-24"},
+		{name: "past due date", response: "2023-12-31", createdDate: "2023-12-01", expected: "2023-12-31"},
+		{name: "no due date", response: "none", expected: ""},
+		{name: "no due date uppercase", response: "None", expected: ""},
+		{name: "invalid format", response: "24.03.2024", expectError: true},
+		{name: "before document date", response: "2024-02-01", createdDate: "2024-03-01", expectError: true},
+		{name: "too far in the future", response: "2040-01-01", createdDate: "2024-03-01", expectError: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dueDate, err := parseDueDate(tc.response, tc.createdDate, now)
+			if tc.expectError {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, dueDate)
+		})
+	}
+}
+
+func TestIsDueSoon(t *testing.T) {
+	originalDays := dueSoonDays
+	defer func() { dueSoonDays = originalDays }()
+	dueSoonDays = 7
+
+	now := time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC)
+
+	assert.True(t, isDueSoon("2024-03-10", now))
+	assert.True(t, isDueSoon("2024-03-17", now))
+	assert.False(t, isDueSoon("2024-03-18", now))
+	assert.False(t, isDueSoon("2024-03-09", now))
+	assert.False(t, isDueSoon("", now))
+}
@@ -38,22 +38,26 @@ func NewGoogleAIProvider(ctx context.Context, model string, apiKey string, think
 
 // GenerateText sends a text generation request to Gemini API
 func (p *GoogleAIProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return p.generate(ctx, genai.Text(prompt), nil)
+}
+
+// generate sends the contents to Gemini API with an optional system instruction
+func (p *GoogleAIProvider) generate(ctx context.Context, contents []*genai.Content, systemInstruction *genai.Content) (string, error) {
 	if p.client == nil {
 		return "", fmt.Errorf("googleai client not initialized")
 	}
 
 	// Prepare generation config with thinking budget if set
 	var genConfig *genai.GenerateContentConfig
+	if p.thinkingBudget != nil || systemInstruction != nil {
+		genConfig = &genai.GenerateContentConfig{SystemInstruction: systemInstruction}
+	}
 	if p.thinkingBudget != nil {
-		genConfig = &genai.GenerateContentConfig{
-			ThinkingConfig: &genai.ThinkingConfig{
-				ThinkingBudget: genai.Ptr(*p.thinkingBudget),
-			},
+		genConfig.ThinkingConfig = &genai.ThinkingConfig{
+			ThinkingBudget: genai.Ptr(*p.thinkingBudget),
 		}
 	}
 
-	contents := genai.Text(prompt)
-
 	resp, err := p.client.Models.GenerateContent(ctx, p.model, contents, genConfig)
 	if err != nil {
 		return "", fmt.Errorf("googleai GenerateContent API error: %w", err)
@@ -74,17 +78,37 @@ func (p *GoogleAIProvider) Close() error {
 
 /*
 GenerateContent implements the llms.Model interface for GoogleAIProvider.
-It adapts a single-message prompt to the Google Gemini API and wraps the result.
+It sends system messages as the system instruction and the other text messages as the user and model turns.
 */
 func (p *GoogleAIProvider) GenerateContent(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (*llms.ContentResponse, error) {
-	if len(messages) == 0 || len(messages[0].Parts) == 0 {
-		return nil, fmt.Errorf("no prompt provided")
+	var systemInstruction *genai.Content
+	var contents []*genai.Content
+	for _, message := range messages {
+		var parts []*genai.Part
+		for _, part := range message.Parts {
+			textPart, ok := part.(llms.TextContent)
+			if !ok {
+				return nil, fmt.Errorf("message part is not TextContent")
+			}
+			parts = append(parts, &genai.Part{Text: textPart.Text})
+		}
+		switch message.Role {
+		case llms.ChatMessageTypeSystem:
+			if systemInstruction == nil {
+				systemInstruction = &genai.Content{Role: genai.RoleUser}
+			}
+			systemInstruction.Parts = append(systemInstruction.Parts, parts...)
+		case llms.ChatMessageTypeAI:
+			contents = append(contents, &genai.Content{Role: genai.RoleModel, Parts: parts})
+		default:
+			contents = append(contents, &genai.Content{Role: genai.RoleUser, Parts: parts})
+		}
 	}
-	textPart, ok := messages[0].Parts[0].(llms.TextContent)
-	if !ok {
-		return nil, fmt.Errorf("first message part is not TextContent")
+	if len(contents) == 0 {
+		return nil, fmt.Errorf("no prompt provided")
 	}
-	result, err := p.GenerateText(ctx, textPart.Text)
+
+	result, err := p.generate(ctx, contents, systemInstruction)
 	if err != nil {
 		return nil, err
 	}
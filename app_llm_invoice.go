@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// invoiceFieldKeys lists the invoice values extracted by the invoice pipeline, in prompt order
+var invoiceFieldKeys = []string{"invoice_number", "net_amount", "gross_amount", "currency", "iban", "vat_id"}
+
+// defaultInvoiceCustomFields maps the invoice values to the names of the custom fields they are written to
+var defaultInvoiceCustomFields = map[string]string{
+	"invoice_number": "Invoice Number",
+	"net_amount":     "Net Amount",
+	"gross_amount":   "Gross Amount",
+	"currency":       "Currency",
+	"iban":           "IBAN",
+	"vat_id":         "VAT ID",
+}
+
+var (
+	currencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+	ibanPattern     = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	vatIDPattern    = regexp.MustCompile(`^[A-Z]{2}[A-Z0-9]{2,13}$`)
+)
+
+// isInvoiceDocument reports whether the invoice pipeline should run for the document,
+// based on the configured INVOICE_TAG and INVOICE_DOCUMENT_TYPE
+func isInvoiceDocument(doc Document) bool {
+	return documentMatches(doc, invoiceTag, invoiceDocumentType)
+}
+
+// getSuggestedInvoiceFields extracts invoice data from the document content and maps it to the configured custom fields
+func (app *App) getSuggestedInvoiceFields(ctx context.Context, content string, suggestedTitle string, customFields []CustomField, logger *logrus.Entry) ([]CustomFieldValue, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "invoice", invoiceTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return nil, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return nil, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return nil, fmt.Errorf("error executing invoice template: %v", err)
+	}
+
+	logger.Debugf("Invoice extraction prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "invoice"), prompt.messages())
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	values, err := parseInvoiceResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)), logger)
+	if err != nil {
+		return nil, err
+	}
+	return invoiceCustomFieldValues(values, customFields, logger), nil
+}
+
+// parseInvoiceResponse parses and validates the JSON answer of the LLM.
+// Invalid values are dropped, so the returned map only contains usable values.
+func parseInvoiceResponse(response string, logger *logrus.Entry) (map[string]interface{}, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var rawValues map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonObject), &rawValues); err != nil {
+		return nil, fmt.Errorf("error parsing invoice response: %v", err)
+	}
+
+	values := make(map[string]interface{})
+	for _, key := range invoiceFieldKeys {
+		raw, ok := rawValues[key]
+		if !ok || raw == nil {
+			continue
+		}
+		value, err := normalizeInvoiceValue(key, raw)
+		if err != nil {
+			logger.Warnf("Ignoring invoice value %v for %s: %v", raw, key, err)
+			continue
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// normalizeInvoiceValue validates a single invoice value and brings it into a canonical form
+func normalizeInvoiceValue(key string, raw interface{}) (interface{}, error) {
+	text := strings.TrimSpace(fmt.Sprintf("%v", raw))
+	if text == "" {
+		return nil, fmt.Errorf("empty value")
+	}
+
+	switch key {
+	case "net_amount", "gross_amount":
+		if amount, ok := raw.(float64); ok {
+			return amount, nil
+		}
+		return normalizeAmount(text)
+	case "currency":
+		currency := strings.ToUpper(text)
+		if !currencyPattern.MatchString(currency) {
+			return nil, fmt.Errorf("invalid currency code")
+		}
+		return currency, nil
+	case "iban":
+		iban := strings.ToUpper(strings.ReplaceAll(text, " ", ""))
+		if !isValidIBAN(iban) {
+			return nil, fmt.Errorf("invalid IBAN")
+		}
+		return iban, nil
+	case "vat_id":
+		vatID := strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "").Replace(text))
+		if !vatIDPattern.MatchString(vatID) {
+			return nil, fmt.Errorf("invalid VAT ID")
+		}
+		return vatID, nil
+	default:
+		return text, nil
+	}
+}
+
+// isValidIBAN checks the format and the ISO 7064 mod 97 checksum of an IBAN
+func isValidIBAN(iban string) bool {
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+
+	// Move the country code and checksum to the end and replace letters by numbers (A=10, ..., Z=35)
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+
+	number, ok := new(big.Int).SetString(digits.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+// invoiceCustomFieldValues maps the extracted invoice values to the custom fields configured in INVOICE_CUSTOM_FIELDS.
+// Values whose custom field does not exist in paperless-ngx are skipped.
+func invoiceCustomFieldValues(values map[string]interface{}, customFields []CustomField, logger *logrus.Entry) []CustomFieldValue {
+	currency, _ := values["currency"].(string)
+
+	result := []CustomFieldValue{}
+	for _, key := range invoiceFieldKeys {
+		raw, ok := values[key]
+		name := invoiceCustomFields[key]
+		if !ok || name == "" {
+			continue
+		}
+		field, ok := findCustomField(customFields, name)
+		if !ok {
+			logger.Debugf("Custom field '%s' for invoice value %s does not exist, skipping", name, key)
+			continue
+		}
+
+		var value interface{}
+		var err error
+		if amount, isAmount := raw.(float64); isAmount {
+			value, err = monetaryValue(field, amount, currency)
+		} else {
+			value, err = convertCustomFieldValue(field, raw)
+		}
+		if err != nil {
+			logger.Warnf("Cannot store invoice value %v in custom field '%s': %v", raw, field.Name, err)
+			continue
+		}
+		result = append(result, CustomFieldValue{Field: field.ID, Value: value})
+	}
+	return result
+}
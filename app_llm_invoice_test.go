@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsValidIBAN(t *testing.T) {
+	assert.True(t, isValidIBAN("DE89370400440532013000"))
+	assert.True(t, isValidIBAN("GB29NWBK60161331926819"))
+	assert.False(t, isValidIBAN("DE89370400440532013001"))
+	assert.False(t, isValidIBAN("DE8937"))
+	assert.False(t, isValidIBAN(""))
+}
+
+func TestParseNameMapping(t *testing.T) {
+	customFields := parseNameMapping("INVOICE_CUSTOM_FIELDS", "invoice_number=Rechnungsnummer, iban=, unknown=Foo", defaultInvoiceCustomFields)
+	assert.Equal(t, "Rechnungsnummer", customFields["invoice_number"])
+	assert.Equal(t, "", customFields["iban"])
+	assert.Equal(t, "Gross Amount", customFields["gross_amount"])
+	assert.NotContains(t, customFields, "unknown")
+}
+
+func TestIsInvoiceDocument(t *testing.T) {
+	originalTag, originalType := invoiceTag, invoiceDocumentType
+	defer func() { invoiceTag, invoiceDocumentType = originalTag, originalType }()
+
+	invoiceTag, invoiceDocumentType = "", ""
+	assert.False(t, isInvoiceDocument(Document{Tags: []string{"invoice"}, DocumentType: "Invoice"}))
+
+	invoiceTag = "invoice"
+	assert.True(t, isInvoiceDocument(Document{Tags: []string{"other", "Invoice"}}))
+	assert.False(t, isInvoiceDocument(Document{Tags: []string{"other"}}))
+
+	invoiceTag, invoiceDocumentType = "", "Invoice"
+	assert.True(t, isInvoiceDocument(Document{DocumentType: "invoice"}))
+	assert.False(t, isInvoiceDocument(Document{DocumentType: "Letter"}))
+}
+
+func TestGetSuggestedInvoiceFields(t *testing.T) {
+	originalCustomFields := invoiceCustomFields
+	defer func() { invoiceCustomFields = originalCustomFields }()
+	invoiceCustomFields = defaultInvoiceCustomFields
+
+	var err error
+	invoiceTemplate, err = template.New("invoice").Funcs(sprig.FuncMap()).Parse(defaultInvoiceTemplate)
+	require.NoError(t, err)
+
+	customFields := []CustomField{
+		{ID: 1, Name: "Invoice Number", DataType: "string"},
+		{ID: 2, Name: "Net Amount", DataType: "monetary", ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}},
+		{ID: 3, Name: "Gross Amount", DataType: "float"},
+		{ID: 4, Name: "IBAN", DataType: "string"},
+		{ID: 5, Name: "VAT ID", DataType: "string"},
+	}
+
+	llm := &mockJSONLLM{response: "```json\n" + `{
+		"invoice_number": "RE-2024-001",
+		"net_amount": 100,
+		"gross_amount": "119.00",
+		"currency": "usd",
+		"iban": "DE89 3704 0044 0532 0130 01",
+		"vat_id": "de 123.456.789"
+	}` + "\n```"}
+	app := &App{LLM: llm}
+
+	values, err := app.getSuggestedInvoiceFields(context.Background(), "content", "Invoice", customFields, logrus.WithField("test", "invoice"))
+	require.NoError(t, err)
+	assert.Equal(t, []CustomFieldValue{
+		{Field: 1, Value: "RE-2024-001"},
+		{Field: 2, Value: "USD100.00"},
+		{Field: 3, Value: 119.0},
+		{Field: 5, Value: "DE123456789"},
+	}, values)
+	assert.Contains(t, llm.lastPrompt, "vat_id")
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// documentLanguageKey is the context key holding the language of the document being processed
+type documentLanguageKey struct{}
+
+// languageCodePattern matches ISO 639-1 (and 639-2) language codes
+var languageCodePattern = regexp.MustCompile(`^[a-z]{2,3}$`)
+
+// languageNames maps ISO 639-1 codes to the English language names used in the prompts
+var languageNames = map[string]string{
+	"ar": "Arabic",
+	"bg": "Bulgarian",
+	"cs": "Czech",
+	"da": "Danish",
+	"de": "German",
+	"el": "Greek",
+	"en": "English",
+	"es": "Spanish",
+	"et": "Estonian",
+	"fi": "Finnish",
+	"fr": "French",
+	"hr": "Croatian",
+	"hu": "Hungarian",
+	"it": "Italian",
+	"ja": "Japanese",
+	"ko": "Korean",
+	"lt": "Lithuanian",
+	"lv": "Latvian",
+	"nl": "Dutch",
+	"no": "Norwegian",
+	"pl": "Polish",
+	"pt": "Portuguese",
+	"ro": "Romanian",
+	"ru": "Russian",
+	"sk": "Slovak",
+	"sl": "Slovenian",
+	"sv": "Swedish",
+	"tr": "Turkish",
+	"uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// documentLanguage is a language of LLM_LANGUAGE
+type documentLanguage struct {
+	Code string // ISO 639-1 code, empty for languages without a known code
+	Name string // English name used in the prompts
+}
+
+// expectedLanguages returns the languages of LLM_LANGUAGE, a comma-separated list of names like German or ISO 639-1
+// codes like de, of the settings of the context's job. The first one is the default language, English if none is set.
+func expectedLanguages(ctx context.Context) []documentLanguage {
+	var languages []documentLanguage
+	for _, value := range splitAndTrim(settingsFor(ctx).LLMLanguage) {
+		languages = append(languages, parseExpectedLanguage(value))
+	}
+	if len(languages) == 0 {
+		return []documentLanguage{{Code: "en", Name: "English"}}
+	}
+	return languages
+}
+
+// parseExpectedLanguage returns the language of a name or code of LLM_LANGUAGE
+func parseExpectedLanguage(value string) documentLanguage {
+	lower := strings.ToLower(value)
+	if name, ok := languageNames[lower]; ok {
+		return documentLanguage{Code: lower, Name: name}
+	}
+	for code, name := range languageNames {
+		if strings.EqualFold(name, value) {
+			return documentLanguage{Code: code, Name: name}
+		}
+	}
+	return documentLanguage{Name: strings.Title(lower)}
+}
+
+// promptLanguageFor returns the language of the prompts for a document in the detected language. With several
+// languages in LLM_LANGUAGE, documents in other languages get the first one, so the answers stay in the expected
+// languages.
+func promptLanguageFor(ctx context.Context, code string) documentLanguage {
+	languages := expectedLanguages(ctx)
+	if len(languages) < 2 {
+		return documentLanguage{Code: code, Name: languageName(code)}
+	}
+	for _, language := range languages {
+		if language.Code == code {
+			return language
+		}
+	}
+	return languages[0]
+}
+
+// withDocumentLanguage returns a context carrying the detected language of a document
+func withDocumentLanguage(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, documentLanguageKey{}, language)
+}
+
+// getDocumentLanguage returns the detected language of the document being processed,
+// falling back to the globally configured language
+func getDocumentLanguage(ctx context.Context) string {
+	if language, ok := ctx.Value(documentLanguageKey{}).(string); ok && language != "" {
+		return language
+	}
+	return getLikelyLanguage(ctx)
+}
+
+// languageName returns the English name for a language code, or the code itself if unknown
+func languageName(code string) string {
+	if name, ok := languageNames[code]; ok {
+		return name
+	}
+	return code
+}
+
+// detectDocumentLanguage asks the LLM for the ISO 639-1 code of the document's language
+func (app *App) detectDocumentLanguage(ctx context.Context, content string, logger *logrus.Entry) (string, error) {
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{}
+
+	addDocumentPromptData(ctx, templateData)
+	// The languages to choose from, if several are expected
+	if languages := expectedLanguages(ctx); len(languages) > 1 {
+		names := make([]string, 0, len(languages))
+		for _, language := range languages {
+			names = append(names, language.Name)
+		}
+		templateData["Languages"] = names
+	}
+	tmpl := promptFor(ctx, "language", languageTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing language template: %v", err)
+	}
+
+	logger.Debugf("Language detection prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "language"), prompt.messages())
+	if err != nil {
+		return "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseLanguageCode(prompt.complete(stripReasoning(completion.Choices[0].Content)))
+}
+
+// parseLanguageCode normalizes the LLM answer to a lowercase language code
+func parseLanguageCode(response string) (string, error) {
+	code := strings.ToLower(strings.Trim(strings.TrimSpace(response), "\"'`."))
+	if !languageCodePattern.MatchString(code) {
+		// Some models answer with the language name instead of the code
+		for languageCode, name := range languageNames {
+			if strings.EqualFold(name, code) {
+				return languageCode, nil
+			}
+		}
+		return "", fmt.Errorf("invalid language code in response: %s", response)
+	}
+	return code, nil
+}
+
+// languageField looks up the custom field configured to hold the detected language.
+// Fields that are extracted by the LLM anyway are not overwritten.
+func languageField(extractCustomFields []CustomField, allCustomFields []CustomField) (CustomField, bool) {
+	if languageCustomField == "" {
+		return CustomField{}, false
+	}
+	if _, ok := findCustomField(extractCustomFields, languageCustomField); ok {
+		return CustomField{}, false
+	}
+	field, ok := findCustomField(allCustomFields, languageCustomField)
+	if !ok {
+		log.Warnf("Custom field '%s' configured in LANGUAGE_CUSTOM_FIELD does not exist", languageCustomField)
+	}
+	return field, ok
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// mockPromptLLM answers prompts using a response function and records all prompts
+type mockPromptLLM struct {
+	mu      sync.Mutex
+	respond func(prompt string) string
+	prompts []string
+}
+
+func (m *mockPromptLLM) Call(_ context.Context, prompt string, _ ...llms.CallOption) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.prompts = append(m.prompts, prompt)
+	return m.respond(prompt), nil
+}
+
+func (m *mockPromptLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, opts ...llms.CallOption) (*llms.ContentResponse, error) {
+	response, err := m.Call(ctx, messages[0].Parts[0].(llms.TextContent).Text, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{
+			{
+				Content: response,
+			},
+		},
+	}, nil
+}
+
+func TestParseLanguageCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		response string
+		expected string
+		wantErr  bool
+	}{
+		{name: "plain code", response: "de", expected: "de"},
+		{name: "uppercase quoted code", response: "\"FR\".", expected: "fr"},
+		{name: "language name", response: "German", expected: "de"},
+		{name: "garbage", response: "The document is written in German.", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code, err := parseLanguageCode(tc.response)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, code)
+		})
+	}
+}
+
+func TestGetDocumentLanguage(t *testing.T) {
+	setSettings(t, func(settings *runtimeSettings) { settings.LLMLanguage = "english" })
+
+	ctx := context.Background()
+	assert.Equal(t, "English", getDocumentLanguage(ctx))
+	assert.Equal(t, "German", getDocumentLanguage(withDocumentLanguage(ctx, languageName("de"))))
+}
+
+func TestGenerateDocumentSuggestions_DetectLanguage(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	var err error
+	languageTemplate, err = template.New("language").Funcs(sprig.FuncMap()).Parse(defaultLanguageTemplate)
+	require.NoError(t, err)
+	titleTemplate, err = template.New("title").Funcs(sprig.FuncMap()).Parse("Language: {{.Language}}\n{{.Content}}")
+	require.NoError(t, err)
+
+	llm := &mockPromptLLM{respond: func(prompt string) string {
+		if strings.Contains(prompt, "ISO 639-1") {
+			return "de"
+		}
+		return "Rechnung"
+	}}
+	app := &App{Client: env.client, LLM: llm}
+
+	request := GenerateSuggestionsRequest{
+		Documents:      []Document{{ID: 1, Title: "scan", Content: "Sehr geehrte Damen und Herren"}},
+		GenerateTitles: true,
+		DetectLanguage: true,
+	}
+
+	suggestions, err := app.generateDocumentSuggestions(context.Background(), request, logrus.WithField("test", "test"))
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "de", suggestions[0].SuggestedLanguage)
+	assert.Equal(t, "Rechnung", suggestions[0].SuggestedTitle)
+	assert.Contains(t, llm.prompts[len(llm.prompts)-1], "Language: German")
+}
+
+func TestExpectedLanguages(t *testing.T) {
+	ctx := context.Background()
+	setSettings(t, func(settings *runtimeSettings) { settings.LLMLanguage = "" })
+	assert.Equal(t, []documentLanguage{{Code: "en", Name: "English"}}, expectedLanguages(ctx))
+	assert.Equal(t, "English", getLikelyLanguage(ctx))
+
+	setSettings(t, func(settings *runtimeSettings) { settings.LLMLanguage = "german, en, Klingon" })
+	assert.Equal(t, []documentLanguage{{Code: "de", Name: "German"}, {Code: "en", Name: "English"}, {Name: "Klingon"}}, expectedLanguages(ctx))
+	assert.Equal(t, "German", getLikelyLanguage(ctx))
+
+	// Documents in other languages get the first language
+	assert.Equal(t, documentLanguage{Code: "en", Name: "English"}, promptLanguageFor(ctx, "en"))
+	assert.Equal(t, documentLanguage{Code: "de", Name: "German"}, promptLanguageFor(ctx, "fr"))
+
+	// With a single language, the detected one is used
+	setSettings(t, func(settings *runtimeSettings) { settings.LLMLanguage = "German" })
+	assert.Equal(t, documentLanguage{Code: "fr", Name: "French"}, promptLanguageFor(ctx, "fr"))
+}
+
+func TestGenerateDocumentSuggestions_ExpectedLanguages(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	setSettings(t, func(settings *runtimeSettings) { settings.LLMLanguage = "English,German" })
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	var err error
+	languageTemplate, err = template.New("language").Funcs(sprig.FuncMap()).Parse(defaultLanguageTemplate)
+	require.NoError(t, err)
+	titleTemplate, err = template.New("title").Funcs(sprig.FuncMap()).Parse("Language: {{.Language}}\n{{.Content}}")
+	require.NoError(t, err)
+
+	llm := &mockPromptLLM{respond: func(prompt string) string {
+		if strings.Contains(prompt, "ISO 639-1") {
+			if strings.Contains(prompt, "Bonjour") {
+				return "fr"
+			}
+			return "de"
+		}
+		return "Rechnung"
+	}}
+	app := &App{Client: env.client, LLM: llm}
+
+	// The languages are detected without DETECT_LANGUAGE when several are expected
+	request := GenerateSuggestionsRequest{
+		Documents:      []Document{{ID: 1, Title: "scan", Content: "Sehr geehrte Damen und Herren"}},
+		GenerateTitles: true,
+	}
+	suggestions, err := app.generateDocumentSuggestions(context.Background(), request, logrus.WithField("test", "test"))
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "de", suggestions[0].SuggestedLanguage)
+	assert.Contains(t, llm.prompts[0], "one of these languages: English, German.")
+	assert.Contains(t, llm.prompts[len(llm.prompts)-1], "Language: German")
+
+	request.Documents = []Document{{ID: 2, Title: "scan", Content: "Bonjour Madame, Monsieur"}}
+	_, err = app.generateDocumentSuggestions(context.Background(), request, logrus.WithField("test", "test"))
+	require.NoError(t, err)
+	assert.Contains(t, llm.prompts[len(llm.prompts)-1], "Language: English", "documents in other languages get the first language")
+
+	// The documents are processed concurrently, each with its own language
+	request.Documents = []Document{
+		{ID: 1, Title: "scan", Content: "Sehr geehrte Damen und Herren"},
+		{ID: 2, Title: "scan", Content: "Bonjour Madame, Monsieur"},
+	}
+	suggestions, err = app.generateDocumentSuggestions(context.Background(), request, logrus.WithField("test", "test"))
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	languages := map[int]string{}
+	for _, suggestion := range suggestions {
+		languages[suggestion.ID] = suggestion.SuggestedLanguage
+	}
+	assert.Equal(t, map[int]string{1: "de", 2: "fr"}, languages)
+}
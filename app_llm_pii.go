@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// piiCategories lists the categories of personal data the PII scan looks for
+var piiCategories = []string{"name", "address", "email", "phone", "date_of_birth", "iban", "credit_card", "national_id", "health"}
+
+// defaultPIICategoryTags maps the PII categories to additional tags. All are disabled by default.
+var defaultPIICategoryTags = func() map[string]string {
+	tags := make(map[string]string)
+	for _, category := range piiCategories {
+		tags[category] = ""
+	}
+	return tags
+}()
+
+var (
+	emailPattern         = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	ibanCandidatePattern = regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`)
+	ssnPattern           = regexp.MustCompile(`\b(\d{3})-(\d{2})-(\d{4})\b`)
+	creditCardPattern    = regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`)
+)
+
+// scanDocumentPII looks for personal data in the document content. Structured data like IBANs is
+// detected with patterns, everything else by the LLM. It returns the number of findings per category.
+func (app *App) scanDocumentPII(ctx context.Context, content string, logger *logrus.Entry) (map[string]int, error) {
+	findings := detectPIIPatterns(content)
+
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language":   likelyLanguage,
+		"Categories": piiCategories,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "pii", piiTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return nil, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return nil, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return nil, fmt.Errorf("error executing PII template: %v", err)
+	}
+
+	logger.Debugf("PII scan prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "pii"), prompt.messages())
+	if err != nil {
+		return nil, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	llmFindings, err := parsePIIResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Patterns are exact, so the LLM can only add findings
+	for category, count := range llmFindings {
+		findings[category] = max(findings[category], count)
+	}
+	return findings, nil
+}
+
+// detectPIIPatterns finds personal data with a fixed format in the content
+func detectPIIPatterns(content string) map[string]int {
+	findings := make(map[string]int)
+
+	if matches := emailPattern.FindAllString(content, -1); len(matches) > 0 {
+		findings["email"] = len(matches)
+	}
+
+	// Remove IBAN-like numbers so their digits are not mistaken for credit card numbers
+	content = ibanCandidatePattern.ReplaceAllStringFunc(content, func(match string) string {
+		if isValidIBAN(strings.ReplaceAll(match, " ", "")) {
+			findings["iban"]++
+		}
+		return ""
+	})
+
+	for _, match := range ssnPattern.FindAllStringSubmatch(content, -1) {
+		// Area numbers 000, 666 and 900-999 as well as zero groups are never assigned
+		if match[1] != "000" && match[1] != "666" && match[1][0] != '9' && match[2] != "00" && match[3] != "0000" {
+			findings["national_id"]++
+		}
+	}
+
+	for _, match := range creditCardPattern.FindAllString(content, -1) {
+		if isValidLuhn(strings.NewReplacer(" ", "", "-", "").Replace(match)) {
+			findings["credit_card"]++
+		}
+	}
+
+	return findings
+}
+
+// isValidLuhn checks the Luhn checksum used by credit card numbers
+func isValidLuhn(number string) bool {
+	if len(number) < 13 || len(number) > 19 {
+		return false
+	}
+	sum := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		digit := int(number[i] - '0')
+		if (len(number)-i)%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return sum%10 == 0
+}
+
+// parsePIIResponse parses the JSON answer of the LLM. Unknown categories are ignored.
+func parsePIIResponse(response string) (map[string]int, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return nil, err
+	}
+
+	var answer map[string]interface{}
+	if err := json.Unmarshal([]byte(jsonObject), &answer); err != nil {
+		return nil, fmt.Errorf("error parsing PII response: %v", err)
+	}
+
+	findings := make(map[string]int)
+	for category, value := range answer {
+		category = strings.ToLower(strings.TrimSpace(category))
+		if !slices.Contains(piiCategories, category) {
+			continue
+		}
+		switch count := value.(type) {
+		case float64:
+			if count > 0 {
+				findings[category] = int(count)
+			}
+		case bool:
+			if count {
+				findings[category] = 1
+			}
+		}
+	}
+	return findings, nil
+}
+
+// piiTagsFor returns the tags to add for the PII categories found in a document
+func (settings *runtimeSettings) piiTagsFor(categories []string) []string {
+	if len(categories) == 0 {
+		return nil
+	}
+	tags := []string{}
+	if settings.PIITag != "" {
+		tags = append(tags, settings.PIITag)
+	}
+	for _, category := range categories {
+		if tag := piiCategoryTags[category]; tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// piiCategoryNames returns the sorted categories with at least one finding
+func piiCategoryNames(findings map[string]int) []string {
+	categories := slices.Collect(maps.Keys(findings))
+	slices.Sort(categories)
+	return categories
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectPIIPatterns(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected map[string]int
+	}{
+		{
+			name:     "No personal data",
+			content:  "Invoice 2024-001 for 3 items, total 123.45 EUR",
+			expected: map[string]int{},
+		},
+		{
+			name:     "Email addresses",
+			content:  "Contact: max.mustermann@example.com or support@example.org",
+			expected: map[string]int{"email": 2},
+		},
+		{
+			name:     "Valid and invalid IBAN",
+			content:  "IBAN: DE89 3704 0044 0532 0130 00, old account DE89 3704 0044 0532 0130 01",
+			expected: map[string]int{"iban": 1},
+		},
+		{
+			name:     "Social security numbers",
+			content:  "SSN 123-45-6789, not assigned: 666-12-3456 and 900-12-3456",
+			expected: map[string]int{"national_id": 1},
+		},
+		{
+			name:     "Credit card numbers",
+			content:  "Card 4111 1111 1111 1111, typo 4111 1111 1111 1112",
+			expected: map[string]int{"credit_card": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, detectPIIPatterns(tc.content))
+		})
+	}
+}
+
+func TestParsePIIResponse(t *testing.T) {
+	findings, err := parsePIIResponse(`Here you go: {"name": 2, "Health": true, "phone": 0, "email": false, "shoe_size": 3}`)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"name": 2, "health": 1}, findings)
+
+	findings, err = parsePIIResponse("{}")
+	require.NoError(t, err)
+	assert.Empty(t, findings)
+
+	_, err = parsePIIResponse("no personal data")
+	assert.Error(t, err)
+}
+
+func TestPIITagsFor(t *testing.T) {
+	originalCategoryTags := piiCategoryTags
+	defer func() { piiCategoryTags = originalCategoryTags }()
+
+	settings := &runtimeSettings{PIITag: "contains-pii"}
+	piiCategoryTags = parseNameMapping("PII_CATEGORY_TAGS", "health=medical,iban=banking", defaultPIICategoryTags)
+
+	assert.Nil(t, settings.piiTagsFor(nil))
+	assert.Equal(t, []string{"contains-pii"}, settings.piiTagsFor([]string{"name"}))
+	assert.Equal(t, []string{"contains-pii", "medical", "banking"}, settings.piiTagsFor([]string{"health", "iban", "name"}))
+
+	settings.PIITag = ""
+	assert.Equal(t, []string{"medical"}, settings.piiTagsFor([]string{"health"}))
+}
+
+func TestPIIReports(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("DELETE FROM pii_reports").Error)
+
+	_, err = GetPIIReport(db, 42)
+	assert.Error(t, err)
+
+	require.NoError(t, SavePIIReport(db, 42, map[string]int{"email": 1}))
+	require.NoError(t, SavePIIReport(db, 42, map[string]int{"iban": 2, "name": 1}))
+
+	report, err := GetPIIReport(db, 42)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), report.DocumentID)
+
+	var categories map[string]int
+	require.NoError(t, json.Unmarshal([]byte(report.Categories), &categories))
+	assert.Equal(t, map[string]int{"iban": 2, "name": 1}, categories)
+
+	var count int64
+	db.Model(&PIIReport{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// relatedEmbeddingMaxRunes limits the content used for embeddings, which is enough to capture the topic of a document
+const relatedEmbeddingMaxRunes = 8000
+
+// referencePattern matches labeled reference numbers like "Invoice No. INV-2024-001" or "Rechnungsnummer: 12345"
+var referencePattern = regexp.MustCompile(`(?i)\b(?:invoice|rechnung|order|bestellung|auftrag|customer|kunden|contract|vertrag|reference|referenz|policy|police)s?(?:[ -]?(?:no|nr|number|nummer|id))?\.?\s*[:#]?\s*([A-Z0-9][A-Z0-9/\-]{3,})`)
+
+// embedder creates vector embeddings for texts, as implemented by the OpenAI and Ollama clients
+type embedder interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// extractDocumentReferences finds the reference numbers in the content, normalized to uppercase.
+// Only references containing a digit are returned, which filters out words following the labels.
+func extractDocumentReferences(content string) []string {
+	references := []string{}
+	for _, match := range referencePattern.FindAllStringSubmatch(content, -1) {
+		reference := strings.ToUpper(strings.TrimRight(match[1], "/-"))
+		if len(reference) < 4 || !strings.ContainsAny(reference, "0123456789") || slices.Contains(references, reference) {
+			continue
+		}
+		references = append(references, reference)
+	}
+	return references
+}
+
+// findRelatedDocuments indexes the document and returns the already indexed documents related to it
+func (app *App) findRelatedDocuments(ctx context.Context, doc Document, content string, logger *logrus.Entry) ([]RelatedDocument, error) {
+	references := extractDocumentReferences(content)
+
+	var embedding []float32
+	if app.Embedder != nil && strings.TrimSpace(content) != "" {
+		text := content
+		if runes := []rune(text); len(runes) > relatedEmbeddingMaxRunes {
+			text = string(runes[:relatedEmbeddingMaxRunes])
+		}
+		embeddings, err := app.Embedder.CreateEmbedding(ctx, []string{doc.Title + "\n\n" + text})
+		if err != nil {
+			return nil, fmt.Errorf("error creating embedding: %v", err)
+		}
+		if len(embeddings) > 0 {
+			embedding = embeddings[0]
+		}
+	}
+
+	if err := SaveDocumentIndex(app.Database, uint(doc.ID), doc.Title, references, embedding); err != nil {
+		return nil, fmt.Errorf("error indexing document: %v", err)
+	}
+
+	indexes, err := GetDocumentIndexes(app.Database)
+	if err != nil {
+		return nil, fmt.Errorf("error loading document index: %v", err)
+	}
+
+	return relatedDocuments(doc.ID, references, embedding, indexes, logger), nil
+}
+
+// relatedDocuments compares a document with the indexed documents. Documents sharing a reference number come first,
+// followed by documents with similar content, limited to RELATED_DOCUMENTS_MAX.
+func relatedDocuments(documentID int, references []string, embedding []float32, indexes []DocumentIndex, logger *logrus.Entry) []RelatedDocument {
+	type candidate struct {
+		document   RelatedDocument
+		shared     bool
+		similarity float64
+	}
+
+	var candidates []candidate
+	for _, index := range indexes {
+		if int(index.DocumentID) == documentID {
+			continue
+		}
+
+		var indexReferences []string
+		if index.References != "" {
+			if err := json.Unmarshal([]byte(index.References), &indexReferences); err != nil {
+				logger.Warnf("Ignoring invalid references of document %d: %v", index.DocumentID, err)
+			}
+		}
+		if i := slices.IndexFunc(indexReferences, func(reference string) bool {
+			return slices.Contains(references, reference)
+		}); i >= 0 {
+			candidates = append(candidates, candidate{
+				document: RelatedDocument{ID: int(index.DocumentID), Title: index.Title, Reason: "reference " + indexReferences[i]},
+				shared:   true,
+			})
+			continue
+		}
+
+		if len(embedding) == 0 || index.Embedding == "" {
+			continue
+		}
+		var indexEmbedding []float32
+		if err := json.Unmarshal([]byte(index.Embedding), &indexEmbedding); err != nil {
+			logger.Warnf("Ignoring invalid embedding of document %d: %v", index.DocumentID, err)
+			continue
+		}
+		if similarity := cosineSimilarity(embedding, indexEmbedding); similarity >= relatedDocumentsMinSimilarity {
+			candidates = append(candidates, candidate{
+				document:   RelatedDocument{ID: int(index.DocumentID), Title: index.Title, Reason: fmt.Sprintf("similar content (%.2f)", similarity)},
+				similarity: similarity,
+			})
+		}
+	}
+
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.shared != b.shared {
+			if a.shared {
+				return -1
+			}
+			return 1
+		}
+		if a.similarity != b.similarity {
+			if a.similarity > b.similarity {
+				return -1
+			}
+			return 1
+		}
+		return a.document.ID - b.document.ID
+	})
+
+	related := []RelatedDocument{}
+	for _, c := range candidates {
+		if len(related) >= relatedDocumentsMax {
+			break
+		}
+		related = append(related, c.document)
+	}
+	return related
+}
+
+// cosineSimilarity returns the cosine similarity of two vectors, or 0 if they can't be compared
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// relatedDocumentsFieldValue returns the value of a document link custom field with the related documents
+// added to the documents it already links to
+func relatedDocumentsFieldValue(field CustomField, existing []CustomFieldValue, related []RelatedDocument) CustomFieldValue {
+	ids := []int{}
+	for _, value := range existing {
+		if value.Field != field.ID {
+			continue
+		}
+		if linked, ok := value.Value.([]interface{}); ok {
+			for _, id := range linked {
+				if id, ok := id.(float64); ok {
+					ids = append(ids, int(id))
+				}
+			}
+		}
+	}
+	for _, document := range related {
+		if !slices.Contains(ids, document.ID) {
+			ids = append(ids, document.ID)
+		}
+	}
+	return CustomFieldValue{Field: field.ID, Value: ids}
+}
+
+// relatedDocumentsNote formats the related documents as a note with links to the paperless-ngx web interface
+func relatedDocumentsNote(related []RelatedDocument) string {
+	var note strings.Builder
+	note.WriteString("Related documents:")
+	for _, document := range related {
+		fmt.Fprintf(&note, "\n- %s (%s): %s/documents/%d/details", document.Title, document.Reason, paperlessPublicURL(), document.ID)
+	}
+	return note.String()
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockEmbedder returns a fixed embedding per text prefix
+type mockEmbedder struct {
+	embeddings map[string][]float32
+}
+
+func (m *mockEmbedder) CreateEmbedding(_ context.Context, texts []string) ([][]float32, error) {
+	result := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		for prefix, embedding := range m.embeddings {
+			if len(text) >= len(prefix) && text[:len(prefix)] == prefix {
+				result = append(result, embedding)
+			}
+		}
+	}
+	return result, nil
+}
+
+func TestExtractDocumentReferences(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "English invoice number",
+			content:  "Invoice No. INV-2024-001\nInvoice date: 2024-01-15",
+			expected: []string{"INV-2024-001"},
+		},
+		{
+			name:     "German invoice and customer number",
+			content:  "Rechnungsnummer: 12345\nKundennummer: K-9876\nZahlungserinnerung zur Rechnung Nr. 12345",
+			expected: []string{"12345", "K-9876"},
+		},
+		{
+			name:     "Labels without numbers",
+			content:  "Thank you for your order. Your invoice is attached.",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, extractDocumentReferences(tc.content))
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, cosineSimilarity([]float32{1, 2}, []float32{2, 4}), 1e-9)
+	assert.InDelta(t, 0.0, cosineSimilarity([]float32{1, 0}, []float32{0, 1}), 1e-9)
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{1}, []float32{1, 2}))
+	assert.Equal(t, 0.0, cosineSimilarity([]float32{0, 0}, []float32{1, 2}))
+}
+
+func TestFindRelatedDocuments(t *testing.T) {
+	originalMax, originalSimilarity := relatedDocumentsMax, relatedDocumentsMinSimilarity
+	defer func() {
+		relatedDocumentsMax, relatedDocumentsMinSimilarity = originalMax, originalSimilarity
+	}()
+	relatedDocumentsMax = 2
+	relatedDocumentsMinSimilarity = 0.9
+
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("DELETE FROM document_indices").Error)
+
+	app := &App{
+		Database: db,
+		Embedder: &mockEmbedder{embeddings: map[string][]float32{
+			"Invoice":  {1, 0, 0},
+			"Receipt":  {0.95, 0.1, 0},
+			"Reminder": {0, 1, 0},
+			"Letter":   {0, 0, 1},
+			"Offer":    {0.9, 0.3, 0},
+		}},
+	}
+	logger := logrus.WithField("test", "related")
+	ctx := context.Background()
+
+	// The first document has nothing to relate to yet
+	related, err := app.findRelatedDocuments(ctx, Document{ID: 1, Title: "Invoice"}, "Invoice No. INV-2024-001", logger)
+	require.NoError(t, err)
+	assert.Empty(t, related)
+
+	_, err = app.findRelatedDocuments(ctx, Document{ID: 2, Title: "Receipt"}, "Payment received", logger)
+	require.NoError(t, err)
+	_, err = app.findRelatedDocuments(ctx, Document{ID: 3, Title: "Letter"}, "Hello", logger)
+	require.NoError(t, err)
+	_, err = app.findRelatedDocuments(ctx, Document{ID: 4, Title: "Offer"}, "Our offer", logger)
+	require.NoError(t, err)
+
+	// The reminder shares the invoice number, so the invoice comes first despite the different content
+	related, err = app.findRelatedDocuments(ctx, Document{ID: 5, Title: "Reminder"}, "Reminder for invoice no. INV-2024-001", logger)
+	require.NoError(t, err)
+	assert.Equal(t, []RelatedDocument{{ID: 1, Title: "Invoice", Reason: "reference INV-2024-001"}}, related)
+
+	// Re-indexing the invoice finds the similar documents, most similar first, limited to RELATED_DOCUMENTS_MAX
+	related, err = app.findRelatedDocuments(ctx, Document{ID: 1, Title: "Invoice"}, "Invoice No. INV-2024-001", logger)
+	require.NoError(t, err)
+	require.Len(t, related, 2)
+	assert.Equal(t, 5, related[0].ID)
+	assert.Equal(t, 2, related[1].ID)
+	assert.Equal(t, "similar content (0.99)", related[1].Reason)
+
+	var count int64
+	db.Model(&DocumentIndex{}).Count(&count)
+	assert.Equal(t, int64(5), count)
+}
+
+func TestRelatedDocumentsFieldValue(t *testing.T) {
+	field := CustomField{ID: 7, Name: "Related", DataType: "documentlink"}
+	existing := []CustomFieldValue{
+		{Field: 1, Value: "other"},
+		{Field: 7, Value: []interface{}{float64(3), float64(4)}},
+	}
+	related := []RelatedDocument{{ID: 4}, {ID: 9}}
+
+	assert.Equal(t, CustomFieldValue{Field: 7, Value: []int{3, 4, 9}}, relatedDocumentsFieldValue(field, existing, related))
+	assert.Equal(t, CustomFieldValue{Field: 7, Value: []int{4, 9}}, relatedDocumentsFieldValue(field, nil, related))
+}
+
+func TestRelatedDocumentsNote(t *testing.T) {
+	t.Setenv("PAPERLESS_PUBLIC_URL", "https://paperless.example.com/")
+
+	note := relatedDocumentsNote([]RelatedDocument{
+		{ID: 1, Title: "Invoice", Reason: "reference INV-2024-001"},
+		{ID: 4, Title: "Offer", Reason: "similar content (0.99)"},
+	})
+	assert.Equal(t, "Related documents:\n"+
+		"- Invoice (reference INV-2024-001): https://paperless.example.com/documents/1/details\n"+
+		"- Offer (similar content (0.99)): https://paperless.example.com/documents/4/details", note)
+}
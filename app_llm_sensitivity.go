@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// getSuggestedSensitivity classifies the document into one of the configured sensitivity levels using the LLM
+func (app *App) getSuggestedSensitivity(ctx context.Context, content string, suggestedTitle string, logger *logrus.Entry) (string, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+		"Levels":   sensitivityLevels,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "sensitivity", sensitivityTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing sensitivity template: %v", err)
+	}
+
+	logger.Debugf("Sensitivity classification prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "sensitivity"), prompt.messages())
+	if err != nil {
+		return "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseSensitivityLevel(prompt.complete(stripReasoning(completion.Choices[0].Content)))
+}
+
+// parseSensitivityLevel matches the LLM answer against the configured sensitivity levels
+func parseSensitivityLevel(response string) (string, error) {
+	answer := strings.Trim(strings.TrimSpace(response), "\"'`.*")
+	for _, level := range sensitivityLevels {
+		if strings.EqualFold(answer, level) {
+			return level, nil
+		}
+	}
+	return "", fmt.Errorf("unknown sensitivity level in response: %s", response)
+}
+
+// sensitivityTag returns the tag for a sensitivity level
+func sensitivityTag(level string) string {
+	return sensitivityTagPrefix + level
+}
+
+// sensitivityLevelOf returns the sensitivity level a document is tagged with, or an empty string
+func sensitivityLevelOf(tags []string) string {
+	for _, level := range sensitivityLevels {
+		if slices.Contains(tags, sensitivityTag(level)) {
+			return level
+		}
+	}
+	return ""
+}
+
+// withSensitivityLevel replaces the sensitivity tag in the list of tags. An empty level only removes it.
+func withSensitivityLevel(tags []string, level string) []string {
+	result := slices.DeleteFunc(slices.Clone(tags), func(tag string) bool {
+		return slices.ContainsFunc(sensitivityLevels, func(l string) bool {
+			return tag == sensitivityTag(l)
+		})
+	})
+	if level != "" {
+		result = append(result, sensitivityTag(level))
+	}
+	return result
+}
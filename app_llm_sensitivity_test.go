@@ -0,0 +1,56 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSensitivityLevel(t *testing.T) {
+	originalLevels := sensitivityLevels
+	defer func() { sensitivityLevels = originalLevels }()
+	sensitivityLevels = []string{"public", "internal", "confidential"}
+
+	testCases := []struct {
+		name     string
+		response string
+		expected string
+		wantErr  bool
+	}{
+		{name: "Plain level", response: "confidential", expected: "confidential"},
+		{name: "Different case and quotes", response: " \"Internal\".\n", expected: "internal"},
+		{name: "Markdown emphasis", response: "**public**", expected: "public"},
+		{name: "Unknown level", response: "secret", wantErr: true},
+		{name: "Sentence", response: "The document is confidential", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			level, err := parseSensitivityLevel(tc.response)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, level)
+		})
+	}
+}
+
+func TestSensitivityTags(t *testing.T) {
+	originalLevels, originalPrefix := sensitivityLevels, sensitivityTagPrefix
+	defer func() {
+		sensitivityLevels, sensitivityTagPrefix = originalLevels, originalPrefix
+	}()
+	sensitivityLevels = []string{"public", "internal", "confidential"}
+	sensitivityTagPrefix = "sensitivity:"
+
+	tags := []string{"invoice", "sensitivity:internal"}
+	assert.Equal(t, "internal", sensitivityLevelOf(tags))
+	assert.Equal(t, "", sensitivityLevelOf([]string{"invoice"}))
+
+	assert.Equal(t, []string{"invoice", "sensitivity:confidential"}, withSensitivityLevel(tags, "confidential"))
+	assert.Equal(t, []string{"invoice"}, withSensitivityLevel(tags, ""))
+	assert.Equal(t, []string{"invoice", "sensitivity:internal"}, tags, "input must not be modified")
+}
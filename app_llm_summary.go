@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// getSuggestedSummary generates a concise summary of a document using the LLM
+func (app *App) getSuggestedSummary(ctx context.Context, content string, suggestedTitle string, logger *logrus.Entry) (string, error) {
+	// The summary is written in the configured summary language, otherwise in the document language
+	language := getDocumentLanguage(ctx)
+	if summaryLanguage != "" {
+		language = summaryLanguage
+	}
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": language,
+		"Title":    suggestedTitle,
+		"MaxWords": summaryMaxWords,
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "summary", summaryTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing summary template: %v", err)
+	}
+
+	logger.Debugf("Summary suggestion prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "summary"), prompt.messages())
+	if err != nil {
+		return "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return strings.TrimSpace(prompt.complete(stripReasoning(completion.Choices[0].Content))), nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSuggestedSummary(t *testing.T) {
+	originalTemplate := summaryTemplate
+	originalLanguage := summaryLanguage
+	originalMaxWords := summaryMaxWords
+	defer func() {
+		summaryTemplate = originalTemplate
+		summaryLanguage = originalLanguage
+		summaryMaxWords = originalMaxWords
+	}()
+
+	summaryTemplate = template.Must(template.New("summary").Parse("{{.Language}}|{{.MaxWords}}|{{.Title}}|{{.Content}}"))
+	summaryMaxWords = 50
+
+	llm := &mockJSONLLM{response: "<think>reasoning</think>\n  A short summary.  "}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "summary")
+
+	t.Run("uses document language", func(t *testing.T) {
+		summaryLanguage = ""
+		ctx := withDocumentLanguage(context.Background(), "German")
+		summary, err := app.getSuggestedSummary(ctx, "content", "Invoice", logger)
+		require.NoError(t, err)
+		assert.Equal(t, "A short summary.", summary)
+		assert.Equal(t, "German|50|Invoice|content", llm.lastPrompt)
+	})
+
+	t.Run("summary language overrides document language", func(t *testing.T) {
+		summaryLanguage = "English"
+		ctx := withDocumentLanguage(context.Background(), "German")
+		_, err := app.getSuggestedSummary(ctx, "content", "Invoice", logger)
+		require.NoError(t, err)
+		assert.Equal(t, "English|50|Invoice|content", llm.lastPrompt)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"text/template"
 
+	"github.com/Masterminds/sprig/v3"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -117,7 +118,7 @@ Content: {{.Content}}
 		t.Run(tc.name, func(t *testing.T) {
 			// Set token limit for this test
 			os.Setenv("TOKEN_LIMIT", fmt.Sprintf("%d", tc.tokenLimit))
-			resetTokenLimit()
+			resetTokenLimit(t)
 
 			// Prepare test data
 			data := map[string]interface{}{
@@ -125,11 +126,11 @@ Content: {{.Content}}
 			}
 
 			// Calculate available tokens
-			availableTokens, err := getAvailableTokensForContent(testTemplate, data)
+			availableTokens, err := getAvailableTokensForContent(context.Background(), testTemplate, data)
 			require.NoError(t, err)
 
 			// Truncate content if needed
-			truncatedContent, err := truncateContentByTokens(tc.content, availableTokens)
+			truncatedContent, err := truncateContentByTokens(context.Background(), tc.content, availableTokens)
 			require.NoError(t, err)
 
 			// Test with the app's LLM
@@ -180,14 +181,14 @@ func TestTokenLimitInCorrespondentGeneration(t *testing.T) {
 
 	// Set a small token limit
 	os.Setenv("TOKEN_LIMIT", "50")
-	resetTokenLimit()
+	resetTokenLimit(t)
 
 	// Call getSuggestedCorrespondent
 	ctx := context.Background()
 	availableCorrespondents := []string{"Test Corp", "Example Inc"}
 	correspondentBlackList := []string{"Blocked Corp"}
 
-	_, err := app.getSuggestedCorrespondent(ctx, longContent, "Test Title", availableCorrespondents, correspondentBlackList)
+	_, err := app.getSuggestedCorrespondent(ctx, longContent, "Test Title", availableCorrespondents, correspondentBlackList, logrus.WithField("test", "test"))
 	require.NoError(t, err)
 
 	// Verify the final prompt size
@@ -218,14 +219,14 @@ func TestTokenLimitInTagGeneration(t *testing.T) {
 
 	// Set a small token limit
 	os.Setenv("TOKEN_LIMIT", "50")
-	resetTokenLimit()
+	resetTokenLimit(t)
 
 	// Call getSuggestedTags
 	ctx := context.Background()
 	availableTags := []string{"test", "example"}
 	originalTags := []string{"original"}
 
-	_, err := app.getSuggestedTags(ctx, longContent, "Test Title", availableTags, originalTags, testLogger)
+	_, _, err := app.getSuggestedTags(ctx, longContent, "Test Title", availableTags, originalTags, testLogger)
 	require.NoError(t, err)
 
 	// Verify the final prompt size
@@ -256,7 +257,7 @@ func TestTokenLimitInTitleGeneration(t *testing.T) {
 
 	// Set a small token limit
 	os.Setenv("TOKEN_LIMIT", "50")
-	resetTokenLimit()
+	resetTokenLimit(t)
 
 	// Call getSuggestedTitle
 	ctx := context.Background()
@@ -292,7 +293,7 @@ func TestTokenLimitInCreatedDateGeneration(t *testing.T) {
 
 	// Set a small token limit
 	os.Setenv("TOKEN_LIMIT", "50")
-	resetTokenLimit()
+	resetTokenLimit(t)
 
 	// Call getSuggestedCreatedDate
 	ctx := context.Background()
@@ -334,3 +335,130 @@ func TestStripReasoning(t *testing.T) {
 		})
 	}
 }
+
+func TestGetSuggestedTags_NewTags(t *testing.T) {
+	var err error
+	tagTemplate, err = template.New("tag").Funcs(sprig.FuncMap()).Parse(defaultTagTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: "Invoice, Travel Expenses, original"}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "test")
+
+	setSettings(t, func(settings *runtimeSettings) { settings.AllowNewTags, settings.TokenLimit = false, 0 })
+	tags, newTags, err := app.getSuggestedTags(context.Background(), "content", "Title", []string{"invoice", "receipt"}, []string{"original"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice"}, tags)
+	assert.Empty(t, newTags)
+	assert.Contains(t, llm.lastPrompt, "Only select tags from the provided list.")
+
+	setSettings(t, func(settings *runtimeSettings) { settings.AllowNewTags = true })
+	tags, newTags, err = app.getSuggestedTags(context.Background(), "content", "Title", []string{"invoice", "receipt"}, []string{"original"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice"}, tags)
+	assert.Equal(t, []string{"Travel Expenses"}, newTags)
+	assert.Contains(t, llm.lastPrompt, "you may propose a new short tag")
+}
+
+func TestGetSuggestedTags_WhiteAndBlackList(t *testing.T) {
+	var err error
+	tagTemplate, err = template.New("tag").Funcs(sprig.FuncMap()).Parse(defaultTagTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: "Invoice, inbox, Receipt, Insurance"}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "test")
+	available := []string{"invoice", "receipt", "inbox", "todo", "insurance"}
+
+	// Blacklisted tags are neither offered nor suggested, but kept on the document
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.TagBlackList, settings.TagWhiteList, settings.AllowNewTags = []string{"Inbox", "todo"}, nil, true
+		settings.TokenLimit = 0
+	})
+	tags, newTags, err := app.getSuggestedTags(context.Background(), "content", "Title", available, []string{"todo"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"insurance", "invoice", "receipt", "todo"}, tags)
+	assert.Empty(t, newTags)
+	assert.Contains(t, llm.lastPrompt, "invoice, receipt, insurance\n")
+
+	// The whitelist limits the suggestions to its tags
+	setSettings(t, func(settings *runtimeSettings) { settings.TagWhiteList = []string{"invoice", "receipt"} })
+	tags, _, err = app.getSuggestedTags(context.Background(), "content", "Title", available, nil, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice", "receipt"}, tags)
+	assert.Contains(t, llm.lastPrompt, "invoice, receipt\n")
+
+	// New tags must be whitelisted as well
+	llm.response = "Invoice, Travel Expenses"
+	tags, newTags, err = app.getSuggestedTags(context.Background(), "content", "Title", available, nil, logger)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"invoice"}, tags)
+	assert.Empty(t, newTags)
+}
+
+func TestGetSuggestedCorrespondent_Restricted(t *testing.T) {
+	var err error
+	correspondentTemplate, err = template.New("correspondent").Funcs(sprig.FuncMap()).Parse(defaultCorrespondentTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: "acme corp"}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "test")
+	available := []string{"ACME Corp", "Stadtwerke", "Unknown"}
+
+	// Existing correspondents only, the spelling of paperless-ngx is kept
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.CorrespondentWhiteList, settings.AllowNewCorrespondents = nil, false
+		settings.TokenLimit = 0
+	})
+	correspondent, err := app.getSuggestedCorrespondent(context.Background(), "content", "Title", available, []string{"Unknown"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Corp", correspondent)
+	assert.Contains(t, llm.lastPrompt, "Never come up with a new correspondent.")
+	assert.Contains(t, llm.lastPrompt, "ACME Corp, Stadtwerke\n")
+
+	llm.response = "ACME Corporation Ltd"
+	correspondent, err = app.getSuggestedCorrespondent(context.Background(), "content", "Title", available, []string{"Unknown"}, logger)
+	require.NoError(t, err)
+	assert.Empty(t, correspondent, "new correspondents aren't suggested")
+
+	// The whitelist replaces the existing correspondents
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.CorrespondentWhiteList = []string{"ACME Corporation Ltd", "Finanzamt"}
+	})
+	correspondent, err = app.getSuggestedCorrespondent(context.Background(), "content", "Title", available, []string{"Unknown"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "ACME Corporation Ltd", correspondent)
+	assert.NotContains(t, llm.lastPrompt, "Stadtwerke")
+
+	// Without restrictions any correspondent is suggested
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.CorrespondentWhiteList, settings.AllowNewCorrespondents = nil, true
+	})
+	llm.response = "Someone New"
+	correspondent, err = app.getSuggestedCorrespondent(context.Background(), "content", "Title", available, []string{"Unknown"}, logger)
+	require.NoError(t, err)
+	assert.Equal(t, "Someone New", correspondent)
+	assert.Contains(t, llm.lastPrompt, "come up with a new correspondent")
+}
+
+func TestGetSuggestedTags_TagTaxonomy(t *testing.T) {
+	originalTaxonomy := tagTaxonomy
+	defer func() { tagTaxonomy = originalTaxonomy }()
+	tagTaxonomy = &TagTaxonomy{Groups: []TagGroup{
+		{Name: "Year", Tags: []string{"2023", "2024"}, Mode: TagGroupModeExactlyOne},
+	}}
+
+	var err error
+	tagTemplate, err = template.New("tag").Funcs(sprig.FuncMap()).Parse(defaultTagTemplate)
+	require.NoError(t, err)
+
+	llm := &mockJSONLLM{response: "2024, invoice"}
+	app := &App{LLM: llm}
+	setSettings(t, func(settings *runtimeSettings) { settings.TokenLimit = 0 })
+
+	tags, _, err := app.getSuggestedTags(context.Background(), "content", "Title", []string{"invoice", "2023", "2024"}, []string{"2023"}, logrus.WithField("test", "test"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2024", "invoice"}, tags)
+	assert.Contains(t, llm.lastPrompt, "- Year: select exactly one of 2023, 2024")
+}
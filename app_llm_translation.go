@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// translationHeadingPrefix starts the heading of translations stored in notes or the document content
+const translationHeadingPrefix = "--- Translation"
+
+// needsTranslation reports whether documents in the given language should be translated.
+// Documents without a detected language are never translated.
+func needsTranslation(language string) bool {
+	if language == "" || language == translationLanguage {
+		return false
+	}
+	return len(translationSourceLanguages) == 0 || slices.Contains(translationSourceLanguages, language)
+}
+
+// hasTranslation reports whether the content already contains a translation section
+func hasTranslation(content string) bool {
+	return strings.Contains(content, translationHeadingPrefix)
+}
+
+// getSuggestedTranslation translates the document content into the configured translation language using the LLM
+func (app *App) getSuggestedTranslation(ctx context.Context, content string, sourceLanguage string, logger *logrus.Entry) (string, error) {
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"SourceLanguage": languageName(sourceLanguage),
+		"Language":       languageName(translationLanguage),
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "translation", translationTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return "", fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing translation template: %v", err)
+	}
+
+	logger.Debugf("Translation prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "translation"), prompt.messages())
+	if err != nil {
+		return "", fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	translation := strings.TrimSpace(prompt.complete(stripReasoning(completion.Choices[0].Content)))
+	if translation == "" {
+		return "", fmt.Errorf("empty translation in response")
+	}
+	return translation, nil
+}
+
+// translationSection formats a translation with a heading naming the translation language
+func translationSection(translation string) string {
+	return fmt.Sprintf("%s (%s) ---\n\n%s", translationHeadingPrefix, languageName(translationLanguage), translation)
+}
+
+// appendTranslation appends the translation as a separate section to the document content
+func appendTranslation(content string, translation string) string {
+	return strings.TrimRight(content, "\n") + "\n\n" + translationSection(translation)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNeedsTranslation(t *testing.T) {
+	originalLanguage, originalSources := translationLanguage, translationSourceLanguages
+	defer func() {
+		translationLanguage, translationSourceLanguages = originalLanguage, originalSources
+	}()
+	translationLanguage = "en"
+
+	translationSourceLanguages = nil
+	assert.True(t, needsTranslation("de"))
+	assert.False(t, needsTranslation("en"), "documents in the translation language are not translated")
+	assert.False(t, needsTranslation(""), "documents without a detected language are not translated")
+
+	translationSourceLanguages = []string{"fr", "it"}
+	assert.True(t, needsTranslation("fr"))
+	assert.False(t, needsTranslation("de"))
+}
+
+func TestGetSuggestedTranslation(t *testing.T) {
+	originalTemplate, originalLanguage := translationTemplate, translationLanguage
+	defer func() {
+		translationTemplate, translationLanguage = originalTemplate, originalLanguage
+	}()
+
+	translationTemplate = template.Must(template.New("translation").Parse("{{.SourceLanguage}}|{{.Language}}|{{.Content}}"))
+	translationLanguage = "en"
+
+	llm := &mockJSONLLM{response: "<think>reasoning</think>\nInvoice for March\n"}
+	app := &App{LLM: llm}
+
+	translation, err := app.getSuggestedTranslation(context.Background(), "Rechnung für März", "de", logrus.WithField("test", "translation"))
+	require.NoError(t, err)
+	assert.Equal(t, "Invoice for March", translation)
+	assert.Equal(t, "German|English|Rechnung für März", llm.lastPrompt)
+
+	llm.response = "  "
+	_, err = app.getSuggestedTranslation(context.Background(), "Rechnung", "de", logrus.WithField("test", "translation"))
+	assert.Error(t, err)
+}
+
+func TestAppendTranslation(t *testing.T) {
+	originalLanguage := translationLanguage
+	defer func() { translationLanguage = originalLanguage }()
+	translationLanguage = "en"
+
+	content := appendTranslation("Rechnung für März\n\n", "Invoice for March")
+	assert.Equal(t, "Rechnung für März\n\n--- Translation (English) ---\n\nInvoice for March", content)
+	assert.True(t, hasTranslation(content))
+	assert.False(t, hasTranslation("Rechnung für März"))
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// triageActions lists the kinds of action a document can require
+var triageActions = []string{"payment", "reply", "signature", "other"}
+
+// triageResult holds the decision whether a document requires action
+type triageResult struct {
+	Action   string // Empty if no action is required
+	Deadline string // YYYY-MM-DD, empty if there is no deadline
+}
+
+// getSuggestedTriage decides whether the document requires action (payment, reply or signature) using the LLM
+func (app *App) getSuggestedTriage(ctx context.Context, content string, suggestedTitle string, createdDate string, logger *logrus.Entry) (triageResult, error) {
+	likelyLanguage := getDocumentLanguage(ctx)
+
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	// Get available tokens for content
+	templateData := map[string]interface{}{
+		"Language": likelyLanguage,
+		"Title":    suggestedTitle,
+		"Actions":  triageActions,
+		"Today":    getTodayDate(), // must be in YYYY-MM-DD format
+	}
+
+	addDocumentPromptData(ctx, templateData)
+	tmpl := promptFor(ctx, "triage", triageTemplate)
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, templateData)
+	if err != nil {
+		logger.Errorf("Error calculating available tokens: %v", err)
+		return triageResult{}, fmt.Errorf("error calculating available tokens: %v", err)
+	}
+
+	// Truncate content if needed
+	truncatedContent, err := app.truncateContent(ctx, content, availableTokens)
+	if err != nil {
+		logger.Errorf("Error truncating content: %v", err)
+		return triageResult{}, fmt.Errorf("error truncating content: %v", err)
+	}
+
+	// Execute template with truncated content
+	templateData["Content"] = truncatedContent
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
+		return triageResult{}, fmt.Errorf("error executing triage template: %v", err)
+	}
+
+	logger.Debugf("Triage prompt: %s", prompt.text())
+
+	completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "triage"), prompt.messages())
+	if err != nil {
+		return triageResult{}, fmt.Errorf("error getting response from LLM: %v", err)
+	}
+
+	return parseTriageResponse(prompt.complete(stripReasoning(completion.Choices[0].Content)), createdDate, time.Now(), logger)
+}
+
+// parseTriageResponse parses the JSON answer of the LLM. An implausible deadline is dropped
+// without discarding the decision itself.
+func parseTriageResponse(response string, createdDate string, now time.Time, logger *logrus.Entry) (triageResult, error) {
+	jsonObject, err := extractJSONObject(response)
+	if err != nil {
+		return triageResult{}, err
+	}
+
+	var answer struct {
+		ActionRequired bool    `json:"action_required"`
+		Action         *string `json:"action"`
+		Deadline       *string `json:"deadline"`
+	}
+	if err := json.Unmarshal([]byte(jsonObject), &answer); err != nil {
+		return triageResult{}, fmt.Errorf("error parsing triage response: %v", err)
+	}
+
+	var result triageResult
+	if !answer.ActionRequired {
+		return result, nil
+	}
+
+	result.Action = "other"
+	if answer.Action != nil {
+		if action := strings.ToLower(strings.TrimSpace(*answer.Action)); slices.Contains(triageActions, action) {
+			result.Action = action
+		}
+	}
+
+	if answer.Deadline != nil {
+		deadline, err := parseDueDate(*answer.Deadline, createdDate, now)
+		if err != nil {
+			logger.Warnf("Ignoring action deadline: %v", err)
+		} else {
+			result.Deadline = deadline
+		}
+	}
+
+	return result, nil
+}
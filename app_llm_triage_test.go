@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTriageResponse(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	logger := logrus.WithField("test", "triage")
+
+	testCases := []struct {
+		name        string
+		response    string
+		createdDate string
+		expected    triageResult
+		wantErr     bool
+	}{
+		{
+			name:     "Payment with deadline",
+			response: `{"action_required": true, "action": "Payment", "deadline": "2024-03-31"}`,
+			expected: triageResult{Action: "payment", Deadline: "2024-03-31"},
+		},
+		{
+			name:     "No action required",
+			response: `{"action_required": false, "action": null, "deadline": null}`,
+			expected: triageResult{},
+		},
+		{
+			name:     "Unknown action",
+			response: "```json\n{\"action_required\": true, \"action\": \"call back\", \"deadline\": null}\n```",
+			expected: triageResult{Action: "other"},
+		},
+		{
+			name:        "Deadline before document date is dropped",
+			response:    `{"action_required": true, "action": "signature", "deadline": "2023-01-01"}`,
+			createdDate: "2024-03-01",
+			expected:    triageResult{Action: "signature"},
+		},
+		{
+			name:     "Invalid deadline is dropped",
+			response: `{"action_required": true, "action": "reply", "deadline": "next week"}`,
+			expected: triageResult{Action: "reply"},
+		},
+		{
+			name:     "No JSON",
+			response: "The document requires payment.",
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			result, err := parseTriageResponse(tc.response, tc.createdDate, now, logger)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, result)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// backfillBatchSize is the number of documents fetched and processed at a time during a backfill
+const backfillBatchSize = 25
+
+// backfillRequest requests the suggestions a document lacks. It reports false if the document lacks nothing.
+func backfillRequest(document Document) (GenerateSuggestionsRequest, bool) {
+	request := GenerateSuggestionsRequest{
+		Documents:              []Document{document},
+		GenerateTitles:         lacksTitle(document),
+		GenerateTags:           len(document.Tags) == 0,
+		GenerateCorrespondents: document.Correspondent == "",
+	}
+	return request, request.GenerateTitles || request.GenerateTags || request.GenerateCorrespondents
+}
+
+// lacksTitle reports whether the document has no title of its own. paperless-ngx uses the file name as title of new documents.
+func lacksTitle(document Document) bool {
+	title := strings.TrimSpace(document.Title)
+	if title == "" {
+		return true
+	}
+	fileName := document.OriginalFileName
+	return fileName != "" && strings.EqualFold(title, strings.TrimSuffix(fileName, filepath.Ext(fileName)))
+}
+
+// runBackfill walks all documents matching the filter query in the order of their IDs and enriches the ones lacking
+// a title, tags or a correspondent. Progress is checkpointed after every batch, so an interrupted backfill resumes
+// where it stopped. Documents failing to be enriched are logged and skipped.
+func (app *App) runBackfill(ctx context.Context, query string) error {
+	checkpoint, err := GetBackfillCheckpoint(app.Database, query)
+	if err != nil {
+		return fmt.Errorf("error loading backfill checkpoint: %w", err)
+	}
+
+	documentIDs, err := app.Client.GetDocumentIDs(ctx, query)
+	if err != nil {
+		return err
+	}
+	slices.Sort(documentIDs)
+	remaining := slices.DeleteFunc(documentIDs, func(documentID int) bool { return documentID <= checkpoint.LastDocumentID })
+
+	if checkpoint.LastDocumentID > 0 {
+		log.Infof("Resuming backfill after document %d, %d documents were enriched so far", checkpoint.LastDocumentID, checkpoint.Enriched)
+	}
+	log.Infof("Backfill checks %d documents", len(remaining))
+
+	checked := 0
+	for batch := range slices.Chunk(remaining, backfillBatchSize) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		ids := make([]string, len(batch))
+		for i, documentID := range batch {
+			ids[i] = strconv.Itoa(documentID)
+		}
+		var documents []Document
+		for document, err := range app.Client.Documents(ctx, "id__in="+strings.Join(ids, ","), len(batch)) {
+			if err != nil {
+				return err
+			}
+			documents = append(documents, document)
+		}
+
+		enriched, errs := processDocumentsConcurrently(ctx, documents, app.backfillDocument(ctx))
+		for _, err := range errs {
+			log.Errorf("Backfill skipped a document: %v", err)
+		}
+
+		checkpoint.LastDocumentID = batch[len(batch)-1]
+		checkpoint.Enriched += enriched
+		if err := SaveBackfillCheckpoint(app.Database, checkpoint); err != nil {
+			return fmt.Errorf("error saving backfill checkpoint: %w", err)
+		}
+		checked += len(batch)
+		log.Infof("Backfill checked %d of %d documents, %d enriched in total", checked, len(remaining), checkpoint.Enriched)
+	}
+
+	log.Infof("Backfill finished, %d documents were enriched", checkpoint.Enriched)
+	return DeleteBackfillCheckpoint(app.Database, query)
+}
+
+// backfillDocument returns the processing of a single document during a backfill
+func (app *App) backfillDocument(ctx context.Context) func(document Document) (bool, error) {
+	return func(document Document) (bool, error) {
+		// Every document is enriched with the settings in effect when it is started
+		ctx := withSettings(ctx)
+		request, lacking := backfillRequest(document)
+		if !lacking {
+			return false, nil
+		}
+
+		docLogger := documentLogger(document.ID)
+		docLogger.Info("Enriching document during backfill")
+
+		suggestions, err := app.generateDocumentSuggestions(ctx, request, docLogger)
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error generating suggestions for document %d: %w", document.ID, err)
+		}
+
+		if settingsFor(ctx).AutoReview || app.needsReview(ctx, suggestions, docLogger) {
+			if err := app.queueForReview(ctx, suggestions); err != nil {
+				return false, err
+			}
+			return true, nil
+		}
+
+		documentUpdateMu.Lock()
+		err = app.Client.UpdateDocuments(ctx, suggestions, app.Database, false)
+		documentUpdateMu.Unlock()
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("error updating document %d: %w", document.ID, err)
+		}
+		return true, nil
+	}
+}
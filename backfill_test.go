@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackfillRequest(t *testing.T) {
+	_, lacking := backfillRequest(Document{Title: "Invoice 42", Tags: []string{"invoice"}, Correspondent: "ACME"})
+	assert.False(t, lacking)
+
+	request, lacking := backfillRequest(Document{Title: "SCAN_0042", OriginalFileName: "scan_0042.pdf", Tags: []string{"invoice"}})
+	assert.True(t, lacking)
+	assert.True(t, request.GenerateTitles)
+	assert.False(t, request.GenerateTags)
+	assert.True(t, request.GenerateCorrespondents)
+}
+
+func TestRunBackfill_Resume(t *testing.T) {
+	env := setupTest(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&BackfillCheckpoint{}).Error)
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "invoice"}], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 4, "name": "ACME"}], "next": null}`))
+	})
+	var listed []string
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		ids := r.URL.Query().Get("id__in")
+		if ids == "" {
+			w.Write([]byte(`{"count": 3, "all": [3, 1, 2], "results": []}`))
+			return
+		}
+		listed = append(listed, ids)
+		w.Write([]byte(`{"results": [
+			{"id": 2, "title": "Contract", "tags": [1], "correspondent": 4},
+			{"id": 3, "title": "scan_0003", "original_file_name": "scan_0003.pdf", "tags": [1], "correspondent": 4}
+		], "next": null}`))
+	})
+	var patched map[string]interface{}
+	env.setMockResponse("/api/documents/3/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &patched))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 3}`))
+	})
+
+	// Document 1 was handled before the backfill was interrupted
+	require.NoError(t, SaveBackfillCheckpoint(env.db, &BackfillCheckpoint{LastDocumentID: 1, Enriched: 1}))
+
+	app := &App{Client: env.client, Database: env.db, LLM: &mockLLM{}}
+	require.NoError(t, app.runBackfill(context.Background(), ""))
+
+	assert.Equal(t, []string{"2,3"}, listed)
+	assert.Equal(t, "test response", patched["title"])
+
+	// A finished backfill starts from the beginning next time
+	checkpoint, err := GetBackfillCheckpoint(env.db, "")
+	require.NoError(t, err)
+	assert.Zero(t, checkpoint.LastDocumentID)
+}
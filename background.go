@@ -21,9 +21,13 @@ func StartBackgroundTasks(ctx context.Context, app BackgroundProcessor) {
 	go func() {
 		minBackoffDuration := 10 * time.Second
 		maxBackoffDuration := time.Hour
-		pollingInterval := 10 * time.Second
+		pollingInterval := backgroundPollInterval
 
 		backoffDuration := minBackoffDuration
+		paused := false
+
+		// OCR and auto-tagging are polled at their own intervals, but run again right away while they find documents
+		var nextOcrRun, nextTagRun time.Time
 
 		for {
 			select {
@@ -33,28 +37,64 @@ func StartBackgroundTasks(ctx context.Context, app BackgroundProcessor) {
 			default: // needed to make this non-blocking
 			}
 
+			// Outside of the processing schedule, only check again later
+			if !processingAllowed(time.Now()) {
+				if !paused {
+					log.Infoln("Outside of the processing schedule, pausing background processing")
+					events.publish(EventProcessingIdle, nil)
+					paused = true
+				}
+				time.Sleep(pollingInterval)
+				continue
+			}
+			if paused {
+				log.Infoln("Processing schedule reached, resuming background processing")
+				paused = false
+			}
+
+			// While paused through /api/processing/pause, wait until processing is resumed
+			if err := backgroundPause.wait(ctx); err != nil {
+				continue
+			}
+
 			processedCount, err := func() (count int, err error) {
 				count = 0
 
 				// If OCR is enabled, run OCR tagging first
-				if app.isOcrEnabled() {
+				if app.isOcrEnabled() && !time.Now().Before(nextOcrRun) {
 					ocrCount, err := app.processAutoOcrTagDocuments(ctx)
 					if err != nil {
 						return 0, fmt.Errorf("error in processAutoOcrTagDocuments: %w", err)
 					}
+					if ocrCount == 0 {
+						nextOcrRun = time.Now().Add(ocrPollInterval)
+					}
 					count += ocrCount
 				}
 
 				// Run auto-tagging after OCR
-				autoCount, err := app.processAutoTagDocuments(ctx)
-				if err != nil {
-					return 0, fmt.Errorf("error in processAutoTagDocuments: %w", err)
+				if !time.Now().Before(nextTagRun) {
+					autoCount, err := app.processAutoTagDocuments(ctx)
+					if err != nil {
+						return 0, fmt.Errorf("error in processAutoTagDocuments: %w", err)
+					}
+					if autoCount == 0 {
+						nextTagRun = time.Now().Add(pollingInterval)
+					}
+					count += autoCount
 				}
-				count += autoCount
 
 				return count, nil
 			}()
 
+			if processedCount > 0 || err != nil {
+				run := map[string]any{"processed": processedCount}
+				if err != nil {
+					run["error"] = err.Error()
+				}
+				events.publish(EventProcessingRun, run)
+			}
+
 			if err != nil {
 				log.Errorf("Error in background tagging: %v", err)
 				time.Sleep(backoffDuration)
@@ -70,9 +110,15 @@ func StartBackgroundTasks(ctx context.Context, app BackgroundProcessor) {
 				backoffDuration = minBackoffDuration
 			}
 
-			// If nothing was processed, pause before next cycle
+			// If nothing was processed, pause until the next poll is due
 			if processedCount == 0 {
-				time.Sleep(pollingInterval)
+				nextRun := nextTagRun
+				if app.isOcrEnabled() && nextOcrRun.Before(nextRun) {
+					nextRun = nextOcrRun
+				}
+				if wait := time.Until(nextRun); wait > 0 {
+					time.Sleep(wait)
+				}
 			}
 		}
 	}()
@@ -80,59 +126,104 @@ func StartBackgroundTasks(ctx context.Context, app BackgroundProcessor) {
 
 // processAutoTagDocuments handles the background auto-tagging of documents
 func (app *App) processAutoTagDocuments(ctx context.Context) (int, error) {
+	// The documents of a run are processed with the settings in effect when it starts
+	ctx = withSettings(ctx)
+	settings := settingsFor(ctx)
 
-	documents, err := app.Client.GetDocumentsByTags(ctx, []string{autoTag}, 25)
+	documents, err := app.getDocumentsByTagWithPriority(ctx, settings.AutoTag, 25)
 	if err != nil {
 		return 0, fmt.Errorf("error fetching documents with autoTag: %w", err)
 	}
 
+	// Documents can also be selected by a saved view or filter query instead of tagging them
+	if autoSavedView != "" || autoDocumentQuery != "" {
+		query, err := app.documentSelectionQuery(ctx, autoSavedView, autoDocumentQuery)
+		if err != nil {
+			return 0, fmt.Errorf("error resolving document selection: %w", err)
+		}
+		query = joinQueries(query, autoProcessingCutoff.query())
+		selected, err := app.selectUnprocessedDocuments(ctx, query, 25)
+		if err != nil {
+			return 0, fmt.Errorf("error fetching documents matching %q: %w", query, err)
+		}
+		for _, document := range selected {
+			if !autoProcessingCutoff.includes(document) {
+				continue
+			}
+			if !slices.ContainsFunc(documents, func(d Document) bool { return d.ID == document.ID }) {
+				documents = append(documents, document)
+			}
+		}
+	}
+	settings.prioritizeDocuments(documents)
+
 	if len(documents) == 0 {
-		log.Debugf("No documents with tag %s found", autoTag)
+		log.Debugf("No documents with tag %s found", settings.AutoTag)
 		return 0, nil // No documents to process
 	}
 
-	log.Debugf("Found at least %d remaining documents with tag %s", len(documents), autoTag)
+	log.Debugf("Found at least %d remaining documents to process", len(documents))
 
-	var errs []error
-	processedCount := 0
-
-	for _, document := range documents {
+	processedCount, errs := processDocumentsConcurrently(ctx, documents, app.trackDocumentFailures(ctx, settings.AutoTag, func(document Document) (bool, error) {
 		// Skip documents that have the autoOcrTag
-		if slices.Contains(document.Tags, autoOcrTag) {
-			log.Debugf("Skipping document %d as it has the OCR tag %s", document.ID, autoOcrTag)
-			continue
+		if slices.Contains(document.Tags, settings.AutoOCRTag) {
+			log.Debugf("Skipping document %d as it has the OCR tag %s", document.ID, settings.AutoOCRTag)
+			return false, nil
+		}
+
+		fingerprint, skip, err := app.skipUnchangedDocument(ctx, document, JobKindSuggestions, settings.AutoTag)
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
+		if skip || err != nil {
+			return false, err
 		}
 
 		docLogger := documentLogger(document.ID)
 		docLogger.Info("Processing document for auto-tagging")
 
-		suggestionRequest := GenerateSuggestionsRequest{
-			Documents:              []Document{document},
-			GenerateTitles:         strings.ToLower(autoGenerateTitle) != "false",
-			GenerateTags:           strings.ToLower(autoGenerateTags) != "false",
-			GenerateCorrespondents: strings.ToLower(autoGenerateCorrespondents) != "false",
-			GenerateCreatedDate:    strings.ToLower(autoGenerateCreatedDate) != "false",
+		suggestions, err := app.generateDocumentSuggestions(ctx, settings.autoSuggestionRequest(document), docLogger)
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
 		}
-
-		suggestions, err := app.generateDocumentSuggestions(ctx, suggestionRequest, docLogger)
 		if err != nil {
 			err = fmt.Errorf("error generating suggestions for document %d: %w", document.ID, err)
 			docLogger.Error(err.Error())
-			errs = append(errs, err)
-			continue
+			return false, err
 		}
 
-		err = app.Client.UpdateDocuments(ctx, suggestions, app.Database, false)
-		if err != nil {
-			err = fmt.Errorf("error updating document %d: %w", document.ID, err)
-			docLogger.Error(err.Error())
-			errs = append(errs, err)
-			continue
+		// With AUTO_REVIEW the suggestions wait for a user to approve them instead of being applied right away, with
+		// AUTO_APPLY_CONFIDENCE only if the LLM isn't confident enough in them
+		review := settings.AutoReview || app.needsReview(ctx, suggestions, docLogger)
+		if review {
+			if err := app.queueForReview(ctx, suggestions); err != nil {
+				docLogger.Error(err.Error())
+				return false, err
+			}
+		} else {
+			documentUpdateMu.Lock()
+			err = app.Client.UpdateDocuments(ctx, suggestions, app.Database, false)
+			documentUpdateMu.Unlock()
+			if app.skipDeletedDocument(document.ID, err) {
+				return false, nil
+			}
+			if err != nil {
+				err = fmt.Errorf("error updating document %d: %w", document.ID, err)
+				docLogger.Error(err.Error())
+				return false, err
+			}
 		}
 
+		if err := MarkDocumentProcessed(app.Database, uint(document.ID)); err != nil {
+			docLogger.Warnf("Failed to remember document as processed: %v", err)
+		}
+		app.recordFingerprint(fingerprint)
+
 		docLogger.Info("Successfully processed document")
-		processedCount++
-	}
+		processed := settings.processedDocument(document, suggestions)
+		events.publish(EventDocumentProcessed, map[string]any{"document_id": document.ID, "title": processed.Title, "correspondent": processed.Correspondent, "tags": processed.Tags, "review": review})
+		return true, nil
+	}))
 
 	if len(errs) > 0 {
 		return processedCount, errors.Join(errs...)
@@ -141,10 +232,57 @@ func (app *App) processAutoTagDocuments(ctx context.Context) (int, error) {
 	return processedCount, nil
 }
 
+// autoSuggestionRequest requests the suggestions enabled by the AUTO_GENERATE_* settings for a document
+func (settings *runtimeSettings) autoSuggestionRequest(document Document) GenerateSuggestionsRequest {
+	return GenerateSuggestionsRequest{
+		Documents:              []Document{document},
+		GenerateTitles:         strings.ToLower(settings.AutoGenerateTitle) != "false",
+		GenerateTags:           strings.ToLower(settings.AutoGenerateTags) != "false",
+		GenerateCorrespondents: strings.ToLower(settings.AutoGenerateCorrespondents) != "false",
+		GenerateCreatedDate:    strings.ToLower(settings.AutoGenerateCreatedDate) != "false",
+		GenerateCustomFields:   strings.ToLower(settings.AutoGenerateCustomFields) == "true",
+		GenerateSummary:        strings.ToLower(settings.AutoGenerateSummary) == "true",
+		GenerateDueDate:        strings.ToLower(settings.AutoGenerateDueDate) == "true",
+		GenerateAmount:         strings.ToLower(settings.AutoGenerateAmount) == "true",
+		Translate:              strings.ToLower(settings.AutoTranslate) == "true",
+		Triage:                 strings.ToLower(settings.AutoTriage) == "true",
+		LinkRelated:            strings.ToLower(settings.AutoLinkRelatedDocuments) == "true",
+	}
+}
+
+// processedDocument returns a processed document with the suggested title, correspondent and tags, without the tags
+// removed by the processing
+func (settings *runtimeSettings) processedDocument(document Document, suggestions []DocumentSuggestion) Document {
+	for _, suggestion := range suggestions {
+		if suggestion.ID != document.ID {
+			continue
+		}
+		if suggestion.SuggestedTitle != "" {
+			document.Title = suggestion.SuggestedTitle
+		}
+		if suggestion.SuggestedCorrespondent != "" {
+			document.Correspondent = suggestion.SuggestedCorrespondent
+		} else if suggestion.RemoveCorrespondent {
+			document.Correspondent = ""
+		}
+		tags := document.Tags
+		if len(suggestion.SuggestedTags) > 0 {
+			tags = suggestion.SuggestedTags
+		}
+		document.Tags = slices.DeleteFunc(slices.Clone(tags), func(tag string) bool {
+			return tag == settings.AutoTag || slices.Contains(suggestion.RemoveTags, tag)
+		})
+	}
+	return document
+}
+
 // processAutoOcrTagDocuments handles the background auto-tagging of OCR documents
 func (app *App) processAutoOcrTagDocuments(ctx context.Context) (int, error) {
+	// The documents of a run are processed with the settings in effect when it starts
+	ctx = withSettings(ctx)
+	autoOcrTag := settingsFor(ctx).AutoOCRTag
 
-	documents, err := app.Client.GetDocumentsByTags(ctx, []string{autoOcrTag}, 25)
+	documents, err := app.getDocumentsByTagWithPriority(ctx, autoOcrTag, 25)
 	if err != nil {
 		return 0, fmt.Errorf("error fetching documents with autoOcrTag: %w", err)
 	}
@@ -156,38 +294,45 @@ func (app *App) processAutoOcrTagDocuments(ctx context.Context) (int, error) {
 
 	log.Debugf("Found %d documents with tag %s", len(documents), autoOcrTag)
 
-	successCount := 0
-	var errs []error
+	successCount, errs := processDocumentsConcurrently(ctx, documents, app.trackDocumentFailures(ctx, autoOcrTag, func(document Document) (bool, error) {
+		fingerprint, skip, err := app.skipUnchangedDocument(ctx, document, JobKindOCR, autoOcrTag)
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
+		if skip || err != nil {
+			return false, err
+		}
 
-	for _, document := range documents {
 		docLogger := documentLogger(document.ID)
 		docLogger.Info("Processing document for OCR")
 
-		ocrContent, err := app.ProcessDocumentOCR(ctx, document.ID)
+		ocrResult, err := app.ProcessDocumentOCR(ctx, document.ID)
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
 		if err != nil {
 			docLogger.Errorf("OCR processing failed: %v", err)
-			errs = append(errs, fmt.Errorf("document %d OCR error: %w", document.ID, err))
-			continue
+			return false, fmt.Errorf("document %d OCR error: %w", document.ID, err)
+		}
+		if len(ocrResult.JunkPages) > 0 {
+			docLogger.Infof("Junk pages found during OCR: %v", ocrResult.JunkPages)
 		}
 		docLogger.Debug("OCR processing completed")
 
-		err = app.Client.UpdateDocuments(ctx, []DocumentSuggestion{
-			{
-				ID:               document.ID,
-				OriginalDocument: document,
-				SuggestedContent: ocrContent,
-				RemoveTags:       []string{autoOcrTag},
-			},
-		}, app.Database, false)
+		err = app.applyOCRResult(ctx, document, ocrResult, []string{autoOcrTag})
+		if app.skipDeletedDocument(document.ID, err) {
+			return false, nil
+		}
 		if err != nil {
-			docLogger.Errorf("Update after OCR failed: %v", err)
-			errs = append(errs, fmt.Errorf("document %d update error: %w", document.ID, err))
-			continue
+			docLogger.Error(err.Error())
+			return false, fmt.Errorf("document %d: %w", document.ID, err)
 		}
 
+		app.recordFingerprint(fingerprint)
 		docLogger.Info("Successfully processed document OCR")
-		successCount++
-	}
+		events.publish(EventOCRFinished, map[string]any{"document_id": document.ID, "title": document.Title, "pages": len(ocrResult.PageTexts)})
+		return true, nil
+	}))
 
 	if len(errs) > 0 {
 		return successCount, fmt.Errorf("one or more errors occurred: %w", errors.Join(errs...))
@@ -195,3 +340,37 @@ func (app *App) processAutoOcrTagDocuments(ctx context.Context) (int, error) {
 
 	return successCount, nil
 }
+
+// applyOCRResult updates a document with the text and the ASN found by OCR and removes the tags. The page of the ASN
+// barcode is removed afterwards if ASN_BARCODE_REMOVE_PAGE is set.
+func (app *App) applyOCRResult(ctx context.Context, document Document, ocrResult *DocumentOCRResult, removeTags []string) error {
+	// Checking the ASN is part of the update, so concurrently processed documents can't claim the same ASN
+	documentUpdateMu.Lock()
+	asn, err := app.assignableASN(ctx, document, ocrResult.ASN)
+	if err != nil {
+		documentUpdateMu.Unlock()
+		return fmt.Errorf("ASN check failed: %w", err)
+	}
+
+	err = app.Client.UpdateDocuments(ctx, []DocumentSuggestion{
+		{
+			ID:               document.ID,
+			OriginalDocument: document,
+			SuggestedContent: ocrResult.Text,
+			SuggestedASN:     asn,
+			RemoveTags:       removeTags,
+		},
+	}, app.Database, false)
+	documentUpdateMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("update after OCR failed: %w", err)
+	}
+
+	// Removing the page makes paperless-ngx create a new archived version, so it is done after the update
+	if ocrResult.ASNPage > 0 && asnBarcodeRemovePage {
+		if err := app.Client.DeleteDocumentPages(ctx, document.ID, []int{ocrResult.ASNPage}); err != nil {
+			return fmt.Errorf("removing ASN barcode page %d failed: %w", ocrResult.ASNPage, err)
+		}
+	}
+	return nil
+}
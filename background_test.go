@@ -53,12 +53,13 @@ func setupTest(t *testing.T) *testEnv {
 
 // Setup a single Test-Case
 func setupTestCase(tc TestCase, env *testEnv) {
+	settings := loadSettings()
 	// Mock the GetAllTags response
 	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
 		response := map[string]interface{}{
 			"results": []map[string]interface{}{
-				{"id": 1, "name": autoTag},
-				{"id": 2, "name": autoOcrTag},
+				{"id": 1, "name": settings.AutoTag},
+				{"id": 2, "name": settings.AutoOCRTag},
 				{"id": 3, "name": "other-tag"},
 			},
 		}
@@ -75,9 +76,9 @@ func setupTestCase(tc TestCase, env *testEnv) {
 			tagIds := make([]int, len(doc.Tags))
 			for j, tagName := range doc.Tags {
 				switch tagName {
-				case autoTag:
+				case settings.AutoTag:
 					tagIds[j] = 1
-				case autoOcrTag:
+				case settings.AutoOCRTag:
 					tagIds[j] = 2
 				default:
 					tagIds[j] = 3
@@ -148,9 +149,11 @@ func TestBackgroundTasks_ShutdownOnContextCancel(t *testing.T) {
 
 // Test processAutoTagDocument (OCR tag is used to test skipping)
 func TestProcessAutoTagDocuments(t *testing.T) {
-	// Initialize required global variables
-	autoTag = "paperless-gpt-auto"
-	autoOcrTag = "paperless-gpt-ocr-auto"
+	// Initialize required settings
+	autoTag, autoOcrTag := "paperless-gpt-auto", "paperless-gpt-ocr-auto"
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.AutoTag, settings.AutoOCRTag = autoTag, autoOcrTag
+	})
 
 	// Set up test cases
 	testCases := []TestCase{
@@ -199,6 +202,7 @@ func TestProcessAutoTagDocuments(t *testing.T) {
 	// Setup the Test
 	env := setupTest(t)
 	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&DocumentFingerprint{}).Error)
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -219,10 +223,12 @@ func TestProcessAutoTagDocuments(t *testing.T) {
 			}
 
 			// Set auto-generate flags
-			autoGenerateTitle = "true"
-			autoGenerateTags = "true"
-			autoGenerateCorrespondents = "true"
-			autoGenerateCreatedDate = "true"
+			setSettings(t, func(settings *runtimeSettings) {
+				settings.AutoGenerateTitle = "true"
+				settings.AutoGenerateTags = "true"
+				settings.AutoGenerateCorrespondents = "true"
+				settings.AutoGenerateCreatedDate = "true"
+			})
 
 			// Handle the invidual documents
 			for _, doc := range tc.documents {
@@ -252,6 +258,13 @@ func TestProcessAutoTagDocuments(t *testing.T) {
 						})
 					})
 
+					// GET /api/documents/{id}/metadata/ for the fingerprint
+					env.setMockResponse(updatePath+"metadata/", func(w http.ResponseWriter, r *http.Request) {
+						w.WriteHeader(http.StatusOK)
+						_ = json.NewEncoder(w).Encode(map[string]interface{}{
+							"original_checksum": fmt.Sprintf("checksum-%d", docID),
+						})
+					})
 				}(doc.ID, doc.Title, doc.FailUpdate, tc.updateResponse)
 			}
 
@@ -269,3 +282,26 @@ func TestProcessAutoTagDocuments(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessedDocument(t *testing.T) {
+	settings := &runtimeSettings{AutoTag: "paperless-gpt-auto"}
+	autoTag := settings.AutoTag
+	document := Document{ID: 544, Title: "scan_001", Correspondent: "Unknown", Tags: []string{autoTag, "inbox"}}
+
+	processed := settings.processedDocument(document, []DocumentSuggestion{{
+		ID:                     544,
+		SuggestedTitle:         "Invoice ACME",
+		SuggestedCorrespondent: "ACME",
+		SuggestedTags:          []string{"invoice", "inbox", autoTag},
+		RemoveTags:             []string{"inbox"},
+	}})
+	assert.Equal(t, "Invoice ACME", processed.Title)
+	assert.Equal(t, "ACME", processed.Correspondent)
+	assert.Equal(t, []string{"invoice"}, processed.Tags)
+	assert.Equal(t, []string{autoTag, "inbox"}, document.Tags, "the tags of the document aren't changed")
+
+	// Without suggestions the document is unchanged, except for the tags removed by the processing
+	processed = settings.processedDocument(document, []DocumentSuggestion{{ID: 544}})
+	assert.Equal(t, "scan_001", processed.Title)
+	assert.Equal(t, []string{"inbox"}, processed.Tags)
+}
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// backupCheckInterval is the interval in which the backups are checked for being older than BACKUP_INTERVAL_HOURS
+const backupCheckInterval = time.Hour
+
+// backupVersion is the version of the backup archive format
+const backupVersion = 1
+
+// maxBackupSize is the largest backup archive accepted by POST /api/admin/restore
+const maxBackupSize = 1 << 30
+
+const (
+	backupNamePrefix = "paperless-gpt-backup-"
+	backupNameSuffix = ".zip"
+	backupTimeFormat = "20060102T150405Z"
+)
+
+// errBackupPathMissing is returned when backups are stored without BACKUP_PATH
+var errBackupPathMissing = errors.New("BACKUP_PATH is not set")
+
+// errInvalidBackup is returned for backup archives that can't be restored
+var errInvalidBackup = errors.New("invalid backup")
+
+// backupMutex keeps backups and restores from running at the same time
+var backupMutex sync.Mutex
+
+// BackupInfo describes a stored backup
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BackupManifest is the manifest.json of a backup archive
+type BackupManifest struct {
+	Version   int            `json:"version"`
+	CreatedAt time.Time      `json:"created_at"`
+	Migration string         `json:"migration"` // Latest schema migration applied to the database
+	Tables    map[string]int `json:"tables"`    // Number of rows by table
+}
+
+// backupStore stores the backup archives, in a directory or an S3 bucket
+type backupStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	List(ctx context.Context) ([]BackupInfo, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// newBackupStore returns the store of BACKUP_PATH, s3://bucket/prefix for an S3 bucket or the path of a directory
+func newBackupStore(target string) (backupStore, error) {
+	if target == "" {
+		return nil, errBackupPathMissing
+	}
+	if location, ok := strings.CutPrefix(target, "s3://"); ok {
+		return newS3BackupStore(location)
+	}
+	return dirBackupStore{dir: target}, nil
+}
+
+// backupName returns the name of the backup created at the time
+func backupName(createdAt time.Time) string {
+	return backupNamePrefix + createdAt.UTC().Format(backupTimeFormat) + backupNameSuffix
+}
+
+// parseBackupName returns the time the backup with the name was created. It reports false for other names, so
+// other files next to the backups are left alone and restores can't reach outside of the store.
+func parseBackupName(name string) (time.Time, bool) {
+	stamp, ok := strings.CutPrefix(name, backupNamePrefix)
+	if !ok {
+		return time.Time{}, false
+	}
+	stamp, ok = strings.CutSuffix(stamp, backupNameSuffix)
+	if !ok {
+		return time.Time{}, false
+	}
+	createdAt, err := time.Parse(backupTimeFormat, stamp)
+	return createdAt, err == nil
+}
+
+// dirBackupStore stores the backups in a directory
+type dirBackupStore struct {
+	dir string
+}
+
+func (store dirBackupStore) Put(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(store.dir, os.ModePerm); err != nil {
+		return err
+	}
+	// Written to a temporary file first, so an interrupted backup doesn't look complete
+	temp := filepath.Join(store.dir, "."+name+".tmp")
+	if err := os.WriteFile(temp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(temp, filepath.Join(store.dir, name))
+}
+
+func (store dirBackupStore) Get(_ context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(store.dir, name))
+}
+
+func (store dirBackupStore) List(_ context.Context) ([]BackupInfo, error) {
+	entries, err := os.ReadDir(store.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []BackupInfo{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	backups := []BackupInfo{}
+	for _, entry := range entries {
+		createdAt, ok := parseBackupName(entry.Name())
+		if !ok || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, BackupInfo{Name: entry.Name(), Size: info.Size(), CreatedAt: createdAt})
+	}
+	return backups, nil
+}
+
+func (store dirBackupStore) Delete(_ context.Context, name string) error {
+	return os.Remove(filepath.Join(store.dir, name))
+}
+
+// StartBackupScheduler backs up the database to BACKUP_PATH once the newest backup is older than
+// BACKUP_INTERVAL_HOURS. It keeps running while backups are disabled, so they can be enabled at runtime.
+func StartBackupScheduler(ctx context.Context, app *App) {
+	go func() {
+		ticker := time.NewTicker(backupCheckInterval)
+		defer ticker.Stop()
+		for {
+			check := withSettings(ctx)
+			if settingsFor(check).BackupIntervalHours > 0 {
+				if err := app.backupIfDue(check, time.Now()); err != nil {
+					log.Errorf("Error backing up the database: %v", err)
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Infoln("Backup scheduler shutting down")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// backupIfDue backs up the database if there is no backup younger than BACKUP_INTERVAL_HOURS
+func (app *App) backupIfDue(ctx context.Context, now time.Time) error {
+	store, err := newBackupStore(backupPath)
+	if err != nil {
+		return err
+	}
+	backups, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+	for _, backup := range backups {
+		if now.Sub(backup.CreatedAt) < time.Duration(settingsFor(ctx).BackupIntervalHours)*time.Hour {
+			return nil
+		}
+	}
+	_, err = app.backup(ctx, store, now)
+	return err
+}
+
+// backup stores a backup of the database and the prompts and deletes all but the newest BACKUP_KEEP backups
+func (app *App) backup(ctx context.Context, store backupStore, now time.Time) (BackupInfo, error) {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	data, err := createBackup(app.Database, now)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	info := BackupInfo{Name: backupName(now), Size: int64(len(data)), CreatedAt: now.UTC().Truncate(time.Second)}
+	if err := store.Put(ctx, info.Name, data); err != nil {
+		return BackupInfo{}, fmt.Errorf("failed to store backup %s: %w", info.Name, err)
+	}
+	log.Infof("Backed up the database to %s", info.Name)
+
+	if err := rotateBackups(ctx, store, settingsFor(ctx).BackupKeep); err != nil {
+		return info, fmt.Errorf("failed to delete old backups: %w", err)
+	}
+	return info, nil
+}
+
+// rotateBackups deletes all but the newest keep backups, 0 keeps all of them
+func rotateBackups(ctx context.Context, store backupStore, keep int) error {
+	if keep <= 0 {
+		return nil
+	}
+	backups, err := store.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.After(backups[j].CreatedAt) })
+	for _, backup := range backups[min(keep, len(backups)):] {
+		if err := store.Delete(ctx, backup.Name); err != nil {
+			return err
+		}
+		log.Infof("Deleted old backup %s", backup.Name)
+	}
+	return nil
+}
+
+// createBackup returns a zip archive with the rows of all tables, the prompts and a manifest. The archive is
+// encrypted with DATABASE_ENCRYPTION_KEY if it is set, as it holds the document text of the encrypted columns.
+func createBackup(db *gorm.DB, now time.Time) ([]byte, error) {
+	manifest := BackupManifest{Version: backupVersion, CreatedAt: now.UTC(), Tables: map[string]int{}}
+	var migrations []string
+	if err := db.Model(&SchemaMigration{}).Order("id desc").Limit(1).Pluck("id", &migrations).Error; err != nil {
+		return nil, err
+	}
+	if len(migrations) > 0 {
+		manifest.Migration = migrations[0]
+	}
+
+	var archive bytes.Buffer
+	writer := zip.NewWriter(&archive)
+	for _, model := range databaseModels() {
+		table, err := modelTable(db, model)
+		if err != nil {
+			return nil, err
+		}
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+		if err := db.Model(model).Find(rows.Interface()).Error; err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", table, err)
+		}
+		file, err := writer.Create("tables/" + table + ".gob")
+		if err != nil {
+			return nil, err
+		}
+		if err := gob.NewEncoder(file).Encode(rows.Interface()); err != nil {
+			return nil, fmt.Errorf("failed to encode %s: %w", table, err)
+		}
+		manifest.Tables[table] = rows.Elem().Len()
+	}
+
+	templateMutex.RLock()
+	prompts, err := exportPrompts()
+	templateMutex.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompts: %w", err)
+	}
+	if err := writeBackupJSON(writer, "prompts.json", prompts); err != nil {
+		return nil, err
+	}
+	if err := writeBackupJSON(writer, "manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	if databaseCipher == nil {
+		return archive.Bytes(), nil
+	}
+	sealed, err := sealBytes(archive.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(encryptedValuePrefix), sealed...), nil
+}
+
+// restoreBackup replaces the rows of all tables and the prompts with the ones of a backup archive
+func restoreBackup(db *gorm.DB, data []byte) (BackupManifest, error) {
+	backupMutex.Lock()
+	defer backupMutex.Unlock()
+
+	if sealed, ok := bytes.CutPrefix(data, []byte(encryptedValuePrefix)); ok {
+		var err error
+		if data, err = openBytes(sealed); err != nil {
+			return BackupManifest{}, fmt.Errorf("%w: %v", errInvalidBackup, err)
+		}
+	}
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return BackupManifest{}, fmt.Errorf("%w: not a zip archive: %v", errInvalidBackup, err)
+	}
+
+	var manifest BackupManifest
+	if err := readBackupJSON(archive, "manifest.json", &manifest); err != nil {
+		return BackupManifest{}, err
+	}
+	if manifest.Version > backupVersion {
+		return BackupManifest{}, fmt.Errorf("%w: unsupported backup version %d", errInvalidBackup, manifest.Version)
+	}
+	if manifest.Migration != "" && !knownMigration(manifest.Migration) {
+		return BackupManifest{}, fmt.Errorf("%w: the backup is from a newer version of paperless-gpt with migration %s", errInvalidBackup, manifest.Migration)
+	}
+	var prompts PromptBundle
+	if err := readBackupJSON(archive, "prompts.json", &prompts); err != nil {
+		return BackupManifest{}, err
+	}
+	templates, _, problems := parsePromptBundle(prompts)
+	if len(problems) > 0 {
+		return BackupManifest{}, fmt.Errorf("%w: invalid prompts %v", errInvalidBackup, problems)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range databaseModels() {
+			if err := restoreTable(tx, archive, model); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return BackupManifest{}, err
+	}
+
+	if _, err := importPrompts(prompts, templates); err != nil {
+		return manifest, fmt.Errorf("failed to restore prompts: %w", err)
+	}
+	log.Infof("Restored the backup of %s", manifest.CreatedAt.Format(time.RFC3339))
+	return manifest, nil
+}
+
+// restoreTable replaces the rows of the table of the model with the ones in the archive. Tables missing from the
+// archive, added after the backup was made, are left empty.
+func restoreTable(tx *gorm.DB, archive *zip.Reader, model interface{}) error {
+	table, err := modelTable(tx, model)
+	if err != nil {
+		return err
+	}
+	if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error; err != nil {
+		return fmt.Errorf("failed to clear %s: %w", table, err)
+	}
+
+	file, err := archive.Open("tables/" + table + ".gob")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+	rows := reflect.New(reflect.SliceOf(reflect.TypeOf(model).Elem()))
+	if err := gob.NewDecoder(file).Decode(rows.Interface()); err != nil {
+		return fmt.Errorf("%w: failed to decode %s: %v", errInvalidBackup, table, err)
+	}
+	// Rows are created one at a time, batches of rows with and without defaults don't work with SQLite
+	for i := 0; i < rows.Elem().Len(); i++ {
+		if err := tx.Create(rows.Elem().Index(i).Addr().Interface()).Error; err != nil {
+			return fmt.Errorf("failed to restore %s: %w", table, err)
+		}
+	}
+
+	// PostgreSQL sequences don't advance when rows are created with their IDs
+	if tx.Dialector.Name() == "postgres" {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		if primary := stmt.Schema.PrioritizedPrimaryField; primary != nil && primary.AutoIncrement {
+			err := tx.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+				table, primary.DBName, stmt.Quote(primary.DBName), stmt.Quote(table))).Error
+			if err != nil {
+				return fmt.Errorf("failed to reset the sequence of %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// modelTable returns the name of the table of a model
+func modelTable(db *gorm.DB, model interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// knownMigration reports whether the migration is one of schemaMigrations
+func knownMigration(id string) bool {
+	for _, migration := range schemaMigrations {
+		if migration.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func writeBackupJSON(writer *zip.Writer, name string, value interface{}) error {
+	file, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+func readBackupJSON(archive *zip.Reader, name string, value interface{}) error {
+	file, err := archive.Open(name)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errInvalidBackup, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, value); err != nil {
+		return fmt.Errorf("%w: invalid %s: %v", errInvalidBackup, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// s3BackupStore stores the backups in an S3 bucket, or a bucket of an S3 compatible service like MinIO. Requests are
+// signed with AWS Signature Version 4 and address the bucket in the path, which all of these services support.
+type s3BackupStore struct {
+	client       *http.Client
+	endpoint     *url.URL
+	bucket       string
+	prefix       string // Prefix of the object keys of the backups, empty or ending in a slash
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// newS3BackupStore returns the store of an S3 location, the bucket optionally followed by a slash and a prefix
+func newS3BackupStore(location string) (*s3BackupStore, error) {
+	bucket, prefix, _ := strings.Cut(location, "/")
+	if bucket == "" {
+		return nil, errors.New("BACKUP_PATH is missing the bucket, expected s3://bucket/prefix")
+	}
+	if awsAccessKeyID == "" || awsSecretAccessKey == "" {
+		return nil, errors.New("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are required for backups to S3")
+	}
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	region := backupS3Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint := backupS3Endpoint
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpointURL, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil || endpointURL.Host == "" {
+		return nil, fmt.Errorf("invalid BACKUP_S3_ENDPOINT %q", endpoint)
+	}
+
+	return &s3BackupStore{
+		client:       &http.Client{Timeout: 10 * time.Minute},
+		endpoint:     endpointURL,
+		bucket:       bucket,
+		prefix:       prefix,
+		region:       region,
+		accessKey:    awsAccessKeyID,
+		secretKey:    awsSecretAccessKey,
+		sessionToken: awsSessionToken,
+	}, nil
+}
+
+func (store *s3BackupStore) Put(ctx context.Context, name string, data []byte) error {
+	response, err := store.do(ctx, http.MethodPut, store.prefix+name, nil, data)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+func (store *s3BackupStore) Get(ctx context.Context, name string) ([]byte, error) {
+	response, err := store.do(ctx, http.MethodGet, store.prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	return io.ReadAll(response.Body)
+}
+
+func (store *s3BackupStore) Delete(ctx context.Context, name string) error {
+	response, err := store.do(ctx, http.MethodDelete, store.prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// s3ListResult is the response of ListObjectsV2
+type s3ListResult struct {
+	Contents []struct {
+		Key  string `xml:"Key"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+func (store *s3BackupStore) List(ctx context.Context) ([]BackupInfo, error) {
+	backups := []BackupInfo{}
+	query := url.Values{"list-type": {"2"}, "prefix": {store.prefix + backupNamePrefix}}
+	for {
+		response, err := store.do(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return nil, err
+		}
+		var result s3ListResult
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("invalid response listing the backups: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, store.prefix)
+			if createdAt, ok := parseBackupName(name); ok {
+				backups = append(backups, BackupInfo{Name: name, Size: object.Size, CreatedAt: createdAt})
+			}
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return backups, nil
+		}
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// do sends a signed request for an object of the bucket, or for the bucket itself with an empty key. Responses with
+// an error status are returned as error.
+func (store *s3BackupStore) do(ctx context.Context, method, key string, query url.Values, body []byte) (*http.Response, error) {
+	target := *store.endpoint
+	target.Path = store.endpoint.Path + "/" + store.bucket
+	if key != "" {
+		target.Path += "/" + key
+	}
+	target.RawQuery = s3CanonicalQuery(query)
+
+	request, err := http.NewRequestWithContext(ctx, method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	store.sign(request, body, time.Now())
+
+	response, err := store.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= http.StatusMultipleChoices {
+		defer response.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return nil, fmt.Errorf("S3 %s %s failed with status %d: %s", method, target.Path, response.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return response, nil
+}
+
+// sign adds the headers of AWS Signature Version 4 to a request with the payload
+func (store *s3BackupStore) sign(request *http.Request, payload []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	payloadHash := sha256Hex(payload)
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if store.sessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", store.sessionToken)
+	}
+
+	headers := map[string]string{"host": request.URL.Host}
+	for name := range request.Header {
+		if lower := strings.ToLower(name); strings.HasPrefix(lower, "x-amz-") {
+			headers[lower] = strings.TrimSpace(request.Header.Get(name))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := request.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		s3CanonicalQuery(request.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + store.region + "/s3/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+	key := hmacSHA256([]byte("AWS4"+store.secretKey), date)
+	key = hmacSHA256(key, store.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		store.accessKey, scope, signedHeaders, signature))
+}
+
+// s3CanonicalQuery encodes the query sorted by key with spaces as %20, as required by Signature Version 4
+func s3CanonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// normalizeBasePath turns the BASE_PATH of a reverse proxy like "paperless-gpt/" into the route prefix "/paperless-gpt",
+// the empty string serves paperless-gpt at the root
+func normalizeBasePath(text string) (string, error) {
+	trimmed := strings.Trim(strings.TrimSpace(text), "/")
+	if trimmed == "" {
+		return "", nil
+	}
+	for _, segment := range strings.Split(trimmed, "/") {
+		if segment == "" || segment == "." || segment == ".." || strings.ContainsAny(segment, "?#:*\\ \"<>") {
+			return "", fmt.Errorf("%q is not a path like /paperless-gpt", text)
+		}
+	}
+	return "/" + trimmed, nil
+}
+
+// injectBaseElement adds a <base> element pointing to the base path to the head of the web UI, so the relative URLs of
+// its assets and API calls resolve under the base path on every page
+func injectBaseElement(page []byte, basePath string) []byte {
+	element := []byte(`<base href="` + html.EscapeString(basePath+"/") + `" />`)
+	index := bytes.Index(page, []byte("<head>"))
+	if index < 0 {
+		return append(element, page...)
+	}
+	index += len("<head>")
+	injected := make([]byte, 0, len(page)+len(element))
+	injected = append(injected, page[:index]...)
+	injected = append(injected, element...)
+	return append(injected, page[index:]...)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBasePath(t *testing.T) {
+	testCases := map[string]string{
+		"":                   "",
+		"/":                  "",
+		"paperless-gpt":      "/paperless-gpt",
+		"/paperless-gpt/":    "/paperless-gpt",
+		" /tools/ai-tagger ": "/tools/ai-tagger",
+	}
+	for input, want := range testCases {
+		got, err := normalizeBasePath(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	for _, invalid := range []string{"https://host/paperless-gpt", "/a//b", "/../etc", "/a?b", "/with space"} {
+		_, err := normalizeBasePath(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestInjectBaseElement(t *testing.T) {
+	page := injectBaseElement([]byte(`<html><head><title>Paperless GPT</title></head></html>`), "/paperless-gpt")
+	assert.Equal(t, `<html><head><base href="/paperless-gpt/" /><title>Paperless GPT</title></head></html>`, string(page))
+
+	page = injectBaseElement([]byte(`<html><head></head></html>`), "")
+	assert.Contains(t, string(page), `<base href="/" />`)
+}
+
+func TestWebUIRoutesUnderBasePath(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	root := router.Group("/paperless-gpt")
+	registerWebUIRoutes(root, "/paperless-gpt", nil)
+	root.Group("/api").GET("/tags", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/paperless-gpt/", "/paperless-gpt/history"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		require.Equal(t, http.StatusOK, w.Code, path)
+		assert.Contains(t, w.Body.String(), `<base href="/paperless-gpt/" />`, path)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/paperless-gpt/api/tags", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// The bare prefix redirects to the web UI
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/paperless-gpt", nil))
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/paperless-gpt/", w.Header().Get("Location"))
+
+	// Nothing is served outside of the prefix
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/history", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// batchDocumentQuery converts the filters of a batch to a paperless-ngx filter query. It reports false if the tag or
+// correspondent doesn't exist, so no document can match.
+func (app *App) batchDocumentQuery(ctx context.Context, request GenerateSuggestionsBatchRequest) (string, bool, error) {
+	if request.Tag == "" && request.Correspondent == "" && request.SavedView == "" && request.Query == "" &&
+		request.CreatedAfter == "" && request.CreatedBefore == "" {
+		return "", false, fmt.Errorf("at least one of tag, correspondent, saved_view, query, created_after or created_before is required")
+	}
+
+	dates := url.Values{}
+	for name, value := range map[string]string{"created__date__gte": request.CreatedAfter, "created__date__lte": request.CreatedBefore} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return "", false, fmt.Errorf("%q is not a date like 2024-12-31", value)
+		}
+		dates.Set(name, value)
+	}
+
+	selection := ""
+	if request.SavedView != "" || request.Query != "" {
+		var err error
+		selection, err = app.documentSelectionQuery(ctx, request.SavedView, request.Query)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	options := documentListOptions{Correspondent: strings.TrimSpace(request.Correspondent)}
+	var tagIDs map[string]int
+	if tag := strings.TrimSpace(request.Tag); tag != "" {
+		options.Tags = []string{tag}
+		var err error
+		tagIDs, err = app.Client.GetAllTags(ctx)
+		if err != nil {
+			return "", false, err
+		}
+	}
+	filters, matchesAny := options.filterQuery(tagIDs)
+	return joinQueries(selection, filters, dates.Encode()), matchesAny, nil
+}
+
+// queueBatchSuggestions queues a generate job with the options for each document matching the filter query.
+// Documents that already have an unfinished generate job are skipped.
+func (app *App) queueBatchSuggestions(ctx context.Context, query string, options GenerateSuggestionsRequest) ([]*Job, error) {
+	documentIDs, err := app.Client.GetDocumentIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	queued := map[int]bool{}
+	for _, job := range jobStore.GetAllJobs() {
+		if job.Kind == JobKindGenerate && (job.Status == "pending" || job.Status == "in_progress") {
+			queued[job.DocumentID] = true
+		}
+	}
+
+	jobs := []*Job{}
+	for _, documentID := range documentIDs {
+		if queued[documentID] {
+			continue
+		}
+		job, err := newDocumentGenerateJob(options, documentID)
+		if err != nil {
+			return nil, err
+		}
+		job.User = requestUser(ctx)
+		jobs = append(jobs, job)
+	}
+	enqueueJobs(jobs)
+	return jobs, nil
+}
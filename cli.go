@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// cliOperation is a one-off operation run instead of the server, writing its result to out
+type cliOperation func(ctx context.Context, app *App, out io.Writer) error
+
+// cliCommand is a subcommand of paperless-gpt, e.g. paperless-gpt process --id 544. parse parses the flags of the
+// command before anything is initialized, so mistakes are reported right away.
+type cliCommand struct {
+	name        string
+	description string
+	parse       func(flags *flag.FlagSet, args []string) (cliOperation, error)
+}
+
+// cliCommands are the subcommands of paperless-gpt
+var cliCommands = []cliCommand{
+	{name: "process", description: "Generate suggestions for a document and apply them", parse: parseProcessCommand},
+	{name: "ocr", description: "Run OCR on a document and store the text", parse: parseOCRCommand},
+	{name: "prompt-test", description: "Render a prompt template for a document or sample data and try it on the LLM", parse: parsePromptTestCommand},
+	{name: "check", description: "Check paperless-ngx, the database, the models and the OCR provider", parse: parseCheckCommand},
+}
+
+// suggestionTasks sets the suggestions of a request by the task names of the process command
+var suggestionTasks = map[string]func(request *GenerateSuggestionsRequest){
+	"title":         func(request *GenerateSuggestionsRequest) { request.GenerateTitles = true },
+	"tags":          func(request *GenerateSuggestionsRequest) { request.GenerateTags = true },
+	"correspondent": func(request *GenerateSuggestionsRequest) { request.GenerateCorrespondents = true },
+	"created_date":  func(request *GenerateSuggestionsRequest) { request.GenerateCreatedDate = true },
+	"custom_fields": func(request *GenerateSuggestionsRequest) { request.GenerateCustomFields = true },
+	"language":      func(request *GenerateSuggestionsRequest) { request.DetectLanguage = true },
+	"summary":       func(request *GenerateSuggestionsRequest) { request.GenerateSummary = true },
+	"due_date":      func(request *GenerateSuggestionsRequest) { request.GenerateDueDate = true },
+	"amount":        func(request *GenerateSuggestionsRequest) { request.GenerateAmount = true },
+	"pii":           func(request *GenerateSuggestionsRequest) { request.ScanPII = true },
+	"sensitivity":   func(request *GenerateSuggestionsRequest) { request.ClassifySensitivity = true },
+	"translation":   func(request *GenerateSuggestionsRequest) { request.Translate = true },
+	"triage":        func(request *GenerateSuggestionsRequest) { request.Triage = true },
+	"related":       func(request *GenerateSuggestionsRequest) { request.LinkRelated = true },
+}
+
+// parseCommand parses the subcommand and its flags left after the global flags. It returns nil without a subcommand,
+// when the server is run.
+func parseCommand(args []string) (cliOperation, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	index := slices.IndexFunc(cliCommands, func(command cliCommand) bool { return command.name == args[0] })
+	if index < 0 {
+		return nil, fmt.Errorf("unknown command %s, the commands are:\n%s", args[0], commandList())
+	}
+	command := cliCommands[index]
+	flags := flag.NewFlagSet(command.name, flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage of %s %s: %s\n", filepath.Base(os.Args[0]), command.name, command.description)
+		flags.PrintDefaults()
+	}
+	return command.parse(flags, args[1:])
+}
+
+// commandList lists the subcommands with their descriptions, one per line
+func commandList() string {
+	var lines []string
+	for _, command := range cliCommands {
+		lines = append(lines, fmt.Sprintf("  %-12s %s", command.name, command.description))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseDocumentFlags parses the flags of a command with a required --id of a document
+func parseDocumentFlags(flags *flag.FlagSet, args []string, documentID *int) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	if *documentID <= 0 {
+		return fmt.Errorf("--id of the document is required")
+	}
+	return nil
+}
+
+// parseProcessCommand parses paperless-gpt process --id <document> [--tasks title,tags] [--dry-run]. Without --tasks
+// the suggestions enabled by the AUTO_GENERATE_* settings are generated.
+func parseProcessCommand(flags *flag.FlagSet, args []string) (cliOperation, error) {
+	documentID := flags.Int("id", 0, "ID of the document")
+	tasks := flags.String("tasks", "", "Comma-separated suggestions to generate: "+strings.Join(slices.Sorted(maps.Keys(suggestionTasks)), ", ")+" (default: the AUTO_GENERATE_* settings)")
+	dryRun := flags.Bool("dry-run", false, "Print the suggestions without applying them")
+	if err := parseDocumentFlags(flags, args, documentID); err != nil {
+		return nil, err
+	}
+	for _, task := range splitAndTrim(*tasks) {
+		if _, ok := suggestionTasks[task]; !ok {
+			return nil, fmt.Errorf("unknown task %s, the tasks are: %s", task, strings.Join(slices.Sorted(maps.Keys(suggestionTasks)), ", "))
+		}
+	}
+
+	return func(ctx context.Context, app *App, out io.Writer) error {
+		document, err := app.Client.GetDocument(ctx, *documentID)
+		if err != nil {
+			return err
+		}
+		request := settingsFor(ctx).autoSuggestionRequest(document)
+		if *tasks != "" {
+			request = GenerateSuggestionsRequest{Documents: []Document{document}}
+			for _, task := range splitAndTrim(*tasks) {
+				suggestionTasks[task](&request)
+			}
+		}
+
+		suggestions, err := app.generateDocumentSuggestions(ctx, request, documentLogger(document.ID))
+		if err != nil {
+			return err
+		}
+		if !*dryRun {
+			documentUpdateMu.Lock()
+			err = app.Client.UpdateDocuments(ctx, suggestions, app.Database, false)
+			documentUpdateMu.Unlock()
+			if err != nil {
+				return err
+			}
+		}
+		return writeJSON(out, suggestions)
+	}, nil
+}
+
+// parseOCRCommand parses paperless-gpt ocr --id <document> [--dry-run]
+func parseOCRCommand(flags *flag.FlagSet, args []string) (cliOperation, error) {
+	documentID := flags.Int("id", 0, "ID of the document")
+	dryRun := flags.Bool("dry-run", false, "Print the text without storing it in paperless-ngx")
+	if err := parseDocumentFlags(flags, args, documentID); err != nil {
+		return nil, err
+	}
+
+	return func(ctx context.Context, app *App, out io.Writer) error {
+		if !app.isOcrEnabled() {
+			return fmt.Errorf("no OCR provider is configured")
+		}
+		document, err := app.Client.GetDocument(ctx, *documentID)
+		if err != nil {
+			return err
+		}
+		ocrResult, err := app.ProcessDocumentOCR(ctx, document.ID)
+		if err != nil {
+			return err
+		}
+		if !*dryRun {
+			if err := app.applyOCRResult(ctx, document, ocrResult, nil); err != nil {
+				return err
+			}
+		}
+		_, err = fmt.Fprintln(out, ocrResult.Text)
+		return err
+	}, nil
+}
+
+// parsePromptTestCommand parses paperless-gpt prompt-test --prompt <name> [--file <template>] [--id <document>]
+// [--send]. The template is validated like by POST /api/prompts/validate and the messages are printed, with --send
+// also the answer of the LLM.
+func parsePromptTestCommand(flags *flag.FlagSet, args []string) (cliOperation, error) {
+	name := flags.String("prompt", "", "Prompt the template is for, e.g. title")
+	file := flags.String("file", "", "Template to test (default: the template of the prompt in the prompts directory)")
+	documentID := flags.Int("id", 0, "ID of the document to render the template with (default: sample data)")
+	send := flags.Bool("send", false, "Send the prompt to the LLM and print the answer")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+	prompt, ok := findPromptTemplate(*name)
+	if !ok {
+		return nil, fmt.Errorf("unknown prompt %q, use --prompt with one of: %s", *name, strings.Join(promptNames(), ", "))
+	}
+
+	return func(ctx context.Context, app *App, out io.Writer) error {
+		path := *file
+		if path == "" {
+			path = filepath.Join(promptsDir, prompt.fileName)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		data := samplePromptData()
+		if *documentID > 0 {
+			if data, err = app.documentValidationData(ctx, *documentID); err != nil {
+				return err
+			}
+		}
+
+		result := validatePrompt(ctx, prompt.name, string(content), data)
+		if !result.Valid {
+			var problems []string
+			for _, problem := range result.Errors {
+				if problem.Line > 0 {
+					problems = append(problems, fmt.Sprintf("%s:%d: %s", path, problem.Line, problem.Message))
+				} else {
+					problems = append(problems, fmt.Sprintf("%s: %s", path, problem.Message))
+				}
+			}
+			return errors.New(strings.Join(problems, "\n"))
+		}
+
+		rendered := renderedPrompt{System: result.System, User: result.Rendered, Prefill: result.Prefill}
+		for _, message := range []struct{ role, text string }{{"system", rendered.System}, {"user", rendered.User}, {"assistant", rendered.Prefill}} {
+			if message.text != "" {
+				fmt.Fprintf(out, "--- %s ---\n%s\n", message.role, message.text)
+			}
+		}
+		if !*send {
+			return nil
+		}
+		completion, err := app.LLM.GenerateContent(withLLMTask(ctx, "prompt_test"), rendered.messages())
+		if err != nil {
+			return fmt.Errorf("error getting response from LLM: %v", err)
+		}
+		_, err = fmt.Fprintf(out, "--- answer ---\n%s\n", rendered.complete(stripReasoning(completion.Choices[0].Content)))
+		return err
+	}, nil
+}
+
+// parseCheckCommand parses paperless-gpt check [--json]. The command fails if one of the checks fails.
+func parseCheckCommand(flags *flag.FlagSet, args []string) (cliOperation, error) {
+	asJSON := flags.Bool("json", false, "Print the results as JSON")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if flags.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(flags.Args(), " "))
+	}
+
+	return func(ctx context.Context, app *App, out io.Writer) error {
+		results := runChecks(ctx, app.configChecks(), true)
+		write := writeCheckReport
+		if *asJSON {
+			write = func(out io.Writer, results []CheckResult) error { return writeJSON(out, results) }
+		}
+		if err := write(out, results); err != nil {
+			return err
+		}
+		if failed := failedChecks(results); failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(results))
+		}
+		return nil
+	}, nil
+}
+
+// promptNames returns the names of the prompts
+func promptNames() []string {
+	names := make([]string, 0, len(promptTemplates))
+	for _, prompt := range promptTemplates {
+		names = append(names, prompt.name)
+	}
+	return names
+}
+
+// writeJSON writes the value as indented JSON
+func writeJSON(out io.Writer, value interface{}) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	command, err := parseCommand(nil)
+	require.NoError(t, err)
+	assert.Nil(t, command, "the server is run without a command")
+
+	_, err = parseCommand([]string{"reprocess"})
+	assert.ErrorContains(t, err, "unknown command reprocess")
+
+	_, err = parseCommand([]string{"process", "--tasks", "title"})
+	assert.ErrorContains(t, err, "--id of the document is required")
+
+	_, err = parseCommand([]string{"process", "--id", "544", "--tasks", "title,colour"})
+	assert.ErrorContains(t, err, "unknown task colour")
+
+	_, err = parseCommand([]string{"prompt-test", "--prompt", "abstract"})
+	assert.ErrorContains(t, err, `unknown prompt "abstract"`)
+
+	command, err = parseCommand([]string{"ocr", "--id", "544", "--dry-run"})
+	require.NoError(t, err)
+	assert.NotNil(t, command)
+}
+
+func TestProcessCommand(t *testing.T) {
+	env := setupTest(t)
+	defer env.teardown()
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "invoice"}], "next": null}`))
+	})
+	patched := false
+	env.setMockResponse("/api/documents/544/", func(w http.ResponseWriter, r *http.Request) {
+		patched = patched || r.Method == http.MethodPatch
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 544, "title": "scan_0544", "content": "Invoice 2024-117", "tags": [1]}`))
+	})
+	app := &App{Client: env.client, Database: env.db, LLM: &mockLLM{}}
+
+	command, err := parseCommand([]string{"process", "--id", "544", "--tasks", "title", "--dry-run"})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, command(context.Background(), app, &out))
+	var suggestions []DocumentSuggestion
+	require.NoError(t, json.Unmarshal(out.Bytes(), &suggestions))
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "test response", suggestions[0].SuggestedTitle)
+	assert.False(t, patched, "a dry run doesn't update the document")
+
+	command, err = parseCommand([]string{"process", "--id", "544", "--tasks", "title"})
+	require.NoError(t, err)
+	require.NoError(t, command(context.Background(), app, &out))
+	assert.True(t, patched)
+}
+
+func TestPromptTestCommand(t *testing.T) {
+	usePromptsDir(t)
+	templatePath := filepath.Join(promptsDir, "title_draft.tmpl")
+	require.NoError(t, os.WriteFile(templatePath, []byte(`{{define "system"}}You name documents.{{end}}Title for {{.Content}}`), 0644))
+	llm := &mockLLM{}
+	app := &App{LLM: llm}
+
+	command, err := parseCommand([]string{"prompt-test", "--prompt", "title", "--file", templatePath, "--send"})
+	require.NoError(t, err)
+	var out bytes.Buffer
+	require.NoError(t, command(context.Background(), app, &out))
+	assert.Contains(t, out.String(), "--- system ---\nYou name documents.\n")
+	assert.Contains(t, out.String(), "--- user ---\nTitle for ")
+	assert.Contains(t, out.String(), "--- answer ---\ntest response\n")
+
+	require.NoError(t, os.WriteFile(templatePath, []byte("Title for {{.Content"), 0644))
+	err = command(context.Background(), app, &out)
+	assert.ErrorContains(t, err, templatePath+":1:")
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"paperless-gpt/ocr"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// documentUpdateMu serializes document updates of concurrently processed documents, so tags and correspondents
+// created while updating one document are known when updating the next instead of being created twice
+var documentUpdateMu sync.Mutex
+
+// processDocumentsConcurrently runs process for the documents with up to AUTO_PROCESSING_CONCURRENCY documents at
+// a time. process reports whether the document was processed, it returns the number of processed documents.
+func processDocumentsConcurrently(ctx context.Context, documents []Document, process func(document Document) (bool, error)) (int, []error) {
+	workers := min(max(settingsFor(ctx).AutoProcessingConcurrency, 1), len(documents))
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	processedCount := 0
+	var errs []error
+
+	queue := make(chan Document)
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for document := range queue {
+				// Documents not started yet wait while processing is paused
+				if backgroundPause.wait(ctx) != nil {
+					continue
+				}
+				processed, err := process(document)
+				mu.Lock()
+				if processed {
+					processedCount++
+				}
+				if err != nil {
+					errs = append(errs, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+feed:
+	for _, document := range documents {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- document:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return processedCount, errs
+}
+
+// concurrencySlots limits how many requests run at the same time
+type concurrencySlots chan struct{}
+
+// acquire waits for a free slot, the returned function releases it
+func (slots concurrencySlots) acquire(ctx context.Context) (func(), error) {
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// tryAcquire takes a free slot without waiting, the returned function releases it
+func (slots concurrencySlots) tryAcquire() (func(), bool) {
+	select {
+	case slots <- struct{}{}:
+		return func() { <-slots }, true
+	default:
+		return nil, false
+	}
+}
+
+// newConcurrencySlots creates slots for up to limit concurrent requests, a limit of 0 means no limit
+func newConcurrencySlots(limit int) concurrencySlots {
+	if limit <= 0 {
+		return nil
+	}
+	return make(concurrencySlots, limit)
+}
+
+// concurrencyLimitedModel limits the number of concurrent requests to an LLM provider
+type concurrencyLimitedModel struct {
+	llms.Model
+	slots concurrencySlots
+}
+
+// limitModelConcurrency allows at most limit concurrent requests to the model, a limit of 0 means no limit
+func limitModelConcurrency(model llms.Model, limit int) llms.Model {
+	if model == nil || limit <= 0 {
+		return model
+	}
+	return &concurrencyLimitedModel{Model: model, slots: make(concurrencySlots, limit)}
+}
+
+func (model *concurrencyLimitedModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	release, err := model.slots.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return model.Model.GenerateContent(ctx, messages, options...)
+}
+
+func (model *concurrencyLimitedModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	release, err := model.slots.acquire(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return model.Model.Call(ctx, prompt, options...)
+}
+
+// concurrencyLimitedOCRProvider limits the number of concurrent requests to an OCR provider
+type concurrencyLimitedOCRProvider struct {
+	ocr.Provider
+	slots concurrencySlots
+}
+
+// limitOCRConcurrency allows at most limit concurrent requests to the OCR provider, a limit of 0 means no limit
+func limitOCRConcurrency(provider ocr.Provider, limit int) ocr.Provider {
+	if provider == nil || limit <= 0 {
+		return provider
+	}
+	return &concurrencyLimitedOCRProvider{Provider: provider, slots: make(concurrencySlots, limit)}
+}
+
+func (provider *concurrencyLimitedOCRProvider) ProcessImage(ctx context.Context, imageContent []byte) (*ocr.OCRResult, error) {
+	release, err := provider.slots.acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return provider.Provider.ProcessImage(ctx, imageContent)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// concurrencyProbe records the highest number of concurrent calls
+type concurrencyProbe struct {
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func (probe *concurrencyProbe) enter() {
+	running := probe.running.Add(1)
+	for {
+		peak := probe.peak.Load()
+		if running <= peak || probe.peak.CompareAndSwap(peak, running) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	probe.running.Add(-1)
+}
+
+func TestProcessDocumentsConcurrently(t *testing.T) {
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoProcessingConcurrency = 3 })
+
+	documents := make([]Document, 10)
+	for i := range documents {
+		documents[i].ID = i + 1
+	}
+
+	var probe concurrencyProbe
+	processedCount, errs := processDocumentsConcurrently(context.Background(), documents, func(document Document) (bool, error) {
+		probe.enter()
+		switch {
+		case document.ID == 4:
+			return false, errors.New("failed")
+		case document.ID%5 == 0:
+			return false, nil // Skipped
+		}
+		return true, nil
+	})
+
+	assert.Equal(t, 7, processedCount)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, int32(3), probe.peak.Load())
+}
+
+// probeLLM is a model recording the concurrency of its calls
+type probeLLM struct {
+	concurrencyProbe
+}
+
+func (m *probeLLM) Call(ctx context.Context, prompt string, _ ...llms.CallOption) (string, error) {
+	m.enter()
+	return "", nil
+}
+
+func (m *probeLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.enter()
+	return &llms.ContentResponse{}, nil
+}
+
+func TestLimitModelConcurrency(t *testing.T) {
+	probe := &probeLLM{}
+	assert.Same(t, probe, limitModelConcurrency(probe, 0))
+
+	model := limitModelConcurrency(probe, 2)
+	done := make(chan error)
+	for range 6 {
+		go func() {
+			_, err := model.GenerateContent(context.Background(), nil)
+			done <- err
+		}()
+	}
+	for range 6 {
+		require.NoError(t, <-done)
+	}
+	assert.Equal(t, int32(2), probe.peak.Load())
+
+	// Waiting for a free slot ends with the context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	blocked := limitModelConcurrency(probe, 1).(*concurrencyLimitedModel)
+	blocked.slots <- struct{}{}
+	_, err := blocked.Call(ctx, "prompt")
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/tmc/langchaingo/llms"
+	"gorm.io/gorm"
+)
+
+// Outcomes of a configuration check
+const (
+	checkPass = "pass"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// checkTimeout is how long a single check may take
+var checkTimeout = 30 * time.Second
+
+// errCheckSkipped is returned by checks of services that are not configured, its detail says why
+var errCheckSkipped = errors.New("skipped")
+
+// errCheckRollback rolls back the transaction of the database check
+var errCheckRollback = errors.New("rollback")
+
+// CheckResult is the outcome of checking one of the configured services
+type CheckResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// configCheck checks that a configured service works. run returns a detail of the working service, or
+// errCheckSkipped with the reason in the detail.
+type configCheck struct {
+	name       string
+	callsModel bool // The check sends a request to a model, which may cost money, so it is not run at startup
+	run        func(ctx context.Context) (string, error)
+}
+
+// configChecks returns the checks of paperless-ngx, the database, the models and the OCR provider
+func (app *App) configChecks() []configCheck {
+	return []configCheck{
+		{name: "paperless-ngx", run: app.checkPaperless},
+		{name: "database", run: app.checkDatabase},
+		{name: "llm", callsModel: true, run: func(ctx context.Context) (string, error) {
+			ctx = withSettings(ctx)
+			settings := settingsFor(ctx)
+			return checkModel(ctx, app.llm(ctx), settings.LLMProvider, settings.LLMModel)
+		}},
+		{name: "embeddings", callsModel: true, run: app.checkEmbeddings},
+		{name: "ocr", callsModel: app.isOcrEnabled() && ocrProviderType() == "llm", run: app.checkOCR},
+	}
+}
+
+// runChecks runs the checks one after another. The ones calling models are skipped unless callModels is set.
+func runChecks(ctx context.Context, checks []configCheck, callModels bool) []CheckResult {
+	results := make([]CheckResult, 0, len(checks))
+	for _, check := range checks {
+		result := CheckResult{Name: check.name}
+		if check.callsModel && !callModels {
+			result.Status, result.Detail = checkSkip, "not checked at startup, run paperless-gpt check"
+			results = append(results, result)
+			continue
+		}
+
+		start := time.Now()
+		checkCtx, cancel := context.WithTimeout(ctx, checkTimeout)
+		detail, err := check.run(withLLMTask(checkCtx, "check"))
+		cancel()
+		result.DurationMs = time.Since(start).Milliseconds()
+		switch {
+		case errors.Is(err, errCheckSkipped):
+			result.Status, result.Detail = checkSkip, detail
+		case err != nil:
+			result.Status, result.Detail = checkFail, err.Error()
+		default:
+			result.Status, result.Detail = checkPass, detail
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// checkPaperless checks that paperless-ngx is reachable and accepts the credentials
+func (app *App) checkPaperless(ctx context.Context) (string, error) {
+	status := app.Client.GetStatus(ctx, nil)
+	if !status.Authenticated {
+		return "", fmt.Errorf("%s", status.Error)
+	}
+	if status.Version != "" {
+		return fmt.Sprintf("%s, paperless-ngx %s", paperlessBaseURL, status.Version), nil
+	}
+	return paperlessBaseURL, nil
+}
+
+// checkDatabase checks that the database can be written to, in a transaction that is rolled back
+func (app *App) checkDatabase(ctx context.Context) (string, error) {
+	err := app.Database.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ProcessedDocument{DocumentID: 0, ProcessedAt: time.Now().Format(time.RFC3339)}).Error; err != nil {
+			return err
+		}
+		return errCheckRollback
+	})
+	if !errors.Is(err, errCheckRollback) {
+		return "", fmt.Errorf("the database is not writable: %v", err)
+	}
+	return fmt.Sprintf("%s database is writable", app.Database.Dialector.Name()), nil
+}
+
+// checkModel sends a short prompt to a model and expects an answer
+func checkModel(ctx context.Context, model llms.Model, provider, name string) (string, error) {
+	completion, err := model.GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Reply with OK.")})
+	if err != nil {
+		return "", fmt.Errorf("%s %s: %v", provider, name, err)
+	}
+	if len(completion.Choices) == 0 || strings.TrimSpace(stripReasoning(completion.Choices[0].Content)) == "" {
+		return "", fmt.Errorf("%s %s gave an empty answer", provider, name)
+	}
+	return fmt.Sprintf("%s %s answered", provider, name), nil
+}
+
+// checkEmbeddings creates the embedding of a short text
+func (app *App) checkEmbeddings(ctx context.Context) (string, error) {
+	if app.Embedder == nil {
+		return "EMBEDDING_MODEL is not set", errCheckSkipped
+	}
+	embeddings, err := app.Embedder.CreateEmbedding(ctx, []string{"paperless-gpt"})
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", embeddingModel, err)
+	}
+	if len(embeddings) == 0 || len(embeddings[0]) == 0 {
+		return "", fmt.Errorf("%s returned no embedding", embeddingModel)
+	}
+	return fmt.Sprintf("%s returned %d dimensions", embeddingModel, len(embeddings[0])), nil
+}
+
+// checkOCR checks that the OCR provider is reachable. The vision model of LLM based OCR answers a prompt, the
+// endpoints of the cloud providers are connected to without processing a document.
+func (app *App) checkOCR(ctx context.Context) (string, error) {
+	if !app.isOcrEnabled() {
+		return "no OCR provider is configured", errCheckSkipped
+	}
+	switch providerType := ocrProviderType(); providerType {
+	case "llm":
+		if app.VisionLLM == nil {
+			return "", fmt.Errorf("VISION_LLM_PROVIDER is not set")
+		}
+		return checkModel(ctx, app.VisionLLM, visionLlmProvider, visionLlmModel)
+	case "azure":
+		endpoint, err := url.Parse(azureDocAIEndpoint)
+		if err != nil || endpoint.Host == "" {
+			return "", fmt.Errorf("invalid AZURE_DOCAI_ENDPOINT %q", azureDocAIEndpoint)
+		}
+		address := endpoint.Host
+		if endpoint.Port() == "" {
+			address = net.JoinHostPort(endpoint.Hostname(), "443")
+		}
+		return checkReachable(ctx, "azure", address)
+	case "google_docai":
+		return checkReachable(ctx, "google_docai", fmt.Sprintf("%s-documentai.googleapis.com:443", os.Getenv("GOOGLE_LOCATION")))
+	default:
+		return "", fmt.Errorf("unsupported OCR provider: %s", providerType)
+	}
+}
+
+// ocrProviderType returns the OCR provider configured with OCR_PROVIDER
+func ocrProviderType() string {
+	if providerType := os.Getenv("OCR_PROVIDER"); providerType != "" {
+		return providerType
+	}
+	return "llm"
+}
+
+// checkReachable connects to the address of a provider
+func checkReachable(ctx context.Context, provider, address string) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return "", fmt.Errorf("%s is not reachable: %v", address, err)
+	}
+	conn.Close()
+	return fmt.Sprintf("%s reachable at %s", provider, address), nil
+}
+
+// writeCheckReport writes the results as a table, one check per line
+func writeCheckReport(out io.Writer, results []CheckResult) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintf(out, "%-4s  %-13s %6dms  %s\n", strings.ToUpper(result.Status), result.Name, result.DurationMs, result.Detail); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// failedChecks counts the failed checks
+func failedChecks(results []CheckResult) int {
+	failed := 0
+	for _, result := range results {
+		if result.Status == checkFail {
+			failed++
+		}
+	}
+	return failed
+}
+
+// logStartupChecks checks the configured services without calling the models and logs the results, so
+// misconfigurations show up before the first document fails
+func (app *App) logStartupChecks(ctx context.Context) {
+	for _, result := range runChecks(ctx, app.configChecks(), false) {
+		switch result.Status {
+		case checkPass:
+			log.Infof("Check %s passed: %s", result.Name, result.Detail)
+		case checkFail:
+			log.Warnf("Check %s failed: %s", result.Name, result.Detail)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunChecks(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "2.14.7")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	app := &App{Client: env.client, Database: env.db, LLM: &mockLLM{}}
+
+	results := runChecks(context.Background(), app.configChecks(), true)
+	statuses := map[string]string{}
+	for _, result := range results {
+		statuses[result.Name] = result.Status
+	}
+	assert.Equal(t, map[string]string{
+		"paperless-ngx": checkPass,
+		"database":      checkPass,
+		"llm":           checkPass,
+		"embeddings":    checkSkip,
+		"ocr":           checkSkip,
+	}, statuses)
+	assert.Contains(t, results[0].Detail, "paperless-ngx 2.14.7")
+	assert.Zero(t, failedChecks(results))
+
+	var count int64
+	require.NoError(t, env.db.Model(&ProcessedDocument{}).Where("document_id = 0").Count(&count).Error)
+	assert.Zero(t, count, "the database check leaves no records")
+
+	// At startup the models are not called
+	startup := runChecks(context.Background(), app.configChecks(), false)
+	assert.Equal(t, checkSkip, startup[2].Status)
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+	results = runChecks(context.Background(), app.configChecks(), true)
+	assert.Equal(t, checkFail, results[0].Status)
+	assert.Contains(t, results[0].Detail, "rejected the credentials")
+	assert.Equal(t, 1, failedChecks(results))
+
+	var out bytes.Buffer
+	require.NoError(t, writeCheckReport(&out, results[:1]))
+	assert.Regexp(t, `^FAIL  paperless-ngx +\d+ms  paperless-ngx rejected the credentials: 401\n$`, out.String())
+}
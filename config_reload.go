@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// configMutex serializes changes of the reloadable settings
+var configMutex sync.Mutex
+
+// reloadableSetting parses the value of a setting that can be changed at runtime and returns a function storing it in
+// a copy of the settings
+type reloadableSetting func(value string) (apply func(settings *runtimeSettings), err error)
+
+// stringSetting stores the value as is
+func stringSetting(field func(settings *runtimeSettings) *string) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		return func(settings *runtimeSettings) { *field(settings) = value }, nil
+	}
+}
+
+// boolSetting stores true or false
+func boolSetting(field func(settings *runtimeSettings) *bool) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("must be true or false, got: %s", value)
+		}
+		return func(settings *runtimeSettings) { *field(settings) = parsed }, nil
+	}
+}
+
+// switchSetting stores true or false in a setting that is on or off by default, or nothing for its default
+func switchSetting(field func(settings *runtimeSettings) *string) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		if value != "" {
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("must be true or false, got: %s", value)
+			}
+			value = strconv.FormatBool(parsed)
+		}
+		return func(settings *runtimeSettings) { *field(settings) = value }, nil
+	}
+}
+
+// listSetting stores a comma-separated list
+func listSetting(field func(settings *runtimeSettings) *[]string) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		return func(settings *runtimeSettings) { *field(settings) = splitAndTrim(value) }, nil
+	}
+}
+
+// intSetting stores a number of at least min
+func intSetting(field func(settings *runtimeSettings) *int, min int) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < min {
+			return nil, fmt.Errorf("must be a number of at least %d, got: %s", min, value)
+		}
+		return func(settings *runtimeSettings) { *field(settings) = parsed }, nil
+	}
+}
+
+// floatSetting stores a number from min to max
+func floatSetting(field func(settings *runtimeSettings) *float64, min, max float64) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < min || parsed > max {
+			return nil, fmt.Errorf("must be a number between %g and %g, got: %s", min, max, value)
+		}
+		return func(settings *runtimeSettings) { *field(settings) = parsed }, nil
+	}
+}
+
+// choiceSetting stores one of the choices
+func choiceSetting(field func(settings *runtimeSettings) *string, choices []string) reloadableSetting {
+	return func(value string) (func(*runtimeSettings), error) {
+		if !slices.Contains(choices, value) {
+			return nil, fmt.Errorf("must be one of %s, got: %s", strings.Join(choices, ", "), value)
+		}
+		return func(settings *runtimeSettings) { *field(settings) = value }, nil
+	}
+}
+
+// reloadableSettings are the settings that can be changed without a restart, by PATCH /api/config or CONFIG_FILE.
+// Secrets and settings only used at startup, like the paperless-ngx connection and the listen interface, aren't among them.
+var reloadableSettings = map[string]reloadableSetting{
+	"LLM_PROVIDER":                 stringSetting(func(s *runtimeSettings) *string { return &s.LLMProvider }),
+	"LLM_MODEL":                    stringSetting(func(s *runtimeSettings) *string { return &s.LLMModel }),
+	"LLM_LANGUAGE":                 stringSetting(func(s *runtimeSettings) *string { return &s.LLMLanguage }),
+	"LLM_CONCURRENCY_LIMIT":        intSetting(func(s *runtimeSettings) *int { return &s.LLMConcurrencyLimit }, 0),
+	"TOKEN_LIMIT":                  intSetting(func(s *runtimeSettings) *int { return &s.TokenLimit }, 0),
+	"TRUNCATION_STRATEGY":          choiceSetting(func(s *runtimeSettings) *string { return &s.TruncationStrategy }, truncationStrategies),
+	"MANUAL_TAG":                   stringSetting(func(s *runtimeSettings) *string { return &s.ManualTag }),
+	"AUTO_TAG":                     stringSetting(func(s *runtimeSettings) *string { return &s.AutoTag }),
+	"AUTO_OCR_TAG":                 stringSetting(func(s *runtimeSettings) *string { return &s.AutoOCRTag }),
+	"ERROR_TAG":                    stringSetting(func(s *runtimeSettings) *string { return &s.ErrorTag }),
+	"PII_TAG":                      stringSetting(func(s *runtimeSettings) *string { return &s.PIITag }),
+	"ACTION_REQUIRED_TAG":          stringSetting(func(s *runtimeSettings) *string { return &s.ActionRequiredTag }),
+	"DUE_SOON_TAG":                 stringSetting(func(s *runtimeSettings) *string { return &s.DueSoonTag }),
+	"PRIORITY_TAGS":                listSetting(func(s *runtimeSettings) *[]string { return &s.PriorityTags }),
+	"PRIORITY_CORRESPONDENTS":      listSetting(func(s *runtimeSettings) *[]string { return &s.PriorityCorrespondents }),
+	"ALLOW_NEW_TAGS":               boolSetting(func(s *runtimeSettings) *bool { return &s.AllowNewTags }),
+	"ALLOW_NEW_CORRESPONDENTS":     boolSetting(func(s *runtimeSettings) *bool { return &s.AllowNewCorrespondents }),
+	"CORRESPONDENT_WHITE_LIST":     listSetting(func(s *runtimeSettings) *[]string { return &s.CorrespondentWhiteList }),
+	"TAG_BLACK_LIST":               listSetting(func(s *runtimeSettings) *[]string { return &s.TagBlackList }),
+	"TAG_WHITE_LIST":               listSetting(func(s *runtimeSettings) *[]string { return &s.TagWhiteList }),
+	"AUTO_GENERATE_TITLE":          switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateTitle }),
+	"AUTO_GENERATE_TAGS":           switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateTags }),
+	"AUTO_GENERATE_CORRESPONDENTS": switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateCorrespondents }),
+	"AUTO_GENERATE_CREATED_DATE":   switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateCreatedDate }),
+	"AUTO_GENERATE_CUSTOM_FIELDS":  switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateCustomFields }),
+	"AUTO_GENERATE_SUMMARY":        switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateSummary }),
+	"AUTO_GENERATE_DUE_DATE":       switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateDueDate }),
+	"AUTO_GENERATE_AMOUNT":         switchSetting(func(s *runtimeSettings) *string { return &s.AutoGenerateAmount }),
+	"AUTO_TRANSLATE":               switchSetting(func(s *runtimeSettings) *string { return &s.AutoTranslate }),
+	"AUTO_TRIAGE":                  switchSetting(func(s *runtimeSettings) *string { return &s.AutoTriage }),
+	"AUTO_LINK_RELATED_DOCUMENTS":  switchSetting(func(s *runtimeSettings) *string { return &s.AutoLinkRelatedDocuments }),
+	"AUTO_REVIEW":                  boolSetting(func(s *runtimeSettings) *bool { return &s.AutoReview }),
+	"AUTO_APPLY_CONFIDENCE":        floatSetting(func(s *runtimeSettings) *float64 { return &s.AutoApplyConfidence }, 0, 1),
+	"DETECT_LANGUAGE":              boolSetting(func(s *runtimeSettings) *bool { return &s.DetectLanguage }),
+	"PII_SCAN":                     boolSetting(func(s *runtimeSettings) *bool { return &s.PIIScan }),
+	"CLASSIFY_SENSITIVITY":         boolSetting(func(s *runtimeSettings) *bool { return &s.ClassifySensitivity }),
+	"AUTO_PROCESSING_CONCURRENCY":  intSetting(func(s *runtimeSettings) *int { return &s.AutoProcessingConcurrency }, 1),
+	"OCR_LIMIT_PAGES":              intSetting(func(s *runtimeSettings) *int { return &s.LimitOCRPages }, 0),
+	"PDF_RENDER_DPI":               intSetting(func(s *runtimeSettings) *int { return &s.PDFRenderDPI }, 72),
+	"PDF_RENDER_COLOR_MODE":        choiceSetting(func(s *runtimeSettings) *string { return &s.PDFRenderColorMode }, pdfColorModes),
+	"PDF_RENDER_MAX_DIMENSION":     intSetting(func(s *runtimeSettings) *int { return &s.PDFRenderMaxDimension }, 0),
+	"PDF_RENDER_JPEG_QUALITY":      intSetting(func(s *runtimeSettings) *int { return &s.PDFRenderJPEGQuality }, 1),
+	"JOB_MAX_ATTEMPTS":             intSetting(func(s *runtimeSettings) *int { return &s.JobMaxAttempts }, 1),
+	"MAX_DOCUMENT_FAILURES":        intSetting(func(s *runtimeSettings) *int { return &s.MaxDocumentFailures }, 0),
+	"HISTORY_RETENTION_DAYS":       intSetting(func(s *runtimeSettings) *int { return &s.HistoryRetentionDays }, 0),
+	"HISTORY_MAX_ENTRIES":          intSetting(func(s *runtimeSettings) *int { return &s.HistoryMaxEntries }, 0),
+	"BACKUP_INTERVAL_HOURS":        intSetting(func(s *runtimeSettings) *int { return &s.BackupIntervalHours }, 0),
+	"BACKUP_KEEP":                  intSetting(func(s *runtimeSettings) *int { return &s.BackupKeep }, 0),
+	"FEW_SHOT_EXAMPLES":            intSetting(func(s *runtimeSettings) *int { return &s.FewShotExamples }, 0),
+	"DOCUMENT_LOG_RETENTION_DAYS":  intSetting(func(s *runtimeSettings) *int { return &s.DocumentLogRetentionDays }, 0),
+}
+
+// llmSettings are the reloadable settings the LLM client is created with
+var llmSettings = []string{"LLM_PROVIDER", "LLM_MODEL", "LLM_CONCURRENCY_LIMIT"}
+
+// applySettings changes the settings, keyed by the name of their environment variable. All values are checked before
+// any is changed. The changed settings are stored as a whole, together with a new LLM client if its settings change.
+// Running jobs finish with the settings and the LLM client they started with.
+func (app *App) applySettings(settings map[string]string) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	applies := map[string]func(*runtimeSettings){}
+	var rejected []string
+	for name, value := range settings {
+		setting, ok := reloadableSettings[name]
+		if !ok {
+			rejected = append(rejected, name)
+			continue
+		}
+		apply, err := setting(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s %w", name, err)
+		}
+		applies[name] = apply
+	}
+	if len(rejected) > 0 {
+		slices.Sort(rejected)
+		return fmt.Errorf("%s can't be changed at runtime", strings.Join(rejected, ", "))
+	}
+
+	// The LLM client is created first, so invalid LLM settings leave everything unchanged
+	changed := loadSettings().clone()
+	rebuildLLM := false
+	for _, name := range llmSettings {
+		if apply, ok := applies[name]; ok {
+			apply(changed)
+			delete(applies, name)
+			rebuildLLM = true
+		}
+	}
+	if rebuildLLM {
+		llm, err := createLLM(changed)
+		if err != nil {
+			return fmt.Errorf("failed to create LLM client: %w", err)
+		}
+		changed.llm = limitModelConcurrency(recordLLMUsage(llm, app.Database, changed.LLMProvider, changed.LLMModel), changed.LLMConcurrencyLimit)
+	}
+
+	for _, apply := range applies {
+		apply(changed)
+	}
+	currentSettings.Store(changed)
+	return nil
+}
+
+// readConfigFile reads the KEY=VALUE lines of a config file. Empty lines and lines starting with # are skipped.
+func readConfigFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	settings := map[string]string{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE, got: %s", lineNumber, line)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		settings[strings.TrimSpace(name)] = value
+	}
+	return settings, scanner.Err()
+}
+
+// reloadConfigFile applies the settings of the config file
+func (app *App) reloadConfigFile(path string) error {
+	settings, err := readConfigFile(path)
+	if err != nil {
+		return err
+	}
+	if err := app.applySettings(settings); err != nil {
+		return err
+	}
+	log.Infof("Applied %d settings of %s", len(settings), path)
+	return nil
+}
+
+// reloadConfigOnSignal applies the settings of the config file again whenever the process receives SIGHUP
+func (app *App) reloadConfigOnSignal(ctx context.Context, path string) {
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+			if err := app.reloadConfigFile(path); err != nil {
+				log.Errorf("Failed to reload %s, keeping the current settings: %v", path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Strategies of shortening the content of documents exceeding TOKEN_LIMIT
+const (
+	truncateHead      = "head"      // Keep the start of the content
+	truncateHeadTail  = "head_tail" // Keep the start and the end, where dates and totals often are
+	truncateSentence  = "sentence"  // Keep the start, cut after the last complete sentence
+	truncateSummarize = "summarize" // Condense the content with the LLM first
+)
+
+// truncationStrategies are the values of TRUNCATION_STRATEGY
+var truncationStrategies = []string{truncateHead, truncateHeadTail, truncateSentence, truncateSummarize}
+
+// headShare is the share of the available tokens head_tail keeps from the start of the content, the rest is kept from
+// the end
+const headShare = 0.7
+
+// truncationMarker separates the start and the end of the content kept by head_tail
+const truncationMarker = "\n\n[...]\n\n"
+
+// maxCondenseParts is the number of parts the content is condensed in at most, longer content is kept at its start and
+// end instead
+const maxCondenseParts = 8
+
+// condensedContents caches the condensed content of documents by the hash of the content and the token limit, so the
+// prompts of a document condense it once
+var (
+	condensedContents      = map[string]string{}
+	condensedContentsMutex sync.Mutex
+)
+
+// maxCondensedContents is the number of condensed contents kept, the cache is emptied when it is full
+const maxCondensedContents = 32
+
+// truncateContent shortens the content to availableTokens with the TRUNCATION_STRATEGY. If condensing the content with
+// the LLM fails, its start and end are kept instead. templateMutex must be held.
+func (app *App) truncateContent(ctx context.Context, content string, availableTokens int) (string, error) {
+	settings := settingsFor(ctx)
+	if settings.TruncationStrategy != truncateSummarize {
+		return truncateContentWith(ctx, settings.TruncationStrategy, content, availableTokens)
+	}
+	if availableTokens < 0 || settings.TokenLimit <= 0 {
+		return content, nil
+	}
+	totalTokens, err := getTokenCount(ctx, content)
+	if err != nil {
+		return "", fmt.Errorf("error counting tokens: %v", err)
+	}
+	if totalTokens <= availableTokens {
+		return content, nil
+	}
+
+	condensed, err := app.condenseContent(ctx, content)
+	if err != nil {
+		log.Warnf("Error condensing the content, keeping its start and end instead: %v", err)
+		return truncateContentWith(ctx, truncateHeadTail, content, availableTokens)
+	}
+	return truncateContentWith(ctx, truncateSentence, condensed, availableTokens)
+}
+
+// truncateContentWith shortens the content to availableTokens with a strategy not using the LLM. Content is kept at
+// its start for summarize.
+func truncateContentWith(ctx context.Context, strategy, content string, availableTokens int) (string, error) {
+	switch strategy {
+	case truncateHeadTail:
+		return truncateHeadAndTail(ctx, content, availableTokens)
+	case truncateSentence:
+		return truncateAtSentence(ctx, content, availableTokens)
+	default:
+		return truncateContentByTokens(ctx, content, availableTokens)
+	}
+}
+
+// truncateHeadAndTail keeps the start and the end of the content within availableTokens, separated by a marker
+func truncateHeadAndTail(ctx context.Context, content string, availableTokens int) (string, error) {
+	truncated, err := truncateContentByTokens(ctx, content, availableTokens)
+	if err != nil || len(truncated) == len(content) {
+		return truncated, err
+	}
+
+	markerTokens, err := getTokenCount(ctx, truncationMarker)
+	if err != nil {
+		return "", fmt.Errorf("error counting tokens: %v", err)
+	}
+	// The tokens of the joined parts can differ slightly from the sum of their tokens, the parts are shortened by the
+	// difference until they fit
+	for available := availableTokens - markerTokens; available > 0; {
+		result, err := joinHeadAndTail(ctx, []rune(content), available)
+		if err != nil {
+			return "", err
+		}
+		resultTokens, err := getTokenCount(ctx, result)
+		if err != nil {
+			return "", fmt.Errorf("error counting tokens: %v", err)
+		}
+		if resultTokens <= availableTokens {
+			return result, nil
+		}
+		available -= resultTokens - availableTokens
+	}
+	return truncated, nil
+}
+
+// joinHeadAndTail joins the start and the end of the runes within the tokens with the marker
+func joinHeadAndTail(ctx context.Context, runes []rune, tokens int) (string, error) {
+	headTokens := int(float64(tokens) * headShare)
+	head, err := tokenPrefixLength(ctx, runes, headTokens)
+	if err != nil {
+		return "", err
+	}
+	tail, err := tokenSuffixLength(ctx, runes[head:], tokens-headTokens)
+	if err != nil {
+		return "", err
+	}
+	return string(runes[:head]) + truncationMarker + string(runes[len(runes)-tail:]), nil
+}
+
+// truncateAtSentence keeps the start of the content within availableTokens up to the end of its last complete
+// sentence or paragraph, unless that would drop more than half of it
+func truncateAtSentence(ctx context.Context, content string, availableTokens int) (string, error) {
+	truncated, err := truncateContentByTokens(ctx, content, availableTokens)
+	if err != nil || len(truncated) == len(content) {
+		return truncated, err
+	}
+	if end := lastSentenceEnd(truncated); end >= len(truncated)/2 {
+		return truncated[:end], nil
+	}
+	return truncated, nil
+}
+
+// lastSentenceEnd returns the byte offset after the last sentence or paragraph followed by more text, 0 if there is none
+func lastSentenceEnd(text string) int {
+	for i := len(text) - 2; i >= 0; i-- {
+		next := rune(text[i+1])
+		switch {
+		case strings.ContainsRune(".!?", rune(text[i])) && unicode.IsSpace(next):
+			return i + 1
+		case text[i] == '\n' && next == '\n':
+			return i
+		}
+	}
+	return 0
+}
+
+// tokenPrefixLength returns the number of runes of the longest start of the runes within the tokens
+func tokenPrefixLength(ctx context.Context, runes []rune, tokens int) (int, error) {
+	low, high, valid := 0, len(runes), 0
+	for low <= high {
+		mid := (low + high) / 2
+		count, err := getTokenCount(ctx, string(runes[:mid]))
+		if err != nil {
+			return 0, fmt.Errorf("error counting tokens in substring: %v", err)
+		}
+		if count <= tokens {
+			valid = mid
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return valid, nil
+}
+
+// tokenSuffixLength returns the number of runes of the longest end of the runes within the tokens
+func tokenSuffixLength(ctx context.Context, runes []rune, tokens int) (int, error) {
+	low, high, valid := 0, len(runes), 0
+	for low <= high {
+		mid := (low + high) / 2
+		count, err := getTokenCount(ctx, string(runes[len(runes)-mid:]))
+		if err != nil {
+			return 0, fmt.Errorf("error counting tokens in substring: %v", err)
+		}
+		if count <= tokens {
+			valid = mid
+			low = mid + 1
+		} else {
+			high = mid - 1
+		}
+	}
+	return valid, nil
+}
+
+// condenseContent condenses content exceeding the token limit with the condense prompt. The content is split into
+// parts fitting the prompt, which are condensed to half of TOKEN_LIMIT together, leaving the other half to the prompts
+// the content is used in. templateMutex must be held.
+func (app *App) condenseContent(ctx context.Context, content string) (string, error) {
+	tokenLimit := settingsFor(ctx).TokenLimit
+	key := fmt.Sprintf("%x-%d", sha256.Sum256([]byte(content)), tokenLimit)
+	condensedContentsMutex.Lock()
+	condensed, ok := condensedContents[key]
+	condensedContentsMutex.Unlock()
+	if ok {
+		return condensed, nil
+	}
+
+	templateData := map[string]interface{}{
+		"Language": getDocumentLanguage(ctx),
+		"MaxWords": tokenLimit,
+		"Part":     maxCondenseParts,
+		"Parts":    maxCondenseParts,
+	}
+	partTokens, err := getAvailableTokensForContent(ctx, condenseTemplate, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error calculating available tokens: %v", err)
+	}
+	parts, err := splitContentByTokens(ctx, content, partTokens)
+	if err != nil {
+		return "", err
+	}
+	if len(parts) > maxCondenseParts {
+		return "", fmt.Errorf("the content has %d parts, more than the %d that are condensed", len(parts), maxCondenseParts)
+	}
+
+	// Words are shorter than tokens on average
+	templateData["MaxWords"] = tokenLimit / 2 / len(parts) * 3 / 4
+	templateData["Parts"] = len(parts)
+	condensedParts := make([]string, 0, len(parts))
+	for i, part := range parts {
+		templateData["Part"] = i + 1
+		templateData["Content"] = part
+		prompt, err := renderPrompt(condenseTemplate, templateData)
+		if err != nil {
+			return "", fmt.Errorf("error executing condense template: %v", err)
+		}
+		completion, err := app.llm(ctx).GenerateContent(withLLMTask(ctx, "condense"), prompt.messages())
+		if err != nil {
+			return "", fmt.Errorf("error getting response from LLM: %v", err)
+		}
+		condensedParts = append(condensedParts, strings.TrimSpace(prompt.complete(stripReasoning(completion.Choices[0].Content))))
+	}
+	condensed = strings.Join(condensedParts, "\n\n")
+
+	condensedContentsMutex.Lock()
+	if len(condensedContents) >= maxCondensedContents {
+		clear(condensedContents)
+	}
+	condensedContents[key] = condensed
+	condensedContentsMutex.Unlock()
+	return condensed, nil
+}
+
+// splitContentByTokens splits the content into parts within the tokens, at the end of a sentence or paragraph where
+// possible
+func splitContentByTokens(ctx context.Context, content string, tokens int) ([]string, error) {
+	var parts []string
+	runes := []rune(content)
+	for len(runes) > 0 {
+		length, err := tokenPrefixLength(ctx, runes, tokens)
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			return nil, fmt.Errorf("the condense prompt leaves no tokens for the content")
+		}
+		if length < len(runes) {
+			part := string(runes[:length])
+			if end := lastSentenceEnd(part); end >= len(part)/2 {
+				length = len([]rune(part[:end]))
+			}
+		}
+		parts = append(parts, strings.TrimSpace(string(runes[:length])))
+		runes = runes[length:]
+	}
+	return parts, nil
+}
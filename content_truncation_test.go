@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// longInvoice has its number at the start and its total at the end
+var longInvoice = "Invoice 2024-117 from ACME Corp. " + strings.Repeat("Position with a long description of the delivered goods. ", 60) + "\n\nTotal: 119.00 EUR, due 2024-12-31."
+
+func TestTruncationStrategies(t *testing.T) {
+	setSettings(t, func(settings *runtimeSettings) { settings.TokenLimit = 1000 })
+
+	for _, strategy := range []string{truncateHead, truncateHeadTail, truncateSentence} {
+		truncated, err := truncateContentWith(context.Background(), strategy, longInvoice, 100)
+		require.NoError(t, err, strategy)
+		tokens, err := getTokenCount(context.Background(), truncated)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, tokens, 100, strategy)
+		assert.True(t, strings.HasPrefix(truncated, "Invoice 2024-117 from ACME Corp."), strategy)
+
+		short, err := truncateContentWith(context.Background(), strategy, "Invoice 2024-117", 100)
+		require.NoError(t, err)
+		assert.Equal(t, "Invoice 2024-117", short, "content within the limit is kept as it is")
+	}
+
+	headTail, err := truncateContentWith(context.Background(), truncateHeadTail, longInvoice, 100)
+	require.NoError(t, err)
+	assert.Contains(t, headTail, truncationMarker)
+	assert.True(t, strings.HasSuffix(headTail, "Total: 119.00 EUR, due 2024-12-31."), "the end of the content is kept")
+
+	sentence, err := truncateContentWith(context.Background(), truncateSentence, longInvoice, 100)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(sentence, "goods."), "cut after a complete sentence: %q", sentence)
+
+	assert.Equal(t, 0, lastSentenceEnd("No sentence end"))
+	assert.Equal(t, len("First. Second."), lastSentenceEnd("First. Second. Third"))
+	assert.Equal(t, len("Heading"), lastSentenceEnd("Heading\n\nText without end"))
+	assert.Equal(t, 0, lastSentenceEnd("Total: 119.00"), "decimal points don't end sentences")
+}
+
+// condensingLLM condenses every part to its first sentence
+type condensingLLM struct {
+	mockJSONLLM
+	calls atomic.Int32
+}
+
+func (m *condensingLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	m.calls.Add(1)
+	part := messages[0].Parts[0].(llms.TextContent).Text
+	part = part[strings.Index(part, ":\n")+2:]
+	first, _, _ := strings.Cut(part, ". ")
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: first + "."}}}, nil
+}
+
+func TestSummarizeTruncation(t *testing.T) {
+	usePromptsDir(t)
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.TokenLimit, settings.TruncationStrategy = 400, truncateSummarize
+	})
+	condenseTemplate = template.Must(template.New("condense").Parse("Condense to {{.MaxWords}} words, part {{.Part}} of {{.Parts}}:\n{{.Content}}"))
+	clear(condensedContents)
+
+	llm := &condensingLLM{}
+	app := &App{LLM: llm}
+	truncated, err := app.truncateContent(context.Background(), longInvoice, 100)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(truncated, "Invoice 2024-117 from ACME Corp."))
+	calls := llm.calls.Load()
+	assert.Greater(t, calls, int32(1), "the content is condensed in parts")
+
+	_, err = app.truncateContent(context.Background(), longInvoice, 80)
+	require.NoError(t, err)
+	assert.Equal(t, calls, llm.calls.Load(), "the condensed content is reused by the prompts of the document")
+
+	short, err := app.truncateContent(context.Background(), "Invoice 2024-117", 100)
+	require.NoError(t, err)
+	assert.Equal(t, "Invoice 2024-117", short)
+
+	// Content too long to be condensed in parts is kept at its start and end
+	setSettings(t, func(settings *runtimeSettings) { settings.TokenLimit = 100 })
+	truncated, err = app.truncateContent(context.Background(), strings.Repeat(longInvoice, 4), 60)
+	require.NoError(t, err)
+	assert.Contains(t, truncated, truncationMarker)
+}
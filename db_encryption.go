@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// encryptedValuePrefix marks values encrypted with DATABASE_ENCRYPTION_KEY, values without it are plaintext
+const encryptedValuePrefix = "enc:v1:"
+
+// databaseCipher encrypts the columns tagged serializer:encrypted, they are stored as plaintext without it
+var databaseCipher cipher.AEAD
+
+// errDatabaseEncryptionKeyMissing is returned when reading an encrypted value without DATABASE_ENCRYPTION_KEY
+var errDatabaseEncryptionKeyMissing = errors.New("DATABASE_ENCRYPTION_KEY is required to read encrypted values")
+
+// encryptedColumns lists the columns holding document text, by model. They are tagged serializer:encrypted.
+var encryptedColumns = []struct {
+	model   interface{}
+	columns []string
+}{
+	{&ModificationHistory{}, []string{"previous_value", "new_value", "document_before", "document_after"}},
+	{&PendingReview{}, []string{"suggestion"}},
+	{&Job{}, []string{"result", "request"}},
+	{&DocumentLog{}, []string{"message"}},
+}
+
+func init() {
+	schema.RegisterSerializer("encrypted", encryptedSerializer{})
+}
+
+// setDatabaseEncryptionKey encrypts the columns tagged serializer:encrypted with AES-256-GCM and a key derived from
+// the given one, an empty key stores them as plaintext
+func setDatabaseEncryptionKey(key string) error {
+	if key == "" {
+		databaseCipher = nil
+		return nil
+	}
+	derived := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(derived[:])
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	databaseCipher = aead
+	return nil
+}
+
+// encryptValue encrypts a value to store, empty values and all values without a key are stored as they are
+func encryptValue(value string) (string, error) {
+	if databaseCipher == nil || value == "" {
+		return value, nil
+	}
+	sealed, err := sealBytes([]byte(value))
+	if err != nil {
+		return "", err
+	}
+	return encryptedValuePrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decryptValue decrypts a stored value, plaintext stored before DATABASE_ENCRYPTION_KEY was set is returned as is
+func decryptValue(stored string) (string, error) {
+	encoded, ok := strings.CutPrefix(stored, encryptedValuePrefix)
+	if !ok {
+		return stored, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %w", err)
+	}
+	plain, err := openBytes(sealed)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// sealBytes encrypts data with DATABASE_ENCRYPTION_KEY, the random nonce is prepended to the ciphertext
+func sealBytes(data []byte) ([]byte, error) {
+	nonce := make([]byte, databaseCipher.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return databaseCipher.Seal(nonce, nonce, data, nil), nil
+}
+
+// openBytes decrypts data encrypted by sealBytes
+func openBytes(sealed []byte) ([]byte, error) {
+	if databaseCipher == nil {
+		return nil, errDatabaseEncryptionKeyMissing
+	}
+	nonceSize := databaseCipher.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("invalid encrypted value: too short")
+	}
+	plain, err := databaseCipher.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value, is DATABASE_ENCRYPTION_KEY right? %w", err)
+	}
+	return plain, nil
+}
+
+// encryptedSerializer stores string fields encrypted with DATABASE_ENCRYPTION_KEY
+type encryptedSerializer struct{}
+
+func (encryptedSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var stored string
+	switch value := dbValue.(type) {
+	case nil:
+	case string:
+		stored = value
+	case []byte:
+		stored = string(value)
+	default:
+		return fmt.Errorf("failed to decrypt %s: unsupported value %#v", field.Name, dbValue)
+	}
+	value, err := decryptValue(stored)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(value)
+	return nil
+}
+
+func (encryptedSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("failed to encrypt %s: not a string", field.Name)
+	}
+	return encryptValue(value)
+}
+
+// encryptStoredValues encrypts the values stored as plaintext before DATABASE_ENCRYPTION_KEY was set and returns the
+// number of rows encrypted
+func encryptStoredValues(db *gorm.DB) (int64, error) {
+	if databaseCipher == nil {
+		return 0, nil
+	}
+
+	var encrypted int64
+	for _, table := range encryptedColumns {
+		conditions := make([]string, 0, len(table.columns))
+		var args []interface{}
+		for _, column := range table.columns {
+			conditions = append(conditions, fmt.Sprintf("(%s <> '' AND %s NOT LIKE ?)", column, column))
+			args = append(args, encryptedValuePrefix+"%")
+		}
+
+		// The columns are written again, which encrypts them
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(table.model)))
+		err := db.Model(table.model).Where(strings.Join(conditions, " OR "), args...).
+			FindInBatches(rows.Interface(), 100, func(tx *gorm.DB, _ int) error {
+				for i := 0; i < rows.Elem().Len(); i++ {
+					row := rows.Elem().Index(i).Interface()
+					if err := db.Model(row).Select(table.columns).UpdateColumns(row).Error; err != nil {
+						return err
+					}
+				}
+				encrypted += tx.RowsAffected
+				return nil
+			}).Error
+		if err != nil {
+			return encrypted, err
+		}
+	}
+	return encrypted, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseEncryption(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+	defer setDatabaseEncryptionKey("")
+
+	// Stored before DATABASE_ENCRYPTION_KEY was set
+	plain := ModificationHistory{DocumentID: 1, DateChanged: "2024-05-01T10:00:00Z", ModField: "content", PreviousValue: "Dear tenant", NewValue: "Dear landlord"}
+	require.NoError(t, db.Create(&plain).Error)
+
+	require.NoError(t, setDatabaseEncryptionKey("secret"))
+	encrypted := ModificationHistory{DocumentID: 2, DateChanged: "2024-05-02T10:00:00Z", ModField: "title", PreviousValue: "scan.pdf", NewValue: "Lease"}
+	require.NoError(t, db.Create(&encrypted).Error)
+
+	stored := func(id uint) (previous, new string) {
+		row := db.Table("modification_histories").Select("previous_value, new_value").Where("id = ?", id).Row()
+		require.NoError(t, row.Scan(&previous, &new))
+		return previous, new
+	}
+	previous, _ := stored(encrypted.ID)
+	assert.True(t, strings.HasPrefix(previous, encryptedValuePrefix))
+	assert.NotContains(t, previous, "scan.pdf")
+	previous, _ = stored(plain.ID)
+	assert.Equal(t, "Dear tenant", previous)
+
+	var modifications []ModificationHistory
+	require.NoError(t, db.Order("id").Find(&modifications).Error)
+	require.Len(t, modifications, 2)
+	assert.Equal(t, "Dear tenant", modifications[0].PreviousValue, "plaintext is still read")
+	assert.Equal(t, "scan.pdf", modifications[1].PreviousValue)
+	assert.Equal(t, "Lease", modifications[1].NewValue)
+
+	count, err := encryptStoredValues(db)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+	previous, new := stored(plain.ID)
+	assert.True(t, strings.HasPrefix(previous, encryptedValuePrefix))
+	assert.True(t, strings.HasPrefix(new, encryptedValuePrefix))
+	var modification ModificationHistory
+	require.NoError(t, db.First(&modification, plain.ID).Error)
+	assert.Equal(t, "Dear landlord", modification.NewValue)
+
+	require.NoError(t, setDatabaseEncryptionKey("wrong"))
+	assert.Error(t, db.First(&modification, plain.ID).Error)
+	require.NoError(t, setDatabaseEncryptionKey(""))
+	assert.ErrorIs(t, db.First(&modification, plain.ID).Error, errDatabaseEncryptionKeyMissing)
+}
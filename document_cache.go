@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// cachedDocumentFile is the downloaded document inside the cache directory of a document
+	cachedDocumentFile = "document.pdf"
+	// cachedChecksumFile holds the checksum of the document the cached files were created from
+	cachedChecksumFile = "checksum"
+	// cachedPagesCompleteFile marks that all pages of the document were rendered, not just the first few
+	cachedPagesCompleteFile = "pages-complete"
+	// cachedRenderSettingsFile holds the settings the cached pages were rendered with
+	cachedRenderSettingsFile = "render-settings"
+)
+
+// getDocumentChecksum returns the checksum of the file paperless-ngx serves for downloads,
+// which is the archived version if there is one and the original otherwise
+func (client *PaperlessClient) getDocumentChecksum(ctx context.Context, documentID int) (string, error) {
+	path := fmt.Sprintf("api/documents/%d/metadata/", documentID)
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("error fetching metadata of document %d: %w", documentID, errDocumentNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("error fetching metadata of document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	var metadata struct {
+		OriginalChecksum  string `json:"original_checksum"`
+		ArchiveChecksum   string `json:"archive_checksum"`
+		HasArchiveVersion bool   `json:"has_archive_version"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&metadata)
+	if err != nil {
+		return "", err
+	}
+
+	if metadata.HasArchiveVersion && metadata.ArchiveChecksum != "" {
+		return metadata.ArchiveChecksum, nil
+	}
+	return metadata.OriginalChecksum, nil
+}
+
+// documentCacheDir returns the cache directory of a document, which holds the downloaded file and the rendered pages.
+// Files cached for a different checksum are removed, so a document that changed in paperless-ngx is downloaded again.
+func (client *PaperlessClient) documentCacheDir(ctx context.Context, documentID int) (string, error) {
+	docDir := filepath.Join(client.GetCacheFolder(), fmt.Sprintf("document-%d", documentID))
+
+	checksum, err := client.getDocumentChecksum(ctx, documentID)
+	if errors.Is(err, errDocumentNotFound) {
+		return "", err
+	}
+	if err != nil {
+		// Without a checksum the cache can't be validated, but it is still better than failing the download
+		log.Warnf("Could not verify cached files of document %d: %v", documentID, err)
+		return docDir, os.MkdirAll(docDir, 0755)
+	}
+
+	client.cacheMu.Lock()
+	defer client.cacheMu.Unlock()
+
+	checksumPath := filepath.Join(docDir, cachedChecksumFile)
+	cachedChecksum, err := os.ReadFile(checksumPath)
+	if err == nil && strings.TrimSpace(string(cachedChecksum)) == checksum {
+		return docDir, nil
+	}
+
+	if err == nil {
+		log.Debugf("Document %d changed in paperless-ngx, dropping cached files", documentID)
+	}
+	if err := os.RemoveAll(docDir); err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(docDir, 0755); err != nil {
+		return "", err
+	}
+	return docDir, os.WriteFile(checksumPath, []byte(checksum), 0644)
+}
+
+// dropPagesRenderedDifferently removes the cached pages of a document if they were rendered with other settings, e.g.
+// before PDF_RENDER_DPI was changed
+func (client *PaperlessClient) dropPagesRenderedDifferently(docDir string, settings pdfRenderSettings) error {
+	client.cacheMu.Lock()
+	defer client.cacheMu.Unlock()
+
+	settingsPath := filepath.Join(docDir, cachedRenderSettingsFile)
+	cachedSettings, err := os.ReadFile(settingsPath)
+	if err == nil && string(cachedSettings) == settings.String() {
+		return nil
+	}
+	pages, err := filepath.Glob(filepath.Join(docDir, "page*.jpg"))
+	if err != nil {
+		return err
+	}
+	for _, page := range append(pages, filepath.Join(docDir, cachedPagesCompleteFile)) {
+		if err := os.Remove(page); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.WriteFile(settingsPath, []byte(settings.String()), 0644)
+}
+
+// downloadDocument returns the document file from the cache directory, downloading it from paperless-ngx if needed
+func (client *PaperlessClient) downloadDocument(ctx context.Context, documentID int, docDir string) ([]byte, error) {
+	cachedPath := filepath.Join(docDir, cachedDocumentFile)
+	if data, err := os.ReadFile(cachedPath); err == nil {
+		log.Debugf("Using cached download of document %d", documentID)
+		return data, nil
+	}
+
+	path := fmt.Sprintf("api/documents/%d/download/", documentID)
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("error downloading document %d: %w", documentID, errDocumentNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error downloading document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	// Write to a temporary file first, so a concurrent reader never sees a partial download
+	tmpFile, err := os.CreateTemp(docDir, "download-*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmpFile.Name())
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return nil, err
+	}
+	if err := tmpFile.Close(); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmpFile.Name(), cachedPath); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// RemoveCachedDocument removes the cached download and pages of a document
+func (client *PaperlessClient) RemoveCachedDocument(documentID int) error {
+	client.cacheMu.Lock()
+	defer client.cacheMu.Unlock()
+	return os.RemoveAll(filepath.Join(client.GetCacheFolder(), fmt.Sprintf("document-%d", documentID)))
+}
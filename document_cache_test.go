@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentCache_ReusesDownloadsAndPages(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.CacheFolder = t.TempDir()
+
+	pdfContent, err := os.ReadFile("tests/pdf/many-pages.pdf")
+	require.NoError(t, err)
+
+	downloads := 0
+	env.setMockResponse("/api/documents/7/download/", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdfContent)
+	})
+	env.mockDocumentMetadata(7, pdfContent)
+
+	ctx := context.Background()
+	imagePaths, err := env.client.DownloadDocumentAsImages(ctx, 7, 2)
+	require.NoError(t, err)
+	assert.Len(t, imagePaths, 2)
+
+	// The same pages are served from the cache
+	cachedPaths, err := env.client.DownloadDocumentAsImages(ctx, 7, 2)
+	require.NoError(t, err)
+	assert.Equal(t, imagePaths, cachedPaths)
+
+	// More pages are rendered from the cached download
+	imagePaths, err = env.client.DownloadDocumentAsImages(ctx, 7, 4)
+	require.NoError(t, err)
+	assert.Len(t, imagePaths, 4)
+
+	data, err := env.client.DownloadPDF(ctx, Document{ID: 7})
+	require.NoError(t, err)
+	assert.Equal(t, pdfContent, data)
+	assert.Equal(t, 1, downloads)
+}
+
+func TestDocumentCache_InvalidatedByChecksum(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.CacheFolder = t.TempDir()
+
+	original, err := os.ReadFile("tests/pdf/sample.pdf")
+	require.NoError(t, err)
+	changed, err := os.ReadFile("tests/pdf/many-pages.pdf")
+	require.NoError(t, err)
+
+	content := original
+	downloads := 0
+	env.setMockResponse("/api/documents/8/download/", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.WriteHeader(http.StatusOK)
+		w.Write(content)
+	})
+	env.mockDocumentMetadata(8, original)
+
+	ctx := context.Background()
+	imagePaths, err := env.client.DownloadDocumentAsImages(ctx, 8, 0)
+	require.NoError(t, err)
+	assert.Len(t, imagePaths, 1)
+
+	// The archived version was regenerated, so the cached files no longer match
+	content = changed
+	env.mockDocumentMetadata(8, changed)
+	imagePaths, err = env.client.DownloadDocumentAsImages(ctx, 8, 3)
+	require.NoError(t, err)
+	assert.Len(t, imagePaths, 3)
+	assert.Equal(t, 2, downloads)
+}
+
+func TestDocumentCache_InvalidatedByRenderSettings(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.CacheFolder = t.TempDir()
+
+	pdfContent, err := os.ReadFile("tests/pdf/sample.pdf")
+	require.NoError(t, err)
+	env.setMockResponse("/api/documents/10/download/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write(pdfContent)
+	})
+	env.mockDocumentMetadata(10, pdfContent)
+
+	ctx := context.Background()
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.PDFRenderDPI, settings.PDFRenderColorMode = 150, pdfColorModeColor
+	})
+	imagePaths, err := env.client.DownloadDocumentAsImages(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, imagePaths, 1)
+	colorPage := decodeCachedPage(t, imagePaths[0])
+
+	// The page is rendered again with the new settings
+	setSettings(t, func(settings *runtimeSettings) { settings.PDFRenderColorMode = pdfColorModeGray })
+	imagePaths, err = env.client.DownloadDocumentAsImages(ctx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, imagePaths, 1)
+	grayPage := decodeCachedPage(t, imagePaths[0])
+	assert.IsType(t, &image.Gray{}, grayPage)
+	assert.NotEqual(t, colorPage.ColorModel(), grayPage.ColorModel())
+}
+
+// decodeCachedPage reads a rendered page image
+func decodeCachedPage(t *testing.T, path string) image.Image {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	img, err := jpeg.Decode(file)
+	require.NoError(t, err)
+	return img
+}
+
+func TestGetDocumentChecksum_PrefersArchive(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/9/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"original_checksum": "aaa", "archive_checksum": "bbb", "has_archive_version": true}`))
+	})
+
+	checksum, err := env.client.getDocumentChecksum(context.Background(), 9)
+	require.NoError(t, err)
+	assert.Equal(t, "bbb", checksum)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// trackDocumentFailures wraps the processing of documents selected by the tag. Failures are recorded, and documents
+// failing MAX_DOCUMENT_FAILURES times in a row are dead-lettered instead of being retried forever.
+func (app *App) trackDocumentFailures(ctx context.Context, tag string, process func(document Document) (bool, error)) func(document Document) (bool, error) {
+	return func(document Document) (bool, error) {
+		processed, err := process(document)
+		if err != nil {
+			app.recordDocumentFailure(ctx, document.ID, tag, err)
+		} else if processed {
+			if err := ClearDocumentFailures(app.Database, uint(document.ID)); err != nil {
+				documentLogger(document.ID).Warnf("Failed to clear earlier failures: %v", err)
+			}
+		}
+		return processed, err
+	}
+}
+
+// recordDocumentFailure counts a failure of the document and dead-letters it once it failed too often
+func (app *App) recordDocumentFailure(ctx context.Context, documentID int, tag string, processErr error) {
+	docLogger := documentLogger(documentID)
+	failure, err := RecordDocumentFailure(app.Database, uint(documentID), tag, processErr.Error())
+	if err != nil {
+		docLogger.Errorf("Failed to record processing failure: %v", err)
+		return
+	}
+	maxFailures := settingsFor(ctx).MaxDocumentFailures
+	deadLettered := maxFailures > 0 && failure.Failures >= maxFailures
+	events.publish(EventDocumentFailed, map[string]any{"document_id": documentID, "failures": failure.Failures, "reason": failure.Reason, "dead_lettered": deadLettered})
+	if !deadLettered {
+		return
+	}
+
+	docLogger.Warnf("Processing failed %d times, giving up on the document: %s", failure.Failures, failure.Reason)
+	if err := app.deadLetterDocument(ctx, failure); err != nil {
+		docLogger.Errorf("Failed to dead-letter document: %v", err)
+	}
+}
+
+// deadLetterDocument replaces the tag that selected the document for processing with the ERROR_TAG
+func (app *App) deadLetterDocument(ctx context.Context, failure *DocumentFailure) error {
+	documentID := int(failure.DocumentID)
+	availableTags, err := app.Client.GetAllTags(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching tags: %w", err)
+	}
+
+	errorTag := settingsFor(ctx).ErrorTag
+	errorTagID, exists := availableTags[errorTag]
+	if !exists {
+		errorTagID, err = app.Client.CreateTag(ctx, errorTag)
+		if err != nil {
+			return fmt.Errorf("error creating tag %s: %w", errorTag, err)
+		}
+	}
+	if err := app.Client.AddTagToDocuments(ctx, []int{documentID}, errorTagID); err != nil {
+		return fmt.Errorf("error adding tag %s: %w", errorTag, err)
+	}
+	if tagID, exists := availableTags[failure.Tag]; exists {
+		if err := app.Client.RemoveTagFromDocuments(ctx, []int{documentID}, tagID); err != nil {
+			return fmt.Errorf("error removing tag %s: %w", failure.Tag, err)
+		}
+	}
+
+	// Documents selected by a filter query still match it, so they are remembered as processed
+	if err := MarkDocumentProcessed(app.Database, failure.DocumentID); err != nil {
+		return err
+	}
+	return MarkDocumentDeadLettered(app.Database, failure)
+}
+
+// retryDeadLetteredDocument removes the ERROR_TAG and tags the document for processing again
+func (app *App) retryDeadLetteredDocument(ctx context.Context, failure *DocumentFailure) error {
+	documentID := int(failure.DocumentID)
+	availableTags, err := app.Client.GetAllTags(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching tags: %w", err)
+	}
+
+	errorTag := settingsFor(ctx).ErrorTag
+	if errorTagID, exists := availableTags[errorTag]; exists {
+		if err := app.Client.RemoveTagFromDocuments(ctx, []int{documentID}, errorTagID); err != nil {
+			return fmt.Errorf("error removing tag %s: %w", errorTag, err)
+		}
+	}
+	tagID, exists := availableTags[failure.Tag]
+	if !exists {
+		tagID, err = app.Client.CreateTag(ctx, failure.Tag)
+		if err != nil {
+			return fmt.Errorf("error creating tag %s: %w", failure.Tag, err)
+		}
+	}
+	if err := app.Client.AddTagToDocuments(ctx, []int{documentID}, tagID); err != nil {
+		return fmt.Errorf("error adding tag %s: %w", failure.Tag, err)
+	}
+
+	return ClearDocumentFailures(app.Database, failure.DocumentID)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadLetterDocument(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&DocumentFailure{}).Error)
+
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.ErrorTag, settings.MaxDocumentFailures = "paperless-gpt-error", 2
+	})
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 9}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "paperless-gpt-auto"}], "next": null}`))
+	})
+	type bulkEdit struct {
+		Method     string         `json:"method"`
+		Parameters map[string]int `json:"parameters"`
+	}
+	var bulkEdits []bulkEdit
+	env.setMockResponse("/api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		var edit bulkEdit
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&edit))
+		bulkEdits = append(bulkEdits, edit)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result": "OK"}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	failing := app.trackDocumentFailures(context.Background(), "paperless-gpt-auto", func(document Document) (bool, error) {
+		return false, errors.New("corrupt PDF")
+	})
+
+	_, err := failing(Document{ID: 31})
+	require.Error(t, err)
+	assert.Empty(t, bulkEdits, "the first failure is retried")
+
+	_, err = failing(Document{ID: 31})
+	require.Error(t, err)
+	assert.Equal(t, []bulkEdit{
+		{Method: "add_tag", Parameters: map[string]int{"tag": 9}},
+		{Method: "remove_tag", Parameters: map[string]int{"tag": 1}},
+	}, bulkEdits)
+
+	failures, err := GetDeadLetteredDocuments(env.db)
+	require.NoError(t, err)
+	require.Len(t, failures, 1)
+	assert.Equal(t, uint(31), failures[0].DocumentID)
+	assert.Equal(t, 2, failures[0].Failures)
+	assert.Equal(t, "corrupt PDF", failures[0].Reason)
+
+	// Retrying tags the document for processing again
+	bulkEdits = nil
+	require.NoError(t, app.retryDeadLetteredDocument(context.Background(), &failures[0]))
+	assert.Equal(t, []bulkEdit{{Method: "add_tag", Parameters: map[string]int{"tag": 1}}}, bulkEdits)
+	failures, err = GetDeadLetteredDocuments(env.db)
+	require.NoError(t, err)
+	assert.Empty(t, failures)
+}
+
+func TestTrackDocumentFailures_ClearedOnSuccess(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+
+	app := &App{Database: db}
+	_, err = RecordDocumentFailure(db, 32, "paperless-gpt-auto", "timeout")
+	require.NoError(t, err)
+
+	processed, err := app.trackDocumentFailures(context.Background(), "paperless-gpt-auto", func(document Document) (bool, error) {
+		return true, nil
+	})(Document{ID: 32})
+	require.NoError(t, err)
+	assert.True(t, processed)
+
+	_, err = GetDocumentFailure(db, 32)
+	assert.Error(t, err)
+}
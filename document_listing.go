@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultDocumentListPageSize is the number of documents GET /api/documents returns without page_size
+	defaultDocumentListPageSize = 25
+	// maxDocumentListPageSize keeps single responses small enough for the web UI
+	maxDocumentListPageSize = 100
+)
+
+// documentListSortKeys maps the sort keys of GET /api/documents to the ordering fields of paperless-ngx
+var documentListSortKeys = map[string]string{
+	"created":       "created",
+	"added":         "added",
+	"modified":      "modified",
+	"title":         "title",
+	"correspondent": "correspondent__name",
+	"document_type": "document_type__name",
+	"asn":           "archive_serial_number",
+}
+
+// documentListOptions are the pagination, sorting and filters of GET /api/documents
+type documentListOptions struct {
+	Page          int
+	PageSize      int
+	Sort          string   // Sort key, prefixed with "-" for descending order
+	Search        string   // Text the title or content must contain
+	Tags          []string // Names of tags the documents must all have
+	Correspondent string   // Name of the correspondent
+}
+
+// parseDocumentListOptions reads the options from the query parameters page, page_size, sort, search, tag and
+// correspondent. tag may be given multiple times.
+func parseDocumentListOptions(values url.Values) (documentListOptions, error) {
+	page, pageSize, err := parseDocumentPage(values)
+	if err != nil {
+		return documentListOptions{}, err
+	}
+	options := documentListOptions{
+		Page:          page,
+		PageSize:      pageSize,
+		Sort:          strings.TrimSpace(values.Get("sort")),
+		Search:        strings.TrimSpace(values.Get("search")),
+		Correspondent: strings.TrimSpace(values.Get("correspondent")),
+	}
+	if options.Sort != "" {
+		if _, ok := documentListSortKeys[strings.TrimPrefix(options.Sort, "-")]; !ok {
+			keys := make([]string, 0, len(documentListSortKeys))
+			for key := range documentListSortKeys {
+				keys = append(keys, key)
+			}
+			slices.Sort(keys)
+			return documentListOptions{}, fmt.Errorf("unknown sort key %q, use one of %s", options.Sort, strings.Join(keys, ", "))
+		}
+	}
+	for _, tag := range values["tag"] {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			options.Tags = append(options.Tags, tag)
+		}
+	}
+	return options, nil
+}
+
+// parseDocumentPage reads the page and page_size query parameters of document listings
+func parseDocumentPage(values url.Values) (int, int, error) {
+	page, pageSize := 1, defaultDocumentListPageSize
+	if text := values.Get("page"); text != "" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed <= 0 {
+			return 0, 0, fmt.Errorf("page must be a positive number, got: %s", text)
+		}
+		page = parsed
+	}
+	if text := values.Get("page_size"); text != "" {
+		parsed, err := strconv.Atoi(text)
+		if err != nil || parsed <= 0 || parsed > maxDocumentListPageSize {
+			return 0, 0, fmt.Errorf("page_size must be between 1 and %d, got: %s", maxDocumentListPageSize, text)
+		}
+		pageSize = parsed
+	}
+	return page, pageSize, nil
+}
+
+// filterQuery converts the sorting and filters to a paperless-ngx filter query, resolving the tag names with the
+// tag IDs by name. It reports false if a tag doesn't exist, so no document can match.
+func (options documentListOptions) filterQuery(tagIDs map[string]int) (string, bool) {
+	query := url.Values{}
+	if options.Sort != "" {
+		field := documentListSortKeys[strings.TrimPrefix(options.Sort, "-")]
+		if strings.HasPrefix(options.Sort, "-") {
+			field = "-" + field
+		}
+		query.Set("ordering", field)
+	}
+	if options.Search != "" {
+		query.Set("title_content", options.Search)
+	}
+	if options.Correspondent != "" {
+		query.Set("correspondent__name__iexact", options.Correspondent)
+	}
+	if len(options.Tags) > 0 {
+		ids := make([]string, 0, len(options.Tags))
+		for _, tag := range options.Tags {
+			id, ok := findTagID(tagIDs, tag)
+			if !ok {
+				return "", false
+			}
+			ids = append(ids, strconv.Itoa(id))
+		}
+		query.Set("tags__id__all", strings.Join(ids, ","))
+	}
+	return query.Encode(), true
+}
+
+// findTagID returns the ID of the tag with the name, ignoring case like paperless-ngx does
+func findTagID(tagIDs map[string]int, name string) (int, bool) {
+	if id, ok := tagIDs[name]; ok {
+		return id, true
+	}
+	for tagName, id := range tagIDs {
+		if strings.EqualFold(tagName, name) {
+			return id, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDocumentListOptions(t *testing.T) {
+	options, err := parseDocumentListOptions(url.Values{})
+	require.NoError(t, err)
+	assert.Equal(t, documentListOptions{Page: 1, PageSize: defaultDocumentListPageSize}, options)
+
+	options, err = parseDocumentListOptions(url.Values{
+		"page": {"3"}, "page_size": {"50"}, "sort": {"-created"}, "search": {" electricity "},
+		"tag": {"invoice", "", "2024"}, "correspondent": {"ACME Corp"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, documentListOptions{
+		Page: 3, PageSize: 50, Sort: "-created", Search: "electricity", Tags: []string{"invoice", "2024"}, Correspondent: "ACME Corp",
+	}, options)
+
+	for _, invalid := range []url.Values{{"page": {"0"}}, {"page_size": {"1000"}}, {"page_size": {"all"}}, {"sort": {"owner"}}} {
+		_, err := parseDocumentListOptions(invalid)
+		assert.Error(t, err, invalid.Encode())
+	}
+}
+
+func TestDocumentListFilterQuery(t *testing.T) {
+	tagIDs := map[string]int{"Invoice": 1, "2024": 4}
+	query, ok := documentListOptions{Sort: "-correspondent", Search: "tax & fees", Tags: []string{"invoice", "2024"}, Correspondent: "ACME Corp"}.filterQuery(tagIDs)
+	require.True(t, ok)
+	assert.Equal(t, "correspondent__name__iexact=ACME+Corp&ordering=-correspondent__name&tags__id__all=1%2C4&title_content=tax+%26+fees", query)
+
+	_, ok = documentListOptions{Tags: []string{"missing"}}.filterQuery(tagIDs)
+	assert.False(t, ok, "unknown tags match no documents")
+}
+
+func TestDocumentsHandlerPagination(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	setSettings(t, func(settings *runtimeSettings) { settings.ManualTag = "paperless-gpt" })
+
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "paperless-gpt", query.Get("tags__name__iexact"))
+		assert.Equal(t, "2", query.Get("page"))
+		assert.Equal(t, "10", query.Get("page_size"))
+		assert.Equal(t, "-added", query.Get("ordering"))
+		assert.Equal(t, "7", query.Get("tags__id__all"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count": 42, "next": null, "results": [{"id": 11, "title": "Electricity bill", "tags": [7]}]}`))
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 7, "name": "invoice"}], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/documents", app.documentsHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/documents?page=2&page_size=10&sort=-added&tag=invoice", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "42", w.Header().Get("X-Total-Count"))
+	var documents []Document
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &documents))
+	require.Len(t, documents, 1)
+	assert.Equal(t, []string{"invoice"}, documents[0].Tags)
+
+	// Documents can't have tags that don't exist
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/documents?tag=missing", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "0", w.Header().Get("X-Total-Count"))
+	assert.JSONEq(t, `[]`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/documents?sort=owner", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
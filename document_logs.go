@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// documentLogFlushInterval is the interval in which the captured log lines of documents are written to the database
+const documentLogFlushInterval = time.Second
+
+// maxPendingDocumentLogs limits the lines waiting to be written, further lines are dropped until the next flush
+const maxPendingDocumentLogs = 10000
+
+// Limits of the stored lines, longer messages like prompts and field values like errors are cut off
+const (
+	maxDocumentLogMessage    = 65536
+	maxDocumentLogFieldValue = 1024
+)
+
+// Number of lines returned by GET /api/documents/:id/logs by default and at most
+const (
+	defaultDocumentLogLimit = 500
+	maxDocumentLogLimit     = 5000
+)
+
+// DocumentLogLine is a captured log line of a document, returned by GET /api/documents/:id/logs
+type DocumentLogLine struct {
+	Time    string                 `json:"time"`
+	Level   string                 `json:"level"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+}
+
+// documentLogHook captures the lines logged with a document_id field and writes them to the local database in batches,
+// so the processing of a document can be followed without searching the logs of the container
+type documentLogHook struct {
+	db     *gorm.DB
+	levels []logrus.Level
+
+	mu      sync.Mutex
+	pending []DocumentLog
+	dropped int
+}
+
+// startDocumentLogCapture captures the lines of documents at the level of DOCUMENT_LOG_LEVEL until the context is
+// cancelled. It returns nil if the capture is turned off.
+func startDocumentLogCapture(ctx context.Context, db *gorm.DB, level string) *documentLogHook {
+	levels, err := documentLogLevels(level)
+	if err != nil || len(levels) == 0 {
+		return nil
+	}
+	hook := &documentLogHook{db: db, levels: levels}
+	log.AddHook(hook)
+	go hook.run(ctx)
+	return hook
+}
+
+// Levels returns the captured levels
+func (hook *documentLogHook) Levels() []logrus.Level {
+	return hook.levels
+}
+
+// Fire queues a line of a document to be written with the next flush
+func (hook *documentLogHook) Fire(entry *logrus.Entry) error {
+	var documentID uint
+	switch id := entry.Data["document_id"].(type) {
+	case int:
+		documentID = uint(max(id, 0))
+	case uint:
+		documentID = id
+	}
+	if documentID == 0 {
+		return nil
+	}
+
+	record := DocumentLog{
+		DocumentID: documentID,
+		Level:      entry.Level.String(),
+		Message:    truncateString(entry.Message, maxDocumentLogMessage),
+		Fields:     documentLogFields(entry.Data),
+		LoggedAt:   entry.Time.Format(time.RFC3339),
+	}
+	hook.mu.Lock()
+	if len(hook.pending) < maxPendingDocumentLogs {
+		hook.pending = append(hook.pending, record)
+	} else {
+		hook.dropped++
+	}
+	hook.mu.Unlock()
+
+	// The process exits after fatal lines, so they are written right away
+	if entry.Level <= logrus.FatalLevel {
+		hook.flush()
+	}
+	return nil
+}
+
+// run flushes the captured lines periodically and a last time when the context is cancelled
+func (hook *documentLogHook) run(ctx context.Context) {
+	ticker := time.NewTicker(documentLogFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			hook.flush()
+			return
+		case <-ticker.C:
+			hook.flush()
+		}
+	}
+}
+
+// flush writes the captured lines to the database
+func (hook *documentLogHook) flush() {
+	if hook == nil {
+		return
+	}
+	hook.mu.Lock()
+	records, dropped := hook.pending, hook.dropped
+	hook.pending, hook.dropped = nil, 0
+	hook.mu.Unlock()
+
+	if dropped > 0 {
+		log.Warnf("Dropped %d log lines of documents, they were logged faster than they could be stored", dropped)
+	}
+	if len(records) == 0 {
+		return
+	}
+	if err := hook.db.CreateInBatches(records, 100).Error; err != nil {
+		log.Errorf("Failed to store %d log lines of documents: %v", len(records), err)
+	}
+}
+
+// documentLogFields returns the fields of a line except the document ID as JSON, errors as their message
+func documentLogFields(data logrus.Fields) string {
+	fields := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key == "document_id" {
+			continue
+		}
+		switch value := value.(type) {
+		case error:
+			fields[key] = truncateString(value.Error(), maxDocumentLogFieldValue)
+		case string:
+			fields[key] = truncateString(value, maxDocumentLogFieldValue)
+		default:
+			fields[key] = value
+		}
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+	encoded, err := json.Marshal(fields)
+	if err != nil {
+		return fmt.Sprintf(`{"error":%q}`, "fields can't be encoded: "+err.Error())
+	}
+	return string(encoded)
+}
+
+// truncateString cuts a string off after maxBytes, at the start of the rune exceeding the limit
+func truncateString(value string, maxBytes int) string {
+	if len(value) <= maxBytes {
+		return value
+	}
+	end := maxBytes
+	for end > 0 && !utf8.RuneStart(value[end]) {
+		end--
+	}
+	return value[:end]
+}
+
+// documentLogLines returns the stored lines of a document, with their fields decoded
+func documentLogLines(records []DocumentLog) []DocumentLogLine {
+	lines := make([]DocumentLogLine, 0, len(records))
+	for _, record := range records {
+		line := DocumentLogLine{Time: record.LoggedAt, Level: record.Level, Message: record.Message}
+		if record.Fields != "" {
+			if err := json.Unmarshal([]byte(record.Fields), &line.Fields); err != nil {
+				line.Fields = map[string]interface{}{"fields": record.Fields}
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pruneDocumentLogs deletes the log lines of documents older than the given number of days, 0 keeps them
+func (app *App) pruneDocumentLogs(retentionDays int, now time.Time) (int64, error) {
+	if retentionDays <= 0 {
+		return 0, nil
+	}
+	deleted, err := PruneDocumentLogs(app.Database, now.AddDate(0, 0, -retentionDays))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Infof("Pruned %d log lines of documents", deleted)
+	}
+	return deleted, nil
+}
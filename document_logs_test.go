@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentLogCapture(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&DocumentLog{}).Error)
+	defer db.Where("1 = 1").Delete(&DocumentLog{})
+
+	levels, err := documentLogLevels("")
+	require.NoError(t, err)
+	hook := &documentLogHook{db: db, levels: levels}
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(hook)
+
+	logger.WithField("document_id", 544).Info("Suggested correspondent for document 544: ACME")
+	logger.WithField("document_id", 544).Debug("Correspondent suggestion prompt: ...")
+	logger.WithFields(logrus.Fields{"document_id": uint(544), "task": "title"}).WithError(errors.New("timeout")).Warn("title request failed")
+	logger.WithField("document_id", 545).Info("Processing Document ID 545...")
+	logger.Info("Background tasks started")
+	hook.flush()
+
+	records, err := GetDocumentLogs(db, 544, 10)
+	require.NoError(t, err)
+	lines := documentLogLines(records)
+	require.Len(t, lines, 2, "debug lines aren't captured at info level")
+	assert.Equal(t, "info", lines[0].Level)
+	assert.Equal(t, "Suggested correspondent for document 544: ACME", lines[0].Message)
+	assert.Empty(t, lines[0].Fields)
+	assert.Equal(t, "warning", lines[1].Level)
+	assert.Equal(t, map[string]interface{}{"task": "title", "error": "timeout"}, lines[1].Fields)
+
+	// The newest lines are returned, oldest first
+	records, err = GetDocumentLogs(db, 544, 1)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "title request failed", records[0].Message)
+
+	var count int64
+	require.NoError(t, db.Model(&DocumentLog{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	// Old lines are pruned
+	app := &App{Database: db}
+	deleted, err := app.pruneDocumentLogs(30, time.Now().AddDate(0, 0, 31))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
+
+func TestDocumentLogLevels(t *testing.T) {
+	levels, err := documentLogLevels("debug")
+	require.NoError(t, err)
+	assert.Contains(t, levels, logrus.DebugLevel)
+
+	levels, err = documentLogLevels("off")
+	require.NoError(t, err)
+	assert.Empty(t, levels)
+
+	_, err = documentLogLevels("trace")
+	assert.ErrorContains(t, err, "'trace' is not one of off, error, warn, info, debug")
+}
+
+func TestLevelWriterHook(t *testing.T) {
+	var output bytes.Buffer
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.SetOutput(io.Discard)
+	logger.SetLevel(logrus.DebugLevel)
+	logger.AddHook(newLevelWriterHook(&output, logrus.InfoLevel))
+
+	logger.Debug("prompt")
+	logger.Info("processed")
+
+	assert.NotContains(t, output.String(), "prompt")
+	assert.Contains(t, output.String(), `"msg":"processed"`)
+}
+
+func TestTruncateString(t *testing.T) {
+	assert.Equal(t, "abc", truncateString("abc", 5))
+	assert.Equal(t, "Gr", truncateString("Grüße", 3), "a rune isn't cut in half")
+}
+
+func TestGetDocumentLogsHandler(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&DocumentLog{}).Error)
+	defer db.Where("1 = 1").Delete(&DocumentLog{})
+	require.NoError(t, db.Create(&DocumentLog{DocumentID: 544, Level: "info", Message: "Processing Document ID 544...", Fields: `{"job_id":"j1"}`, LoggedAt: "2025-01-31T09:12:44Z"}).Error)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	app := &App{Database: db}
+	router.GET("/api/documents/:id/logs", app.getDocumentLogsHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/documents/544/logs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		DocumentID int               `json:"document_id"`
+		Logs       []DocumentLogLine `json:"logs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 544, response.DocumentID)
+	require.Len(t, response.Logs, 1)
+	assert.Equal(t, "Processing Document ID 544...", response.Logs[0].Message)
+	assert.Equal(t, "j1", response.Logs[0].Fields["job_id"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/documents/545/logs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"logs":[]`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/documents/544/logs?limit=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
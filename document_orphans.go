@@ -0,0 +1,19 @@
+package main
+
+import "errors"
+
+// errDocumentNotFound is returned when paperless-ngx responds with 404 for a document, usually because it was deleted
+var errDocumentNotFound = errors.New("document not found in paperless-ngx")
+
+// skipDeletedDocument reports whether err means the document was deleted in paperless-ngx.
+// The document is then marked as orphaned, so its local records can be purged, and processing it again is pointless.
+func (app *App) skipDeletedDocument(documentID int, err error) bool {
+	if !errors.Is(err, errDocumentNotFound) {
+		return false
+	}
+	documentLogger(documentID).Warn("Document was deleted in paperless-ngx, skipping it")
+	if err := MarkDocumentOrphaned(app.Database, uint(documentID)); err != nil {
+		documentLogger(documentID).Errorf("Failed to mark document as orphaned: %v", err)
+	}
+	return true
+}
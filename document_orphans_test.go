@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkipDeletedDocument(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&OrphanedDocument{}).Error)
+
+	env.setMockResponse("/api/documents/5/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"detail": "No Document matches the given query."}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+
+	_, err := env.client.GetDocument(context.Background(), 5)
+	require.ErrorIs(t, err, errDocumentNotFound)
+	assert.True(t, app.skipDeletedDocument(5, err))
+	// Noticing the deletion again doesn't add another record
+	assert.True(t, app.skipDeletedDocument(5, err))
+	assert.False(t, app.skipDeletedDocument(6, errors.New("error fetching document 6: 500")))
+
+	orphanedDocuments, err := GetOrphanedDocuments(env.db)
+	require.NoError(t, err)
+	require.Len(t, orphanedDocuments, 1)
+	assert.Equal(t, uint(5), orphanedDocuments[0].DocumentID)
+}
+
+func TestPurgeOrphanedDocuments(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&OrphanedDocument{}).Error)
+	require.NoError(t, db.Where("document_id IN ?", []uint{21, 22}).Delete(&ModificationHistory{}).Error)
+
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 21, ModField: "title", PreviousValue: "a", NewValue: "b"}))
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 22, ModField: "title", PreviousValue: "a", NewValue: "b"}))
+	require.NoError(t, MarkDocumentProcessed(db, 21))
+	require.NoError(t, MarkDocumentOrphaned(db, 21))
+
+	purged, err := PurgeOrphanedDocuments(db)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{21}, purged)
+
+	var remaining []ModificationHistory
+	require.NoError(t, db.Where("document_id IN ?", []uint{21, 22}).Find(&remaining).Error)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, uint(22), remaining[0].DocumentID)
+
+	processed, err := GetProcessedDocumentIDs(db)
+	require.NoError(t, err)
+	assert.False(t, processed[21])
+
+	orphanedDocuments, err := GetOrphanedDocuments(db)
+	require.NoError(t, err)
+	assert.Empty(t, orphanedDocuments)
+}
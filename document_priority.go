@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// isPriorityDocument reports whether a document carries one of the PRIORITY_TAGS or is from one of the
+// PRIORITY_CORRESPONDENTS. Names are compared case-insensitively.
+func (settings *runtimeSettings) isPriorityDocument(document Document) bool {
+	for _, tag := range document.Tags {
+		if slices.ContainsFunc(settings.PriorityTags, func(priorityTag string) bool { return strings.EqualFold(tag, priorityTag) }) {
+			return true
+		}
+	}
+	return document.Correspondent != "" &&
+		slices.ContainsFunc(settings.PriorityCorrespondents, func(correspondent string) bool { return strings.EqualFold(document.Correspondent, correspondent) })
+}
+
+// prioritizeDocuments moves priority documents to the front, keeping the order otherwise
+func (settings *runtimeSettings) prioritizeDocuments(documents []Document) {
+	slices.SortStableFunc(documents, func(a, b Document) int {
+		switch aPriority, bPriority := settings.isPriorityDocument(a), settings.isPriorityDocument(b); {
+		case aPriority && !bPriority:
+			return -1
+		case bPriority && !aPriority:
+			return 1
+		}
+		return 0
+	})
+}
+
+// priorityQueries returns the filter queries for documents matching the priority rules, resolving the names to IDs.
+// Names unknown to paperless-ngx are ignored.
+func (app *App) priorityQueries(ctx context.Context) ([]string, error) {
+	settings := settingsFor(ctx)
+	var queries []string
+	if len(settings.PriorityTags) > 0 {
+		tags, err := app.Client.GetAllTags(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching tags: %w", err)
+		}
+		if ids := idsByName(tags, settings.PriorityTags); len(ids) > 0 {
+			queries = append(queries, "tags__id__in="+strings.Join(ids, ","))
+		}
+	}
+	if len(settings.PriorityCorrespondents) > 0 {
+		correspondents, err := app.Client.GetAllCorrespondents(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching correspondents: %w", err)
+		}
+		if ids := idsByName(correspondents, settings.PriorityCorrespondents); len(ids) > 0 {
+			queries = append(queries, "correspondent__id__in="+strings.Join(ids, ","))
+		}
+	}
+	return queries, nil
+}
+
+// idsByName returns the IDs of the given names, compared case-insensitively
+func idsByName(idByName map[string]int, names []string) []string {
+	var ids []string
+	for name, id := range idByName {
+		if slices.ContainsFunc(names, func(wanted string) bool { return strings.EqualFold(name, wanted) }) {
+			ids = append(ids, strconv.Itoa(id))
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// getDocumentsByTagWithPriority returns up to limit documents with the tag. Documents matching the priority rules
+// are fetched first, so they are processed before the rest of a large backlog. Documents before the
+// PROCESS_DOCUMENTS_ADDED_AFTER cutoff are left out.
+func (app *App) getDocumentsByTagWithPriority(ctx context.Context, tag string, limit int) ([]Document, error) {
+	queries, err := app.priorityQueries(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// The documents without priority come last
+	queries = append(queries, "")
+
+	var documents []Document
+	for _, query := range queries {
+		if len(documents) >= limit {
+			break
+		}
+		query = joinQueries(fmt.Sprintf("tags__name__iexact=%s", tag), query, autoProcessingCutoff.query())
+		for document, err := range app.Client.Documents(ctx, urlEncode(query), min(limit, paperlessPageSize)) {
+			if err != nil {
+				return nil, err
+			}
+			// The newest documents are listed first with a document ID cutoff, so all following ones are older
+			if !autoProcessingCutoff.includes(document) {
+				break
+			}
+			if !slices.ContainsFunc(documents, func(d Document) bool { return d.ID == document.ID }) {
+				documents = append(documents, document)
+			}
+			if len(documents) >= limit {
+				break
+			}
+		}
+	}
+	return documents, nil
+}
+
+// joinQueries combines filter queries, leaving out empty ones
+func joinQueries(queries ...string) string {
+	return strings.Join(slices.DeleteFunc(queries, func(query string) bool { return query == "" }), "&")
+}
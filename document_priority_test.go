@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrioritizeDocuments(t *testing.T) {
+	settings := &runtimeSettings{PriorityTags: []string{"urgent"}, PriorityCorrespondents: []string{"Tax Office"}}
+
+	documents := []Document{
+		{ID: 1, Tags: []string{"inbox"}},
+		{ID: 2, Tags: []string{"Urgent"}},
+		{ID: 3, Correspondent: "Bakery"},
+		{ID: 4, Correspondent: "tax office"},
+	}
+	settings.prioritizeDocuments(documents)
+
+	var ids []int
+	for _, document := range documents {
+		ids = append(ids, document.ID)
+	}
+	assert.Equal(t, []int{2, 4, 1, 3}, ids)
+}
+
+func TestGetDocumentsByTagWithPriority(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	setSettings(t, func(settings *runtimeSettings) {
+		settings.PriorityTags = []string{"urgent", "unknown"}
+		settings.PriorityCorrespondents = []string{"Tax Office"}
+	})
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "paperless-gpt-auto"}, {"id": 2, "name": "urgent"}], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 5, "name": "Tax Office"}], "next": null}`))
+	})
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		query := r.URL.Query()
+		switch {
+		case query.Get("tags__id__in") == "2":
+			w.Write([]byte(`{"results": [{"id": 30, "tags": [1, 2]}], "next": null}`))
+		case query.Get("correspondent__id__in") == "5":
+			w.Write([]byte(`{"results": [{"id": 20, "correspondent": 5, "tags": [1]}, {"id": 30, "tags": [1, 2]}], "next": null}`))
+		default:
+			w.Write([]byte(`{"results": [{"id": 10, "tags": [1]}, {"id": 20, "correspondent": 5, "tags": [1]}, {"id": 40, "tags": [1]}], "next": null}`))
+		}
+	})
+
+	app := &App{Client: env.client}
+	documents, err := app.getDocumentsByTagWithPriority(context.Background(), "paperless-gpt-auto", 3)
+	require.NoError(t, err)
+
+	var ids []int
+	for _, document := range documents {
+		ids = append(ids, document.ID)
+	}
+	assert.Equal(t, []int{30, 20, 10}, ids)
+}
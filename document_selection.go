@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// savedViewRuleParams maps the filter rule types of paperless-ngx saved views to document filter query parameters
+var savedViewRuleParams = map[int]string{
+	0:  "title__icontains",
+	1:  "content__icontains",
+	2:  "archive_serial_number",
+	3:  "correspondent__id",
+	4:  "document_type__id",
+	5:  "is_in_inbox",
+	6:  "tags__id__all",
+	7:  "is_tagged",
+	8:  "created__date__lt",
+	9:  "created__date__gt",
+	10: "created__year",
+	11: "created__month",
+	12: "created__day",
+	13: "added__date__lt",
+	14: "added__date__gt",
+	15: "modified__date__lt",
+	16: "modified__date__gt",
+	17: "tags__id__none",
+	18: "archive_serial_number__isnull",
+	19: "title_content",
+	20: "query",
+	21: "more_like_id",
+	22: "tags__id__in",
+	23: "archive_serial_number__gt",
+	24: "archive_serial_number__lt",
+	25: "storage_path__id",
+	26: "correspondent__id__in",
+	27: "correspondent__id__none",
+	28: "document_type__id__in",
+	29: "document_type__id__none",
+	30: "storage_path__id__in",
+	31: "storage_path__id__none",
+}
+
+// savedViewListParams are the query parameters that take a comma separated list when a rule occurs several times
+var savedViewListParams = map[string]bool{
+	"tags__id__all":           true,
+	"tags__id__none":          true,
+	"tags__id__in":            true,
+	"correspondent__id__in":   true,
+	"correspondent__id__none": true,
+	"document_type__id__in":   true,
+	"document_type__id__none": true,
+	"storage_path__id__in":    true,
+	"storage_path__id__none":  true,
+}
+
+// savedViewQuery converts the filter rules of a saved view into a document filter query string
+func savedViewQuery(view SavedView) (string, error) {
+	values := make(map[string][]string)
+	for _, rule := range view.FilterRules {
+		param, ok := savedViewRuleParams[rule.RuleType]
+		if !ok {
+			return "", fmt.Errorf("saved view %q uses filter rule type %d, which is not supported; use AUTO_DOCUMENT_QUERY instead", view.Name, rule.RuleType)
+		}
+		if rule.Value == nil {
+			// "Not assigned" is stored as a rule without value
+			if strings.HasSuffix(param, "__id") {
+				values[strings.TrimSuffix(param, "__id")+"__isnull"] = []string{"1"}
+			}
+			continue
+		}
+		values[param] = append(values[param], *rule.Value)
+	}
+
+	params := make([]string, 0, len(values))
+	for param := range values {
+		params = append(params, param)
+	}
+	sort.Strings(params)
+
+	query := make([]string, 0, len(params))
+	for _, param := range params {
+		if savedViewListParams[param] {
+			query = append(query, param+"="+url.QueryEscape(strings.Join(values[param], ",")))
+			continue
+		}
+		for _, value := range values[param] {
+			query = append(query, param+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(query, "&"), nil
+}
+
+// documentSelectionQuery combines a saved view (name or ID) and a filter query string into a single filter query.
+// An empty string is returned when neither is given.
+func (app *App) documentSelectionQuery(ctx context.Context, savedView, query string) (string, error) {
+	var parts []string
+	if savedView != "" {
+		view, err := app.Client.GetSavedView(ctx, savedView)
+		if err != nil {
+			return "", err
+		}
+		viewQuery, err := savedViewQuery(view)
+		if err != nil {
+			return "", err
+		}
+		if viewQuery != "" {
+			parts = append(parts, viewQuery)
+		}
+	}
+	if query = strings.Trim(strings.TrimSpace(query), "?&"); query != "" {
+		parts = append(parts, query)
+	}
+	return strings.Join(parts, "&"), nil
+}
+
+// selectUnprocessedDocuments returns up to limit documents matching the filter query that were not processed before.
+// Documents selected by a query keep matching it after processing, so processed documents are remembered locally.
+func (app *App) selectUnprocessedDocuments(ctx context.Context, query string, limit int) ([]Document, error) {
+	documentIDs, err := app.Client.GetDocumentIDs(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	processed, err := GetProcessedDocumentIDs(app.Database)
+	if err != nil {
+		return nil, err
+	}
+
+	var unprocessed []string
+	for _, documentID := range documentIDs {
+		if processed[uint(documentID)] {
+			continue
+		}
+		unprocessed = append(unprocessed, fmt.Sprintf("%d", documentID))
+		if len(unprocessed) >= limit {
+			break
+		}
+	}
+	if len(unprocessed) == 0 {
+		return nil, nil
+	}
+
+	var documents []Document
+	for document, err := range app.Client.Documents(ctx, "id__in="+strings.Join(unprocessed, ","), len(unprocessed)) {
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSavedViewQuery(t *testing.T) {
+	value := func(s string) *string { return &s }
+
+	view := SavedView{
+		Name: "Inbox without correspondent",
+		FilterRules: []SavedViewFilterRule{
+			{RuleType: 6, Value: value("1")},
+			{RuleType: 6, Value: value("4")},
+			{RuleType: 3, Value: nil},
+			{RuleType: 14, Value: value("2024-01-01")},
+			{RuleType: 19, Value: value("tax & fees")},
+		},
+	}
+
+	query, err := savedViewQuery(view)
+	require.NoError(t, err)
+	assert.Equal(t, "added__date__gt=2024-01-01&correspondent__isnull=1&tags__id__all=1%2C4&title_content=tax+%26+fees", query)
+
+	_, err = savedViewQuery(SavedView{Name: "Custom", FilterRules: []SavedViewFilterRule{{RuleType: 99, Value: value("x")}}})
+	assert.ErrorContains(t, err, "filter rule type 99")
+}
+
+func TestDocumentSelectionQuery(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/saved_views/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 3, "name": "To Review", "filter_rules": [{"rule_type": 4, "value": "2"}]}], "next": null}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	ctx := context.Background()
+
+	query, err := app.documentSelectionQuery(ctx, "to review", "?added__date__gt=2024-01-01")
+	require.NoError(t, err)
+	assert.Equal(t, "document_type__id=2&added__date__gt=2024-01-01", query)
+
+	query, err = app.documentSelectionQuery(ctx, "3", "")
+	require.NoError(t, err)
+	assert.Equal(t, "document_type__id=2", query)
+
+	_, err = app.documentSelectionQuery(ctx, "Missing", "")
+	assert.ErrorContains(t, err, `saved view "Missing" not found`)
+}
+
+func TestSelectUnprocessedDocuments(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("id__in") != "" {
+			assert.Equal(t, "1,3", r.URL.Query().Get("id__in"))
+			w.Write([]byte(`{"results": [{"id": 1, "title": "One"}, {"id": 3, "title": "Three"}], "next": null}`))
+			return
+		}
+		assert.Equal(t, "1", r.URL.Query().Get("correspondent__isnull"))
+		w.Write([]byte(`{"count": 4, "all": [1, 2, 3, 4], "results": [{"id": 1}]}`))
+	})
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	require.NoError(t, MarkDocumentProcessed(env.db, 2))
+
+	documents, err := app.selectUnprocessedDocuments(context.Background(), "correspondent__isnull=1", 2)
+	require.NoError(t, err)
+	require.Len(t, documents, 2)
+	assert.Equal(t, 1, documents[0].ID)
+	assert.Equal(t, 3, documents[1].ID)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// DocumentSnapshot is the state of a document before or after an update, recorded as JSON with each modification
+type DocumentSnapshot struct {
+	Title               string             `json:"title"`
+	Content             string             `json:"content"`
+	Tags                []string           `json:"tags"`
+	Correspondent       string             `json:"correspondent"`
+	CreatedDate         string             `json:"created_date"`
+	CustomFields        []CustomFieldValue `json:"custom_fields"`
+	DocumentType        string             `json:"document_type"`
+	StoragePath         string             `json:"storage_path"`
+	ArchiveSerialNumber *int               `json:"archive_serial_number"`
+}
+
+// snapshotOf returns the snapshot of a document as fetched from paperless-ngx
+func snapshotOf(document Document) DocumentSnapshot {
+	snapshot := DocumentSnapshot{
+		Title:               document.Title,
+		Content:             document.Content,
+		Tags:                slices.Clone(document.Tags),
+		Correspondent:       document.Correspondent,
+		CreatedDate:         document.CreatedDate,
+		CustomFields:        slices.Clone(document.CustomFields),
+		DocumentType:        document.DocumentType,
+		StoragePath:         document.StoragePath,
+		ArchiveSerialNumber: document.ArchiveSerialNumber,
+	}
+	if snapshot.Tags == nil {
+		snapshot.Tags = []string{}
+	}
+	if snapshot.CustomFields == nil {
+		snapshot.CustomFields = []CustomFieldValue{}
+	}
+	return snapshot
+}
+
+// snapshots returns the state of the document before and after the update
+func (update *documentUpdate) snapshots() (DocumentSnapshot, DocumentSnapshot) {
+	document := update.document
+	before := snapshotOf(document.OriginalDocument)
+
+	after := before
+	after.Tags = slices.Clone(update.tags)
+	if after.Tags == nil {
+		after.Tags = []string{}
+	}
+	slices.Sort(after.Tags)
+	if title, ok := update.updatedFields["title"].(string); ok {
+		after.Title = title
+	}
+	if content, ok := update.updatedFields["content"].(string); ok {
+		after.Content = content
+	}
+	if createdDate, ok := update.updatedFields["created_date"].(string); ok {
+		after.CreatedDate = createdDate
+	}
+	if correspondent, ok := update.updatedFields["correspondent"]; ok {
+		after.Correspondent = document.SuggestedCorrespondent
+		if correspondent == nil {
+			after.Correspondent = ""
+		}
+	}
+	if customFields, ok := update.updatedFields["custom_fields"].([]CustomFieldValue); ok {
+		after.CustomFields = slices.Clone(customFields)
+	}
+	if _, ok := update.updatedFields["storage_path"]; ok {
+		after.StoragePath = document.SuggestedStoragePath
+	}
+	if asn, ok := update.updatedFields["archive_serial_number"].(int); ok {
+		after.ArchiveSerialNumber = &asn
+	}
+	return before, after
+}
+
+// marshalSnapshots marshals the snapshots before and after an update for the modification history
+func marshalSnapshots(before, after DocumentSnapshot) (string, string, error) {
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return "", "", err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return "", "", err
+	}
+	return string(beforeJSON), string(afterJSON), nil
+}
+
+// modificationSnapshots returns the snapshots recorded with a modification, false for modifications recorded without
+func modificationSnapshots(modification ModificationHistory) (DocumentSnapshot, DocumentSnapshot, bool) {
+	var before, after DocumentSnapshot
+	if modification.DocumentBefore == "" || modification.DocumentAfter == "" {
+		return before, after, false
+	}
+	if json.Unmarshal([]byte(modification.DocumentBefore), &before) != nil ||
+		json.Unmarshal([]byte(modification.DocumentAfter), &after) != nil {
+		return before, after, false
+	}
+	return before, after, true
+}
+
+// rebasedModificationValue returns the value undoing or redoing a modification sets, applied to the current state of
+// the document: only the tags and custom fields the modification changed are changed back, so tags and custom fields
+// changed in paperless-ngx since are kept. Other fields and modifications without snapshots use the recorded value.
+func rebasedModificationValue(modification ModificationHistory, operation string, current Document) (string, error) {
+	value := modification.PreviousValue
+	if operation == ModificationOperationRedo {
+		value = modification.NewValue
+	}
+	if modification.ModField != "tags" && modification.ModField != "custom_fields" {
+		return value, nil
+	}
+	before, after, ok := modificationSnapshots(modification)
+	if !ok {
+		return value, nil
+	}
+
+	from, to := after, before
+	if operation == ModificationOperationRedo {
+		from, to = before, after
+	}
+
+	if modification.ModField == "tags" {
+		tags := []string{}
+		for _, tag := range current.Tags {
+			if slices.Contains(to.Tags, tag) || !slices.Contains(from.Tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		for _, tag := range to.Tags {
+			if !slices.Contains(from.Tags, tag) && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+		slices.Sort(tags)
+		data, err := json.Marshal(tags)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal tags: %w", err)
+		}
+		return string(data), nil
+	}
+
+	customFields := slices.Clone(current.CustomFields)
+	for _, fromValue := range from.CustomFields {
+		if !slices.ContainsFunc(to.CustomFields, func(value CustomFieldValue) bool { return value.Field == fromValue.Field }) {
+			customFields = slices.DeleteFunc(customFields, func(value CustomFieldValue) bool { return value.Field == fromValue.Field })
+		}
+	}
+	var changed []CustomFieldValue
+	for _, toValue := range to.CustomFields {
+		i := slices.IndexFunc(from.CustomFields, func(value CustomFieldValue) bool { return value.Field == toValue.Field })
+		if i < 0 || !reflect.DeepEqual(from.CustomFields[i].Value, toValue.Value) {
+			changed = append(changed, toValue)
+		}
+	}
+	return marshalCustomFields(mergeCustomFields(customFields, changed))
+}
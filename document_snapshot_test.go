@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDocuments_RecordsSnapshots(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer env.db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"results": []map[string]interface{}{{"id": 1, "name": "bills"}, {"id": 2, "name": "inbox"}},
+			"next":    nil,
+		})
+	})
+	env.setMockResponse("/api/documents/7/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	asn := 12
+	documents := []DocumentSuggestion{{
+		ID: 7,
+		OriginalDocument: Document{
+			ID:                  7,
+			Title:               "scan.pdf",
+			Content:             "Electricity invoice",
+			Tags:                []string{"inbox"},
+			Correspondent:       "ACME",
+			CreatedDate:         "2024-05-01",
+			CustomFields:        []CustomFieldValue{{Field: 1, Value: "42"}},
+			ArchiveSerialNumber: &asn,
+		},
+		SuggestedTitle:        "Electricity Invoice",
+		SuggestedTags:         []string{"bills", "inbox"},
+		SuggestedCustomFields: []CustomFieldValue{{Field: 2, Value: "2024-06-01"}},
+	}}
+	require.NoError(t, env.client.UpdateDocuments(context.Background(), documents, env.db, false))
+
+	modifications, err := GetDocumentModifications(env.db, 7)
+	require.NoError(t, err)
+	require.Len(t, modifications, 3)
+
+	before := DocumentSnapshot{
+		Title:               "scan.pdf",
+		Content:             "Electricity invoice",
+		Tags:                []string{"inbox"},
+		Correspondent:       "ACME",
+		CreatedDate:         "2024-05-01",
+		CustomFields:        []CustomFieldValue{{Field: 1, Value: "42"}},
+		ArchiveSerialNumber: &asn,
+	}
+	after := before
+	after.Title = "Electricity Invoice"
+	after.Tags = []string{"bills", "inbox"}
+	after.CustomFields = []CustomFieldValue{{Field: 1, Value: "42"}, {Field: 2, Value: "2024-06-01"}}
+	for _, modification := range modifications {
+		recordedBefore, recordedAfter, ok := modificationSnapshots(modification)
+		require.True(t, ok, modification.ModField)
+		assert.Equal(t, before, recordedBefore, modification.ModField)
+		assert.Equal(t, after, recordedAfter, modification.ModField)
+	}
+}
+
+func TestRebasedModificationValue(t *testing.T) {
+	snapshots := func(before, after DocumentSnapshot) (string, string) {
+		beforeJSON, afterJSON, err := marshalSnapshots(before, after)
+		require.NoError(t, err)
+		return beforeJSON, afterJSON
+	}
+	tagsBefore, tagsAfter := snapshots(
+		DocumentSnapshot{Tags: []string{"inbox", "scan"}},
+		DocumentSnapshot{Tags: []string{"bills", "inbox"}},
+	)
+	fieldsBefore, fieldsAfter := snapshots(
+		DocumentSnapshot{CustomFields: []CustomFieldValue{{Field: 1, Value: "42"}, {Field: 3, Value: "EUR"}}},
+		DocumentSnapshot{CustomFields: []CustomFieldValue{{Field: 1, Value: "43"}, {Field: 2, Value: "2024-06-01"}}},
+	)
+
+	tests := []struct {
+		name         string
+		modification ModificationHistory
+		operation    string
+		current      Document
+		want         string
+	}{
+		{
+			name:         "undo keeps tags added since",
+			modification: ModificationHistory{ModField: "tags", PreviousValue: `["inbox","scan"]`, DocumentBefore: tagsBefore, DocumentAfter: tagsAfter},
+			operation:    ModificationOperationUndo,
+			current:      Document{Tags: []string{"bills", "paid"}},
+			want:         `["paid","scan"]`,
+		},
+		{
+			name:         "redo keeps tags added since",
+			modification: ModificationHistory{ModField: "tags", NewValue: `["bills","inbox"]`, DocumentBefore: tagsBefore, DocumentAfter: tagsAfter},
+			operation:    ModificationOperationRedo,
+			current:      Document{Tags: []string{"inbox", "scan", "paid"}},
+			want:         `["bills","inbox","paid"]`,
+		},
+		{
+			name:         "undo keeps custom fields changed since",
+			modification: ModificationHistory{ModField: "custom_fields", DocumentBefore: fieldsBefore, DocumentAfter: fieldsAfter},
+			operation:    ModificationOperationUndo,
+			current:      Document{CustomFields: []CustomFieldValue{{Field: 1, Value: "43"}, {Field: 2, Value: "2024-06-01"}, {Field: 4, Value: "x"}}},
+			want:         `[{"field":1,"value":"42"},{"field":4,"value":"x"},{"field":3,"value":"EUR"}]`,
+		},
+		{
+			name:         "without snapshots",
+			modification: ModificationHistory{ModField: "tags", PreviousValue: `["inbox"]`},
+			operation:    ModificationOperationUndo,
+			current:      Document{Tags: []string{"bills", "paid"}},
+			want:         `["inbox"]`,
+		},
+		{
+			name:         "other fields",
+			modification: ModificationHistory{ModField: "title", NewValue: "Invoice", DocumentBefore: tagsBefore, DocumentAfter: tagsAfter},
+			operation:    ModificationOperationRedo,
+			current:      Document{Title: "Renamed"},
+			want:         "Invoice",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := rebasedModificationValue(tt.modification, tt.operation, tt.current)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, value)
+		})
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Periods of EMAIL_DIGEST
+const (
+	emailDigestDaily  = "daily"
+	emailDigestWeekly = "weekly"
+)
+
+// emailDigestCheckInterval is the interval in which the scheduler checks whether a digest is due
+const emailDigestCheckInterval = 5 * time.Minute
+
+// maxDigestDocuments limits the processed documents listed in a digest, the others are only counted
+const maxDigestDocuments = 50
+
+// digestDocument is a document processed in the time of a digest
+type digestDocument struct {
+	ID    int
+	Title string // New title of the document, empty if the title wasn't changed
+}
+
+// digestSummary is what paperless-gpt did from Since until Until, and what is waiting for review
+type digestSummary struct {
+	Since          time.Time
+	Until          time.Time
+	Documents      []digestDocument // Processed documents, in the order they were first changed
+	Correspondents []string         // Correspondents created for suggestions
+	PendingReviews int
+	PendingTags    int64
+}
+
+// empty reports whether there is nothing to report
+func (summary digestSummary) empty() bool {
+	return len(summary.Documents) == 0 && len(summary.Correspondents) == 0 && summary.PendingReviews == 0 && summary.PendingTags == 0
+}
+
+// StartEmailDigest emails the digest of EMAIL_DIGEST to SMTP_TO at EMAIL_DIGEST_HOUR until the context is cancelled
+func StartEmailDigest(ctx context.Context, app *App) {
+	if emailDigest == "" {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(emailDigestCheckInterval)
+		defer ticker.Stop()
+		for {
+			if err := app.sendEmailDigestIfDue(ctx, time.Now()); err != nil {
+				log.Errorf("Error sending the email digest: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Infoln("Email digest shutting down")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// emailDigestDue returns when the latest digest was due, at the hour of each day or, for weekly digests, of each Monday
+func emailDigestDue(period string, hour int, now time.Time) time.Time {
+	due := time.Date(now.Year(), now.Month(), now.Day(), hour, 0, 0, 0, now.Location())
+	if due.After(now) {
+		due = due.AddDate(0, 0, -1)
+	}
+	if period == emailDigestWeekly {
+		for due.Weekday() != time.Monday {
+			due = due.AddDate(0, 0, -1)
+		}
+	}
+	return due
+}
+
+// emailDigestSince returns the start of the time summarized by a digest due at the time
+func emailDigestSince(period string, due time.Time) time.Time {
+	if period == emailDigestWeekly {
+		return due.AddDate(0, 0, -7)
+	}
+	return due.AddDate(0, 0, -1)
+}
+
+// sendEmailDigestIfDue sends the latest due digest unless it was already sent. Digests without anything to report
+// are recorded, but not sent.
+func (app *App) sendEmailDigestIfDue(ctx context.Context, now time.Time) error {
+	due := emailDigestDue(emailDigest, emailDigestHour, now)
+	last, err := GetLastEmailDigest(app.Database)
+	if err != nil {
+		return err
+	}
+	if last != nil {
+		if until, err := time.Parse(time.RFC3339, last.Until); err == nil && !until.Before(due) {
+			return nil
+		}
+	}
+
+	since := emailDigestSince(emailDigest, due)
+	summary, err := collectDigest(app.Database, since, due)
+	if err != nil {
+		return fmt.Errorf("failed to summarize the %s digest: %w", emailDigest, err)
+	}
+	record := EmailDigest{Period: emailDigest, Since: since.Format(time.RFC3339), Until: due.Format(time.RFC3339), SentAt: now.Format(time.RFC3339)}
+	if !summary.empty() {
+		config := smtpConfigFromEnv()
+		if err := sendMail(ctx, config, digestSubject(emailDigest, summary), digestBody(summary), false); err != nil {
+			return err
+		}
+		record.Sent = true
+		log.Infof("Sent the %s email digest to %s", emailDigest, strings.Join(config.to, ", "))
+	}
+	return app.Database.Create(&record).Error
+}
+
+// collectDigest summarizes the documents processed and the correspondents created from since until until, and the
+// suggestions and tags waiting for review
+func collectDigest(db *gorm.DB, since, until time.Time) (digestSummary, error) {
+	summary := digestSummary{Since: since, Until: until}
+	sinceText, untilText := since.In(time.Local).Format(time.RFC3339), until.In(time.Local).Format(time.RFC3339)
+
+	// Undoing and redoing modifications doesn't process documents
+	var modifications []ModificationHistory
+	err := db.Select("document_id", "mod_field", "new_value").
+		Where("date_changed >= ? AND date_changed < ? AND COALESCE(operation, '') = ''", sinceText, untilText).
+		Order("id").Find(&modifications).Error
+	if err != nil {
+		return digestSummary{}, err
+	}
+	positions := make(map[uint]int)
+	for _, modification := range modifications {
+		position, seen := positions[modification.DocumentID]
+		if !seen {
+			position = len(summary.Documents)
+			positions[modification.DocumentID] = position
+			summary.Documents = append(summary.Documents, digestDocument{ID: int(modification.DocumentID)})
+		}
+		if modification.ModField == "title" {
+			summary.Documents[position].Title = modification.NewValue
+		}
+	}
+
+	correspondents, err := GetCreatedCorrespondents(db, since, until)
+	if err != nil {
+		return digestSummary{}, err
+	}
+	for _, correspondent := range correspondents {
+		summary.Correspondents = append(summary.Correspondents, correspondent.Name)
+	}
+
+	reviews, err := GetPendingReviews(db)
+	if err != nil {
+		return digestSummary{}, err
+	}
+	summary.PendingReviews = len(reviews)
+	err = db.Model(&PendingTag{}).Where("status = ?", PendingTagStatusPending).Distinct("name").Count(&summary.PendingTags).Error
+	if err != nil {
+		return digestSummary{}, err
+	}
+	return summary, nil
+}
+
+// digestSubject returns the subject of a digest, e.g. paperless-gpt daily digest: 3 documents processed
+func digestSubject(period string, summary digestSummary) string {
+	return fmt.Sprintf("paperless-gpt %s digest: %s processed", period, pluralize(len(summary.Documents), "document"))
+}
+
+// digestBody returns the text of a digest
+func digestBody(summary digestSummary) string {
+	const timeFormat = "Mon, 02 Jan 2006 15:04"
+	var body strings.Builder
+	fmt.Fprintf(&body, "paperless-gpt processed %s from %s until %s.\n", pluralize(len(summary.Documents), "document"),
+		summary.Since.Format(timeFormat), summary.Until.Format(timeFormat))
+
+	if len(summary.Documents) > 0 {
+		body.WriteString("\nProcessed documents:\n")
+		for i, document := range summary.Documents {
+			if i == maxDigestDocuments {
+				fmt.Fprintf(&body, "- and %d more\n", len(summary.Documents)-maxDigestDocuments)
+				break
+			}
+			title := document.Title
+			if title == "" {
+				title = "Document"
+			}
+			fmt.Fprintf(&body, "- %s (#%d) %s\n", title, document.ID, documentURL(document.ID))
+		}
+	}
+
+	if len(summary.Correspondents) > 0 {
+		body.WriteString("\nNew correspondents:\n")
+		for _, name := range summary.Correspondents {
+			fmt.Fprintf(&body, "- %s\n", name)
+		}
+	}
+
+	if summary.PendingReviews > 0 || summary.PendingTags > 0 {
+		body.WriteString("\nAwaiting review:\n")
+		if summary.PendingReviews > 0 {
+			fmt.Fprintf(&body, "- %s with suggestions\n", pluralize(summary.PendingReviews, "document"))
+		}
+		if summary.PendingTags > 0 {
+			fmt.Fprintf(&body, "- %s\n", pluralize(int(summary.PendingTags), "new tag"))
+		}
+	}
+	return body.String()
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailDigestDue(t *testing.T) {
+	// Thursday
+	now := time.Date(2026, 10, 15, 7, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Date(2026, 10, 14, 8, 0, 0, 0, time.UTC), emailDigestDue(emailDigestDaily, 8, now))
+	assert.Equal(t, time.Date(2026, 10, 15, 6, 0, 0, 0, time.UTC), emailDigestDue(emailDigestDaily, 6, now))
+	assert.Equal(t, time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC), emailDigestDue(emailDigestWeekly, 8, now))
+
+	due := emailDigestDue(emailDigestWeekly, 8, now)
+	assert.Equal(t, time.Date(2026, 10, 5, 8, 0, 0, 0, time.UTC), emailDigestSince(emailDigestWeekly, due))
+}
+
+// setupDigestTest clears the tables summarized by the digest
+func setupDigestTest(t *testing.T) *App {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	for _, model := range []interface{}{&ModificationHistory{}, &CreatedCorrespondent{}, &PendingReview{}, &PendingTag{}, &EmailDigest{}} {
+		require.NoError(t, db.Where("1 = 1").Delete(model).Error)
+		t.Cleanup(func() { db.Where("1 = 1").Delete(model) })
+	}
+	return &App{Database: db}
+}
+
+func TestCollectDigest(t *testing.T) {
+	app := setupDigestTest(t)
+	db := app.Database
+	t.Setenv("PAPERLESS_PUBLIC_URL", "https://paperless.example.com")
+	until := time.Now().Add(time.Minute)
+	since := until.Add(-24 * time.Hour)
+
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 544, ModField: "tags", PreviousValue: "[]", NewValue: `["invoice"]`}))
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 544, ModField: "title", PreviousValue: "scan_001", NewValue: "Invoice ACME"}))
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 12, ModField: "correspondent", PreviousValue: "", NewValue: "ACME"}))
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 7, ModField: "title", NewValue: "Old", Operation: ModificationOperationUndo}))
+	require.NoError(t, RecordCreatedCorrespondent(db, 3, "ACME", 12))
+	require.NoError(t, InsertPendingReview(db, DocumentSuggestion{ID: 99}))
+	require.NoError(t, InsertPendingTags(db, 99, []string{"Utilities", "Energy"}))
+
+	summary, err := collectDigest(db, since, until)
+	require.NoError(t, err)
+	assert.Equal(t, []digestDocument{{ID: 544, Title: "Invoice ACME"}, {ID: 12}}, summary.Documents, "undone modifications aren't processing")
+	assert.Equal(t, []string{"ACME"}, summary.Correspondents)
+	assert.Equal(t, 1, summary.PendingReviews)
+	assert.Equal(t, int64(2), summary.PendingTags)
+
+	assert.Equal(t, "paperless-gpt daily digest: 2 documents processed", digestSubject(emailDigestDaily, summary))
+	body := digestBody(summary)
+	assert.Contains(t, body, "- Invoice ACME (#544) https://paperless.example.com/documents/544/details\n")
+	assert.Contains(t, body, "- Document (#12) https://paperless.example.com/documents/12/details\n")
+	assert.Contains(t, body, "New correspondents:\n- ACME\n")
+	assert.Contains(t, body, "Awaiting review:\n- 1 document with suggestions\n- 2 new tags\n")
+
+	// The modifications of the day before aren't summarized
+	summary, err = collectDigest(db, since.Add(-24*time.Hour), since)
+	require.NoError(t, err)
+	assert.Empty(t, summary.Documents)
+	assert.Empty(t, summary.Correspondents)
+}
+
+func TestSendEmailDigestIfDue(t *testing.T) {
+	app := setupDigestTest(t)
+	port, received := startFakeSMTPServer(t)
+
+	originalDigest, originalHost, originalPort, originalFrom, originalTo := emailDigest, smtpHost, smtpPort, smtpFrom, smtpTo
+	defer func() {
+		emailDigest, smtpHost, smtpPort, smtpFrom, smtpTo = originalDigest, originalHost, originalPort, originalFrom, originalTo
+	}()
+	emailDigest, smtpHost, smtpPort, smtpFrom, smtpTo = emailDigestDaily, "127.0.0.1", port, "paperless@example.com", []string{"admin@example.com"}
+
+	// Nothing to report, the digest is recorded but not sent
+	now := time.Now()
+	require.NoError(t, app.sendEmailDigestIfDue(context.Background(), now))
+	last, err := GetLastEmailDigest(app.Database)
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.False(t, last.Sent)
+	assert.Empty(t, received)
+
+	// The next digest is sent once it is due
+	require.NoError(t, InsertPendingReview(app.Database, DocumentSuggestion{ID: 1}))
+	require.NoError(t, app.sendEmailDigestIfDue(context.Background(), now))
+	assert.Empty(t, received, "the digest was already recorded")
+
+	require.NoError(t, app.sendEmailDigestIfDue(context.Background(), now.Add(24*time.Hour)))
+	select {
+	case email := <-received:
+		assert.Contains(t, email.data, "Subject: paperless-gpt daily digest: 0 documents processed")
+		assert.Contains(t, email.data, "- 1 document with suggestions")
+	case <-time.After(5 * time.Second):
+		t.Fatal("no digest received")
+	}
+	last, err = GetLastEmailDigest(app.Database)
+	require.NoError(t, err)
+	assert.True(t, last.Sent)
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"embed"
 	"io"
 	"io/fs"
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -52,3 +54,15 @@ func serveEmbeddedFile(c *gin.Context, prefix string, filepath string) {
 	// Serve the file
 	http.ServeContent(c.Writer, c.Request, stat.Name(), stat.ModTime(), f.(io.ReadSeeker))
 }
+
+// serveIndexHTML serves the web UI with a <base> element for the base path it runs under
+func serveIndexHTML(c *gin.Context, basePath string) {
+	page, err := webappContent.ReadFile("web-app/dist/index.html")
+	if err != nil {
+		log.Warnf("File not found: web-app/dist/index.html")
+		http.Error(c.Writer, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", "no-cache")
+	http.ServeContent(c.Writer, c.Request, "index.html", time.Time{}, bytes.NewReader(injectBaseElement(page, basePath)))
+}
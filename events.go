@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event types pushed to the clients of /api/ws. Clients can subscribe to the category before the dot, e.g. "job".
+const (
+	EventJobUpdated           = "job.updated"           // A job was queued, started, made progress or finished
+	EventSuggestionsGenerated = "suggestions.generated" // Suggestions were generated for a document
+	EventReviewQueued         = "review.queued"         // Suggestions are waiting for review (AUTO_REVIEW)
+	EventProcessingPaused     = "processing.paused"     // Background processing was paused through the API
+	EventProcessingResumed    = "processing.resumed"    // Background processing was resumed through the API
+	EventProcessingIdle       = "processing.idle"       // Background processing is outside of the processing schedule
+	EventProcessingRun        = "processing.run"        // A background processing run finished
+	EventDocumentProcessed    = "document.processed"    // Background processing applied or queued the suggestions of a document
+	EventDocumentFailed       = "document.failed"       // Background processing of a document failed
+	EventOCRFinished          = "ocr.finished"          // Background OCR stored the text of a document
+)
+
+const (
+	// eventSubscriberBuffer is the number of events buffered for a subscriber, slower subscribers are disconnected
+	eventSubscriberBuffer = 64
+	// eventWriteTimeout limits how long sending an event to a WebSocket client may take
+	eventWriteTimeout = 10 * time.Second
+	// eventPingInterval keeps idle WebSocket connections open through proxies and detects dead clients
+	eventPingInterval = 30 * time.Second
+)
+
+// Event is a change in paperless-gpt pushed to the connected clients
+type Event struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data,omitempty"`
+}
+
+// eventBroker fans out events to all subscribers without ever blocking the publisher
+type eventBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+// events distributes the events of paperless-gpt to the clients of /api/ws
+var events = newEventBroker()
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{subscribers: make(map[chan Event]struct{})}
+}
+
+// subscribe returns a channel receiving all published events and a function to end the subscription.
+// The channel is closed when the subscription ends, also if the subscriber falls too far behind.
+func (broker *eventBroker) subscribe() (<-chan Event, func()) {
+	subscriber := make(chan Event, eventSubscriberBuffer)
+	broker.mu.Lock()
+	broker.subscribers[subscriber] = struct{}{}
+	broker.mu.Unlock()
+
+	return subscriber, func() {
+		broker.mu.Lock()
+		defer broker.mu.Unlock()
+		if _, ok := broker.subscribers[subscriber]; ok {
+			delete(broker.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// publish sends the event to all subscribers. The data must not be changed afterwards.
+func (broker *eventBroker) publish(eventType string, data any) {
+	event := Event{Type: eventType, Time: time.Now(), Data: data}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	for subscriber := range broker.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			log.Warnf("Disconnecting event subscriber that fell %d events behind", eventSubscriberBuffer)
+			delete(broker.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+}
+
+// matchesEventTypes reports whether the event type is one of the types or categories, all events match no types
+func matchesEventTypes(eventType string, types []string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	category, _, _ := strings.Cut(eventType, ".")
+	for _, t := range types {
+		if t == eventType || t == category {
+			return true
+		}
+	}
+	return false
+}
+
+// eventUpgrader accepts WebSocket connections of the same origin only, as browsers send the session cookie along
+var eventUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// streamEvents sends the events of the given types to the WebSocket client until it disconnects or the context is
+// done. Messages of the client are ignored.
+func streamEvents(ctx context.Context, conn *websocket.Conn, types []string) {
+	defer conn.Close()
+	subscription, unsubscribe := events.subscribe()
+	defer unsubscribe()
+
+	// Reading handles pongs and notices when the client closes the connection
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		conn.SetReadLimit(4096)
+		conn.SetReadDeadline(time.Now().Add(2 * eventPingInterval))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(2 * eventPingInterval))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(eventPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(eventWriteTimeout))
+			return
+		case <-closed:
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(eventWriteTimeout)); err != nil {
+				return
+			}
+		case event, ok := <-subscription:
+			if !ok {
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too slow"), time.Now().Add(eventWriteTimeout))
+				return
+			}
+			if !matchesEventTypes(event.Type, types) {
+				continue
+			}
+			conn.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventBroker(t *testing.T) {
+	broker := newEventBroker()
+	subscription, unsubscribe := broker.subscribe()
+
+	broker.publish(EventJobUpdated, map[string]any{"job_id": "1"})
+	event := <-subscription
+	assert.Equal(t, EventJobUpdated, event.Type)
+	assert.Equal(t, map[string]any{"job_id": "1"}, event.Data)
+
+	unsubscribe()
+	unsubscribe()
+	_, open := <-subscription
+	assert.False(t, open)
+
+	// Subscribers falling behind are disconnected instead of blocking the publisher
+	slow, _ := broker.subscribe()
+	for range eventSubscriberBuffer + 1 {
+		broker.publish(EventProcessingRun, nil)
+	}
+	received := 0
+	for range slow {
+		received++
+	}
+	assert.Equal(t, eventSubscriberBuffer, received)
+}
+
+func TestMatchesEventTypes(t *testing.T) {
+	assert.True(t, matchesEventTypes(EventJobUpdated, nil))
+	assert.True(t, matchesEventTypes(EventJobUpdated, []string{"job"}))
+	assert.True(t, matchesEventTypes(EventReviewQueued, []string{"job", "review.queued"}))
+	assert.False(t, matchesEventTypes(EventProcessingRun, []string{"job", "processing.paused"}))
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/ws", eventsWebSocketHandler)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	subscribers := func() int {
+		events.mu.Lock()
+		defer events.mu.Unlock()
+		return len(events.subscribers)
+	}
+	before := subscribers()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api/ws?types=job", nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return subscribers() == before+1 }, time.Second, 10*time.Millisecond)
+
+	// Only the subscribed types are sent
+	events.publish(EventProcessingRun, map[string]any{"processed": 3})
+	job := newJob(JobKindOCR, 12)
+	jobStore.addJob(job)
+	defer func() {
+		jobStore.Lock()
+		delete(jobStore.jobs, job.ID)
+		jobStore.Unlock()
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var event struct {
+		Type string         `json:"type"`
+		Data map[string]any `json:"data"`
+	}
+	require.NoError(t, conn.ReadJSON(&event))
+	assert.Equal(t, EventJobUpdated, event.Type)
+	assert.Equal(t, job.ID, event.Data["job_id"])
+	assert.Equal(t, "pending", event.Data["status"])
+
+	// Closing the connection ends the subscription
+	conn.Close()
+	assert.Eventually(t, func() bool { return subscribers() == before }, time.Second, 10*time.Millisecond)
+}
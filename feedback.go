@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Fields that feedback can be given on
+var feedbackFields = []string{"title", "tag", "correspondent"}
+
+// Outcomes of a suggested value
+const (
+	FeedbackAccepted = "accepted"
+	FeedbackEdited   = "edited"
+	FeedbackRejected = "rejected"
+)
+
+// feedbackRecords checks the feedback on the suggestions for a document and turns it into records to store
+func feedbackRecords(ctx context.Context, request FeedbackRequest) ([]SuggestionFeedback, error) {
+	if request.DocumentID <= 0 {
+		return nil, fmt.Errorf("document_id is required")
+	}
+	if len(request.Fields) == 0 {
+		return nil, fmt.Errorf("fields must not be empty")
+	}
+
+	now := time.Now().Format(time.RFC3339)
+	settings := settingsFor(ctx)
+	model := settings.LLMProvider + "/" + settings.LLMModel
+	records := make([]SuggestionFeedback, 0, len(request.Fields))
+	for i, feedback := range request.Fields {
+		field := strings.ToLower(strings.TrimSpace(feedback.Field))
+		suggested := strings.TrimSpace(feedback.Suggested)
+		final := strings.TrimSpace(feedback.Final)
+
+		if !slices.Contains(feedbackFields, field) {
+			return nil, fmt.Errorf("fields[%d]: field must be one of %s, got: %s", i, strings.Join(feedbackFields, ", "), feedback.Field)
+		}
+		if suggested == "" {
+			return nil, fmt.Errorf("fields[%d]: suggested is required", i)
+		}
+		switch feedback.Outcome {
+		case FeedbackAccepted:
+			if final == "" {
+				final = suggested
+			} else if final != suggested {
+				return nil, fmt.Errorf("fields[%d]: final differs from the suggested value, the outcome is edited", i)
+			}
+		case FeedbackEdited:
+			if final == "" || final == suggested {
+				return nil, fmt.Errorf("fields[%d]: an edited value needs a final value other than the suggested one", i)
+			}
+		case FeedbackRejected:
+		default:
+			return nil, fmt.Errorf("fields[%d]: outcome must be accepted, edited or rejected, got: %s", i, feedback.Outcome)
+		}
+
+		records = append(records, SuggestionFeedback{
+			DocumentID:     uint(request.DocumentID),
+			Field:          field,
+			Outcome:        feedback.Outcome,
+			SuggestedValue: suggested,
+			FinalValue:     final,
+			Model:          model,
+			CreatedAt:      now,
+		})
+	}
+	return records, nil
+}
+
+// promptExamples returns the values the user recently accepted or corrected for a field, for the prompt of the field
+// to follow their naming conventions. It returns none if FEW_SHOT_EXAMPLES is 0.
+func (app *App) promptExamples(ctx context.Context, field string) []string {
+	fewShotExamples := settingsFor(ctx).FewShotExamples
+	if fewShotExamples <= 0 || app.Database == nil {
+		return []string{}
+	}
+	examples, err := GetRecentFeedbackValues(app.Database, field, llmDocument(ctx), fewShotExamples)
+	if err != nil {
+		// The prompt works without examples
+		log.Warnf("Error fetching examples for the %s prompt: %v", field, err)
+		return []string{}
+	}
+	return examples
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedback(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&SuggestionFeedback{}).Error)
+	defer db.Where("1 = 1").Delete(&SuggestionFeedback{})
+
+	gin.SetMode(gin.TestMode)
+	app := &App{Database: db}
+	router := gin.New()
+	router.POST("/api/feedback", app.feedbackHandler)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/feedback", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"document_id": 7, "fields": [
+		{"field": "title", "outcome": "edited", "suggested": "Invoice", "final": "Invoice 2024-001"},
+		{"field": "tag", "outcome": "accepted", "suggested": "Finance"},
+		{"field": "tag", "outcome": "rejected", "suggested": "Travel"},
+		{"field": "Correspondent", "outcome": "accepted", "suggested": "ACME", "final": "ACME"}
+	]}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"recorded": 4}`, w.Body.String())
+
+	var records []SuggestionFeedback
+	require.NoError(t, db.Order("id").Find(&records).Error)
+	require.Len(t, records, 4)
+	assert.Equal(t, "Invoice 2024-001", records[0].FinalValue)
+	assert.Equal(t, "Finance", records[1].FinalValue, "accepted values default to the suggestion")
+	assert.Equal(t, FeedbackRejected, records[2].Outcome)
+	assert.Empty(t, records[2].FinalValue)
+	assert.Equal(t, "correspondent", records[3].Field)
+	assert.EqualValues(t, 7, records[3].DocumentID)
+
+	for name, body := range map[string]string{
+		"missing document":     `{"fields": [{"field": "title", "outcome": "accepted", "suggested": "Invoice"}]}`,
+		"no fields":            `{"document_id": 7, "fields": []}`,
+		"unknown field":        `{"document_id": 7, "fields": [{"field": "summary", "outcome": "accepted", "suggested": "Text"}]}`,
+		"unknown outcome":      `{"document_id": 7, "fields": [{"field": "title", "outcome": "ignored", "suggested": "Invoice"}]}`,
+		"edited without final": `{"document_id": 7, "fields": [{"field": "title", "outcome": "edited", "suggested": "Invoice"}]}`,
+		"accepted but changed": `{"document_id": 7, "fields": [{"field": "title", "outcome": "accepted", "suggested": "Invoice", "final": "Bill"}]}`,
+		"invalid payload":      `[]`,
+	} {
+		w := post(body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+	var count int64
+	require.NoError(t, db.Model(&SuggestionFeedback{}).Count(&count).Error)
+	assert.EqualValues(t, 4, count, "nothing is recorded from invalid feedback")
+}
+
+func TestPromptExamples(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&SuggestionFeedback{}).Error)
+	defer db.Where("1 = 1").Delete(&SuggestionFeedback{})
+	usePromptsDir(t)
+	titleTemplate = template.Must(template.New("title").Funcs(sprig.FuncMap()).Parse(defaultTitleTemplate))
+
+	require.NoError(t, InsertSuggestionFeedback(db, []SuggestionFeedback{
+		{DocumentID: 1, Field: "title", Outcome: FeedbackAccepted, SuggestedValue: "ACME Invoice", FinalValue: "ACME Invoice"},
+		{DocumentID: 2, Field: "title", Outcome: FeedbackEdited, SuggestedValue: "Invoice", FinalValue: "2024-03 Stadtwerke Invoice"},
+		{DocumentID: 3, Field: "title", Outcome: FeedbackRejected, SuggestedValue: "Letter"},
+		{DocumentID: 4, Field: "title", Outcome: FeedbackAccepted, SuggestedValue: "2024-04 ACME Invoice", FinalValue: "2024-04 ACME Invoice"},
+		{DocumentID: 5, Field: "tag", Outcome: FeedbackAccepted, SuggestedValue: "Utilities", FinalValue: "Utilities"},
+		{DocumentID: 6, Field: "title", Outcome: FeedbackAccepted, SuggestedValue: "2024-04 ACME Invoice", FinalValue: "2024-04 ACME Invoice"},
+		{DocumentID: 7, Field: "title", Outcome: FeedbackAccepted, SuggestedValue: "2024-05 Tax Return", FinalValue: "2024-05 Tax Return"},
+	}))
+
+	llm := &mockJSONLLM{response: "2024-06 ACME Invoice"}
+	app := &App{LLM: llm, Database: db}
+	logger := logrus.WithField("test", "examples")
+
+	setSettings(t, func(settings *runtimeSettings) { settings.FewShotExamples, settings.TokenLimit = 0, 0 })
+	_, err = app.getSuggestedTitle(context.Background(), "content", "scan.pdf", logger)
+	require.NoError(t, err)
+	assert.NotContains(t, llm.lastPrompt, "Titles recently chosen")
+
+	// The newest distinct values kept by the user, without the ones of the document itself
+	setSettings(t, func(settings *runtimeSettings) { settings.FewShotExamples = 2 })
+	_, err = app.getSuggestedTitle(withLLMDocument(context.Background(), 7), "content", "scan.pdf", logger)
+	require.NoError(t, err)
+	assert.Contains(t, llm.lastPrompt, "Titles recently chosen for other documents, follow their style:\n- 2024-04 ACME Invoice\n- 2024-03 Stadtwerke Invoice\n\nContent:")
+}
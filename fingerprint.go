@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"gorm.io/gorm"
+)
+
+// taskPromptHash returns the SHA-256 hash of the prompts a task processes documents with
+func (settings *runtimeSettings) taskPromptHash(task string) string {
+	templateMutex.RLock()
+	defer templateMutex.RUnlock()
+
+	templates := []*template.Template{ocrTemplate}
+	if task != JobKindOCR {
+		templates = []*template.Template{
+			titleTemplate, tagTemplate, correspondentTemplate, createdDateTemplate, customFieldsTemplate,
+			languageTemplate, summaryTemplate, dueDateTemplate, invoiceTemplate, amountTemplate, contractTemplate,
+			piiTemplate, sensitivityTemplate, translationTemplate, triageTemplate,
+		}
+		templates = append(templates, promptRules.ruleTemplates()...)
+		templates = append(templates, promptExperiments.experimentTemplates()...)
+		_, languageTemplates := languageTemplateFiles(languagePromptTemplates)
+		templates = append(templates, languageTemplates...)
+		if settings.TruncationStrategy == truncateSummarize {
+			templates = append(templates, condenseTemplate)
+		}
+	}
+
+	hash := sha256.New()
+	for _, tmpl := range templates {
+		if tmpl != nil && tmpl.Tree != nil {
+			io.WriteString(hash, tmpl.Tree.Root.String())
+		}
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// taskModel returns the provider and model a task processes documents with
+func (settings *runtimeSettings) taskModel(task string) string {
+	if task != JobKindOCR {
+		return settings.LLMProvider + "/" + settings.LLMModel
+	}
+	providerType := os.Getenv("OCR_PROVIDER")
+	if providerType == "" || providerType == "llm" {
+		return "llm/" + visionLlmProvider + "/" + visionLlmModel
+	}
+	return providerType
+}
+
+// currentFingerprint returns the fingerprint the task would complete on the document with now
+func (app *App) currentFingerprint(ctx context.Context, documentID int, task string) (*DocumentFingerprint, error) {
+	checksum, err := app.Client.getDocumentChecksum(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	settings := settingsFor(ctx)
+	return &DocumentFingerprint{
+		DocumentID: uint(documentID),
+		Task:       task,
+		Checksum:   checksum,
+		PromptHash: settings.taskPromptHash(task),
+		Model:      settings.taskModel(task),
+	}, nil
+}
+
+// skipUnchangedDocument reports whether the task already completed on the document with the same fingerprint, unless
+// the -force flag is set. The tag selecting the document is removed then, so it isn't selected again. The current
+// fingerprint is returned to be recorded once the task completes, it is nil if it couldn't be determined.
+func (app *App) skipUnchangedDocument(ctx context.Context, document Document, task, tag string) (*DocumentFingerprint, bool, error) {
+	docLogger := documentLogger(document.ID)
+	fingerprint, err := app.currentFingerprint(ctx, document.ID, task)
+	if errors.Is(err, errDocumentNotFound) {
+		return nil, false, err
+	}
+	if err != nil {
+		// Processing the document again is better than not processing it at all
+		docLogger.Warnf("Could not determine the fingerprint of the document: %v", err)
+		return nil, false, nil
+	}
+	if forceReprocessing {
+		return fingerprint, false, nil
+	}
+
+	previous, err := GetDocumentFingerprint(app.Database, fingerprint.DocumentID, task)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fingerprint, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("error loading fingerprint of document %d: %w", document.ID, err)
+	}
+	if previous.Checksum != fingerprint.Checksum || previous.PromptHash != fingerprint.PromptHash || previous.Model != fingerprint.Model {
+		return fingerprint, false, nil
+	}
+
+	docLogger.Infof("Skipping document, it is unchanged since the %s task completed on %s", task, previous.ProcessedAt)
+	availableTags, err := app.Client.GetAllTags(ctx)
+	if err != nil {
+		return nil, true, fmt.Errorf("error fetching tags: %w", err)
+	}
+	if tagID, exists := availableTags[tag]; exists {
+		if err := app.Client.RemoveTagFromDocuments(ctx, []int{document.ID}, tagID); err != nil {
+			return nil, true, fmt.Errorf("error removing tag %s from document %d: %w", tag, document.ID, err)
+		}
+	}
+	// Documents selected by a filter query still match it, so they are remembered as processed
+	if err := MarkDocumentProcessed(app.Database, uint(document.ID)); err != nil {
+		docLogger.Warnf("Failed to remember document as processed: %v", err)
+	}
+	return nil, true, nil
+}
+
+// recordFingerprint remembers the fingerprint a task completed on a document with
+func (app *App) recordFingerprint(fingerprint *DocumentFingerprint) {
+	if fingerprint == nil {
+		return
+	}
+	if err := SaveDocumentFingerprint(app.Database, fingerprint); err != nil {
+		documentLogger(int(fingerprint.DocumentID)).Warnf("Failed to record the fingerprint of the document: %v", err)
+	}
+}
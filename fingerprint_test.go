@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskPromptHash(t *testing.T) {
+	originalTitleTemplate := titleTemplate
+	defer func() { titleTemplate = originalTitleTemplate }()
+
+	titleTemplate = template.Must(template.New("title").Parse("Title of {{.Content}}"))
+	ocrHash, suggestionsHash := loadSettings().taskPromptHash(JobKindOCR), loadSettings().taskPromptHash(JobKindSuggestions)
+	assert.NotEqual(t, ocrHash, suggestionsHash)
+
+	// Changing a prompt only changes the fingerprint of the tasks using it
+	titleTemplate = template.Must(template.New("title").Parse("A better title of {{.Content}}"))
+	assert.Equal(t, ocrHash, loadSettings().taskPromptHash(JobKindOCR))
+	assert.NotEqual(t, suggestionsHash, loadSettings().taskPromptHash(JobKindSuggestions))
+}
+
+func TestSkipUnchangedDocument(t *testing.T) {
+	env := setupTest(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&DocumentFingerprint{}).Error)
+
+	originalForce := forceReprocessing
+	defer func() { forceReprocessing = originalForce }()
+	forceReprocessing = false
+
+	checksum := "a1b2c3"
+	env.setMockResponse("/api/documents/41/metadata/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"original_checksum": "` + checksum + `", "has_archive_version": false}`))
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "paperless-gpt-auto"}], "next": null}`))
+	})
+	var bulkEdits []string
+	env.setMockResponse("/api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		var edit struct {
+			Method string `json:"method"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&edit))
+		bulkEdits = append(bulkEdits, edit.Method)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result": "OK"}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	ctx := context.Background()
+	document := Document{ID: 41, Tags: []string{"paperless-gpt-auto"}}
+
+	// A document that was never processed is processed
+	fingerprint, skip, err := app.skipUnchangedDocument(ctx, document, JobKindSuggestions, "paperless-gpt-auto")
+	require.NoError(t, err)
+	assert.False(t, skip)
+	require.NotNil(t, fingerprint)
+	assert.Equal(t, checksum, fingerprint.Checksum)
+	app.recordFingerprint(fingerprint)
+
+	// Once processed, the unchanged document is skipped and loses the tag
+	fingerprint, skip, err = app.skipUnchangedDocument(ctx, document, JobKindSuggestions, "paperless-gpt-auto")
+	require.NoError(t, err)
+	assert.True(t, skip)
+	assert.Nil(t, fingerprint)
+	assert.Equal(t, []string{"remove_tag"}, bulkEdits)
+
+	// Other tasks have their own fingerprint
+	_, skip, err = app.skipUnchangedDocument(ctx, document, JobKindOCR, "paperless-gpt-auto")
+	require.NoError(t, err)
+	assert.False(t, skip)
+
+	// The -force flag processes it again
+	forceReprocessing = true
+	_, skip, err = app.skipUnchangedDocument(ctx, document, JobKindSuggestions, "paperless-gpt-auto")
+	require.NoError(t, err)
+	assert.False(t, skip)
+	forceReprocessing = false
+
+	// A changed file is processed again
+	checksum = "d4e5f6"
+	_, skip, err = app.skipUnchangedDocument(ctx, document, JobKindSuggestions, "paperless-gpt-auto")
+	require.NoError(t, err)
+	assert.False(t, skip)
+}
@@ -0,0 +1,1011 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The GraphQL API answers queries over the same data as the REST API. It implements the query language without
+// mutations, subscriptions and introspection, which is all integrators need to fetch nested data in one round trip.
+
+const (
+	// maxGraphQLQueryLength rejects huge queries before parsing them
+	maxGraphQLQueryLength = 64 << 10
+	// maxGraphQLDepth limits the nesting of selections, every level may fetch from paperless-ngx
+	maxGraphQLDepth = 10
+	// maxGraphQLFields limits the fields selected once fragments are expanded, aliases of a field count separately
+	maxGraphQLFields = 1000
+	// maxGraphQLCost limits the fields computed by resolvers, counted once for every item of the lists they are in
+	maxGraphQLCost = 2500
+	// graphQLListSize is the number of items assumed for lists without a page_size argument
+	graphQLListSize = 25
+)
+
+// GraphQLRequest is the request payload of the /graphql endpoint
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	Variables     map[string]any `json:"variables,omitempty"`
+	OperationName string         `json:"operationName,omitempty"`
+}
+
+// GraphQLResponse is the response of the /graphql endpoint. Data is missing if the query couldn't be run, fields
+// that failed are null in the data and listed in the errors.
+type GraphQLResponse struct {
+	Data   any            `json:"data,omitempty"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+// GraphQLError is an error of a query, with the path of the field that failed
+type GraphQLError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path,omitempty"`
+}
+
+// graphQLResolver computes a field from the JSON encoding of its parent, which is nil for the fields of the query
+type graphQLResolver func(ctx context.Context, parent map[string]any, args map[string]any) (any, error)
+
+// graphQLField describes a field of an object type
+type graphQLField struct {
+	Type    string          // Object type of the value, e.g. Document or [Document] for lists, empty for scalars and plain objects
+	Args    string          // Arguments of the field in the schema, e.g. "(id: Int!)"
+	Resolve graphQLResolver // Computes the value, nil reads it from the JSON encoding of the parent
+}
+
+// graphQLType lists the fields of an object type
+type graphQLType map[string]graphQLField
+
+// graphQLSchema maps the names of the object types to their fields, the entry point is Query
+type graphQLSchema map[string]graphQLType
+
+// graphQLFieldsOf lists the fields of the JSON encoding of a struct, including fields that are omitted when empty
+func graphQLFieldsOf(prototype any, fields graphQLType) graphQLType {
+	typ := graphQLType{}
+	addJSONFields(reflect.TypeOf(prototype), typ)
+	for name, field := range fields {
+		typ[name] = field
+	}
+	return typ
+}
+
+// addJSONFields adds the JSON field names of a struct and of its embedded structs
+func addJSONFields(structType reflect.Type, typ graphQLType) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if field.Anonymous && tag == "" {
+			addJSONFields(field.Type, typ)
+			continue
+		}
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		typ[name] = graphQLField{}
+	}
+}
+
+// graphQLObject is a JSON object keeping the order of the selected fields
+type graphQLObject []graphQLEntry
+
+type graphQLEntry struct {
+	Key   string
+	Value any
+}
+
+func (object graphQLObject) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, entry := range object {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(entry.Key)
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		value, err := json.Marshal(entry.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+// graphQLVariable is a reference to a variable in an argument
+type graphQLVariable string
+
+// graphQLDirective is @include or @skip on a selection
+type graphQLDirective struct {
+	Name      string
+	Arguments map[string]any
+}
+
+// graphQLSelection is a field, a fragment spread or an inline fragment of a selection set
+type graphQLSelection struct {
+	Alias      string
+	Name       string
+	Arguments  map[string]any
+	Directives []graphQLDirective
+	Selections []*graphQLSelection
+	Fragment   string // Name of the spread fragment
+	Inline     bool   // Inline fragment, its selections belong to the parent
+}
+
+// graphQLOperation is a query of a document
+type graphQLOperation struct {
+	Kind       string
+	Name       string
+	Defaults   map[string]any // Default values of the variables
+	Selections []*graphQLSelection
+}
+
+// graphQLDocument is a parsed query document
+type graphQLDocument struct {
+	Operations []*graphQLOperation
+	Fragments  map[string][]*graphQLSelection
+}
+
+// graphQLToken is a token of the query language
+type graphQLToken struct {
+	kind  byte // 'n' name, 'i' int, 'f' float, 's' string, 'p' punctuator, 0 end
+	text  string
+	value string // Unescaped value of strings
+	pos   int
+}
+
+// graphQLParser parses query documents by recursive descent
+type graphQLParser struct {
+	source string
+	pos    int
+	token  graphQLToken
+}
+
+// parseGraphQL parses a query document
+func parseGraphQL(source string) (*graphQLDocument, error) {
+	if len(source) > maxGraphQLQueryLength {
+		return nil, fmt.Errorf("query is longer than %d bytes", maxGraphQLQueryLength)
+	}
+	parser := &graphQLParser{source: source}
+	if err := parser.next(); err != nil {
+		return nil, err
+	}
+
+	document := &graphQLDocument{Fragments: map[string][]*graphQLSelection{}}
+	for parser.token.kind != 0 {
+		switch {
+		case parser.peek("{"):
+			selections, err := parser.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			document.Operations = append(document.Operations, &graphQLOperation{Kind: "query", Selections: selections})
+		case parser.token.kind == 'n' && parser.token.text == "fragment":
+			name, selections, err := parser.fragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, exists := document.Fragments[name]; exists {
+				return nil, fmt.Errorf("fragment %s is defined twice", name)
+			}
+			document.Fragments[name] = selections
+		case parser.token.kind == 'n' && slices.Contains([]string{"query", "mutation", "subscription"}, parser.token.text):
+			operation, err := parser.operation()
+			if err != nil {
+				return nil, err
+			}
+			document.Operations = append(document.Operations, operation)
+		default:
+			return nil, parser.unexpected()
+		}
+	}
+	if len(document.Operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	return document, nil
+}
+
+// next reads the next token, skipping white space, commas and comments
+func (p *graphQLParser) next() error {
+	for p.pos < len(p.source) {
+		c := p.source[p.pos]
+		if c == '#' {
+			for p.pos < len(p.source) && p.source[p.pos] != '\n' && p.source[p.pos] != '\r' {
+				p.pos++
+			}
+			continue
+		}
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+			continue
+		}
+		if strings.HasPrefix(p.source[p.pos:], "\uFEFF") {
+			p.pos += len("\uFEFF")
+			continue
+		}
+		break
+	}
+
+	start := p.pos
+	if p.pos >= len(p.source) {
+		p.token = graphQLToken{pos: start}
+		return nil
+	}
+	c := p.source[p.pos]
+	switch {
+	case strings.HasPrefix(p.source[p.pos:], "..."):
+		p.pos += 3
+		p.token = graphQLToken{kind: 'p', text: "...", pos: start}
+	case strings.ContainsRune("!$():=@[]{}|&", rune(c)):
+		p.pos++
+		p.token = graphQLToken{kind: 'p', text: string(c), pos: start}
+	case c == '_' || isLetter(c):
+		for p.pos < len(p.source) && (p.source[p.pos] == '_' || isLetter(p.source[p.pos]) || isDigit(p.source[p.pos])) {
+			p.pos++
+		}
+		p.token = graphQLToken{kind: 'n', text: p.source[start:p.pos], pos: start}
+	case c == '-' || isDigit(c):
+		return p.number()
+	case c == '"':
+		return p.string()
+	default:
+		r, _ := utf8.DecodeRuneInString(p.source[p.pos:])
+		return fmt.Errorf("unexpected character %q at position %d", r, start)
+	}
+	return nil
+}
+
+func isLetter(c byte) bool { return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') }
+func isDigit(c byte) bool  { return c >= '0' && c <= '9' }
+
+// number reads an int or float token
+func (p *graphQLParser) number() error {
+	start := p.pos
+	kind := byte('i')
+	if p.source[p.pos] == '-' {
+		p.pos++
+	}
+	digits := func() {
+		for p.pos < len(p.source) && isDigit(p.source[p.pos]) {
+			p.pos++
+		}
+	}
+	integerStart := p.pos
+	digits()
+	if p.pos-integerStart > 1 && p.source[integerStart] == '0' {
+		return fmt.Errorf("invalid number %q at position %d, numbers must not start with 0", p.source[start:p.pos], start)
+	}
+	if p.pos < len(p.source) && p.source[p.pos] == '.' {
+		kind = 'f'
+		p.pos++
+		digits()
+	}
+	if p.pos < len(p.source) && (p.source[p.pos] == 'e' || p.source[p.pos] == 'E') {
+		kind = 'f'
+		p.pos++
+		if p.pos < len(p.source) && (p.source[p.pos] == '+' || p.source[p.pos] == '-') {
+			p.pos++
+		}
+		digits()
+	}
+	text := p.source[start:p.pos]
+	if text == "-" || strings.HasSuffix(text, ".") || strings.HasSuffix(text, "e") || strings.HasSuffix(text, "E") {
+		return fmt.Errorf("invalid number %q at position %d", text, start)
+	}
+	p.token = graphQLToken{kind: kind, text: text, pos: start}
+	return nil
+}
+
+// string reads a string or block string token
+func (p *graphQLParser) string() error {
+	start := p.pos
+	if strings.HasPrefix(p.source[p.pos:], `"""`) {
+		end := strings.Index(p.source[p.pos+3:], `"""`)
+		if end < 0 {
+			return fmt.Errorf("unterminated block string at position %d", start)
+		}
+		value := p.source[p.pos+3 : p.pos+3+end]
+		p.pos += end + 6
+		p.token = graphQLToken{kind: 's', text: p.source[start:p.pos], value: strings.TrimSpace(value), pos: start}
+		return nil
+	}
+
+	p.pos++
+	for p.pos < len(p.source) && p.source[p.pos] != '"' {
+		switch p.source[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '\n', '\r':
+			return fmt.Errorf("unterminated string at position %d", start)
+		default:
+			p.pos++
+		}
+	}
+	if p.pos >= len(p.source) {
+		return fmt.Errorf("unterminated string at position %d", start)
+	}
+	p.pos++
+	text := p.source[start:p.pos]
+	// GraphQL strings are escaped like JSON strings
+	var value string
+	if err := json.Unmarshal([]byte(text), &value); err != nil {
+		return fmt.Errorf("invalid string at position %d: %v", start, err)
+	}
+	p.token = graphQLToken{kind: 's', text: text, value: value, pos: start}
+	return nil
+}
+
+// peek reports whether the current token is the punctuator
+func (p *graphQLParser) peek(punctuator string) bool {
+	return p.token.kind == 'p' && p.token.text == punctuator
+}
+
+// expect skips the punctuator or fails
+func (p *graphQLParser) expect(punctuator string) error {
+	if !p.peek(punctuator) {
+		return fmt.Errorf("expected %q at position %d, got %s", punctuator, p.token.pos, p.describe())
+	}
+	return p.next()
+}
+
+// name reads a name
+func (p *graphQLParser) name() (string, error) {
+	if p.token.kind != 'n' {
+		return "", fmt.Errorf("expected a name at position %d, got %s", p.token.pos, p.describe())
+	}
+	name := p.token.text
+	return name, p.next()
+}
+
+func (p *graphQLParser) describe() string {
+	if p.token.kind == 0 {
+		return "end of query"
+	}
+	return strconv.Quote(p.token.text)
+}
+
+func (p *graphQLParser) unexpected() error {
+	return fmt.Errorf("unexpected %s at position %d", p.describe(), p.token.pos)
+}
+
+// operation reads a query, mutation or subscription with its variable definitions
+func (p *graphQLParser) operation() (*graphQLOperation, error) {
+	operation := &graphQLOperation{Kind: p.token.text, Defaults: map[string]any{}}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.token.kind == 'n' {
+		operation.Name = p.token.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.peek(")") {
+			if err := p.expect("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if err := p.typeReference(); err != nil {
+				return nil, err
+			}
+			if p.peek("=") {
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				value, err := p.value(true)
+				if err != nil {
+					return nil, err
+				}
+				operation.Defaults[name] = value
+			}
+			if _, err := p.directives(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	operation.Selections = selections
+	return operation, nil
+}
+
+// typeReference skips the type of a variable, values are checked by the fields using them
+func (p *graphQLParser) typeReference() error {
+	if p.peek("[") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if err := p.typeReference(); err != nil {
+			return err
+		}
+		if err := p.expect("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.name(); err != nil {
+		return err
+	}
+	if p.peek("!") {
+		return p.next()
+	}
+	return nil
+}
+
+// fragment reads a fragment definition
+func (p *graphQLParser) fragment() (string, []*graphQLSelection, error) {
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	name, err := p.name()
+	if err != nil {
+		return "", nil, err
+	}
+	if name == "on" {
+		return "", nil, fmt.Errorf("fragment must not be named on")
+	}
+	if p.token.kind != 'n' || p.token.text != "on" {
+		return "", nil, fmt.Errorf("expected type condition of fragment %s at position %d", name, p.token.pos)
+	}
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.name(); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.directives(); err != nil {
+		return "", nil, err
+	}
+	selections, err := p.selectionSet()
+	return name, selections, err
+}
+
+// selectionSet reads the selections between braces
+func (p *graphQLParser) selectionSet() ([]*graphQLSelection, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	var selections []*graphQLSelection
+	for !p.peek("}") {
+		selection, err := p.selection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	if len(selections) == 0 {
+		return nil, fmt.Errorf("empty selection set at position %d", p.token.pos)
+	}
+	return selections, p.next()
+}
+
+// selection reads a field, a fragment spread or an inline fragment
+func (p *graphQLParser) selection() (*graphQLSelection, error) {
+	selection := &graphQLSelection{}
+	var err error
+	if p.peek("...") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if p.token.kind == 'n' && p.token.text != "on" {
+			selection.Fragment = p.token.text
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			selection.Directives, err = p.directives()
+			return selection, err
+		}
+		// Inline fragments apply to the type of the parent, the type condition is skipped
+		selection.Inline = true
+		if p.token.kind == 'n' {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if _, err := p.name(); err != nil {
+				return nil, err
+			}
+		}
+		if selection.Directives, err = p.directives(); err != nil {
+			return nil, err
+		}
+		selection.Selections, err = p.selectionSet()
+		return selection, err
+	}
+
+	if selection.Name, err = p.name(); err != nil {
+		return nil, err
+	}
+	if p.peek(":") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		selection.Alias = selection.Name
+		if selection.Name, err = p.name(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek("(") {
+		if selection.Arguments, err = p.arguments(); err != nil {
+			return nil, err
+		}
+	}
+	if selection.Directives, err = p.directives(); err != nil {
+		return nil, err
+	}
+	if p.peek("{") {
+		if selection.Selections, err = p.selectionSet(); err != nil {
+			return nil, err
+		}
+	}
+	return selection, nil
+}
+
+// arguments reads the arguments between parentheses
+func (p *graphQLParser) arguments() (map[string]any, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	arguments := map[string]any{}
+	for !p.peek(")") {
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(":"); err != nil {
+			return nil, err
+		}
+		if arguments[name], err = p.value(false); err != nil {
+			return nil, err
+		}
+	}
+	return arguments, p.next()
+}
+
+// directives reads the directives of a selection
+func (p *graphQLParser) directives() ([]graphQLDirective, error) {
+	var directives []graphQLDirective
+	for p.peek("@") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		directive := graphQLDirective{Name: name}
+		if p.peek("(") {
+			if directive.Arguments, err = p.arguments(); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, directive)
+	}
+	return directives, nil
+}
+
+// value reads an argument value. Constant values, like the defaults of variables, must not refer to variables.
+func (p *graphQLParser) value(constant bool) (any, error) {
+	token := p.token
+	switch {
+	case p.peek("$") && !constant:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		name, err := p.name()
+		return graphQLVariable(name), err
+	case token.kind == 'i':
+		value, err := strconv.ParseInt(token.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid int %s at position %d", token.text, token.pos)
+		}
+		return value, p.next()
+	case token.kind == 'f':
+		value, err := strconv.ParseFloat(token.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s at position %d", token.text, token.pos)
+		}
+		return value, p.next()
+	case token.kind == 's':
+		return token.value, p.next()
+	case token.kind == 'n':
+		var value any = token.text // Enum values are passed as strings
+		switch token.text {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		}
+		return value, p.next()
+	case p.peek("["):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		list := []any{}
+		for !p.peek("]") {
+			item, err := p.value(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, item)
+		}
+		return list, p.next()
+	case p.peek("{"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		object := map[string]any{}
+		for !p.peek("}") {
+			name, err := p.name()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.value(constant); err != nil {
+				return nil, err
+			}
+		}
+		return object, p.next()
+	}
+	return nil, p.unexpected()
+}
+
+// graphQLExecution runs an operation of a document against a schema
+type graphQLExecution struct {
+	schema    graphQLSchema
+	document  *graphQLDocument
+	variables map[string]any
+	errors    []GraphQLError
+	fields    int // Fields selected so far, checked against maxGraphQLFields
+	cost      int // Estimated cost of the query, checked against maxGraphQLCost
+}
+
+// executeGraphQL runs the query of the request. Errors of the query itself are returned as error, errors of fields
+// are part of the response.
+func executeGraphQL(ctx context.Context, schema graphQLSchema, request GraphQLRequest) (GraphQLResponse, error) {
+	document, err := parseGraphQL(request.Query)
+	if err != nil {
+		return GraphQLResponse{}, fmt.Errorf("syntax error: %w", err)
+	}
+
+	var operation *graphQLOperation
+	for _, candidate := range document.Operations {
+		if request.OperationName == "" || candidate.Name == request.OperationName {
+			if operation != nil {
+				return GraphQLResponse{}, fmt.Errorf("operationName is required for documents with several operations")
+			}
+			operation = candidate
+		}
+	}
+	if operation == nil {
+		return GraphQLResponse{}, fmt.Errorf("unknown operation %s", request.OperationName)
+	}
+	if operation.Kind != "query" {
+		return GraphQLResponse{}, fmt.Errorf("%s operations are not supported, use the REST API for changes", operation.Kind)
+	}
+
+	variables := map[string]any{}
+	for name, value := range operation.Defaults {
+		variables[name] = value
+	}
+	for name, value := range request.Variables {
+		variables[name] = value
+	}
+	execution := &graphQLExecution{schema: schema, document: document, variables: variables}
+	selections, err := execution.collectFields(operation.Selections, map[string]bool{})
+	if err != nil {
+		return GraphQLResponse{}, err
+	}
+	if err := execution.validate("Query", selections, 1, 1, graphQLListSize); err != nil {
+		return GraphQLResponse{}, err
+	}
+
+	data := execution.selectObject(ctx, "Query", nil, selections, nil)
+	return GraphQLResponse{Data: data, Errors: execution.errors}, nil
+}
+
+// collectFields expands fragments and drops the selections excluded by @include and @skip
+func (execution *graphQLExecution) collectFields(selections []*graphQLSelection, spreading map[string]bool) ([]*graphQLSelection, error) {
+	var fields []*graphQLSelection
+	for _, selection := range selections {
+		included, err := execution.included(selection.Directives)
+		if err != nil {
+			return nil, err
+		}
+		if !included {
+			continue
+		}
+		switch {
+		case selection.Fragment != "":
+			fragment, ok := execution.document.Fragments[selection.Fragment]
+			if !ok {
+				return nil, fmt.Errorf("unknown fragment %s", selection.Fragment)
+			}
+			if spreading[selection.Fragment] {
+				return nil, fmt.Errorf("fragment %s spreads itself", selection.Fragment)
+			}
+			spreading[selection.Fragment] = true
+			expanded, err := execution.collectFields(fragment, spreading)
+			delete(spreading, selection.Fragment)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, expanded...)
+		case selection.Inline:
+			expanded, err := execution.collectFields(selection.Selections, spreading)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, expanded...)
+		default:
+			// Fragments spreading other fragments several times multiply the fields, so they are counted while expanding
+			execution.fields++
+			if execution.fields > maxGraphQLFields {
+				return nil, fmt.Errorf("query selects more than %d fields", maxGraphQLFields)
+			}
+			field := *selection
+			if field.Selections != nil {
+				if field.Selections, err = execution.collectFields(selection.Selections, spreading); err != nil {
+					return nil, err
+				}
+			}
+			fields = append(fields, &field)
+		}
+	}
+	return fields, nil
+}
+
+// included evaluates the @include and @skip directives
+func (execution *graphQLExecution) included(directives []graphQLDirective) (bool, error) {
+	for _, directive := range directives {
+		if directive.Name != "include" && directive.Name != "skip" {
+			return false, fmt.Errorf("unknown directive @%s", directive.Name)
+		}
+		condition, ok := execution.resolveValue(directive.Arguments["if"]).(bool)
+		if !ok {
+			return false, fmt.Errorf("@%s needs a boolean argument if", directive.Name)
+		}
+		if condition == (directive.Name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// validate checks the selected fields exist and estimates the cost of the query before anything is fetched. Fields
+// with a resolver cost one for every item of the lists they are in, the lists are assumed to have listSize items.
+func (execution *graphQLExecution) validate(typeName string, selections []*graphQLSelection, depth int, items int, listSize int) error {
+	if depth > maxGraphQLDepth {
+		return fmt.Errorf("query is nested deeper than %d levels", maxGraphQLDepth)
+	}
+	typ := execution.schema[typeName]
+	for _, selection := range selections {
+		if selection.Name == "__typename" {
+			continue
+		}
+		field, ok := typ[selection.Name]
+		if typ != nil && !ok {
+			return fmt.Errorf("cannot query field %s on type %s", selection.Name, typeName)
+		}
+		if field.Type != "" && selection.Selections == nil {
+			return fmt.Errorf("field %s of type %s must have a selection of subfields", selection.Name, field.Type)
+		}
+		if field.Resolve != nil {
+			execution.cost += items
+			if execution.cost > maxGraphQLCost {
+				return fmt.Errorf("query is too expensive, it computes more than %d fields, select fewer fields or smaller pages", maxGraphQLCost)
+			}
+		}
+		if selection.Selections != nil {
+			// page_size is the size of a list field, or of the list of items of a page. Lists below have the default size.
+			size, itemsSize := listSize, graphQLListSize
+			arguments, _ := execution.resolveValue(selection.Arguments).(map[string]any)
+			if pageSize, err := graphQLIntArg(arguments, "page_size", 0); err == nil && pageSize > 0 {
+				size, itemsSize = min(pageSize, maxGraphQLCost+1), min(pageSize, maxGraphQLCost+1)
+			}
+			fieldItems := items
+			if strings.HasPrefix(field.Type, "[") {
+				fieldItems = min(items*size, maxGraphQLCost+1)
+				itemsSize = graphQLListSize
+			}
+			if err := execution.validate(graphQLTypeName(field.Type), selection.Selections, depth+1, fieldItems, itemsSize); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolveValue replaces the variables in an argument value
+func (execution *graphQLExecution) resolveValue(value any) any {
+	switch value := value.(type) {
+	case graphQLVariable:
+		return execution.variables[string(value)]
+	case []any:
+		resolved := make([]any, len(value))
+		for i, item := range value {
+			resolved[i] = execution.resolveValue(item)
+		}
+		return resolved
+	case map[string]any:
+		resolved := make(map[string]any, len(value))
+		for key, item := range value {
+			resolved[key] = execution.resolveValue(item)
+		}
+		return resolved
+	}
+	return value
+}
+
+// selectObject selects the fields of an object. Fields that fail are null and recorded as errors.
+func (execution *graphQLExecution) selectObject(ctx context.Context, typeName string, parent map[string]any, selections []*graphQLSelection, path []any) graphQLObject {
+	typ := execution.schema[typeName]
+	object := graphQLObject{}
+	for _, selection := range selections {
+		key := selection.Name
+		if selection.Alias != "" {
+			key = selection.Alias
+		}
+		if selection.Name == "__typename" {
+			if typeName == "" {
+				typeName = "Object"
+			}
+			object = append(object, graphQLEntry{key, typeName})
+			continue
+		}
+
+		fieldPath := append(slices.Clone(path), key)
+		field := typ[selection.Name]
+		var value any
+		if field.Resolve != nil {
+			arguments := map[string]any{}
+			for name, argument := range selection.Arguments {
+				arguments[name] = execution.resolveValue(argument)
+			}
+			resolved, err := field.Resolve(ctx, parent, arguments)
+			if err != nil {
+				execution.errors = append(execution.errors, GraphQLError{Message: err.Error(), Path: fieldPath})
+				object = append(object, graphQLEntry{key, nil})
+				continue
+			}
+			value, err = toJSONValue(resolved)
+			if err != nil {
+				execution.errors = append(execution.errors, GraphQLError{Message: err.Error(), Path: fieldPath})
+				object = append(object, graphQLEntry{key, nil})
+				continue
+			}
+		} else {
+			value = parent[selection.Name]
+		}
+
+		if selection.Selections != nil {
+			value = execution.selectValue(ctx, graphQLTypeName(field.Type), value, selection.Selections, fieldPath)
+		}
+		object = append(object, graphQLEntry{key, value})
+	}
+	return object
+}
+
+// selectValue selects the fields of an object or of the objects of a list
+func (execution *graphQLExecution) selectValue(ctx context.Context, typeName string, value any, selections []*graphQLSelection, path []any) any {
+	switch value := value.(type) {
+	case map[string]any:
+		return execution.selectObject(ctx, typeName, value, selections, path)
+	case []any:
+		list := make([]any, len(value))
+		for i, item := range value {
+			list[i] = execution.selectValue(ctx, typeName, item, selections, append(slices.Clone(path), i))
+		}
+		return list
+	case nil:
+		return nil
+	}
+	execution.errors = append(execution.errors, GraphQLError{Message: "field is not an object, it has no subfields", Path: path})
+	return nil
+}
+
+// graphQLTypeName returns the object type of a field type, without list brackets and non-null marks
+func graphQLTypeName(fieldType string) string {
+	return strings.Trim(fieldType, "[]!")
+}
+
+// toJSONValue converts a resolved value to its JSON encoding as maps, lists and scalars
+func toJSONValue(value any) (any, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var decoded any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// graphQLIntArg reads an optional int argument
+func graphQLIntArg(args map[string]any, name string, defaultValue int) (int, error) {
+	switch value := args[name].(type) {
+	case nil:
+		return defaultValue, nil
+	case int64:
+		return int(value), nil
+	case float64:
+		if value == math.Trunc(value) {
+			return int(value), nil
+		}
+	case json.Number:
+		if parsed, err := value.Int64(); err == nil {
+			return int(parsed), nil
+		}
+	}
+	return 0, fmt.Errorf("argument %s must be an Int", name)
+}
+
+// graphQLParentInt reads an int field of the parent object
+func graphQLParentInt(parent map[string]any, name string) int {
+	value, _ := graphQLIntArg(parent, name, 0)
+	return value
+}
+
+// graphQLStringArg reads an optional string argument
+func graphQLStringArg(args map[string]any, name string) (string, error) {
+	switch value := args[name].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	}
+	return "", fmt.Errorf("argument %s must be a String", name)
+}
+
+// graphQLSchemaText describes the schema in the schema definition language
+func graphQLSchemaText(schema graphQLSchema) string {
+	var text strings.Builder
+	for _, typeName := range slices.Sorted(maps.Keys(schema)) {
+		fmt.Fprintf(&text, "type %s {\n", typeName)
+		typ := schema[typeName]
+		for _, name := range slices.Sorted(maps.Keys(typ)) {
+			field := typ[name]
+			fieldType := "JSON"
+			if field.Type != "" {
+				fieldType = field.Type
+			}
+			fmt.Fprintf(&text, "  %s%s: %s\n", name, field.Args, fieldType)
+		}
+		text.WriteString("}\n\n")
+	}
+	return text.String()
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// graphQLSchema builds the schema of the GraphQL API. Documents are fetched from paperless-ngx at most once per query,
+// so the schema is built for every query.
+func (app *App) graphQLSchema() graphQLSchema {
+	documents := map[int]Document{}
+	document := func(ctx context.Context, id int) (any, error) {
+		if id <= 0 {
+			return nil, nil
+		}
+		if cached, ok := documents[id]; ok {
+			return cached, nil
+		}
+		fetched, err := app.Client.GetDocument(ctx, id)
+		if app.skipDeletedDocument(id, err) {
+			return nil, fmt.Errorf("document %d was deleted in paperless-ngx", id)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error fetching document %d: %w", id, err)
+		}
+		documents[id] = fetched
+		return fetched, nil
+	}
+	documentOf := func(field string) graphQLField {
+		return graphQLField{Type: "Document", Resolve: func(ctx context.Context, parent map[string]any, _ map[string]any) (any, error) {
+			return document(ctx, graphQLParentInt(parent, field))
+		}}
+	}
+
+	return graphQLSchema{
+		"Query": {
+			"documents": {
+				Type:    "DocumentPage",
+				Args:    "(page: Int, page_size: Int, sort: String, search: String, tags: [String], correspondent: String, saved_view: String, query: String)",
+				Resolve: app.resolveGraphQLDocuments,
+			},
+			"document": {
+				Type: "Document",
+				Args: "(id: Int!)",
+				Resolve: func(ctx context.Context, _ map[string]any, args map[string]any) (any, error) {
+					id, err := graphQLIntArg(args, "id", 0)
+					if err != nil {
+						return nil, err
+					}
+					return document(ctx, id)
+				},
+			},
+			"jobs": {
+				Type:    "[Job]",
+				Args:    "(kind: String, status: String, document_id: Int)",
+				Resolve: resolveGraphQLJobs,
+			},
+			"job": {
+				Type: "Job",
+				Args: "(id: String!)",
+				Resolve: func(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+					id, err := graphQLStringArg(args, "id")
+					if err != nil {
+						return nil, err
+					}
+					if job, ok := jobStore.getJob(id); ok {
+						return jobResponse(job), nil
+					}
+					return nil, nil
+				},
+			},
+			"history": {
+				Type:    "HistoryPage",
+				Args:    "(page: Int, page_size: Int, document_id: Int, field: String, from: String, to: String, undone: Boolean, archived: String, search: String)",
+				Resolve: app.resolveGraphQLHistory,
+			},
+			"reviews": {
+				Type: "[Review]",
+				Resolve: func(_ context.Context, _ map[string]any, _ map[string]any) (any, error) {
+					return app.pendingReviews(0)
+				},
+			},
+			"stats": {
+				Type:    "Stats",
+				Args:    "(days: Int)",
+				Resolve: app.resolveGraphQLStats,
+			},
+		},
+		"DocumentPage": {
+			"total_count": {},
+			"page":        {},
+			"page_size":   {},
+			"items":       {Type: "[Document]"},
+		},
+		"Document": graphQLFieldsOf(Document{}, graphQLType{
+			"history": {
+				Type: "[Modification]",
+				Resolve: func(_ context.Context, parent map[string]any, _ map[string]any) (any, error) {
+					return GetDocumentModifications(app.Database, uint(graphQLParentInt(parent, "id")))
+				},
+			},
+			"jobs": {
+				Type: "[Job]",
+				Resolve: func(ctx context.Context, parent map[string]any, _ map[string]any) (any, error) {
+					return resolveGraphQLJobs(ctx, nil, map[string]any{"document_id": int64(graphQLParentInt(parent, "id"))})
+				},
+			},
+			"review": {
+				Type: "Review",
+				Resolve: func(_ context.Context, parent map[string]any, _ map[string]any) (any, error) {
+					reviews, err := app.pendingReviews(uint(graphQLParentInt(parent, "id")))
+					if err != nil || len(reviews) == 0 {
+						return nil, err
+					}
+					return reviews[0], nil
+				},
+			},
+		}),
+		"HistoryPage": {
+			"items":       {Type: "[Modification]"},
+			"totalItems":  {},
+			"totalPages":  {},
+			"currentPage": {},
+			"pageSize":    {},
+		},
+		"Modification": graphQLFieldsOf(ModificationHistory{}, graphQLType{"document": documentOf("DocumentID")}),
+		"Job": {
+			"job_id":      {},
+			"kind":        {},
+			"document_id": {},
+			"status":      {},
+			"attempts":    {},
+			"created_at":  {},
+			"updated_at":  {},
+			"pages_done":  {},
+			"user":        {},
+			"result":      {},
+			"error":       {},
+			"junk_pages":  {},
+			"document":    documentOf("document_id"),
+		},
+		"Review": graphQLFieldsOf(reviewedSuggestion{}, graphQLType{"document": documentOf("document_id")}),
+		"Stats":  graphQLFieldsOf(Stats{}, nil),
+	}
+}
+
+// resolveGraphQLDocuments lists documents like GET /api/documents
+func (app *App) resolveGraphQLDocuments(ctx context.Context, _ map[string]any, args map[string]any) (any, error) {
+	values := url.Values{}
+	for _, name := range []string{"page", "page_size"} {
+		if value, err := graphQLIntArg(args, name, 0); err != nil {
+			return nil, err
+		} else if value != 0 {
+			values.Set(name, strconv.Itoa(value))
+		}
+	}
+	for _, name := range []string{"sort", "search", "correspondent"} {
+		value, err := graphQLStringArg(args, name)
+		if err != nil {
+			return nil, err
+		}
+		values.Set(name, value)
+	}
+	if tags, ok := args["tags"].([]any); ok {
+		for _, tag := range tags {
+			if name, ok := tag.(string); ok {
+				values.Add("tag", name)
+			}
+		}
+	}
+	options, err := parseDocumentListOptions(values)
+	if err != nil {
+		return nil, err
+	}
+	savedView, err := graphQLStringArg(args, "saved_view")
+	if err != nil {
+		return nil, err
+	}
+	filterQuery, err := graphQLStringArg(args, "query")
+	if err != nil {
+		return nil, err
+	}
+
+	selection := "tags__name__iexact=" + urlEncode(settingsFor(ctx).ManualTag)
+	if savedView != "" || filterQuery != "" {
+		if selection, err = app.documentSelectionQuery(ctx, savedView, filterQuery); err != nil {
+			return nil, fmt.Errorf("invalid document selection: %w", err)
+		}
+	}
+	var tagIDs map[string]int
+	if len(options.Tags) > 0 {
+		if tagIDs, err = app.Client.GetAllTags(ctx); err != nil {
+			return nil, fmt.Errorf("error fetching tags: %w", err)
+		}
+	}
+
+	page := map[string]any{"page": options.Page, "page_size": options.PageSize, "total_count": 0, "items": []Document{}}
+	filters, matchesAny := options.filterQuery(tagIDs)
+	if !matchesAny {
+		return page, nil
+	}
+	documents, total, err := app.Client.GetDocumentsPage(ctx, joinQueries(selection, filters), options.Page, options.PageSize)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching documents: %w", err)
+	}
+	page["items"], page["total_count"] = documents, total
+	return page, nil
+}
+
+// resolveGraphQLJobs lists the jobs like GET /api/jobs, optionally only those of a document
+func resolveGraphQLJobs(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+	kind, err := graphQLStringArg(args, "kind")
+	if err != nil {
+		return nil, err
+	}
+	status, err := graphQLStringArg(args, "status")
+	if err != nil {
+		return nil, err
+	}
+	documentID, err := graphQLIntArg(args, "document_id", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := []any{}
+	for _, job := range jobStore.GetAllJobs() {
+		if (kind != "" && job.Kind != kind) || (status != "" && job.Status != status) || (documentID != 0 && job.DocumentID != documentID) {
+			continue
+		}
+		jobs = append(jobs, jobResponse(job))
+	}
+	return jobs, nil
+}
+
+// resolveGraphQLHistory pages through the modification history like GET /api/modifications
+func (app *App) resolveGraphQLHistory(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+	page, err := graphQLIntArg(args, "page", 1)
+	if err != nil {
+		return nil, err
+	}
+	pageSize, err := graphQLIntArg(args, "page_size", 20)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 || pageSize < 1 || pageSize > 100 {
+		return nil, fmt.Errorf("page must be positive and page_size between 1 and 100")
+	}
+	values := url.Values{}
+	if documentID, err := graphQLIntArg(args, "document_id", 0); err != nil {
+		return nil, err
+	} else if documentID != 0 {
+		values.Set("document_id", strconv.Itoa(documentID))
+	}
+	for _, name := range []string{"field", "from", "to", "search", "archived"} {
+		value, err := graphQLStringArg(args, name)
+		if err != nil {
+			return nil, err
+		}
+		values.Set(name, value)
+	}
+	if undone, ok := args["undone"].(bool); ok {
+		values.Set("undone", strconv.FormatBool(undone))
+	}
+	filter, err := parseModificationFilter(values)
+	if err != nil {
+		return nil, err
+	}
+
+	modifications, total, err := GetPaginatedModifications(app.Database, page, pageSize, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve modification history: %w", err)
+	}
+	return map[string]any{
+		"items":       modifications,
+		"totalItems":  total,
+		"totalPages":  (int(total) + pageSize - 1) / pageSize,
+		"currentPage": page,
+		"pageSize":    pageSize,
+	}, nil
+}
+
+// resolveGraphQLStats aggregates the statistics like GET /api/stats
+func (app *App) resolveGraphQLStats(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+	days, err := graphQLIntArg(args, "days", defaultStatsDays)
+	if err != nil {
+		return nil, err
+	}
+	if days < 1 || days > maxStatsDays {
+		return nil, fmt.Errorf("days must be a number between 1 and %d", maxStatsDays)
+	}
+	return collectStats(app.Database, statsSince(time.Now(), days))
+}
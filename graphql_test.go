@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	document, err := parseGraphQL(`
+		# Comments and commas are ignored
+		query Documents($id: Int! = 3, $tags: [String!]) @cached {
+			first: document(id: $id) { title, ...Fields @include(if: true) }
+			documents(tags: $tags, search: "say \"hi\"\n", page_size: 10) { items { ... on Document { id } } }
+		}
+		fragment Fields on Document { tags }
+	`)
+	require.NoError(t, err)
+	require.Len(t, document.Operations, 1)
+	operation := document.Operations[0]
+	assert.Equal(t, "Documents", operation.Name)
+	assert.Equal(t, map[string]any{"id": int64(3)}, operation.Defaults)
+	require.Len(t, operation.Selections, 2)
+	assert.Equal(t, "first", operation.Selections[0].Alias)
+	assert.Equal(t, graphQLVariable("id"), operation.Selections[0].Arguments["id"])
+	assert.Equal(t, "Fields", operation.Selections[0].Selections[1].Fragment)
+	assert.Equal(t, "say \"hi\"\n", operation.Selections[1].Arguments["search"])
+	assert.True(t, operation.Selections[1].Selections[0].Selections[0].Inline)
+	assert.Contains(t, document.Fragments, "Fields")
+
+	for _, invalid := range []string{"", "{}", "{ title", `{ document(id: "open) { id } }`, "{ a(b: ) }", "query { a } }", "{ a @ }"} {
+		_, err := parseGraphQL(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	documentRequests := 0
+	env.setMockResponse("/api/documents/5/", func(w http.ResponseWriter, r *http.Request) {
+		documentRequests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 5, "title": "Electricity bill", "tags": [1], "correspondent": 1}`))
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "bills"}], "next": null}`))
+	})
+
+	require.NoError(t, env.db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	require.NoError(t, InsertModification(env.db, &ModificationHistory{DocumentID: 5, ModField: "title", PreviousValue: "scan.pdf", NewValue: "Electricity bill", User: "alice"}))
+	defer env.db.Where("1 = 1").Delete(&ModificationHistory{})
+	job := newJob(JobKindOCR, 5)
+	jobStore.addJob(job)
+	defer func() {
+		jobStore.Lock()
+		delete(jobStore.jobs, job.ID)
+		jobStore.Unlock()
+	}()
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/graphql", app.graphQLHandler)
+	router.POST("/graphql", app.graphQLHandler)
+	router.GET("/graphql/schema", app.graphQLSchemaHandler)
+	query := func(request GraphQLRequest) (int, string) {
+		body, err := json.Marshal(request)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/graphql", strings.NewReader(string(body))))
+		return w.Code, w.Body.String()
+	}
+
+	// Nested data in one round trip, in the order of the selections
+	code, body := query(GraphQLRequest{
+		Query: `query Bill($id: Int!, $withJobs: Boolean!) {
+			bill: document(id: $id) {
+				__typename title tags
+				history { ModField NewValue User document { id } }
+				jobs @include(if: $withJobs) { kind status }
+				review { id }
+			}
+		}`,
+		Variables: map[string]any{"id": 5, "withJobs": true},
+	})
+	require.Equal(t, http.StatusOK, code, body)
+	assert.JSONEq(t, `{"data": {"bill": {
+		"__typename": "Document", "title": "Electricity bill", "tags": ["bills"],
+		"history": [{"ModField": "title", "NewValue": "Electricity bill", "User": "alice", "document": {"id": 5}}],
+		"jobs": [{"kind": "ocr", "status": "pending"}],
+		"review": null
+	}}}`, body)
+	assert.True(t, strings.Index(body, `"title"`) < strings.Index(body, `"history"`), "fields keep the order of the query")
+	assert.Equal(t, 1, documentRequests, "documents are fetched once per query")
+
+	// Queries can be sent with GET
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/graphql?query="+url.QueryEscape(`{ history(page_size: 5) { totalItems items { DocumentID } } }`), nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data": {"history": {"totalItems": 1, "items": [{"DocumentID": 5}]}}}`, w.Body.String())
+
+	// Invalid queries fail as a whole, failing fields are null
+	for _, invalid := range []string{
+		`{ document(id: 5) { secret } }`,
+		`{ document(id: 5) }`,
+		`mutation { undo(id: 1) }`,
+		`{ ...Loop } fragment Loop on Query { ...Loop }`,
+	} {
+		code, body := query(GraphQLRequest{Query: invalid})
+		assert.Equal(t, http.StatusOK, code)
+		assert.NotContains(t, body, `"data"`, invalid)
+		assert.Contains(t, body, `"errors"`, invalid)
+	}
+	code, body = query(GraphQLRequest{Query: `{ stats(days: 0) { since } document(id: 5) { id } }`})
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"data": {"stats": null, "document": {"id": 5}}, "errors": [{"message": "days must be a number between 1 and 365", "path": ["stats"]}]}`, body)
+
+	code, _ = query(GraphQLRequest{})
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/graphql/schema", nil))
+	assert.Contains(t, w.Body.String(), "document(id: Int!): Document")
+	assert.Contains(t, w.Body.String(), "history: [Modification]")
+}
+
+func TestParseGraphQLErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"", "document has no operation"},
+		{"{}", "empty selection set at position 1"},
+		{"{ title", "expected a name at position 7, got end of query"},
+		{`{ document(id: "open) { id } }`, "unterminated string at position 15"},
+		{`{ a(b: "\q") }`, "invalid escape sequence"},
+		{"{ a(b: ) }", `unexpected ")" at position 7`},
+		{"{ a(b: 1.) }", `invalid number "1." at position 7`},
+		{"{ a(b: 01) }", "numbers must not start with 0"},
+		{"{ a(b: [1, 2) }", `unexpected ")" at position 12`},
+		{"{ a(b: $) }", "expected a name at position 8"},
+		{"query Q($x: Int = $y) { a }", `unexpected "$" at position 18`},
+		{"query { a } }", `unexpected "}" at position 12`},
+		{"{ a @ }", "expected a name at position 6"},
+		{"{ ... }", `expected "{" at position 6`},
+		{"{ a } fragment on T { a }", "fragment must not be named on"},
+		{"fragment F on T { a } fragment F on T { b } { a }", "fragment F is defined twice"},
+		{strings.Repeat(" ", maxGraphQLQueryLength+1) + "{ a }", "query is longer than"},
+	}
+	for _, tc := range tests {
+		_, err := parseGraphQL(tc.query)
+		if assert.Error(t, err, tc.query) {
+			assert.Contains(t, err.Error(), tc.err, tc.query)
+		}
+	}
+}
+
+// testGraphQLSchema is a schema of items with IDs, item 2 has no parent
+func testGraphQLSchema() graphQLSchema {
+	item := func(id int) map[string]any { return map[string]any{"id": id, "name": fmt.Sprintf("item %d", id)} }
+	return graphQLSchema{
+		"Query": {
+			"item": {Type: "Item", Args: "(id: Int!)", Resolve: func(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+				id, err := graphQLIntArg(args, "id", 0)
+				if err != nil || id == 0 {
+					return nil, fmt.Errorf("argument id must be an Int")
+				}
+				return item(id), nil
+			}},
+			"items": {Type: "[Item]", Args: "(page_size: Int)", Resolve: func(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+				pageSize, err := graphQLIntArg(args, "page_size", 3)
+				if err != nil {
+					return nil, err
+				}
+				var items []any
+				for id := 1; id <= pageSize; id++ {
+					items = append(items, item(id))
+				}
+				return items, nil
+			}},
+			"echo": {Args: "(value: String)", Resolve: func(_ context.Context, _ map[string]any, args map[string]any) (any, error) {
+				return args["value"], nil
+			}},
+			"fail": {Resolve: func(context.Context, map[string]any, map[string]any) (any, error) {
+				return nil, errors.New("broken")
+			}},
+		},
+		"Item": {
+			"id":   {},
+			"name": {},
+			"parent": {Type: "Item", Resolve: func(_ context.Context, parent map[string]any, _ map[string]any) (any, error) {
+				if graphQLParentInt(parent, "id") == 2 {
+					return nil, errors.New("item 2 has no parent")
+				}
+				return item(graphQLParentInt(parent, "id") * 10), nil
+			}},
+			"children": {Type: "[Item]", Resolve: func(_ context.Context, parent map[string]any, _ map[string]any) (any, error) {
+				return []any{item(graphQLParentInt(parent, "id") + 1), item(graphQLParentInt(parent, "id") + 2)}, nil
+			}},
+		},
+	}
+}
+
+func TestExecuteGraphQL(t *testing.T) {
+	run := func(request GraphQLRequest) string {
+		t.Helper()
+		response, err := executeGraphQL(context.Background(), testGraphQLSchema(), request)
+		require.NoError(t, err, request.Query)
+		body, err := json.Marshal(response)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	// Variables override their defaults, variables that aren't set are null
+	query := `query Item($id: Int = 2, $value: String) { item(id: $id) { name } echo(value: $value) }`
+	assert.JSONEq(t, `{"data": {"item": {"name": "item 2"}, "echo": null}}`, run(GraphQLRequest{Query: query}))
+	assert.JSONEq(t, `{"data": {"item": {"name": "item 3"}, "echo": "hi"}}`,
+		run(GraphQLRequest{Query: query, Variables: map[string]any{"id": float64(3), "value": "hi"}}))
+	query = `query Skip($skip: Boolean!) { echo(value: "shown") skipped: echo(value: "hidden") @skip(if: $skip) }`
+	assert.JSONEq(t, `{"data": {"echo": "shown"}}`, run(GraphQLRequest{Query: query, Variables: map[string]any{"skip": true}}))
+	assert.JSONEq(t, `{"data": {"echo": "shown", "skipped": "hidden"}}`, run(GraphQLRequest{Query: query, Variables: map[string]any{"skip": false}}))
+
+	// Fragments are expanded in place, also nested and inline
+	assert.JSONEq(t, `{"data": {"item": {"id": 1, "name": "item 1", "parent": {"id": 10}}}}`, run(GraphQLRequest{
+		Query: `{ item(id: 1) { ...Fields } } fragment Fields on Item { id ...Name } fragment Name on Item { name ... on Item { parent { id } } }`,
+	}))
+
+	// Aliases select a field several times, in the order of the query
+	body := run(GraphQLRequest{Query: `{ second: item(id: 2) { key: id } first: item(id: 1) { id label: name } }`})
+	assert.JSONEq(t, `{"data": {"second": {"key": 2}, "first": {"id": 1, "label": "item 1"}}}`, body)
+	assert.Less(t, strings.Index(body, "second"), strings.Index(body, "first"))
+
+	// The operation is chosen by its name
+	query = `query One { item(id: 1) { id } } query Two { item(id: 2) { id } }`
+	assert.JSONEq(t, `{"data": {"item": {"id": 2}}}`, run(GraphQLRequest{Query: query, OperationName: "Two"}))
+
+	// Fields that fail are null and listed in the errors with their path, the other fields are still selected
+	assert.JSONEq(t, `{
+		"data": {"fail": null, "items": [{"id": 1, "parent": {"id": 10}}, {"id": 2, "parent": null}]},
+		"errors": [{"message": "broken", "path": ["fail"]}, {"message": "item 2 has no parent", "path": ["items", 1, "parent"]}]
+	}`, run(GraphQLRequest{Query: `{ fail items(page_size: 2) { id parent { id } } }`}))
+	assert.JSONEq(t, `{"data": {"echo": null}, "errors": [{"message": "field is not an object, it has no subfields", "path": ["echo"]}]}`,
+		run(GraphQLRequest{Query: `{ echo(value: "text") { id } }`}))
+	assert.JSONEq(t, `{"data": {"item": null}, "errors": [{"message": "argument id must be an Int", "path": ["item"]}]}`,
+		run(GraphQLRequest{Query: `query Item($id: Int) { item(id: $id) { id } }`, Variables: map[string]any{"id": "one"}}))
+}
+
+func TestExecuteGraphQLErrors(t *testing.T) {
+	// Queries that can't be run fail as a whole, before any field is resolved
+	nested := "{ item(id: 1) { " + strings.Repeat("parent { ", maxGraphQLDepth) + "id" + strings.Repeat(" }", maxGraphQLDepth) + " } }"
+	var fragments strings.Builder
+	for i := 1; i <= 12; i++ {
+		fmt.Fprintf(&fragments, " fragment F%d on Item { ...F%d ...F%d }", i, i-1, i-1)
+	}
+	var aliases strings.Builder
+	for i := 0; i <= maxGraphQLFields; i++ {
+		fmt.Fprintf(&aliases, " a%d: echo(value: \"x\")", i)
+	}
+	tests := []struct {
+		request GraphQLRequest
+		err     string
+	}{
+		{GraphQLRequest{Query: "{ item(id: 1) { id }"}, "syntax error: expected a name"},
+		{GraphQLRequest{Query: "{ secret }"}, "cannot query field secret on type Query"},
+		{GraphQLRequest{Query: "{ item(id: 1) { secret } }"}, "cannot query field secret on type Item"},
+		{GraphQLRequest{Query: "{ item(id: 1) }"}, "field item of type Item must have a selection of subfields"},
+		{GraphQLRequest{Query: "query One { echo } query Two { echo }"}, "operationName is required"},
+		{GraphQLRequest{Query: "query One { echo }", OperationName: "Two"}, "unknown operation Two"},
+		{GraphQLRequest{Query: "mutation { echo }"}, "mutation operations are not supported"},
+		{GraphQLRequest{Query: "{ ...Missing }"}, "unknown fragment Missing"},
+		{GraphQLRequest{Query: "{ ...A } fragment A on Query { ...B } fragment B on Query { ...A }"}, "fragment A spreads itself"},
+		{GraphQLRequest{Query: "{ echo @deprecated }"}, "unknown directive @deprecated"},
+		{GraphQLRequest{Query: "query Skip($skip: Boolean) { echo @skip(if: $skip) }"}, "@skip needs a boolean argument if"},
+		{GraphQLRequest{Query: nested}, fmt.Sprintf("query is nested deeper than %d levels", maxGraphQLDepth)},
+		// Fragments spreading others twice double the fields on every level
+		{GraphQLRequest{Query: "{ item(id: 1) { ...F12 } } fragment F0 on Item { id }" + fragments.String()}, fmt.Sprintf("query selects more than %d fields", maxGraphQLFields)},
+		{GraphQLRequest{Query: "{" + aliases.String() + " }"}, fmt.Sprintf("query selects more than %d fields", maxGraphQLFields)},
+	}
+	for _, tc := range tests {
+		_, err := executeGraphQL(context.Background(), testGraphQLSchema(), tc.request)
+		if assert.Error(t, err, tc.request.Query) {
+			assert.Contains(t, err.Error(), tc.err, tc.request.Query)
+		}
+	}
+}
+
+func TestGraphQLCost(t *testing.T) {
+	var parents strings.Builder
+	for i := 0; i < 25; i++ {
+		fmt.Fprintf(&parents, " p%d: parent { id }", i)
+	}
+	tests := []struct {
+		request  GraphQLRequest
+		tooLarge bool
+	}{
+		// The fields with resolvers below a list are resolved for each of its items, aliases are resolved separately
+		{GraphQLRequest{Query: "{ items(page_size: 100) {" + parents.String() + " } }"}, true},
+		{GraphQLRequest{Query: "{ items(page_size: 10) {" + parents.String() + " } }"}, false},
+		// Lists without page_size are assumed to have graphQLListSize items
+		{GraphQLRequest{Query: "{ items(page_size: 100) { children { children { id } } } }"}, true},
+		{GraphQLRequest{Query: "{ items(page_size: 10) { children { children { id } } } }"}, false},
+		{GraphQLRequest{Query: "query Items($size: Int) { items(page_size: $size) { children { children { id } } } }", Variables: map[string]any{"size": float64(100)}}, true},
+	}
+	for _, tc := range tests {
+		_, err := executeGraphQL(context.Background(), testGraphQLSchema(), tc.request)
+		if tc.tooLarge {
+			assert.ErrorContains(t, err, "query is too expensive", tc.request.Query)
+		} else {
+			assert.NoError(t, err, tc.request.Query)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// historyExportBatchSize is the number of modifications read from the database at once while exporting the history
+const historyExportBatchSize = 500
+
+// historyExportColumns are the columns of the CSV export of the modification history. The document snapshots are
+// only part of the JSON export.
+var historyExportColumns = []string{
+	"id", "document_id", "date_changed", "field", "previous_value", "new_value",
+	"undone", "undone_date", "user", "batch_id", "operation", "origin_id", "archived", "archived_date",
+}
+
+// exportModifications writes the modifications matching the filter, oldest first, as CSV or as a JSON array.
+// The modifications are read in batches, so the whole history is never held in memory.
+func exportModifications(db *gorm.DB, filter ModificationFilter, format string, w io.Writer) error {
+	if format == "json" {
+		return exportModificationsJSON(db, filter, w)
+	}
+	return exportModificationsCSV(db, filter, w)
+}
+
+// exportModificationsCSV writes the modifications matching the filter as CSV with historyExportColumns
+func exportModificationsCSV(db *gorm.DB, filter ModificationFilter, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(historyExportColumns); err != nil {
+		return err
+	}
+
+	var batch []ModificationHistory
+	err := filter.apply(db).FindInBatches(&batch, historyExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, modification := range batch {
+			err := writer.Write([]string{
+				strconv.FormatUint(uint64(modification.ID), 10),
+				strconv.FormatUint(uint64(modification.DocumentID), 10),
+				modification.DateChanged,
+				modification.ModField,
+				modification.PreviousValue,
+				modification.NewValue,
+				strconv.FormatBool(modification.Undone),
+				modification.UndoneDate,
+				modification.User,
+				modification.BatchID,
+				modification.Operation,
+				strconv.FormatUint(uint64(modification.OriginID), 10),
+				strconv.FormatBool(modification.Archived),
+				modification.ArchivedDate,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		return writer.Error()
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// exportModificationsJSON writes the modifications matching the filter as a JSON array, in the format of the items of
+// GET /api/modifications
+func exportModificationsJSON(db *gorm.DB, filter ModificationFilter, w io.Writer) error {
+	if _, err := io.WriteString(w, "["); err != nil {
+		return err
+	}
+
+	first := true
+	var batch []ModificationHistory
+	err := filter.apply(db).FindInBatches(&batch, historyExportBatchSize, func(tx *gorm.DB, _ int) error {
+		for _, modification := range batch {
+			data, err := json.Marshal(modification)
+			if err != nil {
+				return err
+			}
+			if !first {
+				if _, err := io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+			first = false
+			if _, err := w.Write(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "]\n")
+	return err
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportModificationHistoryHandler(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	for _, modification := range []ModificationHistory{
+		{DocumentID: 1, DateChanged: "2024-05-01T10:00:00Z", ModField: "title", PreviousValue: "scan.pdf", NewValue: "Invoice, May", User: "alice"},
+		{DocumentID: 1, DateChanged: "2024-05-02T10:00:00Z", ModField: "tags", PreviousValue: "[]", NewValue: `["bills"]`, Undone: true, UndoneDate: "2024-05-03T10:00:00Z"},
+		{DocumentID: 2, DateChanged: "2024-05-03T10:00:00Z", ModField: "title", PreviousValue: "scan2.pdf", NewValue: "Lease", DocumentBefore: `{"title":"scan2.pdf"}`},
+	} {
+		require.NoError(t, db.Create(&modification).Error)
+	}
+
+	app := &App{Database: db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/modifications/export", app.exportModificationHistoryHandler)
+
+	export := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/modifications/export"+query, nil))
+		return w
+	}
+
+	w := export("")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "paperless-gpt-history.csv")
+	rows, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, historyExportColumns, rows[0])
+	assert.Equal(t, []string{"1", "2024-05-01T10:00:00Z", "title", "scan.pdf", "Invoice, May", "false", "", "alice"}, rows[1][1:9])
+	assert.Equal(t, "true", rows[2][6])
+
+	w = export("?format=json&document_id=1")
+	require.Equal(t, http.StatusOK, w.Code)
+	var modifications []ModificationHistory
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &modifications))
+	require.Len(t, modifications, 2)
+	assert.Equal(t, "title", modifications[0].ModField)
+	assert.Equal(t, "tags", modifications[1].ModField)
+
+	w = export("?format=json&document_id=5")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, "[]", w.Body.String())
+
+	assert.Equal(t, http.StatusBadRequest, export("?format=xml").Code)
+	assert.Equal(t, http.StatusBadRequest, export("?document_id=none").Code)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseModificationFilter reads the filters of GET /api/modifications from the query parameters document_id, field,
+// from, to, undone, archived and search. from and to are dates or RFC 3339 times, both included in the range.
+// Archived modifications are left out unless archived is true, for only archived modifications, or all.
+func parseModificationFilter(values url.Values) (ModificationFilter, error) {
+	filter := ModificationFilter{
+		Field:  strings.TrimSpace(values.Get("field")),
+		Search: strings.TrimSpace(values.Get("search")),
+	}
+	if text := values.Get("document_id"); text != "" {
+		id, err := strconv.ParseUint(text, 10, 0)
+		if err != nil || id == 0 {
+			return ModificationFilter{}, fmt.Errorf("document_id must be a positive number, got: %s", text)
+		}
+		filter.DocumentID = uint(id)
+	}
+	if text := values.Get("from"); text != "" {
+		from, _, err := parseModificationTime(text)
+		if err != nil {
+			return ModificationFilter{}, fmt.Errorf("invalid from: %w", err)
+		}
+		filter.From = from
+	}
+	if text := values.Get("to"); text != "" {
+		to, dateOnly, err := parseModificationTime(text)
+		if err != nil {
+			return ModificationFilter{}, fmt.Errorf("invalid to: %w", err)
+		}
+		// Include the whole day or second, as modifications are dated to the second
+		if dateOnly {
+			filter.Until = to.AddDate(0, 0, 1)
+		} else {
+			filter.Until = to.Add(time.Second)
+		}
+	}
+	if !filter.From.IsZero() && !filter.Until.IsZero() && !filter.From.Before(filter.Until) {
+		return ModificationFilter{}, fmt.Errorf("from must not be after to")
+	}
+	if text := values.Get("undone"); text != "" {
+		undone, err := strconv.ParseBool(text)
+		if err != nil {
+			return ModificationFilter{}, fmt.Errorf("undone must be true or false, got: %s", text)
+		}
+		filter.Undone = &undone
+	}
+	switch text := values.Get("archived"); text {
+	case "all":
+	case "":
+		archived := false
+		filter.Archived = &archived
+	default:
+		archived, err := strconv.ParseBool(text)
+		if err != nil {
+			return ModificationFilter{}, fmt.Errorf("archived must be true, false or all, got: %s", text)
+		}
+		filter.Archived = &archived
+	}
+	return filter, nil
+}
+
+// parseModificationTime parses a date in the local time zone or an RFC 3339 time, reporting whether it was a date
+func parseModificationTime(text string) (time.Time, bool, error) {
+	if date, err := time.ParseInLocation(time.DateOnly, text, time.Local); err == nil {
+		return date, true, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, text)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected a date like 2006-01-02 or an RFC 3339 time, got: %s", text)
+	}
+	return parsed, false, nil
+}
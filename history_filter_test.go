@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseModificationFilter(t *testing.T) {
+	filter, err := parseModificationFilter(url.Values{
+		"document_id": {"7"},
+		"field":       {" tags "},
+		"from":        {"2024-05-01"},
+		"to":          {"2024-05-31"},
+		"undone":      {"false"},
+		"search":      {"Invoice"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint(7), filter.DocumentID)
+	assert.Equal(t, "tags", filter.Field)
+	assert.Equal(t, time.Date(2024, 5, 1, 0, 0, 0, 0, time.Local), filter.From)
+	assert.Equal(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local), filter.Until, "the last day is included")
+	require.NotNil(t, filter.Undone)
+	assert.False(t, *filter.Undone)
+	assert.Equal(t, "Invoice", filter.Search)
+	require.NotNil(t, filter.Archived)
+	assert.False(t, *filter.Archived, "archived modifications are hidden by default")
+
+	filter, err = parseModificationFilter(url.Values{"to": {"2024-05-31T12:00:00Z"}})
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 5, 31, 12, 0, 1, 0, time.UTC), filter.Until.UTC())
+	assert.Nil(t, filter.Undone)
+
+	filter, err = parseModificationFilter(url.Values{"archived": {"all"}})
+	require.NoError(t, err)
+	assert.Nil(t, filter.Archived)
+
+	for _, invalid := range []url.Values{
+		{"document_id": {"0"}},
+		{"document_id": {"seven"}},
+		{"from": {"May 1st"}},
+		{"from": {"2024-06-01"}, "to": {"2024-05-31"}},
+		{"undone": {"maybe"}},
+		{"archived": {"hidden"}},
+	} {
+		_, err := parseModificationFilter(invalid)
+		assert.Error(t, err, invalid.Encode())
+	}
+}
+
+func TestGetPaginatedModificationsFilter(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	day := func(d int) string { return time.Date(2024, 5, d, 10, 0, 0, 0, time.Local).Format(time.RFC3339) }
+	require.NoError(t, db.Create(&[]ModificationHistory{
+		{DocumentID: 1, DateChanged: day(1), ModField: "title", PreviousValue: "scan.pdf", NewValue: "Electricity Invoice"},
+		{DocumentID: 1, DateChanged: day(2), ModField: "tags", PreviousValue: "[]", NewValue: `["bills"]`, Undone: true},
+		{DocumentID: 2, DateChanged: day(3), ModField: "title", PreviousValue: "scan2.pdf", NewValue: "Lease", User: "alice"},
+	}).Error)
+
+	undone := true
+	tests := []struct {
+		name   string
+		filter ModificationFilter
+		want   []string
+	}{
+		{"all, newest first", ModificationFilter{}, []string{"Lease", `["bills"]`, "Electricity Invoice"}},
+		{"document", ModificationFilter{DocumentID: 1}, []string{`["bills"]`, "Electricity Invoice"}},
+		{"field", ModificationFilter{Field: "title"}, []string{"Lease", "Electricity Invoice"}},
+		{"date range", ModificationFilter{From: time.Date(2024, 5, 2, 0, 0, 0, 0, time.Local), Until: time.Date(2024, 5, 3, 0, 0, 0, 0, time.Local)}, []string{`["bills"]`}},
+		{"undone", ModificationFilter{Undone: &undone}, []string{`["bills"]`}},
+		{"search in values", ModificationFilter{Search: "invoice"}, []string{"Electricity Invoice"}},
+		{"search in user", ModificationFilter{Search: "ALICE"}, []string{"Lease"}},
+		{"combined", ModificationFilter{DocumentID: 2, Search: "invoice"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, total, err := GetPaginatedModifications(db, 1, 20, tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), total)
+			var values []string
+			for _, record := range records {
+				values = append(values, record.NewValue)
+			}
+			assert.Equal(t, tt.want, values)
+		})
+	}
+
+	// The total counts all matching modifications, not only the page
+	records, total, err := GetPaginatedModifications(db, 2, 1, ModificationFilter{Field: "title"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, records, 1)
+	assert.Equal(t, "Electricity Invoice", records[0].NewValue)
+}
+
+func TestArchiveModificationsHandler(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	first := ModificationHistory{DocumentID: 1, DateChanged: "2024-05-01T10:00:00Z", ModField: "title", NewValue: "Invoice"}
+	second := ModificationHistory{DocumentID: 2, DateChanged: "2024-05-02T10:00:00Z", ModField: "title", NewValue: "Lease"}
+	require.NoError(t, db.Create(&first).Error)
+	require.NoError(t, db.Create(&second).Error)
+
+	app := &App{Database: db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/modifications", app.getModificationHistoryHandler)
+	router.POST("/api/modifications/archive", app.archiveModificationsHandler)
+	router.POST("/api/modifications/restore", app.restoreModificationsHandler)
+
+	post := func(target string, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, target, bytes.NewBufferString(body)))
+		return w
+	}
+	listed := func(query string) []string {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/modifications"+query, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var response struct{ Items []ModificationHistory }
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		values := []string{}
+		for _, item := range response.Items {
+			values = append(values, item.NewValue)
+		}
+		return values
+	}
+
+	w := post("/api/modifications/archive", `{"ids": [`+strconv.Itoa(int(first.ID))+`]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"archived": 1}`, w.Body.String())
+	assert.Equal(t, []string{"Lease"}, listed(""))
+	assert.Equal(t, []string{"Invoice"}, listed("?archived=true"))
+	assert.Equal(t, []string{"Lease", "Invoice"}, listed("?archived=all"))
+
+	var archived ModificationHistory
+	require.NoError(t, db.First(&archived, first.ID).Error)
+	assert.True(t, archived.Archived)
+	assert.NotEmpty(t, archived.ArchivedDate)
+
+	w = post("/api/modifications/restore", `{"ids": [`+strconv.Itoa(int(first.ID))+`, `+strconv.Itoa(int(second.ID))+`]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"restored": 1}`, w.Body.String(), "only archived modifications are restored")
+	assert.Equal(t, []string{"Lease", "Invoice"}, listed(""))
+
+	assert.Equal(t, http.StatusBadRequest, post("/api/modifications/archive", `{"ids": []}`).Code)
+}
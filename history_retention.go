@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// historyPruneInterval is the interval in which the modification history is pruned to HISTORY_RETENTION_DAYS and HISTORY_MAX_ENTRIES,
+// and the log lines of documents to DOCUMENT_LOG_RETENTION_DAYS
+const historyPruneInterval = time.Hour
+
+// StartHistoryPruner prunes the modification history and the log lines of documents periodically. It keeps running
+// while retention is disabled, so retention settings changed at runtime take effect without a restart.
+func StartHistoryPruner(ctx context.Context, app *App) {
+	go func() {
+		ticker := time.NewTicker(historyPruneInterval)
+		defer ticker.Stop()
+		for {
+			settings := loadSettings()
+			if settings.HistoryRetentionDays > 0 || settings.HistoryMaxEntries > 0 {
+				if _, err := app.pruneHistory(settings.HistoryRetentionDays, settings.HistoryMaxEntries, time.Now()); err != nil {
+					log.Errorf("Error pruning the modification history: %v", err)
+				}
+			}
+			if _, err := app.pruneDocumentLogs(settings.DocumentLogRetentionDays, time.Now()); err != nil {
+				log.Errorf("Error pruning the log lines of documents: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Infoln("History pruner shutting down")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// pruneHistory deletes the modifications older than the given number of days and all but the newest maxEntries
+// modifications, 0 doesn't limit the history by age or count. It returns the number of deleted modifications.
+func (app *App) pruneHistory(retentionDays, maxEntries int, now time.Time) (int64, error) {
+	var olderThan time.Time
+	if retentionDays > 0 {
+		olderThan = now.AddDate(0, 0, -retentionDays)
+	}
+	deleted, err := PruneModifications(app.Database, olderThan, maxEntries)
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		log.Infof("Pruned %d modifications from the history", deleted)
+	}
+	return deleted, nil
+}
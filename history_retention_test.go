@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneModifications(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	now := time.Date(2024, 5, 31, 12, 0, 0, 0, time.Local)
+	daysAgo := func(days int) string { return now.AddDate(0, 0, -days).Format(time.RFC3339) }
+	require.NoError(t, db.Create(&[]ModificationHistory{
+		{DocumentID: 1, DateChanged: daysAgo(60), ModField: "title", NewValue: "oldest"},
+		{DocumentID: 1, DateChanged: daysAgo(31), ModField: "title", NewValue: "old"},
+		{DocumentID: 2, DateChanged: daysAgo(10), ModField: "title", NewValue: "recent"},
+		{DocumentID: 2, DateChanged: daysAgo(5), ModField: "title", NewValue: "newer"},
+		{DocumentID: 3, DateChanged: daysAgo(1), ModField: "title", NewValue: "newest"},
+	}).Error)
+
+	remaining := func() []string {
+		var values []string
+		require.NoError(t, db.Model(&ModificationHistory{}).Order("id").Pluck("new_value", &values).Error)
+		return values
+	}
+
+	deleted, err := PruneModifications(db, time.Time{}, 0)
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "no limit deletes nothing")
+
+	deleted, err = PruneModifications(db, now.AddDate(0, 0, -30), 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	assert.Equal(t, []string{"recent", "newer", "newest"}, remaining())
+
+	deleted, err = PruneModifications(db, time.Time{}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	assert.Equal(t, []string{"newer", "newest"}, remaining())
+
+	deleted, err = PruneModifications(db, time.Time{}, 5)
+	require.NoError(t, err)
+	assert.Zero(t, deleted, "fewer modifications than kept")
+}
+
+func TestPruneModificationHistoryHandler(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer db.Where("1 = 1").Delete(&ModificationHistory{})
+
+	setSettings(t, func(settings *runtimeSettings) { settings.HistoryRetentionDays, settings.HistoryMaxEntries = 0, 0 })
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: uint(i + 1), ModField: "title", NewValue: "title"}))
+	}
+
+	app := &App{Database: db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/api/modifications", app.pruneModificationHistoryHandler)
+
+	prune := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/api/modifications"+query, nil))
+		return w
+	}
+
+	assert.Equal(t, http.StatusBadRequest, prune("").Code, "no retention configured")
+	assert.Equal(t, http.StatusBadRequest, prune("?keep=-1").Code)
+	assert.Equal(t, http.StatusBadRequest, prune("?older_than_days=a").Code)
+
+	w := prune("?keep=2")
+	require.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Deleted int64 `json:"deleted"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Deleted)
+
+	setSettings(t, func(settings *runtimeSettings) { settings.HistoryMaxEntries = 1 })
+	w = prune("")
+	require.Equal(t, http.StatusOK, w.Code, "the configured retention is the default")
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, int64(1), response.Deleted)
+
+	var count int64
+	require.NoError(t, db.Model(&ModificationHistory{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"os"
 	"sort"
 	"sync"
@@ -9,23 +11,42 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
 )
 
-// Job represents an OCR job
+// Job kinds
+const (
+	JobKindOCR          = "ocr"           // OCR of the document, the result is the text
+	JobKindSuggestions  = "suggestions"   // Suggestions enabled by AUTO_GENERATE_*, the result is the JSON suggestion
+	JobKindSummary      = "summary"       // Summary of the document, the result is the summary
+	JobKindCustomFields = "custom_fields" // Custom field extraction, the result is the JSON list of custom field values
+	JobKindGenerate     = "generate"      // Suggestions of POST /api/generate-suggestions, the result is the JSON list of suggestions
+)
+
+// jobKinds lists the kinds of jobs that can be queued for a document, generate jobs are queued with their request
+var jobKinds = []string{JobKindOCR, JobKindSuggestions, JobKindSummary, JobKindCustomFields}
+
+// Job represents a queued job working on a document. Jobs are stored in the database, so queued jobs survive a restart.
 type Job struct {
-	ID         string
-	DocumentID int
-	Status     string // "pending", "in_progress", "completed", "failed"
-	Result     string // OCR result or error message
-	CreatedAt  time.Time
-	UpdatedAt  time.Time
-	PagesDone  int // Number of pages processed
+	ID         string     `gorm:"primaryKey;size:36"`
+	Kind       string     `gorm:"size:32;not null;default:ocr;index"`
+	DocumentID int        `gorm:"not null;index"`
+	Status     string     `gorm:"size:16;not null;index"`            // "pending", "in_progress", "completed", "failed"
+	Result     string     `gorm:"size:1048576;serializer:encrypted"` // Result or error message
+	Attempts   int        `gorm:"not null;default:0"`                // Number of times processing was started
+	CreatedAt  time.Time  `gorm:"not null"`
+	UpdatedAt  time.Time  `gorm:"not null"`
+	PagesDone  int        // Number of pages processed
+	JunkPages  []JunkPage `gorm:"serializer:json"`                    // Blank pages, calibration sheets and fax cover pages found during OCR
+	Request    string     `gorm:"size:16777216;serializer:encrypted"` // JSON encoded GenerateSuggestionsRequest of generate jobs
+	User       string     `gorm:"size:255;index"`                     // User who submitted the job, empty for background processing
 }
 
 // JobStore manages jobs and their statuses
 type JobStore struct {
 	sync.RWMutex
 	jobs map[string]*Job
+	db   *gorm.DB // Optional, jobs are only kept in memory without a database
 }
 
 var (
@@ -35,6 +56,8 @@ var (
 		jobs: make(map[string]*Job),
 	}
 	jobQueue = make(chan *Job, 100) // Buffered channel with capacity of 100 jobs
+
+	jobRetryDelay = 30 * time.Second // Time before a failed job is attempted again
 )
 
 func init() {
@@ -45,18 +68,66 @@ func init() {
 		FullTimestamp: true,
 	})
 	logger.SetLevel(logrus.InfoLevel)
-	logger.WithField("prefix", "OCR_JOB")
+	logger.WithField("prefix", "JOB")
 }
 
 func generateJobID() string {
 	return uuid.New().String()
 }
 
+// newJob creates a pending job of the given kind for a document
+func newJob(kind string, documentID int) *Job {
+	return &Job{
+		ID:         generateJobID(),
+		Kind:       kind,
+		DocumentID: documentID,
+		Status:     "pending",
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
+// persist stores the job in the database, the store must be locked
+func (store *JobStore) persist(job *Job) {
+	if store.db == nil {
+		return
+	}
+	if err := store.db.Save(job).Error; err != nil {
+		logger.Errorf("Failed to store job %s: %v", job.ID, err)
+	}
+}
+
+// restore loads the jobs stored in the database and returns the unfinished ones, which have to be queued again.
+// Jobs interrupted by the restart are pending again.
+func (store *JobStore) restore(db *gorm.DB) ([]*Job, error) {
+	var jobs []*Job
+	if err := db.Order("created_at").Find(&jobs).Error; err != nil {
+		return nil, err
+	}
+
+	store.Lock()
+	defer store.Unlock()
+	store.db = db
+
+	var unfinished []*Job
+	for _, job := range jobs {
+		store.jobs[job.ID] = job
+		if job.Status == "pending" || job.Status == "in_progress" {
+			job.Status = "pending"
+			store.persist(job)
+			unfinished = append(unfinished, job)
+		}
+	}
+	return unfinished, nil
+}
+
 func (store *JobStore) addJob(job *Job) {
 	store.Lock()
 	defer store.Unlock()
 	job.PagesDone = 0 // Initialize PagesDone to 0
 	store.jobs[job.ID] = job
+	store.persist(job)
+	events.publish(EventJobUpdated, jobResponse(job))
 	logger.Infof("Job added: %v", job)
 }
 
@@ -92,25 +163,132 @@ func (store *JobStore) updateJobStatus(jobID, status, result string) {
 			job.Result = result
 		}
 		job.UpdatedAt = time.Now()
+		store.persist(job)
+		events.publish(EventJobUpdated, jobResponse(job))
 		logger.Infof("Job status updated: %v", job)
 	}
 }
 
+// startJob marks the job as in progress and counts the attempt
+func (store *JobStore) startJob(jobID string) {
+	store.Lock()
+	defer store.Unlock()
+	if job, exists := store.jobs[jobID]; exists {
+		job.Status = "in_progress"
+		job.Attempts++
+		job.UpdatedAt = time.Now()
+		store.persist(job)
+		events.publish(EventJobUpdated, jobResponse(job))
+	}
+}
+
 func (store *JobStore) updatePagesDone(jobID string, pagesDone int) {
 	store.Lock()
 	defer store.Unlock()
 	if job, exists := store.jobs[jobID]; exists {
 		job.PagesDone = pagesDone
 		job.UpdatedAt = time.Now()
+		store.persist(job)
+		events.publish(EventJobUpdated, jobResponse(job))
 		logger.Infof("Job pages done updated: %v", job)
 	}
 }
 
+func (store *JobStore) updateJunkPages(jobID string, junkPages []JunkPage) {
+	store.Lock()
+	defer store.Unlock()
+	if job, exists := store.jobs[jobID]; exists {
+		job.JunkPages = junkPages
+		job.UpdatedAt = time.Now()
+		store.persist(job)
+	}
+}
+
+// newGenerateJob creates a pending job generating the suggestions of the request. Jobs for a single document refer to
+// it, jobs for several documents have no document ID.
+func newGenerateJob(request GenerateSuggestionsRequest) (*Job, error) {
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	documentID := 0
+	if len(request.Documents) == 1 {
+		documentID = request.Documents[0].ID
+	}
+	job := newJob(JobKindGenerate, documentID)
+	job.Request = string(requestJSON)
+	return job, nil
+}
+
+// newDocumentGenerateJob creates a pending job generating the suggestions of the options for a document of a batch
+func newDocumentGenerateJob(options GenerateSuggestionsRequest, documentID int) (*Job, error) {
+	options.Documents = nil
+	job, err := newGenerateJob(options)
+	if err != nil {
+		return nil, err
+	}
+	job.DocumentID = documentID
+	return job, nil
+}
+
+// countUnfinishedJobs returns the number of pending and running jobs of the kind
+func (store *JobStore) countUnfinishedJobs(kind string) int {
+	store.RLock()
+	defer store.RUnlock()
+	count := 0
+	for _, job := range store.jobs {
+		if job.Kind == kind && (job.Status == "pending" || job.Status == "in_progress") {
+			count++
+		}
+	}
+	return count
+}
+
+// enqueueJob adds a new job to the store and the queue
+func enqueueJob(job *Job) {
+	jobStore.addJob(job)
+	jobQueue <- job
+}
+
+// enqueueJobs adds the jobs to the store right away and queues them while the workers run, as there may be more jobs
+// than fit into the queue
+func enqueueJobs(jobs []*Job) {
+	for _, job := range jobs {
+		jobStore.addJob(job)
+	}
+	go func() {
+		for _, job := range jobs {
+			jobQueue <- job
+		}
+	}()
+}
+
+// restoreJobs loads the stored jobs and queues the unfinished ones again
+func restoreJobs(db *gorm.DB) {
+	unfinished, err := jobStore.restore(db)
+	if err != nil {
+		logger.Errorf("Failed to restore jobs: %v", err)
+		return
+	}
+	if len(unfinished) == 0 {
+		return
+	}
+	logger.Infof("Queueing %d unfinished jobs again", len(unfinished))
+	// The queue may be smaller than the number of unfinished jobs, so they are queued while the workers run
+	go func() {
+		for _, job := range unfinished {
+			jobQueue <- job
+		}
+	}()
+}
+
 func startWorkerPool(app *App, numWorkers int) {
 	for i := 0; i < numWorkers; i++ {
 		go func(workerID int) {
 			logger.Infof("Worker %d started", workerID)
 			for job := range jobQueue {
+				// Jobs wait while processing is paused
+				backgroundPause.wait(context.Background())
 				logger.Infof("Worker %d processing job: %s", workerID, job.ID)
 				processJob(app, job)
 			}
@@ -119,17 +297,116 @@ func startWorkerPool(app *App, numWorkers int) {
 }
 
 func processJob(app *App, job *Job) {
-	jobStore.updateJobStatus(job.ID, "in_progress", "")
+	jobStore.startJob(job.ID)
 
-	ctx := context.Background()
+	// The LLM requests and modifications of the job are attributed to the user who submitted it. The job runs with the
+	// settings in effect when it starts.
+	ctx := withSettings(withRequestUser(context.Background(), job.User))
 
-	fullOcrText, err := app.ProcessDocumentOCR(ctx, job.DocumentID)
+	var result string
+	var err error
+	switch job.Kind {
+	case JobKindSuggestions, JobKindSummary, JobKindCustomFields:
+		result, err = app.runSuggestionJob(ctx, job)
+	case JobKindGenerate:
+		result, err = app.runGenerateJob(ctx, job)
+	default:
+		result, err = app.runOCRJob(ctx, job)
+	}
+	if app.skipDeletedDocument(job.DocumentID, err) {
+		jobStore.updateJobStatus(job.ID, "failed", "Document was deleted in paperless-ngx")
+		return
+	}
 	if err != nil {
-		logger.Errorf("Error processing document OCR for job %s: %v", job.ID, err)
+		logger.Errorf("Error processing %s job %s: %v", job.Kind, job.ID, err)
+		jobMaxAttempts := settingsFor(ctx).JobMaxAttempts
+		if job.Attempts < jobMaxAttempts {
+			jobStore.updateJobStatus(job.ID, "pending", err.Error())
+			logger.Infof("Retrying job %s in %v (attempt %d of %d)", job.ID, jobRetryDelay, job.Attempts+1, jobMaxAttempts)
+			time.AfterFunc(jobRetryDelay, func() { jobQueue <- job })
+			return
+		}
 		jobStore.updateJobStatus(job.ID, "failed", err.Error())
 		return
 	}
 
-	jobStore.updateJobStatus(job.ID, "completed", fullOcrText)
+	jobStore.updateJobStatus(job.ID, "completed", result)
 	logger.Infof("Job completed: %s", job.ID)
 }
+
+// runOCRJob runs the OCR of the job's document and returns the text
+func (app *App) runOCRJob(ctx context.Context, job *Job) (string, error) {
+	ocrResult, err := app.ProcessDocumentOCR(ctx, job.DocumentID)
+	if err != nil {
+		return "", err
+	}
+	jobStore.updateJunkPages(job.ID, ocrResult.JunkPages)
+	return ocrResult.Text, nil
+}
+
+// runSuggestionJob generates the suggestions requested by the job's kind for its document
+func (app *App) runSuggestionJob(ctx context.Context, job *Job) (string, error) {
+	document, err := app.Client.GetDocument(ctx, job.DocumentID)
+	if err != nil {
+		return "", err
+	}
+
+	suggestionRequest := GenerateSuggestionsRequest{Documents: []Document{document}}
+	switch job.Kind {
+	case JobKindSuggestions:
+		suggestionRequest = settingsFor(ctx).autoSuggestionRequest(document)
+	case JobKindSummary:
+		suggestionRequest.GenerateSummary = true
+	case JobKindCustomFields:
+		suggestionRequest.GenerateCustomFields = true
+	}
+
+	suggestions, err := app.generateDocumentSuggestions(ctx, suggestionRequest, documentLogger(job.DocumentID))
+	if err != nil {
+		return "", err
+	}
+	if len(suggestions) == 0 {
+		return "", fmt.Errorf("no suggestions generated for document %d", job.DocumentID)
+	}
+	suggestion := suggestions[0]
+
+	var result any = suggestion
+	switch job.Kind {
+	case JobKindSummary:
+		return suggestion.SuggestedSummary, nil
+	case JobKindCustomFields:
+		result = suggestion.SuggestedCustomFields
+	}
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(resultJSON), nil
+}
+
+// runGenerateJob generates the suggestions of the job's request and returns them as JSON
+func (app *App) runGenerateJob(ctx context.Context, job *Job) (string, error) {
+	var request GenerateSuggestionsRequest
+	if err := json.Unmarshal([]byte(job.Request), &request); err != nil {
+		return "", fmt.Errorf("invalid request stored for job %s: %w", job.ID, err)
+	}
+
+	// Jobs of batches only store the options, the document is fetched when the job runs to work on its current content
+	if len(request.Documents) == 0 && job.DocumentID > 0 {
+		document, err := app.Client.GetDocument(ctx, job.DocumentID)
+		if err != nil {
+			return "", err
+		}
+		request.Documents = []Document{document}
+	}
+
+	suggestions, err := app.generateDocumentSuggestions(ctx, request, logger.WithField("job_id", job.ID))
+	if err != nil {
+		return "", err
+	}
+	resultJSON, err := json.Marshal(suggestions)
+	if err != nil {
+		return "", err
+	}
+	return string(resultJSON), nil
+}
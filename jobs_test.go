@@ -0,0 +1,277 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobStoreRestore(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&Job{}).Error)
+
+	store := &JobStore{jobs: make(map[string]*Job), db: db}
+	pending := newJob(JobKindSummary, 1)
+	interrupted := newJob(JobKindOCR, 2)
+	completed := newJob(JobKindCustomFields, 3)
+	for _, job := range []*Job{pending, interrupted, completed} {
+		store.addJob(job)
+	}
+	store.startJob(interrupted.ID)
+	store.updateJunkPages(completed.ID, []JunkPage{{Page: 2, Kind: "blank"}})
+	store.updateJobStatus(completed.ID, "completed", `[{"field":1,"value":"x"}]`)
+
+	// After a restart, unfinished jobs are pending again
+	restored := &JobStore{jobs: make(map[string]*Job)}
+	unfinished, err := restored.restore(db)
+	require.NoError(t, err)
+	require.Len(t, unfinished, 2)
+	assert.Equal(t, pending.ID, unfinished[0].ID)
+	assert.Equal(t, interrupted.ID, unfinished[1].ID)
+	assert.Equal(t, "pending", unfinished[1].Status)
+	assert.Equal(t, 1, unfinished[1].Attempts)
+
+	job, exists := restored.getJob(completed.ID)
+	require.True(t, exists)
+	assert.Equal(t, JobKindCustomFields, job.Kind)
+	assert.Equal(t, "completed", job.Status)
+	assert.Equal(t, []JunkPage{{Page: 2, Kind: "blank"}}, job.JunkPages)
+}
+
+func TestProcessJob_Summary(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalTemplate := summaryTemplate
+	defer func() { summaryTemplate = originalTemplate }()
+	summaryTemplate = template.Must(template.New("summary").Parse("{{.Content}}"))
+
+	env.setMockResponse("/api/documents/7/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 7, "title": "Invoice", "content": "Invoice content", "tags": []}`))
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db, LLM: &mockJSONLLM{response: "A short summary."}}
+	job := newJob(JobKindSummary, 7)
+	jobStore.addJob(job)
+
+	processJob(app, job)
+
+	job, exists := jobStore.getJob(job.ID)
+	require.True(t, exists)
+	assert.Equal(t, "completed", job.Status)
+	assert.Equal(t, "A short summary.", job.Result)
+	assert.Equal(t, 1, job.Attempts)
+}
+
+func TestProcessJob_Retries(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.retry = retryPolicy{}
+
+	originalDelay := jobRetryDelay
+	defer func() { jobRetryDelay = originalDelay }()
+	jobRetryDelay = time.Hour
+	setSettings(t, func(settings *runtimeSettings) { settings.JobMaxAttempts = 2 })
+
+	env.setMockResponse("/api/documents/8/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	job := newJob(JobKindCustomFields, 8)
+	jobStore.addJob(job)
+
+	processJob(app, job)
+	assert.Equal(t, "pending", job.Status)
+	assert.Contains(t, job.Result, "500")
+
+	processJob(app, job)
+	assert.Equal(t, "failed", job.Status)
+	assert.Equal(t, 2, job.Attempts)
+}
+
+func TestSubmitJobHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	app := &App{}
+	router := gin.New()
+	router.POST("/api/jobs", app.submitJobHandler)
+	router.GET("/api/jobs", app.getAllJobsHandler)
+	router.GET("/api/jobs/ocr", app.getOCRJobsHandler)
+	router.GET("/api/jobs/:job_id", app.getJobStatusHandler)
+
+	for _, body := range []string{`{"kind": "translation", "document_id": 1}`, `{"kind": "summary"}`, `{"kind": "ocr", "document_id": 1}`} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/jobs", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/jobs", strings.NewReader(`{"kind": "summary", "document_id": 12}`)))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	queued := <-jobQueue
+	assert.Equal(t, JobKindSummary, queued.Kind)
+	assert.Equal(t, 12, queued.DocumentID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/jobs/"+queued.ID, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"kind":"summary"`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/jobs?kind=summary&status=pending", nil))
+	assert.Contains(t, w.Body.String(), queued.ID)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/jobs/ocr", nil))
+	assert.NotContains(t, w.Body.String(), queued.ID)
+}
+
+func TestGenerateSuggestionsJob(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	originalLimit := suggestionsConcurrencyLimit
+	defer func() { suggestionsConcurrencyLimit = originalLimit }()
+	suggestionsConcurrencyLimit = 1
+
+	gin.SetMode(gin.TestMode)
+	app := &App{Client: env.client, Database: env.db, LLM: &mockJSONLLM{response: "{}"}}
+	router := gin.New()
+	router.POST("/api/generate-suggestions", app.generateSuggestionsHandler)
+	body := `{"documents": [{"id": 21, "title": "Scan", "content": "Invoice"}, {"id": 22, "title": "Letter", "content": "Dear"}]}`
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions", strings.NewReader(body)))
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	job := <-jobQueue
+	defer func() {
+		jobStore.Lock()
+		delete(jobStore.jobs, job.ID)
+		jobStore.Unlock()
+	}()
+	assert.Contains(t, w.Body.String(), job.ID)
+	assert.Equal(t, JobKindGenerate, job.Kind)
+	assert.Equal(t, 0, job.DocumentID, "jobs for several documents have no document ID")
+
+	// Unfinished jobs count towards the cap
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions", strings.NewReader(body)))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+
+	processJob(app, job)
+	require.Equal(t, "completed", job.Status, job.Result)
+	var suggestions []DocumentSuggestion
+	require.NoError(t, json.Unmarshal([]byte(job.Result), &suggestions))
+	assert.ElementsMatch(t, []int{21, 22}, []int{suggestions[0].ID, suggestions[1].ID})
+
+	// The suggestions can still be generated within the request
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions?wait=true", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &suggestions))
+	assert.Len(t, suggestions, 2)
+}
+
+func TestGenerateSuggestionsBatch(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 4, "name": "Inbox"}], "next": null}`))
+	})
+	var documentsQuery url.Values
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		documentsQuery = r.URL.Query()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count": 2, "all": [31, 32], "results": [{"id": 31}]}`))
+	})
+
+	originalTemplate := titleTemplate
+	defer func() { titleTemplate = originalTemplate }()
+	titleTemplate = template.Must(template.New("title").Parse("{{.Content}}"))
+
+	gin.SetMode(gin.TestMode)
+	app := &App{Client: env.client, Database: env.db, LLM: &mockJSONLLM{response: "{}"}}
+	router := gin.New()
+	router.POST("/api/generate-suggestions/batch", app.generateSuggestionsBatchHandler)
+	post := func(body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/generate-suggestions/batch", strings.NewReader(body)))
+		return w
+	}
+
+	w := post(`{"tag": "Inbox", "created_after": "2024-01-01", "generate_titles": true}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	jobs := []*Job{<-jobQueue, <-jobQueue}
+	defer func() {
+		jobStore.Lock()
+		for _, job := range jobs {
+			delete(jobStore.jobs, job.ID)
+		}
+		jobStore.Unlock()
+	}()
+	assert.Equal(t, "4", documentsQuery.Get("tags__id__all"))
+	assert.Equal(t, "2024-01-01", documentsQuery.Get("created__date__gte"))
+	assert.Equal(t, []int{31, 32}, []int{jobs[0].DocumentID, jobs[1].DocumentID})
+	assert.Equal(t, JobKindGenerate, jobs[0].Kind)
+	assert.Contains(t, jobs[0].Request, `"generate_titles":true`)
+
+	// The document is fetched when the job runs
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/documents/31/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 31, "title": "Scan", "content": "Invoice", "tags": [4]}`))
+	})
+	processJob(app, jobs[0])
+	require.Equal(t, "completed", jobs[0].Status, jobs[0].Result)
+	assert.Contains(t, jobs[0].Result, `"id":31`)
+
+	// Documents with unfinished jobs aren't queued twice
+	w = post(`{"tag": "Inbox"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"count":1`)
+	jobs = append(jobs, <-jobQueue)
+	assert.Equal(t, 31, jobs[2].DocumentID)
+
+	// Unknown tags match no documents
+	documentsQuery = nil
+	w = post(`{"tag": "Missing"}`)
+	require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	assert.Nil(t, documentsQuery)
+
+	assert.Equal(t, http.StatusBadRequest, post(`{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"created_before": "yesterday"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"tag": "Inbox", "documents": [{"id": 1}]}`).Code)
+}
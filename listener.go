@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// unixSocketPrefix marks a LISTEN_INTERFACE as path of a Unix domain socket, e.g. unix:/run/paperless-gpt.sock
+const unixSocketPrefix = "unix:"
+
+// parseSocketMode parses the octal file mode of the Unix domain socket, e.g. 0660
+func parseSocketMode(text string) (fs.FileMode, error) {
+	mode, err := strconv.ParseUint(text, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("%q is not an octal file mode like 0660", text)
+	}
+	return fs.FileMode(mode), nil
+}
+
+// listen opens the listener of the HTTP server: a Unix domain socket with the given mode for addresses starting
+// with "unix:", a TCP address otherwise
+func listen(address string, socketMode fs.FileMode) (net.Listener, error) {
+	path, isSocket := strings.CutPrefix(address, unixSocketPrefix)
+	if !isSocket {
+		return net.Listen("tcp", address)
+	}
+
+	// A socket left over by an earlier run that didn't shut down cleanly blocks listening, other files are kept
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != fs.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("error removing stale socket %s: %w", path, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, socketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("error setting the permissions of %s: %w", path, err)
+	}
+	return listener, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSocketMode(t *testing.T) {
+	mode, err := parseSocketMode("0660")
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0660), mode)
+
+	for _, invalid := range []string{"rw-rw----", "0999", "1777", ""} {
+		_, err := parseSocketMode(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestListen_UnixSocket(t *testing.T) {
+	// Socket paths are limited to about 100 characters, which the test temp directory may exceed
+	dir, err := os.MkdirTemp("", "pgpt")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "paperless-gpt.sock")
+
+	// A stale socket of an earlier run is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := listen("unix:"+path, 0600)
+	require.NoError(t, err)
+	defer listener.Close()
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0600), info.Mode().Perm())
+
+	go http.Serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	client := &http.Client{Transport: &http.Transport{
+		Dial: func(network, address string) (net.Conn, error) { return net.Dial("unix", path) },
+	}}
+	resp, err := client.Get("http://paperless-gpt/api/tags")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusTeapot, resp.StatusCode)
+
+	// Other files are never removed
+	other := filepath.Join(dir, "data.db")
+	require.NoError(t, os.WriteFile(other, []byte("data"), 0644))
+	_, err = listen("unix:"+other, 0600)
+	assert.Error(t, err)
+	assert.FileExists(t, other)
+}
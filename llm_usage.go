@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"paperless-gpt/ocr"
+
+	"github.com/sirupsen/logrus"
+	"github.com/tmc/langchaingo/llms"
+	"gorm.io/gorm"
+)
+
+// llmTaskKey is the context key of the task an LLM request is made for
+type llmTaskKey struct{}
+
+// withLLMTask marks the LLM requests made with the context as requests for the task, e.g. the name of their prompt
+func withLLMTask(ctx context.Context, task string) context.Context {
+	return context.WithValue(ctx, llmTaskKey{}, task)
+}
+
+// llmTask returns the task of an LLM request, requests without a task are counted as other
+func llmTask(ctx context.Context) string {
+	if task, ok := ctx.Value(llmTaskKey{}).(string); ok && task != "" {
+		return task
+	}
+	return "other"
+}
+
+// llmDocumentKey is the context key of the document LLM and OCR requests are made for
+type llmDocumentKey struct{}
+
+// withLLMDocument records the LLM and OCR requests made with the context as requests for the document
+func withLLMDocument(ctx context.Context, documentID int) context.Context {
+	return context.WithValue(ctx, llmDocumentKey{}, documentID)
+}
+
+// llmDocument returns the document of an LLM or OCR request, 0 for requests made for no document
+func llmDocument(ctx context.Context) uint {
+	documentID, _ := ctx.Value(llmDocumentKey{}).(int)
+	if documentID < 0 {
+		return 0
+	}
+	return uint(documentID)
+}
+
+// promptHash returns the SHA-256 hash of a prompt, to tell which prompt a request was made with without storing it
+func promptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// usageRecordingModel records the tokens, cost and latency of every request to the LLM
+type usageRecordingModel struct {
+	llms.Model
+	db       *gorm.DB
+	provider string
+	model    string
+}
+
+// recordLLMUsage records the requests to the model of the provider in the database, it returns the model as is
+// without a database
+func recordLLMUsage(model llms.Model, db *gorm.DB, provider, modelName string) llms.Model {
+	if model == nil || db == nil {
+		return model
+	}
+	return &usageRecordingModel{Model: model, db: db, provider: provider, model: modelName}
+}
+
+func (model *usageRecordingModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	start := time.Now()
+	response, err := model.Model.GenerateContent(ctx, messages, options...)
+
+	prompt := messagesText(messages)
+	usage := LLMUsage{
+		Task:       llmTask(ctx),
+		Model:      model.model,
+		Provider:   model.provider,
+		PromptHash: promptHash(prompt),
+		DocumentID: llmDocument(ctx),
+		DurationMs: time.Since(start).Milliseconds(),
+		Failed:     err != nil,
+		User:       requestUser(ctx),
+		CreatedAt:  start.Format(time.RFC3339),
+	}
+	if experiment, variant := experimentVariant(ctx, usage.Task); experiment != nil {
+		usage.Experiment, usage.Variant = experiment.Name, variant
+	}
+	if err == nil && response != nil && len(response.Choices) > 0 {
+		choice := response.Choices[0]
+		usage.PromptTokens, _ = choice.GenerationInfo["PromptTokens"].(int)
+		usage.CompletionTokens, _ = choice.GenerationInfo["CompletionTokens"].(int)
+		// Not all providers report the tokens, they are estimated from the text instead
+		if usage.PromptTokens == 0 {
+			usage.PromptTokens, _ = getTokenCount(ctx, prompt)
+		}
+		if usage.CompletionTokens == 0 {
+			usage.CompletionTokens, _ = getTokenCount(ctx, choice.Content)
+		}
+		usage.Cost = (float64(usage.PromptTokens)*llmPromptTokenCost + float64(usage.CompletionTokens)*llmCompletionTokenCost) / 1e6
+	}
+	logUsage(usage, err)
+	if dbErr := model.db.Create(&usage).Error; dbErr != nil {
+		log.Errorf("Failed to record LLM usage: %v", dbErr)
+	}
+	return response, err
+}
+
+func (model *usageRecordingModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, model, prompt, options...)
+}
+
+// usageRecordingOCRProvider records the latency of every request to the OCR provider, and its tokens if the provider
+// reports them
+type usageRecordingOCRProvider struct {
+	ocr.Provider
+	db         *gorm.DB
+	provider   string
+	promptHash string
+}
+
+// recordOCRUsage records the requests to the OCR provider in the database as task ocr. The prompt is the one of LLM
+// based OCR, empty for other providers. It returns the provider as is without a database.
+func recordOCRUsage(provider ocr.Provider, db *gorm.DB, providerName string, prompt string) ocr.Provider {
+	if provider == nil || db == nil {
+		return provider
+	}
+	recording := &usageRecordingOCRProvider{Provider: provider, db: db, provider: providerName}
+	if prompt != "" {
+		recording.promptHash = promptHash(prompt)
+	}
+	return recording
+}
+
+func (provider *usageRecordingOCRProvider) ProcessImage(ctx context.Context, imageContent []byte) (*ocr.OCRResult, error) {
+	start := time.Now()
+	result, err := provider.Provider.ProcessImage(ctx, imageContent)
+
+	usage := LLMUsage{
+		Task:       "ocr",
+		Model:      provider.provider,
+		Provider:   provider.provider,
+		PromptHash: provider.promptHash,
+		DocumentID: llmDocument(ctx),
+		DurationMs: time.Since(start).Milliseconds(),
+		Failed:     err != nil,
+		User:       requestUser(ctx),
+		CreatedAt:  start.Format(time.RFC3339),
+	}
+	if err == nil && result != nil {
+		if model := result.Metadata["model"]; model != "" {
+			usage.Model = model
+		}
+		usage.PromptTokens, _ = strconv.Atoi(result.Metadata["prompt_tokens"])
+		usage.CompletionTokens, _ = strconv.Atoi(result.Metadata["completion_tokens"])
+	}
+	logUsage(usage, err)
+	if dbErr := provider.db.Create(&usage).Error; dbErr != nil {
+		log.Errorf("Failed to record OCR usage: %v", dbErr)
+	}
+	return result, err
+}
+
+// logUsage logs a request to the LLM or the OCR provider with its task, provider, duration and tokens as fields, so
+// they can be queried in structured logs
+func logUsage(usage LLMUsage, err error) {
+	entry := log.WithFields(logrus.Fields{
+		"task":              usage.Task,
+		"provider":          usage.Provider,
+		"model":             usage.Model,
+		"duration_ms":       usage.DurationMs,
+		"prompt_tokens":     usage.PromptTokens,
+		"completion_tokens": usage.CompletionTokens,
+	})
+	if usage.DocumentID > 0 {
+		entry = entry.WithField("document_id", usage.DocumentID)
+	}
+	if err != nil {
+		entry.WithError(err).Warnf("%s request failed", usage.Task)
+		return
+	}
+	entry.Infof("%s request completed", usage.Task)
+}
+
+// GenerationUsage sums up the LLM and OCR requests that generated the modifications of a batch
+type GenerationUsage struct {
+	Models     []string `json:"models"`
+	Requests   int      `json:"requests"`
+	Tokens     int      `json:"tokens"`
+	DurationMs int64    `json:"duration_ms"`
+}
+
+// linkLLMUsage links the LLM and OCR requests made for a document since its last update to the modifications of the
+// update
+func linkLLMUsage(db *gorm.DB, documentID uint, batchID string) error {
+	return db.Model(&LLMUsage{}).
+		Where("document_id = ? AND (batch_id = '' OR batch_id IS NULL)", documentID).
+		Update("batch_id", batchID).Error
+}
+
+// batchUsage returns the usage of the LLM and OCR requests that generated the modifications of the batches, by batch
+func batchUsage(db *gorm.DB, batchIDs []string) (map[string]GenerationUsage, error) {
+	usage := make(map[string]GenerationUsage)
+	if len(batchIDs) == 0 {
+		return usage, nil
+	}
+
+	var records []LLMUsage
+	if err := db.Where("batch_id IN ?", batchIDs).Order("id").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		batch := usage[record.BatchID]
+		if !slices.Contains(batch.Models, record.Model) {
+			batch.Models = append(batch.Models, record.Model)
+		}
+		batch.Requests++
+		batch.Tokens += record.PromptTokens + record.CompletionTokens
+		batch.DurationMs += record.DurationMs
+		usage[record.BatchID] = batch
+	}
+	return usage, nil
+}
+
+// messagesText joins the text parts of the messages
+func messagesText(messages []llms.MessageContent) string {
+	var text strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if textPart, ok := part.(llms.TextContent); ok {
+				text.WriteString(textPart.Text)
+			}
+		}
+	}
+	return text.String()
+}
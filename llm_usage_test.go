@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestLLMUsageOfModifications(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&LLMUsage{}).Error)
+	defer db.Where("1 = 1").Delete(&LLMUsage{})
+
+	ctx := withLLMDocument(context.Background(), 7)
+	model := recordLLMUsage(&usageReportingLLM{}, db, "openai", "gpt-4o-mini")
+	_, err = model.GenerateContent(withLLMTask(ctx, "title"), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Title?")})
+	require.NoError(t, err)
+	provider := recordOCRUsage(&sequenceOCRProvider{texts: []string{"Invoice"}}, db, "azure", "")
+	_, err = provider.ProcessImage(ctx, nil)
+	require.NoError(t, err)
+	_, err = model.GenerateContent(withLLMTask(context.Background(), "tag"), nil)
+	require.NoError(t, err)
+
+	var usages []LLMUsage
+	require.NoError(t, db.Order("id").Find(&usages).Error)
+	require.Len(t, usages, 3)
+	assert.Equal(t, "gpt-4o-mini", usages[0].Model)
+	assert.Equal(t, "openai", usages[0].Provider)
+	assert.Equal(t, promptHash("Title?"), usages[0].PromptHash)
+	assert.Equal(t, uint(7), usages[0].DocumentID)
+	assert.Equal(t, "ocr", usages[1].Task)
+	assert.Equal(t, "azure", usages[1].Model)
+	assert.Equal(t, uint(7), usages[1].DocumentID)
+	assert.Zero(t, usages[2].DocumentID, "requests for no document")
+
+	require.NoError(t, linkLLMUsage(db, 7, "batch-1"))
+	require.NoError(t, linkLLMUsage(db, 7, "batch-2"), "requests are linked to the first update only")
+
+	usage, err := batchUsage(db, []string{"batch-1", "batch-2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]GenerationUsage{
+		"batch-1": {
+			Models:     []string{"gpt-4o-mini", "azure"},
+			Requests:   2,
+			Tokens:     1010,
+			DurationMs: usages[0].DurationMs + usages[1].DurationMs,
+		},
+	}, usage)
+}
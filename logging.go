@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// logFormatter returns the formatter of LOG_FORMAT: text, the default, or json for log collectors like Loki or ELK
+func logFormatter(format string) (logrus.Formatter, error) {
+	switch format {
+	case "", "text":
+		return &logrus.TextFormatter{FullTimestamp: true}, nil
+	case "json":
+		return &logrus.JSONFormatter{}, nil
+	default:
+		return nil, fmt.Errorf("'%s' is not one of text, json", format)
+	}
+}
+
+// rotatingLogFile returns a writer to the log file that starts a new file when it exceeds maxSizeMB. Old files are
+// deleted after maxAgeDays and beyond maxBackups, 0 keeps them.
+func rotatingLogFile(path string, maxSizeMB, maxAgeDays, maxBackups int) io.Writer {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxAge:     maxAgeDays,
+		MaxBackups: maxBackups,
+		LocalTime:  true,
+	}
+}
+
+// documentLogLevels returns the levels of the lines captured for documents with DOCUMENT_LOG_LEVEL: off, error, warn,
+// info, the default, or debug for the prompts too
+func documentLogLevels(level string) ([]logrus.Level, error) {
+	switch level {
+	case "off":
+		return nil, nil
+	case "":
+		return logrus.AllLevels[:logrus.InfoLevel+1], nil
+	case "error", "warn", "info", "debug":
+		parsed, err := logrus.ParseLevel(level)
+		if err != nil {
+			return nil, err
+		}
+		return logrus.AllLevels[:parsed+1], nil
+	default:
+		return nil, fmt.Errorf("'%s' is not one of off, error, warn, info, debug", level)
+	}
+}
+
+// levelWriterHook writes the lines up to a level to the output. It replaces the output of a logger whose level is
+// more verbose than the output for other hooks, like capturing the debug lines of documents.
+type levelWriterHook struct {
+	mu     sync.Mutex
+	output io.Writer
+	levels []logrus.Level
+}
+
+// newLevelWriterHook returns a hook writing the lines up to the level to the output
+func newLevelWriterHook(output io.Writer, level logrus.Level) *levelWriterHook {
+	return &levelWriterHook{output: output, levels: logrus.AllLevels[:level+1]}
+}
+
+// Levels returns the written levels
+func (hook *levelWriterHook) Levels() []logrus.Level {
+	return hook.levels
+}
+
+// Fire writes the formatted line to the output
+func (hook *levelWriterHook) Fire(entry *logrus.Entry) error {
+	line, err := entry.Bytes()
+	if err != nil {
+		return err
+	}
+	hook.mu.Lock()
+	defer hook.mu.Unlock()
+	_, err = hook.output.Write(line)
+	return err
+}
+
+// configureGin sets the mode of Gin from GIN_MODE, keeping the default debug mode if it is empty. The debug output of
+// Gin, like the registered routes, is logged with logrus in the configured format.
+func configureGin(mode string) error {
+	switch mode {
+	case "":
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+		gin.SetMode(mode)
+	default:
+		return fmt.Errorf("'%s' is not one of %s, %s, %s", mode, gin.DebugMode, gin.ReleaseMode, gin.TestMode)
+	}
+	gin.DebugPrintFunc = func(format string, values ...interface{}) {
+		log.WithField("component", "gin").Debugf(strings.TrimSuffix(format, "\n"), values...)
+	}
+	gin.DebugPrintRouteFunc = func(httpMethod, absolutePath, handlerName string, handlers int) {
+		log.WithField("component", "gin").Debugf("%-6s %s --> %s (%d handlers)", httpMethod, absolutePath, handlerName, handlers)
+	}
+	return nil
+}
+
+// accessLogger logs every request served by Gin with its method, path, status and duration. Server errors are logged
+// as errors and client errors as warnings.
+func accessLogger() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		entry := log.WithFields(logrus.Fields{
+			"method":      c.Request.Method,
+			"path":        c.Request.URL.Path,
+			"status":      c.Writer.Status(),
+			"duration_ms": time.Since(start).Milliseconds(),
+			"client_ip":   c.ClientIP(),
+		})
+		if user := requestUser(c.Request.Context()); user != "" {
+			entry = entry.WithField("user", user)
+		}
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("error", c.Errors.String())
+		}
+
+		switch status := c.Writer.Status(); {
+		case status >= 500:
+			entry.Error("HTTP request")
+		case status >= 400:
+			entry.Warn("HTTP request")
+		default:
+			entry.Info("HTTP request")
+		}
+	}
+}
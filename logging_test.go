@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogFormatter(t *testing.T) {
+	_, err := logFormatter("logfmt")
+	assert.ErrorContains(t, err, "'logfmt' is not one of text, json")
+	assert.Error(t, configureGin("production"))
+}
+
+func TestAccessLoggerJSON(t *testing.T) {
+	formatter, err := logFormatter("json")
+	require.NoError(t, err)
+	originalFormatter, originalOut := log.Formatter, log.Out
+	defer func() { log.SetFormatter(originalFormatter); log.SetOutput(originalOut) }()
+	var logs bytes.Buffer
+	log.SetFormatter(formatter)
+	log.SetOutput(&logs)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(accessLogger())
+	router.GET("/api/documents/:id", func(c *gin.Context) {
+		c.Request = c.Request.WithContext(withRequestUser(c.Request.Context(), "alice"))
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/documents/544", nil))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry), logs.String())
+	assert.Equal(t, "HTTP request", entry["msg"])
+	assert.Equal(t, "warning", entry["level"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/api/documents/544", entry["path"])
+	assert.Equal(t, float64(http.StatusNotFound), entry["status"])
+	assert.Equal(t, "alice", entry["user"])
+	assert.Contains(t, entry, "duration_ms")
+
+	logs.Reset()
+	logUsage(LLMUsage{Task: "title", Provider: "openai", Model: "gpt-4o", DocumentID: 544, DurationMs: 812, PromptTokens: 900}, nil)
+	require.NoError(t, json.Unmarshal(logs.Bytes(), &entry), logs.String())
+	assert.Equal(t, "title request completed", entry["msg"])
+	assert.Equal(t, "openai", entry["provider"])
+	assert.Equal(t, float64(544), entry["document_id"])
+	assert.Equal(t, float64(812), entry["duration_ms"])
+}
+
+func TestRotatingLogFile(t *testing.T) {
+	dir := t.TempDir()
+	writer := rotatingLogFile(filepath.Join(dir, "paperless-gpt.log"), 1, 0, 1)
+	defer writer.(io.Closer).Close()
+
+	// Each write takes more than half of the maximum size, so every write after the first one starts a new file
+	line := []byte(strings.Repeat("x", 600*1024) + "\n")
+	for range 3 {
+		_, err := writer.Write(line)
+		require.NoError(t, err)
+	}
+
+	// Old backups are removed in the background
+	assert.Eventually(t, func() bool {
+		files, err := os.ReadDir(dir)
+		return err == nil && len(files) == 2
+	}, 5*time.Second, 10*time.Millisecond, "the current file and one backup are kept")
+}
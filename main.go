@@ -3,12 +3,19 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
 	"net/http"
+	"net/mail"
 	"os"
 	"paperless-gpt/ocr"
 	"path/filepath"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -33,53 +40,230 @@ var (
 
 	// Environment Variables
 	paperlessInsecureSkipVerify   = os.Getenv("PAPERLESS_INSECURE_SKIP_VERIFY") == "true"
+	paperlessCACert               = os.Getenv("PAPERLESS_CA_CERT")
+	paperlessClientCert           = os.Getenv("PAPERLESS_CLIENT_CERT")
+	paperlessClientKey            = os.Getenv("PAPERLESS_CLIENT_KEY")
 	correspondentBlackList        = strings.Split(os.Getenv("CORRESPONDENT_BLACK_LIST"), ",")
 	paperlessBaseURL              = os.Getenv("PAPERLESS_BASE_URL")
-	paperlessAPIToken             = os.Getenv("PAPERLESS_API_TOKEN")
+	paperlessAPIToken             = secretEnv("PAPERLESS_API_TOKEN")
+	paperlessAuthHeader           = os.Getenv("PAPERLESS_AUTH_HEADER")
+	paperlessAuthHeaderValue      = os.Getenv("PAPERLESS_AUTH_HEADER_VALUE")
+	paperlessSessionCookie        = os.Getenv("PAPERLESS_SESSION_COOKIE")
+	paperlessUsername             = os.Getenv("PAPERLESS_USERNAME")
+	paperlessPassword             = secretEnv("PAPERLESS_PASSWORD")
+	verifyDocumentUpdates         = os.Getenv("VERIFY_DOCUMENT_UPDATES") == "true"
+	graphQLEnabled                = os.Getenv("GRAPHQL_ENABLED") == "true"
+	asnBarcodeDetection           = os.Getenv("ASN_BARCODE_DETECTION") == "true"
+	asnBarcodePrefix              = os.Getenv("ASN_BARCODE_PREFIX")
+	asnBarcodeRemovePage          = os.Getenv("ASN_BARCODE_REMOVE_PAGE") == "true"
+	processingScheduleSpec        = os.Getenv("PROCESSING_SCHEDULE")
+	quietHoursSpec                = os.Getenv("QUIET_HOURS")
+	apiKeysSpec                   = secretEnv("API_KEYS")
+	oidcIssuerURL                 = os.Getenv("OIDC_ISSUER_URL")
+	oidcClientID                  = os.Getenv("OIDC_CLIENT_ID")
+	oidcClientSecret              = secretEnv("OIDC_CLIENT_SECRET")
+	oidcRedirectURL               = os.Getenv("OIDC_REDIRECT_URL")
+	oidcScopes                    = strings.Fields(strings.ReplaceAll(os.Getenv("OIDC_SCOPES"), ",", " "))
+	oidcAudience                  = os.Getenv("OIDC_AUDIENCE")
+	oidcSessionSecret             = secretEnv("OIDC_SESSION_SECRET")
 	azureDocAIEndpoint            = os.Getenv("AZURE_DOCAI_ENDPOINT")
-	azureDocAIKey                 = os.Getenv("AZURE_DOCAI_KEY")
+	azureDocAIKey                 = secretEnv("AZURE_DOCAI_KEY")
 	azureDocAIModelID             = os.Getenv("AZURE_DOCAI_MODEL_ID")
 	azureDocAITimeout             = os.Getenv("AZURE_DOCAI_TIMEOUT_SECONDS")
 	AzureDocAIOutputContentFormat = os.Getenv("AZURE_DOCAI_OUTPUT_CONTENT_FORMAT")
-	openaiAPIKey                  = os.Getenv("OPENAI_API_KEY")
-	manualTag                     = os.Getenv("MANUAL_TAG")
-	autoTag                       = os.Getenv("AUTO_TAG")
+	openaiAPIKey                  = secretEnv("OPENAI_API_KEY")
+	autoSavedView                 = os.Getenv("AUTO_SAVED_VIEW")
+	autoDocumentQuery             = os.Getenv("AUTO_DOCUMENT_QUERY")
+	backfillQuery                 = os.Getenv("BACKFILL_QUERY")
+	processDocumentsAddedAfter    = os.Getenv("PROCESS_DOCUMENTS_ADDED_AFTER")
 	manualOcrTag                  = os.Getenv("MANUAL_OCR_TAG") // Not used yet
-	autoOcrTag                    = os.Getenv("AUTO_OCR_TAG")
-	llmProvider                   = os.Getenv("LLM_PROVIDER")
-	llmModel                      = os.Getenv("LLM_MODEL")
 	visionLlmProvider             = os.Getenv("VISION_LLM_PROVIDER")
 	visionLlmModel                = os.Getenv("VISION_LLM_MODEL")
 	logLevel                      = strings.ToLower(os.Getenv("LOG_LEVEL"))
+	logFormat                     = strings.ToLower(os.Getenv("LOG_FORMAT"))
+	logFile                       = os.Getenv("LOG_FILE")
+	documentLogLevel              = strings.ToLower(os.Getenv("DOCUMENT_LOG_LEVEL"))
+	ginMode                       = strings.ToLower(os.Getenv("GIN_MODE"))
 	listenInterface               = os.Getenv("LISTEN_INTERFACE")
-	autoGenerateTitle             = os.Getenv("AUTO_GENERATE_TITLE")
-	autoGenerateTags              = os.Getenv("AUTO_GENERATE_TAGS")
-	autoGenerateCorrespondents    = os.Getenv("AUTO_GENERATE_CORRESPONDENTS")
-	autoGenerateCreatedDate       = os.Getenv("AUTO_GENERATE_CREATED_DATE")
-	limitOcrPages                 int // Will be read from OCR_LIMIT_PAGES
-	tokenLimit                    = 0 // Will be read from TOKEN_LIMIT
+	basePathSpec                  = os.Getenv("BASE_PATH")
+	configFile                    = os.Getenv("CONFIG_FILE")
+	databaseURL                   = os.Getenv("DATABASE_URL")
+	databaseEncryptionKey         = secretEnv("DATABASE_ENCRYPTION_KEY")
+	backupPath                    = os.Getenv("BACKUP_PATH")
+	backupS3Endpoint              = os.Getenv("BACKUP_S3_ENDPOINT")
+	backupS3Region                = os.Getenv("BACKUP_S3_REGION")
+	awsAccessKeyID                = secretEnv("AWS_ACCESS_KEY_ID")
+	awsSecretAccessKey            = secretEnv("AWS_SECRET_ACCESS_KEY")
+	awsSessionToken               = secretEnv("AWS_SESSION_TOKEN")
+	ntfyURL                       = os.Getenv("NTFY_URL")
+	ntfyToken                     = secretEnv("NTFY_TOKEN")
+	gotifyURL                     = os.Getenv("GOTIFY_URL")
+	gotifyToken                   = secretEnv("GOTIFY_TOKEN")
+	appriseURL                    = os.Getenv("APPRISE_URL")
+	appriseTargets                = strings.TrimSpace(secretEnv("APPRISE_TARGETS"))
+	slackWebhookURL               = secretEnv("SLACK_WEBHOOK_URL")
+	slackNotifyEvents             = splitAndTrim(os.Getenv("SLACK_NOTIFY_EVENTS"))
+	discordWebhookURL             = secretEnv("DISCORD_WEBHOOK_URL")
+	discordNotifyEvents           = splitAndTrim(os.Getenv("DISCORD_NOTIFY_EVENTS"))
+	notifyEvents                  = splitAndTrim(os.Getenv("NOTIFY_EVENTS"))
+	smtpHost                      = os.Getenv("SMTP_HOST")
+	smtpUsername                  = os.Getenv("SMTP_USERNAME")
+	smtpPassword                  = secretEnv("SMTP_PASSWORD")
+	smtpFrom                      = os.Getenv("SMTP_FROM")
+	smtpTo                        = splitAndTrim(os.Getenv("SMTP_TO"))
+	emailNotifyEvents             = splitAndTrim(os.Getenv("EMAIL_NOTIFY_EVENTS"))
+	emailDigest                   = strings.ToLower(os.Getenv("EMAIL_DIGEST"))
+	tagTaxonomyFile               = os.Getenv("TAG_TAXONOMY_FILE")
+	promptRulesFile               = os.Getenv("PROMPT_RULES_FILE")
+	promptExperimentsFile         = os.Getenv("PROMPT_EXPERIMENTS_FILE")
+	promptPresetsSpec             = os.Getenv("PROMPT_PRESETS")
+	newTagColors                  = splitAndTrim(os.Getenv("NEW_TAG_COLORS"))
+	newTagMatchingAlgorithm       = strings.ToLower(os.Getenv("NEW_TAG_MATCHING_ALGORITHM"))
+	newTagIsInbox                 = os.Getenv("NEW_TAG_IS_INBOX") == "true"
+	customFieldWhiteList          = splitAndTrim(os.Getenv("CUSTOM_FIELD_WHITE_LIST"))
+	languageTagPrefix             = os.Getenv("LANGUAGE_TAG_PREFIX")
+	languageCustomField           = os.Getenv("LANGUAGE_CUSTOM_FIELD")
+	summaryLanguage               = os.Getenv("SUMMARY_LANGUAGE")
+	summaryCustomField            = os.Getenv("SUMMARY_CUSTOM_FIELD")
+	summaryMaxWords               = 100 // Will be read from SUMMARY_MAX_WORDS
+	dueDateCustomField            = os.Getenv("DUE_DATE_CUSTOM_FIELD")
+	dueSoonDays                   = 7 // Will be read from DUE_SOON_DAYS
+	amountCustomField             = os.Getenv("AMOUNT_CUSTOM_FIELD")
+	piiCategoryTags               = parseNameMapping("PII_CATEGORY_TAGS", os.Getenv("PII_CATEGORY_TAGS"), defaultPIICategoryTags)
+	sensitivityLevels             = splitAndTrim(os.Getenv("SENSITIVITY_LEVELS"))
+	sensitivityTagPrefix          = os.Getenv("SENSITIVITY_TAG_PREFIX")
+	translationLanguage           = os.Getenv("TRANSLATION_LANGUAGE")
+	translationSourceLanguages    = splitAndTrim(strings.ToLower(os.Getenv("TRANSLATION_SOURCE_LANGUAGES")))
+	translationTarget             = os.Getenv("TRANSLATION_TARGET")
+	actionDeadlineCustomField     = os.Getenv("ACTION_DEADLINE_CUSTOM_FIELD")
+	relatedDocumentsCustomField   = os.Getenv("RELATED_DOCUMENTS_CUSTOM_FIELD")
+	relatedDocumentsMax           = 5    // Will be read from RELATED_DOCUMENTS_MAX
+	relatedDocumentsMinSimilarity = 0.85 // Will be read from RELATED_DOCUMENTS_MIN_SIMILARITY
+	embeddingModel                = os.Getenv("EMBEDDING_MODEL")
+	invoiceTag                    = os.Getenv("INVOICE_TAG")
+	invoiceDocumentType           = os.Getenv("INVOICE_DOCUMENT_TYPE")
+	contractTag                   = os.Getenv("CONTRACT_TAG")
+	contractDocumentType          = os.Getenv("CONTRACT_DOCUMENT_TYPE")
+	contractCustomFields          = parseNameMapping("CONTRACT_CUSTOM_FIELDS", os.Getenv("CONTRACT_CUSTOM_FIELDS"), defaultContractCustomFields)
+	cancellationTag               = os.Getenv("CANCELLATION_TAG")
+	cancellationHorizonDays       = 30 // Will be read from CANCELLATION_HORIZON_DAYS
+	invoiceCustomFields           = parseNameMapping("INVOICE_CUSTOM_FIELDS", os.Getenv("INVOICE_CUSTOM_FIELDS"), defaultInvoiceCustomFields)
+	ocrJunkPageMode               = os.Getenv("OCR_JUNK_PAGES")
+	preConsumeInputDir            = os.Getenv("PRECONSUME_INPUT_DIR")
+	preConsumeOutputDir           = os.Getenv("PRECONSUME_OUTPUT_DIR")
+	paperlessPageSize             = 100 // Will be read from PAPERLESS_PAGE_SIZE
+	paperlessRateLimit            = 0.0 // Will be read from PAPERLESS_RATE_LIMIT
+	paperlessMaxRetries           = 3   // Will be read from PAPERLESS_MAX_RETRIES
+	llmPromptTokenCost            = 0.0 // Will be read from LLM_PROMPT_TOKEN_COST
+	llmCompletionTokenCost        = 0.0 // Will be read from LLM_COMPLETION_TOKEN_COST
+	apiRateLimit                  = 0.0 // Will be read from API_RATE_LIMIT
+	apiRateLimitBurst             = 10  // Will be read from API_RATE_LIMIT_BURST
+	suggestionsConcurrencyLimit   = 0   // Will be read from SUGGESTIONS_CONCURRENCY_LIMIT
+	bulkEditBatchSize             = 100 // Will be read from PAPERLESS_BULK_EDIT_BATCH_SIZE
+	circuitBreakerThreshold       = 5   // Will be read from PAPERLESS_CIRCUIT_BREAKER_THRESHOLD
+	circuitBreakerCooldown        = 30  // Will be read from PAPERLESS_CIRCUIT_BREAKER_COOLDOWN_SECONDS
+	verifyUpdateRetries           = 2   // Will be read from VERIFY_DOCUMENT_UPDATE_RETRIES
+	ocrConcurrencyLimit           = 0   // Will be read from OCR_CONCURRENCY_LIMIT
+	logFileMaxSizeMB              = 100 // Will be read from LOG_FILE_MAX_SIZE_MB
+	logFileMaxAgeDays             = 0   // Will be read from LOG_FILE_MAX_AGE_DAYS
+	logFileMaxBackups             = 5   // Will be read from LOG_FILE_MAX_BACKUPS
+	smtpPort                      = 587 // Will be read from SMTP_PORT
+	emailDigestHour               = 8   // Will be read from EMAIL_DIGEST_HOUR
+
+	// Path prefix the web UI and the API are served under behind a reverse proxy, normalized from BASE_PATH
+	basePath string
+
+	// Permissions of the Unix domain socket with LISTEN_INTERFACE=unix:<path>, read from LISTEN_SOCKET_MODE
+	listenSocketMode fs.FileMode = 0660
+
+	// Background polling intervals, read from POLL_INTERVAL and OCR_POLL_INTERVAL
+	backgroundPollInterval = 10 * time.Second
+	ocrPollInterval        = 10 * time.Second
 
 	// Templates
-	titleTemplate         *template.Template
-	tagTemplate           *template.Template
-	correspondentTemplate *template.Template
-	createdDateTemplate   *template.Template
-	ocrTemplate           *template.Template
-	templateMutex         sync.RWMutex
+	titleTemplate               *template.Template
+	tagTemplate                 *template.Template
+	correspondentTemplate       *template.Template
+	createdDateTemplate         *template.Template
+	ocrTemplate                 *template.Template
+	customFieldsTemplate        *template.Template
+	languageTemplate            *template.Template
+	summaryTemplate             *template.Template
+	dueDateTemplate             *template.Template
+	invoiceTemplate             *template.Template
+	amountTemplate              *template.Template
+	contractTemplate            *template.Template
+	correspondentDedupeTemplate *template.Template
+	piiTemplate                 *template.Template
+	sensitivityTemplate         *template.Template
+	translationTemplate         *template.Template
+	triageTemplate              *template.Template
+	condenseTemplate            *template.Template
+	confidenceTemplate          *template.Template
+	templateMutex               sync.RWMutex
+
+	// Tag taxonomy, loaded from TAG_TAXONOMY_FILE
+	tagTaxonomy *TagTaxonomy
+
+	// Prompts replaced for documents with specific tags or document types, loaded from PROMPT_RULES_FILE
+	promptRules *PromptRules
+
+	// Variants of prompts compared by the feedback on their suggestions, loaded from PROMPT_EXPERIMENTS_FILE
+	promptExperiments *PromptExperiments
+
+	// Templates of the language directories of the prompts directory, by language code and prompt name
+	languagePromptTemplates map[string]map[string]*template.Template
+
+	// Background processing windows, parsed from PROCESSING_SCHEDULE and QUIET_HOURS
+	processingSchedule   *cronSchedule
+	processingQuietHours *quietHoursWindow
+
+	// Documents the automatic pipelines may touch, parsed from PROCESS_DOCUMENTS_ADDED_AFTER
+	autoProcessingCutoff *processingCutoff
+
+	// Set by the -force flag to process documents again even if their fingerprint is unchanged
+	forceReprocessing bool
+
+	// Keys accepted by the HTTP API, parsed from API_KEYS
+	apiKeys []apiKey
+
+	// OpenID Connect login for the web UI and the API, set up from the OIDC_* settings
+	oidcAuth *oidcProvider
+
+	// TLS settings of the paperless-ngx connection, built from PAPERLESS_CA_CERT, PAPERLESS_CLIENT_CERT and PAPERLESS_CLIENT_KEY
+	paperlessTLSConfig *tls.Config
 
 	// Default templates
 	defaultTitleTemplate = `I will provide you with the content of a document that has been partially read by OCR (so it may contain errors).
 Your task is to find a suitable document title that I can use as the title in the paperless-ngx program.
 Respond only with the title, without any additional information. The content is likely in {{.Language}}.
+{{- with .Examples}}
+
+Titles recently chosen for other documents, follow their style:
+{{- range .}}
+- {{.}}
+{{- end}}
+{{- end}}
 
 Content:
 {{.Content}}
 `
 
-	defaultTagTemplate = `I will provide you with the content and the title of a document. Your task is to select appropriate tags for the document from the list of available tags I will provide. Only select tags from the provided list. Respond only with the selected tags as a comma-separated list, without any additional information. The content is likely in {{.Language}}.
+	defaultTagTemplate = `I will provide you with the content and the title of a document. Your task is to select appropriate tags for the document from the list of available tags I will provide. {{if .AllowNewTags}}Prefer tags from the provided list. Only if an important topic of the document is not covered by any of them, you may propose a new short tag in the style of the existing ones.{{else}}Only select tags from the provided list.{{end}} Respond only with the selected tags as a comma-separated list, without any additional information. The content is likely in {{.Language}}.
 
 Available Tags:
 {{.AvailableTags | join ", "}}
+{{- with .TagGroups}}
+
+Some tags belong to groups with selection rules that must be followed:
+{{- range .}}
+- {{.Rule}}
+{{- end}}
+{{- end}}
+{{- with .Examples}}
+
+Tags recently chosen for other documents: {{join ", " .}}
+{{- end}}
 
 Title:
 {{.Title}}
@@ -94,7 +278,11 @@ Be very selective and only choose the most relevant tags since too many tags wil
 
 Correspondents are the senders of documents that reach you. In the other direction, correspondents are the recipients of documents that you send.
 In Paperless-ngx we can imagine correspondents as virtual drawers in which all documents of a person or company are stored. With just one click, we can find all the documents assigned to a specific correspondent.
+{{- if .Restricted}}
+Choose a correspondent from the list of correspondents below. Never come up with a new correspondent.
+{{- else}}
 Try to suggest a correspondent, either from the example list or come up with a new correspondent.
+{{- end}}
 
 Respond only with a correspondent, without any additional information!
 
@@ -103,11 +291,15 @@ Try to avoid any legal or financial suffixes like "GmbH" or "AG" in the correspo
 
 If you can't find a suitable correspondent, you can respond with "Unknown".
 
-Example Correspondents:
+{{if .Restricted}}Correspondents{{else}}Example Correspondents{{end}}:
 {{.AvailableCorrespondents | join ", "}}
 
 List of Correspondents with Blacklisted Names. Please avoid these correspondents or variations of their names:
 {{.BlackList | join ", "}}
+{{- with .Examples}}
+
+Correspondents recently chosen for other documents, prefer their spelling: {{join ", " .}}
+{{- end}}
 
 Title of the document:
 {{.Title}}
@@ -121,6 +313,170 @@ Document Content:
 Respond only with the date in YYYY-MM-DD format, without any additional information. If no day was found, use the first day of the month. If no month was found, use January. If no date was found at all, answer with today's date.
 The content is likely in {{.Language}}. Today's date is {{.Today}}.
 
+Content:
+{{.Content}}
+`
+	defaultCustomFieldsTemplate = `I will provide you with the content of a document. Your task is to extract the values of the following custom fields from the document:
+{{range .CustomFields}}
+- "{{.Name}}" (type: {{.DataType}}{{with .Options}}, one of: {{. | join ", "}}{{end}})
+{{- end}}
+
+Respond only with a JSON object that maps each field name to its value, without any additional information. Use null for fields whose value cannot be found in the document.
+Format dates as YYYY-MM-DD, monetary amounts as a number with two decimals prefixed by the ISO currency code (e.g. "EUR123.45"), booleans as true or false and numbers without thousands separators.
+The content is likely in {{.Language}}. Today's date is {{.Today}}.
+
+Title of the document:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultLanguageTemplate = `I will provide you with the content of a document. Your task is to detect the language the document is written in.
+{{- if .Languages}}
+The documents are usually written in one of these languages: {{join ", " .Languages}}.
+{{- end}}
+Respond only with the two-letter ISO 639-1 code of the language (e.g. "en", "de", "fr"), without any additional information.
+
+Content:
+{{.Content}}
+`
+	defaultSummaryTemplate = `I will provide you with the content and the title of a document. Your task is to write a concise summary of the document.
+The summary should cover what the document is about, who sent it and any important dates, amounts or required actions.
+Respond only with the summary in {{.Language}}, using at most {{.MaxWords}} words, without any additional information.
+
+Title:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultDueDateTemplate = `I will provide you with the content and the title of a document. Your task is to find the date by which a payment is due or an action has to be taken (e.g. the payment deadline of an invoice or the response deadline of an official letter).
+If the deadline is given relative to the document date (e.g. "within 14 days"), calculate the date from the document date.
+Respond only with the date in YYYY-MM-DD format, without any additional information. If the document has no due date or deadline, answer with "none".
+The content is likely in {{.Language}}. Today's date is {{.Today}}.
+
+Title:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultAmountTemplate = `I will provide you with the content of a document. Your task is to find the total amount of the document (e.g. the amount to pay on an invoice or the total on a receipt) and its currency.
+Respond only with a JSON object of the form {"amount": "...", "currency": "..."}, without any additional information.
+Write the amount exactly as it appears in the document and the currency as ISO 4217 code (e.g. "EUR", "USD"). Use null for both if the document has no amount.
+The content is likely in {{.Language}}.
+
+Title of the document:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultPIITemplate = `I will provide you with the content of a document. Your task is to find personal data in the document that is relevant for data protection (GDPR).
+Count the occurrences of the following categories of personal data:
+{{range .Categories}}- {{.}}
+{{end}}
+Respond only with a JSON object that maps each category found to the number of occurrences, without any additional information. Leave out categories that were not found and respond with {} if the document contains no personal data.
+Do not repeat any of the personal data itself. The content is likely in {{.Language}}.
+
+Content:
+{{.Content}}
+`
+	defaultSensitivityTemplate = `I will provide you with the content of a document. Your task is to classify how confidential the document is.
+The available sensitivity levels, from least to most sensitive, are:
+{{range .Levels}}- {{.}}
+{{end}}
+Choose the most sensitive level that applies. As a guideline: "public" documents can be shared with anyone (e.g. brochures, published articles), "internal" documents are not meant for the public but contain nothing harmful (e.g. receipts, letters), and "confidential" documents contain financial, medical, legal or personal details (e.g. bank statements, contracts, medical reports).
+Respond only with the name of the level, without any additional information. The content is likely in {{.Language}}.
+
+The document is titled "{{.Title}}".
+
+Content:
+{{.Content}}
+`
+	defaultTranslationTemplate = `I will provide you with the content of a document written in {{.SourceLanguage}}. Your task is to translate it into {{.Language}}.
+Keep the structure of the document, including line breaks, lists and tables. Keep names, addresses, numbers, amounts and dates as they are.
+Respond only with the translation, without any additional information or comments.
+
+Content:
+{{.Content}}
+`
+	defaultTriageTemplate = `I will provide you with the content of a document that was just received. Your task is to decide whether the recipient has to take action on it:
+- "action_required": true if the recipient has to do something, e.g. pay a bill, reply to a letter or sign and return a document, otherwise false
+- "action": the kind of action, one of {{range $i, $action := .Actions}}{{if $i}}, {{end}}"{{$action}}"{{end}}
+- "deadline": the date by which the action has to be taken, in YYYY-MM-DD format
+
+Documents that are only for information, like statements, confirmations or receipts of payments already made, do not require action.
+Respond only with a JSON object containing these keys, without any additional information. Use null for values that cannot be found in the document.
+The content is likely in {{.Language}}. Today's date is {{.Today}}.
+
+Title of the document:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultCorrespondentDedupeTemplate = `I will provide you with groups of correspondent names from a document management system. The names within a group look similar, but may or may not refer to the same person or organization.
+For every group, decide which names refer to the same person or organization and choose the best canonical name among them. Prefer short, commonly used names without legal suffixes like "GmbH" or "Inc.", for example "Telekom" instead of "Telekom Deutschland GmbH".
+
+Respond only with a JSON array of objects of the form {"canonical": "...", "duplicates": ["...", "..."]}, without any additional information.
+The canonical name must be one of the names of the group and must not be repeated in "duplicates". Leave out names that refer to different correspondents. Respond with [] if there are no duplicates at all.
+
+Groups:
+{{range $i, $group := .Groups}}{{add1 $i}}. {{$group | join " | "}}
+{{end}}`
+	defaultContractTemplate = `I will provide you with the content of a contract or subscription. Your task is to extract its term and cancellation conditions:
+- "end_date": the date the current contract term ends, in YYYY-MM-DD format
+- "notice_period": the notice period for cancellation, e.g. "3 months", "6 weeks" or "30 days"
+- "renewal_period": the period by which the contract renews automatically if it is not cancelled, e.g. "12 months"
+
+Respond only with a JSON object containing these keys, without any additional information. Use null for values that cannot be found in the document.
+If the end date is given relative to the start of the contract (e.g. "minimum term of 24 months"), calculate it from the start date.
+The content is likely in {{.Language}}. Today's date is {{.Today}}.
+
+Title of the document:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultInvoiceTemplate = `I will provide you with the content of an invoice. Your task is to extract the following invoice data:
+- "invoice_number": the invoice number
+- "net_amount": the total amount without VAT
+- "gross_amount": the total amount including VAT
+- "currency": the ISO 4217 currency code (e.g. "EUR", "USD")
+- "iban": the IBAN the invoice should be paid to
+- "vat_id": the VAT identification number of the vendor
+
+Respond only with a JSON object containing these keys, without any additional information. Use null for values that cannot be found in the invoice.
+Write amounts as numbers without thousands separators and currency symbols (e.g. 1234.56).
+The content is likely in {{.Language}}.
+
+Title of the document:
+{{.Title}}
+
+Content:
+{{.Content}}
+`
+	defaultCondenseTemplate = `I will provide you with a part of a longer document that has been read by OCR (so it may contain errors). The document is too long to be processed as a whole, so your task is to condense this part to at most {{.MaxWords}} words.
+Keep the kind of document, its subject and all names, dates, amounts, totals, reference numbers and account numbers exactly as written. Leave out boilerplate like terms and conditions, greetings and page headers.
+Respond only with the condensed text in the language of the document, without any additional information.
+
+Part {{.Part}} of {{.Parts}}:
+{{.Content}}
+`
+	defaultConfidenceTemplate = `I will provide you with the content of a document and the values another assistant suggested for some of its fields. Your task is to rate how confident you are that each suggested value is correct and fits the document.
+Rate each field with a number between 0 and 1, where 1 means the value is certainly right and 0 means it is certainly wrong or not supported by the document.
+Respond only with a JSON object with the field names as keys and the ratings as values, e.g. {"title": 0.9}, without any additional information. The content is likely in {{.Language}}.
+
+Current title of the document:
+{{.Title}}
+
+Suggested values:
+{{- range .Suggestions}}
+- {{.Field}}: {{.Value}}
+{{- end}}
+
 Content:
 {{.Content}}
 `
@@ -133,10 +489,35 @@ type App struct {
 	Database    *gorm.DB
 	LLM         llms.Model
 	VisionLLM   llms.Model
+	Embedder    embedder     // Optional, used to find related documents
 	ocrProvider ocr.Provider // OCR provider interface
 }
 
 func main() {
+	backfill := flag.Bool("backfill", false, "Enrich the existing documents lacking a title, tags or a correspondent once, then exit")
+	migrateDown := flag.Int("migrate-down", 0, "Roll back this number of database migrations before downgrading paperless-gpt, then exit")
+	flag.BoolVar(&forceReprocessing, "force", false, "Process tagged documents again even if they are unchanged since they were processed")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage of %s [flags] [command]:\n", os.Args[0])
+		flag.PrintDefaults()
+		fmt.Fprintf(flag.CommandLine.Output(), "Commands, run instead of the server (see <command> -h):\n%s\n", commandList())
+	}
+	flag.Parse()
+
+	// A command runs a single operation instead of the server. Its result is written to stdout, everything else that
+	// would be printed there goes to stderr, so the output can be used in scripts.
+	command, err := parseCommand(flag.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	commandOutput := os.Stdout
+	if command != nil {
+		os.Stdout = os.Stderr
+	}
+
 	// Context for proper control of background-thread
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -156,11 +537,55 @@ func main() {
 	// Initialize Database
 	database := InitializeDB()
 
+	// Capture the log lines of documents for GET /api/documents/:id/logs
+	documentLogs := startDocumentLogCapture(ctx, database, documentLogLevel)
+
+	// Roll back migrations instead of running the server
+	if *migrateDown > 0 {
+		rolledBack, err := rollbackMigrations(database, *migrateDown)
+		if err != nil {
+			log.Fatalf("Failed to roll back database migrations: %v", err)
+		}
+		log.Infof("Rolled back %d database migrations: %s", len(rolledBack), strings.Join(rolledBack, ", "))
+		return
+	}
+
 	// Load Templates
 	loadTemplates()
 
+	// Load tag taxonomy
+	if tagTaxonomyFile != "" {
+		taxonomy, err := loadTagTaxonomy(tagTaxonomyFile)
+		if err != nil {
+			log.Fatalf("Failed to load tag taxonomy: %v", err)
+		}
+		tagTaxonomy = taxonomy
+		log.Infof("Loaded tag taxonomy with %d groups", len(taxonomy.Groups))
+	}
+
+	// Load prompt rules
+	if promptRulesFile != "" {
+		rules, err := loadPromptRules(promptRulesFile)
+		if err != nil {
+			log.Fatalf("Failed to load prompt rules: %v", err)
+		}
+		promptRules = rules
+		log.Infof("Loaded %d prompt rules", len(rules.Rules))
+	}
+
+	// Load prompt experiments
+	if promptExperimentsFile != "" {
+		experiments, err := loadPromptExperiments(promptExperimentsFile)
+		if err != nil {
+			log.Fatalf("Failed to load prompt experiments: %v", err)
+		}
+		promptExperiments = experiments
+		log.Infof("Loaded %d prompt experiments", len(experiments.Experiments))
+	}
+
 	// Initialize LLM
-	llm, err := createLLM()
+	settings := loadSettings()
+	llm, err := createLLM(settings)
 	if err != nil {
 		log.Fatalf("Failed to create LLM client: %v", err)
 	}
@@ -171,16 +596,19 @@ func main() {
 		log.Fatalf("Failed to create Vision LLM client: %v", err)
 	}
 
+	// Initialize embedding model
+	embeddingLlm, err := createEmbedder()
+	if err != nil {
+		log.Fatalf("Failed to create embedding client: %v", err)
+	}
+
 	// Initialize OCR provider
 	var ocrProvider ocr.Provider
-	providerType := os.Getenv("OCR_PROVIDER")
-	if providerType == "" {
-		providerType = "llm" // Default to LLM provider
-	}
+	providerType := ocrProviderType()
 
 	var promptBuffer bytes.Buffer
 	err = ocrTemplate.Execute(&promptBuffer, map[string]interface{}{
-		"Language": getLikelyLanguage(),
+		"Language": getLikelyLanguage(ctx),
 	})
 	if err != nil {
 		log.Fatalf("error executing tag template: %v", err)
@@ -219,123 +647,285 @@ func main() {
 		if err != nil {
 			log.Fatalf("Failed to initialize OCR provider: %v", err)
 		}
+		// The requests are recorded with the provider of the vision model for LLM based OCR
+		if providerType == "llm" {
+			ocrProvider = recordOCRUsage(ocrProvider, database, visionLlmProvider, ocrPrompt)
+		} else {
+			ocrProvider = recordOCRUsage(ocrProvider, database, providerType, "")
+		}
 	}
 
 	// Initialize App with dependencies
 	app := &App{
 		Client:      client,
 		Database:    database,
-		LLM:         llm,
+		LLM:         limitModelConcurrency(recordLLMUsage(llm, database, settings.LLMProvider, settings.LLMModel), settings.LLMConcurrencyLimit),
 		VisionLLM:   visionLlm,
-		ocrProvider: ocrProvider,
+		Embedder:    embeddingLlm,
+		ocrProvider: limitOCRConcurrency(ocrProvider, ocrConcurrencyLimit),
 	}
 
 	if app.isOcrEnabled() {
 		fmt.Printf("Using %s as manual OCR tag\n", manualOcrTag)
-		fmt.Printf("Using %s as auto OCR tag\n", autoOcrTag)
+		fmt.Printf("Using %s as auto OCR tag\n", settings.AutoOCRTag)
+		settings = settings.clone()
 		rawLimitOcrPages := os.Getenv("OCR_LIMIT_PAGES")
 		if rawLimitOcrPages == "" {
-			limitOcrPages = 5
+			settings.LimitOCRPages = 5
 		} else {
 			var err error
-			limitOcrPages, err = strconv.Atoi(rawLimitOcrPages)
+			settings.LimitOCRPages, err = strconv.Atoi(rawLimitOcrPages)
 			if err != nil {
 				log.Fatalf("Invalid OCR_LIMIT_PAGES value: %v", err)
 			}
 		}
+		currentSettings.Store(settings)
+
+		if ocrJunkPageMode == "" {
+			ocrJunkPageMode = junkPageModeOff
+		}
+		if ocrJunkPageMode != junkPageModeOff && ocrJunkPageMode != junkPageModeSkip && ocrJunkPageMode != junkPageModeFlag {
+			log.Fatalf("Invalid OCR_JUNK_PAGES value: %s (must be 'off', 'skip' or 'flag')", ocrJunkPageMode)
+		}
+	}
+
+	// Apply changes of the prompt templates without a restart
+	go watchPrompts(ctx)
+
+	// Apply the settings of CONFIG_FILE, which are applied again on SIGHUP
+	if configFile != "" {
+		if err := app.reloadConfigFile(configFile); err != nil {
+			log.Fatalf("Failed to load CONFIG_FILE: %v", err)
+		}
+		go app.reloadConfigOnSignal(ctx, configFile)
+	}
+
+	if command != nil {
+		if err := command(ctx, app, commandOutput); err != nil {
+			log.Fatalf("%s failed: %v", flag.Arg(0), err)
+		}
+		documentLogs.flush()
+		return
+	}
+
+	// Backfill the existing library instead of running the server
+	if *backfill {
+		query, err := app.documentSelectionQuery(ctx, "", backfillQuery)
+		if err != nil {
+			log.Fatalf("Invalid BACKFILL_QUERY: %v", err)
+		}
+		if err := app.runBackfill(ctx, query); err != nil {
+			log.Fatalf("Backfill failed, run it again to resume: %v", err)
+		}
+		return
 	}
 
+	// Report misconfigured services right away instead of when the first document fails
+	go app.logStartupChecks(ctx)
+
 	// Start Background-Tasks for Auto-Tagging and Auto-OCR (if enabled)
 	StartBackgroundTasks(ctx, app)
 
-	// Create a Gin router with default middleware (logger and recovery)
-	router := gin.Default()
-
-	// API routes
-	api := router.Group("/api")
-	{
-		api.GET("/documents", app.documentsHandler)
-		// http://localhost:8080/api/documents/544
-		api.GET("/documents/:id", app.getDocumentHandler())
-		api.POST("/generate-suggestions", app.generateSuggestionsHandler)
-		api.PATCH("/update-documents", app.updateDocumentsHandler)
-		api.GET("/filter-tag", func(c *gin.Context) {
-			c.JSON(http.StatusOK, gin.H{"tag": manualTag})
-		})
-		// Get all tags
-		api.GET("/tags", app.getAllTagsHandler)
-		api.GET("/prompts", getPromptsHandler)
-		api.POST("/prompts", updatePromptsHandler)
-
-		// OCR endpoints
-		api.POST("/documents/:id/ocr", app.submitOCRJobHandler)
-		api.GET("/jobs/ocr/:job_id", app.getJobStatusHandler)
-		api.GET("/jobs/ocr", app.getAllJobsHandler)
-
-		// Endpoint to see if user enabled OCR
-		api.GET("/experimental/ocr", func(c *gin.Context) {
-			enabled := app.isOcrEnabled()
-			c.JSON(http.StatusOK, gin.H{"enabled": enabled})
-		})
-
-		// Local db actions
-		api.GET("/modifications", app.getModificationHistoryHandler)
-		api.POST("/undo-modification/:id", app.undoModificationHandler)
-
-		// Get public Paperless environment (as set in environment variables)
-		api.GET("/paperless-url", func(c *gin.Context) {
-			baseUrl := os.Getenv("PAPERLESS_PUBLIC_URL")
-			if baseUrl == "" {
-				baseUrl = os.Getenv("PAPERLESS_BASE_URL")
-			}
-			baseUrl = strings.TrimRight(baseUrl, "/")
-			c.JSON(http.StatusOK, gin.H{"url": baseUrl})
-		})
+	// Prune the modification history to HISTORY_RETENTION_DAYS and HISTORY_MAX_ENTRIES
+	StartHistoryPruner(ctx, app)
+
+	// Back up the database to BACKUP_PATH every BACKUP_INTERVAL_HOURS
+	StartBackupScheduler(ctx, app)
+
+	// Send notifications for NOTIFY_EVENTS to ntfy, Gotify and Apprise, and for EMAIL_NOTIFY_EVENTS by email
+	StartNotifier(ctx, app)
+
+	// Email the daily or weekly digest of EMAIL_DIGEST
+	StartEmailDigest(ctx, app)
+
+	// Start watching the pre-consume directory (if enabled)
+	if preConsumeInputDir != "" {
+		if !app.isOcrEnabled() {
+			log.Fatal("PRECONSUME_INPUT_DIR requires an OCR provider to be configured.")
+		}
+		if preConsumeOutputDir == "" {
+			log.Fatal("Please set the PRECONSUME_OUTPUT_DIR environment variable to the paperless-ngx consumption directory.")
+		}
+		StartPreConsumeWatcher(ctx, app)
 	}
 
-	// Serve embedded web-app files
-	// router.GET("/*filepath", func(c *gin.Context) {
-	// 	filepath := c.Param("filepath")
-	// 	// Remove leading slash from filepath
-	// 	filepath = strings.TrimPrefix(filepath, "/")
-	// 	// Handle static assets under /assets/
-	// 	serveEmbeddedFile(c, "", filepath)
-	// })
+	// Set up the OpenID Connect login (if enabled)
+	if oidcIssuerURL != "" {
+		oidcAuth, err = newOIDCProvider(ctx, oidcConfig{
+			IssuerURL:     oidcIssuerURL,
+			ClientID:      oidcClientID,
+			ClientSecret:  oidcClientSecret,
+			RedirectURL:   oidcRedirectURL,
+			Scopes:        oidcScopes,
+			Audience:      oidcAudience,
+			SessionSecret: oidcSessionSecret,
+			BasePath:      basePath,
+		}, &http.Client{Timeout: 30 * time.Second})
+		if err != nil {
+			log.Fatalf("Failed to set up the OIDC login: %v", err)
+		}
+	}
 
-	// Instead of wildcard, serve specific files
-	router.GET("/favicon.ico", func(c *gin.Context) {
-		serveEmbeddedFile(c, "", "favicon.ico")
-	})
-	router.GET("/assets/*filepath", func(c *gin.Context) {
-		filepath := c.Param("filepath")
-		fmt.Printf("Serving asset: %s\n", filepath)
-		serveEmbeddedFile(c, "assets", filepath)
-	})
-	router.GET("/", func(c *gin.Context) {
-		serveEmbeddedFile(c, "", "index.html")
-	})
-	// history route
-	router.GET("/history", func(c *gin.Context) {
-		serveEmbeddedFile(c, "", "index.html")
-	})
-	// experimental-ocr route
-	router.GET("/experimental-ocr", func(c *gin.Context) {
-		serveEmbeddedFile(c, "", "index.html")
-	})
+	// Create a Gin router logging the requests with logrus
+	router := gin.New()
+	router.Use(accessLogger(), gin.Recovery())
 
-	// Start OCR worker pool
+	// All routes are served under the base path, which is empty unless BASE_PATH is set
+	root := router.Group(basePath)
+
+	// API routes, rate limited per client after authenticating it
+	rateLimiter := newAPIRateLimiter(apiRateLimit, apiRateLimitBurst)
+	app.registerAPIRoutes(root.Group("/api", apiAuth(apiKeys, app.Database, oidcAuth), rateLimiter.middleware()))
+
+	// Optional GraphQL API, protected like the REST API
+	if graphQLEnabled {
+		graphQL := root.Group("/graphql", apiAuth(apiKeys, app.Database, oidcAuth), rateLimiter.middleware())
+		graphQL.GET("", app.graphQLHandler)
+		graphQL.POST("", app.graphQLHandler)
+		graphQL.GET("/schema", app.graphQLSchemaHandler)
+	}
+
+	// OpenID Connect login
+	if oidcAuth != nil {
+		root.GET("/auth/login", oidcAuth.loginHandler)
+		root.GET("/auth/callback", oidcAuth.callbackHandler)
+		root.GET("/auth/logout", oidcAuth.logoutHandler)
+	}
+
+	// Serve embedded web-app files
+	registerWebUIRoutes(root, basePath, oidcAuth)
+
+	// Queue the jobs that were unfinished at the last shutdown again and start the worker pool
+	restoreJobs(database)
 	numWorkers := 1 // Number of workers to start
 	startWorkerPool(app, numWorkers)
 
 	if listenInterface == "" {
 		listenInterface = ":8080"
 	}
+	listener, err := listen(listenInterface, listenSocketMode)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", listenInterface, err)
+	}
 	log.Infoln("Server started on interface", listenInterface)
-	if err := router.Run(listenInterface); err != nil {
+	if err := router.RunListener(listener); err != nil {
 		log.Fatalf("Failed to run server: %v", err)
 	}
 }
 
+// registerWebUIRoutes registers the routes of the embedded web UI. Its pages are served with a <base> element for the
+// base path, which the relative URLs of the assets and API calls resolve against.
+func registerWebUIRoutes(root *gin.RouterGroup, basePath string, provider *oidcProvider) {
+	// Instead of wildcard, serve specific files
+	root.GET("/favicon.ico", func(c *gin.Context) {
+		serveEmbeddedFile(c, "", "favicon.ico")
+	})
+	root.GET("/assets/*filepath", func(c *gin.Context) {
+		serveEmbeddedFile(c, "assets", c.Param("filepath"))
+	})
+	serveIndex := func(c *gin.Context) {
+		serveIndexHTML(c, basePath)
+	}
+	root.GET("/", provider.requireLogin(), serveIndex)
+	// history route
+	root.GET("/history", provider.requireLogin(), serveIndex)
+	// experimental-ocr route
+	root.GET("/experimental-ocr", provider.requireLogin(), serveIndex)
+	// dashboard route
+	root.GET("/dashboard", provider.requireLogin(), serveIndex)
+}
+
+// registerAPIRoutes registers the routes of the HTTP API, they are documented in openapi.json
+func (app *App) registerAPIRoutes(api *gin.RouterGroup) {
+	api.GET("/documents", app.documentsHandler)
+	// http://localhost:8080/api/documents/544
+	api.GET("/documents/:id", app.getDocumentHandler())
+	api.GET("/search", app.searchDocumentsHandler)
+	api.GET("/documents/:id/pii", app.getPIIReportHandler)
+	api.GET("/documents/:id/logs", app.getDocumentLogsHandler)
+	api.POST("/generate-suggestions", limitConcurrentRequests(newConcurrencySlots(suggestionsConcurrencyLimit)), app.generateSuggestionsHandler)
+	api.POST("/generate-suggestions/batch", app.generateSuggestionsBatchHandler)
+	api.PATCH("/update-documents", app.updateDocumentsHandler)
+	api.GET("/filter-tag", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"tag": settingsFor(c.Request.Context()).ManualTag})
+	})
+	// Get all tags
+	api.GET("/tags", app.getAllTagsHandler)
+	api.GET("/correspondents/dedupe", app.getCorrespondentDedupeHandler)
+	api.POST("/correspondents/dedupe", app.mergeCorrespondentsHandler)
+	api.GET("/prompts", getPromptsHandler)
+	api.POST("/prompts", updatePromptsHandler)
+	api.GET("/prompts/export", exportPromptsHandler)
+	api.POST("/prompts/import", importPromptsHandler)
+	api.POST("/prompts/validate", app.validatePromptHandler)
+	api.GET("/prompts/presets", getPromptPresetsHandler)
+	api.POST("/prompts/presets/:name/apply", applyPromptPresetHandler)
+
+	// OCR endpoints
+	api.POST("/documents/:id/ocr", app.submitOCRJobHandler)
+	api.GET("/jobs/ocr/:job_id", app.getJobStatusHandler)
+	api.GET("/jobs/ocr", app.getOCRJobsHandler)
+
+	// Job queue for all kinds of jobs
+	api.POST("/jobs", app.submitJobHandler)
+	api.GET("/jobs", app.getAllJobsHandler)
+	api.GET("/jobs/:job_id", app.getJobStatusHandler)
+
+	// Endpoint to see if user enabled OCR
+	api.GET("/experimental/ocr", func(c *gin.Context) {
+		enabled := app.isOcrEnabled()
+		c.JSON(http.StatusOK, gin.H{"enabled": enabled})
+	})
+
+	// Local db actions
+	api.GET("/modifications", app.getModificationHistoryHandler)
+	api.GET("/modifications/export", app.exportModificationHistoryHandler)
+	api.DELETE("/modifications", app.pruneModificationHistoryHandler)
+	api.POST("/modifications/archive", app.archiveModificationsHandler)
+	api.POST("/modifications/restore", app.restoreModificationsHandler)
+	api.POST("/undo-modification/:id", app.undoModificationHandler)
+	api.POST("/undo-batch/:id", app.undoBatchHandler)
+	api.POST("/redo-modification/:id", app.redoModificationHandler)
+	api.GET("/pending-tags", app.getPendingTagsHandler)
+	api.POST("/pending-tags/:id/approve", app.approvePendingTagHandler)
+	api.POST("/pending-tags/:id/reject", app.rejectPendingTagHandler)
+	api.GET("/review", app.getReviewsHandler)
+	api.POST("/review/:id/approve", app.approveReviewHandler)
+	api.POST("/review/:id/reject", app.rejectReviewHandler)
+	api.POST("/feedback", app.feedbackHandler)
+	api.GET("/experiments", app.getExperimentsHandler)
+	api.GET("/failures", app.getFailuresHandler)
+	api.GET("/stats", app.getStatsHandler)
+	api.POST("/failures/:document_id/retry", app.retryFailureHandler)
+	api.GET("/paperless/status", app.paperlessStatusHandler)
+	api.GET("/config", app.getConfigHandler)
+	api.PATCH("/config", app.updateConfigHandler)
+	api.GET("/processing/status", getProcessingStatusHandler)
+	api.POST("/processing/pause", pauseProcessingHandler)
+	api.POST("/processing/resume", resumeProcessingHandler)
+	api.GET("/ws", eventsWebSocketHandler)
+	api.GET("/orphaned-documents", app.getOrphanedDocumentsHandler)
+	api.DELETE("/orphaned-documents", app.purgeOrphanedDocumentsHandler)
+	api.GET("/migrations", app.getMigrationsHandler)
+	api.GET("/admin/backups", app.getBackupsHandler)
+	api.GET("/admin/backup", app.downloadBackupHandler)
+	api.POST("/admin/backup", app.createBackupHandler)
+	api.POST("/admin/restore", app.restoreBackupHandler)
+	api.GET("/tokens", app.getAPITokensHandler)
+	api.POST("/tokens", app.createAPITokenHandler)
+	api.DELETE("/tokens/:id", app.revokeAPITokenHandler)
+
+	// Get public Paperless environment (as set in environment variables)
+	api.GET("/paperless-url", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"url": paperlessPublicURL()})
+	})
+
+	// API documentation
+	api.GET("/openapi.json", openAPISpecHandler)
+	api.GET("/docs", swaggerUIHandler)
+	api.GET("/docs/:file", swaggerUIAssetHandler)
+}
+
 func printVersion() {
 	cyan := color.New(color.FgCyan).SprintFunc()
 	yellow := color.New(color.FgYellow).SprintFunc()
@@ -376,9 +966,37 @@ func initLogger() {
 		}
 	}
 
-	log.SetFormatter(&logrus.TextFormatter{
-		FullTimestamp: true,
-	})
+	formatter, err := logFormatter(logFormat)
+	if err != nil {
+		log.Fatalf("Invalid LOG_FORMAT: %v", err)
+	}
+	log.SetFormatter(formatter)
+	ocr.SetLogLevel(log.GetLevel())
+	ocr.SetLogFormatter(formatter)
+
+	// Logs are written to LOG_FILE in addition to stderr
+	var output io.Writer = os.Stderr
+	if logFile != "" {
+		output = io.MultiWriter(os.Stderr, rotatingLogFile(logFile, logFileMaxSizeMB, logFileMaxAgeDays, logFileMaxBackups))
+		log.SetOutput(output)
+		ocr.SetLogOutput(output)
+	}
+
+	// Lines of documents are captured at DOCUMENT_LOG_LEVEL. If it is more verbose than LOG_LEVEL, the other lines are
+	// filtered before they are written.
+	captureLevels, err := documentLogLevels(documentLogLevel)
+	if err != nil {
+		log.Fatalf("Invalid DOCUMENT_LOG_LEVEL: %v", err)
+	}
+	if len(captureLevels) > 0 && captureLevels[len(captureLevels)-1] > log.GetLevel() {
+		log.AddHook(newLevelWriterHook(output, log.GetLevel()))
+		log.SetOutput(io.Discard)
+		log.SetLevel(captureLevels[len(captureLevels)-1])
+	}
+
+	if err := configureGin(ginMode); err != nil {
+		log.Fatalf("Invalid GIN_MODE: %v", err)
+	}
 }
 
 func (app *App) isOcrEnabled() bool {
@@ -387,40 +1005,84 @@ func (app *App) isOcrEnabled() bool {
 
 // validateOrDefaultEnvVars ensures all necessary environment variables are set
 func validateOrDefaultEnvVars() {
-	if manualTag == "" {
-		manualTag = "paperless-gpt"
+	settings := environmentSettings()
+
+	if settings.ManualTag == "" {
+		settings.ManualTag = "paperless-gpt"
 	}
-	fmt.Printf("Using %s as manual tag\n", manualTag)
+	fmt.Printf("Using %s as manual tag\n", settings.ManualTag)
 
-	if autoTag == "" {
-		autoTag = "paperless-gpt-auto"
+	if settings.AutoTag == "" {
+		settings.AutoTag = "paperless-gpt-auto"
 	}
-	fmt.Printf("Using %s as auto tag\n", autoTag)
+	fmt.Printf("Using %s as auto tag\n", settings.AutoTag)
 
 	if manualOcrTag == "" {
 		manualOcrTag = "paperless-gpt-ocr"
 	}
 
-	if autoOcrTag == "" {
-		autoOcrTag = "paperless-gpt-ocr-auto"
+	if settings.AutoOCRTag == "" {
+		settings.AutoOCRTag = "paperless-gpt-ocr-auto"
+	}
+
+	if settings.ErrorTag == "" {
+		settings.ErrorTag = "paperless-gpt-error"
+	}
+
+	if asnBarcodePrefix == "" {
+		asnBarcodePrefix = "ASN"
+	}
+
+	if settings.PIITag == "" {
+		settings.PIITag = "contains-pii"
+	}
+
+	if settings.ActionRequiredTag == "" {
+		settings.ActionRequiredTag = "action-required"
+	}
+
+	if len(sensitivityLevels) == 0 {
+		sensitivityLevels = []string{"public", "internal", "confidential"}
+	}
+
+	if sensitivityTagPrefix == "" {
+		sensitivityTagPrefix = "sensitivity:"
+	}
+
+	if translationLanguage == "" {
+		translationLanguage = "en"
+	}
+	translationLanguage = strings.ToLower(translationLanguage)
+
+	if translationTarget == "" {
+		translationTarget = "note"
+	}
+	if translationTarget != "note" && translationTarget != "content" {
+		log.Fatal("Please set the TRANSLATION_TARGET environment variable to 'note' or 'content'.")
 	}
 
 	if paperlessBaseURL == "" {
 		log.Fatal("Please set the PAPERLESS_BASE_URL environment variable.")
 	}
 
-	if paperlessAPIToken == "" {
-		log.Fatal("Please set the PAPERLESS_API_TOKEN environment variable.")
+	if (paperlessUsername == "") != (paperlessPassword == "") {
+		log.Fatal("Please set both PAPERLESS_USERNAME and PAPERLESS_PASSWORD to authenticate with username and password.")
+	}
+	if paperlessAuthHeader != "" && paperlessAuthHeaderValue == "" {
+		log.Fatal("Please set PAPERLESS_AUTH_HEADER_VALUE to the user sent in the PAPERLESS_AUTH_HEADER header.")
+	}
+	if paperlessAPIToken == "" && paperlessAuthHeader == "" && paperlessSessionCookie == "" && paperlessUsername == "" {
+		log.Fatal("Please set the PAPERLESS_API_TOKEN environment variable, or configure PAPERLESS_AUTH_HEADER, PAPERLESS_SESSION_COOKIE or PAPERLESS_USERNAME and PAPERLESS_PASSWORD.")
 	}
 
-	if llmProvider == "" {
+	if settings.LLMProvider == "" {
 		log.Fatal("Please set the LLM_PROVIDER environment variable.")
 	}
 
 	if visionLlmProvider != "" && visionLlmProvider != "openai" && visionLlmProvider != "ollama" {
 		log.Fatal("Please set the VISION_LLM_PROVIDER environment variable to 'openai' or 'ollama'.")
 	}
-	if llmProvider != "openai" && llmProvider != "ollama" && llmProvider != "googleai" {
+	if settings.LLMProvider != "openai" && settings.LLMProvider != "ollama" && settings.LLMProvider != "googleai" {
 		log.Fatal("Please set the LLM_PROVIDER environment variable to 'openai', 'ollama', or 'googleai'.")
 	}
 
@@ -435,24 +1097,459 @@ func validateOrDefaultEnvVars() {
 		}
 	}
 
-	if llmModel == "" {
+	if settings.LLMModel == "" {
 		log.Fatal("Please set the LLM_MODEL environment variable.")
 	}
 
-	if (llmProvider == "openai" || visionLlmProvider == "openai") && openaiAPIKey == "" {
+	if (settings.LLMProvider == "openai" || visionLlmProvider == "openai") && openaiAPIKey == "" {
 		log.Fatal("Please set the OPENAI_API_KEY environment variable for OpenAI provider.")
 	}
 
+	// Initialize summary length from environment variable
+	if maxWords := os.Getenv("SUMMARY_MAX_WORDS"); maxWords != "" {
+		parsed, err := strconv.Atoi(maxWords)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("SUMMARY_MAX_WORDS must be a positive number, got: %s", maxWords)
+		}
+		summaryMaxWords = parsed
+	}
+
+	// Initialize due soon window from environment variable
+	if days := os.Getenv("DUE_SOON_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			log.Fatalf("DUE_SOON_DAYS must be a non-negative number, got: %s", days)
+		}
+		dueSoonDays = parsed
+	}
+
+	// Initialize cancellation horizon from environment variable
+	if days := os.Getenv("CANCELLATION_HORIZON_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			log.Fatalf("CANCELLATION_HORIZON_DAYS must be a non-negative number, got: %s", days)
+		}
+		cancellationHorizonDays = parsed
+	}
+
+	// Initialize related documents settings from environment variables
+	if max := os.Getenv("RELATED_DOCUMENTS_MAX"); max != "" {
+		parsed, err := strconv.Atoi(max)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("RELATED_DOCUMENTS_MAX must be a positive number, got: %s", max)
+		}
+		relatedDocumentsMax = parsed
+	}
+	// Initialize the rendering of PDF pages from environment variables
+	if dpi := os.Getenv("PDF_RENDER_DPI"); dpi != "" {
+		parsed, err := strconv.Atoi(dpi)
+		if err != nil || parsed < 72 {
+			log.Fatalf("PDF_RENDER_DPI must be a number of at least 72, got: %s", dpi)
+		}
+		settings.PDFRenderDPI = parsed
+	}
+	if colorMode := os.Getenv("PDF_RENDER_COLOR_MODE"); colorMode != "" {
+		if !slices.Contains(pdfColorModes, colorMode) {
+			log.Fatalf("PDF_RENDER_COLOR_MODE must be one of %s, got: %s", strings.Join(pdfColorModes, ", "), colorMode)
+		}
+		settings.PDFRenderColorMode = colorMode
+	}
+	settings.PDFRenderMaxDimension, settings.PDFRenderJPEGQuality = defaultPDFRenderLimits(ocrProviderType())
+	if dimension := os.Getenv("PDF_RENDER_MAX_DIMENSION"); dimension != "" {
+		parsed, err := strconv.Atoi(dimension)
+		if err != nil || parsed < 0 {
+			log.Fatalf("PDF_RENDER_MAX_DIMENSION must be a non-negative number, got: %s", dimension)
+		}
+		settings.PDFRenderMaxDimension = parsed
+	}
+	if quality := os.Getenv("PDF_RENDER_JPEG_QUALITY"); quality != "" {
+		parsed, err := strconv.Atoi(quality)
+		if err != nil || parsed < 1 || parsed > 100 {
+			log.Fatalf("PDF_RENDER_JPEG_QUALITY must be a number between 1 and 100, got: %s", quality)
+		}
+		settings.PDFRenderJPEGQuality = parsed
+	}
+
+	if confidence := os.Getenv("AUTO_APPLY_CONFIDENCE"); confidence != "" {
+		parsed, err := strconv.ParseFloat(confidence, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			log.Fatalf("AUTO_APPLY_CONFIDENCE must be a number between 0 and 1, got: %s", confidence)
+		}
+		settings.AutoApplyConfidence = parsed
+	}
+	if similarity := os.Getenv("RELATED_DOCUMENTS_MIN_SIMILARITY"); similarity != "" {
+		parsed, err := strconv.ParseFloat(similarity, 64)
+		if err != nil || parsed <= 0 || parsed > 1 {
+			log.Fatalf("RELATED_DOCUMENTS_MIN_SIMILARITY must be a number between 0 and 1, got: %s", similarity)
+		}
+		relatedDocumentsMinSimilarity = parsed
+	}
+
+	// Initialize the page size of document listings from environment variable
+	if size := os.Getenv("PAPERLESS_PAGE_SIZE"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("PAPERLESS_PAGE_SIZE must be a positive number, got: %s", size)
+		}
+		paperlessPageSize = parsed
+	}
+
+	// Initialize rate limiting, retries and circuit breaking of paperless-ngx requests from environment variables
+	if limit := os.Getenv("PAPERLESS_RATE_LIMIT"); limit != "" {
+		parsed, err := strconv.ParseFloat(limit, 64)
+		if err != nil || parsed < 0 {
+			log.Fatalf("PAPERLESS_RATE_LIMIT must be a non-negative number, got: %s", limit)
+		}
+		paperlessRateLimit = parsed
+	}
+	if retries := os.Getenv("PAPERLESS_MAX_RETRIES"); retries != "" {
+		parsed, err := strconv.Atoi(retries)
+		if err != nil || parsed < 0 {
+			log.Fatalf("PAPERLESS_MAX_RETRIES must be a non-negative number, got: %s", retries)
+		}
+		paperlessMaxRetries = parsed
+	}
+	if size := os.Getenv("PAPERLESS_BULK_EDIT_BATCH_SIZE"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed < 0 {
+			log.Fatalf("PAPERLESS_BULK_EDIT_BATCH_SIZE must be a non-negative number, got: %s", size)
+		}
+		bulkEditBatchSize = parsed
+	}
+	if threshold := os.Getenv("PAPERLESS_CIRCUIT_BREAKER_THRESHOLD"); threshold != "" {
+		parsed, err := strconv.Atoi(threshold)
+		if err != nil || parsed < 0 {
+			log.Fatalf("PAPERLESS_CIRCUIT_BREAKER_THRESHOLD must be a non-negative number, got: %s", threshold)
+		}
+		circuitBreakerThreshold = parsed
+	}
+	if cooldown := os.Getenv("PAPERLESS_CIRCUIT_BREAKER_COOLDOWN_SECONDS"); cooldown != "" {
+		parsed, err := strconv.Atoi(cooldown)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("PAPERLESS_CIRCUIT_BREAKER_COOLDOWN_SECONDS must be a positive number, got: %s", cooldown)
+		}
+		circuitBreakerCooldown = parsed
+	}
+
+	// Initialize rate limiting of API clients and the cap of concurrent suggestion requests
+	if cost := os.Getenv("LLM_PROMPT_TOKEN_COST"); cost != "" {
+		parsed, err := strconv.ParseFloat(cost, 64)
+		if err != nil || parsed < 0 {
+			log.Fatalf("LLM_PROMPT_TOKEN_COST must be a non-negative number, got: %s", cost)
+		}
+		llmPromptTokenCost = parsed
+	}
+
+	if cost := os.Getenv("LLM_COMPLETION_TOKEN_COST"); cost != "" {
+		parsed, err := strconv.ParseFloat(cost, 64)
+		if err != nil || parsed < 0 {
+			log.Fatalf("LLM_COMPLETION_TOKEN_COST must be a non-negative number, got: %s", cost)
+		}
+		llmCompletionTokenCost = parsed
+	}
+
+	if limit := os.Getenv("API_RATE_LIMIT"); limit != "" {
+		parsed, err := strconv.ParseFloat(limit, 64)
+		if err != nil || parsed < 0 {
+			log.Fatalf("API_RATE_LIMIT must be a non-negative number, got: %s", limit)
+		}
+		apiRateLimit = parsed
+	}
+	if burst := os.Getenv("API_RATE_LIMIT_BURST"); burst != "" {
+		parsed, err := strconv.Atoi(burst)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("API_RATE_LIMIT_BURST must be a positive number, got: %s", burst)
+		}
+		apiRateLimitBurst = parsed
+	}
+	if limit := os.Getenv("SUGGESTIONS_CONCURRENCY_LIMIT"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			log.Fatalf("SUGGESTIONS_CONCURRENCY_LIMIT must be a non-negative number, got: %s", limit)
+		}
+		suggestionsConcurrencyLimit = parsed
+	}
+
+	if retries := os.Getenv("VERIFY_DOCUMENT_UPDATE_RETRIES"); retries != "" {
+		parsed, err := strconv.Atoi(retries)
+		if err != nil || parsed < 0 {
+			log.Fatalf("VERIFY_DOCUMENT_UPDATE_RETRIES must be a non-negative number, got: %s", retries)
+		}
+		verifyUpdateRetries = parsed
+	}
+
+	if concurrency := os.Getenv("AUTO_PROCESSING_CONCURRENCY"); concurrency != "" {
+		parsed, err := strconv.Atoi(concurrency)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("AUTO_PROCESSING_CONCURRENCY must be a positive number, got: %s", concurrency)
+		}
+		settings.AutoProcessingConcurrency = parsed
+	}
+	if limit := os.Getenv("LLM_CONCURRENCY_LIMIT"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			log.Fatalf("LLM_CONCURRENCY_LIMIT must be a non-negative number, got: %s", limit)
+		}
+		settings.LLMConcurrencyLimit = parsed
+	}
+	if limit := os.Getenv("OCR_CONCURRENCY_LIMIT"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 0 {
+			log.Fatalf("OCR_CONCURRENCY_LIMIT must be a non-negative number, got: %s", limit)
+		}
+		ocrConcurrencyLimit = parsed
+	}
+
+	if attempts := os.Getenv("JOB_MAX_ATTEMPTS"); attempts != "" {
+		parsed, err := strconv.Atoi(attempts)
+		if err != nil || parsed <= 0 {
+			log.Fatalf("JOB_MAX_ATTEMPTS must be a positive number, got: %s", attempts)
+		}
+		settings.JobMaxAttempts = parsed
+	}
+
+	if failures := os.Getenv("MAX_DOCUMENT_FAILURES"); failures != "" {
+		parsed, err := strconv.Atoi(failures)
+		if err != nil || parsed < 0 {
+			log.Fatalf("MAX_DOCUMENT_FAILURES must be a non-negative number, got: %s", failures)
+		}
+		settings.MaxDocumentFailures = parsed
+	}
+
+	if days := os.Getenv("HISTORY_RETENTION_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			log.Fatalf("HISTORY_RETENTION_DAYS must be a non-negative number, got: %s", days)
+		}
+		settings.HistoryRetentionDays = parsed
+	}
+
+	if entries := os.Getenv("HISTORY_MAX_ENTRIES"); entries != "" {
+		parsed, err := strconv.Atoi(entries)
+		if err != nil || parsed < 0 {
+			log.Fatalf("HISTORY_MAX_ENTRIES must be a non-negative number, got: %s", entries)
+		}
+		settings.HistoryMaxEntries = parsed
+	}
+
+	if examples := os.Getenv("FEW_SHOT_EXAMPLES"); examples != "" {
+		parsed, err := strconv.Atoi(examples)
+		if err != nil || parsed < 0 {
+			log.Fatalf("FEW_SHOT_EXAMPLES must be a non-negative number, got: %s", examples)
+		}
+		settings.FewShotExamples = parsed
+	}
+
+	if hours := os.Getenv("BACKUP_INTERVAL_HOURS"); hours != "" {
+		parsed, err := strconv.Atoi(hours)
+		if err != nil || parsed < 0 {
+			log.Fatalf("BACKUP_INTERVAL_HOURS must be a non-negative number, got: %s", hours)
+		}
+		settings.BackupIntervalHours = parsed
+	}
+	if settings.BackupIntervalHours > 0 && backupPath == "" {
+		log.Fatalf("BACKUP_INTERVAL_HOURS requires BACKUP_PATH")
+	}
+
+	if keep := os.Getenv("BACKUP_KEEP"); keep != "" {
+		parsed, err := strconv.Atoi(keep)
+		if err != nil || parsed < 0 {
+			log.Fatalf("BACKUP_KEEP must be a non-negative number, got: %s", keep)
+		}
+		settings.BackupKeep = parsed
+	}
+
+	if size := os.Getenv("LOG_FILE_MAX_SIZE_MB"); size != "" {
+		parsed, err := strconv.Atoi(size)
+		if err != nil || parsed < 1 {
+			log.Fatalf("LOG_FILE_MAX_SIZE_MB must be a positive number, got: %s", size)
+		}
+		logFileMaxSizeMB = parsed
+	}
+
+	if days := os.Getenv("LOG_FILE_MAX_AGE_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			log.Fatalf("LOG_FILE_MAX_AGE_DAYS must be a non-negative number, got: %s", days)
+		}
+		logFileMaxAgeDays = parsed
+	}
+
+	if backups := os.Getenv("LOG_FILE_MAX_BACKUPS"); backups != "" {
+		parsed, err := strconv.Atoi(backups)
+		if err != nil || parsed < 0 {
+			log.Fatalf("LOG_FILE_MAX_BACKUPS must be a non-negative number, got: %s", backups)
+		}
+		logFileMaxBackups = parsed
+	}
+
+	types, err := validateNotifyEvents(notifyEvents)
+	if err != nil {
+		log.Fatalf("Invalid NOTIFY_EVENTS: %v", err)
+	}
+	notifyEvents = types
+	if gotifyURL != "" && gotifyToken == "" {
+		log.Fatal("GOTIFY_URL requires GOTIFY_TOKEN, the token of a Gotify application.")
+	}
+	if appriseTargets != "" && appriseURL == "" {
+		log.Fatal("APPRISE_TARGETS requires APPRISE_URL, the notify endpoint of an Apprise API server.")
+	}
+	if slackNotifyEvents, err = parseNotifyEvents(slackNotifyEvents, defaultWebhookNotificationEvents); err != nil {
+		log.Fatalf("Invalid SLACK_NOTIFY_EVENTS: %v", err)
+	}
+	if discordNotifyEvents, err = parseNotifyEvents(discordNotifyEvents, defaultWebhookNotificationEvents); err != nil {
+		log.Fatalf("Invalid DISCORD_NOTIFY_EVENTS: %v", err)
+	}
+
+	if port := os.Getenv("SMTP_PORT"); port != "" {
+		parsed, err := strconv.Atoi(port)
+		if err != nil || parsed < 1 || parsed > 65535 {
+			log.Fatalf("SMTP_PORT must be a port number, got: %s", port)
+		}
+		smtpPort = parsed
+	}
+	if smtpFrom == "" {
+		smtpFrom = smtpUsername
+	}
+	if smtpHost != "" {
+		if len(smtpTo) == 0 {
+			log.Fatal("SMTP_HOST requires SMTP_TO, the addresses emails are sent to.")
+		}
+		for _, address := range append([]string{smtpFrom}, smtpTo...) {
+			if _, err := mail.ParseAddress(address); err != nil {
+				log.Fatalf("Invalid email address %q in SMTP_FROM or SMTP_TO: %v", address, err)
+			}
+		}
+	}
+	if emailNotifyEvents, err = parseNotifyEvents(emailNotifyEvents, defaultEmailNotificationEvents); err != nil {
+		log.Fatalf("Invalid EMAIL_NOTIFY_EVENTS: %v", err)
+	}
+	if emailDigest != "" {
+		if emailDigest != emailDigestDaily && emailDigest != emailDigestWeekly {
+			log.Fatalf("EMAIL_DIGEST must be daily or weekly, got: %s", emailDigest)
+		}
+		if smtpHost == "" {
+			log.Fatal("EMAIL_DIGEST requires SMTP_HOST, the mail server the digest is sent with.")
+		}
+	}
+	if hour := os.Getenv("EMAIL_DIGEST_HOUR"); hour != "" {
+		parsed, err := strconv.Atoi(hour)
+		if err != nil || parsed < 0 || parsed > 23 {
+			log.Fatalf("EMAIL_DIGEST_HOUR must be an hour from 0 to 23, got: %s", hour)
+		}
+		emailDigestHour = parsed
+	}
+
+	if days := os.Getenv("DOCUMENT_LOG_RETENTION_DAYS"); days != "" {
+		parsed, err := strconv.Atoi(days)
+		if err != nil || parsed < 0 {
+			log.Fatalf("DOCUMENT_LOG_RETENTION_DAYS must be a non-negative number, got: %s", days)
+		}
+		settings.DocumentLogRetentionDays = parsed
+	}
+
+	if interval := os.Getenv("POLL_INTERVAL"); interval != "" {
+		parsed, err := parsePollInterval(interval)
+		if err != nil {
+			log.Fatalf("Invalid POLL_INTERVAL: %v", err)
+		}
+		backgroundPollInterval = parsed
+	}
+	ocrPollInterval = backgroundPollInterval
+	if interval := os.Getenv("OCR_POLL_INTERVAL"); interval != "" {
+		parsed, err := parsePollInterval(interval)
+		if err != nil {
+			log.Fatalf("Invalid OCR_POLL_INTERVAL: %v", err)
+		}
+		ocrPollInterval = parsed
+	}
+
+	if processingScheduleSpec != "" {
+		schedule, err := parseCronSchedule(processingScheduleSpec)
+		if err != nil {
+			log.Fatalf("Invalid PROCESSING_SCHEDULE: %v", err)
+		}
+		processingSchedule = schedule
+	}
+	if quietHoursSpec != "" {
+		quietHours, err := parseQuietHours(quietHoursSpec)
+		if err != nil {
+			log.Fatalf("Invalid QUIET_HOURS: %v", err)
+		}
+		processingQuietHours = quietHours
+	}
+	if processDocumentsAddedAfter != "" {
+		cutoff, err := parseProcessingCutoff(processDocumentsAddedAfter)
+		if err != nil {
+			log.Fatalf("Invalid PROCESS_DOCUMENTS_ADDED_AFTER: %v", err)
+		}
+		autoProcessingCutoff = cutoff
+	}
+	if mode := os.Getenv("LISTEN_SOCKET_MODE"); mode != "" {
+		parsed, err := parseSocketMode(mode)
+		if err != nil {
+			log.Fatalf("Invalid LISTEN_SOCKET_MODE: %v", err)
+		}
+		listenSocketMode = parsed
+	}
+	if basePathSpec != "" {
+		normalized, err := normalizeBasePath(basePathSpec)
+		if err != nil {
+			log.Fatalf("Invalid BASE_PATH: %v", err)
+		}
+		basePath = normalized
+	}
+	if apiKeysSpec != "" {
+		keys, err := parseAPIKeys(apiKeysSpec)
+		if err != nil {
+			log.Fatalf("Invalid API_KEYS: %v", err)
+		}
+		apiKeys = keys
+	}
+	if oidcIssuerURL != "" && (oidcClientID == "" || oidcRedirectURL == "") {
+		log.Fatal("OIDC_ISSUER_URL requires OIDC_CLIENT_ID and OIDC_REDIRECT_URL to be set.")
+	}
+	if len(oidcScopes) == 0 {
+		oidcScopes = []string{"openid", "profile", "email"}
+	}
+
+	tlsConfig, err := newPaperlessTLSConfig(paperlessCACert, paperlessClientCert, paperlessClientKey, paperlessInsecureSkipVerify)
+	if err != nil {
+		log.Fatalf("Invalid TLS settings for paperless-ngx: %v", err)
+	}
+	paperlessTLSConfig = tlsConfig
+
+	if newTagMatchingAlgorithm == "" {
+		newTagMatchingAlgorithm = "none"
+	}
+	if _, ok := tagMatchingAlgorithms[newTagMatchingAlgorithm]; !ok {
+		log.Fatalf("NEW_TAG_MATCHING_ALGORITHM must be one of none, auto, got: %s", newTagMatchingAlgorithm)
+	}
+	for _, color := range newTagColors {
+		if !hexColorPattern.MatchString(color) {
+			log.Fatalf("NEW_TAG_COLORS must be a comma-separated list of hex colors like #a6cee3, got: %s", color)
+		}
+	}
+
 	// Initialize token limit from environment variable
 	if limit := os.Getenv("TOKEN_LIMIT"); limit != "" {
 		if parsed, err := strconv.Atoi(limit); err == nil {
 			if parsed < 0 {
 				log.Fatalf("TOKEN_LIMIT must be non-negative, got: %d", parsed)
 			}
-			tokenLimit = parsed
-			log.Infof("Using token limit: %d", tokenLimit)
+			settings.TokenLimit = parsed
+			log.Infof("Using token limit: %d", settings.TokenLimit)
+		}
+	}
+
+	if strategy := os.Getenv("TRUNCATION_STRATEGY"); strategy != "" {
+		if !slices.Contains(truncationStrategies, strategy) {
+			log.Fatalf("TRUNCATION_STRATEGY must be one of %s, got: %s", strings.Join(truncationStrategies, ", "), strategy)
 		}
+		settings.TruncationStrategy = strategy
 	}
+
+	currentSettings.Store(settings)
 }
 
 // documentLogger creates a logger with document context
@@ -471,113 +1568,93 @@ func removeTagFromList(tags []string, tagToRemove string) []string {
 	return filteredTags
 }
 
-// getLikelyLanguage determines the likely language of the document content
-func getLikelyLanguage() string {
-	likelyLanguage := os.Getenv("LLM_LANGUAGE")
-	if likelyLanguage == "" {
-		likelyLanguage = "English"
+// splitAndTrim splits a comma-separated list and removes surrounding whitespace and empty entries
+func splitAndTrim(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
 	}
-	return strings.Title(strings.ToLower(likelyLanguage))
+	return items
 }
 
-// loadTemplates loads the title and tag templates from files or uses default templates
+// getLikelyLanguage determines the likely language of the document content, the first language of LLM_LANGUAGE
+func getLikelyLanguage(ctx context.Context) string {
+	return expectedLanguages(ctx)[0].Name
+}
+
+// loadTemplates loads the prompt templates from files or uses default templates
 func loadTemplates() {
 	templateMutex.Lock()
 	defer templateMutex.Unlock()
 
 	// Ensure prompts directory exists
-	promptsDir := "prompts"
 	if err := os.MkdirAll(promptsDir, os.ModePerm); err != nil {
 		log.Fatalf("Failed to create prompts directory: %v", err)
 	}
 
-	// Load title template
-	titleTemplatePath := filepath.Join(promptsDir, "title_prompt.tmpl")
-	titleTemplateContent, err := os.ReadFile(titleTemplatePath)
-	if err != nil {
-		log.Errorf("Could not read %s, using default template: %v", titleTemplatePath, err)
-		titleTemplateContent = []byte(defaultTitleTemplate)
-		if err := os.WriteFile(titleTemplatePath, titleTemplateContent, os.ModePerm); err != nil {
-			log.Fatalf("Failed to write default title template to disk: %v", err)
-		}
-	}
-	titleTemplate, err = template.New("title").Funcs(sprig.FuncMap()).Parse(string(titleTemplateContent))
-	if err != nil {
-		log.Fatalf("Failed to parse title template: %v", err)
+	for _, prompt := range promptTemplates {
+		*prompt.target = loadTemplate(promptsDir, prompt.name, prompt.fileName, prompt.defaultContent)
 	}
 
-	// Load tag template
-	tagTemplatePath := filepath.Join(promptsDir, "tag_prompt.tmpl")
-	tagTemplateContent, err := os.ReadFile(tagTemplatePath)
+	// Templates of the presets selected with PROMPT_PRESETS replace the ones of the prompts directory
+	presetSelection, err := parsePromptPresets(promptPresetsSpec)
 	if err != nil {
-		log.Errorf("Could not read %s, using default template: %v", tagTemplatePath, err)
-		tagTemplateContent = []byte(defaultTagTemplate)
-		if err := os.WriteFile(tagTemplatePath, tagTemplateContent, os.ModePerm); err != nil {
-			log.Fatalf("Failed to write default tag template to disk: %v", err)
-		}
+		log.Fatalf("Invalid PROMPT_PRESETS: %v", err)
 	}
-	tagTemplate, err = template.New("tag").Funcs(sprig.FuncMap()).Parse(string(tagTemplateContent))
+	presetTemplates, err := loadPresetTemplates(presetSelection)
 	if err != nil {
-		log.Fatalf("Failed to parse tag template: %v", err)
+		log.Fatalf("Failed to load the prompt presets: %v", err)
 	}
-
-	// Load correspondent template
-	correspondentTemplatePath := filepath.Join(promptsDir, "correspondent_prompt.tmpl")
-	correspondentTemplateContent, err := os.ReadFile(correspondentTemplatePath)
-	if err != nil {
-		log.Errorf("Could not read %s, using default template: %v", correspondentTemplatePath, err)
-		correspondentTemplateContent = []byte(defaultCorrespondentTemplate)
-		if err := os.WriteFile(correspondentTemplatePath, correspondentTemplateContent, os.ModePerm); err != nil {
-			log.Fatalf("Failed to write default correspondent template to disk: %v", err)
+	for _, prompt := range promptTemplates {
+		if tmpl, ok := presetTemplates[prompt.name]; ok {
+			*prompt.target = tmpl
+			log.Infof("Using the %s prompt of the %s preset", prompt.name, presetSelection[prompt.name])
 		}
 	}
-	correspondentTemplate, err = template.New("correspondent").Funcs(sprig.FuncMap()).Parse(string(correspondentTemplateContent))
-	if err != nil {
-		log.Fatalf("Failed to parse correspondent template: %v", err)
-	}
 
-	// Load createdDate template
-	createdDateTemplatePath := filepath.Join(promptsDir, "created_date_prompt.tmpl")
-	createdDateTemplateContent, err := os.ReadFile(createdDateTemplatePath)
+	// Templates of prompts/<language>/ replace the global ones for documents in the language
+	languageTemplates, err := loadLanguageTemplates()
 	if err != nil {
-		log.Errorf("Could not read %s, using default template: %v", createdDateTemplatePath, err)
-		createdDateTemplateContent = []byte(defaultCreatedDateTemplate)
-		if err := os.WriteFile(createdDateTemplatePath, createdDateTemplateContent, os.ModePerm); err != nil {
-			log.Fatalf("Failed to write default date template to disk: %v", err)
-		}
+		log.Fatalf("Failed to load the prompt templates by language: %v", err)
 	}
-
-	createdDateTemplate, err = template.New("created_date").Funcs(sprig.FuncMap()).Parse(string(createdDateTemplateContent))
-	if err != nil {
-		log.Fatalf("Failed to parse createdDate template: %v", err)
+	languagePromptTemplates = languageTemplates
+	if files, _ := languageTemplateFiles(languageTemplates); len(files) > 0 {
+		log.Infof("Loaded prompt templates by language: %s", strings.Join(files, ", "))
 	}
+}
 
-	// Load OCR template
-	ocrTemplatePath := filepath.Join(promptsDir, "ocr_prompt.tmpl")
-	ocrTemplateContent, err := os.ReadFile(ocrTemplatePath)
+// loadTemplate reads a single template from the prompts directory.
+// If the file does not exist, the default template is written to disk and used instead.
+func loadTemplate(promptsDir, name, fileName, defaultContent string) *template.Template {
+	templatePath := filepath.Join(promptsDir, fileName)
+	templateContent, err := os.ReadFile(templatePath)
 	if err != nil {
-		log.Errorf("Could not read %s, using default template: %v", ocrTemplatePath, err)
-		ocrTemplateContent = []byte(defaultOcrPrompt)
-		if err := os.WriteFile(ocrTemplatePath, ocrTemplateContent, os.ModePerm); err != nil {
-			log.Fatalf("Failed to write default OCR template to disk: %v", err)
+		log.Errorf("Could not read %s, using default template: %v", templatePath, err)
+		templateContent = []byte(defaultContent)
+		if err := os.WriteFile(templatePath, templateContent, os.ModePerm); err != nil {
+			log.Fatalf("Failed to write default %s template to disk: %v", name, err)
 		}
 	}
-	ocrTemplate, err = template.New("ocr").Funcs(sprig.FuncMap()).Parse(string(ocrTemplateContent))
+	tmpl, err := template.New(name).Funcs(sprig.FuncMap()).Parse(string(templateContent))
 	if err != nil {
-		log.Fatalf("Failed to parse OCR template: %v", err)
+		log.Fatalf("Failed to parse %s template: %v", name, err)
 	}
+	return tmpl
 }
 
 // createLLM creates the appropriate LLM client based on the provider
-func createLLM() (llms.Model, error) {
-	switch strings.ToLower(llmProvider) {
+func createLLM(settings *runtimeSettings) (llms.Model, error) {
+	switch strings.ToLower(settings.LLMProvider) {
 	case "openai":
 		if openaiAPIKey == "" {
 			return nil, fmt.Errorf("OpenAI API key is not set")
 		}
 
 		return openai.New(
-			openai.WithModel(llmModel),
+			openai.WithModel(settings.LLMModel),
 			openai.WithToken(openaiAPIKey),
 			openai.WithHTTPClient(createCustomHTTPClient()),
 		)
@@ -587,12 +1664,12 @@ func createLLM() (llms.Model, error) {
 			host = "http://127.0.0.1:11434"
 		}
 		return ollama.New(
-			ollama.WithModel(llmModel),
+			ollama.WithModel(settings.LLMModel),
 			ollama.WithServerURL(host),
 		)
 	case "googleai":
 		ctx := context.Background()
-		apiKey := os.Getenv("GOOGLEAI_API_KEY")
+		apiKey := secretEnv("GOOGLEAI_API_KEY")
 		var thinkingBudget *int32
 		if val, ok := os.LookupEnv("GOOGLEAI_THINKING_BUDGET"); ok {
 			if v, err := strconv.Atoi(val); err == nil {
@@ -600,14 +1677,50 @@ func createLLM() (llms.Model, error) {
 				thinkingBudget = &b
 			}
 		}
-		provider, err := NewGoogleAIProvider(ctx, llmModel, apiKey, thinkingBudget)
+		provider, err := NewGoogleAIProvider(ctx, settings.LLMModel, apiKey, thinkingBudget)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create GoogleAI provider: %w", err)
 		}
 		return provider, nil
 	default:
-		return nil, fmt.Errorf("unsupported LLM provider: %s (supported: openai, ollama, googleai)", llmProvider)
+		return nil, fmt.Errorf("unsupported LLM provider: %s (supported: openai, ollama, googleai)", settings.LLMProvider)
+	}
+}
+
+// createEmbedder creates the client for EMBEDDING_MODEL using the LLM provider. Embeddings are disabled without a model.
+func createEmbedder() (embedder, error) {
+	if embeddingModel == "" {
+		return nil, nil
+	}
+	provider := loadSettings().LLMProvider
+	switch strings.ToLower(provider) {
+	case "openai":
+		return openai.New(
+			openai.WithEmbeddingModel(embeddingModel),
+			openai.WithToken(openaiAPIKey),
+			openai.WithHTTPClient(createCustomHTTPClient()),
+		)
+	case "ollama":
+		host := os.Getenv("OLLAMA_HOST")
+		if host == "" {
+			host = "http://127.0.0.1:11434"
+		}
+		return ollama.New(
+			ollama.WithModel(embeddingModel),
+			ollama.WithServerURL(host),
+		)
+	default:
+		return nil, fmt.Errorf("embeddings are not supported for LLM provider: %s (supported: openai, ollama)", provider)
+	}
+}
+
+// paperlessPublicURL returns the URL of the paperless-ngx web interface as seen by users
+func paperlessPublicURL() string {
+	baseUrl := os.Getenv("PAPERLESS_PUBLIC_URL")
+	if baseUrl == "" {
+		baseUrl = os.Getenv("PAPERLESS_BASE_URL")
 	}
+	return strings.TrimRight(baseUrl, "/")
 }
 
 func createVisionLLM() (llms.Model, error) {
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned change of the schema of the local database. Migrations are applied in the order of
+// schemaMigrations and never change once released: a later schema change is a new migration. Migrations use their own
+// copies of the models, so they keep working when the models change.
+type Migration struct {
+	ID          string                  // Unique, sortable ID of the migration
+	Description string                  // What the migration changes
+	Migrate     func(tx *gorm.DB) error // Applies the change
+	Rollback    func(tx *gorm.DB) error // Reverts the change, for downgrading to a release without the migration
+}
+
+// SchemaMigration records a migration applied to the local database
+type SchemaMigration struct {
+	ID        string `gorm:"primaryKey;size:255"` // ID of the applied migration
+	AppliedAt string `gorm:"not null"`            // Date and time the migration was applied
+}
+
+// MigrationStatus is a migration and whether it is applied to the local database, returned by GET /api/migrations
+type MigrationStatus struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Applied     bool   `json:"applied"`
+	AppliedAt   string `json:"applied_at,omitempty"`
+}
+
+// databaseModels returns the models of the current schema, the tables of a new database are created from them
+func databaseModels() []interface{} {
+	return []interface{}{&ModificationHistory{}, &PendingTag{}, &PIIReport{}, &DocumentIndex{}, &ProcessedDocument{}, &OrphanedDocument{}, &Job{}, &PendingReview{}, &DocumentFailure{}, &BackfillCheckpoint{}, &DocumentFingerprint{}, &LLMUsage{}, &SuggestionFeedback{}, &APIToken{}, &OCRConfidence{}, &DocumentLog{}, &CreatedCorrespondent{}, &EmailDigest{}}
+}
+
+// schemaMigrations lists the migrations in the order they are applied
+var schemaMigrations = []Migration{
+	{
+		ID:          "0001_modification_batches",
+		Description: "Record the batch of modifications applied to a document together",
+		Migrate: func(tx *gorm.DB) error {
+			return addColumns(tx, &modificationBatchV1{}, "BatchID")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return dropColumns(tx, &modificationBatchV1{}, "BatchID")
+		},
+	},
+	{
+		ID:          "0002_modification_origins",
+		Description: "Record the modification an undo or redo reverses",
+		Migrate: func(tx *gorm.DB) error {
+			return addColumns(tx, &modificationOriginV1{}, "Operation", "OriginID")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return dropColumns(tx, &modificationOriginV1{}, "Operation", "OriginID")
+		},
+	},
+	{
+		ID:          "0003_document_snapshots",
+		Description: "Record the document before and after each modification",
+		Migrate: func(tx *gorm.DB) error {
+			return addColumns(tx, &modificationSnapshotV1{}, "DocumentBefore", "DocumentAfter")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return dropColumns(tx, &modificationSnapshotV1{}, "DocumentBefore", "DocumentAfter")
+		},
+	},
+	{
+		ID:          "0004_llm_usage_details",
+		Description: "Record the provider, prompt hash, document and modifications of LLM and OCR requests",
+		Migrate: func(tx *gorm.DB) error {
+			return addColumns(tx, &llmUsageDetailsV1{}, "Provider", "PromptHash", "DocumentID", "BatchID")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return dropColumns(tx, &llmUsageDetailsV1{}, "Provider", "PromptHash", "DocumentID", "BatchID")
+		},
+	},
+	{
+		ID:          "0005_modification_archive",
+		Description: "Archive modifications to hide them from the history",
+		Migrate: func(tx *gorm.DB) error {
+			return addColumns(tx, &modificationArchiveV1{}, "Archived", "ArchivedDate")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return dropColumns(tx, &modificationArchiveV1{}, "Archived", "ArchivedDate")
+		},
+	},
+	{
+		ID:          "0006_ocr_confidences",
+		Description: "Record the OCR confidence of documents for the prompts",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&ocrConfidenceV1{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&ocrConfidenceV1{})
+		},
+	},
+	{
+		ID:          "0007_prompt_experiments",
+		Description: "Record the prompt experiment and variant of LLM requests and feedback",
+		Migrate: func(tx *gorm.DB) error {
+			if err := addColumns(tx, &llmUsageExperimentV1{}, "Experiment", "Variant"); err != nil {
+				return err
+			}
+			return addColumns(tx, &feedbackExperimentV1{}, "Experiment", "Variant")
+		},
+		Rollback: func(tx *gorm.DB) error {
+			if err := dropColumns(tx, &feedbackExperimentV1{}, "Experiment", "Variant"); err != nil {
+				return err
+			}
+			return dropColumns(tx, &llmUsageExperimentV1{}, "Experiment", "Variant")
+		},
+	},
+	{
+		ID:          "0008_document_logs",
+		Description: "Record the log lines of documents",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&documentLogV1{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&documentLogV1{})
+		},
+	},
+	{
+		ID:          "0009_email_digests",
+		Description: "Record the created correspondents and the sent email digests",
+		Migrate: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&createdCorrespondentV1{}, &emailDigestV1{})
+		},
+		Rollback: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&createdCorrespondentV1{}, &emailDigestV1{})
+		},
+	},
+}
+
+// llmUsageDetailsV1 are the columns added to the LLM usage by 0004_llm_usage_details
+type llmUsageDetailsV1 struct {
+	Provider   string `gorm:"size:64"`
+	PromptHash string `gorm:"size:64"`
+	DocumentID uint   `gorm:"index"`
+	BatchID    string `gorm:"size:36;index"`
+}
+
+func (llmUsageDetailsV1) TableName() string { return "llm_usages" }
+
+// modificationArchiveV1 are the columns added to the modification history by 0005_modification_archive
+type modificationArchiveV1 struct {
+	Archived     bool   `gorm:"not null;default:false;index"`
+	ArchivedDate string `gorm:"default:null"`
+}
+
+func (modificationArchiveV1) TableName() string { return "modification_histories" }
+
+// ocrConfidenceV1 is the table of OCR confidences created by 0006_ocr_confidences
+type ocrConfidenceV1 struct {
+	ID         uint    `gorm:"primaryKey"`
+	DocumentID uint    `gorm:"not null;uniqueIndex"`
+	Confidence float64 `gorm:"not null"`
+	RecordedAt string  `gorm:"not null"`
+}
+
+func (ocrConfidenceV1) TableName() string { return "ocr_confidences" }
+
+// llmUsageExperimentV1 are the columns added to the LLM usage by 0007_prompt_experiments
+type llmUsageExperimentV1 struct {
+	Experiment string `gorm:"size:64;index"`
+	Variant    string `gorm:"size:8"`
+}
+
+func (llmUsageExperimentV1) TableName() string { return "llm_usages" }
+
+// feedbackExperimentV1 are the columns added to the suggestion feedback by 0007_prompt_experiments
+type feedbackExperimentV1 struct {
+	Experiment string `gorm:"size:64;index"`
+	Variant    string `gorm:"size:8"`
+}
+
+func (feedbackExperimentV1) TableName() string { return "suggestion_feedbacks" }
+
+// documentLogV1 is the table of document log lines created by 0008_document_logs
+type documentLogV1 struct {
+	ID         uint   `gorm:"primaryKey"`
+	DocumentID uint   `gorm:"not null;index"`
+	Level      string `gorm:"size:8;not null"`
+	Message    string `gorm:"size:1048576"`
+	Fields     string `gorm:"size:65536"`
+	LoggedAt   string `gorm:"not null;index"`
+}
+
+func (documentLogV1) TableName() string { return "document_logs" }
+
+// createdCorrespondentV1 is the table of created correspondents created by 0009_email_digests
+type createdCorrespondentV1 struct {
+	ID              uint   `gorm:"primaryKey"`
+	CorrespondentID uint   `gorm:"not null"`
+	Name            string `gorm:"size:255;not null"`
+	DocumentID      uint   `gorm:"not null;index"`
+	DateCreated     string `gorm:"not null;index"`
+}
+
+func (createdCorrespondentV1) TableName() string { return "created_correspondents" }
+
+// emailDigestV1 is the table of email digests created by 0009_email_digests
+type emailDigestV1 struct {
+	ID     uint   `gorm:"primaryKey"`
+	Period string `gorm:"size:8;not null"`
+	Since  string `gorm:"not null"`
+	Until  string `gorm:"not null;index"`
+	Sent   bool   `gorm:"not null;default:false"`
+	SentAt string `gorm:"not null"`
+}
+
+func (emailDigestV1) TableName() string { return "email_digests" }
+
+// modificationBatchV1 is the batch column added to the modification history by 0001_modification_batches
+type modificationBatchV1 struct {
+	BatchID string `gorm:"size:36;index"`
+}
+
+func (modificationBatchV1) TableName() string { return "modification_histories" }
+
+// modificationOriginV1 are the columns added to the modification history by 0002_modification_origins
+type modificationOriginV1 struct {
+	Operation string `gorm:"size:8"`
+	OriginID  uint   `gorm:"index"`
+}
+
+func (modificationOriginV1) TableName() string { return "modification_histories" }
+
+// modificationSnapshotV1 are the columns added to the modification history by 0003_document_snapshots
+type modificationSnapshotV1 struct {
+	DocumentBefore string `gorm:"size:4194304"`
+	DocumentAfter  string `gorm:"size:4194304"`
+}
+
+func (modificationSnapshotV1) TableName() string { return "modification_histories" }
+
+// addColumns adds the columns of the fields of a model, with their indexes
+func addColumns(tx *gorm.DB, model interface{}, fields ...string) error {
+	migrator := tx.Migrator()
+	for _, field := range fields {
+		if err := migrator.AddColumn(model, field); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", field, err)
+		}
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if err := migrator.CreateIndex(model, index.Name); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", index.Name, err)
+		}
+	}
+	return nil
+}
+
+// dropColumns drops the columns of the fields of a model, with their indexes
+func dropColumns(tx *gorm.DB, model interface{}, fields ...string) error {
+	migrator := tx.Migrator()
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return err
+	}
+	for _, index := range stmt.Schema.ParseIndexes() {
+		if migrator.HasIndex(model, index.Name) {
+			if err := migrator.DropIndex(model, index.Name); err != nil {
+				return fmt.Errorf("failed to drop index %s: %w", index.Name, err)
+			}
+		}
+	}
+	for _, field := range fields {
+		if err := migrator.DropColumn(model, field); err != nil {
+			return fmt.Errorf("failed to drop column %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// migrateSchema applies the pending migrations. A database without applied migrations is new or was created before
+// versioned migrations, its tables are created or completed from the current models and all migrations are recorded
+// as applied.
+func migrateSchema(db *gorm.DB) error {
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+	var applied []SchemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return err
+	}
+
+	if len(applied) == 0 {
+		return db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(databaseModels()...); err != nil {
+				return err
+			}
+			for _, migration := range schemaMigrations {
+				if err := recordMigration(tx, migration); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	appliedIDs := make(map[string]bool, len(applied))
+	for _, migration := range applied {
+		appliedIDs[migration.ID] = true
+	}
+	for _, migration := range schemaMigrations {
+		if appliedIDs[migration.ID] {
+			continue
+		}
+		log.Infof("Applying database migration %s", migration.ID)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Migrate(tx); err != nil {
+				return err
+			}
+			return recordMigration(tx, migration)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s: %w", migration.ID, err)
+		}
+	}
+	return nil
+}
+
+// recordMigration records a migration as applied
+func recordMigration(tx *gorm.DB, migration Migration) error {
+	return tx.Create(&SchemaMigration{ID: migration.ID, AppliedAt: time.Now().Format(time.RFC3339)}).Error
+}
+
+// rollbackMigrations reverts the last applied migrations, newest first, and returns the IDs of the reverted migrations
+func rollbackMigrations(db *gorm.DB, steps int) ([]string, error) {
+	var rolledBack []string
+	for i := len(schemaMigrations) - 1; i >= 0 && len(rolledBack) < steps; i-- {
+		migration := schemaMigrations[i]
+		var count int64
+		if err := db.Model(&SchemaMigration{}).Where("id = ?", migration.ID).Count(&count).Error; err != nil {
+			return rolledBack, err
+		}
+		if count == 0 {
+			continue
+		}
+
+		log.Infof("Rolling back database migration %s", migration.ID)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Rollback(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&SchemaMigration{ID: migration.ID}).Error
+		})
+		if err != nil {
+			return rolledBack, fmt.Errorf("rollback of migration %s: %w", migration.ID, err)
+		}
+		rolledBack = append(rolledBack, migration.ID)
+	}
+	return rolledBack, nil
+}
+
+// migrationStatus returns all migrations and whether they are applied
+func migrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	var applied []SchemaMigration
+	if err := db.Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	appliedAt := make(map[string]string, len(applied))
+	for _, migration := range applied {
+		appliedAt[migration.ID] = migration.AppliedAt
+	}
+
+	statuses := make([]MigrationStatus, 0, len(schemaMigrations))
+	for _, migration := range schemaMigrations {
+		date, ok := appliedAt[migration.ID]
+		statuses = append(statuses, MigrationStatus{
+			ID:          migration.ID,
+			Description: migration.Description,
+			Applied:     ok,
+			AppliedAt:   date,
+		})
+	}
+	return statuses, nil
+}
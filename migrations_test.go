@@ -0,0 +1,105 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// openMigrationTestDB opens an empty in-memory database of its own, not shared with the other tests
+func openMigrationTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestMigrateSchema(t *testing.T) {
+	db := openMigrationTestDB(t)
+	require.NoError(t, migrateSchema(db))
+
+	migrator := db.Migrator()
+	assert.True(t, migrator.HasColumn(&ModificationHistory{}, "DocumentBefore"))
+	assert.True(t, migrator.HasTable(&APIToken{}))
+
+	statuses, err := migrationStatus(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(schemaMigrations))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.ID)
+	}
+
+	// All but the first migration are rolled back, newest first
+	rolledBack, err := rollbackMigrations(db, len(schemaMigrations)-1)
+	require.NoError(t, err)
+	require.Len(t, rolledBack, len(schemaMigrations)-1)
+	assert.Equal(t, schemaMigrations[len(schemaMigrations)-1].ID, rolledBack[0])
+	assert.Equal(t, "0002_modification_origins", rolledBack[len(rolledBack)-1])
+	assert.False(t, migrator.HasColumn(&ModificationHistory{}, "DocumentBefore"))
+	assert.False(t, migrator.HasColumn(&ModificationHistory{}, "OriginID"))
+	assert.False(t, migrator.HasColumn(&LLMUsage{}, "DocumentID"))
+	assert.False(t, migrator.HasTable(&OCRConfidence{}))
+	assert.False(t, migrator.HasTable(&DocumentLog{}))
+	assert.False(t, migrator.HasTable(&EmailDigest{}))
+	assert.False(t, migrator.HasColumn(&SuggestionFeedback{}, "Experiment"))
+	assert.True(t, migrator.HasColumn(&ModificationHistory{}, "BatchID"))
+
+	statuses, err = migrationStatus(db)
+	require.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	for _, status := range statuses[1:] {
+		assert.False(t, status.Applied, status.ID)
+	}
+
+	// The rolled back migrations are applied again
+	require.NoError(t, migrateSchema(db))
+	assert.True(t, migrator.HasColumn(&ModificationHistory{}, "DocumentBefore"))
+	assert.True(t, migrator.HasIndex(&ModificationHistory{}, "OriginID"))
+	assert.True(t, migrator.HasIndex(&LLMUsage{}, "BatchID"))
+	assert.True(t, migrator.HasIndex(&OCRConfidence{}, "DocumentID"))
+	assert.True(t, migrator.HasIndex(&DocumentLog{}, "DocumentID"))
+	assert.True(t, migrator.HasIndex(&CreatedCorrespondent{}, "DateCreated"))
+	assert.True(t, migrator.HasIndex(&LLMUsage{}, "Experiment"))
+	require.NoError(t, InsertModification(db, &ModificationHistory{DocumentID: 1, ModField: "title", OriginID: 2, DocumentBefore: "{}"}))
+
+	rolledBack, err = rollbackMigrations(db, 10)
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(schemaMigrations), "only applied migrations are rolled back")
+}
+
+func TestMigrateSchema_DatabaseWithoutMigrations(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	// A database created by AutoMigrate before versioned migrations, without the later columns
+	type modificationHistory struct {
+		ID            uint   `gorm:"primaryKey"`
+		DocumentID    uint   `gorm:"not null"`
+		DateChanged   string `gorm:"not null"`
+		ModField      string `gorm:"size:255;not null"`
+		PreviousValue string `gorm:"size:1048576"`
+		NewValue      string `gorm:"size:1048576"`
+		Undone        bool   `gorm:"not null;default:false"`
+		UndoneDate    string `gorm:"default:null"`
+	}
+	require.NoError(t, db.Table("modification_histories").AutoMigrate(&modificationHistory{}))
+	require.NoError(t, db.Table("modification_histories").Create(&modificationHistory{DocumentID: 3, DateChanged: "2024-05-01T10:00:00Z", ModField: "title"}).Error)
+
+	require.NoError(t, migrateSchema(db))
+	assert.True(t, db.Migrator().HasColumn(&ModificationHistory{}, "BatchID"))
+	assert.True(t, db.Migrator().HasColumn(&ModificationHistory{}, "DocumentAfter"))
+
+	modifications, err := GetDocumentModifications(db, 3)
+	require.NoError(t, err)
+	assert.Len(t, modifications, 1, "existing records are kept")
+
+	statuses, err := migrationStatus(db)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.ID)
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// notificationEvents are the events notifications can be sent for with NOTIFY_EVENTS and EMAIL_NOTIFY_EVENTS
+var notificationEvents = []string{EventDocumentProcessed, EventOCRFinished, EventDocumentFailed, EventReviewQueued}
+
+// defaultNotificationEvents are notified without NOTIFY_EVENTS
+var defaultNotificationEvents = []string{EventDocumentFailed, EventReviewQueued}
+
+const (
+	// notificationQueueSize is the number of notifications waiting to be sent, further ones are dropped
+	notificationQueueSize = 100
+	// notificationTimeout limits how long sending a notification to one service may take
+	notificationTimeout = 10 * time.Second
+)
+
+// Notification is a notification sent to ntfy, Gotify, Apprise or by email
+type Notification struct {
+	Event   string // Type of the event the notification is sent for
+	Title   string
+	Message string
+	Tags    []string // ntfy tags, shown as emojis, e.g. warning
+	Click   string   // URL opened when the notification is clicked, empty for none
+	Urgent  bool     // Sent with high priority, for failures
+
+	// Document is the document the notification is about, shown by the services with rich messages. Nil for the
+	// review queue.
+	Document *NotificationDocument
+}
+
+// NotificationDocument is a document as shown in rich messages, the fields besides the ID may be empty
+type NotificationDocument struct {
+	ID            int
+	Title         string
+	Correspondent string
+	Tags          []string
+}
+
+// NotificationSender sends notifications to a service
+type NotificationSender interface {
+	Name() string
+	Send(ctx context.Context, notification Notification) error
+}
+
+// notificationTarget is a service notifications are sent to, with the events it is notified of
+type notificationTarget struct {
+	sender NotificationSender
+	types  []string
+}
+
+// notificationTargets returns the services configured with NTFY_URL, GOTIFY_URL, APPRISE_URL, SLACK_WEBHOOK_URL,
+// DISCORD_WEBHOOK_URL and SMTP_HOST
+func notificationTargets(app *App) []notificationTarget {
+	client := &http.Client{Timeout: notificationTimeout}
+	var targets []notificationTarget
+	if ntfyURL != "" {
+		targets = append(targets, notificationTarget{&ntfySender{client: client, url: ntfyURL, token: ntfyToken}, notifyEvents})
+	}
+	if gotifyURL != "" {
+		targets = append(targets, notificationTarget{&gotifySender{client: client, url: strings.TrimSuffix(gotifyURL, "/"), token: gotifyToken}, notifyEvents})
+	}
+	if appriseURL != "" {
+		targets = append(targets, notificationTarget{&appriseSender{client: client, url: appriseURL, targets: appriseTargets}, notifyEvents})
+	}
+	if slackWebhookURL != "" && len(slackNotifyEvents) > 0 {
+		targets = append(targets, notificationTarget{&slackSender{client: client, url: slackWebhookURL}, slackNotifyEvents})
+	}
+	if discordWebhookURL != "" && len(discordNotifyEvents) > 0 {
+		sender := &discordSender{client: client, url: discordWebhookURL}
+		if app.Client != nil {
+			sender.thumbnail = app.Client.GetThumbnail
+		}
+		targets = append(targets, notificationTarget{sender, discordNotifyEvents})
+	}
+	if smtpHost != "" && len(emailNotifyEvents) > 0 {
+		targets = append(targets, notificationTarget{&smtpSender{config: smtpConfigFromEnv()}, emailNotifyEvents})
+	}
+	return targets
+}
+
+// ntfySender publishes notifications to a topic of ntfy, NTFY_URL is the URL of the topic
+type ntfySender struct {
+	client *http.Client
+	url    string
+	token  string // Access token, empty for public topics
+}
+
+func (sender *ntfySender) Name() string { return "ntfy" }
+
+func (sender *ntfySender) Send(ctx context.Context, notification Notification) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url, strings.NewReader(notification.Message))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Title", notification.Title)
+	if len(notification.Tags) > 0 {
+		request.Header.Set("Tags", strings.Join(notification.Tags, ","))
+	}
+	if notification.Click != "" {
+		request.Header.Set("Click", notification.Click)
+	}
+	if notification.Urgent {
+		request.Header.Set("Priority", "high")
+	}
+	if sender.token != "" {
+		request.Header.Set("Authorization", "Bearer "+sender.token)
+	}
+	return sendNotificationRequest(sender.client, request)
+}
+
+// gotifySender sends notifications to a Gotify server, GOTIFY_URL is the URL of the server and GOTIFY_TOKEN the token
+// of an application
+type gotifySender struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+func (sender *gotifySender) Name() string { return "gotify" }
+
+func (sender *gotifySender) Send(ctx context.Context, notification Notification) error {
+	message := map[string]any{"title": notification.Title, "message": notification.Message, "priority": 5}
+	if notification.Urgent {
+		message["priority"] = 8
+	}
+	if notification.Click != "" {
+		message["extras"] = map[string]any{"client::notification": map[string]any{"click": map[string]string{"url": notification.Click}}}
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url+"/message", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Gotify-Key", sender.token)
+	return sendNotificationRequest(sender.client, request)
+}
+
+// appriseSender sends notifications to an Apprise API server, which fans them out to the services it is configured
+// for. APPRISE_URL is the notify endpoint, either /notify/{key} of a stored configuration or /notify with the Apprise
+// URLs of APPRISE_TARGETS.
+type appriseSender struct {
+	client  *http.Client
+	url     string
+	targets string // Apprise URLs like tgram://bottoken/ChatID, sent with each notification, empty for stored configurations
+}
+
+func (sender *appriseSender) Name() string { return "apprise" }
+
+func (sender *appriseSender) Send(ctx context.Context, notification Notification) error {
+	body := notification.Message
+	if notification.Click != "" {
+		body += "\n\n" + notification.Click
+	}
+	message := map[string]any{"title": notification.Title, "body": body, "type": "info", "format": "text"}
+	if notification.Urgent {
+		message["type"] = "failure"
+	}
+	if sender.targets != "" {
+		message["urls"] = sender.targets
+	}
+	encoded, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return sendNotificationRequest(sender.client, request)
+}
+
+// sendNotificationRequest sends the request of a notification, responses with an error status are returned as error
+func sendNotificationRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("status %d: %s", response.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// validateNotifyEvents checks the events of NOTIFY_EVENTS, the default events are notified if it is empty
+func validateNotifyEvents(types []string) ([]string, error) {
+	if len(types) == 0 {
+		return defaultNotificationEvents, nil
+	}
+	if err := checkNotificationEvents(types); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// parseNotifyEvents returns the events of a service, the default events if none are given and none for none
+func parseNotifyEvents(types []string, defaults []string) ([]string, error) {
+	if len(types) == 1 && types[0] == "none" {
+		return nil, nil
+	}
+	if len(types) == 0 {
+		return defaults, nil
+	}
+	if err := checkNotificationEvents(types); err != nil {
+		return nil, err
+	}
+	return types, nil
+}
+
+// checkNotificationEvents checks that notifications can be sent for the events
+func checkNotificationEvents(types []string) error {
+	for _, eventType := range types {
+		if !slices.Contains(notificationEvents, eventType) {
+			return fmt.Errorf("unknown event %s, the events are: %s", eventType, strings.Join(notificationEvents, ", "))
+		}
+	}
+	return nil
+}
+
+// notifier turns the events of NOTIFY_EVENTS and EMAIL_NOTIFY_EVENTS into notifications
+type notifier struct {
+	app     *App
+	targets []notificationTarget
+	types   []string // Events any of the targets is notified of
+
+	// lastNotifiedReview is the newest pending review when the review queue was last notified. The queue is notified
+	// again once all reviews up to it are done and new ones are queued.
+	lastNotifiedReview uint
+}
+
+// StartNotifier sends notifications for the events of NOTIFY_EVENTS to the services of NTFY_URL, GOTIFY_URL and
+// APPRISE_URL, for the events of SLACK_NOTIFY_EVENTS and DISCORD_NOTIFY_EVENTS to the webhooks and for the events of
+// EMAIL_NOTIFY_EVENTS by email, until the context is cancelled
+func StartNotifier(ctx context.Context, app *App) {
+	targets := notificationTargets(app)
+	if len(targets) == 0 {
+		return
+	}
+	n := &notifier{app: app, targets: targets}
+	for _, target := range targets {
+		for _, eventType := range target.types {
+			if !slices.Contains(n.types, eventType) {
+				n.types = append(n.types, eventType)
+			}
+		}
+	}
+	queue := make(chan Notification, notificationQueueSize)
+	go n.send(ctx, queue)
+	go n.watch(ctx, queue)
+}
+
+// watch turns the published events into notifications until the context is cancelled
+func (n *notifier) watch(ctx context.Context, queue chan<- Notification) {
+	subscription, unsubscribe := events.subscribe()
+	defer func() { unsubscribe() }()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription:
+			if !ok {
+				// The subscription ends if the notifier falls too far behind
+				subscription, unsubscribe = events.subscribe()
+				continue
+			}
+			notification, notify := n.notificationFor(event)
+			if !notify {
+				continue
+			}
+			notification.Event = event.Type
+			select {
+			case queue <- notification:
+			default:
+				log.Warnf("Dropping notification %q, %d notifications are waiting to be sent", notification.Title, notificationQueueSize)
+			}
+		}
+	}
+}
+
+// send sends the queued notifications to the services notified of their events until the context is cancelled
+func (n *notifier) send(ctx context.Context, queue <-chan Notification) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-queue:
+			for _, target := range n.targets {
+				if !slices.Contains(target.types, notification.Event) {
+					continue
+				}
+				if err := target.sender.Send(ctx, notification); err != nil {
+					log.Warnf("Failed to send notification %q to %s: %v", notification.Title, target.sender.Name(), err)
+				}
+			}
+		}
+	}
+}
+
+// notificationFor returns the notification of an event, false if the event isn't notified
+func (n *notifier) notificationFor(event Event) (Notification, bool) {
+	if !slices.Contains(n.types, event.Type) {
+		return Notification{}, false
+	}
+	data, _ := event.Data.(map[string]any)
+	documentID, _ := data["document_id"].(int)
+	title, _ := data["title"].(string)
+	correspondent, _ := data["correspondent"].(string)
+	tags, _ := data["tags"].([]string)
+	document := &NotificationDocument{ID: documentID, Title: title, Correspondent: correspondent, Tags: tags}
+
+	switch event.Type {
+	case EventDocumentProcessed:
+		message := fmt.Sprintf("%s (#%d)", title, documentID)
+		if review, _ := data["review"].(bool); review {
+			message += " is waiting for review"
+		}
+		return Notification{Title: "Document processed", Message: message, Tags: []string{"page_facing_up"}, Click: documentURL(documentID), Document: document}, true
+	case EventOCRFinished:
+		pages, _ := data["pages"].(int)
+		return Notification{Title: "OCR finished", Message: fmt.Sprintf("%s (#%d), %s", title, documentID, pluralize(pages, "page")), Tags: []string{"mag"}, Click: documentURL(documentID), Document: document}, true
+	case EventDocumentFailed:
+		failures, _ := data["failures"].(int)
+		reason, _ := data["reason"].(string)
+		message := fmt.Sprintf("Document #%d failed %s: %s", documentID, pluralize(failures, "time"), reason)
+		if deadLettered, _ := data["dead_lettered"].(bool); deadLettered {
+			message += fmt.Sprintf("\nGave up on the document and tagged it %s", loadSettings().ErrorTag)
+		}
+		return Notification{Title: "Processing failed", Message: message, Tags: []string{"warning"}, Click: documentURL(documentID), Urgent: true, Document: document}, true
+	case EventReviewQueued:
+		return n.reviewNotification()
+	}
+	return Notification{}, false
+}
+
+// reviewNotification notifies that suggestions wait for review, once while the review queue isn't empty
+func (n *notifier) reviewNotification() (Notification, bool) {
+	reviews, err := GetPendingReviews(n.app.Database)
+	if err != nil {
+		log.Warnf("Failed to count the pending reviews for the notification: %v", err)
+		return Notification{}, false
+	}
+	if len(reviews) == 0 {
+		return Notification{}, false
+	}
+	oldest, newest := reviews[0].ID, reviews[0].ID
+	for _, review := range reviews {
+		oldest, newest = min(oldest, review.ID), max(newest, review.ID)
+	}
+	if oldest <= n.lastNotifiedReview {
+		return Notification{}, false
+	}
+	n.lastNotifiedReview = newest
+
+	message := fmt.Sprintf("%s waiting for review", pluralize(len(reviews), "document"))
+	return Notification{Title: "Review queue", Message: message, Tags: []string{"inbox_tray"}}, true
+}
+
+// documentURL returns the URL of a document in the web interface of paperless-ngx
+func documentURL(documentID int) string {
+	return fmt.Sprintf("%s/documents/%d/details", paperlessPublicURL(), documentID)
+}
+
+// pluralize returns the count with the noun, e.g. 1 page or 3 pages
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(count) + " " + noun + "s"
+}
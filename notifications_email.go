@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smtpImplicitTLSPort is the port of SMTP over TLS, other ports upgrade the connection with STARTTLS if offered
+const smtpImplicitTLSPort = 465
+
+// defaultEmailNotificationEvents are sent by email without EMAIL_NOTIFY_EVENTS
+var defaultEmailNotificationEvents = []string{EventDocumentFailed}
+
+// smtpConfig is the mail server of SMTP_HOST and the addresses emails are sent from and to
+type smtpConfig struct {
+	host     string
+	port     int
+	username string // Empty to send without authentication
+	password string
+	from     string
+	to       []string
+}
+
+// smtpConfigFromEnv returns the mail server configured with the SMTP_* variables
+func smtpConfigFromEnv() smtpConfig {
+	return smtpConfig{host: smtpHost, port: smtpPort, username: smtpUsername, password: smtpPassword, from: smtpFrom, to: smtpTo}
+}
+
+// smtpSender sends notifications by email to SMTP_TO
+type smtpSender struct {
+	config smtpConfig
+}
+
+func (sender *smtpSender) Name() string { return "email" }
+
+func (sender *smtpSender) Send(ctx context.Context, notification Notification) error {
+	body := notification.Message
+	if notification.Click != "" {
+		body += "\n\n" + notification.Click
+	}
+	return sendMail(ctx, sender.config, "paperless-gpt: "+notification.Title, body, notification.Urgent)
+}
+
+// sendMail sends a plain text email. The connection is encrypted on port 465 and upgraded with STARTTLS on other ports
+// if the server offers it, the credentials are only sent over encrypted connections or to localhost.
+func sendMail(ctx context.Context, config smtpConfig, subject, body string, urgent bool) error {
+	message := composeMail(config.from, config.to, subject, body, urgent, time.Now())
+
+	ctx, cancel := context.WithTimeout(ctx, notificationTimeout)
+	defer cancel()
+	address := net.JoinHostPort(config.host, strconv.Itoa(config.port))
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if config.port == smtpImplicitTLSPort {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: config.host}}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return err
+	}
+	// The deadline limits the whole conversation with the server
+	deadline, _ := ctx.Deadline()
+	if err := conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+
+	client, err := smtp.NewClient(conn, config.host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if startTLS, _ := client.Extension("STARTTLS"); startTLS && config.port != smtpImplicitTLSPort {
+		if err := client.StartTLS(&tls.Config{ServerName: config.host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	if config.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", config.username, config.password, config.host)); err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(mailAddress(config.from)); err != nil {
+		return err
+	}
+	for _, recipient := range config.to {
+		if err := client.Rcpt(mailAddress(recipient)); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient, err)
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// mailAddress returns the address of a mailbox like paperless-gpt <paperless@example.com>
+func mailAddress(mailbox string) string {
+	if address, err := mail.ParseAddress(mailbox); err == nil {
+		return address.Address
+	}
+	return mailbox
+}
+
+// composeMail returns a plain text email with the body encoded as quoted-printable
+func composeMail(from string, to []string, subject, body string, urgent bool, now time.Time) []byte {
+	var message bytes.Buffer
+	// Line breaks would start new headers
+	subject = strings.Join(strings.Fields(subject), " ")
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	message.WriteString("MIME-Version: 1.0\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	message.WriteString("Content-Transfer-Encoding: quoted-printable\r\n")
+	if urgent {
+		message.WriteString("X-Priority: 1\r\nImportance: high\r\n")
+	}
+	message.WriteString("\r\n")
+
+	writer := quotedprintable.NewWriter(&message)
+	writer.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n")))
+	writer.Close()
+	return message.Bytes()
+}
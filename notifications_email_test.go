@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedMail is an email received by the fake SMTP server
+type receivedMail struct {
+	auth       string
+	from       string
+	recipients []string
+	data       string
+}
+
+// startFakeSMTPServer accepts emails on localhost without TLS and returns its port and the received emails
+func startFakeSMTPServer(t *testing.T) (int, <-chan receivedMail) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan receivedMail, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeSMTP(conn, received)
+		}
+	}()
+	return listener.Addr().(*net.TCPAddr).Port, received
+}
+
+func serveFakeSMTP(conn net.Conn, received chan<- receivedMail) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+	var current receivedMail
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		command := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch command {
+		case "EHLO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			current.auth = line
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL":
+			current.from = line
+			reply("250 OK")
+		case "RCPT":
+			current.recipients = append(current.recipients, line)
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil || dataLine == ".\r\n" {
+					break
+				}
+				data.WriteString(dataLine)
+			}
+			current.data = data.String()
+			received <- current
+			current = receivedMail{}
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestSMTPSender(t *testing.T) {
+	port, received := startFakeSMTPServer(t)
+	sender := &smtpSender{config: smtpConfig{
+		host:     "127.0.0.1",
+		port:     port,
+		username: "paperless",
+		password: "secret",
+		from:     "paperless-gpt <paperless@example.com>",
+		to:       []string{"admin@example.com", "Jane <jane@example.com>"},
+	}}
+
+	notification := Notification{Title: "Processing failed", Message: "Document #544 failed 3 times: Zeitüberschreitung", Click: "https://paperless.example.com/documents/544/details", Urgent: true}
+	require.NoError(t, sender.Send(context.Background(), notification))
+
+	var email receivedMail
+	select {
+	case email = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no email received")
+	}
+	credentials, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(email.auth, "AUTH PLAIN "))
+	require.NoError(t, err)
+	assert.Equal(t, "\x00paperless\x00secret", string(credentials))
+	assert.Equal(t, "MAIL FROM:<paperless@example.com>", email.from)
+	assert.Equal(t, []string{"RCPT TO:<admin@example.com>", "RCPT TO:<jane@example.com>"}, email.recipients)
+
+	message, err := mail.ReadMessage(strings.NewReader(email.data))
+	require.NoError(t, err)
+	assert.Equal(t, "paperless-gpt: Processing failed", message.Header.Get("Subject"))
+	assert.Equal(t, "admin@example.com, Jane <jane@example.com>", message.Header.Get("To"))
+	assert.Equal(t, "high", message.Header.Get("Importance"))
+	body, err := io.ReadAll(quotedprintable.NewReader(message.Body))
+	require.NoError(t, err)
+	assert.Equal(t, "Document #544 failed 3 times: Zeitüberschreitung\r\n\r\nhttps://paperless.example.com/documents/544/details\r\n", string(body))
+}
+
+func TestSMTPSenderError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	sender := &smtpSender{config: smtpConfig{host: "127.0.0.1", port: port, from: "paperless@example.com", to: []string{"admin@example.com"}}}
+	err = sender.Send(context.Background(), Notification{Title: "OCR finished"})
+	assert.ErrorContains(t, err, strconv.Itoa(port))
+}
+
+func TestComposeMail(t *testing.T) {
+	now := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+	message := string(composeMail("paperless@example.com", []string{"admin@example.com"}, "Übersicht\r\nBcc: x@example.com", "Hello", false, now))
+
+	assert.Contains(t, message, "Subject: =?utf-8?q?=C3=9Cbersicht_Bcc:_x@example.com?=\r\n", "line breaks don't start headers")
+	assert.Contains(t, message, "Date: Mon, 12 Oct 2026 08:00:00 +0000\r\n")
+	assert.NotContains(t, message, "Importance")
+	assert.True(t, strings.HasSuffix(message, "\r\n\r\nHello"))
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"strings"
+)
+
+// defaultWebhookNotificationEvents are posted to Slack and Discord without SLACK_NOTIFY_EVENTS and DISCORD_NOTIFY_EVENTS
+var defaultWebhookNotificationEvents = []string{EventDocumentProcessed}
+
+// Colors of the Discord embeds
+const (
+	discordColor       = 0x17541f // Green of paperless-ngx
+	discordUrgentColor = 0xd32f2f
+)
+
+// thumbnailFunc downloads the thumbnail of a document and returns it with its content type
+type thumbnailFunc func(ctx context.Context, documentID int) ([]byte, string, error)
+
+// slackSender posts notifications to a Slack incoming webhook. Incoming webhooks can't upload files, so the messages
+// show no thumbnail.
+type slackSender struct {
+	client *http.Client
+	url    string
+}
+
+func (sender *slackSender) Name() string { return "slack" }
+
+func (sender *slackSender) Send(ctx context.Context, notification Notification) error {
+	text := "*" + slackEscape(notification.Title) + "*\n" + slackEscape(notification.Message)
+	if notification.Click != "" {
+		text += fmt.Sprintf("\n<%s|Open in paperless-ngx>", notification.Click)
+	}
+	section := map[string]any{"type": "section", "text": map[string]string{"type": "mrkdwn", "text": truncateString(text, 3000)}}
+	var fields []map[string]string
+	for _, field := range documentFields(notification.Document) {
+		fields = append(fields, map[string]string{"type": "mrkdwn", "text": truncateString("*"+field.name+"*\n"+slackEscape(field.value), 2000)})
+	}
+	if len(fields) > 0 {
+		section["fields"] = fields
+	}
+	message := map[string]any{
+		// Shown in the notifications of Slack, which don't render blocks
+		"text":   notification.Title + ": " + notification.Message,
+		"blocks": []any{section},
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return sendNotificationRequest(sender.client, request)
+}
+
+// slackEscape escapes the characters of text Slack interprets as markup
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// discordSender posts notifications to a Discord webhook as embeds, with the thumbnail of the document attached
+type discordSender struct {
+	client    *http.Client
+	url       string
+	thumbnail thumbnailFunc // Nil to send no thumbnails
+}
+
+func (sender *discordSender) Name() string { return "discord" }
+
+func (sender *discordSender) Send(ctx context.Context, notification Notification) error {
+	embed := map[string]any{
+		"title":       truncateString(notification.Title, 256),
+		"description": truncateString(notification.Message, 4096),
+		"color":       discordColor,
+	}
+	if notification.Urgent {
+		embed["color"] = discordUrgentColor
+	}
+	if notification.Click != "" {
+		embed["url"] = notification.Click
+	}
+	var fields []map[string]any
+	for _, field := range documentFields(notification.Document) {
+		fields = append(fields, map[string]any{"name": field.name, "value": truncateString(field.value, 1024), "inline": true})
+	}
+	if len(fields) > 0 {
+		embed["fields"] = fields
+	}
+
+	// A missing thumbnail doesn't keep the notification from being sent
+	var thumbnail []byte
+	var thumbnailName, contentType string
+	if sender.thumbnail != nil && notification.Document != nil && notification.Document.ID != 0 {
+		data, dataType, err := sender.thumbnail(ctx, notification.Document.ID)
+		if err != nil {
+			log.Warnf("Failed to fetch the thumbnail of document %d for Discord: %v", notification.Document.ID, err)
+		} else {
+			thumbnail, contentType = data, dataType
+			thumbnailName = "thumbnail" + thumbnailExtension(contentType)
+			embed["thumbnail"] = map[string]string{"url": "attachment://" + thumbnailName}
+		}
+	}
+
+	payload, err := json.Marshal(map[string]any{"embeds": []any{embed}})
+	if err != nil {
+		return err
+	}
+	if thumbnail == nil {
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		return sendNotificationRequest(sender.client, request)
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if err := writer.WriteField("payload_json", string(payload)); err != nil {
+		return err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="files[0]"; filename=%q`, thumbnailName))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	if _, err := part.Write(thumbnail); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, sender.url, &body)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	return sendNotificationRequest(sender.client, request)
+}
+
+// thumbnailExtension returns the file extension of a thumbnail, paperless-ngx creates WebP thumbnails and PNG ones
+// before version 1.9
+func thumbnailExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/jpeg":
+		return ".jpg"
+	}
+	return ".webp"
+}
+
+// notificationField is a field shown in rich messages
+type notificationField struct {
+	name  string
+	value string
+}
+
+// documentFields returns the correspondent and tags of a document as fields, the empty ones are left out
+func documentFields(document *NotificationDocument) []notificationField {
+	if document == nil {
+		return nil
+	}
+	var fields []notificationField
+	if document.Correspondent != "" {
+		fields = append(fields, notificationField{"Correspondent", document.Correspondent})
+	}
+	if len(document.Tags) > 0 {
+		fields = append(fields, notificationField{"Tags", strings.Join(document.Tags, ", ")})
+	}
+	return fields
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var processedNotification = Notification{
+	Title:    "Document processed",
+	Message:  "Invoice <ACME> & Co (#544)",
+	Click:    "https://paperless.example.com/documents/544/details",
+	Document: &NotificationDocument{ID: 544, Title: "Invoice ACME & Co", Correspondent: "ACME", Tags: []string{"invoice", "2025"}},
+}
+
+func TestSlackSender(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	sender := &slackSender{client: server.Client(), url: server.URL}
+	require.NoError(t, sender.Send(context.Background(), processedNotification))
+
+	var message struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Text struct {
+				Text string `json:"text"`
+			} `json:"text"`
+			Fields []struct {
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	require.NoError(t, json.Unmarshal(body, &message))
+	assert.Equal(t, "Document processed: Invoice <ACME> & Co (#544)", message.Text)
+	require.Len(t, message.Blocks, 1)
+	assert.Equal(t, "*Document processed*\nInvoice &lt;ACME&gt; &amp; Co (#544)\n<https://paperless.example.com/documents/544/details|Open in paperless-ngx>", message.Blocks[0].Text.Text)
+	require.Len(t, message.Blocks[0].Fields, 2)
+	assert.Equal(t, "*Correspondent*\nACME", message.Blocks[0].Fields[0].Text)
+	assert.Equal(t, "*Tags*\ninvoice, 2025", message.Blocks[0].Fields[1].Text)
+}
+
+func TestDiscordSender(t *testing.T) {
+	var request *http.Request
+	var payload, thumbnail string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request = r
+		if err := r.ParseMultipartForm(1 << 20); err == nil {
+			payload = r.FormValue("payload_json")
+			file, header, err := r.FormFile("files[0]")
+			require.NoError(t, err)
+			assert.Equal(t, "thumbnail.webp", header.Filename)
+			data, _ := io.ReadAll(file)
+			thumbnail = string(data)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		payload = string(data)
+	}))
+	defer server.Close()
+
+	thumbnails := func(ctx context.Context, documentID int) ([]byte, string, error) {
+		if documentID != 544 {
+			return nil, "", errors.New("error fetching thumbnail of document 545: 404")
+		}
+		return []byte("RIFF....WEBP"), "image/webp", nil
+	}
+	sender := &discordSender{client: server.Client(), url: server.URL, thumbnail: thumbnails}
+	require.NoError(t, sender.Send(context.Background(), processedNotification))
+
+	var message struct {
+		Embeds []struct {
+			Title     string `json:"title"`
+			URL       string `json:"url"`
+			Color     int    `json:"color"`
+			Thumbnail struct {
+				URL string `json:"url"`
+			} `json:"thumbnail"`
+			Fields []struct {
+				Name  string `json:"name"`
+				Value string `json:"value"`
+			} `json:"fields"`
+		} `json:"embeds"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(payload), &message))
+	require.Len(t, message.Embeds, 1)
+	embed := message.Embeds[0]
+	assert.Equal(t, "Document processed", embed.Title)
+	assert.Equal(t, "https://paperless.example.com/documents/544/details", embed.URL)
+	assert.Equal(t, discordColor, embed.Color)
+	assert.Equal(t, "attachment://thumbnail.webp", embed.Thumbnail.URL)
+	require.Len(t, embed.Fields, 2)
+	assert.Equal(t, "ACME", embed.Fields[0].Value)
+	assert.Equal(t, "RIFF....WEBP", thumbnail)
+
+	// Without a thumbnail the embed is sent as JSON
+	failed := Notification{Title: "Processing failed", Message: "Document #545 failed 3 times: timeout", Urgent: true, Document: &NotificationDocument{ID: 545}}
+	require.NoError(t, sender.Send(context.Background(), failed))
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	message.Embeds = nil
+	require.NoError(t, json.Unmarshal([]byte(payload), &message))
+	assert.Equal(t, discordUrgentColor, message.Embeds[0].Color)
+	assert.Empty(t, message.Embeds[0].Fields)
+}
+
+func TestParseNotifyEvents(t *testing.T) {
+	types, err := parseNotifyEvents(nil, defaultWebhookNotificationEvents)
+	require.NoError(t, err)
+	assert.Equal(t, []string{EventDocumentProcessed}, types)
+
+	types, err = parseNotifyEvents([]string{"none"}, defaultWebhookNotificationEvents)
+	require.NoError(t, err)
+	assert.Empty(t, types)
+
+	_, err = parseNotifyEvents([]string{"document.created"}, defaultWebhookNotificationEvents)
+	assert.ErrorContains(t, err, "unknown event document.created")
+}
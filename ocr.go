@@ -4,15 +4,37 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+
+	"github.com/sirupsen/logrus"
 )
 
+// DocumentOCRResult holds the combined text of a document and the junk pages found during OCR
+type DocumentOCRResult struct {
+	Text      string
+	PageTexts []string // Text per page image, empty for pages that were skipped or not processed
+	JunkPages []JunkPage
+	ASN       int // ASN read from a barcode, 0 if there is none
+	ASNPage   int // Page (numbered from 1) the ASN barcode was found on
+	// Confidence is the mean confidence (0-1) of the pages the OCR provider reported one for, 0 if it reported none
+	Confidence float64
+}
+
 // ProcessDocumentOCR processes a document through OCR and returns the combined text
-func (app *App) ProcessDocumentOCR(ctx context.Context, documentID int) (string, error) {
+func (app *App) ProcessDocumentOCR(ctx context.Context, documentID int) (*DocumentOCRResult, error) {
 	docLogger := documentLogger(documentID)
 	docLogger.Info("Starting OCR processing")
+	ctx = withLLMDocument(ctx, documentID)
+
+	// Junk pages don't count towards the page limit, so render some more pages to make up for them
+	limitOcrPages := settingsFor(ctx).LimitOCRPages
+	downloadLimit := limitOcrPages
+	if junkPageDetectionEnabled() && downloadLimit > 0 {
+		downloadLimit *= 2
+	}
 
-	imagePaths, err := app.Client.DownloadDocumentAsImages(ctx, documentID, limitOcrPages)
+	imagePaths, err := app.Client.DownloadDocumentAsImages(ctx, documentID, downloadLimit)
 	defer func() {
 		for _, imagePath := range imagePaths {
 			if err := os.Remove(imagePath); err != nil {
@@ -21,37 +43,120 @@ func (app *App) ProcessDocumentOCR(ctx context.Context, documentID int) (string,
 		}
 	}()
 	if err != nil {
-		return "", fmt.Errorf("error downloading document images for document %d: %w", documentID, err)
+		return nil, fmt.Errorf("error downloading document images for document %d: %w", documentID, err)
 	}
 
 	docLogger.WithField("page_count", len(imagePaths)).Debug("Downloaded document images")
 
+	result, err := app.ocrPages(ctx, documentID, imagePaths, limitOcrPages, docLogger)
+	if err != nil {
+		return nil, err
+	}
+
+	// The confidence is kept for the prompts of the suggestions generated later
+	if result.Confidence > 0 && app.Database != nil {
+		if err := SaveOCRConfidence(app.Database, uint(documentID), result.Confidence); err != nil {
+			docLogger.WithError(err).Warn("Failed to save OCR confidence")
+		}
+	}
+
+	docLogger.Info("OCR processing completed successfully")
+	return result, nil
+}
+
+// ocrConfidence returns the confidence of the last OCR of a document, 0 if it is unknown
+func (app *App) ocrConfidence(documentID int) float64 {
+	if app.Database == nil {
+		return 0
+	}
+	record, err := GetOCRConfidence(app.Database, uint(documentID))
+	if err != nil {
+		return 0
+	}
+	return record.Confidence
+}
+
+// ocrPages runs OCR on the page images until limit pages with content have been processed (0 means no limit).
+// Depending on OCR_JUNK_PAGES, blank pages, calibration sheets and fax cover pages are skipped or flagged.
+func (app *App) ocrPages(ctx context.Context, documentID int, imagePaths []string, limit int, docLogger *logrus.Entry) (*DocumentOCRResult, error) {
+	result := &DocumentOCRResult{PageTexts: make([]string, len(imagePaths))}
 	var ocrTexts []string
+	var confidences []float64
+	contentPages := 0
 	for i, imagePath := range imagePaths {
+		if limit > 0 && contentPages >= limit {
+			break
+		}
+
 		pageLogger := docLogger.WithField("page", i+1)
 		pageLogger.Debug("Processing page")
 
 		imageContent, err := os.ReadFile(imagePath)
 		if err != nil {
-			return "", fmt.Errorf("error reading image file for document %d, page %d: %w", documentID, i+1, err)
+			return nil, fmt.Errorf("error reading image file for document %d, page %d: %w", documentID, i+1, err)
 		}
 
-		result, err := app.ocrProvider.ProcessImage(ctx, imageContent)
+		// The first ASN barcode is used, a page holding only the barcode is left out when it gets removed
+		if asnBarcodeDetection && result.ASN == 0 {
+			if asn := detectASNBarcode(imageContent); asn > 0 {
+				pageLogger.WithField("asn", asn).Info("Detected ASN barcode")
+				result.ASN = asn
+				result.ASNPage = i + 1
+				if asnBarcodeRemovePage {
+					continue
+				}
+			}
+		}
+
+		// Blank pages are detected before OCR to save the request
+		if junkPageDetectionEnabled() && isBlankImage(imageContent) {
+			pageLogger.Info("Skipping OCR of blank page")
+			result.JunkPages = append(result.JunkPages, JunkPage{Page: i + 1, Kind: junkPageBlank})
+			continue
+		}
+
+		ocrResult, err := app.ocrProvider.ProcessImage(ctx, imageContent)
 		if err != nil {
-			return "", fmt.Errorf("error performing OCR for document %d, page %d: %w", documentID, i+1, err)
+			return nil, fmt.Errorf("error performing OCR for document %d, page %d: %w", documentID, i+1, err)
 		}
-		if result == nil {
+		if ocrResult == nil {
 			pageLogger.Error("Got nil result from OCR provider")
-			return "", fmt.Errorf("error performing OCR for document %d, page %d: nil result", documentID, i+1)
+			return nil, fmt.Errorf("error performing OCR for document %d, page %d: nil result", documentID, i+1)
 		}
 
-		pageLogger.WithField("has_hocr", result.HOCR != "").
-			WithField("metadata", result.Metadata).
+		pageLogger.WithField("has_hocr", ocrResult.HOCR != "").
+			WithField("metadata", ocrResult.Metadata).
 			Debug("OCR completed for page")
 
-		ocrTexts = append(ocrTexts, result.Text)
+		isJunk := false
+		if junkPageDetectionEnabled() {
+			if kind := classifyJunkText(ocrResult.Text); kind != "" {
+				pageLogger.WithField("kind", kind).Info("Detected junk page")
+				result.JunkPages = append(result.JunkPages, JunkPage{Page: i + 1, Kind: kind})
+				isJunk = true
+			}
+		}
+		if isJunk && ocrJunkPageMode == junkPageModeSkip {
+			continue
+		}
+
+		ocrTexts = append(ocrTexts, ocrResult.Text)
+		result.PageTexts[i] = ocrResult.Text
+		if confidence, err := strconv.ParseFloat(ocrResult.Metadata["confidence"], 64); err == nil {
+			confidences = append(confidences, confidence)
+		}
+		if !isJunk {
+			contentPages++
+		}
 	}
 
-	docLogger.Info("OCR processing completed successfully")
-	return strings.Join(ocrTexts, "\n\n"), nil
+	result.Text = strings.Join(ocrTexts, "\n\n")
+	if len(confidences) > 0 {
+		var total float64
+		for _, confidence := range confidences {
+			total += confidence
+		}
+		result.Confidence = total / float64(len(confidences))
+	}
+	return result, nil
 }
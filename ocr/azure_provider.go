@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gabriel-vasile/mimetype"
@@ -128,6 +129,9 @@ func (p *AzureProvider) ProcessImage(ctx context.Context, imageContent []byte) (
 			"api_version": result.AnalyzeResult.APIVersion,
 		},
 	}
+	if confidence, ok := azureWordConfidence(result.AnalyzeResult.Pages); ok {
+		ocrResult.Metadata["confidence"] = strconv.FormatFloat(confidence, 'f', 4, 64)
+	}
 
 	logger.WithFields(logrus.Fields{
 		"content_length": len(ocrResult.Text),
@@ -136,6 +140,21 @@ func (p *AzureProvider) ProcessImage(ctx context.Context, imageContent []byte) (
 	return ocrResult, nil
 }
 
+// azureWordConfidence returns the mean confidence of the recognized words, false if there are none
+func azureWordConfidence(pages []AzurePage) (float64, bool) {
+	total, words := 0.0, 0
+	for _, page := range pages {
+		for _, word := range page.Words {
+			total += word.Confidence
+			words++
+		}
+	}
+	if words == 0 {
+		return 0, false
+	}
+	return total / float64(words), true
+}
+
 func (p *AzureProvider) submitDocument(ctx context.Context, imageContent []byte) (string, error) {
 	outputFormatParam := ""
 	if(p.outputContentFormat != "text") {
@@ -220,3 +220,15 @@ func TestAzureProvider_ProcessImage(t *testing.T) {
 		})
 	}
 }
+
+func TestAzureWordConfidence(t *testing.T) {
+	confidence, ok := azureWordConfidence([]AzurePage{
+		{Words: []AzureWord{{Content: "Invoice", Confidence: 0.9}, {Content: "2024", Confidence: 0.7}}},
+		{Words: []AzureWord{{Content: "Total", Confidence: 0.5}}},
+	})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.7, confidence, 0.0001)
+
+	_, ok = azureWordConfidence([]AzurePage{{}})
+	assert.False(t, ok, "pages without words")
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"html"
+	"strconv"
 	"strings"
 
 	documentai "cloud.google.com/go/documentai/apiv1"
@@ -108,6 +109,15 @@ func (p *GoogleDocAIProvider) ProcessImage(ctx context.Context, imageContent []b
 		}
 	}
 
+	// Mean confidence of the layout of the pages
+	if pages := resp.Document.GetPages(); len(pages) > 0 {
+		var total float64
+		for _, page := range pages {
+			total += float64(page.GetLayout().GetConfidence())
+		}
+		metadata["confidence"] = strconv.FormatFloat(total/float64(len(pages)), 'f', 4, 64)
+	}
+
 	result := &OCRResult{
 		Text:     resp.Document.Text,
 		Metadata: metadata,
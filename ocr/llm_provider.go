@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"image"
 	"os"
+	"strconv"
 	"strings"
 
 	_ "image/jpeg"
@@ -118,6 +119,12 @@ func (p *LLMProvider) ProcessImage(ctx context.Context, imageContent []byte) (*O
 			"model":    p.model,
 		},
 	}
+	// Token counts are only known if the provider reports them
+	for key, metadataKey := range map[string]string{"PromptTokens": "prompt_tokens", "CompletionTokens": "completion_tokens"} {
+		if tokens, ok := completion.Choices[0].GenerationInfo[key].(int); ok {
+			result.Metadata[metadataKey] = strconv.Itoa(tokens)
+		}
+	}
 	logger.WithField("content_length", len(result.Text)).Info("Successfully processed image")
 	return result, nil
 }
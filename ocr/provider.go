@@ -3,6 +3,7 @@ package ocr
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/sirupsen/logrus"
 )
@@ -17,7 +18,8 @@ type OCRResult struct {
 	// hOCR output (optional, if provider supports it)
 	HOCR string
 
-	// Additional provider-specific metadata
+	// Additional provider-specific metadata, "confidence" is the mean confidence (0-1) of the recognized text if the
+	// provider reports one
 	Metadata map[string]string
 }
 
@@ -92,3 +94,13 @@ func NewProvider(config Config) (Provider, error) {
 func SetLogLevel(level logrus.Level) {
 	log.SetLevel(level)
 }
+
+// SetLogOutput sets where the logs of the OCR package are written to
+func SetLogOutput(output io.Writer) {
+	log.SetOutput(output)
+}
+
+// SetLogFormatter sets the format of the logs of the OCR package, e.g. JSON
+func SetLogFormatter(formatter logrus.Formatter) {
+	log.SetFormatter(formatter)
+}
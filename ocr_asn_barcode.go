@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+// asnBarcodeReaders decode the barcode formats commonly used for ASN labels
+var asnBarcodeReaders = []func() gozxing.Reader{
+	oned.NewCode128Reader,
+	oned.NewCode39Reader,
+	qrcode.NewQRCodeReader,
+}
+
+// detectASNBarcode looks for a barcode or QR code starting with ASN_BARCODE_PREFIX on a page image,
+// like the ASN barcodes paperless-ngx reads while consuming documents. It returns 0 if there is none.
+func detectASNBarcode(imageContent []byte) int {
+	img, _, err := image.Decode(bytes.NewReader(imageContent))
+	if err != nil {
+		return 0
+	}
+	bitmap, err := gozxing.NewBinaryBitmapFromImage(img)
+	if err != nil {
+		return 0
+	}
+
+	hints := map[gozxing.DecodeHintType]interface{}{
+		gozxing.DecodeHintType_TRY_HARDER: true,
+	}
+	for _, newReader := range asnBarcodeReaders {
+		result, err := newReader().Decode(bitmap, hints)
+		if err != nil {
+			continue
+		}
+		if asn := parseASNBarcode(result.GetText()); asn > 0 {
+			return asn
+		}
+	}
+	return 0
+}
+
+// parseASNBarcode returns the ASN of a barcode text like "ASN00123", or 0 if the text is not an ASN barcode
+func parseASNBarcode(text string) int {
+	text = strings.TrimSpace(text)
+	if len(text) <= len(asnBarcodePrefix) || !strings.EqualFold(text[:len(asnBarcodePrefix)], asnBarcodePrefix) {
+		return 0
+	}
+	asn, err := strconv.Atoi(strings.TrimSpace(text[len(asnBarcodePrefix):]))
+	if err != nil || asn <= 0 {
+		return 0
+	}
+	return asn
+}
+
+// assignableASN returns the ASN found during OCR if it can be assigned to the document, or 0.
+// paperless-ngx rejects an ASN that is already used by another document.
+func (app *App) assignableASN(ctx context.Context, document Document, asn int) (int, error) {
+	if asn == 0 {
+		return 0, nil
+	}
+	docLogger := documentLogger(document.ID)
+	if document.ArchiveSerialNumber != nil {
+		if *document.ArchiveSerialNumber != asn {
+			docLogger.Warnf("Document already has ASN %d, ignoring ASN %d from barcode", *document.ArchiveSerialNumber, asn)
+		}
+		return 0, nil
+	}
+
+	documentIDs, err := app.Client.GetDocumentIDs(ctx, fmt.Sprintf("archive_serial_number=%d", asn))
+	if err != nil {
+		return 0, fmt.Errorf("error checking whether ASN %d is in use: %w", asn, err)
+	}
+	if len(documentIDs) > 0 {
+		docLogger.Warnf("ASN %d from barcode is already assigned to document %d", asn, documentIDs[0])
+		return 0, nil
+	}
+	return asn, nil
+}
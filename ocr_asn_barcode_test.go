@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/oned"
+	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeBarcodePage renders a white page with a barcode in its top left corner
+func encodeBarcodePage(t *testing.T, writer gozxing.Writer, format gozxing.BarcodeFormat, text string, width, height int) []byte {
+	barcode, err := writer.Encode(text, format, width, height, nil)
+	require.NoError(t, err)
+
+	img := image.NewRGBA(image.Rect(0, 0, 600, 800))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(40, 40, 40+width, 40+height), barcode, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95}))
+	return buf.Bytes()
+}
+
+func TestDetectASNBarcode(t *testing.T) {
+	originalPrefix := asnBarcodePrefix
+	defer func() { asnBarcodePrefix = originalPrefix }()
+	asnBarcodePrefix = "ASN"
+
+	code128 := encodeBarcodePage(t, oned.NewCode128Writer(), gozxing.BarcodeFormat_CODE_128, "ASN00123", 300, 80)
+	assert.Equal(t, 123, detectASNBarcode(code128))
+
+	qr := encodeBarcodePage(t, qrcode.NewQRCodeWriter(), gozxing.BarcodeFormat_QR_CODE, "ASN4711", 200, 200)
+	assert.Equal(t, 4711, detectASNBarcode(qr))
+
+	// Barcodes without the prefix are not ASN labels
+	other := encodeBarcodePage(t, oned.NewCode128Writer(), gozxing.BarcodeFormat_CODE_128, "INV-2024-17", 300, 80)
+	assert.Equal(t, 0, detectASNBarcode(other))
+
+	assert.Equal(t, 0, detectASNBarcode(encodeTestPage(t, 0.1)))
+}
+
+func TestParseASNBarcode(t *testing.T) {
+	originalPrefix := asnBarcodePrefix
+	defer func() { asnBarcodePrefix = originalPrefix }()
+	asnBarcodePrefix = "ASN"
+
+	assert.Equal(t, 42, parseASNBarcode("ASN00042"))
+	assert.Equal(t, 42, parseASNBarcode("asn 42"))
+	assert.Equal(t, 0, parseASNBarcode("ASN"))
+	assert.Equal(t, 0, parseASNBarcode("ASNabc"))
+	assert.Equal(t, 0, parseASNBarcode("00042"))
+}
+
+func TestOcrPages_ASNBarcode(t *testing.T) {
+	originalDetection, originalPrefix, originalRemove := asnBarcodeDetection, asnBarcodePrefix, asnBarcodeRemovePage
+	defer func() {
+		asnBarcodeDetection, asnBarcodePrefix, asnBarcodeRemovePage = originalDetection, originalPrefix, originalRemove
+	}()
+	asnBarcodeDetection = true
+	asnBarcodePrefix = "ASN"
+	asnBarcodeRemovePage = true
+
+	dir := t.TempDir()
+	pages := [][]byte{
+		encodeBarcodePage(t, oned.NewCode128Writer(), gozxing.BarcodeFormat_CODE_128, "ASN00007", 300, 80),
+		encodeTestPage(t, 0.1),
+	}
+	var imagePaths []string
+	for i, page := range pages {
+		imagePath := filepath.Join(dir, fmt.Sprintf("page%03d.jpg", i))
+		require.NoError(t, os.WriteFile(imagePath, page, 0644))
+		imagePaths = append(imagePaths, imagePath)
+	}
+
+	provider := &sequenceOCRProvider{texts: []string{"Page 2"}}
+	app := &App{ocrProvider: provider}
+
+	result, err := app.ocrPages(context.Background(), 1, imagePaths, 0, logrus.WithField("test", "asn"))
+	require.NoError(t, err)
+	assert.Equal(t, 7, result.ASN)
+	assert.Equal(t, 1, result.ASNPage)
+	assert.Equal(t, "Page 2", result.Text)
+	assert.Equal(t, 1, provider.calls, "the barcode page is removed, so it is not sent to OCR")
+}
+
+func TestAssignableASN(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Query().Get("archive_serial_number") == "5" {
+			w.Write([]byte(`{"count": 1, "all": [9], "results": [{"id": 9}]}`))
+			return
+		}
+		w.Write([]byte(`{"count": 0, "all": [], "results": []}`))
+	})
+
+	app := &App{Client: env.client}
+	ctx := context.Background()
+
+	asn, err := app.assignableASN(ctx, Document{ID: 1}, 6)
+	require.NoError(t, err)
+	assert.Equal(t, 6, asn)
+
+	// Used by another document
+	asn, err = app.assignableASN(ctx, Document{ID: 1}, 5)
+	require.NoError(t, err)
+	assert.Equal(t, 0, asn)
+
+	// The document already has an ASN
+	existing := 3
+	asn, err = app.assignableASN(ctx, Document{ID: 1, ArchiveSerialNumber: &existing}, 6)
+	require.NoError(t, err)
+	assert.Equal(t, 0, asn)
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	_ "image/jpeg"
+	"strings"
+)
+
+// Modes for OCR_JUNK_PAGES
+const (
+	junkPageModeOff  = "off"
+	junkPageModeSkip = "skip"
+	junkPageModeFlag = "flag"
+)
+
+// Kinds of junk pages
+const (
+	junkPageBlank       = "blank"
+	junkPageCalibration = "calibration"
+	junkPageFaxCover    = "fax_cover"
+)
+
+const (
+	// blankPageInkRatio is the share of dark pixels below which a page is considered blank
+	blankPageInkRatio = 0.002
+	// blankPageMargin is the share of the page width and height ignored at the edges, where scanners leave shadows
+	blankPageMargin = 0.05
+	// junkPageMaxWords is the maximum number of words of a calibration sheet or fax cover page
+	junkPageMaxWords = 120
+)
+
+// calibrationKeywords are typical words printed on scanner calibration and test sheets
+var calibrationKeywords = []string{"calibration", "kalibrierung", "it8", "test chart", "testchart", "color target", "shading sheet", "white reference"}
+
+// faxCoverKeywords are typical words printed on fax cover pages, in addition to the word "fax"
+var faxCoverKeywords = []string{"cover", "deckblatt", "page de garde", "number of pages", "anzahl der seiten"}
+
+// JunkPage is a page that was detected as blank, a calibration sheet or a fax cover page during OCR
+type JunkPage struct {
+	Page int    `json:"page"`
+	Kind string `json:"kind"`
+}
+
+// junkPageDetectionEnabled reports whether OCR_JUNK_PAGES enables the detection of junk pages
+func junkPageDetectionEnabled() bool {
+	return ocrJunkPageMode == junkPageModeSkip || ocrJunkPageMode == junkPageModeFlag
+}
+
+// isBlankImage reports whether the page image contains (almost) no ink. Images that cannot be decoded are never blank.
+func isBlankImage(imageContent []byte) bool {
+	img, _, err := image.Decode(bytes.NewReader(imageContent))
+	if err != nil {
+		return false
+	}
+
+	bounds := img.Bounds()
+	marginX := int(float64(bounds.Dx()) * blankPageMargin)
+	marginY := int(float64(bounds.Dy()) * blankPageMargin)
+
+	// Sampling every other pixel is accurate enough and twice as fast
+	var pixels, inkPixels int
+	for y := bounds.Min.Y + marginY; y < bounds.Max.Y-marginY; y += 2 {
+		for x := bounds.Min.X + marginX; x < bounds.Max.X-marginX; x += 2 {
+			r, g, b, _ := img.At(x, y).RGBA()
+			// Relative luminance on the 16 bit scale returned by RGBA; the threshold tolerates JPEG noise and paper tint
+			luminance := (299*r + 587*g + 114*b) / 1000
+			if luminance < 0x9000 {
+				inkPixels++
+			}
+			pixels++
+		}
+	}
+	if pixels == 0 {
+		return false
+	}
+	return float64(inkPixels)/float64(pixels) < blankPageInkRatio
+}
+
+// classifyJunkText returns the kind of junk page based on its OCR text, or an empty string for regular pages
+func classifyJunkText(text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return junkPageBlank
+	}
+	if len(words) > junkPageMaxWords {
+		return ""
+	}
+
+	lower := strings.ToLower(text)
+	for _, keyword := range calibrationKeywords {
+		if strings.Contains(lower, keyword) {
+			return junkPageCalibration
+		}
+	}
+	if strings.Contains(lower, "fax") {
+		for _, keyword := range faxCoverKeywords {
+			if strings.Contains(lower, keyword) {
+				return junkPageFaxCover
+			}
+		}
+	}
+	return ""
+}
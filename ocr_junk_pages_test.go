@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"paperless-gpt/ocr"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodeTestPage renders a white page with a black block covering the given share of its height
+func encodeTestPage(t *testing.T, inkShare float64) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 300))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	if inkShare > 0 {
+		block := image.Rect(20, 20, 180, 20+int(260*inkShare))
+		draw.Draw(img, block, &image.Uniform{color.Black}, image.Point{}, draw.Src)
+	}
+	// Scanner shadow at the edge, which must be ignored
+	draw.Draw(img, image.Rect(0, 0, 4, 300), &image.Uniform{color.Black}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpeg.DefaultQuality}))
+	return buf.Bytes()
+}
+
+func TestIsBlankImage(t *testing.T) {
+	assert.True(t, isBlankImage(encodeTestPage(t, 0)))
+	assert.False(t, isBlankImage(encodeTestPage(t, 0.1)))
+	assert.False(t, isBlankImage([]byte("not an image")))
+}
+
+func TestClassifyJunkText(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "Empty page", text: " \n ", expected: junkPageBlank},
+		{name: "Calibration sheet", text: "IT8.7/2 Color Target\nBatch 2023:04", expected: junkPageCalibration},
+		{name: "Fax cover page", text: "FAX\nTo: Accounting\nFrom: ACME\nNumber of pages (including cover): 3", expected: junkPageFaxCover},
+		{name: "German fax cover page", text: "Telefax Deckblatt\nAn: Buchhaltung", expected: junkPageFaxCover},
+		{name: "Cover without fax", text: "Cover letter for my application", expected: ""},
+		{name: "Regular page", text: "Invoice 2024-001\nTotal: 123.45 EUR", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, classifyJunkText(tc.text))
+		})
+	}
+}
+
+// sequenceOCRProvider returns the given texts in order, one per processed page
+type sequenceOCRProvider struct {
+	texts []string
+	calls int
+}
+
+func (p *sequenceOCRProvider) ProcessImage(ctx context.Context, imageContent []byte) (*ocr.OCRResult, error) {
+	text := p.texts[p.calls]
+	p.calls++
+	return &ocr.OCRResult{Text: text}, nil
+}
+
+func TestOcrPages_JunkPages(t *testing.T) {
+	originalMode := ocrJunkPageMode
+	defer func() { ocrJunkPageMode = originalMode }()
+
+	// Page 1 is blank, page 3 a fax cover page
+	dir := t.TempDir()
+	var imagePaths []string
+	for i, inkShare := range []float64{0, 0.1, 0.1, 0.1, 0.1} {
+		imagePath := filepath.Join(dir, fmt.Sprintf("page%03d.jpg", i))
+		require.NoError(t, os.WriteFile(imagePath, encodeTestPage(t, inkShare), 0644))
+		imagePaths = append(imagePaths, imagePath)
+	}
+	texts := []string{"Page 2", "Fax cover sheet", "Page 4", "Page 5"}
+	logger := logrus.WithField("test", "junk_pages")
+
+	t.Run("skip", func(t *testing.T) {
+		ocrJunkPageMode = junkPageModeSkip
+		provider := &sequenceOCRProvider{texts: texts}
+		app := &App{ocrProvider: provider}
+
+		result, err := app.ocrPages(context.Background(), 1, imagePaths, 2, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "Page 2\n\nPage 4", result.Text)
+		assert.Equal(t, []JunkPage{{Page: 1, Kind: junkPageBlank}, {Page: 3, Kind: junkPageFaxCover}}, result.JunkPages)
+		assert.Equal(t, 3, provider.calls, "junk pages must not count towards the page limit")
+	})
+
+	t.Run("flag", func(t *testing.T) {
+		ocrJunkPageMode = junkPageModeFlag
+		app := &App{ocrProvider: &sequenceOCRProvider{texts: texts}}
+
+		result, err := app.ocrPages(context.Background(), 1, imagePaths, 2, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "Page 2\n\nFax cover sheet\n\nPage 4", result.Text)
+		assert.Len(t, result.JunkPages, 2)
+	})
+
+	t.Run("off", func(t *testing.T) {
+		ocrJunkPageMode = junkPageModeOff
+		app := &App{ocrProvider: &sequenceOCRProvider{texts: append([]string{""}, texts...)}}
+
+		result, err := app.ocrPages(context.Background(), 1, imagePaths, 2, logger)
+		require.NoError(t, err)
+		assert.Equal(t, "\n\nPage 2", result.Text)
+		assert.Empty(t, result.JunkPages)
+	})
+}
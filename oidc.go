@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	jose "github.com/go-jose/go-jose/v4"
+	"golang.org/x/oauth2"
+)
+
+const (
+	// oidcSessionCookie holds the signed session of a user logged in to the web UI
+	oidcSessionCookie = "paperless_gpt_session"
+	// oidcLoginCookie holds the state of a login in progress
+	oidcLoginCookie = "paperless_gpt_login"
+	// oidcSessionDuration is how long a login to the web UI lasts
+	oidcSessionDuration = 12 * time.Hour
+	// oidcLoginDuration is how long a user may take to log in at the OpenID Connect provider
+	oidcLoginDuration = 10 * time.Minute
+	// oidcClockSkew is the tolerated difference between the clocks of paperless-gpt and the OpenID Connect provider for
+	// the expiry of tokens
+	oidcClockSkew = time.Minute
+	// oidcKeysRefreshInterval limits how often the signing keys are fetched again for tokens signed with an unknown key
+	oidcKeysRefreshInterval = time.Minute
+)
+
+// oidcConfig is the configuration of the OpenID Connect login, read from the OIDC_* environment variables
+type oidcConfig struct {
+	IssuerURL     string
+	ClientID      string
+	ClientSecret  string
+	RedirectURL   string   // URL of /auth/callback as registered at the provider
+	Scopes        []string // Requested scopes, openid is always requested
+	Audience      string   // Audience bearer tokens must be issued for, defaults to the client ID
+	SessionSecret string   // Signs the session cookies, sessions end on restart without it
+	BasePath      string   // Path prefix paperless-gpt is served under, see BASE_PATH
+}
+
+// oidcProvider authenticates users of the web UI and the API with an OpenID Connect provider such as Authelia,
+// Keycloak or authentik. The web UI uses a session cookie after logging in, the API also accepts bearer tokens
+// issued by the provider.
+type oidcProvider struct {
+	oauth2        oauth2.Config
+	audience      string
+	verifier      *oidc.IDTokenVerifier
+	endSessionURL string
+	sessionKey    []byte
+	secureCookies bool
+	basePath      string
+	httpClient    *http.Client
+}
+
+// oidcDiscovery is the subset of the provider metadata paperless-gpt needs
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	EndSessionEndpoint    string `json:"end_session_endpoint"`
+}
+
+// oidcClaims are the claims of ID and access tokens paperless-gpt reads, the issuer and lifetime are checked by go-oidc
+type oidcClaims struct {
+	Subject           string `json:"sub"`
+	AuthorizedParty   string `json:"azp"`
+	Nonce             string `json:"nonce"`
+	Name              string `json:"name"`
+	PreferredUsername string `json:"preferred_username"`
+	Email             string `json:"email"`
+}
+
+// userName returns the most readable name of the user the token was issued to
+func (claims *oidcClaims) userName() string {
+	for _, name := range []string{claims.PreferredUsername, claims.Email, claims.Name} {
+		if name != "" {
+			return name
+		}
+	}
+	return claims.Subject
+}
+
+// oidcSession is the payload of the session cookie
+type oidcSession struct {
+	User   string `json:"user"`
+	Expiry int64  `json:"exp"`
+}
+
+// oidcLoginState is the payload of the login cookie, it ties the callback to the login that was started
+type oidcLoginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+	Expiry   int64  `json:"exp"`
+}
+
+// newOIDCProvider discovers the endpoints of the OpenID Connect provider
+func newOIDCProvider(ctx context.Context, config oidcConfig, httpClient *http.Client) (*oidcProvider, error) {
+	issuer := strings.TrimSuffix(config.IssuerURL, "/")
+	discoveryURL := issuer + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", discoveryURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error fetching %s: %d, %s", discoveryURL, resp.StatusCode, string(body))
+	}
+	var discovery oidcDiscovery
+	if err := json.NewDecoder(resp.Body).Decode(&discovery); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", discoveryURL, err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("the provider reports the issuer %q instead of %q", discovery.Issuer, config.IssuerURL)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("the provider metadata at %s lacks required endpoints", discoveryURL)
+	}
+
+	sessionKey := sha256.Sum256([]byte(config.SessionSecret))
+	if config.SessionSecret == "" {
+		log.Warn("OIDC_SESSION_SECRET is not set, users have to log in again after every restart")
+		if _, err := rand.Read(sessionKey[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	scopes := []string{"openid"}
+	for _, scope := range config.Scopes {
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	audience := config.Audience
+	if audience == "" {
+		audience = config.ClientID
+	}
+
+	signingAlgorithms := make([]string, len(oidcSigningAlgorithms))
+	for i, algorithm := range oidcSigningAlgorithms {
+		signingAlgorithms[i] = string(algorithm)
+	}
+	keySet := &oidcKeySet{url: discovery.JWKSURI, httpClient: httpClient}
+	verifier := oidc.NewVerifier(discovery.Issuer, keySet, &oidc.Config{
+		// The audience is checked by verifyToken, which accepts tokens issued to the client (azp) as well
+		SkipClientIDCheck:    true,
+		SupportedSigningAlgs: signingAlgorithms,
+		// go-oidc checks the expiry against Now without tolerance, and the not before time with a few minutes of it
+		Now: func() time.Time { return time.Now().Add(-oidcClockSkew) },
+	})
+
+	return &oidcProvider{
+		oauth2: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  discovery.AuthorizationEndpoint,
+				TokenURL: discovery.TokenEndpoint,
+			},
+		},
+		audience:      audience,
+		verifier:      verifier,
+		endSessionURL: discovery.EndSessionEndpoint,
+		sessionKey:    sessionKey[:],
+		secureCookies: strings.HasPrefix(config.RedirectURL, "https://"),
+		basePath:      config.BasePath,
+		httpClient:    httpClient,
+	}, nil
+}
+
+// oidcSigningAlgorithms are the algorithms tokens may be signed with
+var oidcSigningAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+}
+
+// oidcKeySet holds the keys the provider signs tokens with. The keys are fetched again for tokens signed with an
+// unknown key, as providers rotate their keys, at most once per oidcKeysRefreshInterval.
+type oidcKeySet struct {
+	url        string
+	httpClient *http.Client
+
+	mu      sync.Mutex
+	keys    jose.JSONWebKeySet
+	fetched time.Time
+}
+
+// VerifySignature verifies the signature of a token and returns its payload, it implements oidc.KeySet
+func (set *oidcKeySet) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token, oidcSigningAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("malformed token")
+	}
+	keys, err := set.signingKeys(ctx, jws.Signatures[0].Header.KeyID)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range keys {
+		if payload, err := jws.Verify(key); err == nil {
+			return payload, nil
+		}
+	}
+	return nil, errors.New("invalid token signature")
+}
+
+// signingKeys returns the keys a token with the key ID may be signed with
+func (set *oidcKeySet) signingKeys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	set.mu.Lock()
+	defer set.mu.Unlock()
+
+	if keys := set.lookup(keyID); len(keys) > 0 {
+		return keys, nil
+	}
+	if time.Since(set.fetched) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", keyID)
+	}
+
+	keys, err := set.fetch(ctx)
+	set.fetched = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	set.keys = keys
+	if keys := set.lookup(keyID); len(keys) > 0 {
+		return keys, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", keyID)
+}
+
+// lookup returns the keys with the ID, tokens without key ID are accepted if the provider has a single key
+func (set *oidcKeySet) lookup(keyID string) []jose.JSONWebKey {
+	if keyID == "" && len(set.keys.Keys) == 1 {
+		return set.keys.Keys
+	}
+	return set.keys.Key(keyID)
+}
+
+// fetch fetches the signing keys of the provider, keys of unsupported types are ignored
+func (set *oidcKeySet) fetch(ctx context.Context) (jose.JSONWebKeySet, error) {
+	var keys jose.JSONWebKeySet
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, set.url, nil)
+	if err != nil {
+		return keys, err
+	}
+	resp, err := set.httpClient.Do(req)
+	if err != nil {
+		return keys, fmt.Errorf("error fetching signing keys: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return keys, fmt.Errorf("error fetching signing keys: %d", resp.StatusCode)
+	}
+
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&jwks); err != nil {
+		return keys, fmt.Errorf("error decoding signing keys: %w", err)
+	}
+	for _, data := range jwks.Keys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(data); err != nil {
+			log.Debugf("Ignoring signing key of the OIDC provider: %v", err)
+			continue
+		}
+		if (key.Use != "" && key.Use != "sig") || !key.IsPublic() {
+			continue
+		}
+		keys.Keys = append(keys.Keys, key)
+	}
+	return keys, nil
+}
+
+// verifyToken checks the signature, issuer, audience and lifetime of a token issued by the provider and returns its
+// claims. Tokens issued to the client itself (azp) are accepted as well, Keycloak issues access tokens like that.
+func (provider *oidcProvider) verifyToken(ctx context.Context, token, audience string) (*oidcClaims, error) {
+	idToken, err := provider.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	if !slices.Contains(idToken.Audience, audience) && claims.AuthorizedParty != provider.oauth2.ClientID {
+		return nil, fmt.Errorf("token not issued for %q", audience)
+	}
+	return &claims, nil
+}
+
+// signCookie encodes the payload of a cookie and signs it, so it can't be forged
+func (provider *oidcProvider) signCookie(payload any) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	mac := hmac.New(sha256.New, provider.sessionKey)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// readCookie verifies the signature of a cookie and decodes its payload
+func (provider *oidcProvider) readCookie(value string, payload any) error {
+	encoded, signature, found := strings.Cut(value, ".")
+	if !found {
+		return errors.New("malformed cookie")
+	}
+	mac := hmac.New(sha256.New, provider.sessionKey)
+	mac.Write([]byte(encoded))
+	expected := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(expected)) != 1 {
+		return errors.New("invalid cookie signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return errors.New("malformed cookie")
+	}
+	return json.Unmarshal(data, payload)
+}
+
+// setCookie sets or, with an empty value, deletes a cookie
+func (provider *oidcProvider) setCookie(c *gin.Context, name, value, path string, lifetime time.Duration) {
+	maxAge := int(lifetime.Seconds())
+	if value == "" {
+		maxAge = -1
+	}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(name, value, maxAge, path, "", provider.secureCookies, true)
+}
+
+// authenticate returns the user a request was made by, with the session cookie of the web UI or a bearer token
+func (provider *oidcProvider) authenticate(r *http.Request) (string, bool) {
+	if cookie, err := r.Cookie(oidcSessionCookie); err == nil {
+		var session oidcSession
+		if err := provider.readCookie(cookie.Value, &session); err == nil && time.Now().Unix() < session.Expiry {
+			return session.User, true
+		}
+	}
+
+	token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !found {
+		return "", false
+	}
+	claims, err := provider.verifyToken(r.Context(), strings.TrimSpace(token), provider.audience)
+	if err != nil {
+		log.Debugf("Rejected bearer token: %v", err)
+		return "", false
+	}
+	return claims.userName(), true
+}
+
+// requireLogin redirects users of the web UI to the login unless they are logged in. Without OIDC, it allows all requests.
+func (provider *oidcProvider) requireLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if provider == nil {
+			c.Next()
+			return
+		}
+		if _, ok := provider.authenticate(c.Request); ok {
+			c.Next()
+			return
+		}
+		c.Redirect(http.StatusFound, provider.basePath+"/auth/login?return_to="+url.QueryEscape(c.Request.URL.RequestURI()))
+		c.Abort()
+	}
+}
+
+// localReturnPath returns the path to return to after logging in, only paths of paperless-gpt under the base path are
+// allowed
+func localReturnPath(returnTo, basePath string) string {
+	if !strings.HasPrefix(returnTo, basePath+"/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return basePath + "/"
+	}
+	return returnTo
+}
+
+// randomToken returns a random URL-safe string for the state and nonce of a login
+func randomToken() (string, error) {
+	data := make([]byte, 32)
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// exchangeContext makes the oauth2 package use the HTTP client of the provider
+func (provider *oidcProvider) exchangeContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, oauth2.HTTPClient, provider.httpClient)
+}
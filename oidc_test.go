@@ -0,0 +1,303 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOIDCProvider is an OpenID Connect provider issuing tokens signed with its RSA keys
+type fakeOIDCProvider struct {
+	server       *httptest.Server
+	nonce        string // Nonce of the last authorization request
+	jwksRequests atomic.Int32
+
+	mu    sync.Mutex
+	keys  map[string]*rsa.PrivateKey
+	keyID string // Key tokens are signed with
+}
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	provider := &fakeOIDCProvider{keys: map[string]*rsa.PrivateKey{}}
+	provider.rotateKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                provider.server.URL,
+			AuthorizationEndpoint: provider.server.URL + "/authorize",
+			TokenEndpoint:         provider.server.URL + "/token",
+			JWKSURI:               provider.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		provider.jwksRequests.Add(1)
+		provider.mu.Lock()
+		defer provider.mu.Unlock()
+		keys := []map[string]string{}
+		for keyID, key := range provider.keys {
+			keys = append(keys, map[string]string{
+				"kty": "RSA",
+				"kid": keyID,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": keys})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "auth-code", r.Form.Get("code"))
+		assert.NotEmpty(t, r.Form.Get("code_verifier"))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token": "opaque",
+			"token_type":   "Bearer",
+			"id_token": provider.sign(t, map[string]any{
+				"iss": provider.server.URL, "aud": "paperless-gpt", "sub": "1234", "preferred_username": "jane",
+				"nonce": provider.nonce, "exp": time.Now().Add(time.Hour).Unix(),
+			}),
+		})
+	})
+	provider.server = httptest.NewServer(mux)
+	t.Cleanup(provider.server.Close)
+	return provider
+}
+
+// rotateKey adds a key and signs the tokens issued from now on with it
+func (provider *fakeOIDCProvider) rotateKey(t *testing.T, keyID string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.keys[keyID] = key
+	provider.keyID = keyID
+}
+
+// sign issues a token with the claims
+func (provider *fakeOIDCProvider) sign(t *testing.T, claims map[string]any) string {
+	provider.mu.Lock()
+	keyID := provider.keyID
+	provider.mu.Unlock()
+	return provider.signWithHeader(t, map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"}, claims)
+}
+
+// signWithHeader issues a token with the header and claims, signed with RS256 whatever algorithm the header names
+func (provider *fakeOIDCProvider) signWithHeader(t *testing.T, header map[string]string, claims map[string]any) string {
+	provider.mu.Lock()
+	key := provider.keys[provider.keyID]
+	provider.mu.Unlock()
+	headerJSON, err := json.Marshal(header)
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	hashed := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+	require.NoError(t, err)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerifyToken(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+	provider, err := newOIDCProvider(context.Background(), oidcConfig{
+		IssuerURL: fake.server.URL, ClientID: "paperless-gpt", RedirectURL: "http://localhost:8080/auth/callback",
+		Audience: "paperless-gpt-api", SessionSecret: "secret",
+	}, fake.server.Client())
+	require.NoError(t, err)
+
+	valid := map[string]any{"iss": fake.server.URL, "aud": []string{"paperless-gpt-api"}, "sub": "1234", "email": "jane@example.com", "exp": time.Now().Add(time.Hour).Unix()}
+	claims, err := provider.verifyToken(context.Background(), fake.sign(t, valid), provider.audience)
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", claims.userName())
+
+	withClaim := func(name string, value any) map[string]any {
+		claims := map[string]any{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[name] = value
+		return claims
+	}
+	unsigned := fake.signWithHeader(t, map[string]string{"alg": "none", "kid": "key-1"}, valid)
+	testCases := map[string]string{
+		"other audience":         fake.sign(t, withClaim("aud", "other-app")),
+		"other audiences":        fake.sign(t, withClaim("aud", []string{"other-app", "paperless-gpt-other"})),
+		"other issuer":           fake.sign(t, withClaim("iss", "https://evil.example.com")),
+		"expired":                fake.sign(t, withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"expired beyond skew":    fake.sign(t, withClaim("exp", time.Now().Add(-2*oidcClockSkew).Unix())),
+		"without expiry":         fake.sign(t, withClaim("exp", nil)),
+		"not valid yet":          fake.sign(t, withClaim("nbf", time.Now().Add(time.Hour).Unix())),
+		"not valid for minutes":  fake.sign(t, withClaim("nbf", time.Now().Add(10*time.Minute).Unix())),
+		"tampered":               fake.sign(t, valid)[:50] + "x" + fake.sign(t, valid)[51:],
+		"malformed":              "not-a-token",
+		"algorithm none":         unsigned[:strings.LastIndex(unsigned, ".")+1],
+		"algorithm of other key": fake.signWithHeader(t, map[string]string{"alg": "ES256", "kid": "key-1"}, valid),
+		"algorithm other hash":   fake.signWithHeader(t, map[string]string{"alg": "RS512", "kid": "key-1"}, valid),
+		"algorithm HMAC":         fake.signWithHeader(t, map[string]string{"alg": "HS256", "kid": "key-1"}, valid),
+	}
+	for name, token := range testCases {
+		_, err := provider.verifyToken(context.Background(), token, provider.audience)
+		assert.Error(t, err, name)
+	}
+
+	// The clocks of paperless-gpt and the provider may differ a little
+	for name, claims := range map[string]map[string]any{
+		"expired within skew":    withClaim("exp", time.Now().Add(-oidcClockSkew/2).Unix()),
+		"not valid for a moment": withClaim("nbf", time.Now().Add(oidcClockSkew/2).Unix()),
+		"audience among others":  withClaim("aud", []string{"other-app", "paperless-gpt-api"}),
+		"single audience":        withClaim("aud", "paperless-gpt-api"),
+	} {
+		_, err := provider.verifyToken(context.Background(), fake.sign(t, claims), provider.audience)
+		assert.NoError(t, err, name)
+	}
+
+	// Keycloak issues access tokens for other audiences with the client as authorized party
+	_, err = provider.verifyToken(context.Background(), fake.sign(t, withClaim("azp", "paperless-gpt")), provider.audience)
+	assert.NoError(t, err)
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+	keySet := &oidcKeySet{url: fake.server.URL + "/jwks", httpClient: fake.server.Client()}
+	claims := map[string]any{"iss": fake.server.URL, "aud": "paperless-gpt", "exp": time.Now().Add(time.Hour).Unix()}
+
+	_, err := keySet.VerifySignature(context.Background(), fake.sign(t, claims))
+	require.NoError(t, err)
+	_, err = keySet.VerifySignature(context.Background(), fake.sign(t, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), fake.jwksRequests.Load(), "known keys are not fetched again")
+
+	// Tokens signed with a new key are rejected until the keys may be fetched again
+	fake.rotateKey(t, "key-2")
+	_, err = keySet.VerifySignature(context.Background(), fake.sign(t, claims))
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Equal(t, int32(1), fake.jwksRequests.Load())
+
+	keySet.fetched = time.Now().Add(-oidcKeysRefreshInterval)
+	_, err = keySet.VerifySignature(context.Background(), fake.sign(t, claims))
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), fake.jwksRequests.Load())
+
+	// Unknown keys don't make every token fetch the keys
+	_, err = keySet.VerifySignature(context.Background(), fake.signWithHeader(t, map[string]string{"alg": "RS256", "kid": "key-3"}, claims))
+	assert.ErrorContains(t, err, "unknown signing key")
+	_, err = keySet.VerifySignature(context.Background(), fake.signWithHeader(t, map[string]string{"alg": "RS256", "kid": "key-4"}, claims))
+	assert.ErrorContains(t, err, "unknown signing key")
+	assert.Equal(t, int32(2), fake.jwksRequests.Load())
+}
+
+func TestOIDCLogin(t *testing.T) {
+	fake := newFakeOIDCProvider(t)
+	provider, err := newOIDCProvider(context.Background(), oidcConfig{
+		IssuerURL: fake.server.URL, ClientID: "paperless-gpt", ClientSecret: "client-secret",
+		RedirectURL: "http://localhost:8080/auth/callback", SessionSecret: "secret",
+	}, fake.server.Client())
+	require.NoError(t, err)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/auth/login", provider.loginHandler)
+	router.GET("/auth/callback", provider.callbackHandler)
+	router.GET("/history", provider.requireLogin(), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.Group("/api", apiAuth(nil, nil, provider)).GET("/tags", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("user")) })
+
+	// Opening the web UI without session starts the login
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/history", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/auth/login?return_to=%2Fhistory", w.Header().Get("Location"))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/auth/login?return_to=%2Fhistory", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	authURL, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, fake.server.URL+"/authorize", authURL.Scheme+"://"+authURL.Host+authURL.Path)
+	assert.Equal(t, "S256", authURL.Query().Get("code_challenge_method"))
+	fake.nonce = authURL.Query().Get("nonce")
+	loginCookies := w.Result().Cookies()
+
+	// A callback of another login is rejected
+	w = httptest.NewRecorder()
+	request := httptest.NewRequest("GET", "/auth/callback?code=auth-code&state=forged", nil)
+	for _, cookie := range loginCookies {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/auth/callback?code=auth-code&state="+authURL.Query().Get("state"), nil)
+	for _, cookie := range loginCookies {
+		request.AddCookie(cookie)
+	}
+	router.ServeHTTP(w, request)
+	require.Equal(t, http.StatusFound, w.Code, w.Body.String())
+	assert.Equal(t, "/history", w.Header().Get("Location"))
+
+	var session *http.Cookie
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oidcSessionCookie {
+			session = cookie
+		}
+	}
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+
+	// The session grants access to the web UI and the API
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/api/tags", nil)
+	request.AddCookie(session)
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "jane", w.Body.String())
+
+	// API clients use bearer tokens of the provider
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/api/tags", nil)
+	request.Header.Set("Authorization", "Bearer "+fake.sign(t, map[string]any{
+		"iss": fake.server.URL, "aud": "paperless-gpt", "sub": "svc-backup", "exp": time.Now().Add(time.Minute).Unix(),
+	}))
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "svc-backup", w.Body.String())
+
+	// Forged sessions are rejected
+	w = httptest.NewRecorder()
+	request = httptest.NewRequest("GET", "/api/tags", nil)
+	forged, err := (&oidcProvider{sessionKey: []byte("guessed")}).signCookie(oidcSession{User: "admin", Expiry: time.Now().Add(time.Hour).Unix()})
+	require.NoError(t, err)
+	request.AddCookie(&http.Cookie{Name: oidcSessionCookie, Value: forged})
+	router.ServeHTTP(w, request)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestLocalReturnPath(t *testing.T) {
+	assert.Equal(t, "/history?page=2", localReturnPath("/history?page=2", ""))
+	for _, external := range []string{"", "https://evil.example.com", "//evil.example.com", "/\\evil.example.com"} {
+		assert.Equal(t, "/", localReturnPath(external, ""), external)
+	}
+
+	// Under a base path, only its pages are allowed
+	assert.Equal(t, "/paperless-gpt/history", localReturnPath("/paperless-gpt/history", "/paperless-gpt"))
+	assert.Equal(t, "/paperless-gpt/", localReturnPath("/paperless", "/paperless-gpt"))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// openAPISpec is the OpenAPI 3 specification of the /api routes. Keep it in sync when adding or changing routes,
+// TestOpenAPISpecCoversRoutes fails for undocumented routes.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// swaggerUIAssets are the files of Swagger UI served under /api/docs, see swagger-ui-dist/README.md
+//
+//go:embed swagger-ui-dist/swagger-ui-bundle.js swagger-ui-dist/swagger-ui.css
+var swaggerUIAssets embed.FS
+
+// swaggerUIPage renders the OpenAPI specification with Swagger UI. The Swagger UI assets are embedded, so the page
+// works without internet access in the browser.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8" />
+  <meta name="viewport" content="width=device-width, initial-scale=1" />
+  <title>paperless-gpt API</title>
+  <link rel="stylesheet" href="docs/swagger-ui.css" />
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="docs/swagger-ui-bundle.js"></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({ url: "openapi.json", dom_id: "#swagger-ui" });
+    };
+  </script>
+</body>
+</html>
+`
+
+// openAPISpecHandler handles the GET /api/openapi.json endpoint
+func openAPISpecHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openAPISpec)
+}
+
+// swaggerUIHandler handles the GET /api/docs endpoint
+func swaggerUIHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(swaggerUIPage))
+}
+
+// swaggerUIAssetHandler handles the GET /api/docs/:file endpoint
+func swaggerUIAssetHandler(c *gin.Context) {
+	name := path.Base(c.Param("file"))
+	content, err := swaggerUIAssets.ReadFile("swagger-ui-dist/" + name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(content))
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPISpecCoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(openAPISpec, &spec))
+	assert.True(t, strings.HasPrefix(spec.OpenAPI, "3."))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	app := &App{}
+	app.registerAPIRoutes(router.Group("/api"))
+
+	// Path parameters are written as :id by gin and as {id} by OpenAPI
+	pathParameter := regexp.MustCompile(`:(\w+)`)
+	registered := map[string]bool{}
+	for _, route := range router.Routes() {
+		path := pathParameter.ReplaceAllString(route.Path, "{$1}")
+		method := strings.ToLower(route.Method)
+		registered[method+" "+path] = true
+		assert.Contains(t, spec.Paths[path], method, "%s %s is not documented in openapi.json", route.Method, route.Path)
+	}
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			assert.True(t, registered[method+" "+path], "openapi.json documents %s %s, which is not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPIHandlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/openapi.json", openAPISpecHandler)
+	router.GET("/api/docs", swaggerUIHandler)
+	router.GET("/api/docs/:file", swaggerUIAssetHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, json.Valid(w.Body.Bytes()))
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `url: "openapi.json"`)
+	assert.NotContains(t, w.Body.String(), "https://", "the assets are served by paperless-gpt")
+
+	// The assets of the page are embedded
+	for file, contentType := range map[string]string{"swagger-ui-bundle.js": "javascript", "swagger-ui.css": "text/css"} {
+		assert.Contains(t, w.Body.String(), `"docs/`+file+`"`)
+		asset := httptest.NewRecorder()
+		router.ServeHTTP(asset, httptest.NewRequest("GET", "/api/docs/"+file, nil))
+		require.Equal(t, http.StatusOK, asset.Code, file)
+		assert.Contains(t, asset.Header().Get("Content-Type"), contentType, file)
+		assert.NotEmpty(t, asset.Body.Bytes(), file)
+	}
+	for _, file := range []string{"index.html", "..%2Fopenapi.json"} {
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/api/docs/"+file, nil))
+		assert.Equal(t, http.StatusNotFound, w.Code, file)
+	}
+}
@@ -6,20 +6,27 @@ import (
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"hash/fnv"
 	"image/jpeg"
 	"io"
+	"iter"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gen2brain/go-fitz"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
+	"golang.org/x/time/rate"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +36,13 @@ type PaperlessClient struct {
 	APIToken    string
 	HTTPClient  *http.Client
 	CacheFolder string
+
+	limiter *rate.Limiter
+	retry   retryPolicy
+	breaker *circuitBreaker
+	cacheMu sync.Mutex
+	auth    paperlessAuth
+	tokenMu sync.Mutex
 }
 
 func hasSameTags(original, suggested []string) bool {
@@ -62,10 +76,14 @@ func NewPaperlessClient(baseURL, apiToken string) *PaperlessClient {
 	cacheFolder := os.Getenv("PAPERLESS_GPT_CACHE_DIR")
 
 	// Create a custom HTTP transport with TLS configuration
-	tr := &http.Transport{
-		TLSClientConfig: &tls.Config{
+	tlsConfig := paperlessTLSConfig
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{
 			InsecureSkipVerify: paperlessInsecureSkipVerify,
-		},
+		}
+	}
+	tr := &http.Transport{
+		TLSClientConfig: tlsConfig,
 	}
 	httpClient := &http.Client{Transport: tr}
 
@@ -74,20 +92,109 @@ func NewPaperlessClient(baseURL, apiToken string) *PaperlessClient {
 		APIToken:    apiToken,
 		HTTPClient:  httpClient,
 		CacheFolder: cacheFolder,
+		limiter:     newRequestLimiter(paperlessRateLimit),
+		retry: retryPolicy{
+			MaxRetries: paperlessMaxRetries,
+			BaseDelay:  time.Second,
+			MaxDelay:   30 * time.Second,
+		},
+		breaker: newCircuitBreaker(circuitBreakerThreshold, time.Duration(circuitBreakerCooldown)*time.Second),
+		auth: paperlessAuth{
+			Header:        paperlessAuthHeader,
+			HeaderValue:   paperlessAuthHeaderValue,
+			SessionCookie: paperlessSessionCookie,
+			Username:      paperlessUsername,
+			Password:      paperlessPassword,
+		},
 	}
 }
 
-// Do method to make requests to the Paperless-NGX API
+// Do method to make requests to the Paperless-NGX API.
+// Requests are rate limited, retried when paperless-ngx is temporarily overloaded and
+// rejected right away while the circuit breaker is open.
 func (client *PaperlessClient) Do(ctx context.Context, method, path string, body io.Reader) (*http.Response, error) {
+	// The body is buffered so it can be sent again when the request is retried
+	var bodyBytes []byte
+	if body != nil {
+		var err error
+		bodyBytes, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !client.breaker.allow() {
+		return nil, errCircuitOpen
+	}
+
+	if err := client.ensureToken(ctx); err != nil {
+		return nil, err
+	}
+
+	tokenRefreshed := false
+	for attempt := 0; ; attempt++ {
+		if client.limiter != nil {
+			if err := client.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+		}
+
+		token := client.token()
+		resp, err := client.doOnce(ctx, method, path, bodyBytes, body != nil)
+		if ctx.Err() != nil {
+			return resp, err
+		}
+
+		// An expired token is replaced once, without counting as a retry
+		if err == nil && resp.StatusCode == http.StatusUnauthorized && client.auth.canRefreshToken() && !tokenRefreshed {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			if err := client.refreshToken(ctx, token); err != nil {
+				return nil, err
+			}
+			tokenRefreshed = true
+			attempt--
+			continue
+		}
+
+		retryable := (err != nil && isIdempotentMethod(method)) || (err == nil && isRetryableStatus(resp.StatusCode))
+		if !retryable || attempt >= client.retry.MaxRetries {
+			client.breaker.record(err == nil && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests)
+			return resp, err
+		}
+
+		delay := client.retry.delay(attempt, resp)
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			log.Warnf("paperless-ngx responded with %d to %s %s, retrying in %s", resp.StatusCode, method, path, delay)
+		} else {
+			log.Warnf("Request %s %s failed, retrying in %s: %v", method, path, delay, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// doOnce sends a single request to the Paperless-NGX API
+func (client *PaperlessClient) doOnce(ctx context.Context, method, path string, bodyBytes []byte, hasBody bool) (*http.Response, error) {
 	url := fmt.Sprintf("%s/%s", client.BaseURL, strings.TrimLeft(path, "/"))
+	var body io.Reader
+	if hasBody {
+		body = bytes.NewReader(bodyBytes)
+	}
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("Token %s", client.APIToken))
+	client.authenticate(req)
 
 	// Set Content-Type if body is present
-	if body != nil {
+	if hasBody {
 		req.Header.Set("Content-Type", "application/json")
 	}
 
@@ -174,48 +281,501 @@ func (client *PaperlessClient) GetAllTags(ctx context.Context) (map[string]int,
 		}
 
 		// Extract relative path from the Next URL
-		if tagsResponse.Next != "" {
-			nextURL := tagsResponse.Next
-			if strings.HasPrefix(nextURL, "http") {
-				// Extract just the path portion from the full URL
-				if parsedURL, err := url.Parse(nextURL); err == nil {
-					path = strings.TrimPrefix(parsedURL.Path, "/")
-					if parsedURL.RawQuery != "" {
-						path += "?" + parsedURL.RawQuery
-					}
-				} else {
-					return nil, fmt.Errorf("failed to parse next URL: %v", err)
-				}
-			} else {
-				path = strings.TrimPrefix(nextURL, "/")
-			}
-		} else {
-			path = ""
+		path, err = nextPagePath(tagsResponse.Next)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return tagIDMapping, nil
 }
 
-// GetDocumentsByTags retrieves documents that match the specified tags
-func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []string, pageSize int) ([]Document, error) {
+// GetSavedView retrieves a saved view by its ID or name
+func (client *PaperlessClient) GetSavedView(ctx context.Context, nameOrID string) (SavedView, error) {
+	path := "api/saved_views/"
+
+	for path != "" {
+		resp, err := client.Do(ctx, "GET", path, nil)
+		if err != nil {
+			return SavedView{}, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return SavedView{}, fmt.Errorf("error fetching saved views: %d, %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var savedViewsResponse struct {
+			Results []SavedView `json:"results"`
+			Next    string      `json:"next"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&savedViewsResponse)
+		if err != nil {
+			return SavedView{}, err
+		}
+
+		for _, view := range savedViewsResponse.Results {
+			if strconv.Itoa(view.ID) == nameOrID || strings.EqualFold(view.Name, nameOrID) {
+				return view, nil
+			}
+		}
+
+		path, err = nextPagePath(savedViewsResponse.Next)
+		if err != nil {
+			return SavedView{}, err
+		}
+	}
+
+	return SavedView{}, fmt.Errorf("saved view %q not found", nameOrID)
+}
+
+// nextPagePath converts the "next" URL of a paginated response into a path relative to the base URL.
+// An empty string is returned when there is no next page.
+func nextPagePath(nextURL string) (string, error) {
+	if nextURL == "" {
+		return "", nil
+	}
+	if !strings.HasPrefix(nextURL, "http") {
+		return strings.TrimPrefix(nextURL, "/"), nil
+	}
+
+	// Extract just the path portion from the full URL
+	parsedURL, err := url.Parse(nextURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse next URL: %v", err)
+	}
+	path := strings.TrimPrefix(parsedURL.Path, "/")
+	if parsedURL.RawQuery != "" {
+		path += "?" + parsedURL.RawQuery
+	}
+	return path, nil
+}
+
+// GetAllDocumentTypes retrieves all document types from the Paperless-NGX API
+func (client *PaperlessClient) GetAllDocumentTypes(ctx context.Context) (map[string]int, error) {
+	documentTypeIDMapping := make(map[string]int)
+	path := "api/document_types/"
+
+	for path != "" {
+		resp, err := client.Do(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return nil, fmt.Errorf("error fetching document types: %d, %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var documentTypesResponse struct {
+			Results []struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+			} `json:"results"`
+			Next string `json:"next"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&documentTypesResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, documentType := range documentTypesResponse.Results {
+			documentTypeIDMapping[documentType.Name] = documentType.ID
+		}
+
+		path, err = nextPagePath(documentTypesResponse.Next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return documentTypeIDMapping, nil
+}
+
+// GetAllStoragePaths retrieves all storage paths from the Paperless-NGX API
+func (client *PaperlessClient) GetAllStoragePaths(ctx context.Context) ([]StoragePath, error) {
+	var storagePaths []StoragePath
+	path := "api/storage_paths/"
+
+	for path != "" {
+		resp, err := client.Do(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return nil, fmt.Errorf("error fetching storage paths: %d, %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var storagePathsResponse struct {
+			Results []StoragePath `json:"results"`
+			Next    string        `json:"next"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&storagePathsResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		storagePaths = append(storagePaths, storagePathsResponse.Results...)
+
+		path, err = nextPagePath(storagePathsResponse.Next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return storagePaths, nil
+}
+
+// CreateStoragePath creates a new storage path and returns it as stored by paperless-ngx
+func (client *PaperlessClient) CreateStoragePath(ctx context.Context, storagePath StoragePath) (StoragePath, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"name":               storagePath.Name,
+		"path":               storagePath.Path,
+		"match":              storagePath.Match,
+		"matching_algorithm": storagePath.MatchingAlgorithm,
+		"is_insensitive":     storagePath.IsInsensitive,
+	})
+	if err != nil {
+		return StoragePath{}, err
+	}
+
+	resp, err := client.Do(ctx, "POST", "api/storage_paths/", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return StoragePath{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return StoragePath{}, fmt.Errorf("error creating storage path: %d, %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var created StoragePath
+	err = json.NewDecoder(resp.Body).Decode(&created)
+	if err != nil {
+		return StoragePath{}, err
+	}
+
+	log.Infof("Created storage path %s with ID %d", created.Name, created.ID)
+	return created, nil
+}
+
+// storagePathNameByID returns the name of the storage path with the given ID, or an empty string if unknown
+func storagePathNameByID(storagePaths []StoragePath, storagePathID int) string {
+	for _, storagePath := range storagePaths {
+		if storagePath.ID == storagePathID {
+			return storagePath.Name
+		}
+	}
+	return ""
+}
+
+// documentTypeNameByID returns the name of the document type with the given ID, or an empty string if unknown
+func documentTypeNameByID(documentTypes map[string]int, documentTypeID int) string {
+	for name, id := range documentTypes {
+		if id == documentTypeID {
+			return name
+		}
+	}
+	return ""
+}
+
+// GetAllCustomFields retrieves all custom field definitions from the Paperless-NGX API
+func (client *PaperlessClient) GetAllCustomFields(ctx context.Context) ([]CustomField, error) {
+	var customFields []CustomField
+	path := "api/custom_fields/"
+
+	for path != "" {
+		resp, err := client.Do(ctx, "GET", path, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			bodyBytes, _ := io.ReadAll(resp.Body)
+			return nil, fmt.Errorf("error fetching custom fields: %d, %s", resp.StatusCode, string(bodyBytes))
+		}
+
+		var customFieldsResponse struct {
+			Results []CustomField `json:"results"`
+			Next    string        `json:"next"`
+		}
+
+		err = json.NewDecoder(resp.Body).Decode(&customFieldsResponse)
+		if err != nil {
+			return nil, err
+		}
+
+		customFields = append(customFields, customFieldsResponse.Results...)
+
+		path, err = nextPagePath(customFieldsResponse.Next)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return customFields, nil
+}
+
+// GetCustomField retrieves a single custom field definition
+func (client *PaperlessClient) GetCustomField(ctx context.Context, fieldID int) (CustomField, error) {
+	path := fmt.Sprintf("api/custom_fields/%d/", fieldID)
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return CustomField{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return CustomField{}, fmt.Errorf("error fetching custom field %d: %d, %s", fieldID, resp.StatusCode, string(bodyBytes))
+	}
+
+	var customField CustomField
+	err = json.NewDecoder(resp.Body).Decode(&customField)
+	if err != nil {
+		return CustomField{}, err
+	}
+	return customField, nil
+}
+
+// CreateCustomField creates a new custom field and returns the created definition including its ID
+func (client *PaperlessClient) CreateCustomField(ctx context.Context, field CustomField) (CustomField, error) {
+	return client.saveCustomField(ctx, "POST", "api/custom_fields/", field, http.StatusCreated)
+}
+
+// UpdateCustomField updates the name and settings of an existing custom field.
+// paperless-ngx does not allow changing the data type of a field that is in use.
+func (client *PaperlessClient) UpdateCustomField(ctx context.Context, field CustomField) (CustomField, error) {
+	return client.saveCustomField(ctx, "PATCH", fmt.Sprintf("api/custom_fields/%d/", field.ID), field, http.StatusOK)
+}
+
+// saveCustomField sends a custom field definition to paperless-ngx
+func (client *PaperlessClient) saveCustomField(ctx context.Context, method, path string, field CustomField, expectedStatus int) (CustomField, error) {
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"name":       field.Name,
+		"data_type":  field.DataType,
+		"extra_data": field.ExtraData,
+	})
+	if err != nil {
+		return CustomField{}, err
+	}
+
+	resp, err := client.Do(ctx, method, path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return CustomField{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != expectedStatus {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return CustomField{}, fmt.Errorf("error saving custom field %s: %d, %s", field.Name, resp.StatusCode, string(bodyBytes))
+	}
+
+	var savedField CustomField
+	err = json.NewDecoder(resp.Body).Decode(&savedField)
+	if err != nil {
+		return CustomField{}, err
+	}
+	return savedField, nil
+}
+
+// DeleteCustomField deletes a custom field, which also removes its values from all documents
+func (client *PaperlessClient) DeleteCustomField(ctx context.Context, fieldID int) error {
+	path := fmt.Sprintf("api/custom_fields/%d/", fieldID)
+	resp, err := client.Do(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error deleting custom field %d: %d, %s", fieldID, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// SetDocumentCustomFields sets custom field values of a document. Values of other fields are kept,
+// as paperless-ngx replaces the whole list of custom fields on update.
+func (client *PaperlessClient) SetDocumentCustomFields(ctx context.Context, documentID int, values []CustomFieldValue) error {
+	path := fmt.Sprintf("api/documents/%d/", documentID)
+	resp, err := client.Do(ctx, "GET", path+"?fields=custom_fields", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error fetching custom fields of document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	var document struct {
+		CustomFields []CustomFieldValue `json:"custom_fields"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&document)
+	if err != nil {
+		return err
+	}
+
+	jsonData, err := json.Marshal(map[string]interface{}{
+		"custom_fields": mergeCustomFields(document.CustomFields, values),
+	})
+	if err != nil {
+		return err
+	}
+
+	resp, err = client.Do(ctx, "PATCH", path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error setting custom fields of document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// NewCustomFieldValue converts a value to the representation paperless-ngx expects for the field's data type,
+// e.g. a select option label to its ID or a number to a monetary value in the field's default currency
+func NewCustomFieldValue(field CustomField, value interface{}) (CustomFieldValue, error) {
+	converted, err := convertCustomFieldValue(field, value)
+	if err != nil {
+		return CustomFieldValue{}, fmt.Errorf("invalid value for custom field %s: %v", field.Name, err)
+	}
+	return CustomFieldValue{Field: field.ID, Value: converted}, nil
+}
+
+// GetDocumentsByTags retrieves up to limit documents that match the specified tags.
+// A limit <= 0 returns all matching documents.
+func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []string, limit int) ([]Document, error) {
 	tagQueries := make([]string, len(tags))
 	for i, tag := range tags {
 		tagQueries[i] = fmt.Sprintf("tags__name__iexact=%s", tag)
 	}
 	searchQuery := strings.Join(tagQueries, "&")
-	path := fmt.Sprintf("api/documents/?%s&page_size=%d", urlEncode(searchQuery), pageSize)
 
+	pageSize := paperlessPageSize
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+
+	documents := []Document{}
+	for document, err := range client.Documents(ctx, urlEncode(searchQuery), pageSize) {
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+		if limit > 0 && len(documents) >= limit {
+			break
+		}
+	}
+
+	return documents, nil
+}
+
+// Documents returns an iterator over all documents matching the filter query, e.g. "tags__name__iexact=paperless-gpt".
+// The pages of paperless-ngx are fetched one at a time while iterating, so only a single page is held in memory.
+// The iteration stops after the first error.
+func (client *PaperlessClient) Documents(ctx context.Context, query string, pageSize int) iter.Seq2[Document, error] {
+	return func(yield func(Document, error) bool) {
+		// full_perms includes the permissions, so they can be sent back unchanged when the documents are updated
+		path := fmt.Sprintf("api/documents/?page_size=%d&full_perms=true", pageSize)
+		if query != "" {
+			path = fmt.Sprintf("api/documents/?%s&page_size=%d&full_perms=true", query, pageSize)
+		}
+		names := &documentNames{}
+
+		for path != "" {
+			page, err := client.getDocumentsPage(ctx, path)
+			if err != nil {
+				yield(Document{}, err)
+				return
+			}
+
+			documents, err := client.resolveDocumentNames(ctx, page.Results, names)
+			if err != nil {
+				yield(Document{}, err)
+				return
+			}
+			for _, document := range documents {
+				if !yield(document, nil) {
+					return
+				}
+			}
+
+			path, err = nextPagePath(page.Next)
+			if err != nil {
+				yield(Document{}, err)
+				return
+			}
+		}
+	}
+}
+
+// GetDocumentsPage returns a single page of the documents matching the filter query and the number of all matching
+// documents. Pages are numbered from 1.
+func (client *PaperlessClient) GetDocumentsPage(ctx context.Context, query string, page, pageSize int) ([]Document, int, error) {
+	path := fmt.Sprintf("api/documents/?%s&page=%d&page_size=%d&full_perms=true", query, page, pageSize)
+	if query == "" {
+		path = fmt.Sprintf("api/documents/?page=%d&page_size=%d&full_perms=true", page, pageSize)
+	}
+	response, err := client.getDocumentsPage(ctx, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	documents, err := client.resolveDocumentNames(ctx, response.Results, &documentNames{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return documents, response.Count, nil
+}
+
+// SearchDocuments runs a full-text search of paperless-ngx and returns a single page of the matching documents, their
+// search hits and the number of all matching documents. Pages are numbered from 1.
+func (client *PaperlessClient) SearchDocuments(ctx context.Context, text string, page, pageSize int) ([]Document, []*SearchHit, int, error) {
+	path := fmt.Sprintf("api/documents/?query=%s&page=%d&page_size=%d&full_perms=true", url.QueryEscape(text), page, pageSize)
+	response, err := client.getDocumentsPage(ctx, path)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	documents, err := client.resolveDocumentNames(ctx, response.Results, &documentNames{})
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	hits := make([]*SearchHit, len(response.Results))
+	for i, result := range response.Results {
+		hits[i] = result.SearchHit
+	}
+	return documents, hits, response.Count, nil
+}
+
+// getDocumentsPage fetches a single page of a document listing
+func (client *PaperlessClient) getDocumentsPage(ctx context.Context, path string) (GetDocumentsApiResponse, error) {
 	resp, err := client.Do(ctx, "GET", path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("HTTP request failed in GetDocumentsByTags: %w", err)
+		return GetDocumentsApiResponse{}, fmt.Errorf("HTTP request failed in GetDocumentsByTags: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read the response body
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return GetDocumentsApiResponse{}, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
@@ -225,7 +785,7 @@ func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []st
 			"response":    string(bodyBytes),
 			"headers":     resp.Header,
 		}).Error("Error response from server in GetDocumentsByTags")
-		return nil, fmt.Errorf("error searching documents: status=%d, body=%s", resp.StatusCode, string(bodyBytes))
+		return GetDocumentsApiResponse{}, fmt.Errorf("error searching documents: status=%d, body=%s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var documentsResponse GetDocumentsApiResponse
@@ -235,24 +795,58 @@ func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []st
 			"response_body": string(bodyBytes),
 			"error":         err,
 		}).Error("Failed to parse JSON response in GetDocumentsByTags")
-		return nil, fmt.Errorf("failed to parse JSON response: %w", err)
+		return GetDocumentsApiResponse{}, fmt.Errorf("failed to parse JSON response: %w", err)
 	}
 
-	allTags, err := client.GetAllTags(ctx)
-	if err != nil {
-		return nil, err
+	return documentsResponse, nil
+}
+
+// documentNames holds the tags, correspondents, document types and storage paths needed to resolve the IDs
+// of a document listing to names. They are fetched on first use and reused for all pages of the listing.
+type documentNames struct {
+	tags           map[string]int
+	correspondents map[string]int
+	documentTypes  map[string]int
+	storagePaths   []StoragePath
+}
+
+// resolveDocumentNames converts the documents of a listing page, resolving tag, correspondent, document type and storage path IDs to names
+func (client *PaperlessClient) resolveDocumentNames(ctx context.Context, results []GetDocumentApiResponseResult, names *documentNames) ([]Document, error) {
+	var err error
+	if names.tags == nil {
+		names.tags, err = client.GetAllTags(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if names.correspondents == nil {
+		names.correspondents, err = client.GetAllCorrespondents(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	allCorrespondents, err := client.GetAllCorrespondents(ctx)
-	if err != nil {
-		return nil, err
+	// Document types and storage paths are only needed if any of the documents has one
+	for _, result := range results {
+		if result.DocumentType != 0 && names.documentTypes == nil {
+			names.documentTypes, err = client.GetAllDocumentTypes(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if result.StoragePath != 0 && names.storagePaths == nil {
+			names.storagePaths, err = client.GetAllStoragePaths(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
-	documents := make([]Document, 0, len(documentsResponse.Results))
-	for _, result := range documentsResponse.Results {
+	documents := make([]Document, 0, len(results))
+	for _, result := range results {
 		tagNames := make([]string, len(result.Tags))
 		for i, resultTagID := range result.Tags {
-			for tagName, tagID := range allTags {
+			for tagName, tagID := range names.tags {
 				if resultTagID == tagID {
 					tagNames[i] = tagName
 					break
@@ -262,7 +856,7 @@ func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []st
 
 		correspondentName := ""
 		if result.Correspondent != 0 {
-			for name, id := range allCorrespondents {
+			for name, id := range names.correspondents {
 				if result.Correspondent == id {
 					correspondentName = name
 					break
@@ -271,43 +865,55 @@ func (client *PaperlessClient) GetDocumentsByTags(ctx context.Context, tags []st
 		}
 
 		documents = append(documents, Document{
-			ID:            result.ID,
-			Title:         result.Title,
-			Content:       result.Content,
-			Correspondent: correspondentName,
-			Tags:          tagNames,
-			CreatedDate:   result.CreatedDate,
+			ID:                  result.ID,
+			Title:               result.Title,
+			Content:             result.Content,
+			Correspondent:       correspondentName,
+			Tags:                tagNames,
+			CreatedDate:         result.CreatedDate,
+			CustomFields:        result.CustomFields,
+			DocumentType:        documentTypeNameByID(names.documentTypes, result.DocumentType),
+			StoragePath:         storagePathNameByID(names.storagePaths, result.StoragePath),
+			OriginalFileName:    result.OriginalFileName,
+			Added:               result.Added,
+			PageCount:           pageCount(result.PageCount),
+			ArchiveSerialNumber: result.ArchiveSerialNumber,
+			Owner:               result.Owner,
+			Permissions:         result.Permissions,
 		})
 	}
 
 	return documents, nil
 }
 
-// DownloadPDF downloads the PDF file of the specified document
+// pageCount returns the number of pages paperless-ngx counted, 0 if it didn't count them
+func pageCount(count *int) int {
+	if count == nil {
+		return 0
+	}
+	return *count
+}
+
+// DownloadPDF downloads the PDF file of the specified document, reusing a cached download of the same file
 func (client *PaperlessClient) DownloadPDF(ctx context.Context, document Document) ([]byte, error) {
-	path := fmt.Sprintf("api/documents/%d/download/", document.ID)
-	resp, err := client.Do(ctx, "GET", path, nil)
+	docDir, err := client.documentCacheDir(ctx, document.ID)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error downloading document %d: %d, %s", document.ID, resp.StatusCode, string(bodyBytes))
-	}
-
-	return io.ReadAll(resp.Body)
+	return client.downloadDocument(ctx, document.ID, docDir)
 }
 
 func (client *PaperlessClient) GetDocument(ctx context.Context, documentID int) (Document, error) {
-	path := fmt.Sprintf("api/documents/%d/", documentID)
+	path := fmt.Sprintf("api/documents/%d/?full_perms=true", documentID)
 	resp, err := client.Do(ctx, "GET", path, nil)
 	if err != nil {
 		return Document{}, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusNotFound {
+		return Document{}, fmt.Errorf("error fetching document %d: %w", documentID, errDocumentNotFound)
+	}
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return Document{}, fmt.Errorf("error fetching document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
@@ -349,217 +955,659 @@ func (client *PaperlessClient) GetDocument(ctx context.Context, documentID int)
 		}
 	}
 
-	return Document{
-		ID:            documentResponse.ID,
-		Title:         documentResponse.Title,
-		Content:       documentResponse.Content,
-		Correspondent: correspondentName,
-		Tags:          tagNames,
-		CreatedDate:   documentResponse.CreatedDate,
+	// Match document type ID to document type name
+	documentTypeName := ""
+	if documentResponse.DocumentType != 0 {
+		allDocumentTypes, err := client.GetAllDocumentTypes(ctx)
+		if err != nil {
+			return Document{}, err
+		}
+		documentTypeName = documentTypeNameByID(allDocumentTypes, documentResponse.DocumentType)
+	}
+
+	// Match storage path ID to storage path name
+	storagePathName := ""
+	if documentResponse.StoragePath != 0 {
+		allStoragePaths, err := client.GetAllStoragePaths(ctx)
+		if err != nil {
+			return Document{}, err
+		}
+		storagePathName = storagePathNameByID(allStoragePaths, documentResponse.StoragePath)
+	}
+
+	return Document{
+		ID:                  documentResponse.ID,
+		Title:               documentResponse.Title,
+		Content:             documentResponse.Content,
+		Correspondent:       correspondentName,
+		Tags:                tagNames,
+		CreatedDate:         documentResponse.CreatedDate,
+		CustomFields:        documentResponse.CustomFields,
+		DocumentType:        documentTypeName,
+		StoragePath:         storagePathName,
+		OriginalFileName:    documentResponse.OriginalFileName,
+		Added:               documentResponse.Added,
+		PageCount:           pageCount(documentResponse.PageCount),
+		ArchiveSerialNumber: documentResponse.ArchiveSerialNumber,
+		Owner:               documentResponse.Owner,
+		Permissions:         documentResponse.Permissions,
+	}, nil
+}
+
+// UpdateDocuments updates the specified documents with suggested changes
+func (client *PaperlessClient) UpdateDocuments(ctx context.Context, documents []DocumentSuggestion, db *gorm.DB, isUndo bool) error {
+	// Fetch all available tags
+	availableTags, err := client.GetAllTags(ctx)
+	if err != nil {
+		log.Errorf("Error fetching available tags: %v", err)
+		return err
+	}
+
+	documentsContainSuggestedCorrespondent := false
+	for _, document := range documents {
+		if document.SuggestedCorrespondent != "" {
+			documentsContainSuggestedCorrespondent = true
+			break
+		}
+	}
+
+	availableCorrespondents := make(map[string]int)
+	if documentsContainSuggestedCorrespondent {
+		availableCorrespondents, err = client.GetAllCorrespondents(ctx)
+		if err != nil {
+			log.Errorf("Error fetching available correspondents: %v",
+				err)
+			return err
+		}
+	}
+
+	// Storage paths can't be created from a name alone, so suggestions must refer to an existing one
+	availableStoragePaths := make(map[string]int)
+	for _, document := range documents {
+		if document.SuggestedStoragePath != "" {
+			storagePaths, err := client.GetAllStoragePaths(ctx)
+			if err != nil {
+				log.Errorf("Error fetching available storage paths: %v", err)
+				return err
+			}
+			for _, storagePath := range storagePaths {
+				availableStoragePaths[storagePath.Name] = storagePath.ID
+			}
+			break
+		}
+	}
+
+	updates := make([]*documentUpdate, 0, len(documents))
+	for _, document := range documents {
+		update, err := client.prepareDocumentUpdate(ctx, document, availableTags, availableCorrespondents, availableStoragePaths, isUndo)
+		if err != nil {
+			return err
+		}
+		updates = append(updates, update)
+	}
+
+	// Tag and correspondent changes of several documents are applied in batches, leaving only the other fields to patch
+	if len(updates) > 1 && bulkEditBatchSize > 0 {
+		if err := client.applyBulkEdits(ctx, updates, availableTags); err != nil {
+			log.Errorf("Error applying bulk edits: %v", err)
+			return err
+		}
+	}
+
+	for _, update := range updates {
+		if err := client.applyDocumentUpdate(ctx, db, update); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// documentUpdate holds the changes prepared for a single document by UpdateDocuments
+type documentUpdate struct {
+	document            DocumentSuggestion
+	originalFields      map[string]interface{}
+	updatedFields       map[string]interface{}
+	tags                []string
+	originalTagsJSON    []byte
+	updatedTagsJSON     []byte
+	previousSensitivity string
+	// createdCorrespondentID is the correspondent created for the suggestion, 0 if it already existed
+	createdCorrespondentID int
+	// bulkEdited is set once the tags and correspondent were applied by bulk edits
+	bulkEdited bool
+}
+
+// patchFields returns the fields to send in the PATCH request of the document, without the ones applied by bulk edits
+func (update *documentUpdate) patchFields() map[string]interface{} {
+	fields := make(map[string]interface{})
+	for field, value := range update.updatedFields {
+		// Removing the correspondent isn't a bulk edit
+		if update.bulkEdited && (field == "tags" || (field == "correspondent" && value != nil)) {
+			continue
+		}
+		fields[field] = value
+	}
+
+	// The owner and permissions are only sent back along with actual changes
+	for field := range fields {
+		if field != "owner" && field != "set_permissions" {
+			return fields
+		}
+	}
+	return nil
+}
+
+// prepareDocumentUpdate works out the fields to update for a suggestion, creating missing tags and correspondents in paperless-ngx
+func (client *PaperlessClient) prepareDocumentUpdate(ctx context.Context, document DocumentSuggestion, availableTags, availableCorrespondents, availableStoragePaths map[string]int, isUndo bool) (*documentUpdate, error) {
+	documentID := document.ID
+
+	//  Original fields will store any updated fields to store records for
+	originalFields := make(map[string]interface{})
+	updatedFields := make(map[string]interface{})
+	newTags := []int{}
+	settings := settingsFor(ctx)
+
+	tags := document.SuggestedTags
+	originalTags := document.OriginalDocument.Tags
+
+	originalTagsJSON, err := json.Marshal(originalTags)
+	if err != nil {
+		log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+		return nil, err
+	}
+
+	// remove autoTag to prevent infinite loop (even if it is in the original tags)
+	for _, tag := range document.RemoveTags {
+		originalTags = removeTagFromList(originalTags, tag)
+	}
+
+	if len(tags) == 0 {
+		tags = originalTags
+	} else {
+		// We have suggested tags to change
+		originalFields["tags"] = originalTags
+		// remove autoTag to prevent infinite loop - this is required in case of undo
+		tags = removeTagFromList(tags, settings.AutoTag)
+
+		// remove duplicates
+		slices.Sort(tags)
+		tags = slices.Compact(tags)
+	}
+
+	// A document has only one sensitivity level, so the tag of a previous level is replaced
+	previousSensitivity := sensitivityLevelOf(document.OriginalDocument.Tags)
+	if document.SuggestedSensitivity != "" && document.SuggestedSensitivity != previousSensitivity {
+		originalFields["tags"] = originalTags
+		tags = withSensitivityLevel(tags, "")
+	}
+
+	// Add the tags for the detected language, sensitivity, required actions, upcoming deadlines and personal data, creating them in paperless-ngx if needed
+	var generatedTags []string
+	if document.SuggestedAction != "" && settings.ActionRequiredTag != "" {
+		generatedTags = append(generatedTags, settings.ActionRequiredTag)
+	}
+	if document.SuggestedSensitivity != "" {
+		generatedTags = append(generatedTags, sensitivityTag(document.SuggestedSensitivity))
+	}
+	if document.SuggestedLanguage != "" && languageTagPrefix != "" {
+		generatedTags = append(generatedTags, languageTagPrefix+document.SuggestedLanguage)
+	}
+	if settings.DueSoonTag != "" && isDueSoon(document.SuggestedDueDate, time.Now()) {
+		generatedTags = append(generatedTags, settings.DueSoonTag)
+	}
+	if cancellationTag != "" && isWithinDays(document.SuggestedCancellationDeadline, time.Now(), cancellationHorizonDays) {
+		generatedTags = append(generatedTags, cancellationTag)
+	}
+	generatedTags = append(generatedTags, settings.piiTagsFor(document.SuggestedPIICategories)...)
+	for _, generatedTag := range generatedTags {
+		if slices.Contains(tags, generatedTag) {
+			continue
+		}
+		if _, exists := availableTags[generatedTag]; !exists {
+			tagID, err := client.CreateTag(ctx, generatedTag)
+			if err != nil {
+				log.Errorf("Error creating tag %s: %v", generatedTag, err)
+				return nil, err
+			}
+			availableTags[generatedTag] = tagID
+		}
+		originalFields["tags"] = originalTags
+		tags = append(slices.Clone(tags), generatedTag)
+		slices.Sort(tags)
+	}
+
+	updatedTagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+		return nil, err
+	}
+
+	// Map suggested tag names to IDs
+	for _, tagName := range tags {
+		if tagID, exists := availableTags[tagName]; exists {
+			// Skip the tag that we are filtering
+			if !isUndo && tagName == settings.ManualTag {
+				continue
+			}
+			newTags = append(newTags, tagID)
+		} else {
+			log.Errorf("Suggested tag '%s' does not exist in paperless-ngx, skipping.", tagName)
+		}
+	}
+	updatedFields["tags"] = newTags
+
+	// Map suggested correspondent names to IDs
+	createdCorrespondentID := 0
+	if document.SuggestedCorrespondent != "" {
+		if correspondentID, exists := availableCorrespondents[document.SuggestedCorrespondent]; exists {
+			updatedFields["correspondent"] = correspondentID
+		} else {
+			newCorrespondent := instantiateCorrespondent(document.SuggestedCorrespondent)
+			newCorrespondentID, created, err := client.CreateOrGetCorrespondent(context.Background(), newCorrespondent)
+			if err != nil {
+				log.Errorf("Error creating/getting correspondent with name %s: %v\n", document.SuggestedCorrespondent, err)
+				return nil, err
+			}
+			log.Infof("Using correspondent with name %s and ID %d\n", document.SuggestedCorrespondent, newCorrespondentID)
+			updatedFields["correspondent"] = newCorrespondentID
+			if created {
+				createdCorrespondentID = newCorrespondentID
+			}
+		}
+		originalFields["correspondent"] = document.OriginalDocument.Correspondent
+	} else if document.RemoveCorrespondent && document.OriginalDocument.Correspondent != "" {
+		originalFields["correspondent"] = document.OriginalDocument.Correspondent
+		updatedFields["correspondent"] = nil
+	}
+
+	// Map suggested storage path names to IDs
+	if document.SuggestedStoragePath != "" {
+		if storagePathID, exists := availableStoragePaths[document.SuggestedStoragePath]; exists {
+			updatedFields["storage_path"] = storagePathID
+		} else {
+			log.Errorf("Suggested storage path '%s' does not exist in paperless-ngx, skipping.", document.SuggestedStoragePath)
+		}
+	}
+
+	// An ASN read from a barcode is only assigned to documents without one
+	if document.SuggestedASN > 0 && document.OriginalDocument.ArchiveSerialNumber == nil {
+		updatedFields["archive_serial_number"] = document.SuggestedASN
+	}
+
+	suggestedTitle := document.SuggestedTitle
+	if len(suggestedTitle) > 128 {
+		suggestedTitle = suggestedTitle[:128]
+	}
+	if suggestedTitle != "" {
+		originalFields["title"] = document.OriginalDocument.Title
+		updatedFields["title"] = suggestedTitle
+	} else {
+		log.Warnf("No valid title found for document %d, skipping.", documentID)
+	}
+
+	// Suggested Content - translations can be appended to the content instead of being stored as a note
+	suggestedContent := document.SuggestedContent
+	if document.SuggestedTranslation != "" && translationTarget == "content" {
+		if suggestedContent == "" {
+			suggestedContent = document.OriginalDocument.Content
+		}
+		suggestedContent = appendTranslation(suggestedContent, document.SuggestedTranslation)
+	}
+	if suggestedContent != "" {
+		originalFields["content"] = document.OriginalDocument.Content
+		updatedFields["content"] = suggestedContent
+	}
+
+	// Suggested CreatedDate
+	suggestedCreatedDate := document.SuggestedCreatedDate
+	if suggestedCreatedDate != "" {
+		originalFields["created_date"] = document.OriginalDocument.CreatedDate
+		updatedFields["created_date"] = suggestedCreatedDate
+	}
+
+	// Suggested CustomFields - paperless-ngx replaces the whole list, so keep the values we don't touch.
+	// On undo the previous list is restored as-is.
+	if document.SuggestedCustomFields != nil {
+		customFields := document.SuggestedCustomFields
+		if !isUndo {
+			customFields = mergeCustomFields(document.OriginalDocument.CustomFields, document.SuggestedCustomFields)
+		}
+		originalFields["custom_fields"] = document.OriginalDocument.CustomFields
+		updatedFields["custom_fields"] = customFields
+	}
+
+	// Send the owner and permissions back unchanged, so updates never reset them on multi-user installations
+	if document.OriginalDocument.Permissions != nil {
+		updatedFields["owner"] = document.OriginalDocument.Owner
+		updatedFields["set_permissions"] = document.OriginalDocument.Permissions
+	}
+
+	return &documentUpdate{
+		document:               document,
+		originalFields:         originalFields,
+		updatedFields:          updatedFields,
+		tags:                   tags,
+		originalTagsJSON:       originalTagsJSON,
+		updatedTagsJSON:        updatedTagsJSON,
+		previousSensitivity:    previousSensitivity,
+		createdCorrespondentID: createdCorrespondentID,
 	}, nil
 }
 
-// UpdateDocuments updates the specified documents with suggested changes
-func (client *PaperlessClient) UpdateDocuments(ctx context.Context, documents []DocumentSuggestion, db *gorm.DB, isUndo bool) error {
-	// Fetch all available tags
-	availableTags, err := client.GetAllTags(ctx)
+// patchDocument sends the given fields of a document to paperless-ngx
+func (client *PaperlessClient) patchDocument(ctx context.Context, documentID int, fields map[string]interface{}) error {
+	// Marshal updated fields to JSON
+	jsonData, err := json.Marshal(fields)
 	if err != nil {
-		log.Errorf("Error fetching available tags: %v", err)
+		log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
 		return err
 	}
 
-	documentsContainSuggestedCorrespondent := false
-	for _, document := range documents {
-		if document.SuggestedCorrespondent != "" {
-			documentsContainSuggestedCorrespondent = true
-			break
-		}
+	// Send the update request using the generic Do method
+	path := fmt.Sprintf("api/documents/%d/", documentID)
+	resp, err := client.Do(ctx, "PATCH", path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		log.Errorf("Error updating document %d: %v", documentID, err)
+		return err
 	}
+	defer resp.Body.Close()
 
-	availableCorrespondents := make(map[string]int)
-	if documentsContainSuggestedCorrespondent {
-		availableCorrespondents, err = client.GetAllCorrespondents(ctx)
-		if err != nil {
-			log.Errorf("Error fetching available correspondents: %v",
-				err)
-			return err
-		}
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("error updating document %d: %w", documentID, errDocumentNotFound)
 	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		log.Errorf("Error updating document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+		return fmt.Errorf("error updating document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+	return nil
+}
 
-	for _, document := range documents {
-		documentID := document.ID
-
-		//  Original fields will store any updated fields to store records for
-		originalFields := make(map[string]interface{})
-		updatedFields := make(map[string]interface{})
-		newTags := []int{}
-
-		tags := document.SuggestedTags
-		originalTags := document.OriginalDocument.Tags
-
-		originalTagsJSON, err := json.Marshal(originalTags)
-		if err != nil {
-			log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+// applyDocumentUpdate sends the prepared changes of a document to paperless-ngx, records them in the history and adds the notes
+func (client *PaperlessClient) applyDocumentUpdate(ctx context.Context, db *gorm.DB, update *documentUpdate) error {
+	document := update.document
+	documentID := document.ID
+	originalFields := update.originalFields
+	updatedFields := update.updatedFields
+	tags := update.tags
+	originalTagsJSON := update.originalTagsJSON
+	updatedTagsJSON := update.updatedTagsJSON
+	previousSensitivity := update.previousSensitivity
+
+	log.Debugf("Document %d: Original fields: %v", documentID, originalFields)
+	log.Debugf("Document %d: Updated fields: %v Tags: %v", documentID, updatedFields, tags)
+
+	// Tags and correspondents may already have been applied by bulk edits, which can leave nothing to patch
+	var err error
+	if patchFields := update.patchFields(); len(patchFields) > 0 {
+		if err := client.patchDocument(ctx, documentID, patchFields); err != nil {
 			return err
 		}
+	}
 
-		// remove autoTag to prevent infinite loop (even if it is in the original tags)
-		for _, tag := range document.RemoveTags {
-			originalTags = removeTagFromList(originalTags, tag)
+	if verifyDocumentUpdates {
+		if err := client.verifyDocumentUpdate(ctx, documentID, updatedFields); err != nil {
+			log.Errorf("Error verifying update of document %d: %v", documentID, err)
+			return err
 		}
+	}
 
-		if len(tags) == 0 {
-			tags = originalTags
-		} else {
-			// We have suggested tags to change
-			originalFields["tags"] = originalTags
-			// remove autoTag to prevent infinite loop - this is required in case of undo
-			tags = removeTagFromList(tags, autoTag)
-
-			// remove duplicates
-			slices.Sort(tags)
-			tags = slices.Compact(tags)
-		}
+	// The modifications of the update are undone together with POST /api/undo-batch/:id
+	batchID := uuid.NewString()
 
-		updatedTagsJSON, err := json.Marshal(tags)
-		if err != nil {
-			log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
-			return err
-		}
+	// Each modification records the whole document before and after the update
+	documentBefore, documentAfter, err := marshalSnapshots(update.snapshots())
+	if err != nil {
+		log.Errorf("Error marshalling snapshots of document %d: %v", documentID, err)
+		return err
+	}
 
-		// Map suggested tag names to IDs
-		for _, tagName := range tags {
-			if tagID, exists := availableTags[tagName]; exists {
-				// Skip the tag that we are filtering
-				if !isUndo && tagName == manualTag {
-					continue
+	for field, value := range originalFields {
+		log.Printf("Document %d: Updated %s from %v to %v", documentID, field, value, updatedFields[field])
+		// Insert the modification record into the database
+		var modificationRecord ModificationHistory
+		if field == "tags" {
+			// Make sure we only store changes where tags are changed - not the same before and after
+			// And we have to use tags, not updatedFields as they are IDs not fields
+			if !hasSameTags(document.OriginalDocument.Tags, tags) {
+				modificationRecord = ModificationHistory{
+					DocumentID:    uint(documentID),
+					ModField:      field,
+					PreviousValue: string(originalTagsJSON),
+					NewValue:      string(updatedTagsJSON),
 				}
-				newTags = append(newTags, tagID)
-			} else {
-				log.Errorf("Suggested tag '%s' does not exist in paperless-ngx, skipping.", tagName)
 			}
-		}
-		updatedFields["tags"] = newTags
-
-		// Map suggested correspondent names to IDs
-		if document.SuggestedCorrespondent != "" {
-			if correspondentID, exists := availableCorrespondents[document.SuggestedCorrespondent]; exists {
-				updatedFields["correspondent"] = correspondentID
-			} else {
-				newCorrespondent := instantiateCorrespondent(document.SuggestedCorrespondent)
-				newCorrespondentID, err := client.CreateOrGetCorrespondent(context.Background(), newCorrespondent)
-				if err != nil {
-					log.Errorf("Error creating/getting correspondent with name %s: %v\n", document.SuggestedCorrespondent, err)
-					return err
+		} else if field == "custom_fields" {
+			previousJSON, err := marshalCustomFields(document.OriginalDocument.CustomFields)
+			if err != nil {
+				log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+				return err
+			}
+			newJSON, err := marshalCustomFields(updatedFields[field].([]CustomFieldValue))
+			if err != nil {
+				log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+				return err
+			}
+			if previousJSON != newJSON {
+				modificationRecord = ModificationHistory{
+					DocumentID:    uint(documentID),
+					ModField:      field,
+					PreviousValue: previousJSON,
+					NewValue:      newJSON,
+				}
+			}
+		} else if field == "correspondent" {
+			// The correspondent is sent as ID, but recorded by name
+			if document.OriginalDocument.Correspondent != document.SuggestedCorrespondent {
+				modificationRecord = ModificationHistory{
+					DocumentID:    uint(documentID),
+					ModField:      field,
+					PreviousValue: document.OriginalDocument.Correspondent,
+					NewValue:      document.SuggestedCorrespondent,
+				}
+			}
+		} else {
+			// Only store mod if field actually changed
+			if originalFields[field] != updatedFields[field] {
+				modificationRecord = ModificationHistory{
+					DocumentID:    uint(documentID),
+					ModField:      field,
+					PreviousValue: fmt.Sprintf("%v", originalFields[field]),
+					NewValue:      fmt.Sprintf("%v", updatedFields[field]),
 				}
-				log.Infof("Using correspondent with name %s and ID %d\n", document.SuggestedCorrespondent, newCorrespondentID)
-				updatedFields["correspondent"] = newCorrespondentID
 			}
 		}
 
-		suggestedTitle := document.SuggestedTitle
-		if len(suggestedTitle) > 128 {
-			suggestedTitle = suggestedTitle[:128]
+		// Only store if we have a valid modification record
+		if (modificationRecord != ModificationHistory{}) {
+			modificationRecord.User = requestUser(ctx)
+			modificationRecord.BatchID = batchID
+			modificationRecord.Operation, modificationRecord.OriginID = modificationOrigin(ctx, field)
+			modificationRecord.DocumentBefore, modificationRecord.DocumentAfter = documentBefore, documentAfter
+			err = InsertModification(db, &modificationRecord)
 		}
-		if suggestedTitle != "" {
-			originalFields["title"] = document.OriginalDocument.Title
-			updatedFields["title"] = suggestedTitle
-		} else {
-			log.Warnf("No valid title found for document %d, skipping.", documentID)
+		if err != nil {
+			log.Errorf("Error inserting modification record for document %d: %v", documentID, err)
+			return err
 		}
+	}
 
-		// Suggested Content
-		suggestedContent := document.SuggestedContent
-		if suggestedContent != "" {
-			originalFields["content"] = document.OriginalDocument.Content
-			updatedFields["content"] = suggestedContent
+	// Correspondents created for suggestions are listed in the email digest
+	if update.createdCorrespondentID != 0 {
+		if err := RecordCreatedCorrespondent(db, update.createdCorrespondentID, document.SuggestedCorrespondent, uint(documentID)); err != nil {
+			log.Warnf("Error recording the created correspondent %s of document %d: %v", document.SuggestedCorrespondent, documentID, err)
 		}
+	}
 
-		// Suggested CreatedDate
-		suggestedCreatedDate := document.SuggestedCreatedDate
-		if suggestedCreatedDate != "" {
-			originalFields["created_date"] = document.OriginalDocument.CreatedDate
-			updatedFields["created_date"] = suggestedCreatedDate
+	// Record the classification so it shows up in the history and can be undone
+	if document.SuggestedSensitivity != "" && document.SuggestedSensitivity != previousSensitivity {
+		operation, originID := modificationOrigin(ctx, "sensitivity")
+		err = InsertModification(db, &ModificationHistory{
+			DocumentID:     uint(documentID),
+			ModField:       "sensitivity",
+			PreviousValue:  previousSensitivity,
+			NewValue:       document.SuggestedSensitivity,
+			User:           requestUser(ctx),
+			BatchID:        batchID,
+			Operation:      operation,
+			OriginID:       originID,
+			DocumentBefore: documentBefore,
+			DocumentAfter:  documentAfter,
+		})
+		if err != nil {
+			log.Errorf("Error inserting modification record for document %d: %v", documentID, err)
+			return err
 		}
+	}
 
-		log.Debugf("Document %d: Original fields: %v", documentID, originalFields)
-		log.Debugf("Document %d: Updated fields: %v Tags: %v", documentID, updatedFields, tags)
+	// The LLM and OCR requests made for the document generated the modifications, unless they undo or redo others
+	if !reversesModifications(ctx) {
+		if err := linkLLMUsage(db, uint(documentID), batchID); err != nil {
+			log.Errorf("Error linking the LLM usage of document %d: %v", documentID, err)
+			return err
+		}
+	}
 
-		// Marshal updated fields to JSON
-		jsonData, err := json.Marshal(updatedFields)
+	// Store the summary as a note unless it is written to a custom field
+	if document.SuggestedSummary != "" && summaryCustomField == "" {
+		err = client.AddNote(ctx, documentID, document.SuggestedSummary)
 		if err != nil {
-			log.Errorf("Error marshalling JSON for document %d: %v", documentID, err)
+			log.Errorf("Error adding summary note to document %d: %v", documentID, err)
 			return err
 		}
+	}
 
-		// Send the update request using the generic Do method
-		path := fmt.Sprintf("api/documents/%d/", documentID)
-		resp, err := client.Do(ctx, "PATCH", path, bytes.NewBuffer(jsonData))
+	// Link related documents in a note unless they are stored in a custom field
+	if len(document.SuggestedRelatedDocuments) > 0 && relatedDocumentsCustomField == "" {
+		err = client.AddNote(ctx, documentID, relatedDocumentsNote(document.SuggestedRelatedDocuments))
 		if err != nil {
-			log.Errorf("Error updating document %d: %v", documentID, err)
+			log.Errorf("Error adding related documents note to document %d: %v", documentID, err)
 			return err
 		}
-		defer resp.Body.Close()
+	}
 
-		if resp.StatusCode != http.StatusOK {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			log.Errorf("Error updating document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
-			return fmt.Errorf("error updating document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
-		} else {
-			for field, value := range originalFields {
-				log.Printf("Document %d: Updated %s from %v to %v", documentID, field, value, updatedFields[field])
-				// Insert the modification record into the database
-				var modificationRecord ModificationHistory
-				if field == "tags" {
-					// Make sure we only store changes where tags are changed - not the same before and after
-					// And we have to use tags, not updatedFields as they are IDs not fields
-					if !hasSameTags(document.OriginalDocument.Tags, tags) {
-						modificationRecord = ModificationHistory{
-							DocumentID:    uint(documentID),
-							ModField:      field,
-							PreviousValue: string(originalTagsJSON),
-							NewValue:      string(updatedTagsJSON),
-						}
-					}
-				} else {
-					// Only store mod if field actually changed
-					if originalFields[field] != updatedFields[field] {
-						modificationRecord = ModificationHistory{
-							DocumentID:    uint(documentID),
-							ModField:      field,
-							PreviousValue: fmt.Sprintf("%v", originalFields[field]),
-							NewValue:      fmt.Sprintf("%v", updatedFields[field]),
-						}
-					}
-				}
+	// Store the translation as a note unless it was appended to the content
+	if document.SuggestedTranslation != "" && translationTarget != "content" {
+		err = client.AddNote(ctx, documentID, translationSection(document.SuggestedTranslation))
+		if err != nil {
+			log.Errorf("Error adding translation note to document %d: %v", documentID, err)
+			return err
+		}
+	}
 
-				// Only store if we have a valid modification record
-				if (modificationRecord != ModificationHistory{}) {
-					err = InsertModification(db, &modificationRecord)
-				}
-				if err != nil {
-					log.Errorf("Error inserting modification record for document %d: %v", documentID, err)
-					return err
-				}
-			}
+	log.Printf("Document %d updated successfully.", documentID)
+	return nil
+}
+
+// mergeCustomFields applies the suggested custom field values on top of the existing ones
+func mergeCustomFields(original, suggested []CustomFieldValue) []CustomFieldValue {
+	merged := make([]CustomFieldValue, 0, len(original)+len(suggested))
+	indexByField := make(map[int]int)
+	for _, value := range original {
+		indexByField[value.Field] = len(merged)
+		merged = append(merged, value)
+	}
+	for _, value := range suggested {
+		if i, exists := indexByField[value.Field]; exists {
+			merged[i].Value = value.Value
+			continue
 		}
+		indexByField[value.Field] = len(merged)
+		merged = append(merged, value)
+	}
+	return merged
+}
+
+// marshalCustomFields marshals custom field values for the modification history, using [] instead of null
+func marshalCustomFields(values []CustomFieldValue) (string, error) {
+	if values == nil {
+		values = []CustomFieldValue{}
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// GetNotes retrieves all notes of the specified document
+func (client *PaperlessClient) GetNotes(ctx context.Context, documentID int) ([]Note, error) {
+	path := fmt.Sprintf("api/documents/%d/notes/", documentID)
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error fetching notes of document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	var notes []Note
+	err = json.NewDecoder(resp.Body).Decode(&notes)
+	if err != nil {
+		return nil, err
+	}
+
+	return notes, nil
+}
+
+// DeleteNote deletes a single note of the specified document
+func (client *PaperlessClient) DeleteNote(ctx context.Context, documentID int, noteID int) error {
+	path := fmt.Sprintf("api/documents/%d/notes/?id=%d", documentID, noteID)
+	resp, err := client.Do(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error deleting note %d of document %d: %d, %s", noteID, documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// AddNote adds a note to the specified document
+func (client *PaperlessClient) AddNote(ctx context.Context, documentID int, note string) error {
+	jsonData, err := json.Marshal(map[string]string{"note": note})
+	if err != nil {
+		return err
+	}
+
+	path := fmt.Sprintf("api/documents/%d/notes/", documentID)
+	resp, err := client.Do(ctx, "POST", path, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
 
-		log.Printf("Document %d updated successfully.", documentID)
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("error adding note to document %d: %w", documentID, errDocumentNotFound)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error adding note to document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
 	}
 
 	return nil
 }
 
 // DownloadDocumentAsImages downloads the PDF file of the specified document and converts it to images
-// If limitPages > 0, only the first N pages will be processed
+// If limitPages > 0, only the first N pages will be processed.
+// Downloads and rendered pages are cached until the document or the render settings change.
 func (client *PaperlessClient) DownloadDocumentAsImages(ctx context.Context, documentId int, limitPages int) ([]string, error) {
-	// Create a directory named after the document ID
-	docDir := filepath.Join(client.GetCacheFolder(), fmt.Sprintf("document-%d", documentId))
-	if _, err := os.Stat(docDir); os.IsNotExist(err) {
-		err = os.MkdirAll(docDir, 0755)
-		if err != nil {
-			return nil, err
-		}
+	// The cache directory is named after the document ID
+	docDir, err := client.documentCacheDir(ctx, documentId)
+	if err != nil {
+		return nil, err
+	}
+	settings := settingsFor(ctx).renderSettings()
+	if err := client.dropPagesRenderedDifferently(docDir, settings); err != nil {
+		return nil, err
 	}
 
 	// Check if images already exist
@@ -575,25 +1623,15 @@ func (client *PaperlessClient) DownloadDocumentAsImages(ctx context.Context, doc
 		imagePaths = append(imagePaths, imagePath)
 	}
 
-	// If images exist, return them
-	if len(imagePaths) > 0 {
+	// If all requested images exist, return them
+	_, err = os.Stat(filepath.Join(docDir, cachedPagesCompleteFile))
+	allPagesCached := err == nil
+	if len(imagePaths) > 0 && (allPagesCached || len(imagePaths) == limitPages) {
 		return imagePaths, nil
 	}
 
 	// Proceed with downloading and converting the document to images
-	path := fmt.Sprintf("api/documents/%d/download/", documentId)
-	resp, err := client.Do(ctx, "GET", path, nil)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("error downloading document %d: %d, %s", documentId, resp.StatusCode, string(bodyBytes))
-	}
-
-	pdfData, err := io.ReadAll(resp.Body)
+	pdfData, err := client.downloadDocument(ctx, documentId, docDir)
 	if err != nil {
 		return nil, err
 	}
@@ -617,19 +1655,22 @@ func (client *PaperlessClient) DownloadDocumentAsImages(ctx context.Context, doc
 	defer doc.Close()
 
 	totalPages := doc.NumPage()
-	if limitPages > 0 && limitPages < totalPages {
+	renderAllPages := limitPages <= 0 || limitPages >= totalPages
+	if !renderAllPages {
 		totalPages = limitPages
 	}
 
 	var mu sync.Mutex
 	var g errgroup.Group
 
-	for n := 0; n < totalPages; n++ {
+	// Pages rendered by an earlier run with a lower page limit are reused
+	cachedPages := len(imagePaths)
+	for n := cachedPages; n < totalPages; n++ {
 		n := n // capture loop variable
 		g.Go(func() error {
 			mu.Lock()
 			// I assume the libmupdf library is not thread-safe
-			img, err := doc.Image(n)
+			img, _, err := renderPage(doc, n, settings)
 			mu.Unlock()
 			if err != nil {
 				return err
@@ -641,7 +1682,7 @@ func (client *PaperlessClient) DownloadDocumentAsImages(ctx context.Context, doc
 				return err
 			}
 
-			err = jpeg.Encode(f, img, &jpeg.Options{Quality: jpeg.DefaultQuality})
+			err = jpeg.Encode(f, img, &jpeg.Options{Quality: settings.JPEGQuality})
 			if err != nil {
 				f.Close()
 				return err
@@ -672,6 +1713,12 @@ func (client *PaperlessClient) DownloadDocumentAsImages(ctx context.Context, doc
 		return nil, err
 	}
 
+	if renderAllPages {
+		if err := os.WriteFile(filepath.Join(docDir, cachedPagesCompleteFile), nil, 0644); err != nil {
+			return nil, err
+		}
+	}
+
 	// sort the image paths to ensure they are in order
 	slices.Sort(imagePaths)
 
@@ -702,47 +1749,127 @@ func instantiateCorrespondent(name string) Correspondent {
 	}
 }
 
-// CreateOrGetCorrespondent creates a new correspondent or returns existing one if name already exists
-func (client *PaperlessClient) CreateOrGetCorrespondent(ctx context.Context, correspondent Correspondent) (int, error) {
+// GetThumbnail downloads the thumbnail of a document and returns it with its content type, e.g. image/webp
+func (client *PaperlessClient) GetThumbnail(ctx context.Context, documentID int) ([]byte, string, error) {
+	resp, err := client.Do(ctx, "GET", fmt.Sprintf("api/documents/%d/thumb/", documentID), nil)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, "", fmt.Errorf("error fetching thumbnail of document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
+
+// CreateOrGetCorrespondent creates a new correspondent or returns existing one if name already exists, reporting
+// whether it was created
+func (client *PaperlessClient) CreateOrGetCorrespondent(ctx context.Context, correspondent Correspondent) (int, bool, error) {
 	// First try to find existing correspondent
 	correspondents, err := client.GetAllCorrespondents(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("error fetching correspondents: %w", err)
+		return 0, false, fmt.Errorf("error fetching correspondents: %w", err)
 	}
 
 	// Check if correspondent already exists
 	if id, exists := correspondents[correspondent.Name]; exists {
 		log.Infof("Using existing correspondent with name %s and ID %d", correspondent.Name, id)
-		return id, nil
+		return id, false, nil
 	}
 
 	// If not found, create new correspondent
 	url := "api/correspondents/"
 	jsonData, err := json.Marshal(correspondent)
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 
 	resp, err := client.Do(ctx, "POST", url, bytes.NewBuffer(jsonData))
 	if err != nil {
-		return 0, err
+		return 0, false, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusCreated {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return 0, fmt.Errorf("error creating correspondent: %d, %s", resp.StatusCode, string(bodyBytes))
+		return 0, false, fmt.Errorf("error creating correspondent: %d, %s", resp.StatusCode, string(bodyBytes))
 	}
 
 	var createdCorrespondent struct {
 		ID int `json:"id"`
 	}
 	err = json.NewDecoder(resp.Body).Decode(&createdCorrespondent)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return createdCorrespondent.ID, true, nil
+}
+
+// tagMatchingAlgorithms maps the values of NEW_TAG_MATCHING_ALGORITHM to the matching algorithms of paperless-ngx
+var tagMatchingAlgorithms = map[string]int{
+	"none": 0,
+	"auto": 6,
+}
+
+// hexColorPattern matches the colors accepted by paperless-ngx for tags
+var hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// newTagColor picks the color of a new tag from NEW_TAG_COLORS, the same name always gets the same color.
+// An empty string leaves the color to paperless-ngx.
+func newTagColor(name string) string {
+	if len(newTagColors) == 0 {
+		return ""
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(strings.ToLower(name)))
+	return newTagColors[hash.Sum32()%uint32(len(newTagColors))]
+}
+
+// CreateTag creates a new tag with the given name and returns its ID.
+// Color, matching algorithm and inbox flag follow the NEW_TAG_* settings.
+func (client *PaperlessClient) CreateTag(ctx context.Context, name string) (int, error) {
+	payload := map[string]interface{}{
+		"name":               name,
+		"matching_algorithm": tagMatchingAlgorithms[newTagMatchingAlgorithm],
+		"is_inbox_tag":       newTagIsInbox,
+	}
+	if color := newTagColor(name); color != "" {
+		payload["color"] = color
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := client.Do(ctx, "POST", "api/tags/", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusCreated {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("error creating tag: %d, %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var createdTag struct {
+		ID int `json:"id"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&createdTag)
 	if err != nil {
 		return 0, err
 	}
 
-	return createdCorrespondent.ID, nil
+	log.Infof("Created tag %s with ID %d", name, createdTag.ID)
+	return createdTag.ID, nil
 }
 
 // CorrespondentResponse represents the response structure for correspondents
@@ -753,6 +1880,102 @@ type CorrespondentResponse struct {
 	} `json:"results"`
 }
 
+// AddTagToDocuments adds a tag to the specified documents using the bulk edit API
+func (client *PaperlessClient) AddTagToDocuments(ctx context.Context, documentIDs []int, tagID int) error {
+	return client.bulkEdit(ctx, documentIDs, "add_tag", map[string]interface{}{"tag": tagID})
+}
+
+// RemoveTagFromDocuments removes a tag from the specified documents using the bulk edit API
+func (client *PaperlessClient) RemoveTagFromDocuments(ctx context.Context, documentIDs []int, tagID int) error {
+	return client.bulkEdit(ctx, documentIDs, "remove_tag", map[string]interface{}{"tag": tagID})
+}
+
+// SetDocumentsCorrespondent assigns a correspondent to the specified documents using the bulk edit API
+func (client *PaperlessClient) SetDocumentsCorrespondent(ctx context.Context, documentIDs []int, correspondentID int) error {
+	return client.bulkEdit(ctx, documentIDs, "set_correspondent", map[string]interface{}{"correspondent": correspondentID})
+}
+
+// DeleteDocumentPages removes pages (numbered from 1) from a document using the bulk edit API.
+// paperless-ngx creates a new archived version of the document afterwards.
+func (client *PaperlessClient) DeleteDocumentPages(ctx context.Context, documentID int, pages []int) error {
+	return client.bulkEdit(ctx, []int{documentID}, "delete_pages", map[string]interface{}{"pages": pages})
+}
+
+// bulkEdit applies a bulk edit method of paperless-ngx to the specified documents
+func (client *PaperlessClient) bulkEdit(ctx context.Context, documentIDs []int, method string, parameters map[string]interface{}) error {
+	payload := map[string]interface{}{
+		"documents":  documentIDs,
+		"method":     method,
+		"parameters": parameters,
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(ctx, "POST", "api/documents/bulk_edit/", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error running bulk edit %s on documents %v: %d, %s", method, documentIDs, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
+// GetDocumentIDsByCorrespondent retrieves the IDs of all documents assigned to the correspondent
+func (client *PaperlessClient) GetDocumentIDsByCorrespondent(ctx context.Context, correspondentID int) ([]int, error) {
+	return client.GetDocumentIDs(ctx, fmt.Sprintf("correspondent__id=%d", correspondentID))
+}
+
+// GetDocumentIDs retrieves the IDs of all documents matching the filter query
+func (client *PaperlessClient) GetDocumentIDs(ctx context.Context, query string) ([]int, error) {
+	// The "all" field of the response lists the IDs of all matching documents, so one result per page is enough
+	path := "api/documents/?page_size=1"
+	if query != "" {
+		path = fmt.Sprintf("api/documents/?%s&page_size=1", query)
+	}
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error fetching documents matching %q: %d, %s", query, resp.StatusCode, string(bodyBytes))
+	}
+
+	var documentsResponse GetDocumentsApiResponse
+	err = json.NewDecoder(resp.Body).Decode(&documentsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	return documentsResponse.All, nil
+}
+
+// DeleteCorrespondent deletes the correspondent with the given ID
+func (client *PaperlessClient) DeleteCorrespondent(ctx context.Context, correspondentID int) error {
+	path := fmt.Sprintf("api/correspondents/%d/", correspondentID)
+	resp, err := client.Do(ctx, "DELETE", path, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error deleting correspondent %d: %d, %s", correspondentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	return nil
+}
+
 // GetAllCorrespondents retrieves all correspondents from the Paperless-NGX API
 func (client *PaperlessClient) GetAllCorrespondents(ctx context.Context) (map[string]int, error) {
 	correspondentIDMapping := make(map[string]int)
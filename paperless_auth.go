@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// paperlessAuth holds the credentials sent to paperless-ngx besides, or instead of, the API token
+type paperlessAuth struct {
+	// Header and HeaderValue identify the user to paperless-ngx behind an authenticating proxy,
+	// e.g. X-Remote-User with PAPERLESS_ENABLE_HTTP_REMOTE_USER_API
+	Header      string
+	HeaderValue string
+	// SessionCookie is the Cookie header of a logged in browser session
+	SessionCookie string
+	// Username and Password are used to fetch an API token, which is fetched again when it is rejected
+	Username string
+	Password string
+}
+
+// canRefreshToken reports whether an API token can be fetched with username and password
+func (auth paperlessAuth) canRefreshToken() bool {
+	return auth.Username != "" && auth.Password != ""
+}
+
+// authenticate adds the configured credentials to a request
+func (client *PaperlessClient) authenticate(req *http.Request) {
+	if token := client.token(); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Token %s", token))
+	}
+	if client.auth.Header != "" {
+		req.Header.Set(client.auth.Header, client.auth.HeaderValue)
+	}
+	if client.auth.SessionCookie != "" {
+		req.Header.Set("Cookie", client.auth.SessionCookie)
+		// Django rejects unsafe requests authenticated by a session without a matching CSRF token
+		safeMethod := req.Method == http.MethodGet || req.Method == http.MethodHead || req.Method == http.MethodOptions
+		if csrfCookie, err := req.Cookie("csrftoken"); err == nil && !safeMethod {
+			req.Header.Set("X-CSRFToken", csrfCookie.Value)
+			req.Header.Set("Referer", client.BaseURL+"/")
+		}
+	}
+}
+
+// token returns the API token currently in use
+func (client *PaperlessClient) token() string {
+	client.tokenMu.Lock()
+	defer client.tokenMu.Unlock()
+	return client.APIToken
+}
+
+// ensureToken fetches an API token before the first request when only username and password are configured
+func (client *PaperlessClient) ensureToken(ctx context.Context) error {
+	if client.token() != "" || !client.auth.canRefreshToken() {
+		return nil
+	}
+	return client.refreshToken(ctx, "")
+}
+
+// refreshToken fetches a new API token with username and password. The token is only replaced if it is still
+// the rejected one, so concurrent requests failing with the same token fetch a new one just once.
+func (client *PaperlessClient) refreshToken(ctx context.Context, rejected string) error {
+	client.tokenMu.Lock()
+	defer client.tokenMu.Unlock()
+	if client.APIToken != rejected {
+		return nil
+	}
+
+	jsonData, err := json.Marshal(map[string]string{
+		"username": client.auth.Username,
+		"password": client.auth.Password,
+	})
+	if err != nil {
+		return err
+	}
+
+	// The token endpoint is called directly, Do would try to authenticate with the token being replaced
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, client.BaseURL+"/api/token/", bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client.auth.Header != "" {
+		req.Header.Set(client.auth.Header, client.auth.HeaderValue)
+	}
+
+	resp, err := client.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error fetching API token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error fetching API token: %d, %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var tokenResponse struct {
+		Token string `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResponse); err != nil {
+		return err
+	}
+	if tokenResponse.Token == "" {
+		return fmt.Errorf("error fetching API token: empty token in response")
+	}
+
+	log.Infof("Fetched a new paperless-ngx API token for user %s", client.auth.Username)
+	client.APIToken = tokenResponse.Token
+	return nil
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticate_HeaderAndSessionCookie(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Equal(t, "paperless", r.Header.Get("X-Remote-User"))
+
+		session, err := r.Cookie("sessionid")
+		require.NoError(t, err)
+		assert.Equal(t, "abc", session.Value)
+
+		switch r.Method {
+		case http.MethodGet:
+			assert.Empty(t, r.Header.Get("X-CSRFToken"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}], "next": null}`))
+		case http.MethodPost:
+			assert.Equal(t, "xyz", r.Header.Get("X-CSRFToken"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 2}`))
+		}
+	}))
+	defer server.Close()
+
+	client := NewPaperlessClient(server.URL, "")
+	client.auth = paperlessAuth{
+		Header:        "X-Remote-User",
+		HeaderValue:   "paperless",
+		SessionCookie: "sessionid=abc; csrftoken=xyz",
+	}
+
+	ctx := context.Background()
+	tags, err := client.GetAllTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"tag1": 1}, tags)
+
+	_, err = client.CreateTag(ctx, "tag2")
+	require.NoError(t, err)
+}
+
+func TestAuthenticate_TokenRefresh(t *testing.T) {
+	issued := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/token/" {
+			var credentials map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&credentials))
+			assert.Equal(t, map[string]string{"username": "gpt", "password": "secret"}, credentials)
+			issued++
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(fmt.Sprintf(`{"token": "token-%d"}`, issued)))
+			return
+		}
+
+		// The first token expired right after it was issued
+		if r.Header.Get("Authorization") != "Token token-2" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"detail": "Invalid token."}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	}))
+	defer server.Close()
+
+	client := NewPaperlessClient(server.URL, "")
+	client.auth = paperlessAuth{Username: "gpt", Password: "secret"}
+
+	ctx := context.Background()
+	_, err := client.GetAllTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, issued)
+	assert.Equal(t, "token-2", client.token())
+
+	// The valid token is kept for later requests
+	_, err = client.GetAllTags(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, issued)
+}
+
+func TestAuthenticate_RejectedCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"non_field_errors": ["Unable to log in with provided credentials."]}`))
+	}))
+	defer server.Close()
+
+	client := NewPaperlessClient(server.URL, "")
+	client.auth = paperlessAuth{Username: "gpt", Password: "wrong"}
+
+	_, err := client.GetAllTags(context.Background())
+	assert.ErrorContains(t, err, "error fetching API token: 400")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"slices"
+)
+
+// applyBulkEdits applies the tag and correspondent changes of several documents with the bulk edit API of paperless-ngx.
+// The changes are grouped per tag and correspondent, so applying suggestions to many documents only needs
+// a request per distinct tag instead of one per document. Each request covers at most bulkEditBatchSize documents.
+func (client *PaperlessClient) applyBulkEdits(ctx context.Context, updates []*documentUpdate, availableTags map[string]int) error {
+	addTag := make(map[int][]int)
+	removeTag := make(map[int][]int)
+	setCorrespondent := make(map[int][]int)
+
+	for _, update := range updates {
+		documentID := update.document.ID
+
+		var originalTagIDs []int
+		for _, tagName := range update.document.OriginalDocument.Tags {
+			if tagID, exists := availableTags[tagName]; exists {
+				originalTagIDs = append(originalTagIDs, tagID)
+			}
+		}
+		newTagIDs, _ := update.updatedFields["tags"].([]int)
+
+		for _, tagID := range newTagIDs {
+			if !slices.Contains(originalTagIDs, tagID) {
+				addTag[tagID] = append(addTag[tagID], documentID)
+			}
+		}
+		for _, tagID := range originalTagIDs {
+			if !slices.Contains(newTagIDs, tagID) {
+				removeTag[tagID] = append(removeTag[tagID], documentID)
+			}
+		}
+		if correspondentID, ok := update.updatedFields["correspondent"].(int); ok {
+			setCorrespondent[correspondentID] = append(setCorrespondent[correspondentID], documentID)
+		}
+	}
+
+	if err := runBulkEdits(ctx, addTag, client.AddTagToDocuments); err != nil {
+		return err
+	}
+	if err := runBulkEdits(ctx, removeTag, client.RemoveTagFromDocuments); err != nil {
+		return err
+	}
+	if err := runBulkEdits(ctx, setCorrespondent, client.SetDocumentsCorrespondent); err != nil {
+		return err
+	}
+
+	for _, update := range updates {
+		update.bulkEdited = true
+	}
+	log.Infof("Applied tag and correspondent changes of %d documents with bulk edits", len(updates))
+	return nil
+}
+
+// runBulkEdits calls edit for every tag or correspondent ID with its documents, split into batches of bulkEditBatchSize
+func runBulkEdits(ctx context.Context, documentsByID map[int][]int, edit func(ctx context.Context, documentIDs []int, id int) error) error {
+	ids := make([]int, 0, len(documentsByID))
+	for id := range documentsByID {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	for _, id := range ids {
+		for batch := range slices.Chunk(documentsByID[id], bulkEditBatchSize) {
+			if err := edit(ctx, batch, id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateDocuments_BulkEdit(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	setSettings(t, func(settings *runtimeSettings) { settings.ManualTag = "manual" })
+
+	originalBatchSize := bulkEditBatchSize
+	defer func() { bulkEditBatchSize = originalBatchSize }()
+	bulkEditBatchSize = 2
+
+	documents := []DocumentSuggestion{
+		{
+			ID:                     1,
+			OriginalDocument:       Document{ID: 1, Title: "One", Tags: []string{"manual"}},
+			SuggestedTags:          []string{"invoice"},
+			SuggestedCorrespondent: "Alpha",
+		},
+		{
+			ID:                     2,
+			OriginalDocument:       Document{ID: 2, Title: "Two", Tags: []string{"manual"}},
+			SuggestedTags:          []string{"invoice"},
+			SuggestedCorrespondent: "Alpha",
+		},
+		{
+			ID:               3,
+			OriginalDocument: Document{ID: 3, Title: "Three", Tags: []string{"manual", "old"}},
+			SuggestedTags:    []string{"invoice"},
+			SuggestedTitle:   "New Three",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "manual"}, {"id": 2, "name": "invoice"}, {"id": 3, "name": "old"}], "next": null}`))
+	})
+
+	type bulkEditRequest struct {
+		Documents  []int                  `json:"documents"`
+		Method     string                 `json:"method"`
+		Parameters map[string]interface{} `json:"parameters"`
+	}
+	var bulkEdits []bulkEditRequest
+	env.setMockResponse("/api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		var request bulkEditRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		bulkEdits = append(bulkEdits, request)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	env.setMockResponse("/api/documents/3/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Equal(t, map[string]interface{}{"title": "New Three"}, updatedFields)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+
+	// Documents 1 and 2 need no PATCH at all, the changes are grouped per tag and correspondent in batches of two
+	assert.Equal(t, []bulkEditRequest{
+		{Documents: []int{1, 2}, Method: "add_tag", Parameters: map[string]interface{}{"tag": float64(2)}},
+		{Documents: []int{3}, Method: "add_tag", Parameters: map[string]interface{}{"tag": float64(2)}},
+		{Documents: []int{1, 2}, Method: "remove_tag", Parameters: map[string]interface{}{"tag": float64(1)}},
+		{Documents: []int{3}, Method: "remove_tag", Parameters: map[string]interface{}{"tag": float64(1)}},
+		{Documents: []int{3}, Method: "remove_tag", Parameters: map[string]interface{}{"tag": float64(3)}},
+		{Documents: []int{1, 2}, Method: "set_correspondent", Parameters: map[string]interface{}{"correspondent": float64(1)}},
+	}, bulkEdits)
+
+	// The tag changes are still recorded, so they can be undone
+	var modifications []ModificationHistory
+	require.NoError(t, env.db.Where("mod_field = ?", "tags").Order("document_id").Find(&modifications).Error)
+	require.Len(t, modifications, 3)
+	assert.Equal(t, `["invoice"]`, modifications[0].NewValue)
+}
+
+func TestDocumentUpdatePatchFields(t *testing.T) {
+	update := &documentUpdate{
+		updatedFields: map[string]interface{}{
+			"tags":            []int{1},
+			"correspondent":   2,
+			"owner":           nil,
+			"set_permissions": &DocumentPermissions{},
+		},
+	}
+	assert.Len(t, update.patchFields(), 4)
+
+	// Only the owner and permissions remain after bulk edits, so there is nothing to patch
+	update.bulkEdited = true
+	assert.Nil(t, update.patchFields())
+
+	update.updatedFields["title"] = "New"
+	assert.Equal(t, map[string]interface{}{
+		"title":           "New",
+		"owner":           nil,
+		"set_permissions": &DocumentPermissions{},
+	}, update.patchFields())
+}
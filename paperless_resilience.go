@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// errCircuitOpen is returned without contacting paperless-ngx while the circuit breaker is open
+var errCircuitOpen = errors.New("paperless-ngx is unavailable, circuit breaker is open")
+
+// retryPolicy controls how often and how long PaperlessClient waits before retrying a failed request
+type retryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// isRetryableStatus reports whether paperless-ngx (or a proxy in front of it) signals a temporary overload.
+// Internal server errors are not retried, as they are usually caused by the request itself.
+func isRetryableStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// isIdempotentMethod reports whether a request can safely be sent again after a network error
+func isIdempotentMethod(method string) bool {
+	return method != http.MethodPost
+}
+
+// delay returns how long to wait before the given retry attempt (starting at 0).
+// A Retry-After header in seconds takes precedence over the exponential backoff.
+func (p retryPolicy) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			return min(time.Duration(seconds)*time.Second, p.MaxDelay)
+		}
+	}
+	return min(p.BaseDelay<<attempt, p.MaxDelay)
+}
+
+// circuitBreaker stops sending requests to paperless-ngx after too many consecutive failures.
+// Once the cooldown has passed a single request is let through; if it fails too the breaker opens again.
+// The other requests are refused until its outcome is recorded, or for another cooldown if it never is.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	now       func() time.Time
+}
+
+// newCircuitBreaker creates a circuit breaker, a threshold <= 0 disables it
+func newCircuitBreaker(threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// allow reports whether a request may be sent
+func (b *circuitBreaker) allow() bool {
+	if b == nil || b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.failures < b.threshold {
+		return true
+	}
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	// The request probes whether paperless-ngx is back, the breaker stays open for the others meanwhile
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// record updates the breaker with the outcome of a request
+func (b *circuitBreaker) record(success bool) {
+	if b == nil || b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if success {
+		if b.failures >= b.threshold {
+			log.Info("paperless-ngx is reachable again, closing circuit breaker")
+		}
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openUntil = b.now().Add(b.cooldown)
+		log.Warnf("paperless-ngx failed %d times in a row, pausing requests for %s", b.failures, b.cooldown)
+	}
+}
+
+// newRequestLimiter creates a limiter for the given number of requests per second, 0 disables rate limiting
+func newRequestLimiter(requestsPerSecond float64) *rate.Limiter {
+	if requestsPerSecond <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(requestsPerSecond), 1)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestDo_RetriesOverloadedRequests(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.retry = retryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	attempts := 0
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"title":"New"}`, string(body))
+
+		switch attempts {
+		case 1:
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	resp, err := env.client.Do(context.Background(), "PATCH", "api/documents/1/", bytes.NewBufferString(`{"title":"New"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDo_GivesUpAfterMaxRetries(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.retry = retryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	attempts := 0
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	resp, err := env.client.Do(context.Background(), "GET", "api/tags/", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDo_DoesNotRetryServerErrors(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.retry = retryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	attempts := 0
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	resp, err := env.client.Do(context.Background(), "GET", "api/tags/", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, 1, attempts)
+}
+
+func TestDo_CircuitBreaker(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	env.client.retry = retryPolicy{}
+	env.client.breaker = newCircuitBreaker(2, time.Minute)
+	env.client.breaker.now = func() time.Time { return now }
+
+	healthy := false
+	attempts := 0
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if healthy {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		resp, err := env.client.Do(ctx, "GET", "api/tags/", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+
+	// The breaker is open, so requests fail without reaching paperless-ngx
+	_, err := env.client.Do(ctx, "GET", "api/tags/", nil)
+	assert.ErrorIs(t, err, errCircuitOpen)
+	assert.Equal(t, 2, attempts)
+
+	// After the cooldown a single request is let through, the others wait for its outcome
+	now = now.Add(time.Minute)
+	assert.True(t, env.client.breaker.allow())
+	assert.False(t, env.client.breaker.allow())
+
+	// Without an outcome the next request is let through after another cooldown, failing it opens the breaker again
+	now = now.Add(time.Minute)
+	resp, err := env.client.Do(ctx, "GET", "api/tags/", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	_, err = env.client.Do(ctx, "GET", "api/tags/", nil)
+	assert.ErrorIs(t, err, errCircuitOpen)
+	assert.Equal(t, 3, attempts)
+
+	// A probe that succeeds closes it
+	now = now.Add(time.Minute)
+	healthy = true
+	resp, err = env.client.Do(ctx, "GET", "api/tags/", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.True(t, env.client.breaker.allow())
+	assert.True(t, env.client.breaker.allow())
+	assert.Equal(t, 4, attempts)
+}
+
+func TestDo_RateLimit(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	env.client.limiter = rate.NewLimiter(rate.Limit(20), 1)
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		resp, err := env.client.Do(context.Background(), "GET", "api/tags/", nil)
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	// The first request is sent right away, the other two wait 50ms each
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := retryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+
+	assert.Equal(t, time.Second, policy.delay(0, nil))
+	assert.Equal(t, 4*time.Second, policy.delay(2, nil))
+	assert.Equal(t, 5*time.Second, policy.delay(5, nil))
+
+	resp := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	assert.Equal(t, 2*time.Second, policy.delay(0, resp))
+	resp.Header.Set("Retry-After", "120")
+	assert.Equal(t, 5*time.Second, policy.delay(0, resp))
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// PaperlessStatus describes the connection to paperless-ngx, for the connection health shown in the web UI
+type PaperlessStatus struct {
+	Reachable     bool   `json:"reachable"`
+	Authenticated bool   `json:"authenticated"`
+	Version       string `json:"version,omitempty"`
+	APIVersion    string `json:"api_version,omitempty"`
+	// TagCounts holds the number of documents per tag used by paperless-gpt, tags missing in paperless-ngx count 0
+	TagCounts map[string]int `json:"tag_counts,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// GetStatus checks whether paperless-ngx is reachable and accepts the credentials, and counts the documents
+// carrying each of the given tags. Problems are reported in the status rather than as an error.
+func (client *PaperlessClient) GetStatus(ctx context.Context, tags []string) PaperlessStatus {
+	var status PaperlessStatus
+
+	resp, err := client.Do(ctx, "GET", "api/tags/?page_size=1", nil)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+	defer resp.Body.Close()
+	status.Reachable = true
+
+	// paperless-ngx only sends its version to authenticated clients
+	status.Version = resp.Header.Get("X-Version")
+	status.APIVersion = resp.Header.Get("X-Api-Version")
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		status.Error = fmt.Sprintf("paperless-ngx rejected the credentials: %d", resp.StatusCode)
+		return status
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		status.Error = fmt.Sprintf("unexpected response from paperless-ngx: %d, %s", resp.StatusCode, string(bodyBytes))
+		return status
+	}
+	status.Authenticated = true
+
+	allTags, err := client.GetAllTags(ctx)
+	if err != nil {
+		status.Error = err.Error()
+		return status
+	}
+
+	status.TagCounts = make(map[string]int, len(tags))
+	for _, tag := range tags {
+		tagID, ok := allTags[tag]
+		if !ok {
+			status.TagCounts[tag] = 0
+			continue
+		}
+		count, err := client.CountDocuments(ctx, fmt.Sprintf("tags__id__all=%d", tagID))
+		if err != nil {
+			status.Error = err.Error()
+			return status
+		}
+		status.TagCounts[tag] = count
+	}
+
+	return status
+}
+
+// CountDocuments returns the number of documents matching the filter query
+func (client *PaperlessClient) CountDocuments(ctx context.Context, query string) (int, error) {
+	resp, err := client.Do(ctx, "GET", fmt.Sprintf("api/documents/?%s&page_size=1", query), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return 0, fmt.Errorf("error counting documents: %d, %s", resp.StatusCode, string(bodyBytes))
+	}
+
+	var documentsResponse GetDocumentsApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&documentsResponse); err != nil {
+		return 0, err
+	}
+	return documentsResponse.Count, nil
+}
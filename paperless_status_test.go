@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetStatus(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Version", "2.14.7")
+		w.Header().Set("X-Api-Version", "7")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count": 2, "results": [{"id": 1, "name": "paperless-gpt"}, {"id": 2, "name": "paperless-gpt-auto"}], "next": null}`))
+	})
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("tags__id__all") {
+		case "1":
+			w.Write([]byte(`{"count": 4, "results": []}`))
+		default:
+			w.Write([]byte(`{"count": 0, "results": []}`))
+		}
+	})
+
+	status := env.client.GetStatus(context.Background(), []string{"paperless-gpt", "paperless-gpt-auto", "paperless-gpt-ocr"})
+	assert.Equal(t, PaperlessStatus{
+		Reachable:     true,
+		Authenticated: true,
+		Version:       "2.14.7",
+		APIVersion:    "7",
+		TagCounts: map[string]int{
+			"paperless-gpt":      4,
+			"paperless-gpt-auto": 0,
+			"paperless-gpt-ocr":  0,
+		},
+	}, status)
+}
+
+func TestGetStatus_InvalidToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"detail": "Invalid token."}`))
+	}))
+	defer server.Close()
+
+	status := NewPaperlessClient(server.URL, "wrong").GetStatus(context.Background(), []string{"paperless-gpt"})
+	assert.True(t, status.Reachable)
+	assert.False(t, status.Authenticated)
+	assert.Contains(t, status.Error, "rejected the credentials")
+}
+
+func TestGetStatus_Unreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewPaperlessClient(server.URL, "token")
+	client.retry = retryPolicy{}
+	status := client.GetStatus(context.Background(), []string{"paperless-gpt"})
+	assert.False(t, status.Reachable)
+	assert.NotEmpty(t, status.Error)
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"crypto/md5"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -71,7 +73,7 @@ func InitializeTestDB() (*gorm.DB, error) {
 	}
 
 	// Migrate schema
-	err = db.AutoMigrate(&ModificationHistory{})
+	err = migrateSchema(db)
 	if err != nil {
 		return nil, err
 	}
@@ -89,6 +91,17 @@ func (env *testEnv) setMockResponse(path string, handler http.HandlerFunc) {
 	env.mockResponses[path] = handler
 }
 
+// mockDocumentMetadata mocks the metadata endpoint of a document, using the MD5 of the content as checksum like paperless-ngx
+func (env *testEnv) mockDocumentMetadata(documentID int, content []byte) {
+	env.setMockResponse(fmt.Sprintf("/api/documents/%d/metadata/", documentID), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"original_checksum":   fmt.Sprintf("%x", md5.Sum(content)),
+			"has_archive_version": false,
+		})
+	})
+}
+
 // TestNewPaperlessClient tests the creation of a new PaperlessClient instance
 func TestNewPaperlessClient(t *testing.T) {
 	baseURL := "http://example.com"
@@ -172,11 +185,63 @@ func TestGetAllTags(t *testing.T) {
 	assert.Equal(t, expectedTags, tags)
 }
 
+func TestCreateTag_Settings(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalColors, originalAlgorithm, originalInbox := newTagColors, newTagMatchingAlgorithm, newTagIsInbox
+	defer func() {
+		newTagColors, newTagMatchingAlgorithm, newTagIsInbox = originalColors, originalAlgorithm, originalInbox
+	}()
+	newTagColors = []string{"#a6cee3", "#1f78b4", "#b2df8a"}
+	newTagMatchingAlgorithm = "auto"
+	newTagIsInbox = true
+
+	var created []map[string]interface{}
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		var payload map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		created = append(created, payload)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(fmt.Sprintf(`{"id": %d}`, len(created))))
+	})
+
+	ctx := context.Background()
+	id, err := env.client.CreateTag(ctx, "Invoices")
+	require.NoError(t, err)
+	assert.Equal(t, 1, id)
+	_, err = env.client.CreateTag(ctx, "invoices")
+	require.NoError(t, err)
+
+	require.Len(t, created, 2)
+	assert.Equal(t, "Invoices", created[0]["name"])
+	assert.Equal(t, float64(6), created[0]["matching_algorithm"])
+	assert.Equal(t, true, created[0]["is_inbox_tag"])
+	assert.Contains(t, newTagColors, created[0]["color"])
+	// The color depends on the name only, so the same tag gets the same color on every instance
+	assert.Equal(t, created[0]["color"], created[1]["color"])
+
+	// Without a palette the color is left to paperless-ngx
+	newTagColors = nil
+	newTagMatchingAlgorithm = "none"
+	_, err = env.client.CreateTag(ctx, "Receipts")
+	require.NoError(t, err)
+	assert.NotContains(t, created[2], "color")
+	assert.Equal(t, float64(0), created[2]["matching_algorithm"])
+}
+
 // TestGetDocumentsByTags tests the GetDocumentsByTags method
 func TestGetDocumentsByTags(t *testing.T) {
 	env := newTestEnv(t)
 	defer env.teardown()
 
+	owner := 3
+	permissions := &DocumentPermissions{
+		View:   PermissionSet{Users: []int{4}, Groups: []int{}},
+		Change: PermissionSet{Users: []int{}, Groups: []int{2}},
+	}
+
 	// Mock data for documents
 	documentsResponse := GetDocumentsApiResponse{
 		Results: []GetDocumentApiResponseResult{
@@ -195,6 +260,8 @@ func TestGetDocumentsByTags(t *testing.T) {
 				Tags:          []int{2, 3},
 				Correspondent: 2,
 				CreatedDate:   "1999-09-02",
+				Owner:         &owner,
+				Permissions:   permissions,
 			},
 		},
 	}
@@ -212,7 +279,7 @@ func TestGetDocumentsByTags(t *testing.T) {
 	// Set mock responses
 	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
 		// Verify query parameters
-		expectedQuery := "tags__name__iexact=tag1&tags__name__iexact=tag2&page_size=25"
+		expectedQuery := "tags__name__iexact=tag1&tags__name__iexact=tag2&page_size=25&full_perms=true"
 		assert.Equal(t, expectedQuery, r.URL.RawQuery)
 		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(documentsResponse)
@@ -244,12 +311,60 @@ func TestGetDocumentsByTags(t *testing.T) {
 			Tags:          []string{"tag2", "tag3"},
 			Correspondent: "Beta",
 			CreatedDate:   "1999-09-02",
+			Owner:         &owner,
+			Permissions:   permissions,
 		},
 	}
 
 	assert.Equal(t, expectedDocuments, documents)
 }
 
+// TestDocuments_Pagination tests that the document iterator follows all pages and resolves names only once
+func TestDocuments_Pagination(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	pageRequests := 0
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		pageRequests++
+		assert.Equal(t, "2", r.URL.Query().Get("page_size"))
+		w.WriteHeader(http.StatusOK)
+		switch r.URL.Query().Get("page") {
+		case "":
+			w.Write([]byte(`{"count": 5, "next": "http://paperless/api/documents/?page=2&page_size=2", "results": [{"id": 1, "tags": [1]}, {"id": 2, "tags": [1]}]}`))
+		case "2":
+			w.Write([]byte(`{"count": 5, "next": "http://paperless/api/documents/?page=3&page_size=2", "results": [{"id": 3, "tags": [1]}, {"id": 4, "tags": [1]}]}`))
+		case "3":
+			w.Write([]byte(`{"count": 5, "next": null, "results": [{"id": 5, "tags": [1]}]}`))
+		}
+	})
+
+	tagRequests := 0
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		tagRequests++
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}], "next": null}`))
+	})
+
+	ctx := context.Background()
+	var ids []int
+	for document, err := range env.client.Documents(ctx, "tags__name__iexact=tag1", 2) {
+		require.NoError(t, err)
+		assert.Equal(t, []string{"tag1"}, document.Tags)
+		ids = append(ids, document.ID)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, ids)
+	assert.Equal(t, 3, pageRequests)
+	assert.Equal(t, 1, tagRequests)
+
+	// Stopping early doesn't fetch the remaining pages
+	pageRequests = 0
+	documents, err := env.client.GetDocumentsByTags(ctx, []string{"tag1"}, 2)
+	require.NoError(t, err)
+	assert.Len(t, documents, 2)
+	assert.Equal(t, 1, pageRequests)
+}
+
 // TestDownloadPDF tests the DownloadPDF method
 func TestDownloadPDF(t *testing.T) {
 	env := newTestEnv(t)
@@ -270,6 +385,7 @@ func TestDownloadPDF(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		w.Write(pdfContent)
 	})
+	env.mockDocumentMetadata(document.ID, pdfContent)
 
 	ctx := context.Background()
 	data, err := env.client.DownloadPDF(ctx, document)
@@ -314,7 +430,7 @@ func TestUpdateDocuments(t *testing.T) {
 	}
 
 	// Set the manual tag
-	manualTag = "manual"
+	setSettings(t, func(settings *runtimeSettings) { settings.ManualTag = "manual" })
 
 	// Set mock responses
 	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
@@ -354,6 +470,107 @@ func TestUpdateDocuments(t *testing.T) {
 	require.NoError(t, err)
 }
 
+// TestGetDocument_Permissions tests that the owner and permissions of a document are fetched
+func TestGetDocument_Permissions(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.URL.Query().Get("full_perms"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 1, "title": "Title", "tags": [], "owner": 3, "permissions": {"view": {"users": [4], "groups": []}, "change": {"users": [], "groups": [2]}}}`))
+	})
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	document, err := env.client.GetDocument(context.Background(), 1)
+	require.NoError(t, err)
+	require.NotNil(t, document.Owner)
+	assert.Equal(t, 3, *document.Owner)
+	assert.Equal(t, &DocumentPermissions{
+		View:   PermissionSet{Users: []int{4}, Groups: []int{}},
+		Change: PermissionSet{Users: []int{}, Groups: []int{2}},
+	}, document.Permissions)
+}
+
+// TestUpdateDocuments_PreservesPermissions tests that the owner and permissions are sent back unchanged on updates
+func TestUpdateDocuments_PreservesPermissions(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	owner := 3
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Old Title",
+				Owner: &owner,
+				Permissions: &DocumentPermissions{
+					View:   PermissionSet{Users: []int{4}, Groups: []int{}},
+					Change: PermissionSet{Users: []int{}, Groups: []int{2}},
+				},
+			},
+			SuggestedTitle: "New Title",
+		},
+		{
+			// Documents without owner are sent back with an explicit null owner
+			ID: 2,
+			OriginalDocument: Document{
+				ID:          2,
+				Title:       "Old Title",
+				Permissions: &DocumentPermissions{},
+			},
+			SuggestedTitle: "New Title",
+		},
+		{
+			// Permissions that were never fetched are left alone
+			ID:               3,
+			OriginalDocument: Document{ID: 3, Title: "Old Title"},
+			SuggestedTitle:   "New Title",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Equal(t, float64(3), updatedFields["owner"])
+		assert.Equal(t, map[string]interface{}{
+			"view":   map[string]interface{}{"users": []interface{}{float64(4)}, "groups": []interface{}{}},
+			"change": map[string]interface{}{"users": []interface{}{}, "groups": []interface{}{float64(2)}},
+		}, updatedFields["set_permissions"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	env.setMockResponse("/api/documents/2/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Contains(t, updatedFields, "owner")
+		assert.Nil(t, updatedFields["owner"])
+		assert.Contains(t, updatedFields, "set_permissions")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	env.setMockResponse("/api/documents/3/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.NotContains(t, updatedFields, "owner")
+		assert.NotContains(t, updatedFields, "set_permissions")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+}
+
 // TestUrlEncode tests the urlEncode function
 func TestUrlEncode(t *testing.T) {
 	input := "tag:tag1 tag:tag2"
@@ -382,6 +599,7 @@ func TestDownloadDocumentAsImages(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		w.Write(pdfContent)
 	})
+	env.mockDocumentMetadata(document.ID, pdfContent)
 
 	ctx := context.Background()
 	imagePaths, err := env.client.DownloadDocumentAsImages(ctx, document.ID, 0)
@@ -416,6 +634,7 @@ func TestDownloadDocumentAsImages_ManyPages(t *testing.T) {
 		w.WriteHeader(http.StatusOK)
 		w.Write(pdfContent)
 	})
+	env.mockDocumentMetadata(document.ID, pdfContent)
 
 	ctx := context.Background()
 	env.client.CacheFolder = "tests/tmp"
@@ -433,3 +652,542 @@ func TestDownloadDocumentAsImages_ManyPages(t *testing.T) {
 		assert.Contains(t, imagePath, "tests/tmp/document-321/page")
 	}
 }
+
+// TestGetAllCustomFields tests the GetAllCustomFields method, including both select option formats
+func TestGetAllCustomFields(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/custom_fields/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [
+			{"id": 1, "name": "Invoice Number", "data_type": "string", "extra_data": {}},
+			{"id": 2, "name": "Category", "data_type": "select", "extra_data": {"select_options": ["Private", "Business"]}},
+			{"id": 3, "name": "Status", "data_type": "select", "extra_data": {"select_options": [{"id": "a1", "label": "Open"}]}}
+		], "next": null}`))
+	})
+
+	ctx := context.Background()
+	customFields, err := env.client.GetAllCustomFields(ctx)
+	require.NoError(t, err)
+
+	require.Len(t, customFields, 3)
+	assert.Equal(t, "Invoice Number", customFields[0].Name)
+	assert.Equal(t, []CustomFieldSelectOption{{Label: "Private"}, {Label: "Business"}}, customFields[1].ExtraData.SelectOptions)
+	assert.Equal(t, []CustomFieldSelectOption{{ID: "a1", Label: "Open"}}, customFields[2].ExtraData.SelectOptions)
+}
+
+// TestCustomFieldCRUD tests creating, reading, updating and deleting custom field definitions
+func TestCustomFieldCRUD(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/custom_fields/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Priority", body["name"])
+		assert.Equal(t, "select", body["data_type"])
+		assert.Equal(t, map[string]interface{}{"select_options": []interface{}{
+			map[string]interface{}{"label": "High"},
+			map[string]interface{}{"label": "Low"},
+		}}, body["extra_data"])
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"id": 5, "name": "Priority", "data_type": "select", "extra_data": {"select_options": [{"id": "h1", "label": "High"}, {"id": "l1", "label": "Low"}]}}`))
+	})
+
+	env.setMockResponse("/api/custom_fields/5/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 5, "name": "Priority", "data_type": "select", "extra_data": {"select_options": [{"id": "h1", "label": "High"}, {"id": "l1", "label": "Low"}]}}`))
+		case "PATCH":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "Urgency", body["name"])
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"id": 5, "name": "Urgency", "data_type": "select", "extra_data": {"select_options": [{"id": "h1", "label": "High"}, {"id": "l1", "label": "Low"}]}}`))
+		case "DELETE":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("Unexpected method %s", r.Method)
+		}
+	})
+
+	ctx := context.Background()
+	created, err := env.client.CreateCustomField(ctx, CustomField{
+		Name:      "Priority",
+		DataType:  "select",
+		ExtraData: CustomFieldExtraData{SelectOptions: []CustomFieldSelectOption{{Label: "High"}, {Label: "Low"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 5, created.ID)
+
+	field, err := env.client.GetCustomField(ctx, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []CustomFieldSelectOption{{ID: "h1", Label: "High"}, {ID: "l1", Label: "Low"}}, field.ExtraData.SelectOptions)
+
+	field.Name = "Urgency"
+	updated, err := env.client.UpdateCustomField(ctx, field)
+	require.NoError(t, err)
+	assert.Equal(t, "Urgency", updated.Name)
+
+	require.NoError(t, env.client.DeleteCustomField(ctx, 5))
+}
+
+// TestSetDocumentCustomFields tests that typed values are set while the other values of the document are kept
+func TestSetDocumentCustomFields(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case "GET":
+			assert.Equal(t, "custom_fields", r.URL.Query().Get("fields"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"custom_fields": [{"field": 1, "value": "keep"}, {"field": 2, "value": "EUR1.00"}]}`))
+		case "PATCH":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, []interface{}{
+				map[string]interface{}{"field": float64(1), "value": "keep"},
+				map[string]interface{}{"field": float64(2), "value": "EUR12.50"},
+				map[string]interface{}{"field": float64(3), "value": "l1"},
+				map[string]interface{}{"field": float64(4), "value": "2024-03-31"},
+				map[string]interface{}{"field": float64(6), "value": "https://example.com"},
+			}, body["custom_fields"])
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{}`))
+		}
+	})
+
+	amount, err := NewCustomFieldValue(CustomField{ID: 2, Name: "Amount", DataType: "monetary", ExtraData: CustomFieldExtraData{DefaultCurrency: "EUR"}}, 12.5)
+	require.NoError(t, err)
+	priority, err := NewCustomFieldValue(CustomField{ID: 3, Name: "Priority", DataType: "select", ExtraData: CustomFieldExtraData{SelectOptions: []CustomFieldSelectOption{{ID: "h1", Label: "High"}, {ID: "l1", Label: "Low"}}}}, "low")
+	require.NoError(t, err)
+	dueDate, err := NewCustomFieldValue(CustomField{ID: 4, Name: "Due", DataType: "date"}, "31.03.2024")
+	require.NoError(t, err)
+	website, err := NewCustomFieldValue(CustomField{ID: 6, Name: "Website", DataType: "url"}, "https://example.com")
+	require.NoError(t, err)
+
+	_, err = NewCustomFieldValue(CustomField{ID: 4, Name: "Due", DataType: "date"}, "soon")
+	assert.Error(t, err)
+
+	err = env.client.SetDocumentCustomFields(context.Background(), 1, []CustomFieldValue{amount, priority, dueDate, website})
+	require.NoError(t, err)
+}
+
+// TestUpdateDocuments_CustomFields tests that suggested custom fields are merged with the existing values
+func TestUpdateDocuments_CustomFields(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Title",
+				Tags:  []string{"tag1"},
+				CustomFields: []CustomFieldValue{
+					{Field: 1, Value: "old"},
+					{Field: 2, Value: "untouched"},
+				},
+			},
+			SuggestedCustomFields: []CustomFieldValue{
+				{Field: 1, Value: "new"},
+				{Field: 3, Value: "2024-01-31"},
+			},
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		err := json.NewDecoder(r.Body).Decode(&updatedFields)
+		require.NoError(t, err)
+
+		expectedCustomFields := []interface{}{
+			map[string]interface{}{"field": float64(1), "value": "new"},
+			map[string]interface{}{"field": float64(2), "value": "untouched"},
+			map[string]interface{}{"field": float64(3), "value": "2024-01-31"},
+		}
+		assert.Equal(t, expectedCustomFields, updatedFields["custom_fields"])
+
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx := context.Background()
+	err := env.client.UpdateDocuments(ctx, documents, env.db, false)
+	require.NoError(t, err)
+
+	var modification ModificationHistory
+	require.NoError(t, env.db.Where("document_id = ? AND mod_field = ?", 1, "custom_fields").Last(&modification).Error)
+	assert.Equal(t, `[{"field":1,"value":"old"},{"field":2,"value":"untouched"}]`, modification.PreviousValue)
+}
+
+// TestStoragePaths tests listing storage paths across pages and creating a new one
+func TestStoragePaths(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/storage_paths/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == "POST":
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "Taxes", body["name"])
+			assert.Equal(t, "taxes/{{ created_year }}/{{ title }}", body["path"])
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 3, "name": "Taxes", "path": "taxes/{{ created_year }}/{{ title }}"}`))
+		case r.URL.Query().Get("page") == "2":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results": [{"id": 2, "name": "Receipts", "path": "receipts/{{ title }}"}], "next": null}`))
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"results": [{"id": 1, "name": "Invoices", "path": "invoices/{{ correspondent }}/{{ title }}"}], "next": "/api/storage_paths/?page=2"}`))
+		}
+	})
+
+	ctx := context.Background()
+	storagePaths, err := env.client.GetAllStoragePaths(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []StoragePath{
+		{ID: 1, Name: "Invoices", Path: "invoices/{{ correspondent }}/{{ title }}"},
+		{ID: 2, Name: "Receipts", Path: "receipts/{{ title }}"},
+	}, storagePaths)
+	assert.Equal(t, "Receipts", storagePathNameByID(storagePaths, 2))
+	assert.Equal(t, "", storagePathNameByID(storagePaths, 9))
+
+	created, err := env.client.CreateStoragePath(ctx, StoragePath{Name: "Taxes", Path: "taxes/{{ created_year }}/{{ title }}"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, created.ID)
+}
+
+// TestUpdateDocuments_StoragePath tests that suggested storage paths are mapped to their IDs and unknown ones are skipped
+func TestUpdateDocuments_StoragePath(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	documents := []DocumentSuggestion{
+		{
+			ID:                   1,
+			OriginalDocument:     Document{ID: 1, Title: "Title", Tags: []string{"tag1"}},
+			SuggestedStoragePath: "Invoices",
+		},
+		{
+			ID:                   2,
+			OriginalDocument:     Document{ID: 2, Title: "Title", Tags: []string{"tag1"}},
+			SuggestedStoragePath: "Unknown",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/storage_paths/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 4, "name": "Invoices", "path": "invoices/{{ title }}"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Equal(t, float64(4), updatedFields["storage_path"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	env.setMockResponse("/api/documents/2/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.NotContains(t, updatedFields, "storage_path")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+}
+
+// TestUpdateDocuments_LanguageTag tests that the language tag is created and added to the document
+func TestUpdateDocuments_LanguageTag(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalPrefix := languageTagPrefix
+	defer func() { languageTagPrefix = originalPrefix }()
+	languageTagPrefix = "lang:"
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Title",
+				Tags:  []string{"tag1"},
+			},
+			SuggestedLanguage: "de",
+		},
+	}
+
+	tagCreated := false
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			var body map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "lang:de", body["name"])
+			tagCreated = true
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"id": 7, "name": "lang:de"}`))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Equal(t, []interface{}{float64(7), float64(1)}, updatedFields["tags"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+	assert.True(t, tagCreated)
+}
+
+// TestUpdateDocuments_SummaryNote tests that a suggested summary is added as a document note
+func TestUpdateDocuments_SummaryNote(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Title",
+			},
+			SuggestedSummary: "Invoice for March.",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	noteAdded := false
+	env.setMockResponse("/api/documents/1/notes/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Invoice for March.", body["note"])
+		noteAdded = true
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[]`))
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+	assert.True(t, noteAdded)
+}
+
+// TestDocumentNotes tests reading, appending and deleting document notes
+func TestDocumentNotes(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	var methods []string
+	env.setMockResponse("/api/documents/1/notes/", func(w http.ResponseWriter, r *http.Request) {
+		methods = append(methods, r.Method)
+		switch r.Method {
+		case "GET":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[{"id": 7, "note": "Summary: invoice for March.", "created": "2024-03-01T10:00:00Z", "user": {"id": 1, "username": "admin"}}]`))
+		case "POST":
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "OCR: processed by llm", body["note"])
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[]`))
+		case "DELETE":
+			assert.Equal(t, "7", r.URL.Query().Get("id"))
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[]`))
+		}
+	})
+
+	ctx := context.Background()
+	notes, err := env.client.GetNotes(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []Note{{ID: 7, Note: "Summary: invoice for March.", Created: "2024-03-01T10:00:00Z"}}, notes)
+
+	require.NoError(t, env.client.AddNote(ctx, 1, "OCR: processed by llm"))
+	require.NoError(t, env.client.DeleteNote(ctx, 1, 7))
+	assert.Equal(t, []string{"GET", "POST", "DELETE"}, methods)
+}
+
+// TestUpdateDocuments_DueSoonTag tests that the due soon tag is added for upcoming due dates
+func TestUpdateDocuments_DueSoonTag(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	setSettings(t, func(settings *runtimeSettings) { settings.DueSoonTag = "due-soon" })
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Invoice",
+				Tags:  []string{"tag1"},
+			},
+			SuggestedDueDate: time.Now().Format("2006-01-02"),
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}, {"id": 3, "name": "due-soon"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.ElementsMatch(t, []interface{}{float64(1), float64(3)}, updatedFields["tags"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+}
+
+func TestUpdateDocuments_SensitivityTag(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalLevels, originalPrefix := sensitivityLevels, sensitivityTagPrefix
+	defer func() {
+		sensitivityLevels, sensitivityTagPrefix = originalLevels, originalPrefix
+	}()
+	sensitivityLevels = []string{"public", "internal", "confidential"}
+	sensitivityTagPrefix = "sensitivity:"
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Bank Statement",
+				Tags:  []string{"tag1", "sensitivity:internal"},
+			},
+			SuggestedSensitivity: "confidential",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}, {"id": 4, "name": "sensitivity:internal"}, {"id": 5, "name": "sensitivity:confidential"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.ElementsMatch(t, []interface{}{float64(1), float64(5)}, updatedFields["tags"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+
+	var record ModificationHistory
+	require.NoError(t, env.db.Where("document_id = ? AND mod_field = ?", 1, "sensitivity").Last(&record).Error)
+	assert.Equal(t, "internal", record.PreviousValue)
+	assert.Equal(t, "confidential", record.NewValue)
+}
+
+// TestUpdateDocuments_TranslationContent tests that translations can be appended to the document content
+func TestUpdateDocuments_TranslationContent(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalTarget, originalLanguage := translationTarget, translationLanguage
+	defer func() {
+		translationTarget, translationLanguage = originalTarget, originalLanguage
+	}()
+	translationTarget = "content"
+	translationLanguage = "en"
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:      1,
+				Title:   "Rechnung",
+				Content: "Rechnung für März",
+			},
+			SuggestedTranslation: "Invoice for March",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.Equal(t, "Rechnung für März\n\n--- Translation (English) ---\n\nInvoice for March", updatedFields["content"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+}
+
+// TestUpdateDocuments_ActionRequiredTag tests that documents requiring action get the action required tag
+func TestUpdateDocuments_ActionRequiredTag(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	setSettings(t, func(settings *runtimeSettings) { settings.ActionRequiredTag = "action-required" })
+
+	documents := []DocumentSuggestion{
+		{
+			ID: 1,
+			OriginalDocument: Document{
+				ID:    1,
+				Title: "Reminder",
+				Tags:  []string{"tag1"},
+			},
+			SuggestedAction: "payment",
+		},
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}, {"id": 6, "name": "action-required"}], "next": null}`))
+	})
+
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		var updatedFields map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+		assert.ElementsMatch(t, []interface{}{float64(1), float64(6)}, updatedFields["tags"])
+		w.WriteHeader(http.StatusOK)
+	})
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+}
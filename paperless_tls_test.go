@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeClientCertificate creates a self-signed client certificate and returns the paths of its PEM files
+func writeClientCertificate(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "paperless-gpt"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	certificate, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certificate, certFile, keyFile
+}
+
+func TestPaperlessTLSConfig_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	clientCertificate, certFile, keyFile := writeClientCertificate(t, dir)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	}))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(clientCertificate)
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0600))
+
+	originalTLSConfig := paperlessTLSConfig
+	defer func() { paperlessTLSConfig = originalTLSConfig }()
+
+	// Trusting the CA is not enough when the proxy asks for a client certificate
+	tlsConfig, err := newPaperlessTLSConfig(caFile, "", "", false)
+	require.NoError(t, err)
+	paperlessTLSConfig = tlsConfig
+	client := NewPaperlessClient(server.URL, "token")
+	client.retry = retryPolicy{}
+	_, err = client.GetAllTags(context.Background())
+	assert.Error(t, err)
+
+	tlsConfig, err = newPaperlessTLSConfig(caFile, certFile, keyFile, false)
+	require.NoError(t, err)
+	paperlessTLSConfig = tlsConfig
+	_, err = NewPaperlessClient(server.URL, "token").GetAllTags(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPaperlessTLSConfig_InvalidSettings(t *testing.T) {
+	dir := t.TempDir()
+	_, certFile, keyFile := writeClientCertificate(t, dir)
+
+	_, err := newPaperlessTLSConfig("", certFile, "", false)
+	assert.ErrorContains(t, err, "PAPERLESS_CLIENT_KEY")
+
+	_, err = newPaperlessTLSConfig(keyFile, "", "", false)
+	assert.ErrorContains(t, err, "no certificates found")
+
+	_, err = newPaperlessTLSConfig(filepath.Join(dir, "missing.pem"), "", "", false)
+	assert.ErrorContains(t, err, "error reading CA bundle")
+}
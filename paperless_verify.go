@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// unverifiableFields are sent with updates but not returned as sent by paperless-ngx
+var unverifiableFields = map[string]bool{
+	"owner":           true,
+	"set_permissions": true,
+}
+
+// verifyDocumentUpdate fetches a document after an update and sends the fields paperless-ngx didn't store again,
+// up to verifyUpdateRetries times. This catches updates that get lost silently, e.g. overwritten by a workflow
+// or a consumer finishing at the same time.
+func (client *PaperlessClient) verifyDocumentUpdate(ctx context.Context, documentID int, fields map[string]interface{}) error {
+	for attempt := 0; ; attempt++ {
+		mismatched, err := client.mismatchedDocumentFields(ctx, documentID, fields)
+		if err != nil {
+			return err
+		}
+		if len(mismatched) == 0 {
+			return nil
+		}
+
+		names := make([]string, 0, len(mismatched))
+		for field := range mismatched {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+
+		if attempt >= verifyUpdateRetries {
+			return fmt.Errorf("update of document %d was not applied to %s", documentID, strings.Join(names, ", "))
+		}
+
+		log.Warnf("Update of document %d was not applied to %s, sending it again", documentID, strings.Join(names, ", "))
+		if err := client.patchDocument(ctx, documentID, mismatched); err != nil {
+			return err
+		}
+	}
+}
+
+// mismatchedDocumentFields returns the fields whose stored value differs from the given one.
+// Fields the document response doesn't contain can't be compared and are skipped.
+func (client *PaperlessClient) mismatchedDocumentFields(ctx context.Context, documentID int, fields map[string]interface{}) (map[string]interface{}, error) {
+	path := fmt.Sprintf("api/documents/%d/", documentID)
+	resp, err := client.Do(ctx, "GET", path, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("error fetching document %d: %w", documentID, errDocumentNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("error fetching document %d: %d, %s", documentID, resp.StatusCode, string(bodyBytes))
+	}
+
+	var stored map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil {
+		return nil, err
+	}
+
+	mismatched := make(map[string]interface{})
+	for field, value := range fields {
+		storedValue, ok := stored[field]
+		if !ok || unverifiableFields[field] {
+			continue
+		}
+		same, err := sameFieldValue(value, storedValue)
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			mismatched[field] = value
+		}
+	}
+	return mismatched, nil
+}
+
+// sameFieldValue compares a sent value with the one decoded from paperless-ngx.
+// Lists are compared regardless of order, as paperless-ngx doesn't keep the order of tags and custom fields.
+func sameFieldValue(sent, stored interface{}) (bool, error) {
+	// Round trip the sent value through JSON so both sides use the same types
+	data, err := json.Marshal(sent)
+	if err != nil {
+		return false, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return false, err
+	}
+
+	sentList, sentIsList := normalized.([]interface{})
+	storedList, storedIsList := stored.([]interface{})
+	if !sentIsList || !storedIsList {
+		return reflect.DeepEqual(normalized, stored), nil
+	}
+	if len(sentList) != len(storedList) {
+		return false, nil
+	}
+
+	sentItems, err := sortedJSONItems(sentList)
+	if err != nil {
+		return false, err
+	}
+	storedItems, err := sortedJSONItems(storedList)
+	if err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(sentItems, storedItems), nil
+}
+
+// sortedJSONItems encodes the items of a list to JSON and sorts them
+func sortedJSONItems(list []interface{}) ([]string, error) {
+	items := make([]string, 0, len(list))
+	for _, item := range list {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, string(data))
+	}
+	sort.Strings(items)
+	return items, nil
+}
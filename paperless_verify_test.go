@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mockLosingDocument mocks a document that drops the first lostUpdates title changes, like a concurrent write would
+func mockLosingDocument(t *testing.T, env *testEnv, lostUpdates int) *int {
+	title := "Title"
+	patches := 0
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "PATCH" {
+			var updatedFields map[string]interface{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&updatedFields))
+			patches++
+			if patches > lostUpdates {
+				title = updatedFields["title"].(string)
+			}
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 1, "title": title, "tags": []int{2, 1}})
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "tag1"}, {"id": 2, "name": "tag2"}], "next": null}`))
+	})
+	return &patches
+}
+
+func TestUpdateDocuments_VerifyResendsLostUpdate(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalVerify := verifyDocumentUpdates
+	defer func() { verifyDocumentUpdates = originalVerify }()
+	verifyDocumentUpdates = true
+
+	patches := mockLosingDocument(t, env, 1)
+
+	documents := []DocumentSuggestion{
+		{
+			ID:               1,
+			OriginalDocument: Document{ID: 1, Title: "Title", Tags: []string{"tag1"}},
+			SuggestedTitle:   "New Title",
+			SuggestedTags:    []string{"tag1", "tag2"},
+		},
+	}
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	require.NoError(t, err)
+	assert.Equal(t, 2, *patches)
+}
+
+func TestUpdateDocuments_VerifyFailsAfterRetries(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalVerify, originalRetries := verifyDocumentUpdates, verifyUpdateRetries
+	defer func() { verifyDocumentUpdates, verifyUpdateRetries = originalVerify, originalRetries }()
+	verifyDocumentUpdates = true
+	verifyUpdateRetries = 1
+
+	patches := mockLosingDocument(t, env, 5)
+
+	documents := []DocumentSuggestion{
+		{
+			ID:               1,
+			OriginalDocument: Document{ID: 1, Title: "Title", Tags: []string{"tag1"}},
+			SuggestedTitle:   "New Title",
+			SuggestedTags:    []string{"tag1", "tag2"},
+		},
+	}
+
+	err := env.client.UpdateDocuments(context.Background(), documents, env.db, false)
+	assert.EqualError(t, err, "update of document 1 was not applied to title")
+	assert.Equal(t, 2, *patches)
+}
+
+func TestSameFieldValue(t *testing.T) {
+	same, err := sameFieldValue([]int{1, 2}, []interface{}{float64(2), float64(1)})
+	require.NoError(t, err)
+	assert.True(t, same)
+
+	same, err = sameFieldValue([]CustomFieldValue{{Field: 3, Value: "a"}}, []interface{}{map[string]interface{}{"field": float64(3), "value": "b"}})
+	require.NoError(t, err)
+	assert.False(t, same)
+
+	same, err = sameFieldValue(4, float64(4))
+	require.NoError(t, err)
+	assert.True(t, same)
+}
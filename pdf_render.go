@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// Color modes of PDF_RENDER_COLOR_MODE
+const (
+	pdfColorModeColor = "color"
+	pdfColorModeGray  = "gray"
+)
+
+// pdfColorModes lists the valid values of PDF_RENDER_COLOR_MODE
+var pdfColorModes = []string{pdfColorModeColor, pdfColorModeGray}
+
+// defaultPDFRenderDPI is the resolution pages are rendered with unless PDF_RENDER_DPI is set
+const defaultPDFRenderDPI = 300
+
+// Defaults of PDF_RENDER_MAX_DIMENSION and PDF_RENDER_JPEG_QUALITY. Vision LLMs scale larger images down anyway and
+// are billed by the pixel, while Azure and Google Document AI read small print better at full resolution.
+const (
+	defaultLLMMaxDimension       = 2048
+	defaultPDFJPEGQuality        = jpeg.DefaultQuality
+	defaultDocumentAIJPEGQuality = 90
+)
+
+// pdfRenderSettings describes how pages are rendered to images
+type pdfRenderSettings struct {
+	DPI          int
+	ColorMode    string
+	MaxDimension int // Longest side of the images in pixels, 0 for no limit
+	JPEGQuality  int
+}
+
+// renderSettings returns the settings of PDF_RENDER_DPI, PDF_RENDER_COLOR_MODE, PDF_RENDER_MAX_DIMENSION and
+// PDF_RENDER_JPEG_QUALITY
+func (settings *runtimeSettings) renderSettings() pdfRenderSettings {
+	return pdfRenderSettings{DPI: settings.PDFRenderDPI, ColorMode: settings.PDFRenderColorMode, MaxDimension: settings.PDFRenderMaxDimension, JPEGQuality: settings.PDFRenderJPEGQuality}
+}
+
+// defaultPDFRenderLimits returns the default longest side and JPEG quality of the page images for an OCR provider
+func defaultPDFRenderLimits(provider string) (maxDimension int, quality int) {
+	if provider == "llm" {
+		return defaultLLMMaxDimension, defaultPDFJPEGQuality
+	}
+	return 0, defaultDocumentAIJPEGQuality
+}
+
+// String identifies the settings, pages cached with other settings are rendered again
+func (settings pdfRenderSettings) String() string {
+	return fmt.Sprintf("%d dpi %s max %d quality %d", settings.DPI, settings.ColorMode, settings.MaxDimension, settings.JPEGQuality)
+}
+
+// renderPage renders a page of a document in process with MuPDF and returns it with the resolution it was rendered
+// with. Pages that would exceed the maximum dimension are rendered with a lower resolution instead of being scaled
+// down afterwards. Grayscale pages are a third of the size of color pages, which is all OCR needs for most scans.
+func renderPage(doc *fitz.Document, pageNumber int, settings pdfRenderSettings) (image.Image, float64, error) {
+	dpi := float64(settings.DPI)
+	if settings.MaxDimension > 0 {
+		// The bounds are in whole points, 1/72 inch, and MuPDF rounds the size of the image up, so a margin of two
+		// pixels keeps the image within the maximum
+		bounds, err := doc.Bound(pageNumber)
+		if err != nil {
+			return nil, 0, err
+		}
+		if longest := max(bounds.Dx(), bounds.Dy()); longest > 0 {
+			dpi = min(dpi, float64(max(settings.MaxDimension-2, 1))*72/float64(longest))
+		}
+	}
+
+	img, err := doc.ImageDPI(pageNumber, dpi)
+	if err != nil {
+		return nil, 0, err
+	}
+	if settings.ColorMode != pdfColorModeGray {
+		return img, dpi, nil
+	}
+	gray := image.NewGray(img.Bounds())
+	draw.Draw(gray, gray.Bounds(), img, img.Bounds().Min, draw.Src)
+	return gray, dpi, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/gen2brain/go-fitz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPage(t *testing.T) {
+	doc, err := fitz.New("tests/pdf/sample.pdf")
+	require.NoError(t, err)
+	defer doc.Close()
+
+	color, dpi, err := renderPage(doc, 0, pdfRenderSettings{DPI: 144, ColorMode: pdfColorModeColor})
+	require.NoError(t, err)
+	assert.IsType(t, &image.RGBA{}, color)
+	assert.Equal(t, 144.0, dpi)
+
+	gray, dpi, err := renderPage(doc, 0, pdfRenderSettings{DPI: 72, ColorMode: pdfColorModeGray})
+	require.NoError(t, err)
+	assert.IsType(t, &image.Gray{}, gray)
+	assert.InDelta(t, color.Bounds().Dx()/2, gray.Bounds().Dx(), 1, "half the resolution")
+
+	// Gray pages are embedded with their own color space
+	var jpegData bytes.Buffer
+	require.NoError(t, jpeg.Encode(&jpegData, gray, nil))
+	var pdf bytes.Buffer
+	require.NoError(t, writeSearchablePDF(&pdf, []pdfPage{{JPEG: jpegData.Bytes(), Width: gray.Bounds().Dx(), Height: gray.Bounds().Dy(), DPI: dpi, Gray: true}}))
+	assert.Contains(t, pdf.String(), "/ColorSpace /DeviceGray")
+
+	written, err := fitz.NewFromMemory(pdf.Bytes())
+	require.NoError(t, err)
+	defer written.Close()
+	bounds, err := written.Bound(0)
+	require.NoError(t, err)
+	original, err := doc.Bound(0)
+	require.NoError(t, err)
+	assert.InDelta(t, original.Dx(), bounds.Dx(), 1, "the page keeps its size at 72 dpi")
+}
+
+func TestRenderPage_MaxDimension(t *testing.T) {
+	doc, err := fitz.New("tests/pdf/sample.pdf")
+	require.NoError(t, err)
+	defer doc.Close()
+
+	img, dpi, err := renderPage(doc, 0, pdfRenderSettings{DPI: 300, ColorMode: pdfColorModeColor, MaxDimension: 1000})
+	require.NoError(t, err)
+	assert.Less(t, dpi, 300.0)
+	assert.LessOrEqual(t, max(img.Bounds().Dx(), img.Bounds().Dy()), 1000)
+	assert.Greater(t, max(img.Bounds().Dx(), img.Bounds().Dy()), 990)
+
+	// Smaller pages keep the resolution
+	img, dpi, err = renderPage(doc, 0, pdfRenderSettings{DPI: 72, ColorMode: pdfColorModeColor, MaxDimension: 5000})
+	require.NoError(t, err)
+	assert.Equal(t, 72.0, dpi)
+	assert.Less(t, max(img.Bounds().Dx(), img.Bounds().Dy()), 5000)
+}
+
+func TestDefaultPDFRenderLimits(t *testing.T) {
+	maxDimension, quality := defaultPDFRenderLimits("llm")
+	assert.Equal(t, 2048, maxDimension)
+	assert.Equal(t, 75, quality)
+
+	maxDimension, quality = defaultPDFRenderLimits("azure")
+	assert.Zero(t, maxDimension, "Azure reads the full resolution")
+	assert.Equal(t, 90, quality)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// pdfTextFontSize is the font size of the invisible text layer
+const pdfTextFontSize = 8
+
+// pdfPage is a scanned page with its OCR text
+type pdfPage struct {
+	JPEG   []byte  // Page image, JPEG encoded
+	Width  int     // Image width in pixels
+	Height int     // Image height in pixels
+	DPI    float64 // Resolution of the image, used to calculate the page size in points. 0 for defaultPDFRenderDPI
+	Gray   bool    // The image has a single gray channel
+	Text   string  // OCR text of the page, may be empty
+}
+
+// writeSearchablePDF writes a PDF with the page images and their OCR text as an invisible text layer,
+// so the document is searchable and paperless-ngx can use the text without running OCR itself.
+// The text is not positioned on the words of the image, as most OCR providers only return plain text.
+func writeSearchablePDF(w io.Writer, pages []pdfPage) error {
+	if len(pages) == 0 {
+		return fmt.Errorf("no pages to write")
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+	addObject := func(body string, stream []byte) int {
+		offsets = append(offsets, buf.Len())
+		id := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n%s", id, body)
+		if stream != nil {
+			buf.WriteString("\nstream\n")
+			buf.Write(stream)
+			buf.WriteString("\nendstream")
+		}
+		buf.WriteString("\nendobj\n")
+		return id
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1 and 2 are the catalog and the page tree, the font is shared by all pages
+	catalogID := addObject("<< /Type /Catalog /Pages 2 0 R >>", nil)
+	pagesOffset := len(offsets)
+	offsets = append(offsets, 0) // Page tree, written once all pages are known
+	fontID := addObject("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+
+	var pageIDs []string
+	for _, page := range pages {
+		dpi := page.DPI
+		if dpi == 0 {
+			dpi = defaultPDFRenderDPI
+		}
+		width := float64(page.Width) * 72 / dpi
+		height := float64(page.Height) * 72 / dpi
+
+		colorSpace := "/DeviceRGB"
+		if page.Gray {
+			colorSpace = "/DeviceGray"
+		}
+		imageID := addObject(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			page.Width, page.Height, colorSpace, len(page.JPEG)), page.JPEG)
+
+		content := pdfPageContent(width, height, page.Text)
+		contentID := addObject(fmt.Sprintf("<< /Length %d >>", len(content)), content)
+
+		pageID := addObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /XObject << /Im0 %d 0 R >> /Font << /F1 %d 0 R >> >> /Contents %d 0 R >>",
+			width, height, imageID, fontID, contentID), nil)
+		pageIDs = append(pageIDs, fmt.Sprintf("%d 0 R", pageID))
+	}
+
+	// Write the page tree now that the page objects are known
+	offsets[pagesOffset] = buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", pagesOffset+1, strings.Join(pageIDs, " "), len(pageIDs))
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalogID, xrefOffset)
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// pdfPageContent draws the page image and writes the text in invisible render mode (3 Tr) from the top of the page
+func pdfPageContent(width, height float64, text string) []byte {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "q %.2f 0 0 %.2f 0 0 cm /Im0 Do Q\n", width, height)
+	if strings.TrimSpace(text) != "" {
+		fmt.Fprintf(&content, "BT /F1 %d Tf 3 Tr %d TL 1 0 0 1 10 %.2f Tm\n", pdfTextFontSize, pdfTextFontSize+2, height-10-pdfTextFontSize)
+		for _, line := range strings.Split(text, "\n") {
+			fmt.Fprintf(&content, "(%s) Tj T*\n", pdfEscapeText(line))
+		}
+		content.WriteString("ET\n")
+	}
+	return content.Bytes()
+}
+
+// pdfEscapeText converts text to the WinAnsi encoding of the standard fonts and escapes it for a PDF string.
+// Characters that cannot be encoded are replaced by a question mark.
+func pdfEscapeText(text string) string {
+	var escaped strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteRune(r)
+		case r == '\t':
+			escaped.WriteByte(' ')
+		case r == '€':
+			escaped.WriteString(`\200`)
+		case r >= 0x20 && r < 0x7f:
+			escaped.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&escaped, `\%03o`, r)
+		case r < 0x20:
+			// Control characters are dropped
+		default:
+			escaped.WriteByte('?')
+		}
+	}
+	return escaped.String()
+}
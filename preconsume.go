@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gen2brain/go-fitz"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// preConsumePollInterval is the interval in which the pre-consume directory is checked for new files
+	preConsumePollInterval = 10 * time.Second
+	// preConsumeSettleTime is the time a file must not have been modified before it is processed,
+	// so files that are still being written by a scanner are not picked up
+	preConsumeSettleTime = 5 * time.Second
+	// preConsumeFailedDir is the subdirectory of the input directory that files are moved to if they cannot be processed
+	preConsumeFailedDir = "failed"
+)
+
+// preConsumeExtensions lists the file types that are processed in the pre-consume directory
+var preConsumeExtensions = []string{".pdf", ".jpg", ".jpeg", ".png", ".tif", ".tiff"}
+
+// unsafeFilenamePattern matches characters that are not allowed in file names on common file systems
+var unsafeFilenamePattern = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]+`)
+
+// StartPreConsumeWatcher watches PRECONSUME_INPUT_DIR and writes searchable PDFs to PRECONSUME_OUTPUT_DIR,
+// which should be the consumption directory of paperless-ngx
+func StartPreConsumeWatcher(ctx context.Context, app *App) {
+	go func() {
+		log.Infof("Watching %s for documents to OCR before they are consumed", preConsumeInputDir)
+		ticker := time.NewTicker(preConsumePollInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := app.processPreConsumeDirectory(ctx, time.Now()); err != nil {
+				log.Errorf("Error processing pre-consume directory: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				log.Infoln("Pre-consume watcher shutting down")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// processPreConsumeDirectory processes all files in the input directory that are ready and returns the number of processed files.
+// Files that fail are moved to the "failed" subdirectory so they are not retried endlessly.
+func (app *App) processPreConsumeDirectory(ctx context.Context, now time.Time) (int, error) {
+	entries, err := os.ReadDir(preConsumeInputDir)
+	if err != nil {
+		return 0, fmt.Errorf("error reading pre-consume directory: %w", err)
+	}
+
+	count := 0
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") ||
+			!slices.Contains(preConsumeExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || now.Sub(info.ModTime()) < preConsumeSettleTime {
+			continue
+		}
+
+		inputPath := filepath.Join(preConsumeInputDir, entry.Name())
+		fileLogger := log.WithField("file", entry.Name())
+		fileLogger.Info("Processing file from pre-consume directory")
+
+		if err := app.processPreConsumeFile(ctx, inputPath, fileLogger); err != nil {
+			fileLogger.Errorf("Pre-consume processing failed: %v", err)
+			failedDir := filepath.Join(preConsumeInputDir, preConsumeFailedDir)
+			if err := os.MkdirAll(failedDir, 0755); err != nil {
+				return count, fmt.Errorf("error creating directory for failed files: %w", err)
+			}
+			if err := os.Rename(inputPath, filepath.Join(failedDir, entry.Name())); err != nil {
+				return count, fmt.Errorf("error moving failed file %s: %w", entry.Name(), err)
+			}
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+// processPreConsumeFile OCRs a file, names it after the suggested title and moves it to the output directory.
+// PDFs that already have a text layer are moved unchanged.
+func (app *App) processPreConsumeFile(ctx context.Context, inputPath string, logger *logrus.Entry) error {
+	// The file is processed with the settings in effect when it is started
+	ctx = withSettings(ctx)
+	doc, err := fitz.New(inputPath)
+	if err != nil {
+		return fmt.Errorf("error opening file: %w", err)
+	}
+	defer doc.Close()
+
+	baseName := strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+
+	if strings.EqualFold(filepath.Ext(inputPath), ".pdf") && hasTextLayer(doc) {
+		logger.Info("PDF already has a text layer, moving it unchanged")
+		return moveToConsumeDir(inputPath, baseName+".pdf")
+	}
+
+	// Render the pages, they are OCRed and embedded in the new PDF
+	imageDir, err := os.MkdirTemp("", "preconsume-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(imageDir)
+
+	var imagePaths []string
+	var pages []pdfPage
+	settings := settingsFor(ctx).renderSettings()
+	for n := 0; n < doc.NumPage(); n++ {
+		img, dpi, err := renderPage(doc, n, settings)
+		if err != nil {
+			return fmt.Errorf("error rendering page %d: %w", n+1, err)
+		}
+		var jpegData bytes.Buffer
+		if err := jpeg.Encode(&jpegData, img, &jpeg.Options{Quality: settings.JPEGQuality}); err != nil {
+			return fmt.Errorf("error encoding page %d: %w", n+1, err)
+		}
+		imagePath := filepath.Join(imageDir, fmt.Sprintf("page%03d.jpg", n))
+		if err := os.WriteFile(imagePath, jpegData.Bytes(), 0644); err != nil {
+			return err
+		}
+		imagePaths = append(imagePaths, imagePath)
+		pages = append(pages, pdfPage{JPEG: jpegData.Bytes(), Width: img.Bounds().Dx(), Height: img.Bounds().Dy(), DPI: dpi, Gray: settings.ColorMode == pdfColorModeGray})
+	}
+
+	ocrResult, err := app.ocrPages(ctx, 0, imagePaths, settingsFor(ctx).LimitOCRPages, logger)
+	if err != nil {
+		return err
+	}
+	for i := range pages {
+		pages[i].Text = ocrResult.PageTexts[i]
+	}
+	if len(ocrResult.JunkPages) > 0 {
+		logger.Infof("Junk pages found during OCR: %v", ocrResult.JunkPages)
+	}
+
+	// paperless-ngx uses the file name as title
+	fileName := baseName
+	if strings.ToLower(settingsFor(ctx).AutoGenerateTitle) != "false" && strings.TrimSpace(ocrResult.Text) != "" {
+		title, err := app.getSuggestedTitle(ctx, ocrResult.Text, baseName, logger)
+		if err != nil {
+			logger.Warnf("Keeping file name, title generation failed: %v", err)
+		} else if title = sanitizeFilename(title); title != "" {
+			fileName = title
+		}
+	}
+
+	var pdf bytes.Buffer
+	if err := writeSearchablePDF(&pdf, pages); err != nil {
+		return fmt.Errorf("error writing PDF: %w", err)
+	}
+	if err := writeToConsumeDir(pdf.Bytes(), fileName+".pdf"); err != nil {
+		return err
+	}
+	return os.Remove(inputPath)
+}
+
+// hasTextLayer reports whether any page of the document contains text
+func hasTextLayer(doc *fitz.Document) bool {
+	for n := 0; n < doc.NumPage(); n++ {
+		if text, err := doc.Text(n); err == nil && strings.TrimSpace(text) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFilename turns a title into a file name that is valid on common file systems
+func sanitizeFilename(title string) string {
+	name := strings.TrimSpace(unsafeFilenamePattern.ReplaceAllString(title, " "))
+	name = strings.Join(strings.Fields(name), " ")
+	if runes := []rune(name); len(runes) > 128 {
+		name = strings.TrimSpace(string(runes[:128]))
+	}
+	return strings.Trim(name, ". ")
+}
+
+// availableOutputPath returns a path in the output directory that doesn't exist yet, adding a counter to the name if needed
+func availableOutputPath(fileName string) string {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+	outputPath := filepath.Join(preConsumeOutputDir, fileName)
+	for i := 2; ; i++ {
+		if _, err := os.Stat(outputPath); os.IsNotExist(err) {
+			return outputPath
+		}
+		outputPath = filepath.Join(preConsumeOutputDir, fmt.Sprintf("%s (%d)%s", base, i, ext))
+	}
+}
+
+// writeToConsumeDir writes the file under a temporary name first, so paperless-ngx never consumes a partial file
+func writeToConsumeDir(data []byte, fileName string) error {
+	outputPath := availableOutputPath(fileName)
+	tmpPath := outputPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("error writing to output directory: %w", err)
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("error writing to output directory: %w", err)
+	}
+	return nil
+}
+
+// moveToConsumeDir moves a file to the output directory, copying it if the directories are on different file systems
+func moveToConsumeDir(inputPath string, fileName string) error {
+	if err := os.Rename(inputPath, availableOutputPath(fileName)); err == nil {
+		return nil
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+	if err := writeToConsumeDir(data, fileName); err != nil {
+		return err
+	}
+	return os.Remove(inputPath)
+}
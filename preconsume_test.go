@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gen2brain/go-fitz"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPdfEscapeText(t *testing.T) {
+	assert.Equal(t, `Total \(net\): 12,50 \200`, pdfEscapeText("Total (net): 12,50 €"))
+	assert.Equal(t, `Gr\374\337e a\\b`, pdfEscapeText("Grüße a\\b"))
+	assert.Equal(t, `? ok`, pdfEscapeText("日 ok\r"))
+}
+
+func TestWriteSearchablePDF(t *testing.T) {
+	var pdf bytes.Buffer
+	require.NoError(t, writeSearchablePDF(&pdf, []pdfPage{
+		{JPEG: encodeTestPage(t, 0.1), Width: 200, Height: 300, Text: "Invoice 2024-001\nTotal: 123.45 EUR"},
+		{JPEG: encodeTestPage(t, 0), Width: 200, Height: 300},
+	}))
+
+	doc, err := fitz.NewFromMemory(pdf.Bytes())
+	require.NoError(t, err)
+	defer doc.Close()
+
+	assert.Equal(t, 2, doc.NumPage())
+	text, err := doc.Text(0)
+	require.NoError(t, err)
+	assert.Contains(t, text, "Invoice 2024-001")
+	assert.Contains(t, text, "Total: 123.45 EUR")
+	assert.True(t, hasTextLayer(doc))
+
+	assert.Error(t, writeSearchablePDF(&pdf, nil))
+}
+
+func TestSanitizeFilename(t *testing.T) {
+	assert.Equal(t, "Invoice ACME 03 2024", sanitizeFilename(" Invoice: ACME 03/2024 "))
+	assert.Equal(t, "Letter", sanitizeFilename("Letter..."))
+	assert.Equal(t, "", sanitizeFilename("???"))
+}
+
+func TestProcessPreConsumeDirectory(t *testing.T) {
+	originalInput, originalOutput := preConsumeInputDir, preConsumeOutputDir
+	originalMode := ocrJunkPageMode
+	defer func() {
+		preConsumeInputDir, preConsumeOutputDir = originalInput, originalOutput
+		ocrJunkPageMode = originalMode
+	}()
+	preConsumeInputDir = t.TempDir()
+	preConsumeOutputDir = t.TempDir()
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoGenerateTitle, settings.LimitOCRPages = "false", 5 })
+	ocrJunkPageMode = junkPageModeOff
+
+	// A scanned image without text
+	img := image.NewRGBA(image.Rect(0, 0, 200, 300))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.White}, image.Point{}, draw.Src)
+	var scan bytes.Buffer
+	require.NoError(t, png.Encode(&scan, img))
+	require.NoError(t, os.WriteFile(filepath.Join(preConsumeInputDir, "scan.png"), scan.Bytes(), 0644))
+
+	// A PDF that already has a text layer
+	var digital bytes.Buffer
+	require.NoError(t, writeSearchablePDF(&digital, []pdfPage{{JPEG: encodeTestPage(t, 0), Width: 200, Height: 300, Text: "Already searchable"}}))
+	require.NoError(t, os.WriteFile(filepath.Join(preConsumeInputDir, "digital.pdf"), digital.Bytes(), 0644))
+
+	// A broken PDF and a file type that is ignored
+	require.NoError(t, os.WriteFile(filepath.Join(preConsumeInputDir, "broken.pdf"), []byte("not a pdf"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(preConsumeInputDir, "notes.txt"), []byte("ignored"), 0644))
+
+	app := &App{ocrProvider: &sequenceOCRProvider{texts: []string{"Scanned letter"}}}
+
+	// Files that were just written are left alone
+	count, err := app.processPreConsumeDirectory(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = app.processPreConsumeDirectory(context.Background(), time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	doc, err := fitz.New(filepath.Join(preConsumeOutputDir, "scan.pdf"))
+	require.NoError(t, err)
+	text, err := doc.Text(0)
+	doc.Close()
+	require.NoError(t, err)
+	assert.Contains(t, text, "Scanned letter")
+
+	moved, err := os.ReadFile(filepath.Join(preConsumeOutputDir, "digital.pdf"))
+	require.NoError(t, err)
+	assert.Equal(t, digital.Bytes(), moved, "PDFs with a text layer must be moved unchanged")
+
+	assert.FileExists(t, filepath.Join(preConsumeInputDir, preConsumeFailedDir, "broken.pdf"))
+	assert.FileExists(t, filepath.Join(preConsumeInputDir, "notes.txt"))
+	assert.NoFileExists(t, filepath.Join(preConsumeInputDir, "scan.png"))
+	assert.NoFileExists(t, filepath.Join(preConsumeInputDir, "digital.pdf"))
+}
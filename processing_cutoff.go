@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// processingCutoff restricts the automatic pipelines to documents added after a date or to documents with an ID
+// above a document ID (PROCESS_DOCUMENTS_ADDED_AFTER), so historical documents are never touched
+type processingCutoff struct {
+	addedAfter time.Time
+	afterID    int
+}
+
+// parseProcessingCutoff parses a date like "2024-01-31", a timestamp like "2024-01-31T18:00:00+01:00" or a document ID
+func parseProcessingCutoff(text string) (*processingCutoff, error) {
+	if id, err := strconv.Atoi(text); err == nil {
+		if id < 0 {
+			return nil, fmt.Errorf("document ID %d must not be negative", id)
+		}
+		return &processingCutoff{afterID: id}, nil
+	}
+	for _, layout := range []string{"2006-01-02", time.RFC3339} {
+		if addedAfter, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+			return &processingCutoff{addedAfter: addedAfter}, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is neither a date like 2024-01-31 nor a document ID", text)
+}
+
+// query returns the filter query for documents after the cutoff. paperless-ngx can't filter by a range of
+// document IDs, so the newest documents are listed first instead and older ones are left out by includes.
+func (cutoff *processingCutoff) query() string {
+	switch {
+	case cutoff == nil:
+		return ""
+	case !cutoff.addedAfter.IsZero():
+		return "added__gt=" + cutoff.addedAfter.UTC().Format(time.RFC3339)
+	default:
+		return "ordering=-id"
+	}
+}
+
+// includes reports whether the document comes after the cutoff
+func (cutoff *processingCutoff) includes(document Document) bool {
+	return cutoff == nil || document.ID > cutoff.afterID
+}
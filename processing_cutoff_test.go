@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseProcessingCutoff(t *testing.T) {
+	byID, err := parseProcessingCutoff("1200")
+	require.NoError(t, err)
+	assert.Equal(t, "ordering=-id", byID.query())
+	assert.False(t, byID.includes(Document{ID: 1200}))
+	assert.True(t, byID.includes(Document{ID: 1201}))
+
+	byTimestamp, err := parseProcessingCutoff("2024-03-01T18:30:00+01:00")
+	require.NoError(t, err)
+	assert.Equal(t, "added__gt=2024-03-01T17:30:00Z", byTimestamp.query())
+	assert.True(t, byTimestamp.includes(Document{ID: 1}))
+
+	byDate, err := parseProcessingCutoff("2024-03-01")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.March, 1, 0, 0, 0, 0, time.Local), byDate.addedAfter)
+
+	var none *processingCutoff
+	assert.Empty(t, none.query())
+	assert.True(t, none.includes(Document{ID: 1}))
+
+	for _, invalid := range []string{"-5", "yesterday", "01.03.2024"} {
+		_, err := parseProcessingCutoff(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestGetDocumentsByTagWithPriority_Cutoff(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	originalCutoff := autoProcessingCutoff
+	defer func() { autoProcessingCutoff = originalCutoff }()
+	autoProcessingCutoff = &processingCutoff{afterID: 30}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	var requests int
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "-id", r.URL.Query().Get("ordering"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 50, "tags": []}, {"id": 40, "tags": []}, {"id": 10, "tags": []}], "next": "http://paperless/api/documents/?page=2"}`))
+	})
+
+	app := &App{Client: env.client}
+	documents, err := app.getDocumentsByTagWithPriority(context.Background(), "paperless-gpt-auto", 25)
+	require.NoError(t, err)
+	require.Len(t, documents, 2)
+	assert.Equal(t, 50, documents[0].ID)
+	assert.Equal(t, 40, documents[1].ID)
+	assert.Equal(t, 1, requests, "older documents on further pages are not fetched")
+}
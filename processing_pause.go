@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// processingPause halts the background processing and the job workers without restarting paperless-gpt,
+// e.g. during maintenance of paperless-ngx or an outage of the LLM provider
+type processingPause struct {
+	mu      sync.Mutex
+	since   time.Time     // Zero while processing isn't paused
+	resumed chan struct{} // Closed when processing is resumed
+}
+
+// backgroundPause is paused and resumed through /api/processing
+var backgroundPause = &processingPause{}
+
+// pause halts processing, it reports false if processing was already paused
+func (pause *processingPause) pause() bool {
+	pause.mu.Lock()
+	defer pause.mu.Unlock()
+	if !pause.since.IsZero() {
+		return false
+	}
+	pause.since = time.Now()
+	pause.resumed = make(chan struct{})
+	return true
+}
+
+// resume continues processing, it reports false if processing wasn't paused
+func (pause *processingPause) resume() bool {
+	pause.mu.Lock()
+	defer pause.mu.Unlock()
+	if pause.since.IsZero() {
+		return false
+	}
+	pause.since = time.Time{}
+	close(pause.resumed)
+	return true
+}
+
+// pausedSince returns when processing was paused, it is zero while processing isn't paused
+func (pause *processingPause) pausedSince() time.Time {
+	pause.mu.Lock()
+	defer pause.mu.Unlock()
+	return pause.since
+}
+
+// wait blocks while processing is paused, until it is resumed or the context is done
+func (pause *processingPause) wait(ctx context.Context) error {
+	pause.mu.Lock()
+	paused, resumed := !pause.since.IsZero(), pause.resumed
+	pause.mu.Unlock()
+	if !paused {
+		return nil
+	}
+
+	select {
+	case <-resumed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcessingPause_Wait(t *testing.T) {
+	pause := &processingPause{}
+	require.NoError(t, pause.wait(context.Background()), "not paused")
+
+	assert.True(t, pause.pause())
+	assert.False(t, pause.pause(), "already paused")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, pause.wait(ctx), context.DeadlineExceeded)
+
+	waited := make(chan error)
+	go func() { waited <- pause.wait(context.Background()) }()
+	assert.True(t, pause.resume())
+	select {
+	case err := <-waited:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("wait did not return after resuming")
+	}
+	assert.False(t, pause.resume(), "not paused")
+}
+
+func TestProcessingPauseHandlers(t *testing.T) {
+	defer backgroundPause.resume()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/processing/status", getProcessingStatusHandler)
+	router.POST("/api/processing/pause", pauseProcessingHandler)
+	router.POST("/api/processing/resume", resumeProcessingHandler)
+
+	request := func(method, url string) processingStatus {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		var status processingStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status
+	}
+
+	status := request("POST", "/api/processing/pause")
+	assert.True(t, status.Paused)
+	require.NotNil(t, status.PausedSince)
+
+	status = request("GET", "/api/processing/status")
+	assert.True(t, status.Paused)
+
+	// Documents wait while processing is paused
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	processed, _ := processDocumentsConcurrently(ctx, []Document{{ID: 1}}, func(document Document) (bool, error) {
+		return true, nil
+	})
+	assert.Zero(t, processed)
+
+	status = request("POST", "/api/processing/resume")
+	assert.False(t, status.Paused)
+	assert.Nil(t, status.PausedSince)
+}
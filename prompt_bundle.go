@@ -0,0 +1,248 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// promptsDir is the directory the prompt templates are read from and written to
+var promptsDir = "prompts"
+
+// promptBundleVersion is the version of the format of exported prompt bundles
+const promptBundleVersion = 1
+
+// maxPromptBundleSize is the maximum size of an imported prompt bundle
+const maxPromptBundleSize = 10 << 20
+
+// promptTemplate is a prompt template loaded from a file of the prompts directory
+type promptTemplate struct {
+	name           string
+	fileName       string
+	defaultContent string
+	target         **template.Template
+}
+
+// promptTemplates lists all prompt templates, in the order they are loaded
+var promptTemplates = []promptTemplate{
+	{"title", "title_prompt.tmpl", defaultTitleTemplate, &titleTemplate},
+	{"tag", "tag_prompt.tmpl", defaultTagTemplate, &tagTemplate},
+	{"correspondent", "correspondent_prompt.tmpl", defaultCorrespondentTemplate, &correspondentTemplate},
+	{"created_date", "created_date_prompt.tmpl", defaultCreatedDateTemplate, &createdDateTemplate},
+	{"ocr", "ocr_prompt.tmpl", defaultOcrPrompt, &ocrTemplate},
+	{"custom_fields", "custom_fields_prompt.tmpl", defaultCustomFieldsTemplate, &customFieldsTemplate},
+	{"language", "language_prompt.tmpl", defaultLanguageTemplate, &languageTemplate},
+	{"summary", "summary_prompt.tmpl", defaultSummaryTemplate, &summaryTemplate},
+	{"due_date", "due_date_prompt.tmpl", defaultDueDateTemplate, &dueDateTemplate},
+	{"invoice", "invoice_prompt.tmpl", defaultInvoiceTemplate, &invoiceTemplate},
+	{"amount", "amount_prompt.tmpl", defaultAmountTemplate, &amountTemplate},
+	{"contract", "contract_prompt.tmpl", defaultContractTemplate, &contractTemplate},
+	{"pii", "pii_prompt.tmpl", defaultPIITemplate, &piiTemplate},
+	{"sensitivity", "sensitivity_prompt.tmpl", defaultSensitivityTemplate, &sensitivityTemplate},
+	{"translation", "translation_prompt.tmpl", defaultTranslationTemplate, &translationTemplate},
+	{"triage", "triage_prompt.tmpl", defaultTriageTemplate, &triageTemplate},
+	{"correspondent_dedupe", "correspondent_dedupe_prompt.tmpl", defaultCorrespondentDedupeTemplate, &correspondentDedupeTemplate},
+	{"condense", "condense_prompt.tmpl", defaultCondenseTemplate, &condenseTemplate},
+	{"confidence", "confidence_prompt.tmpl", defaultConfidenceTemplate, &confidenceTemplate},
+}
+
+// PromptBundle holds the content of all prompt templates, keyed by their name
+type PromptBundle struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Prompts    map[string]string `json:"prompts"`
+}
+
+// findPromptTemplate returns the prompt template with the name or file name
+func findPromptTemplate(name string) (promptTemplate, bool) {
+	for _, prompt := range promptTemplates {
+		if prompt.name == name || prompt.fileName == name {
+			return prompt, true
+		}
+	}
+	return promptTemplate{}, false
+}
+
+// samplePromptData is the data the templates are rendered with to check them before they are imported.
+// It has a value of the right shape for every field any of the prompts is executed with.
+func samplePromptData() map[string]interface{} {
+	return map[string]interface{}{
+		"Language":                "English",
+		"Languages":               []string{"English", "German"},
+		"SourceLanguage":          "German",
+		"Title":                   "Invoice 2024-117",
+		"Content":                 "Invoice 2024-117\nACME Corp\nTotal: 119.00 EUR, due 2024-12-31",
+		"Today":                   getTodayDate(),
+		"AvailableTags":           []string{"Invoice", "Insurance"},
+		"OriginalTags":            []string{"Inbox"},
+		"AllowNewTags":            true,
+		"TagGroups":               []TagGroup{{Name: "Type", Tags: []string{"Invoice", "Contract"}, Mode: "single"}},
+		"AvailableCorrespondents": []string{"ACME Corp"},
+		"BlackList":               []string{"Unknown"},
+		"Restricted":              false,
+		"CustomFields":            []customFieldPrompt{{Name: "Contract number", DataType: "string"}, {Name: "Status", DataType: "select", Options: []string{"Open", "Paid"}}},
+		"MaxWords":                summaryMaxWords,
+		"Categories":              piiCategories,
+		"Levels":                  []string{"public", "internal", "confidential"},
+		"Actions":                 triageActions,
+		"Groups":                  [][]string{{"ACME Corp", "ACME Corporation"}},
+		"OriginalTitle":           "Scan 2024-05-01",
+		"OriginalCorrespondent":   "ACME Corp",
+		"DocumentType":            "Invoice",
+		"CustomFieldValues":       map[string]interface{}{"Contract number": "C-2024-42"},
+		"OriginalFileName":        "scan_2024-05-01.pdf",
+		"AddedDate":               "2024-05-01",
+		"PageCount":               2,
+		"OCRConfidence":           0.97,
+		"Examples":                []string{"ACME Corp Invoice 2024-04"},
+		"Part":                    1,
+		"Parts":                   2,
+		"Suggestions":             []ratedField{{"title", "ACME Corp Invoice 2024-117"}, {"tags", "Invoice"}},
+	}
+}
+
+// exportPrompts returns the bundle of the current content of all prompt templates
+func exportPrompts() (PromptBundle, error) {
+	bundle := PromptBundle{Version: promptBundleVersion, ExportedAt: time.Now().UTC(), Prompts: map[string]string{}}
+	for _, prompt := range promptTemplates {
+		content, err := os.ReadFile(filepath.Join(promptsDir, prompt.fileName))
+		if errors.Is(err, os.ErrNotExist) {
+			content = []byte(prompt.defaultContent)
+		} else if err != nil {
+			return PromptBundle{}, err
+		}
+		bundle.Prompts[prompt.name] = string(content)
+	}
+	return bundle, nil
+}
+
+// writePromptsZip writes the prompts of the bundle as the template files of the prompts directory to a zip archive
+func writePromptsZip(w io.Writer, bundle PromptBundle) error {
+	archive := zip.NewWriter(w)
+	for _, prompt := range promptTemplates {
+		content, ok := bundle.Prompts[prompt.name]
+		if !ok {
+			continue
+		}
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: prompt.fileName, Method: zip.Deflate, Modified: bundle.ExportedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// readPromptsZip reads the template files of a zip archive into a bundle. Directories inside the archive are ignored,
+// so archives of the whole prompts directory can be imported.
+func readPromptsZip(data []byte) (PromptBundle, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return PromptBundle{}, fmt.Errorf("invalid zip archive: %w", err)
+	}
+	bundle := PromptBundle{Version: promptBundleVersion, Prompts: map[string]string{}}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		prompt, ok := findPromptTemplate(path.Base(file.Name))
+		if !ok {
+			return PromptBundle{}, fmt.Errorf("unknown prompt file %q", file.Name)
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return PromptBundle{}, err
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return PromptBundle{}, err
+		}
+		bundle.Prompts[prompt.name] = string(content)
+	}
+	return bundle, nil
+}
+
+// PromptImportResult reports the prompts of an imported bundle. Previews holds the prompts rendered with sample data.
+type PromptImportResult struct {
+	DryRun   bool              `json:"dry_run"`
+	Imported []string          `json:"imported"`
+	Previews map[string]string `json:"previews,omitempty"`
+}
+
+// parsePromptBundle parses and renders every prompt of the bundle with sample data. The errors are keyed by the name of
+// the prompt they occurred in.
+func parsePromptBundle(bundle PromptBundle) (map[string]*template.Template, map[string]string, map[string]string) {
+	templates := map[string]*template.Template{}
+	previews := map[string]string{}
+	problems := map[string]string{}
+	if bundle.Version > promptBundleVersion {
+		problems["version"] = fmt.Sprintf("unsupported bundle version %d", bundle.Version)
+		return nil, nil, problems
+	}
+	if len(bundle.Prompts) == 0 {
+		problems["prompts"] = "the bundle has no prompts"
+		return nil, nil, problems
+	}
+
+	for name, content := range bundle.Prompts {
+		prompt, ok := findPromptTemplate(name)
+		if !ok || prompt.name != name {
+			problems[name] = "unknown prompt"
+			continue
+		}
+		if strings.TrimSpace(content) == "" {
+			problems[name] = "the prompt is empty"
+			continue
+		}
+		tmpl, err := template.New(name).Funcs(sprig.FuncMap()).Parse(content)
+		if err != nil {
+			problems[name] = err.Error()
+			continue
+		}
+		rendered, err := renderPrompt(tmpl, samplePromptData())
+		if err != nil {
+			problems[name] = err.Error()
+			continue
+		}
+		templates[name] = tmpl
+		previews[name] = rendered.text()
+	}
+	return templates, previews, problems
+}
+
+// importPrompts writes the parsed templates of the bundle to the prompts directory and uses them right away.
+// It returns the names of the imported prompts.
+func importPrompts(bundle PromptBundle, templates map[string]*template.Template) ([]string, error) {
+	templateMutex.Lock()
+	defer templateMutex.Unlock()
+
+	if err := os.MkdirAll(promptsDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	imported := []string{}
+	for _, prompt := range promptTemplates {
+		tmpl, ok := templates[prompt.name]
+		if !ok {
+			continue
+		}
+		if err := os.WriteFile(filepath.Join(promptsDir, prompt.fileName), []byte(bundle.Prompts[prompt.name]), 0644); err != nil {
+			return imported, fmt.Errorf("failed to write %s: %w", prompt.fileName, err)
+		}
+		*prompt.target = tmpl
+		imported = append(imported, prompt.name)
+	}
+	return imported, nil
+}
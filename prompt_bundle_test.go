@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPromptsRender(t *testing.T) {
+	bundle := PromptBundle{Version: promptBundleVersion, Prompts: map[string]string{}}
+	for _, prompt := range promptTemplates {
+		bundle.Prompts[prompt.name] = prompt.defaultContent
+	}
+	templates, previews, problems := parsePromptBundle(bundle)
+	assert.Empty(t, problems)
+	assert.Len(t, templates, len(promptTemplates))
+	assert.Contains(t, previews["title"], "Invoice 2024-117")
+}
+
+func TestPromptExportImport(t *testing.T) {
+	originalDir := promptsDir
+	originalTitle := titleTemplate
+	defer func() {
+		promptsDir = originalDir
+		titleTemplate = originalTitle
+	}()
+	promptsDir = t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "title_prompt.tmpl"), []byte("Title for {{.Content}}"), 0644))
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/prompts/export", exportPromptsHandler)
+	router.POST("/api/prompts/import", importPromptsHandler)
+	request := func(method, target string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, bytes.NewReader(body)))
+		return w
+	}
+
+	w := request("GET", "/api/prompts/export", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	var bundle PromptBundle
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &bundle))
+	assert.Equal(t, "Title for {{.Content}}", bundle.Prompts["title"])
+	assert.Equal(t, defaultTagTemplate, bundle.Prompts["tag"], "missing files are exported with the default content")
+	assert.Len(t, bundle.Prompts, len(promptTemplates))
+
+	// Zip archives hold the template files
+	w = request("GET", "/api/prompts/export?format=zip", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	zipped, err := readPromptsZip(w.Body.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, bundle.Prompts, zipped.Prompts)
+
+	// Templates failing to parse or render are rejected
+	body, _ := json.Marshal(PromptBundle{Version: 1, Prompts: map[string]string{"title": "{{.Content", "tag": "{{.Content.Missing}}", "other": "x"}})
+	w = request("POST", "/api/prompts/import", body)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var rejected struct {
+		Problems map[string]string `json:"problems"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &rejected))
+	assert.ElementsMatch(t, []string{"title", "tag", "other"}, slices.Collect(maps.Keys(rejected.Problems)))
+
+	// Dry runs render the prompts without writing them
+	body, _ = json.Marshal(PromptBundle{Version: 1, Prompts: map[string]string{"title": "New title of {{.Title}}"}})
+	w = request("POST", "/api/prompts/import?dry_run=true", body)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var result PromptImportResult
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "New title of Invoice 2024-117", result.Previews["title"])
+	content, _ := os.ReadFile(filepath.Join(promptsDir, "title_prompt.tmpl"))
+	assert.Equal(t, "Title for {{.Content}}", string(content))
+
+	// Imports write the prompts and use them right away
+	zipBundle := bytes.Buffer{}
+	require.NoError(t, writePromptsZip(&zipBundle, PromptBundle{Prompts: map[string]string{"title": "New title of {{.Title}}"}}))
+	w = request("POST", "/api/prompts/import", zipBundle.Bytes())
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, []string{"title"}, result.Imported)
+	content, _ = os.ReadFile(filepath.Join(promptsDir, "title_prompt.tmpl"))
+	assert.Equal(t, "New title of {{.Title}}", string(content))
+	var rendered bytes.Buffer
+	require.NoError(t, titleTemplate.Execute(&rendered, map[string]string{"Title": "Scan"}))
+	assert.Equal(t, "New title of Scan", rendered.String())
+}
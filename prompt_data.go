@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// promptDocument is the document being processed, with the variables describing it to the prompts
+type promptDocument struct {
+	doc  Document
+	data map[string]interface{}
+}
+
+type promptDocumentKey struct{}
+
+// withPromptDocument makes the document available to the following prompts: its variables are added to the prompts
+// and the prompts of the rules matching it replace the global ones
+func withPromptDocument(ctx context.Context, doc Document, customFields []CustomField, ocrConfidence float64) context.Context {
+	return context.WithValue(ctx, promptDocumentKey{}, promptDocument{doc: doc, data: documentPromptData(doc, customFields, ocrConfidence)})
+}
+
+// documentPromptData returns the variables describing a document to the prompts. customFields are the custom field
+// definitions to name the values of the document by, values of fields missing from them are left out.
+func documentPromptData(doc Document, customFields []CustomField, ocrConfidence float64) map[string]interface{} {
+	addedDate := doc.Added
+	if len(addedDate) > len("2006-01-02") {
+		addedDate = addedDate[:len("2006-01-02")]
+	}
+	tags := doc.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return map[string]interface{}{
+		"OriginalTitle":         doc.Title,
+		"OriginalCorrespondent": doc.Correspondent,
+		"OriginalTags":          tags,
+		"DocumentType":          doc.DocumentType,
+		"CustomFieldValues":     customFieldValuesByName(doc.CustomFields, customFields),
+		"OriginalFileName":      doc.OriginalFileName,
+		"AddedDate":             addedDate,
+		"PageCount":             doc.PageCount,
+		"OCRConfidence":         ocrConfidence,
+	}
+}
+
+// customFieldValuesByName maps the names of the custom fields to the values of the document, with the labels of the
+// options of select fields
+func customFieldValuesByName(values []CustomFieldValue, customFields []CustomField) map[string]interface{} {
+	valuesByName := make(map[string]interface{}, len(values))
+	for _, value := range values {
+		if value.Value == nil {
+			continue
+		}
+		for _, field := range customFields {
+			if field.ID != value.Field {
+				continue
+			}
+			valuesByName[field.Name] = value.Value
+			if field.DataType == "select" {
+				valuesByName[field.Name] = selectOptionLabel(field, value.Value)
+			}
+			break
+		}
+	}
+	return valuesByName
+}
+
+// selectOptionLabel returns the label of the option a select field is set to. Newer paperless-ngx versions store the
+// ID of the option, older ones its index.
+func selectOptionLabel(field CustomField, value interface{}) interface{} {
+	options := field.ExtraData.SelectOptions
+	for i, option := range options {
+		if (option.ID != "" && fmt.Sprint(value) == option.ID) || (option.ID == "" && value == float64(i)) {
+			return option.Label
+		}
+	}
+	return value
+}
+
+// addDocumentPromptData adds the variables of the document being processed to the data of a prompt, without
+// replacing the variables the prompt sets itself. The variables are empty without a document.
+func addDocumentPromptData(ctx context.Context, data map[string]interface{}) {
+	document, ok := ctx.Value(promptDocumentKey{}).(promptDocument)
+	if !ok {
+		document.data = documentPromptData(Document{}, nil, 0)
+	}
+	for name, value := range document.data {
+		if _, ok := data[name]; !ok {
+			data[name] = value
+		}
+	}
+}
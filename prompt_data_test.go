@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"paperless-gpt/ocr"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentPromptData(t *testing.T) {
+	customFields := []CustomField{
+		{ID: 1, Name: "Contract number", DataType: "string"},
+		{ID: 2, Name: "Status", DataType: "select", ExtraData: CustomFieldExtraData{SelectOptions: []CustomFieldSelectOption{{ID: "a1", Label: "Open"}, {ID: "b2", Label: "Paid"}}}},
+		{ID: 3, Name: "Priority", DataType: "select", ExtraData: CustomFieldExtraData{SelectOptions: []CustomFieldSelectOption{{Label: "Low"}, {Label: "High"}}}},
+	}
+	doc := Document{
+		Title:            "Scan 2024-05-01",
+		Correspondent:    "ACME Corp",
+		Tags:             []string{"Inbox"},
+		DocumentType:     "Invoice",
+		OriginalFileName: "scan.pdf",
+		Added:            "2024-05-01T10:00:00.123456+02:00",
+		PageCount:        3,
+		CustomFields: []CustomFieldValue{
+			{Field: 1, Value: "C-42"},
+			{Field: 2, Value: "b2"},
+			{Field: 3, Value: float64(1)},
+			{Field: 4, Value: "unknown field"},
+			{Field: 1, Value: nil},
+		},
+	}
+
+	data := documentPromptData(doc, customFields, 0.92)
+	assert.Equal(t, "Scan 2024-05-01", data["OriginalTitle"])
+	assert.Equal(t, "ACME Corp", data["OriginalCorrespondent"])
+	assert.Equal(t, []string{"Inbox"}, data["OriginalTags"])
+	assert.Equal(t, "Invoice", data["DocumentType"])
+	assert.Equal(t, "scan.pdf", data["OriginalFileName"])
+	assert.Equal(t, "2024-05-01", data["AddedDate"])
+	assert.Equal(t, 3, data["PageCount"])
+	assert.Equal(t, 0.92, data["OCRConfidence"])
+	assert.Equal(t, map[string]interface{}{"Contract number": "C-42", "Status": "Paid", "Priority": "High"}, data["CustomFieldValues"])
+}
+
+func TestDocumentPromptVariables(t *testing.T) {
+	originalTemplate := summaryTemplate
+	defer func() { summaryTemplate = originalTemplate }()
+	summaryTemplate = template.Must(template.New("summary").Parse(
+		`{{.Title}}|{{.OriginalTitle}}|{{.DocumentType}}|{{.CustomFieldValues}}|{{.PageCount}}|{{.OCRConfidence}}`))
+
+	llm := &mockJSONLLM{response: "A short summary."}
+	app := &App{LLM: llm}
+	logger := logrus.WithField("test", "prompt data")
+
+	doc := Document{Title: "Scan", DocumentType: "Contract", PageCount: 2, CustomFields: []CustomFieldValue{{Field: 1, Value: "C-42"}}}
+	ctx := withPromptDocument(context.Background(), doc, []CustomField{{ID: 1, Name: "Contract number", DataType: "string"}}, 0.5)
+	_, err := app.getSuggestedSummary(ctx, "content", "Lease", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "Lease|Scan|Contract|map[Contract number:C-42]|2|0.5", llm.lastPrompt, "variables of the prompt itself are kept")
+
+	_, err = app.getSuggestedSummary(context.Background(), "content", "Lease", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "Lease|||map[]|0|0", llm.lastPrompt, "empty without a document")
+}
+
+// confidenceOCRProvider reports the given confidences in order, one per processed page
+type confidenceOCRProvider struct {
+	confidences []string
+	calls       int
+}
+
+func (p *confidenceOCRProvider) ProcessImage(ctx context.Context, imageContent []byte) (*ocr.OCRResult, error) {
+	confidence := p.confidences[p.calls]
+	p.calls++
+	return &ocr.OCRResult{Text: "Page", Metadata: map[string]string{"confidence": confidence}}, nil
+}
+
+func TestOCRConfidence(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&OCRConfidence{}).Error)
+	defer db.Where("1 = 1").Delete(&OCRConfidence{})
+
+	dir := t.TempDir()
+	var imagePaths []string
+	for i := range 3 {
+		imagePath := filepath.Join(dir, fmt.Sprintf("page%03d.jpg", i))
+		require.NoError(t, os.WriteFile(imagePath, encodeTestPage(t, 0.1), 0644))
+		imagePaths = append(imagePaths, imagePath)
+	}
+	app := &App{Database: db, ocrProvider: &confidenceOCRProvider{confidences: []string{"0.9", "", "0.7"}}}
+	result, err := app.ocrPages(context.Background(), 1, imagePaths, 0, logrus.WithField("test", "confidence"))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.8, result.Confidence, 0.0001, "pages without confidence are left out")
+
+	assert.Equal(t, 0.0, app.ocrConfidence(1))
+	require.NoError(t, SaveOCRConfidence(db, 1, 0.5))
+	require.NoError(t, SaveOCRConfidence(db, 1, result.Confidence))
+	assert.InDelta(t, 0.8, app.ocrConfidence(1), 0.0001, "the last OCR replaces earlier ones")
+	var count int64
+	require.NoError(t, db.Model(&OCRConfidence{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gorm.io/gorm"
+)
+
+// Variants of a prompt experiment: a is the prompt documents get without the experiment, b the variant being tested
+const (
+	promptVariantA = "a"
+	promptVariantB = "b"
+)
+
+// PromptExperiments compares variants of prompts by the feedback on their suggestions, loaded from
+// PROMPT_EXPERIMENTS_FILE
+type PromptExperiments struct {
+	Experiments []PromptExperiment `json:"experiments"`
+}
+
+// PromptExperiment splits the documents between the prompt they get otherwise and a variant, a template file of the
+// prompts directory. Share is the percentage of documents getting the variant, 50 if it is not set.
+type PromptExperiment struct {
+	Name    string `json:"name"`
+	Prompt  string `json:"prompt"`
+	Variant string `json:"variant"`
+	Share   *int   `json:"share,omitempty"`
+
+	template *template.Template
+}
+
+// loadPromptExperiments reads the experiments from a JSON file and parses the templates of their variants from the
+// prompts directory. Only prompts the feedback is given on can be compared.
+func loadPromptExperiments(path string) (*PromptExperiments, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prompt experiments: %v", err)
+	}
+
+	var experiments PromptExperiments
+	if err := json.Unmarshal(data, &experiments); err != nil {
+		return nil, fmt.Errorf("error parsing prompt experiments: %v", err)
+	}
+
+	names := map[string]bool{}
+	prompts := map[string]string{}
+	for i := range experiments.Experiments {
+		experiment := &experiments.Experiments[i]
+		experiment.Name = strings.TrimSpace(experiment.Name)
+		if experiment.Name == "" || len(experiment.Name) > 64 {
+			return nil, fmt.Errorf("prompt experiment %d needs a name of up to 64 characters", i+1)
+		}
+		if names[experiment.Name] {
+			return nil, fmt.Errorf("prompt experiment %s is defined twice", experiment.Name)
+		}
+		names[experiment.Name] = true
+		if !slices.Contains(feedbackFields, experiment.Prompt) {
+			return nil, fmt.Errorf("prompt experiment %s: prompt must be one of %s, got: %s", experiment.Name, strings.Join(feedbackFields, ", "), experiment.Prompt)
+		}
+		if other, ok := prompts[experiment.Prompt]; ok {
+			return nil, fmt.Errorf("prompt experiment %s: the %s prompt is already compared by %s", experiment.Name, experiment.Prompt, other)
+		}
+		prompts[experiment.Prompt] = experiment.Name
+		if experiment.Share == nil {
+			share := 50
+			experiment.Share = &share
+		} else if *experiment.Share < 0 || *experiment.Share > 100 {
+			return nil, fmt.Errorf("prompt experiment %s: share must be a percentage, got: %d", experiment.Name, *experiment.Share)
+		}
+
+		tmpl, err := loadRuleTemplate(experiment.Prompt, experiment.Variant)
+		if err != nil {
+			return nil, fmt.Errorf("prompt experiment %s: %v", experiment.Name, err)
+		}
+		experiment.template = tmpl
+	}
+	return &experiments, nil
+}
+
+// variantOf returns the variant the experiment assigns to a document. The assignment only depends on the name of the
+// experiment and the document, so a document gets the same variant every time its suggestions are generated.
+func (experiment PromptExperiment) variantOf(documentID uint) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(experiment.Name + "/" + strconv.FormatUint(uint64(documentID), 10)))
+	if int(hash.Sum32()%100) < *experiment.Share {
+		return promptVariantB
+	}
+	return promptVariantA
+}
+
+// experimentVariant returns the experiment comparing a prompt and the variant of the document of the context, nil
+// and an empty variant for prompts without experiment and requests for no document
+func experimentVariant(ctx context.Context, prompt string) (*PromptExperiment, string) {
+	documentID := llmDocument(ctx)
+	if promptExperiments == nil || documentID == 0 {
+		return nil, ""
+	}
+	for i, experiment := range promptExperiments.Experiments {
+		if experiment.Prompt == prompt {
+			return &promptExperiments.Experiments[i], experiment.variantOf(documentID)
+		}
+	}
+	return nil, ""
+}
+
+// experimentTemplates returns the templates of the variants of all experiments
+func (experiments *PromptExperiments) experimentTemplates() []*template.Template {
+	if experiments == nil {
+		return nil
+	}
+	templates := make([]*template.Template, 0, len(experiments.Experiments))
+	for _, experiment := range experiments.Experiments {
+		templates = append(templates, experiment.template)
+	}
+	return templates
+}
+
+// attributeFeedback assigns the feedback to the experiment and variant of the last request generating the suggestion
+// of the field for the document
+func attributeFeedback(db *gorm.DB, records []SuggestionFeedback) error {
+	for i := range records {
+		var usage LLMUsage
+		err := db.Where("document_id = ? AND task = ?", records[i].DocumentID, records[i].Field).
+			Order("id DESC").Limit(1).Find(&usage).Error
+		if err != nil {
+			return err
+		}
+		records[i].Experiment = usage.Experiment
+		records[i].Variant = usage.Variant
+	}
+	return nil
+}
+
+// VariantResults are the requests made with a variant of a prompt experiment and the feedback on their suggestions
+type VariantResults struct {
+	Variant        string  `json:"variant"`
+	Requests       int64   `json:"requests"`
+	Documents      int64   `json:"documents"`
+	Accepted       int64   `json:"accepted"`
+	Edited         int64   `json:"edited"`
+	Rejected       int64   `json:"rejected"`
+	AcceptanceRate float64 `json:"acceptance_rate"` // Share of the feedback accepting the suggestion as is
+}
+
+// ExperimentResults compares the variants of a prompt experiment, returned by GET /api/experiments. Experiments no
+// longer in PROMPT_EXPERIMENTS_FILE are listed with their results as inactive.
+type ExperimentResults struct {
+	Name     string           `json:"name"`
+	Prompt   string           `json:"prompt"`
+	Variant  string           `json:"variant,omitempty"` // Template file of variant b
+	Share    int              `json:"share,omitempty"`
+	Active   bool             `json:"active"`
+	Variants []VariantResults `json:"variants"`
+}
+
+// collectExperimentResults aggregates the requests and the feedback of the configured and past experiments by variant
+func collectExperimentResults(db *gorm.DB) ([]ExperimentResults, error) {
+	var requests []struct {
+		Experiment string
+		Task       string
+		Variant    string
+		Requests   int64
+		Documents  int64
+	}
+	err := db.Model(&LLMUsage{}).
+		Select("experiment, task, variant, COUNT(*) AS requests, COUNT(DISTINCT document_id) AS documents").
+		Where("experiment <> ''").
+		Group("experiment, task, variant").
+		Scan(&requests).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var feedback []struct {
+		Experiment string
+		Variant    string
+		Outcome    string
+		Count      int64
+	}
+	err = db.Model(&SuggestionFeedback{}).
+		Select("experiment, variant, outcome, COUNT(*) AS count").
+		Where("experiment <> ''").
+		Group("experiment, variant, outcome").
+		Scan(&feedback).Error
+	if err != nil {
+		return nil, err
+	}
+
+	results := []ExperimentResults{}
+	find := func(name string) *ExperimentResults {
+		for i := range results {
+			if results[i].Name == name {
+				return &results[i]
+			}
+		}
+		results = append(results, ExperimentResults{Name: name, Variants: []VariantResults{{Variant: promptVariantA}, {Variant: promptVariantB}}})
+		return &results[len(results)-1]
+	}
+	variant := func(experiment *ExperimentResults, name string) *VariantResults {
+		if name == promptVariantB {
+			return &experiment.Variants[1]
+		}
+		return &experiment.Variants[0]
+	}
+
+	if promptExperiments != nil {
+		for _, experiment := range promptExperiments.Experiments {
+			result := find(experiment.Name)
+			result.Prompt = experiment.Prompt
+			result.Variant = experiment.Variant
+			result.Share = *experiment.Share
+			result.Active = true
+		}
+	}
+	for _, row := range requests {
+		result := find(row.Experiment)
+		if result.Prompt == "" {
+			result.Prompt = row.Task
+		}
+		variantResults := variant(result, row.Variant)
+		variantResults.Requests += row.Requests
+		variantResults.Documents += row.Documents
+	}
+	for _, row := range feedback {
+		variantResults := variant(find(row.Experiment), row.Variant)
+		switch row.Outcome {
+		case FeedbackAccepted:
+			variantResults.Accepted += row.Count
+		case FeedbackEdited:
+			variantResults.Edited += row.Count
+		case FeedbackRejected:
+			variantResults.Rejected += row.Count
+		}
+	}
+	for i := range results {
+		for j := range results[i].Variants {
+			variantResults := &results[i].Variants[j]
+			if total := variantResults.Accepted + variantResults.Edited + variantResults.Rejected; total > 0 {
+				variantResults.AcceptanceRate = float64(variantResults.Accepted) / float64(total)
+			}
+		}
+	}
+	return results, nil
+}
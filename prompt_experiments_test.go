@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePromptExperiments(t *testing.T, experiments string) string {
+	path := filepath.Join(t.TempDir(), "prompt_experiments.json")
+	require.NoError(t, os.WriteFile(path, []byte(experiments), 0644))
+	return path
+}
+
+func TestLoadPromptExperiments(t *testing.T) {
+	usePromptsDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "short_title.tmpl"), []byte("Short title for {{.Content}}"), 0644))
+
+	experiments, err := loadPromptExperiments(writePromptExperiments(t, `{"experiments": [{"name": "short-titles", "prompt": "title", "variant": "short_title.tmpl"}]}`))
+	require.NoError(t, err)
+	require.Len(t, experiments.Experiments, 1)
+	assert.Equal(t, 50, *experiments.Experiments[0].Share)
+	assert.Len(t, experiments.experimentTemplates(), 1)
+
+	for experiments, message := range map[string]string{
+		`{"experiments": [{"prompt": "title", "variant": "short_title.tmpl"}]}`:                                                                                       "needs a name",
+		`{"experiments": [{"name": "short", "prompt": "summary", "variant": "short_title.tmpl"}]}`:                                                                    "prompt must be one of",
+		`{"experiments": [{"name": "short", "prompt": "title", "variant": "short_title.tmpl", "share": 120}]}`:                                                        "share must be a percentage",
+		`{"experiments": [{"name": "short", "prompt": "title", "variant": "missing.tmpl"}]}`:                                                                          "error reading missing.tmpl",
+		`{"experiments": [{"name": "short", "prompt": "title", "variant": "short_title.tmpl"}, {"name": "short", "prompt": "tag", "variant": "short_title.tmpl"}]}`:   "defined twice",
+		`{"experiments": [{"name": "short", "prompt": "title", "variant": "short_title.tmpl"}, {"name": "other", "prompt": "title", "variant": "short_title.tmpl"}]}`: "already compared by short",
+	} {
+		_, err := loadPromptExperiments(writePromptExperiments(t, experiments))
+		assert.ErrorContains(t, err, message, experiments)
+	}
+}
+
+func TestPromptExperimentVariants(t *testing.T) {
+	share := 30
+	experiment := PromptExperiment{Name: "short-titles", Prompt: "title", Share: &share}
+	variantB := 0
+	for documentID := uint(1); documentID <= 1000; documentID++ {
+		variant := experiment.variantOf(documentID)
+		assert.Equal(t, variant, experiment.variantOf(documentID), "a document always gets the same variant")
+		if variant == promptVariantB {
+			variantB++
+		}
+	}
+	assert.InDelta(t, 300, variantB, 60)
+
+	share = 0
+	assert.Equal(t, promptVariantA, experiment.variantOf(1))
+	share = 100
+	assert.Equal(t, promptVariantB, experiment.variantOf(1))
+}
+
+func TestPromptExperimentResults(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	require.NoError(t, db.Where("1 = 1").Delete(&LLMUsage{}).Error)
+	require.NoError(t, db.Where("1 = 1").Delete(&SuggestionFeedback{}).Error)
+	defer db.Where("1 = 1").Delete(&LLMUsage{})
+	defer db.Where("1 = 1").Delete(&SuggestionFeedback{})
+	usePromptsDir(t)
+	originalExperiments := promptExperiments
+	defer func() { promptExperiments = originalExperiments }()
+	titleTemplate = template.Must(template.New("title").Parse("Title for {{.Content}}"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "short_title.tmpl"), []byte("Short title for {{.Content}}"), 0644))
+	promptExperiments, err = loadPromptExperiments(writePromptExperiments(t, `{"experiments": [{"name": "short-titles", "prompt": "title", "variant": "short_title.tmpl"}]}`))
+	require.NoError(t, err)
+
+	// Documents getting each variant
+	documents := map[string]int{}
+	for documentID := 1; len(documents) < 2; documentID++ {
+		documents[promptExperiments.Experiments[0].variantOf(uint(documentID))] = documentID
+	}
+
+	llm := &mockJSONLLM{response: "Invoice"}
+	app := &App{LLM: recordLLMUsage(llm, db, "", ""), Database: db}
+	logger := logrus.WithField("test", "experiments")
+	for variant, documentID := range documents {
+		_, err := app.getSuggestedTitle(withLLMDocument(context.Background(), documentID), "content", "scan.pdf", logger)
+		require.NoError(t, err)
+		if variant == promptVariantB {
+			assert.Equal(t, "Short title for content", llm.lastPrompt)
+		} else {
+			assert.Equal(t, "Title for content", llm.lastPrompt)
+		}
+	}
+	_, err = app.getSuggestedTitle(context.Background(), "content", "scan.pdf", logger)
+	require.NoError(t, err)
+	assert.Equal(t, "Title for content", llm.lastPrompt, "no experiment without a document")
+
+	// The experiment ends before the feedback is given
+	promptExperiments = nil
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/feedback", app.feedbackHandler)
+	router.GET("/api/experiments", app.getExperimentsHandler)
+	for variant, outcome := range map[string]string{promptVariantA: FeedbackRejected, promptVariantB: FeedbackAccepted} {
+		body := `{"document_id": ` + strconv.Itoa(documents[variant]) + `, "fields": [{"field": "title", "outcome": "` + outcome + `", "suggested": "Invoice"}]}`
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/feedback", strings.NewReader(body)))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	results, err := collectExperimentResults(db)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "short-titles", results[0].Name)
+	assert.Equal(t, "title", results[0].Prompt)
+	assert.False(t, results[0].Active)
+	assert.Equal(t, VariantResults{Variant: promptVariantA, Requests: 1, Documents: 1, Rejected: 1}, results[0].Variants[0])
+	assert.Equal(t, VariantResults{Variant: promptVariantB, Requests: 1, Documents: 1, Accepted: 1, AcceptanceRate: 1}, results[0].Variants[1])
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/experiments", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"acceptance_rate":1`)
+}
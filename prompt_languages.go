@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+)
+
+type promptLanguageKey struct{}
+
+// withPromptLanguage selects the templates of prompts/<code>/ for the following prompts of the document
+func withPromptLanguage(ctx context.Context, code string) context.Context {
+	return context.WithValue(ctx, promptLanguageKey{}, code)
+}
+
+// languagePrompts are the prompts that can have templates by language. The language prompt detects the language the
+// others are selected by, so it has none.
+func languagePrompts() []string {
+	return slices.DeleteFunc(slices.Clone(perDocumentPrompts), func(name string) bool { return name == "language" })
+}
+
+// loadLanguageTemplates parses the templates of the language directories of the prompts directory, directories named
+// by an ISO 639-1 code like de or fr holding templates with the file names of the global ones. It returns the templates
+// by language code and prompt name.
+func loadLanguageTemplates() (map[string]map[string]*template.Template, error) {
+	entries, err := os.ReadDir(promptsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	languageTemplates := map[string]map[string]*template.Template{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !languageCodePattern.MatchString(entry.Name()) {
+			continue
+		}
+		code := entry.Name()
+		for _, prompt := range promptTemplates {
+			if !slices.Contains(languagePrompts(), prompt.name) {
+				continue
+			}
+			tmpl, err := parsePromptFile(prompt.name, filepath.Join(code, prompt.fileName))
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+			if languageTemplates[code] == nil {
+				languageTemplates[code] = map[string]*template.Template{}
+			}
+			languageTemplates[code][prompt.name] = tmpl
+		}
+	}
+	return languageTemplates, nil
+}
+
+// promptLanguageTemplate returns the template of a prompt for the language of the document being processed, nil if the
+// language was not detected or has no template for the prompt
+func promptLanguageTemplate(ctx context.Context, name string) *template.Template {
+	code, ok := ctx.Value(promptLanguageKey{}).(string)
+	if !ok {
+		return nil
+	}
+	return languagePromptTemplates[code][name]
+}
+
+// languageTemplateFiles returns the template files of the languages and their templates, ordered by language and
+// prompt
+func languageTemplateFiles(languageTemplates map[string]map[string]*template.Template) ([]string, []*template.Template) {
+	codes := slices.Sorted(maps.Keys(languageTemplates))
+	var files []string
+	var templates []*template.Template
+	for _, code := range codes {
+		for _, prompt := range promptTemplates {
+			if tmpl, ok := languageTemplates[code][prompt.name]; ok {
+				files = append(files, filepath.Join(code, prompt.fileName))
+				templates = append(templates, tmpl)
+			}
+		}
+	}
+	return files, templates
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLanguageTemplates(t *testing.T) {
+	usePromptsDir(t)
+	for fileName, content := range map[string]string{
+		"title_prompt.tmpl":        "global",
+		"de/title_prompt.tmpl":     "Titel für {{.Content}}",
+		"de/language_prompt.tmpl":  "Sprache",
+		"de/notes.txt":             "not a template",
+		"fr/tag_prompt.tmpl":       "Étiquettes",
+		"drafts/title_prompt.tmpl": "not a language",
+	} {
+		path := filepath.Join(promptsDir, fileName)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	languageTemplates, err := loadLanguageTemplates()
+	require.NoError(t, err)
+	files, templates := languageTemplateFiles(languageTemplates)
+	assert.Equal(t, []string{"de/title_prompt.tmpl", "fr/tag_prompt.tmpl"}, files, "the language prompt has no templates by language")
+	assert.Len(t, templates, 2)
+
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "fr", "title_prompt.tmpl"), []byte("{{.Content"), 0644))
+	_, err = loadLanguageTemplates()
+	assert.ErrorContains(t, err, "error parsing fr/title_prompt.tmpl")
+}
+
+func TestPromptForLanguage(t *testing.T) {
+	usePromptsDir(t)
+	originalTemplates, originalRules := languagePromptTemplates, promptRules
+	defer func() { languagePromptTemplates, promptRules = originalTemplates, originalRules }()
+	for fileName, content := range map[string]string{"de/title_prompt.tmpl": "German", "medical_title.tmpl": "medical"} {
+		path := filepath.Join(promptsDir, fileName)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	var err error
+	languagePromptTemplates, err = loadLanguageTemplates()
+	require.NoError(t, err)
+	promptRules, err = loadPromptRules(writePromptRules(t, `{"rules": [{"tags": ["Medical"], "prompts": {"title": "medical_title.tmpl"}}]}`))
+	require.NoError(t, err)
+
+	global := template.Must(template.New("title").Parse("global"))
+	render := func(ctx context.Context, name string) string {
+		var rendered bytes.Buffer
+		require.NoError(t, promptFor(ctx, name, global).Execute(&rendered, nil))
+		return rendered.String()
+	}
+
+	german := withPromptLanguage(context.Background(), "de")
+	assert.Equal(t, "German", render(german, "title"))
+	assert.Equal(t, "global", render(german, "tag"), "no template for the prompt")
+	assert.Equal(t, "global", render(withPromptLanguage(context.Background(), "fr"), "title"))
+	assert.Equal(t, "global", render(context.Background(), "title"), "language not detected")
+	assert.Equal(t, "medical", render(withPromptDocument(german, Document{Tags: []string{"Medical"}}, nil, 0), "title"), "rules take precedence")
+}
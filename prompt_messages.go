@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Names of the templates a prompt template can define for the messages besides the user message
+const (
+	systemMessageTemplate    = "system"
+	assistantMessageTemplate = "assistant"
+)
+
+// renderedPrompt is a prompt template rendered into the messages sent to the LLM
+type renderedPrompt struct {
+	System  string // Instructions sent in the system role, empty without {{define "system"}}
+	User    string // The template itself
+	Prefill string // Start of the answer sent in the assistant role, empty without {{define "assistant"}}
+}
+
+// renderPrompt renders a prompt template with the data. The template is the user message, a {{define "system"}} block
+// in it adds a system message and a {{define "assistant"}} block the start of the answer for the model to continue.
+// The messages of templates with such blocks are trimmed, the blank lines around the blocks would be sent otherwise.
+func renderPrompt(tmpl *template.Template, data map[string]interface{}) (renderedPrompt, error) {
+	var prompt renderedPrompt
+	var user bytes.Buffer
+	if err := tmpl.Execute(&user, data); err != nil {
+		return prompt, err
+	}
+	prompt.User = user.String()
+
+	multiMessage := false
+	for name, message := range map[string]*string{systemMessageTemplate: &prompt.System, assistantMessageTemplate: &prompt.Prefill} {
+		messageTemplate := tmpl.Lookup(name)
+		if messageTemplate == nil {
+			continue
+		}
+		multiMessage = true
+		var rendered bytes.Buffer
+		if err := messageTemplate.Execute(&rendered, data); err != nil {
+			return prompt, err
+		}
+		*message = strings.TrimSpace(rendered.String())
+	}
+	if multiMessage {
+		prompt.User = strings.TrimSpace(prompt.User)
+	}
+	return prompt, nil
+}
+
+// messages returns the messages of the prompt in the order they are sent
+func (prompt renderedPrompt) messages() []llms.MessageContent {
+	var messages []llms.MessageContent
+	if prompt.System != "" {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeSystem, prompt.System))
+	}
+	messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, prompt.User))
+	if prompt.Prefill != "" {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeAI, prompt.Prefill))
+	}
+	return messages
+}
+
+// text returns all messages of the prompt separated by blank lines, to count their tokens and show them
+func (prompt renderedPrompt) text() string {
+	var messages []string
+	for _, message := range []string{prompt.System, prompt.User, prompt.Prefill} {
+		if message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return strings.Join(messages, "\n\n")
+}
+
+// complete returns the answer of the model with the prefill it continues. Models that don't support prefills answer
+// from the start instead, their answers are returned as they are.
+func (prompt renderedPrompt) complete(answer string) string {
+	if prompt.Prefill == "" || strings.HasPrefix(strings.TrimSpace(answer), prompt.Prefill) {
+		return answer
+	}
+	return prompt.Prefill + answer
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+func TestRenderPrompt(t *testing.T) {
+	data := map[string]interface{}{"Language": "German", "Content": "Rechnung"}
+
+	single := template.Must(template.New("title").Parse("Title in {{.Language}} for:\n{{.Content}}\n"))
+	prompt, err := renderPrompt(single, data)
+	require.NoError(t, err)
+	assert.Equal(t, renderedPrompt{User: "Title in German for:\nRechnung\n"}, prompt, "templates without blocks are sent as they are")
+	assert.Equal(t, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Title in German for:\nRechnung\n")}, prompt.messages())
+
+	multi := template.Must(template.New("title").Funcs(sprig.FuncMap()).Parse(`{{define "system"}}
+You name documents in {{.Language}}. Answer with the title only.
+{{end}}
+{{define "assistant"}}Title: {{end}}
+Content:
+{{.Content | upper}}
+`))
+	prompt, err = renderPrompt(multi, data)
+	require.NoError(t, err)
+	assert.Equal(t, renderedPrompt{System: "You name documents in German. Answer with the title only.", User: "Content:\nRECHNUNG", Prefill: "Title:"}, prompt)
+	assert.Equal(t, []llms.MessageContent{
+		llms.TextParts(llms.ChatMessageTypeSystem, "You name documents in German. Answer with the title only."),
+		llms.TextParts(llms.ChatMessageTypeHuman, "Content:\nRECHNUNG"),
+		llms.TextParts(llms.ChatMessageTypeAI, "Title:"),
+	}, prompt.messages())
+	assert.Equal(t, "You name documents in German. Answer with the title only.\n\nContent:\nRECHNUNG\n\nTitle:", prompt.text())
+
+	assert.Equal(t, "Title: Invoice", prompt.complete(" Invoice"), "the model continues the prefill")
+	assert.Equal(t, "Title: Invoice", prompt.complete("Title: Invoice"), "the model ignores the prefill")
+
+	broken := template.Must(template.New("title").Parse(`{{define "system"}}{{.Missing.Field}}{{end}}{{.Content}}`))
+	_, err = renderPrompt(broken, map[string]interface{}{"Content": "x", "Missing": nil})
+	assert.Error(t, err)
+}
+
+func TestMultiMessagePrompt(t *testing.T) {
+	usePromptsDir(t)
+	titleTemplate = template.Must(template.New("title").Parse(`{{define "system"}}Answer with a quoted title.{{end}}
+{{define "assistant"}}"{{end}}
+Title for {{.Content}}`))
+
+	llm := &mockJSONLLM{response: `Invoice ACME"`}
+	app := &App{LLM: llm}
+	title, err := app.getSuggestedTitle(context.Background(), "content", "scan.pdf", logrus.WithField("test", "messages"))
+	require.NoError(t, err)
+	assert.Equal(t, "Invoice ACME", title)
+	assert.Equal(t, "Title for content", llm.lastPrompt)
+	require.Len(t, llm.lastMessages, 3)
+	assert.Equal(t, llms.ChatMessageTypeSystem, llm.lastMessages[0].Role)
+	assert.Equal(t, llms.ChatMessageTypeAI, llm.lastMessages[2].Role)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// promptPresetFiles holds the templates of the built-in presets, prompt_presets/<preset>/<template file>
+//
+//go:embed prompt_presets
+var promptPresetFiles embed.FS
+
+// PromptPreset is a built-in set of prompt templates for a style of documents
+type PromptPreset struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Prompts     []string `json:"prompts"` // Prompts the preset has templates for
+}
+
+// promptPresets lists the built-in presets, their prompts are read from promptPresetFiles
+var promptPresets = []PromptPreset{
+	{Name: "strict-german-bureaucracy", Description: "German titles, tags and correspondents in the terms of German authorities, e.g. \"Bescheid Finanzamt München Einkommensteuer 2023\""},
+	{Name: "minimal-tags", Description: "At most three tags per document, only the ones clearly describing it"},
+	{Name: "verbose-titles", Description: "Descriptive titles naming the kind of document, sender, subject, date and reference numbers"},
+	{Name: "invoice-focused", Description: "Titles, tags and correspondents for archives of mostly invoices, and a stricter invoice extraction"},
+}
+
+// availablePromptPresets returns the built-in presets with the prompts they have templates for
+func availablePromptPresets() []PromptPreset {
+	presets := make([]PromptPreset, 0, len(promptPresets))
+	for _, preset := range promptPresets {
+		preset.Prompts = []string{}
+		for _, prompt := range promptTemplates {
+			if _, err := promptPresetFiles.ReadFile(path.Join("prompt_presets", preset.Name, prompt.fileName)); err == nil {
+				preset.Prompts = append(preset.Prompts, prompt.name)
+			}
+		}
+		presets = append(presets, preset)
+	}
+	return presets
+}
+
+// findPromptPreset returns the built-in preset with the name
+func findPromptPreset(name string) (PromptPreset, bool) {
+	for _, preset := range availablePromptPresets() {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return PromptPreset{}, false
+}
+
+// presetBundle returns the templates of a preset as a prompt bundle, all of them or the ones of the prompts
+func presetBundle(name string, prompts []string) (PromptBundle, error) {
+	preset, ok := findPromptPreset(name)
+	if !ok {
+		return PromptBundle{}, fmt.Errorf("unknown prompt preset %s", name)
+	}
+	if len(prompts) == 0 {
+		prompts = preset.Prompts
+	}
+
+	bundle := PromptBundle{Version: promptBundleVersion, Prompts: map[string]string{}}
+	for _, name := range prompts {
+		prompt, ok := findPromptTemplate(name)
+		if !ok || !slices.Contains(preset.Prompts, prompt.name) {
+			return PromptBundle{}, fmt.Errorf("prompt preset %s has no %s prompt, it has: %s", preset.Name, name, strings.Join(preset.Prompts, ", "))
+		}
+		content, err := promptPresetFiles.ReadFile(path.Join("prompt_presets", preset.Name, prompt.fileName))
+		if err != nil {
+			return PromptBundle{}, err
+		}
+		bundle.Prompts[prompt.name] = string(content)
+	}
+	return bundle, nil
+}
+
+// parsePromptPresets parses the presets selected with PROMPT_PRESETS, a comma-separated list of preset names, used for
+// all their prompts, or prompt=preset pairs. Later entries take precedence. It returns the preset by prompt name.
+func parsePromptPresets(spec string) (map[string]string, error) {
+	selection := map[string]string{}
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		prompt, presetName, forPrompt := strings.Cut(entry, "=")
+		if !forPrompt {
+			presetName = prompt
+		}
+		preset, ok := findPromptPreset(strings.TrimSpace(presetName))
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt preset %s", strings.TrimSpace(presetName))
+		}
+		if !forPrompt {
+			for _, name := range preset.Prompts {
+				selection[name] = preset.Name
+			}
+			continue
+		}
+		prompt = strings.TrimSpace(prompt)
+		if !slices.Contains(preset.Prompts, prompt) {
+			return nil, fmt.Errorf("prompt preset %s has no %s prompt, it has: %s", preset.Name, prompt, strings.Join(preset.Prompts, ", "))
+		}
+		selection[prompt] = preset.Name
+	}
+	return selection, nil
+}
+
+// loadPresetTemplates parses the templates of the presets selected by prompt name
+func loadPresetTemplates(selection map[string]string) (map[string]*template.Template, error) {
+	templates := map[string]*template.Template{}
+	for _, prompt := range promptTemplates {
+		presetName, ok := selection[prompt.name]
+		if !ok {
+			continue
+		}
+		bundle, err := presetBundle(presetName, []string{prompt.name})
+		if err != nil {
+			return nil, err
+		}
+		parsed, _, problems := parsePromptBundle(bundle)
+		if problem, ok := problems[prompt.name]; ok {
+			return nil, fmt.Errorf("error parsing %s of prompt preset %s: %s", prompt.fileName, presetName, problem)
+		}
+		templates[prompt.name] = parsed[prompt.name]
+	}
+	return templates, nil
+}
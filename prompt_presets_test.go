@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptPresetTemplates(t *testing.T) {
+	for _, preset := range availablePromptPresets() {
+		require.NotEmpty(t, preset.Prompts, preset.Name)
+		bundle, err := presetBundle(preset.Name, nil)
+		require.NoError(t, err)
+		templates, previews, problems := parsePromptBundle(bundle)
+		assert.Empty(t, problems, preset.Name)
+		assert.Len(t, templates, len(preset.Prompts), preset.Name)
+		for name, preview := range previews {
+			assert.NotContains(t, preview, "<no value>", "%s of %s", name, preset.Name)
+		}
+	}
+
+	entries, err := promptPresetFiles.ReadDir("prompt_presets")
+	require.NoError(t, err)
+	assert.Len(t, entries, len(promptPresets), "every preset directory is listed")
+}
+
+func TestParsePromptPresets(t *testing.T) {
+	selection, err := parsePromptPresets("")
+	require.NoError(t, err)
+	assert.Empty(t, selection)
+
+	selection, err = parsePromptPresets("invoice-focused, title=verbose-titles")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"title":         "verbose-titles",
+		"tag":           "invoice-focused",
+		"correspondent": "invoice-focused",
+		"invoice":       "invoice-focused",
+	}, selection)
+
+	_, err = parsePromptPresets("chatty")
+	assert.ErrorContains(t, err, "unknown prompt preset chatty")
+	_, err = parsePromptPresets("title=minimal-tags")
+	assert.ErrorContains(t, err, "prompt preset minimal-tags has no title prompt, it has: tag")
+
+	templates, err := loadPresetTemplates(map[string]string{"tag": "minimal-tags"})
+	require.NoError(t, err)
+	require.Contains(t, templates, "tag")
+	assert.NotNil(t, templates["tag"].Lookup(systemMessageTemplate))
+}
+
+func TestApplyPromptPreset(t *testing.T) {
+	usePromptsDir(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/prompts/presets", getPromptPresetsHandler)
+	router.POST("/api/prompts/presets/:name/apply", applyPromptPresetHandler)
+	request := func(method, url, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, url, strings.NewReader(body)))
+		return w
+	}
+
+	w := request(http.MethodGet, "/api/prompts/presets", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var presets []PromptPreset
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &presets))
+	assert.Len(t, presets, len(promptPresets))
+
+	w = request(http.MethodPost, "/api/prompts/presets/invoice-focused/apply?dry_run=true", `{"prompts": ["title"]}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"imported":["title"]`)
+	assert.NoFileExists(t, filepath.Join(promptsDir, "title_prompt.tmpl"))
+
+	w = request(http.MethodPost, "/api/prompts/presets/invoice-focused/apply", "")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"imported":["title","tag","correspondent","invoice"]`)
+	content, err := os.ReadFile(filepath.Join(promptsDir, "invoice_prompt.tmpl"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "You extract invoice data")
+	assert.NotNil(t, invoiceTemplate.Lookup(systemMessageTemplate), "the templates are used right away")
+
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPost, "/api/prompts/presets/chatty/apply", "").Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "/api/prompts/presets/minimal-tags/apply", `{"prompts": ["title"]}`).Code)
+}
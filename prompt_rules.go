@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// perDocumentPrompts are the prompts generating suggestions for a single document, the ones rules can replace
+var perDocumentPrompts = []string{
+	"title", "tag", "correspondent", "created_date", "custom_fields", "language", "summary", "due_date", "invoice",
+	"amount", "contract", "pii", "sensitivity", "translation", "triage",
+}
+
+// PromptRules selects alternate prompt templates for documents by their tags and document type, loaded from
+// PROMPT_RULES_FILE
+type PromptRules struct {
+	Rules []PromptRule `json:"rules"`
+}
+
+// PromptRule replaces prompts for the documents with one of the tags or document types. Prompts maps the name of a
+// prompt, e.g. title, to a template file of the prompts directory.
+type PromptRule struct {
+	Name          string            `json:"name"`
+	Tags          []string          `json:"tags,omitempty"`
+	DocumentTypes []string          `json:"document_types,omitempty"`
+	Prompts       map[string]string `json:"prompts"`
+
+	templates map[string]*template.Template
+}
+
+// matches reports whether the document has one of the tags or document types of the rule (case-insensitive)
+func (rule PromptRule) matches(doc Document) bool {
+	for _, tag := range rule.Tags {
+		if documentMatches(doc, tag, "") {
+			return true
+		}
+	}
+	for _, documentType := range rule.DocumentTypes {
+		if documentMatches(doc, "", documentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadPromptRules reads the rules from a JSON file and parses their templates from the prompts directory. Every
+// template is rendered with sample data, so broken templates are reported at startup instead of during processing.
+func loadPromptRules(path string) (*PromptRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading prompt rules: %v", err)
+	}
+
+	var rules PromptRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error parsing prompt rules: %v", err)
+	}
+
+	for i := range rules.Rules {
+		rule := &rules.Rules[i]
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+		if len(rule.Tags) == 0 && len(rule.DocumentTypes) == 0 {
+			return nil, fmt.Errorf("prompt rule %s needs tags or document types", rule.Name)
+		}
+		if len(rule.Prompts) == 0 {
+			return nil, fmt.Errorf("prompt rule %s has no prompts", rule.Name)
+		}
+
+		rule.templates = make(map[string]*template.Template, len(rule.Prompts))
+		for name, fileName := range rule.Prompts {
+			if !slices.Contains(perDocumentPrompts, name) {
+				return nil, fmt.Errorf("prompt rule %s: the %s prompt can't be replaced by rules", rule.Name, name)
+			}
+			tmpl, err := loadRuleTemplate(name, fileName)
+			if err != nil {
+				return nil, fmt.Errorf("prompt rule %s: %v", rule.Name, err)
+			}
+			rule.templates[name] = tmpl
+		}
+	}
+	return &rules, nil
+}
+
+// loadRuleTemplate parses a template file of the prompts directory and renders it with sample data
+func loadRuleTemplate(name, fileName string) (*template.Template, error) {
+	if fileName == "" || filepath.Base(fileName) != fileName {
+		return nil, fmt.Errorf("the %s prompt must be the name of a file in the prompts directory, got %q", name, fileName)
+	}
+	return parsePromptFile(name, fileName)
+}
+
+// parsePromptFile parses a template file of the prompts directory and renders it with sample data. Errors reading the
+// file wrap the error of the file system.
+func parsePromptFile(name, fileName string) (*template.Template, error) {
+	content, err := os.ReadFile(filepath.Join(promptsDir, fileName))
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", fileName, err)
+	}
+	tmpl, err := template.New(name).Funcs(sprig.FuncMap()).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %v", fileName, err)
+	}
+	if _, err := renderPrompt(tmpl, samplePromptData()); err != nil {
+		return nil, fmt.Errorf("error rendering %s: %v", fileName, err)
+	}
+	return tmpl, nil
+}
+
+// promptFor returns the template of a prompt for the document being processed: the variant of a prompt experiment
+// assigned to the document, the one of the first rule matching the document that replaces the prompt, the one of the
+// language of the document, or the global template. templateMutex must be held.
+func promptFor(ctx context.Context, name string, global *template.Template) *template.Template {
+	if experiment, variant := experimentVariant(ctx, name); variant == promptVariantB {
+		return experiment.template
+	}
+	if document, ok := ctx.Value(promptDocumentKey{}).(promptDocument); ok && promptRules != nil {
+		for _, rule := range promptRules.Rules {
+			if tmpl, ok := rule.templates[name]; ok && rule.matches(document.doc) {
+				return tmpl
+			}
+		}
+	}
+	if tmpl := promptLanguageTemplate(ctx, name); tmpl != nil {
+		return tmpl
+	}
+	return global
+}
+
+// ruleTemplates returns the templates of all rules, ordered by rule and prompt name
+func (rules *PromptRules) ruleTemplates() []*template.Template {
+	if rules == nil {
+		return nil
+	}
+	var templates []*template.Template
+	for _, rule := range rules.Rules {
+		for _, name := range perDocumentPrompts {
+			if tmpl, ok := rule.templates[name]; ok {
+				templates = append(templates, tmpl)
+			}
+		}
+	}
+	return templates
+}
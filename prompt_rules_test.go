@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePromptRules(t *testing.T, rules string) string {
+	path := filepath.Join(t.TempDir(), "prompt_rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(rules), 0644))
+	return path
+}
+
+func TestLoadPromptRules(t *testing.T) {
+	usePromptsDir(t)
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "medical_title.tmpl"), []byte("Medical title for {{.Content}}"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "broken.tmpl"), []byte("{{.Content"), 0644))
+
+	rules, err := loadPromptRules(writePromptRules(t, `{"rules": [{"tags": ["Medical"], "prompts": {"title": "medical_title.tmpl"}}]}`))
+	require.NoError(t, err)
+	require.Len(t, rules.Rules, 1)
+	assert.Equal(t, "rule 1", rules.Rules[0].Name)
+	assert.Len(t, rules.ruleTemplates(), 1)
+
+	for rules, message := range map[string]string{
+		`{"rules": [{"name": "Medical", "prompts": {"title": "medical_title.tmpl"}}]}`:                         "needs tags or document types",
+		`{"rules": [{"name": "Medical", "tags": ["Medical"]}]}`:                                                "has no prompts",
+		`{"rules": [{"name": "Medical", "tags": ["Medical"], "prompts": {"ocr": "medical_title.tmpl"}}]}`:      "can't be replaced",
+		`{"rules": [{"name": "Medical", "tags": ["Medical"], "prompts": {"title": "../medical_title.tmpl"}}]}`: "must be the name of a file",
+		`{"rules": [{"name": "Medical", "tags": ["Medical"], "prompts": {"title": "missing.tmpl"}}]}`:          "error reading missing.tmpl",
+		`{"rules": [{"name": "Medical", "tags": ["Medical"], "prompts": {"title": "broken.tmpl"}}]}`:           "error parsing broken.tmpl",
+		`{"rules": {}}`: "error parsing prompt rules",
+	} {
+		_, err := loadPromptRules(writePromptRules(t, rules))
+		assert.ErrorContains(t, err, message, rules)
+	}
+}
+
+func TestPromptFor(t *testing.T) {
+	usePromptsDir(t)
+	original := promptRules
+	defer func() { promptRules = original }()
+	for name, content := range map[string]string{"medical_title.tmpl": "medical", "lab_title.tmpl": "lab", "lab_summary.tmpl": "lab summary"} {
+		require.NoError(t, os.WriteFile(filepath.Join(promptsDir, name), []byte(content), 0644))
+	}
+	var err error
+	promptRules, err = loadPromptRules(writePromptRules(t, `{"rules": [
+		{"name": "Medical", "tags": ["medical"], "prompts": {"title": "medical_title.tmpl"}},
+		{"name": "Lab", "document_types": ["Lab report"], "prompts": {"title": "lab_title.tmpl", "summary": "lab_summary.tmpl"}}
+	]}`))
+	require.NoError(t, err)
+
+	global := template.Must(template.New("title").Parse("global"))
+	render := func(ctx context.Context, name string) string {
+		var rendered bytes.Buffer
+		require.NoError(t, promptFor(ctx, name, global).Execute(&rendered, nil))
+		return rendered.String()
+	}
+
+	ctx := context.Background()
+	assert.Equal(t, "global", render(ctx, "title"), "no document")
+	assert.Equal(t, "global", render(withPromptDocument(ctx, Document{Tags: []string{"Invoice"}}, nil, 0), "title"))
+	assert.Equal(t, "medical", render(withPromptDocument(ctx, Document{Tags: []string{"Medical"}}, nil, 0), "title"))
+	assert.Equal(t, "lab", render(withPromptDocument(ctx, Document{DocumentType: "lab report"}, nil, 0), "title"))
+	medicalLab := withPromptDocument(ctx, Document{Tags: []string{"Medical"}, DocumentType: "Lab report"}, nil, 0)
+	assert.Equal(t, "medical", render(medicalLab, "title"), "the first matching rule wins")
+	assert.Equal(t, "lab summary", render(medicalLab, "summary"))
+	assert.Equal(t, "global", render(medicalLab, "tag"))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// PromptValidationRequest is the request payload for the POST /api/prompts/validate endpoint
+type PromptValidationRequest struct {
+	Name       string `json:"name"`                  // Prompt the template is for, e.g. title
+	Template   string `json:"template"`              // Content of the template
+	DocumentID int    `json:"document_id,omitempty"` // Document to render the template with, sample data if not set
+}
+
+// TemplateError is a problem of a template, with the position it was found at if known
+type TemplateError struct {
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// PromptValidationResult is the response of POST /api/prompts/validate. Rendered is the user message the template gives
+// for the document or the sample data, System and Prefill the messages of its system and assistant blocks. They are
+// empty if the template is invalid.
+type PromptValidationResult struct {
+	Valid      bool            `json:"valid"`
+	Errors     []TemplateError `json:"errors"`
+	Rendered   string          `json:"rendered,omitempty"`
+	System     string          `json:"system,omitempty"`
+	Prefill    string          `json:"prefill,omitempty"`
+	DocumentID int             `json:"document_id,omitempty"`
+}
+
+// templateErrorPattern matches the position in the errors of text/template, "template: name:line: message" for
+// parse errors and "template: name:line:column: message" for execution errors
+var templateErrorPattern = regexp.MustCompile(`^template: [^:]*:(\d+)(?::(\d+))?: (.*)$`)
+
+// parseTemplateError splits an error of text/template into its position and message
+func parseTemplateError(err error) TemplateError {
+	match := templateErrorPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return TemplateError{Message: err.Error()}
+	}
+	line, _ := strconv.Atoi(match[1])
+	column, _ := strconv.Atoi(match[2])
+	return TemplateError{Line: line, Column: column, Message: match[3]}
+}
+
+// validatePrompt parses a template of a prompt and renders it with the data. Variables missing from the data are
+// reported as errors, they would be rendered as "<no value>" otherwise.
+func validatePrompt(ctx context.Context, name, content string, data map[string]interface{}) PromptValidationResult {
+	result := PromptValidationResult{Errors: []TemplateError{}}
+	if strings.TrimSpace(content) == "" {
+		result.Errors = append(result.Errors, TemplateError{Message: "the prompt is empty"})
+		return result
+	}
+
+	tmpl, err := template.New(name).Funcs(sprig.FuncMap()).Option("missingkey=error").Parse(content)
+	if err != nil {
+		result.Errors = append(result.Errors, parseTemplateError(err))
+		return result
+	}
+
+	// The content is shortened to the token limit like when generating suggestions, without condensing it with the LLM
+	availableTokens, err := getAvailableTokensForContent(ctx, tmpl, data)
+	if err != nil {
+		result.Errors = append(result.Errors, parseTemplateError(unwrapTemplateError(err)))
+		return result
+	}
+	if content, ok := data["Content"].(string); ok {
+		truncated, err := truncateContentWith(ctx, settingsFor(ctx).TruncationStrategy, content, availableTokens)
+		if err != nil {
+			result.Errors = append(result.Errors, TemplateError{Message: err.Error()})
+			return result
+		}
+		data["Content"] = truncated
+	}
+
+	prompt, err := renderPrompt(tmpl, data)
+	if err != nil {
+		result.Errors = append(result.Errors, parseTemplateError(err))
+		return result
+	}
+	result.Valid = true
+	result.Rendered, result.System, result.Prefill = prompt.User, prompt.System, prompt.Prefill
+	return result
+}
+
+// unwrapTemplateError removes the prefix getAvailableTokensForContent adds to the errors of executing the template
+func unwrapTemplateError(err error) error {
+	if message, ok := strings.CutPrefix(err.Error(), "error executing template: "); ok {
+		return fmt.Errorf("%s", message)
+	}
+	return err
+}
+
+// documentValidationData returns the data the prompts of a document are rendered with, for the language configured
+// in LLM_LANGUAGE. Values only known while generating suggestions, like the available tags, are the sample ones.
+func (app *App) documentValidationData(ctx context.Context, documentID int) (map[string]interface{}, error) {
+	doc, err := app.Client.GetDocument(ctx, documentID)
+	if err != nil {
+		return nil, err
+	}
+	var customFields []CustomField
+	if len(doc.CustomFields) > 0 {
+		customFields, err = app.Client.GetAllCustomFields(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data := samplePromptData()
+	maps.Copy(data, documentPromptData(doc, customFields, app.ocrConfidence(doc.ID)))
+	data["Language"] = getLikelyLanguage(ctx)
+	data["Title"] = doc.Title
+	data["Content"] = doc.Content
+	return data, nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidatePrompt(t *testing.T) {
+	result := validatePrompt(context.Background(), "title", "Title in {{.Language}} for:\n{{.Content | upper}}", samplePromptData())
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, "Title in English for:\nINVOICE 2024-117\nACME CORP\nTOTAL: 119.00 EUR, DUE 2024-12-31", result.Rendered)
+
+	result = validatePrompt(context.Background(), "title", "{{define \"system\"}}Answer in {{.Language}}.{{end}}\nTitle for {{.Title}}", samplePromptData())
+	assert.True(t, result.Valid)
+	assert.Equal(t, "Answer in English.", result.System)
+	assert.Equal(t, "Title for Invoice 2024-117", result.Rendered)
+
+	for content, expected := range map[string]TemplateError{
+		"Title for\n{{.Content":              {Line: 2, Message: `unclosed action`},
+		"Title for\n\n{{.Content | upperr}}": {Line: 3, Message: `function "upperr" not defined`},
+		"Title for\n{{.Contents}}":           {Line: 2, Column: 2, Message: `executing "title" at <.Contents>: map has no entry for key "Contents"`},
+		"  ":                                 {Message: "the prompt is empty"},
+	} {
+		result := validatePrompt(context.Background(), "title", content, samplePromptData())
+		assert.False(t, result.Valid, content)
+		assert.Empty(t, result.Rendered, content)
+		assert.Equal(t, []TemplateError{expected}, result.Errors, content)
+	}
+}
+
+func TestValidatePromptHandler(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	defer env.db.Where("1 = 1").Delete(&OrphanedDocument{})
+	env.setMockResponse("/api/documents/1/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 1, "title": "Scan 0042", "content": "Electricity bill", "tags": []}`))
+	})
+	env.setMockResponse("/api/documents/2/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/prompts/validate", app.validatePromptHandler)
+	validate := func(body string) (*httptest.ResponseRecorder, PromptValidationResult) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/prompts/validate", strings.NewReader(body)))
+		var result PromptValidationResult
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		}
+		return w, result
+	}
+
+	w, result := validate(`{"name": "title", "template": "{{.OriginalTitle}}: {{.Content}}", "document_id": 1}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, result.Valid)
+	assert.Equal(t, "Scan 0042: Electricity bill", result.Rendered)
+	assert.Equal(t, 1, result.DocumentID)
+
+	w, result = validate(`{"name": "title_prompt.tmpl", "template": "{{.Content"}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.False(t, result.Valid)
+	assert.Equal(t, 1, result.Errors[0].Line)
+
+	w, _ = validate(`{"name": "abstract", "template": "{{.Content}}"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	w, _ = validate(`{"name": "title", "template": "{{.Content}}", "document_id": 2}`)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"text/template"
+	"time"
+
+	"github.com/Masterminds/sprig/v3"
+	"github.com/fsnotify/fsnotify"
+)
+
+// promptReloadDelay is how long the prompts directory has to be unchanged before the templates are reloaded, as
+// editors often write a file in several steps
+var promptReloadDelay = 500 * time.Millisecond
+
+// watchPrompts reloads the templates whenever files of the prompts directory or its language directories change, until
+// the context is done
+func watchPrompts(ctx context.Context) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		log.Warnf("Not watching %s, changed prompts are applied after a restart: %v", promptsDir, err)
+		return
+	}
+	defer watcher.Close()
+	if err := watchPromptDirs(watcher); err != nil {
+		log.Warnf("Not watching %s, changed prompts are applied after a restart: %v", promptsDir, err)
+		return
+	}
+
+	reload := time.NewTimer(promptReloadDelay)
+	reload.Stop()
+	defer reload.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+			// Language directories created later are watched too
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					if err := watcher.Add(event.Name); err != nil {
+						log.Warnf("Error watching %s: %v", event.Name, err)
+					}
+				}
+			}
+			reload.Reset(promptReloadDelay)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Warnf("Error watching %s: %v", promptsDir, err)
+		case <-reload.C:
+			if err := reloadTemplates(); err != nil {
+				log.Errorf("Failed to reload prompt templates, keeping the last good version: %v", err)
+			} else {
+				log.Infof("Reloaded the prompt templates of %s", promptsDir)
+			}
+		}
+	}
+}
+
+// watchPromptDirs adds the prompts directory and its subdirectories to the watcher, which doesn't watch recursively
+func watchPromptDirs(watcher *fsnotify.Watcher) error {
+	if err := watcher.Add(promptsDir); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(promptsDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if err := watcher.Add(filepath.Join(promptsDir, entry.Name())); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// reloadTemplates reads the templates of the prompts directory again. Templates that fail to parse or render with
+// sample data keep their last good version, so a half-saved edit doesn't break processing, and the errors are
+// returned. Deleted templates fall back to the default ones. Prompt rules and experiments are reloaded too, as their
+// templates are files of the prompts directory.
+func reloadTemplates() error {
+	var errs []error
+	presetSelection, err := parsePromptPresets(promptPresetsSpec)
+	if err != nil {
+		return err
+	}
+	templates := map[string]*template.Template{}
+	for _, prompt := range promptTemplates {
+		// Templates of presets replace the files
+		if _, ok := presetSelection[prompt.name]; ok {
+			continue
+		}
+		tmpl, err := parsePromptFile(prompt.name, prompt.fileName)
+		if errors.Is(err, os.ErrNotExist) {
+			tmpl, err = template.New(prompt.name).Funcs(sprig.FuncMap()).Parse(prompt.defaultContent)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		templates[prompt.name] = tmpl
+	}
+
+	languageTemplates, err := loadLanguageTemplates()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	var rules *PromptRules
+	if promptRulesFile != "" {
+		if rules, err = loadPromptRules(promptRulesFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	var experiments *PromptExperiments
+	if promptExperimentsFile != "" {
+		if experiments, err = loadPromptExperiments(promptExperimentsFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	templateMutex.Lock()
+	defer templateMutex.Unlock()
+	for _, prompt := range promptTemplates {
+		if tmpl, ok := templates[prompt.name]; ok {
+			*prompt.target = tmpl
+		}
+	}
+	if languageTemplates != nil {
+		languagePromptTemplates = languageTemplates
+	}
+	if rules != nil {
+		promptRules = rules
+	}
+	if experiments != nil {
+		promptExperiments = experiments
+	}
+	return errors.Join(errs...)
+}
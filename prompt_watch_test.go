@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadTemplates(t *testing.T) {
+	usePromptsDir(t)
+	originalLanguageTemplates := languagePromptTemplates
+	defer func() { languagePromptTemplates = originalLanguageTemplates }()
+
+	titlePath := filepath.Join(promptsDir, "title_prompt.tmpl")
+	require.NoError(t, os.WriteFile(titlePath, []byte("Title for {{.Content}}"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(promptsDir, "de"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(promptsDir, "de", "title_prompt.tmpl"), []byte("Titel für {{.Content}}"), 0644))
+	require.NoError(t, reloadTemplates())
+	assert.Equal(t, "Title for {{.Content}}", titleTemplate.Tree.Root.String())
+	assert.Contains(t, languagePromptTemplates["de"], "title")
+	assert.Contains(t, tagTemplate.Tree.Root.String(), "select appropriate tags", "missing templates are the default ones")
+
+	// A broken edit keeps the last good version of the template
+	require.NoError(t, os.WriteFile(titlePath, []byte("Title for {{.Content"), 0644))
+	err := reloadTemplates()
+	assert.ErrorContains(t, err, "title_prompt.tmpl")
+	assert.Equal(t, "Title for {{.Content}}", titleTemplate.Tree.Root.String())
+
+	require.NoError(t, os.Remove(titlePath))
+	require.NoError(t, reloadTemplates())
+	assert.Contains(t, titleTemplate.Tree.Root.String(), "find a suitable document title", "deleted templates fall back to the default ones")
+}
+
+func TestWatchPrompts(t *testing.T) {
+	usePromptsDir(t)
+	defer func(delay time.Duration) { promptReloadDelay = delay }(promptReloadDelay)
+	promptReloadDelay = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchPrompts(ctx)
+	// The watcher is set up in the background, so the file is written until it is picked up
+	assert.Eventually(t, func() bool {
+		if err := os.WriteFile(filepath.Join(promptsDir, "summary_prompt.tmpl"), []byte("Summarize {{.Content}}"), 0644); err != nil {
+			return false
+		}
+		templateMutex.RLock()
+		defer templateMutex.RUnlock()
+		return summaryTemplate != nil && summaryTemplate.Tree.Root.String() == "Summarize {{.Content}}"
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// queueForReview stores the suggestions for review instead of applying them (AUTO_REVIEW and AUTO_APPLY_CONFIDENCE).
+// The auto tag is removed from the documents, so they aren't processed again while waiting for review.
+func (app *App) queueForReview(ctx context.Context, suggestions []DocumentSuggestion) error {
+	availableTags, err := app.Client.GetAllTags(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching tags: %w", err)
+	}
+
+	autoTag := settingsFor(ctx).AutoTag
+	for _, suggestion := range suggestions {
+		if err := InsertPendingReview(app.Database, suggestion); err != nil {
+			return fmt.Errorf("error storing suggestions of document %d for review: %w", suggestion.ID, err)
+		}
+		events.publish(EventReviewQueued, map[string]any{"document_id": suggestion.ID})
+		if tagID, exists := availableTags[autoTag]; exists {
+			if err := app.Client.RemoveTagFromDocuments(ctx, []int{suggestion.ID}, tagID); err != nil {
+				return fmt.Errorf("error removing tag %s from document %d: %w", autoTag, suggestion.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// reviewedSuggestion describes suggestions waiting for review for the API
+type reviewedSuggestion struct {
+	ID           uint               `json:"id"`
+	DocumentID   uint               `json:"document_id"`
+	DateProposed string             `json:"date_proposed"`
+	Suggestion   DocumentSuggestion `json:"suggestion"`
+}
+
+// decodeSuggestion returns the suggestions stored for review
+func (review *PendingReview) decodeSuggestion() (DocumentSuggestion, error) {
+	var suggestion DocumentSuggestion
+	if err := json.Unmarshal([]byte(review.Suggestion), &suggestion); err != nil {
+		return DocumentSuggestion{}, fmt.Errorf("invalid suggestions stored for review %d: %w", review.ID, err)
+	}
+	return suggestion, nil
+}
+
+// pendingReviews lists the suggestions waiting for review, of a document or of all documents if documentID is 0
+func (app *App) pendingReviews(documentID uint) ([]reviewedSuggestion, error) {
+	reviews, err := GetPendingReviews(app.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve pending reviews: %w", err)
+	}
+
+	response := make([]reviewedSuggestion, 0, len(reviews))
+	for _, review := range reviews {
+		if documentID != 0 && review.DocumentID != documentID {
+			continue
+		}
+		suggestion, err := review.decodeSuggestion()
+		if err != nil {
+			log.Errorf("Skipping pending review: %v", err)
+			continue
+		}
+		response = append(response, reviewedSuggestion{
+			ID:           review.ID,
+			DocumentID:   review.DocumentID,
+			DateProposed: review.DateProposed,
+			Suggestion:   suggestion,
+		})
+	}
+	return response, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReviewQueue(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+	require.NoError(t, env.db.Where("1 = 1").Delete(&PendingReview{}).Error)
+
+	setSettings(t, func(settings *runtimeSettings) { settings.AutoTag = "paperless-gpt-auto" })
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "paperless-gpt-auto"}, {"id": 2, "name": "invoice"}], "next": null}`))
+	})
+	var bulkEdit map[string]interface{}
+	env.setMockResponse("/api/documents/bulk_edit/", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&bulkEdit))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"result": "OK"}`))
+	})
+	var patched map[string]interface{}
+	env.setMockResponse("/api/documents/5/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodPatch {
+			body, _ := io.ReadAll(r.Body)
+			require.NoError(t, json.Unmarshal(body, &patched))
+		}
+		w.Write([]byte(`{"id": 5, "title": "Scan", "tags": [2]}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	require.NoError(t, app.queueForReview(context.Background(), []DocumentSuggestion{{
+		ID:               5,
+		OriginalDocument: Document{ID: 5, Title: "Scan", Tags: []string{"paperless-gpt-auto", "invoice"}},
+		SuggestedTitle:   "Invoice 42",
+		RemoveTags:       []string{"paperless-gpt-auto"},
+	}}))
+	assert.Equal(t, "remove_tag", bulkEdit["method"])
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/review", app.getReviewsHandler)
+	router.POST("/api/review/:id/approve", app.approveReviewHandler)
+	router.POST("/api/review/:id/reject", app.rejectReviewHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/review", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var reviews []reviewedSuggestion
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &reviews))
+	require.Len(t, reviews, 1)
+	assert.Equal(t, "Invoice 42", reviews[0].Suggestion.SuggestedTitle)
+
+	// The edited title replaces the suggested one
+	w = httptest.NewRecorder()
+	approveURL := fmt.Sprintf("/api/review/%d/approve", reviews[0].ID)
+	router.ServeHTTP(w, httptest.NewRequest("POST", approveURL, strings.NewReader(`{"suggested_title": "Invoice 43"}`)))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Invoice 43", patched["title"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", fmt.Sprintf("/api/review/%d/reject", reviews[0].ID), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	pending, err := GetPendingReviews(env.db)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
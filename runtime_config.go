@@ -0,0 +1,310 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"os"
+)
+
+// redactedValue replaces secrets in the runtime configuration
+const redactedValue = "REDACTED"
+
+// RuntimeConfig is the effective configuration returned by GET /api/config.
+// Secrets like API keys and passwords are never included, they read REDACTED when they are set.
+type RuntimeConfig struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+
+	Paperless  PaperlessConfig  `json:"paperless"`
+	LLM        LLMConfig        `json:"llm"`
+	OCR        OCRConfig        `json:"ocr"`
+	Tags       TagsConfig       `json:"tags"`
+	Generation GenerationConfig `json:"generation"`
+	Processing ProcessingConfig `json:"processing"`
+	Limits     LimitsConfig     `json:"limits"`
+	Auth       AuthConfig       `json:"auth"`
+}
+
+// PaperlessConfig describes the connection to paperless-ngx
+type PaperlessConfig struct {
+	BaseURL            string `json:"base_url"`
+	PublicURL          string `json:"public_url"`
+	APIToken           string `json:"api_token"`
+	AuthHeader         string `json:"auth_header"`
+	AuthHeaderValue    string `json:"auth_header_value"`
+	SessionCookie      string `json:"session_cookie"`
+	Username           string `json:"username"`
+	Password           string `json:"password"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	CACert             string `json:"ca_cert"`
+	ClientCert         string `json:"client_cert"`
+	VerifyUpdates      bool   `json:"verify_updates"`
+}
+
+// LLMConfig describes the language models
+type LLMConfig struct {
+	Provider            string `json:"provider"`
+	Model               string `json:"model"`
+	Language            string `json:"language"`
+	VisionProvider      string `json:"vision_provider"`
+	VisionModel         string `json:"vision_model"`
+	EmbeddingModel      string `json:"embedding_model"`
+	OllamaHost          string `json:"ollama_host"`
+	OpenAIAPIKey        string `json:"openai_api_key"`
+	GoogleAIAPIKey      string `json:"googleai_api_key"`
+	TokenLimit          int    `json:"token_limit"`
+	TruncationStrategy  string `json:"truncation_strategy"`
+	DetectLanguage      bool   `json:"detect_language"`
+	TranslationLanguage string `json:"translation_language"`
+	FewShotExamples     int    `json:"few_shot_examples"`
+}
+
+// OCRConfig describes the OCR provider
+type OCRConfig struct {
+	Provider      string `json:"provider"`
+	LimitPages    int    `json:"limit_pages"`
+	JunkPages     string `json:"junk_pages"`
+	RenderDPI     int    `json:"render_dpi"`
+	ColorMode     string `json:"color_mode"`
+	MaxDimension  int    `json:"max_dimension"`
+	JPEGQuality   int    `json:"jpeg_quality"`
+	AzureEndpoint string `json:"azure_endpoint"`
+	AzureKey      string `json:"azure_key"`
+	AzureModelID  string `json:"azure_model_id"`
+	GoogleProject string `json:"google_project_id"`
+	ASNBarcodes   bool   `json:"asn_barcodes"`
+	PollInterval  string `json:"poll_interval"`
+	PreConsume    bool   `json:"pre_consume"`
+}
+
+// TagsConfig lists the tags paperless-gpt reacts to and sets
+type TagsConfig struct {
+	Manual         string   `json:"manual"`
+	Auto           string   `json:"auto"`
+	AutoOCR        string   `json:"auto_ocr"`
+	Error          string   `json:"error"`
+	Priority       []string `json:"priority"`
+	AllowNew       bool     `json:"allow_new"`
+	TaxonomyFile   string   `json:"taxonomy_file"`
+	AutoSavedView  string   `json:"auto_saved_view"`
+	AutoQuery      string   `json:"auto_document_query"`
+	BackfillQuery  string   `json:"backfill_query"`
+	AddedAfter     string   `json:"process_documents_added_after"`
+	ActionRequired string   `json:"action_required"`
+	PII            string   `json:"pii"`
+}
+
+// GenerationConfig lists what the automatic processing generates, as set by the AUTO_GENERATE_* settings
+type GenerationConfig struct {
+	Titles           bool `json:"titles"`
+	Tags             bool `json:"tags"`
+	Correspondents   bool `json:"correspondents"`
+	CreatedDate      bool `json:"created_date"`
+	CustomFields     bool `json:"custom_fields"`
+	Summary          bool `json:"summary"`
+	DueDate          bool `json:"due_date"`
+	Amount           bool `json:"amount"`
+	Translation      bool `json:"translation"`
+	Triage           bool `json:"triage"`
+	RelatedDocuments bool `json:"related_documents"`
+	PIIScan          bool `json:"pii_scan"`
+	Sensitivity      bool `json:"sensitivity"`
+	AutoReview       bool `json:"auto_review"`
+	// AutoApplyConfidence is the confidence below which suggestions are queued for review, 0 if they are always applied
+	AutoApplyConfidence float64 `json:"auto_apply_confidence"`
+}
+
+// ProcessingConfig describes when the background processing runs
+type ProcessingConfig struct {
+	PollInterval string `json:"poll_interval"`
+	Schedule     string `json:"schedule"`
+	QuietHours   string `json:"quiet_hours"`
+	Concurrency  int    `json:"concurrency"`
+	JobAttempts  int    `json:"job_max_attempts"`
+	MaxFailures  int    `json:"max_document_failures"`
+	// HistoryRetentionDays and HistoryMaxEntries limit the modification history, 0 means no limit
+	HistoryRetentionDays int `json:"history_retention_days"`
+	HistoryMaxEntries    int `json:"history_max_entries"`
+	// BackupIntervalHours is the age of the newest backup a new one is made at, 0 disables backups. BackupKeep is the
+	// number of backups kept, 0 keeps all of them.
+	BackupIntervalHours int `json:"backup_interval_hours"`
+	BackupKeep          int `json:"backup_keep"`
+}
+
+// LimitsConfig lists the rate and concurrency limits, 0 means no limit
+type LimitsConfig struct {
+	LLMConcurrency          int     `json:"llm_concurrency"`
+	OCRConcurrency          int     `json:"ocr_concurrency"`
+	SuggestionsConcurrency  int     `json:"suggestions_concurrency"`
+	APIRateLimit            float64 `json:"api_rate_limit"`
+	APIRateLimitBurst       int     `json:"api_rate_limit_burst"`
+	PaperlessRateLimit      float64 `json:"paperless_rate_limit"`
+	PaperlessMaxRetries     int     `json:"paperless_max_retries"`
+	PaperlessPageSize       int     `json:"paperless_page_size"`
+	BulkEditBatchSize       int     `json:"bulk_edit_batch_size"`
+	CircuitBreakerThreshold int     `json:"circuit_breaker_threshold"`
+}
+
+// AuthConfig describes how the web UI and the API are protected
+type AuthConfig struct {
+	APIKeys           int    `json:"api_keys"`
+	OIDC              bool   `json:"oidc"`
+	OIDCIssuerURL     string `json:"oidc_issuer_url"`
+	OIDCClientID      string `json:"oidc_client_id"`
+	OIDCClientSecret  string `json:"oidc_client_secret"`
+	OIDCSessionSecret string `json:"oidc_session_secret"`
+	BasePath          string `json:"base_path"`
+	ListenInterface   string `json:"listen_interface"`
+}
+
+// redactSecret hides a secret, empty secrets stay empty so it's visible whether they are set
+func redactSecret(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return redactedValue
+}
+
+// redactURL hides the password of a URL with user info
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL
+	}
+	if _, hasPassword := parsed.User.Password(); hasPassword {
+		parsed.User = url.UserPassword(parsed.User.Username(), redactedValue)
+	}
+	return parsed.String()
+}
+
+// currentRuntimeConfig collects the effective configuration after the defaults were applied
+func (app *App) currentRuntimeConfig() RuntimeConfig {
+	ocrProvider := os.Getenv("OCR_PROVIDER")
+	if ocrProvider == "" {
+		ocrProvider = "llm"
+	}
+	if !app.isOcrEnabled() {
+		ocrProvider = ""
+	}
+	settings := loadSettings()
+	generation := settings.autoSuggestionRequest(Document{})
+
+	return RuntimeConfig{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		Paperless: PaperlessConfig{
+			BaseURL:            redactURL(paperlessBaseURL),
+			PublicURL:          redactURL(paperlessPublicURL()),
+			APIToken:           redactSecret(paperlessAPIToken),
+			AuthHeader:         paperlessAuthHeader,
+			AuthHeaderValue:    redactSecret(paperlessAuthHeaderValue),
+			SessionCookie:      redactSecret(paperlessSessionCookie),
+			Username:           paperlessUsername,
+			Password:           redactSecret(paperlessPassword),
+			InsecureSkipVerify: paperlessInsecureSkipVerify,
+			CACert:             paperlessCACert,
+			ClientCert:         paperlessClientCert,
+			VerifyUpdates:      verifyDocumentUpdates,
+		},
+		LLM: LLMConfig{
+			Provider:            settings.LLMProvider,
+			Model:               settings.LLMModel,
+			Language:            getLikelyLanguage(context.Background()),
+			VisionProvider:      visionLlmProvider,
+			VisionModel:         visionLlmModel,
+			EmbeddingModel:      embeddingModel,
+			OllamaHost:          redactURL(os.Getenv("OLLAMA_HOST")),
+			OpenAIAPIKey:        redactSecret(openaiAPIKey),
+			GoogleAIAPIKey:      redactSecret(os.Getenv("GOOGLEAI_API_KEY")),
+			TokenLimit:          settings.TokenLimit,
+			TruncationStrategy:  settings.TruncationStrategy,
+			DetectLanguage:      settings.DetectLanguage,
+			TranslationLanguage: translationLanguage,
+			FewShotExamples:     settings.FewShotExamples,
+		},
+		OCR: OCRConfig{
+			Provider:      ocrProvider,
+			LimitPages:    settings.LimitOCRPages,
+			JunkPages:     ocrJunkPageMode,
+			RenderDPI:     settings.PDFRenderDPI,
+			ColorMode:     settings.PDFRenderColorMode,
+			MaxDimension:  settings.PDFRenderMaxDimension,
+			JPEGQuality:   settings.PDFRenderJPEGQuality,
+			AzureEndpoint: azureDocAIEndpoint,
+			AzureKey:      redactSecret(azureDocAIKey),
+			AzureModelID:  azureDocAIModelID,
+			GoogleProject: os.Getenv("GOOGLE_PROJECT_ID"),
+			ASNBarcodes:   asnBarcodeDetection,
+			PollInterval:  ocrPollInterval.String(),
+			PreConsume:    preConsumeInputDir != "",
+		},
+		Tags: TagsConfig{
+			Manual:         settings.ManualTag,
+			Auto:           settings.AutoTag,
+			AutoOCR:        settings.AutoOCRTag,
+			Error:          settings.ErrorTag,
+			Priority:       settings.PriorityTags,
+			AllowNew:       settings.AllowNewTags,
+			TaxonomyFile:   tagTaxonomyFile,
+			AutoSavedView:  autoSavedView,
+			AutoQuery:      autoDocumentQuery,
+			BackfillQuery:  backfillQuery,
+			AddedAfter:     processDocumentsAddedAfter,
+			ActionRequired: settings.ActionRequiredTag,
+			PII:            settings.PIITag,
+		},
+		Generation: GenerationConfig{
+			Titles:              generation.GenerateTitles,
+			Tags:                generation.GenerateTags,
+			Correspondents:      generation.GenerateCorrespondents,
+			CreatedDate:         generation.GenerateCreatedDate,
+			CustomFields:        generation.GenerateCustomFields,
+			Summary:             generation.GenerateSummary,
+			DueDate:             generation.GenerateDueDate,
+			Amount:              generation.GenerateAmount,
+			Translation:         generation.Translate,
+			Triage:              generation.Triage,
+			RelatedDocuments:    generation.LinkRelated,
+			PIIScan:             settings.PIIScan,
+			Sensitivity:         settings.ClassifySensitivity,
+			AutoReview:          settings.AutoReview,
+			AutoApplyConfidence: settings.AutoApplyConfidence,
+		},
+		Processing: ProcessingConfig{
+			PollInterval:         backgroundPollInterval.String(),
+			Schedule:             processingScheduleSpec,
+			QuietHours:           quietHoursSpec,
+			Concurrency:          settings.AutoProcessingConcurrency,
+			JobAttempts:          settings.JobMaxAttempts,
+			MaxFailures:          settings.MaxDocumentFailures,
+			HistoryRetentionDays: settings.HistoryRetentionDays,
+			HistoryMaxEntries:    settings.HistoryMaxEntries,
+			BackupIntervalHours:  settings.BackupIntervalHours,
+			BackupKeep:           settings.BackupKeep,
+		},
+		Limits: LimitsConfig{
+			LLMConcurrency:          settings.LLMConcurrencyLimit,
+			OCRConcurrency:          ocrConcurrencyLimit,
+			SuggestionsConcurrency:  suggestionsConcurrencyLimit,
+			APIRateLimit:            apiRateLimit,
+			APIRateLimitBurst:       apiRateLimitBurst,
+			PaperlessRateLimit:      paperlessRateLimit,
+			PaperlessMaxRetries:     paperlessMaxRetries,
+			PaperlessPageSize:       paperlessPageSize,
+			BulkEditBatchSize:       bulkEditBatchSize,
+			CircuitBreakerThreshold: circuitBreakerThreshold,
+		},
+		Auth: AuthConfig{
+			APIKeys:           len(apiKeys),
+			OIDC:              oidcAuth != nil,
+			OIDCIssuerURL:     oidcIssuerURL,
+			OIDCClientID:      oidcClientID,
+			OIDCClientSecret:  redactSecret(oidcClientSecret),
+			OIDCSessionSecret: redactSecret(oidcSessionSecret),
+			BasePath:          basePath,
+			ListenInterface:   listenInterface,
+		},
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a standard five field cron expression (minute, hour, day of month, month, day of week)
+// describing the minutes in which background processing may run
+type cronSchedule struct {
+	minutes     []bool
+	hours       []bool
+	daysOfMonth []bool
+	months      []bool
+	daysOfWeek  []bool
+	// Like cron, a day matches either field when both day of month and day of week are restricted
+	restrictedDayOfMonth bool
+	restrictedDayOfWeek  bool
+}
+
+// parseCronSchedule parses a cron expression like "* 22-23,0-6 * * 1-5".
+// Fields support "*", single values, ranges, lists and steps ("*/15", "0-30/5"). Sunday is 0 or 7.
+func parseCronSchedule(expression string) (*cronSchedule, error) {
+	fields := strings.Fields(expression)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields, got %d", expression, len(fields))
+	}
+
+	var schedule cronSchedule
+	var err error
+	if schedule.minutes, err = parseCronField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("invalid minute field: %w", err)
+	}
+	if schedule.hours, err = parseCronField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("invalid hour field: %w", err)
+	}
+	if schedule.daysOfMonth, err = parseCronField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("invalid day of month field: %w", err)
+	}
+	if schedule.months, err = parseCronField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("invalid month field: %w", err)
+	}
+	if schedule.daysOfWeek, err = parseCronField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("invalid day of week field: %w", err)
+	}
+	if schedule.daysOfWeek[7] {
+		schedule.daysOfWeek[0] = true
+	}
+	schedule.restrictedDayOfMonth = fields[2] != "*"
+	schedule.restrictedDayOfWeek = fields[4] != "*"
+	return &schedule, nil
+}
+
+// parseCronField returns which values between min and max are matched by a cron field
+func parseCronField(field string, min, max int) ([]bool, error) {
+	matches := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if before, after, found := strings.Cut(part, "/"); found {
+			rangePart = before
+			parsed, err := strconv.Atoi(after)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid step in %q", part)
+			}
+			step = parsed
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			before, after, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(before); err != nil {
+				return nil, fmt.Errorf("invalid value in %q", part)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(after); err != nil {
+					return nil, fmt.Errorf("invalid value in %q", part)
+				}
+			} else if step > 1 {
+				// "5/15" means every 15 starting at 5
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+
+		for value := start; value <= end; value += step {
+			matches[value] = true
+		}
+	}
+	return matches, nil
+}
+
+// matches reports whether the schedule allows processing at the given time
+func (schedule *cronSchedule) matches(t time.Time) bool {
+	if !schedule.minutes[t.Minute()] || !schedule.hours[t.Hour()] || !schedule.months[int(t.Month())] {
+		return false
+	}
+
+	dayOfMonth := schedule.daysOfMonth[t.Day()]
+	dayOfWeek := schedule.daysOfWeek[int(t.Weekday())]
+	if schedule.restrictedDayOfMonth && schedule.restrictedDayOfWeek {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+// quietHoursWindow is a daily time window without background processing, which may span midnight
+type quietHoursWindow struct {
+	start int // Minutes after midnight
+	end   int // Minutes after midnight, exclusive
+}
+
+// parseQuietHours parses a window like "08:00-18:00" or "22:00-06:00"
+func parseQuietHours(window string) (*quietHoursWindow, error) {
+	startText, endText, found := strings.Cut(window, "-")
+	if !found {
+		return nil, fmt.Errorf("quiet hours %q must look like 08:00-18:00", window)
+	}
+
+	var quietHours quietHoursWindow
+	var err error
+	if quietHours.start, err = parseClockTime(startText); err != nil {
+		return nil, err
+	}
+	if quietHours.end, err = parseClockTime(endText); err != nil {
+		return nil, err
+	}
+	if quietHours.start == quietHours.end {
+		return nil, fmt.Errorf("quiet hours %q start and end at the same time", window)
+	}
+	return &quietHours, nil
+}
+
+// parseClockTime parses a time of day like "06:30" into minutes after midnight
+func parseClockTime(text string) (int, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(text))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", text)
+	}
+	return parsed.Hour()*60 + parsed.Minute(), nil
+}
+
+// contains reports whether the given time falls into the quiet hours
+func (quietHours *quietHoursWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if quietHours.start < quietHours.end {
+		return minute >= quietHours.start && minute < quietHours.end
+	}
+	return minute >= quietHours.start || minute < quietHours.end
+}
+
+// processingAllowed reports whether background processing may run at the given time,
+// according to PROCESSING_SCHEDULE and QUIET_HOURS
+func processingAllowed(t time.Time) bool {
+	if processingSchedule != nil && !processingSchedule.matches(t) {
+		return false
+	}
+	if processingQuietHours != nil && processingQuietHours.contains(t) {
+		return false
+	}
+	return true
+}
+
+// parsePollInterval parses a polling interval like "30s", "5m" or "1h". A plain number is taken as seconds.
+func parsePollInterval(text string) (time.Duration, error) {
+	interval, err := time.ParseDuration(text)
+	if err != nil {
+		seconds, atoiErr := strconv.Atoi(text)
+		if atoiErr != nil {
+			return 0, fmt.Errorf("%q is not a duration like 30s, 5m or 1h", text)
+		}
+		interval = time.Duration(seconds) * time.Second
+	}
+	if interval <= 0 {
+		return 0, fmt.Errorf("%q must be positive", text)
+	}
+	return interval, nil
+}
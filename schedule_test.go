@@ -0,0 +1,97 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCronSchedule(t *testing.T) {
+	// 2024-03-04 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, time.Local)
+	}
+
+	nights, err := parseCronSchedule("* 22-23,0-5 * * *")
+	require.NoError(t, err)
+	assert.True(t, nights.matches(at(4, 23, 30)))
+	assert.True(t, nights.matches(at(4, 3, 0)))
+	assert.False(t, nights.matches(at(4, 12, 0)))
+
+	weekdays, err := parseCronSchedule("*/15 9-17 * * 1-5")
+	require.NoError(t, err)
+	assert.True(t, weekdays.matches(at(4, 9, 45)))
+	assert.False(t, weekdays.matches(at(4, 9, 46)))
+	assert.False(t, weekdays.matches(at(3, 10, 0)), "Sunday")
+
+	// Sunday as 7, or the first of the month
+	either, err := parseCronSchedule("* * 1 * 7")
+	require.NoError(t, err)
+	assert.True(t, either.matches(at(3, 10, 0)))
+	assert.True(t, either.matches(at(1, 10, 0)))
+	assert.False(t, either.matches(at(4, 10, 0)))
+
+	for _, invalid := range []string{"* * * *", "60 * * * *", "* 5-1 * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := parseCronSchedule(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestQuietHours(t *testing.T) {
+	at := func(hour, minute int) time.Time {
+		return time.Date(2024, time.March, 4, hour, minute, 0, 0, time.Local)
+	}
+
+	daytime, err := parseQuietHours("08:00-18:30")
+	require.NoError(t, err)
+	assert.True(t, daytime.contains(at(8, 0)))
+	assert.True(t, daytime.contains(at(18, 29)))
+	assert.False(t, daytime.contains(at(18, 30)))
+	assert.False(t, daytime.contains(at(7, 59)))
+
+	overnight, err := parseQuietHours("22:00-06:00")
+	require.NoError(t, err)
+	assert.True(t, overnight.contains(at(23, 0)))
+	assert.True(t, overnight.contains(at(5, 59)))
+	assert.False(t, overnight.contains(at(12, 0)))
+
+	for _, invalid := range []string{"08:00", "8-18", "25:00-06:00", "06:00-06:00"} {
+		_, err := parseQuietHours(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestProcessingAllowed(t *testing.T) {
+	originalSchedule, originalQuietHours := processingSchedule, processingQuietHours
+	defer func() { processingSchedule, processingQuietHours = originalSchedule, originalQuietHours }()
+
+	monday := time.Date(2024, time.March, 4, 7, 0, 0, 0, time.Local)
+	processingSchedule, processingQuietHours = nil, nil
+	assert.True(t, processingAllowed(monday))
+
+	var err error
+	processingSchedule, err = parseCronSchedule("* 0-7 * * *")
+	require.NoError(t, err)
+	processingQuietHours, err = parseQuietHours("07:00-07:30")
+	require.NoError(t, err)
+	assert.False(t, processingAllowed(monday))
+	assert.True(t, processingAllowed(monday.Add(45*time.Minute)))
+	assert.False(t, processingAllowed(monday.Add(2*time.Hour)))
+}
+
+func TestParsePollInterval(t *testing.T) {
+	interval, err := parsePollInterval("5m")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, interval)
+
+	interval, err = parsePollInterval("90")
+	require.NoError(t, err)
+	assert.Equal(t, 90*time.Second, interval)
+
+	for _, invalid := range []string{"0", "-1m", "hourly"} {
+		_, err := parsePollInterval(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
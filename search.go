@@ -0,0 +1,63 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// documentSearchResult is a document found by the full-text search of paperless-ngx, together with what paperless-gpt
+// knows about it locally
+type documentSearchResult struct {
+	Document       Document   `json:"document"`
+	SearchHit      *SearchHit `json:"search_hit,omitempty"`
+	PendingReviews []uint     `json:"pending_reviews"` // IDs of suggestions waiting for review
+	PendingTags    []string   `json:"pending_tags"`    // New tags proposed for the document waiting for approval
+	Jobs           []gin.H    `json:"jobs"`            // Latest job of each kind for the document
+}
+
+// enrichSearchResults adds the pending reviews, pending tags and jobs of the documents to the search results
+func enrichSearchResults(db *gorm.DB, documents []Document, hits []*SearchHit) ([]documentSearchResult, error) {
+	reviews, err := GetPendingReviews(db)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := GetPendingTags(db)
+	if err != nil {
+		return nil, err
+	}
+	jobs := jobStore.GetAllJobs()
+
+	results := make([]documentSearchResult, len(documents))
+	for i, document := range documents {
+		result := documentSearchResult{
+			Document:       document,
+			SearchHit:      hits[i],
+			PendingReviews: []uint{},
+			PendingTags:    []string{},
+			Jobs:           []gin.H{},
+		}
+		for _, review := range reviews {
+			if int(review.DocumentID) == document.ID {
+				result.PendingReviews = append(result.PendingReviews, review.ID)
+			}
+		}
+		for _, tag := range tags {
+			if int(tag.DocumentID) == document.ID && !slices.Contains(result.PendingTags, tag.Name) {
+				result.PendingTags = append(result.PendingTags, tag.Name)
+			}
+		}
+
+		// Jobs are sorted newest first, so the first job of each kind is the latest
+		var kinds []string
+		for _, job := range jobs {
+			if job.DocumentID == document.ID && !slices.Contains(kinds, job.Kind) {
+				kinds = append(kinds, job.Kind)
+				result.Jobs = append(result.Jobs, jobResponse(job))
+			}
+		}
+		results[i] = result
+	}
+	return results, nil
+}
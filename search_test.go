@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchDocumentsHandler(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	env.setMockResponse("/api/documents/", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "electricity bill", r.URL.Query().Get("query"))
+		assert.Equal(t, "2", r.URL.Query().Get("page"))
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"count": 12, "next": null, "results": [
+			{"id": 31, "title": "Power", "tags": [], "__search_hit__": {"score": 3.5, "highlights": "your <span class=\"match\">electricity</span> bill", "rank": 0}},
+			{"id": 32, "title": "Gas", "tags": []}
+		]}`))
+	})
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+
+	require.NoError(t, env.db.Create(&PendingReview{DocumentID: 31, Suggestion: "{}", Status: ReviewStatusPending, DateProposed: "2025-03-01 12:00:00"}).Error)
+	require.NoError(t, env.db.Create(&PendingTag{Name: "utilities", DocumentID: 31, Status: PendingTagStatusPending, DateProposed: "2025-03-01 12:00:00"}).Error)
+	job := newJob(JobKindOCR, 32)
+	jobStore.addJob(job)
+	defer func() {
+		jobStore.Lock()
+		delete(jobStore.jobs, job.ID)
+		jobStore.Unlock()
+	}()
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/search", app.searchDocumentsHandler)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/search?q=electricity+bill&page=2", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "12", w.Header().Get("X-Total-Count"))
+
+	var results []struct {
+		Document       Document         `json:"document"`
+		SearchHit      *SearchHit       `json:"search_hit"`
+		PendingReviews []uint           `json:"pending_reviews"`
+		PendingTags    []string         `json:"pending_tags"`
+		Jobs           []map[string]any `json:"jobs"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &results))
+	require.Len(t, results, 2)
+
+	assert.Equal(t, 31, results[0].Document.ID)
+	require.NotNil(t, results[0].SearchHit)
+	assert.Contains(t, results[0].SearchHit.Highlights, "electricity")
+	assert.Len(t, results[0].PendingReviews, 1)
+	assert.Equal(t, []string{"utilities"}, results[0].PendingTags)
+	assert.Empty(t, results[0].Jobs)
+
+	assert.Nil(t, results[1].SearchHit)
+	assert.Empty(t, results[1].PendingReviews)
+	require.Len(t, results[1].Jobs, 1)
+	assert.Equal(t, job.ID, results[1].Jobs[0]["job_id"])
+	assert.Equal(t, "pending", results[1].Jobs[0]["status"])
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/search?q=+", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
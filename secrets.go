@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretEnv returns the value of a credential, read from the file named by <NAME>_FILE if that is set, like the Docker
+// secrets mounted to /run/secrets. The value is set in the environment too, for the libraries reading it from there.
+func secretEnv(name string) string {
+	value, err := readSecretEnv(name)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return value
+}
+
+// readSecretEnv returns the value of the variable or the content of the file named by <NAME>_FILE, without the
+// trailing line break most editors add. Setting a variable both ways is an error, as it is unclear which one is meant.
+func readSecretEnv(name string) (string, error) {
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+	if os.Getenv(name) != "" {
+		return "", fmt.Errorf("both %s and %s_FILE are set, only one of them may be", name, name)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s_FILE: %v", name, err)
+	}
+	value := strings.TrimRight(string(content), "\r\n")
+	if err := os.Setenv(name, value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadSecretEnv(t *testing.T) {
+	secretFile := filepath.Join(t.TempDir(), "paperless_token")
+	require.NoError(t, os.WriteFile(secretFile, []byte("token-from-file\n"), 0o600))
+
+	t.Setenv("PAPERLESS_API_TOKEN", "token-from-env")
+	value, err := readSecretEnv("PAPERLESS_API_TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "token-from-env", value)
+
+	t.Setenv("PAPERLESS_API_TOKEN_FILE", secretFile)
+	_, err = readSecretEnv("PAPERLESS_API_TOKEN")
+	assert.ErrorContains(t, err, "both PAPERLESS_API_TOKEN and PAPERLESS_API_TOKEN_FILE are set")
+
+	t.Setenv("PAPERLESS_API_TOKEN", "")
+	value, err = readSecretEnv("PAPERLESS_API_TOKEN")
+	require.NoError(t, err)
+	assert.Equal(t, "token-from-file", value, "the trailing line break is removed")
+	assert.Equal(t, "token-from-file", os.Getenv("PAPERLESS_API_TOKEN"), "libraries reading the environment get the value")
+
+	t.Setenv("OPENAI_API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+	_, err = readSecretEnv("OPENAI_API_KEY")
+	assert.ErrorContains(t, err, "error reading OPENAI_API_KEY_FILE")
+}
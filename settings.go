@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sync/atomic"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// runtimeSettings are the settings that can be changed without a restart, by PATCH /api/config or CONFIG_FILE. A
+// runtimeSettings is never modified once it is stored, applySettings stores a changed copy instead. Jobs pin the
+// settings when they start (see withSettings), so they finish with the settings they started with.
+type runtimeSettings struct {
+	LLMProvider         string
+	LLMModel            string
+	LLMLanguage         string
+	LLMConcurrencyLimit int
+	TokenLimit          int
+	TruncationStrategy  string
+
+	ManualTag              string
+	AutoTag                string
+	AutoOCRTag             string
+	ErrorTag               string
+	PIITag                 string
+	ActionRequiredTag      string
+	DueSoonTag             string
+	PriorityTags           []string
+	PriorityCorrespondents []string
+	AllowNewTags           bool
+	AllowNewCorrespondents bool
+	CorrespondentWhiteList []string
+	TagBlackList           []string
+	TagWhiteList           []string
+
+	AutoGenerateTitle          string
+	AutoGenerateTags           string
+	AutoGenerateCorrespondents string
+	AutoGenerateCreatedDate    string
+	AutoGenerateCustomFields   string
+	AutoGenerateSummary        string
+	AutoGenerateDueDate        string
+	AutoGenerateAmount         string
+	AutoTranslate              string
+	AutoTriage                 string
+	AutoLinkRelatedDocuments   string
+	AutoReview                 bool
+	AutoApplyConfidence        float64
+	DetectLanguage             bool
+	PIIScan                    bool
+	ClassifySensitivity        bool
+
+	AutoProcessingConcurrency int
+	LimitOCRPages             int
+	PDFRenderDPI              int
+	PDFRenderColorMode        string
+	PDFRenderMaxDimension     int
+	PDFRenderJPEGQuality      int
+	JobMaxAttempts            int
+	MaxDocumentFailures       int
+	HistoryRetentionDays      int
+	HistoryMaxEntries         int
+	BackupIntervalHours       int
+	BackupKeep                int
+	FewShotExamples           int
+	DocumentLogRetentionDays  int
+
+	// LLM client created with these settings when the LLM settings were changed at runtime, nil while the client the
+	// App was started with is used
+	llm llms.Model
+}
+
+// environmentSettings reads the settings from the environment. validateOrDefaultEnvVars checks them and fills in the
+// defaults at startup.
+func environmentSettings() *runtimeSettings {
+	return &runtimeSettings{
+		LLMProvider:                os.Getenv("LLM_PROVIDER"),
+		LLMModel:                   os.Getenv("LLM_MODEL"),
+		LLMLanguage:                os.Getenv("LLM_LANGUAGE"),
+		TruncationStrategy:         truncateHead,
+		ManualTag:                  os.Getenv("MANUAL_TAG"),
+		AutoTag:                    os.Getenv("AUTO_TAG"),
+		AutoOCRTag:                 os.Getenv("AUTO_OCR_TAG"),
+		ErrorTag:                   os.Getenv("ERROR_TAG"),
+		PIITag:                     os.Getenv("PII_TAG"),
+		ActionRequiredTag:          os.Getenv("ACTION_REQUIRED_TAG"),
+		DueSoonTag:                 os.Getenv("DUE_SOON_TAG"),
+		PriorityTags:               splitAndTrim(os.Getenv("PRIORITY_TAGS")),
+		PriorityCorrespondents:     splitAndTrim(os.Getenv("PRIORITY_CORRESPONDENTS")),
+		AllowNewTags:               os.Getenv("ALLOW_NEW_TAGS") == "true",
+		AllowNewCorrespondents:     os.Getenv("ALLOW_NEW_CORRESPONDENTS") != "false",
+		CorrespondentWhiteList:     splitAndTrim(os.Getenv("CORRESPONDENT_WHITE_LIST")),
+		TagBlackList:               splitAndTrim(os.Getenv("TAG_BLACK_LIST")),
+		TagWhiteList:               splitAndTrim(os.Getenv("TAG_WHITE_LIST")),
+		AutoGenerateTitle:          os.Getenv("AUTO_GENERATE_TITLE"),
+		AutoGenerateTags:           os.Getenv("AUTO_GENERATE_TAGS"),
+		AutoGenerateCorrespondents: os.Getenv("AUTO_GENERATE_CORRESPONDENTS"),
+		AutoGenerateCreatedDate:    os.Getenv("AUTO_GENERATE_CREATED_DATE"),
+		AutoGenerateCustomFields:   os.Getenv("AUTO_GENERATE_CUSTOM_FIELDS"),
+		AutoGenerateSummary:        os.Getenv("AUTO_GENERATE_SUMMARY"),
+		AutoGenerateDueDate:        os.Getenv("AUTO_GENERATE_DUE_DATE"),
+		AutoGenerateAmount:         os.Getenv("AUTO_GENERATE_AMOUNT"),
+		AutoTranslate:              os.Getenv("AUTO_TRANSLATE"),
+		AutoTriage:                 os.Getenv("AUTO_TRIAGE"),
+		AutoLinkRelatedDocuments:   os.Getenv("AUTO_LINK_RELATED_DOCUMENTS"),
+		AutoReview:                 os.Getenv("AUTO_REVIEW") == "true",
+		DetectLanguage:             os.Getenv("DETECT_LANGUAGE") == "true",
+		PIIScan:                    os.Getenv("PII_SCAN") == "true",
+		ClassifySensitivity:        os.Getenv("CLASSIFY_SENSITIVITY") == "true",
+		AutoProcessingConcurrency:  1,
+		PDFRenderDPI:               defaultPDFRenderDPI,
+		PDFRenderColorMode:         pdfColorModeColor,
+		PDFRenderJPEGQuality:       defaultPDFJPEGQuality,
+		JobMaxAttempts:             3,
+		MaxDocumentFailures:        3,
+		BackupKeep:                 7,
+		DocumentLogRetentionDays:   30,
+	}
+}
+
+// currentSettings holds the settings in effect
+var currentSettings atomic.Pointer[runtimeSettings]
+
+func init() {
+	currentSettings.Store(environmentSettings())
+}
+
+// loadSettings returns the settings in effect. Code running for a job uses settingsFor instead.
+func loadSettings() *runtimeSettings {
+	return currentSettings.Load()
+}
+
+// clone returns a copy of the settings to change before storing it. The lists are shared, they are replaced instead of
+// being modified.
+func (settings *runtimeSettings) clone() *runtimeSettings {
+	clone := *settings
+	return &clone
+}
+
+type settingsKey struct{}
+
+// withSettings pins the settings in effect for the job of the context, unless the context has pinned settings
+// already. Changes of the settings apply to the jobs started after them.
+func withSettings(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(settingsKey{}).(*runtimeSettings); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, settingsKey{}, loadSettings())
+}
+
+// settingsFor returns the settings pinned for the job of the context, or the settings in effect outside of jobs
+func settingsFor(ctx context.Context) *runtimeSettings {
+	if settings, ok := ctx.Value(settingsKey{}).(*runtimeSettings); ok {
+		return settings
+	}
+	return loadSettings()
+}
+
+// llm returns the LLM client of the settings of the context's job
+func (app *App) llm(ctx context.Context) llms.Model {
+	if model := settingsFor(ctx).llm; model != nil {
+		return model
+	}
+	return app.LLM
+}
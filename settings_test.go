@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+)
+
+// setSettings changes the settings in effect for the test, restoring them when it ends
+func setSettings(t testing.TB, change func(settings *runtimeSettings)) {
+	t.Helper()
+	original := loadSettings()
+	changed := original.clone()
+	change(changed)
+	currentSettings.Store(changed)
+	t.Cleanup(func() { currentSettings.Store(original) })
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Window of GET /api/stats in days
+const (
+	defaultStatsDays = 30
+	maxStatsDays     = 365
+)
+
+// DailyDocuments is the number of documents paperless-gpt changed on a day
+type DailyDocuments struct {
+	Date      string `json:"date"`
+	Documents int64  `json:"documents"`
+}
+
+// TaskStats aggregates the LLM requests made for a task
+type TaskStats struct {
+	Task             string  `json:"task"`
+	Calls            int64   `json:"calls"`
+	Failed           int64   `json:"failed"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+	AverageLatencyMs float64 `json:"average_latency_ms"`
+}
+
+// UserStats aggregates the LLM requests made for a user, requests of the background processing have no user
+type UserStats struct {
+	User             string  `json:"user"`
+	Calls            int64   `json:"calls"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+// JobStats aggregates the jobs of a kind
+type JobStats struct {
+	Kind       string `json:"kind"`
+	Completed  int64  `json:"completed"`
+	Failed     int64  `json:"failed"`
+	Unfinished int64  `json:"unfinished"`
+	Pages      int64  `json:"pages"`
+}
+
+// StatsTotals sums up the statistics of all tasks and jobs
+type StatsTotals struct {
+	DocumentsProcessed    int64   `json:"documents_processed"`
+	LLMCalls              int64   `json:"llm_calls"`
+	FailedLLMCalls        int64   `json:"failed_llm_calls"`
+	LLMErrorRate          float64 `json:"llm_error_rate"`
+	PromptTokens          int64   `json:"prompt_tokens"`
+	CompletionTokens      int64   `json:"completion_tokens"`
+	Cost                  float64 `json:"cost"`
+	AverageLatencyMs      float64 `json:"average_latency_ms"`
+	OCRPages              int64   `json:"ocr_pages"`
+	JobsCompleted         int64   `json:"jobs_completed"`
+	JobsFailed            int64   `json:"jobs_failed"`
+	JobErrorRate          float64 `json:"job_error_rate"`
+	DeadLetteredDocuments int64   `json:"dead_lettered_documents"`
+}
+
+// Stats is the response of GET /api/stats, aggregated from the local database since the start of the window
+type Stats struct {
+	Since           time.Time        `json:"since"`
+	DocumentsPerDay []DailyDocuments `json:"documents_per_day"`
+	Tasks           []TaskStats      `json:"tasks"`
+	Users           []UserStats      `json:"users"`
+	Jobs            []JobStats       `json:"jobs"`
+	Totals          StatsTotals      `json:"totals"`
+}
+
+// statsSince returns the start of the window of the statistics of the last days, including today
+func statsSince(now time.Time, days int) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()).AddDate(0, 0, 1-days)
+}
+
+// collectStats aggregates the modifications, LLM requests per task and user, and jobs since the time. Dead-lettered documents are counted
+// regardless of the time, as they are waiting for a retry.
+func collectStats(db *gorm.DB, since time.Time) (Stats, error) {
+	stats := Stats{Since: since, DocumentsPerDay: []DailyDocuments{}, Tasks: []TaskStats{}, Users: []UserStats{}, Jobs: []JobStats{}}
+	sinceText := since.Format(time.RFC3339)
+
+	err := db.Model(&ModificationHistory{}).
+		Select("substr(date_changed, 1, 10) AS date, COUNT(DISTINCT document_id) AS documents").
+		Where("date_changed >= ?", sinceText).
+		Group("date").Order("date").
+		Scan(&stats.DocumentsPerDay).Error
+	if err != nil {
+		return Stats{}, err
+	}
+	err = db.Model(&ModificationHistory{}).
+		Select("COUNT(DISTINCT document_id)").
+		Where("date_changed >= ?", sinceText).
+		Scan(&stats.Totals.DocumentsProcessed).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	err = db.Model(&LLMUsage{}).
+		Select("task, COUNT(*) AS calls, SUM(CASE WHEN failed THEN 1 ELSE 0 END) AS failed, "+
+			"SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, SUM(cost) AS cost, "+
+			"AVG(duration_ms) AS average_latency_ms").
+		Where("created_at >= ?", sinceText).
+		Group("task").Order("task").
+		Scan(&stats.Tasks).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	// user is a reserved word in PostgreSQL
+	user := db.Statement.Quote("user")
+	err = db.Model(&LLMUsage{}).
+		Select("COALESCE("+user+", '') AS "+user+", COUNT(*) AS calls, SUM(prompt_tokens) AS prompt_tokens, "+
+			"SUM(completion_tokens) AS completion_tokens, SUM(cost) AS cost").
+		Where("created_at >= ?", sinceText).
+		Group("COALESCE(" + user + ", '')").Order(user).
+		Scan(&stats.Users).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	err = db.Model(&Job{}).
+		Select("kind, SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END) AS completed, "+
+			"SUM(CASE WHEN status = 'failed' THEN 1 ELSE 0 END) AS failed, "+
+			"SUM(CASE WHEN status IN ('pending', 'in_progress') THEN 1 ELSE 0 END) AS unfinished, "+
+			"SUM(pages_done) AS pages").
+		Where("created_at >= ?", since).
+		Group("kind").Order("kind").
+		Scan(&stats.Jobs).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	err = db.Model(&DocumentFailure{}).Where("dead_lettered = ?", true).Count(&stats.Totals.DeadLetteredDocuments).Error
+	if err != nil {
+		return Stats{}, err
+	}
+
+	totals := &stats.Totals
+	var latency float64
+	for _, task := range stats.Tasks {
+		totals.LLMCalls += task.Calls
+		totals.FailedLLMCalls += task.Failed
+		totals.PromptTokens += task.PromptTokens
+		totals.CompletionTokens += task.CompletionTokens
+		totals.Cost += task.Cost
+		latency += task.AverageLatencyMs * float64(task.Calls)
+	}
+	if totals.LLMCalls > 0 {
+		totals.LLMErrorRate = float64(totals.FailedLLMCalls) / float64(totals.LLMCalls)
+		totals.AverageLatencyMs = latency / float64(totals.LLMCalls)
+	}
+	for _, job := range stats.Jobs {
+		totals.JobsCompleted += job.Completed
+		totals.JobsFailed += job.Failed
+		if job.Kind == JobKindOCR {
+			totals.OCRPages += job.Pages
+		}
+	}
+	if finished := totals.JobsCompleted + totals.JobsFailed; finished > 0 {
+		totals.JobErrorRate = float64(totals.JobsFailed) / float64(finished)
+	}
+	return stats, nil
+}
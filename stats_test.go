@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// usageReportingLLM answers with the token counts of the provider, or fails
+type usageReportingLLM struct {
+	err error
+}
+
+func (m *usageReportingLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *usageReportingLLM) GenerateContent(_ context.Context, _ []llms.MessageContent, _ ...llms.CallOption) (*llms.ContentResponse, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        "Invoice",
+		GenerationInfo: map[string]any{"PromptTokens": 1000, "CompletionTokens": 10},
+	}}}, nil
+}
+
+func TestStats(t *testing.T) {
+	db, err := InitializeTestDB()
+	require.NoError(t, err)
+	clear := func() {
+		for _, model := range []any{&ModificationHistory{}, &LLMUsage{}, &Job{}, &DocumentFailure{}} {
+			require.NoError(t, db.Where("1 = 1").Delete(model).Error)
+		}
+	}
+	clear()
+	defer clear()
+
+	originalPromptCost, originalCompletionCost := llmPromptTokenCost, llmCompletionTokenCost
+	defer func() { llmPromptTokenCost, llmCompletionTokenCost = originalPromptCost, originalCompletionCost }()
+	llmPromptTokenCost, llmCompletionTokenCost = 2.5, 10
+
+	// Every request to the LLM is recorded with its task
+	ctx := context.Background()
+	model := recordLLMUsage(&usageReportingLLM{}, db, "", "")
+	_, err = model.GenerateContent(withLLMTask(ctx, "title"), []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Title?")})
+	require.NoError(t, err)
+	_, err = model.Call(withLLMTask(ctx, "title"), "Title?")
+	require.NoError(t, err)
+	_, err = recordLLMUsage(&usageReportingLLM{err: errors.New("timeout")}, db, "", "").GenerateContent(withLLMTask(ctx, "tag"), nil)
+	require.Error(t, err)
+	_, err = recordLLMUsage(&mockJSONLLM{response: "A summary of the document"}, db, "", "").GenerateContent(ctx, []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeHuman, "Summarize")})
+	require.NoError(t, err)
+
+	now := time.Now()
+	old := now.AddDate(0, 0, -60).Format(time.RFC3339)
+	require.NoError(t, db.Create(&[]ModificationHistory{
+		{DocumentID: 1, DateChanged: now.Format(time.RFC3339), ModField: "title"},
+		{DocumentID: 1, DateChanged: now.Format(time.RFC3339), ModField: "tags"},
+		{DocumentID: 2, DateChanged: now.Format(time.RFC3339), ModField: "title"},
+		{DocumentID: 3, DateChanged: old, ModField: "title"},
+	}).Error)
+	require.NoError(t, db.Create(&[]Job{
+		{ID: "stats-1", Kind: JobKindOCR, DocumentID: 1, Status: "completed", PagesDone: 4, CreatedAt: now, UpdatedAt: now},
+		{ID: "stats-2", Kind: JobKindOCR, DocumentID: 2, Status: "failed", PagesDone: 1, CreatedAt: now, UpdatedAt: now},
+		{ID: "stats-3", Kind: JobKindGenerate, DocumentID: 3, Status: "pending", CreatedAt: now, UpdatedAt: now},
+	}).Error)
+	require.NoError(t, db.Create(&DocumentFailure{DocumentID: 4, Tag: "paperless-gpt-auto", Failures: 3, DeadLettered: true, LastFailedAt: old}).Error)
+
+	gin.SetMode(gin.TestMode)
+	app := &App{Database: db}
+	router := gin.New()
+	router.GET("/api/stats", app.getStatsHandler)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/stats?days=30", nil))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	stats, err := collectStats(db, now.AddDate(0, 0, -30))
+	require.NoError(t, err)
+	require.Len(t, stats.DocumentsPerDay, 1)
+	assert.Equal(t, now.Format(time.DateOnly), stats.DocumentsPerDay[0].Date)
+	assert.EqualValues(t, 2, stats.DocumentsPerDay[0].Documents)
+
+	require.Len(t, stats.Tasks, 3)
+	assert.Equal(t, []string{"other", "tag", "title"}, []string{stats.Tasks[0].Task, stats.Tasks[1].Task, stats.Tasks[2].Task})
+	assert.Positive(t, stats.Tasks[0].PromptTokens, "tokens not reported by the provider are estimated")
+	assert.EqualValues(t, 1, stats.Tasks[1].Failed)
+	assert.EqualValues(t, 2, stats.Tasks[2].Calls)
+	assert.EqualValues(t, 2000, stats.Tasks[2].PromptTokens)
+	assert.InDelta(t, 2*(1000*2.5+10*10)/1e6, stats.Tasks[2].Cost, 1e-9)
+
+	totals := stats.Totals
+	assert.EqualValues(t, 2, totals.DocumentsProcessed)
+	assert.EqualValues(t, 4, totals.LLMCalls)
+	assert.InDelta(t, 0.25, totals.LLMErrorRate, 1e-9)
+	assert.EqualValues(t, 5, totals.OCRPages)
+	assert.InDelta(t, 0.5, totals.JobErrorRate, 1e-9)
+	assert.EqualValues(t, 1, totals.DeadLetteredDocuments)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/api/stats?days=0", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Tag group modes
+const (
+	TagGroupModeAny        = "any"
+	TagGroupModeAtMostOne  = "at_most_one"
+	TagGroupModeExactlyOne = "exactly_one"
+)
+
+// TagTaxonomy is the user-provided structure of the tags, loaded from TAG_TAXONOMY_FILE
+type TagTaxonomy struct {
+	Groups []TagGroup `json:"groups"`
+}
+
+// TagGroup is a set of related tags with a selection rule.
+// If Parent is set, the parent tag is added whenever one of the group's tags is selected.
+type TagGroup struct {
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags"`
+	Mode    string   `json:"mode"`
+	Parent  string   `json:"parent,omitempty"`
+	Default string   `json:"default,omitempty"`
+}
+
+// Rule describes the selection rule of the group for the tag prompt
+func (group TagGroup) Rule() string {
+	tags := strings.Join(group.Tags, ", ")
+	switch group.Mode {
+	case TagGroupModeExactlyOne:
+		return fmt.Sprintf("%s: select exactly one of %s", group.Name, tags)
+	case TagGroupModeAtMostOne:
+		return fmt.Sprintf("%s: select at most one of %s", group.Name, tags)
+	default:
+		return fmt.Sprintf("%s: %s", group.Name, tags)
+	}
+}
+
+// tagGroups returns the groups of the configured tag taxonomy for the tag prompt
+func tagGroups() []TagGroup {
+	if tagTaxonomy == nil {
+		return nil
+	}
+	return tagTaxonomy.Groups
+}
+
+// loadTagTaxonomy reads and validates the tag taxonomy from a JSON file
+func loadTagTaxonomy(path string) (*TagTaxonomy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading tag taxonomy: %v", err)
+	}
+
+	var taxonomy TagTaxonomy
+	if err := json.Unmarshal(data, &taxonomy); err != nil {
+		return nil, fmt.Errorf("error parsing tag taxonomy: %v", err)
+	}
+
+	for i, group := range taxonomy.Groups {
+		if group.Name == "" || len(group.Tags) == 0 {
+			return nil, fmt.Errorf("tag group %d needs a name and at least one tag", i+1)
+		}
+		switch group.Mode {
+		case "":
+			taxonomy.Groups[i].Mode = TagGroupModeAny
+		case TagGroupModeAny, TagGroupModeAtMostOne, TagGroupModeExactlyOne:
+		default:
+			return nil, fmt.Errorf("tag group %s has an invalid mode: %s", group.Name, group.Mode)
+		}
+		if group.Default != "" && !containsFold(group.Tags, group.Default) {
+			return nil, fmt.Errorf("default tag %s of tag group %s is not part of the group", group.Default, group.Name)
+		}
+	}
+
+	return &taxonomy, nil
+}
+
+// Apply enforces the group rules on the selected tags.
+// Conflicts within exclusive groups are resolved in favour of the tag the LLM mentioned first.
+func (taxonomy *TagTaxonomy) Apply(tags []string, preferred []string, logger *logrus.Entry) []string {
+	if taxonomy == nil {
+		return tags
+	}
+
+	result := slices.Clone(tags)
+	for _, group := range taxonomy.Groups {
+		var selected []string
+		for _, tag := range result {
+			if containsFold(group.Tags, tag) {
+				selected = append(selected, tag)
+			}
+		}
+
+		if group.Mode != TagGroupModeAny && len(selected) > 1 {
+			keep := selected[0]
+			for _, tag := range preferred {
+				if containsFold(selected, tag) {
+					keep = tag
+					break
+				}
+			}
+			logger.Warnf("Tag group %s allows only one tag, keeping %s of %v", group.Name, keep, selected)
+			result = slices.DeleteFunc(result, func(tag string) bool {
+				return containsFold(selected, tag) && !strings.EqualFold(tag, keep)
+			})
+			selected = []string{keep}
+		}
+
+		if group.Mode == TagGroupModeExactlyOne && len(selected) == 0 {
+			if group.Default == "" {
+				logger.Warnf("Tag group %s requires exactly one tag, but none was selected", group.Name)
+				continue
+			}
+			result = append(result, group.Default)
+			selected = []string{group.Default}
+		}
+
+		if group.Parent != "" && len(selected) > 0 && !containsFold(result, group.Parent) {
+			result = append(result, group.Parent)
+		}
+	}
+
+	slices.Sort(result)
+	return result
+}
+
+// containsFold reports whether the list contains the value (case-insensitive)
+func containsFold(list []string, value string) bool {
+	return slices.ContainsFunc(list, func(item string) bool {
+		return strings.EqualFold(item, value)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTagTaxonomy(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "taxonomy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"groups": [
+			{"name": "Year", "tags": ["2023", "2024", "2025"], "mode": "exactly_one"},
+			{"name": "Finance", "tags": ["invoice", "receipt"], "parent": "finance"}
+		]
+	}`), 0644))
+
+	taxonomy, err := loadTagTaxonomy(path)
+	require.NoError(t, err)
+	require.Len(t, taxonomy.Groups, 2)
+	assert.Equal(t, TagGroupModeAny, taxonomy.Groups[1].Mode)
+	assert.Equal(t, "Year: select exactly one of 2023, 2024, 2025", taxonomy.Groups[0].Rule())
+
+	invalidPath := filepath.Join(dir, "invalid.json")
+	require.NoError(t, os.WriteFile(invalidPath, []byte(`{"groups": [{"name": "Year", "tags": ["2024"], "mode": "one"}]}`), 0644))
+	_, err = loadTagTaxonomy(invalidPath)
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(invalidPath, []byte(`{"groups": [{"name": "Year", "tags": ["2024"], "default": "2020"}]}`), 0644))
+	_, err = loadTagTaxonomy(invalidPath)
+	assert.Error(t, err)
+}
+
+func TestTagTaxonomyApply(t *testing.T) {
+	logger := logrus.WithField("test", "taxonomy")
+	taxonomy := &TagTaxonomy{Groups: []TagGroup{
+		{Name: "Year", Tags: []string{"2023", "2024", "2025"}, Mode: TagGroupModeExactlyOne, Default: "2025"},
+		{Name: "Type", Tags: []string{"invoice", "receipt"}, Mode: TagGroupModeAtMostOne, Parent: "finance"},
+	}}
+
+	t.Run("keeps the tag the LLM mentioned first", func(t *testing.T) {
+		tags := taxonomy.Apply([]string{"2023", "2024", "invoice"}, []string{"2024", "invoice"}, logger)
+		assert.Equal(t, []string{"2024", "finance", "invoice"}, tags)
+	})
+
+	t.Run("adds the default tag of exactly one groups", func(t *testing.T) {
+		tags := taxonomy.Apply([]string{"car"}, []string{"car"}, logger)
+		assert.Equal(t, []string{"2025", "car"}, tags)
+	})
+
+	t.Run("at most one group", func(t *testing.T) {
+		tags := taxonomy.Apply([]string{"2024", "invoice", "receipt"}, []string{"receipt", "invoice"}, logger)
+		assert.Equal(t, []string{"2024", "finance", "receipt"}, tags)
+	})
+
+	t.Run("nil taxonomy", func(t *testing.T) {
+		var noTaxonomy *TagTaxonomy
+		assert.Equal(t, []string{"b", "a"}, noTaxonomy.Apply([]string{"b", "a"}, nil, logger))
+	})
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bytes"
+	"context"
 	"fmt"
 	"text/template"
 
@@ -10,7 +10,8 @@ import (
 
 // getAvailableTokensForContent calculates how many tokens are available for content
 // by rendering the template with empty content and counting tokens
-func getAvailableTokensForContent(tmpl *template.Template, data map[string]interface{}) (int, error) {
+func getAvailableTokensForContent(ctx context.Context, tmpl *template.Template, data map[string]interface{}) (int, error) {
+	tokenLimit := settingsFor(ctx).TokenLimit
 	if tokenLimit <= 0 {
 		return -1, nil // No limit when disabled
 	}
@@ -23,13 +24,13 @@ func getAvailableTokensForContent(tmpl *template.Template, data map[string]inter
 	templateData["Content"] = ""
 
 	// Execute template with empty content
-	var promptBuffer bytes.Buffer
-	if err := tmpl.Execute(&promptBuffer, templateData); err != nil {
+	prompt, err := renderPrompt(tmpl, templateData)
+	if err != nil {
 		return 0, fmt.Errorf("error executing template: %v", err)
 	}
 
-	// Count tokens in prompt template
-	promptTokens, err := getTokenCount(promptBuffer.String())
+	// Count tokens in all messages of the prompt template
+	promptTokens, err := getTokenCount(ctx, prompt.text())
 	if err != nil {
 		return 0, fmt.Errorf("error counting tokens in prompt: %v", err)
 	}
@@ -46,18 +47,18 @@ func getAvailableTokensForContent(tmpl *template.Template, data map[string]inter
 	return availableTokens, nil
 }
 
-func getTokenCount(content string) (int, error) {
-	return llms.CountTokens(llmModel, content), nil
+func getTokenCount(ctx context.Context, content string) (int, error) {
+	return llms.CountTokens(settingsFor(ctx).LLMModel, content), nil
 }
 
 // truncateContentByTokens truncates the content so that its token count does not exceed availableTokens.
 // This implementation uses a binary search on runes to find the longest prefix whose token count is within the limit.
 // If availableTokens is 0 or negative, the original content is returned.
-func truncateContentByTokens(content string, availableTokens int) (string, error) {
-	if availableTokens < 0 || tokenLimit <= 0 {
+func truncateContentByTokens(ctx context.Context, content string, availableTokens int) (string, error) {
+	if availableTokens < 0 || settingsFor(ctx).TokenLimit <= 0 {
 		return content, nil
 	}
-	totalTokens, err := getTokenCount(content)
+	totalTokens, err := getTokenCount(ctx, content)
 	if err != nil {
 		return "", fmt.Errorf("error counting tokens: %v", err)
 	}
@@ -65,30 +66,16 @@ func truncateContentByTokens(content string, availableTokens int) (string, error
 		return content, nil
 	}
 
-	// Convert content to runes for safe slicing.
+	// Binary search on runes for safe slicing
 	runes := []rune(content)
-	low := 0
-	high := len(runes)
-	validCut := 0
-
-	for low <= high {
-		mid := (low + high) / 2
-		substr := string(runes[:mid])
-		count, err := getTokenCount(substr)
-		if err != nil {
-			return "", fmt.Errorf("error counting tokens in substring: %v", err)
-		}
-		if count <= availableTokens {
-			validCut = mid
-			low = mid + 1
-		} else {
-			high = mid - 1
-		}
+	validCut, err := tokenPrefixLength(ctx, runes, availableTokens)
+	if err != nil {
+		return "", err
 	}
 
 	truncated := string(runes[:validCut])
 	// Final verification
-	finalTokens, err := getTokenCount(truncated)
+	finalTokens, err := getTokenCount(ctx, truncated)
 	if err != nil {
 		return "", fmt.Errorf("error counting tokens in final truncated content: %v", err)
 	}
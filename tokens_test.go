@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"strconv"
@@ -13,16 +14,19 @@ import (
 	"github.com/tmc/langchaingo/textsplitter"
 )
 
-// resetTokenLimit parses TOKEN_LIMIT from environment and sets the tokenLimit variable
-func resetTokenLimit() {
-	// Reset tokenLimit
-	tokenLimit = 0
-	// Parse from environment
-	if limit := os.Getenv("TOKEN_LIMIT"); limit != "" {
-		if parsed, err := strconv.Atoi(limit); err == nil {
-			tokenLimit = parsed
+// resetTokenLimit parses TOKEN_LIMIT from environment and sets the token limit of the settings until the test ends
+func resetTokenLimit(t testing.TB) {
+	t.Helper()
+	setSettings(t, func(settings *runtimeSettings) {
+		// Reset the token limit
+		settings.TokenLimit = 0
+		// Parse from environment
+		if limit := os.Getenv("TOKEN_LIMIT"); limit != "" {
+			if parsed, err := strconv.Atoi(limit); err == nil {
+				settings.TokenLimit = parsed
+			}
 		}
-	}
+	})
 }
 
 func TestTokenLimit(t *testing.T) {
@@ -63,9 +67,9 @@ func TestTokenLimit(t *testing.T) {
 			os.Setenv("TOKEN_LIMIT", tc.envValue)
 
 			// Set tokenLimit based on environment
-			resetTokenLimit()
+			resetTokenLimit(t)
 
-			assert.Equal(t, tc.wantLimit, tokenLimit)
+			assert.Equal(t, tc.wantLimit, loadSettings().TokenLimit)
 		})
 	}
 }
@@ -117,9 +121,9 @@ func TestGetAvailableTokensForContent(t *testing.T) {
 			// Set token limit
 			os.Setenv("TOKEN_LIMIT", fmt.Sprintf("%d", tc.limit))
 			// Set tokenLimit based on environment
-			resetTokenLimit()
+			resetTokenLimit(t)
 
-			count, err := getAvailableTokensForContent(tmpl, tc.data)
+			count, err := getAvailableTokensForContent(context.Background(), tmpl, tc.data)
 
 			if tc.wantErr {
 				assert.Error(t, err)
@@ -139,7 +143,7 @@ func TestTruncateContentByTokens(t *testing.T) {
 	// Set a token limit for testing
 	os.Setenv("TOKEN_LIMIT", "100")
 	// Set tokenLimit based on environment
-	resetTokenLimit()
+	resetTokenLimit(t)
 
 	tests := []struct {
 		name            string
@@ -187,7 +191,7 @@ func TestTruncateContentByTokens(t *testing.T) {
 
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := truncateContentByTokens(tc.content, tc.availableTokens)
+			result, err := truncateContentByTokens(context.Background(), tc.content, tc.availableTokens)
 
 			if tc.wantErr {
 				require.Error(t, err)
@@ -266,10 +270,10 @@ Content: {{.Content}}
 			// Set token limit
 			os.Setenv("TOKEN_LIMIT", fmt.Sprintf("%d", tc.limit))
 			// Set tokenLimit based on environment
-			resetTokenLimit()
+			resetTokenLimit(t)
 
 			// First get available tokens
-			availableTokens, err := getAvailableTokensForContent(tmpl, data)
+			availableTokens, err := getAvailableTokensForContent(context.Background(), tmpl, data)
 			if tc.wantError {
 				require.Error(t, err)
 				return
@@ -277,7 +281,7 @@ Content: {{.Content}}
 			require.NoError(t, err)
 
 			// Then truncate content
-			truncated, err := truncateContentByTokens(tc.content, availableTokens)
+			truncated, err := truncateContentByTokens(context.Background(), tc.content, availableTokens)
 			require.NoError(t, err)
 
 			// Finally execute template with truncated content
@@ -1,10 +1,12 @@
 package main
 
+import "encoding/json"
+
 // GetDocumentsApiResponse is the response payload for /documents endpoint.
 // But we are only interested in a subset of the fields.
 type GetDocumentsApiResponse struct {
-	Count int `json:"count"`
-	// Next     interface{} `json:"next"`
+	Count int    `json:"count"`
+	Next  string `json:"next"`
 	// Previous interface{} `json:"previous"`
 	All     []int                          `json:"all"`
 	Results []GetDocumentApiResponseResult `json:"results"`
@@ -13,62 +15,85 @@ type GetDocumentsApiResponse struct {
 // GetDocumentApiResponseResult is a part of the response payload for /documents endpoint.
 // But we are only interested in a subset of the fields.
 type GetDocumentApiResponseResult struct {
-	ID            int `json:"id"`
-	Correspondent int `json:"correspondent"`
-	// DocumentType        interface{}   `json:"document_type"`
-	// StoragePath         interface{}   `json:"storage_path"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Tags    []int  `json:"tags"`
+	ID            int    `json:"id"`
+	Correspondent int    `json:"correspondent"`
+	DocumentType  int    `json:"document_type"`
+	StoragePath   int    `json:"storage_path"`
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	Tags          []int  `json:"tags"`
 	// Created             time.Time     `json:"created"`
 	CreatedDate string `json:"created_date"`
 	// Modified            time.Time     `json:"modified"`
-	// Added               time.Time     `json:"added"`
-	// ArchiveSerialNumber interface{}   `json:"archive_serial_number"`
-	// OriginalFileName    string        `json:"original_file_name"`
+	Added               string `json:"added"` // Date and time the document was added to paperless-ngx
+	ArchiveSerialNumber *int   `json:"archive_serial_number"`
+	OriginalFileName    string `json:"original_file_name"`
+	PageCount           *int   `json:"page_count"` // Null if paperless-ngx didn't count the pages
 	// ArchivedFileName    string        `json:"archived_file_name"`
-	// Owner               int           `json:"owner"`
 	// UserCanChange       bool          `json:"user_can_change"`
-	Notes []interface{} `json:"notes"`
-	// SearchHit struct {
-	// 	Score          float64 `json:"score"`
-	// 	Highlights     string  `json:"highlights"`
-	// 	NoteHighlights string  `json:"note_highlights"`
-	// 	Rank           int     `json:"rank"`
-	// } `json:"__search_hit__"`
+	Owner        *int                 `json:"owner"`
+	Permissions  *DocumentPermissions `json:"permissions"`
+	Notes        []interface{}        `json:"notes"`
+	CustomFields []CustomFieldValue   `json:"custom_fields"`
+	SearchHit    *SearchHit           `json:"__search_hit__,omitempty"` // Only set for full-text searches
+}
+
+// SearchHit describes how a document matched a full-text search of paperless-ngx
+type SearchHit struct {
+	Score          float64 `json:"score"`
+	Highlights     string  `json:"highlights"`      // HTML snippets of the content with the matches in <span class="match">
+	NoteHighlights string  `json:"note_highlights"` // HTML snippets of the notes with the matches
+	Rank           int     `json:"rank"`
 }
 
 // GetDocumentApiResponse is the response payload for /documents/{id} endpoint.
 // But we are only interested in a subset of the fields.
 type GetDocumentApiResponse struct {
-	ID            int `json:"id"`
-	Correspondent int `json:"correspondent"`
-	// DocumentType        interface{}   `json:"document_type"`
-	// StoragePath         interface{}   `json:"storage_path"`
-	Title   string `json:"title"`
-	Content string `json:"content"`
-	Tags    []int  `json:"tags"`
+	ID            int    `json:"id"`
+	Correspondent int    `json:"correspondent"`
+	DocumentType  int    `json:"document_type"`
+	StoragePath   int    `json:"storage_path"`
+	Title         string `json:"title"`
+	Content       string `json:"content"`
+	Tags          []int  `json:"tags"`
 	// Created             time.Time     `json:"created"`
 	CreatedDate string `json:"created_date"`
 	// Modified            time.Time     `json:"modified"`
-	// Added               time.Time     `json:"added"`
-	// ArchiveSerialNumber interface{}   `json:"archive_serial_number"`
-	// OriginalFileName    string        `json:"original_file_name"`
+	Added               string `json:"added"` // Date and time the document was added to paperless-ngx
+	ArchiveSerialNumber *int   `json:"archive_serial_number"`
+	OriginalFileName    string `json:"original_file_name"`
+	PageCount           *int   `json:"page_count"` // Null if paperless-ngx didn't count the pages
 	// ArchivedFileName    string        `json:"archived_file_name"`
-	// Owner         int           `json:"owner"`
 	// UserCanChange bool          `json:"user_can_change"`
-	Notes []interface{} `json:"notes"`
+	Owner        *int                 `json:"owner"`
+	Permissions  *DocumentPermissions `json:"permissions"`
+	Notes        []interface{}        `json:"notes"`
+	CustomFields []CustomFieldValue   `json:"custom_fields"`
 }
 
 // Document is a stripped down version of the document object from paperless-ngx.
 // Response payload for /documents endpoint and part of request payload for /generate-suggestions endpoint
 type Document struct {
-	ID            int      `json:"id"`
-	Title         string   `json:"title"`
-	Content       string   `json:"content"`
-	Tags          []string `json:"tags"`
-	Correspondent string   `json:"correspondent"`
-	CreatedDate   string   `json:"created_date"`
+	ID            int                `json:"id"`
+	Title         string             `json:"title"`
+	Content       string             `json:"content"`
+	Tags          []string           `json:"tags"`
+	Correspondent string             `json:"correspondent"`
+	CreatedDate   string             `json:"created_date"`
+	CustomFields  []CustomFieldValue `json:"custom_fields,omitempty"`
+	DocumentType  string             `json:"document_type,omitempty"`
+	StoragePath   string             `json:"storage_path,omitempty"`
+	// OriginalFileName is the name of the uploaded file, paperless-ngx uses it as title of new documents
+	OriginalFileName string `json:"original_file_name,omitempty"`
+	// Added is the date and time the document was added to paperless-ngx
+	Added string `json:"added,omitempty"`
+	// PageCount is 0 if paperless-ngx doesn't know the number of pages
+	PageCount int `json:"page_count,omitempty"`
+	// ArchiveSerialNumber is nil for documents without ASN
+	ArchiveSerialNumber *int `json:"archive_serial_number,omitempty"`
+	// Owner and Permissions are only known when the document was fetched with full permissions
+	Owner       *int                 `json:"owner,omitempty"`
+	Permissions *DocumentPermissions `json:"permissions,omitempty"`
 }
 
 // GenerateSuggestionsRequest is the request payload for generating suggestions for /generate-suggestions endpoint
@@ -78,18 +103,76 @@ type GenerateSuggestionsRequest struct {
 	GenerateTags           bool       `json:"generate_tags,omitempty"`
 	GenerateCorrespondents bool       `json:"generate_correspondents,omitempty"`
 	GenerateCreatedDate    bool       `json:"generate_created_date,omitempty"`
+	GenerateCustomFields   bool       `json:"generate_custom_fields,omitempty"`
+	DetectLanguage         bool       `json:"detect_language,omitempty"`
+	GenerateSummary        bool       `json:"generate_summary,omitempty"`
+	GenerateDueDate        bool       `json:"generate_due_date,omitempty"`
+	GenerateAmount         bool       `json:"generate_amount,omitempty"`
+	ScanPII                bool       `json:"scan_pii,omitempty"`
+	ClassifySensitivity    bool       `json:"classify_sensitivity,omitempty"`
+	Translate              bool       `json:"translate,omitempty"`
+	Triage                 bool       `json:"triage,omitempty"`
+	LinkRelated            bool       `json:"link_related,omitempty"`
+}
+
+// GenerateSuggestionsBatchRequest is the request payload for the /generate-suggestions/batch endpoint.
+// The documents are selected by the filters instead of listing them, the options are those of GenerateSuggestionsRequest.
+type GenerateSuggestionsBatchRequest struct {
+	Tag           string `json:"tag,omitempty"`
+	Correspondent string `json:"correspondent,omitempty"`
+	SavedView     string `json:"saved_view,omitempty"`     // Name or ID of a saved view
+	Query         string `json:"query,omitempty"`          // paperless-ngx filter query
+	CreatedAfter  string `json:"created_after,omitempty"`  // Earliest created date, YYYY-MM-DD
+	CreatedBefore string `json:"created_before,omitempty"` // Latest created date, YYYY-MM-DD
+	GenerateSuggestionsRequest
+}
+
+// FeedbackRequest is the request payload for the /feedback endpoint
+type FeedbackRequest struct {
+	DocumentID int             `json:"document_id"`
+	Fields     []FieldFeedback `json:"fields"`
+}
+
+// ModificationIDsRequest is the request payload for the POST /api/modifications/archive and
+// POST /api/modifications/restore endpoints
+type ModificationIDsRequest struct {
+	IDs []uint `json:"ids"`
+}
+
+// FieldFeedback is the outcome of a suggested value. Suggested tags are reported one by one, with the field tag.
+type FieldFeedback struct {
+	Field     string `json:"field"`           // title, tag or correspondent
+	Outcome   string `json:"outcome"`         // accepted, edited or rejected
+	Suggested string `json:"suggested"`       // Value that was suggested
+	Final     string `json:"final,omitempty"` // Value the user kept, defaults to the suggested value when accepted
 }
 
 // DocumentSuggestion is the response payload for /generate-suggestions endpoint and the request payload for /update-documents endpoint (as an array)
 type DocumentSuggestion struct {
-	ID                     int      `json:"id"`
-	OriginalDocument       Document `json:"original_document"`
-	SuggestedTitle         string   `json:"suggested_title,omitempty"`
-	SuggestedTags          []string `json:"suggested_tags,omitempty"`
-	SuggestedContent       string   `json:"suggested_content,omitempty"`
-	SuggestedCorrespondent string   `json:"suggested_correspondent,omitempty"`
-	SuggestedCreatedDate   string   `json:"suggested_created_date,omitempty"`
-	RemoveTags             []string `json:"remove_tags,omitempty"`
+	ID                            int                `json:"id"`
+	OriginalDocument              Document           `json:"original_document"`
+	SuggestedTitle                string             `json:"suggested_title,omitempty"`
+	SuggestedTags                 []string           `json:"suggested_tags,omitempty"`
+	SuggestedNewTags              []string           `json:"suggested_new_tags,omitempty"`
+	SuggestedContent              string             `json:"suggested_content,omitempty"`
+	SuggestedCorrespondent        string             `json:"suggested_correspondent,omitempty"`
+	SuggestedStoragePath          string             `json:"suggested_storage_path,omitempty"`
+	SuggestedASN                  int                `json:"suggested_asn,omitempty"`
+	SuggestedCreatedDate          string             `json:"suggested_created_date,omitempty"`
+	SuggestedCustomFields         []CustomFieldValue `json:"suggested_custom_fields,omitempty"`
+	SuggestedLanguage             string             `json:"suggested_language,omitempty"`
+	SuggestedSummary              string             `json:"suggested_summary,omitempty"`
+	SuggestedDueDate              string             `json:"suggested_due_date,omitempty"`
+	SuggestedCancellationDeadline string             `json:"suggested_cancellation_deadline,omitempty"`
+	SuggestedPIICategories        []string           `json:"suggested_pii_categories,omitempty"`
+	SuggestedSensitivity          string             `json:"suggested_sensitivity,omitempty"`
+	SuggestedTranslation          string             `json:"suggested_translation,omitempty"`
+	SuggestedAction               string             `json:"suggested_action,omitempty"`
+	SuggestedActionDeadline       string             `json:"suggested_action_deadline,omitempty"`
+	SuggestedRelatedDocuments     []RelatedDocument  `json:"suggested_related_documents,omitempty"`
+	RemoveTags                    []string           `json:"remove_tags,omitempty"`
+	RemoveCorrespondent           bool               `json:"remove_correspondent,omitempty"` // Unset the correspondent, e.g. to undo setting the first one
+	Confidence                    map[string]float64 `json:"confidence,omitempty"`           // Confidence of the LLM in the fields, from 0 to 1 (AUTO_APPLY_CONFIDENCE)
 }
 
 type Correspondent struct {
@@ -109,3 +192,123 @@ type Correspondent struct {
 		} `json:"change"`
 	} `json:"set_permissions"`
 }
+
+// DocumentPermissions are the users and groups allowed to view and change a document.
+// paperless-ngx returns them as "permissions" and expects them as "set_permissions" on updates.
+type DocumentPermissions struct {
+	View   PermissionSet `json:"view"`
+	Change PermissionSet `json:"change"`
+}
+
+// PermissionSet is a list of users and groups that were granted a permission
+type PermissionSet struct {
+	Users  []int `json:"users"`
+	Groups []int `json:"groups"`
+}
+
+// StoragePath is a storage path from paperless-ngx.
+// Path is the template paperless-ngx uses to build the file name, e.g. "{{ correspondent }}/{{ created_year }}/{{ title }}".
+type StoragePath struct {
+	ID                int    `json:"id"`
+	Name              string `json:"name"`
+	Path              string `json:"path"`
+	Match             string `json:"match"`
+	MatchingAlgorithm int    `json:"matching_algorithm"`
+	IsInsensitive     bool   `json:"is_insensitive"`
+}
+
+// SavedView is a saved view from paperless-ngx, only the filter rules are used to select documents
+type SavedView struct {
+	ID          int                   `json:"id"`
+	Name        string                `json:"name"`
+	FilterRules []SavedViewFilterRule `json:"filter_rules"`
+}
+
+// SavedViewFilterRule is a single filter rule of a saved view. A nil value means "not assigned".
+type SavedViewFilterRule struct {
+	RuleType int     `json:"rule_type"`
+	Value    *string `json:"value"`
+}
+
+// CustomFieldValue is the value of a single custom field attached to a document.
+// The Value type depends on the data type of the referenced custom field.
+type CustomFieldValue struct {
+	Field int         `json:"field"`
+	Value interface{} `json:"value"`
+}
+
+// CustomField is a custom field definition from paperless-ngx
+type CustomField struct {
+	ID        int                  `json:"id"`
+	Name      string               `json:"name"`
+	DataType  string               `json:"data_type"`
+	ExtraData CustomFieldExtraData `json:"extra_data"`
+}
+
+// CustomFieldExtraData holds the data type specific settings of a custom field
+type CustomFieldExtraData struct {
+	SelectOptions   []CustomFieldSelectOption `json:"select_options,omitempty"`
+	DefaultCurrency string                    `json:"default_currency,omitempty"`
+}
+
+// CustomFieldSelectOption is a single option of a select custom field.
+// Older paperless-ngx versions store options as plain strings, newer ones as {id, label} objects.
+type CustomFieldSelectOption struct {
+	ID    string `json:"id,omitempty"`
+	Label string `json:"label"`
+}
+
+// UnmarshalJSON accepts both the plain string and the object representation of a select option
+func (o *CustomFieldSelectOption) UnmarshalJSON(data []byte) error {
+	var label string
+	if err := json.Unmarshal(data, &label); err == nil {
+		o.ID = ""
+		o.Label = label
+		return nil
+	}
+
+	var option struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+	}
+	if err := json.Unmarshal(data, &option); err != nil {
+		return err
+	}
+	o.ID = option.ID
+	o.Label = option.Label
+	return nil
+}
+
+// Note is a note attached to a document in paperless-ngx
+type Note struct {
+	ID      int    `json:"id"`
+	Note    string `json:"note"`
+	Created string `json:"created"`
+}
+
+// RelatedDocument is a document found to be related to another one, e.g. the payment reminder for an invoice
+type RelatedDocument struct {
+	ID     int    `json:"id"`
+	Title  string `json:"title"`
+	Reason string `json:"reason"`
+}
+
+// CorrespondentRef identifies a correspondent by ID and name
+type CorrespondentRef struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// CorrespondentMergeSuggestion is a group of correspondents that refer to the same person or organization.
+// Response payload for the GET /api/correspondents/dedupe endpoint (as an array).
+type CorrespondentMergeSuggestion struct {
+	Canonical  CorrespondentRef   `json:"canonical"`
+	Duplicates []CorrespondentRef `json:"duplicates"`
+}
+
+// CorrespondentMergeRequest is the request payload for the POST /api/correspondents/dedupe endpoint
+type CorrespondentMergeRequest struct {
+	CanonicalID      int   `json:"canonical_id"`
+	DuplicateIDs     []int `json:"duplicate_ids"`
+	DeleteDuplicates bool  `json:"delete_duplicates,omitempty"`
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errUnsupportedModification is returned for modifications of fields that can't be undone
+var errUnsupportedModification = errors.New("invalid modification field")
+
+// errModifiedDocumentDeleted is returned when undoing or redoing a modification of a document deleted in paperless-ngx
+var errModifiedDocumentDeleted = errors.New("document was deleted in paperless-ngx")
+
+// applyModificationValues undoes or redoes modifications of a document with a single update of the document: the
+// previous values are restored to undo them, the new values to redo them. The modifications recorded by the update
+// refer to the ones they undo or redo.
+func (app *App) applyModificationValues(ctx context.Context, operation string, modifications []ModificationHistory) error {
+	documentID := int(modifications[0].DocumentID)
+	original, err := app.Client.GetDocument(ctx, documentID)
+	if app.skipDeletedDocument(documentID, err) {
+		return errModifiedDocumentDeleted
+	}
+	if err != nil {
+		return fmt.Errorf("failed to retrieve original document: %w", err)
+	}
+
+	suggestion := DocumentSuggestion{ID: documentID, OriginalDocument: original}
+	for _, modification := range modifications {
+		value, err := rebasedModificationValue(modification, operation, original)
+		if err != nil {
+			return fmt.Errorf("modification %d: %w", modification.ID, err)
+		}
+		if err := setModificationValue(&suggestion, modification.ModField, value); err != nil {
+			return fmt.Errorf("modification %d: %w", modification.ID, err)
+		}
+	}
+
+	ctx = withModificationOrigins(ctx, operation, modifications)
+	if err := app.Client.UpdateDocuments(ctx, []DocumentSuggestion{suggestion}, app.Database, true); err != nil {
+		return fmt.Errorf("failed to update document %d: %w", documentID, err)
+	}
+	return nil
+}
+
+// setModificationValue sets a value of a modified field as suggestion. The modifications of a batch are set in the
+// same suggestion, in any order.
+func setModificationValue(suggestion *DocumentSuggestion, field string, value string) error {
+	switch field {
+	case "title":
+		suggestion.SuggestedTitle = value
+	case "tags":
+		var tags []string
+		if err := json.Unmarshal([]byte(value), &tags); err != nil {
+			return fmt.Errorf("failed to unmarshal tags: %w", err)
+		}
+		suggestion.SuggestedTags = tags
+	case "correspondent":
+		suggestion.SuggestedCorrespondent = value
+		suggestion.RemoveCorrespondent = value == ""
+	case "created_date":
+		suggestion.SuggestedCreatedDate = value
+	case "content":
+		suggestion.SuggestedContent = value
+	case "custom_fields":
+		customFields := []CustomFieldValue{}
+		if err := json.Unmarshal([]byte(value), &customFields); err != nil {
+			return fmt.Errorf("failed to unmarshal custom fields: %w", err)
+		}
+		suggestion.SuggestedCustomFields = customFields
+	case "sensitivity":
+		// The tags of the batch already carry the level, which is kept if they were set first
+		tags := suggestion.SuggestedTags
+		if tags == nil {
+			tags = suggestion.OriginalDocument.Tags
+		}
+		suggestion.SuggestedTags = withSensitivityLevel(tags, value)
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedModification, field)
+	}
+	return nil
+}
+
+// modificationOriginsKey is the context key of the modifications an update undoes or redoes
+type modificationOriginsKey struct{}
+
+// modificationOrigins are the modifications an update undoes or redoes, by field
+type modificationOrigins struct {
+	operation string
+	ids       map[string]uint
+}
+
+// withModificationOrigins marks the update done with the context as undoing or redoing the modifications
+func withModificationOrigins(ctx context.Context, operation string, modifications []ModificationHistory) context.Context {
+	origins := modificationOrigins{operation: operation, ids: make(map[string]uint)}
+	for _, modification := range modifications {
+		origins.ids[modification.ModField] = modification.ID
+	}
+	// The sensitivity level is a tag, so restoring it is recorded as a change of the tags
+	if id, ok := origins.ids["sensitivity"]; ok {
+		if _, ok := origins.ids["tags"]; !ok {
+			origins.ids["tags"] = id
+		}
+	}
+	return context.WithValue(ctx, modificationOriginsKey{}, origins)
+}
+
+// reversesModifications returns whether the update done with the context undoes or redoes modifications
+func reversesModifications(ctx context.Context) bool {
+	_, ok := ctx.Value(modificationOriginsKey{}).(modificationOrigins)
+	return ok
+}
+
+// modificationOrigin returns whether the change of a field undoes or redoes a modification and which one, empty and
+// zero for changes
+func modificationOrigin(ctx context.Context, field string) (string, uint) {
+	origins, ok := ctx.Value(modificationOriginsKey{}).(modificationOrigins)
+	if !ok {
+		return "", 0
+	}
+	if id, ok := origins.ids[field]; ok {
+		return origins.operation, id
+	}
+	return "", 0
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetModificationValue(t *testing.T) {
+	originalLevels := sensitivityLevels
+	defer func() { sensitivityLevels = originalLevels }()
+	sensitivityLevels = []string{"public", "confidential"}
+	original := Document{Tags: []string{"bills", sensitivityTag("confidential")}}
+
+	tests := []struct {
+		name          string
+		modifications []ModificationHistory
+		want          DocumentSuggestion
+		wantErr       bool
+	}{
+		{
+			name:          "title",
+			modifications: []ModificationHistory{{ModField: "title", PreviousValue: "scan.pdf"}},
+			want:          DocumentSuggestion{SuggestedTitle: "scan.pdf"},
+		},
+		{
+			name:          "correspondent",
+			modifications: []ModificationHistory{{ModField: "correspondent", PreviousValue: "ACME"}},
+			want:          DocumentSuggestion{SuggestedCorrespondent: "ACME"},
+		},
+		{
+			name:          "first correspondent",
+			modifications: []ModificationHistory{{ModField: "correspondent", PreviousValue: ""}},
+			want:          DocumentSuggestion{RemoveCorrespondent: true},
+		},
+		{
+			name:          "created date",
+			modifications: []ModificationHistory{{ModField: "created_date", PreviousValue: "2024-05-01"}},
+			want:          DocumentSuggestion{SuggestedCreatedDate: "2024-05-01"},
+		},
+		{
+			name:          "sensitivity",
+			modifications: []ModificationHistory{{ModField: "sensitivity", PreviousValue: "public"}},
+			want:          DocumentSuggestion{SuggestedTags: []string{"bills", sensitivityTag("public")}},
+		},
+		{
+			name: "sensitivity after the tags of the batch",
+			modifications: []ModificationHistory{
+				{ModField: "tags", PreviousValue: `["inbox","public"]`},
+				{ModField: "sensitivity", PreviousValue: "public"},
+			},
+			want: DocumentSuggestion{SuggestedTags: []string{"inbox", sensitivityTag("public")}},
+		},
+		{
+			name:          "invalid tags",
+			modifications: []ModificationHistory{{ModField: "tags", PreviousValue: "bills"}},
+			wantErr:       true,
+		},
+		{
+			name:          "unsupported field",
+			modifications: []ModificationHistory{{ModField: "notes", PreviousValue: "note"}},
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			suggestion := DocumentSuggestion{OriginalDocument: original}
+			var err error
+			for _, modification := range tt.modifications {
+				if err = setModificationValue(&suggestion, modification.ModField, modification.PreviousValue); err != nil {
+					break
+				}
+			}
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			tt.want.OriginalDocument = original
+			assert.Equal(t, tt.want, suggestion)
+		})
+	}
+}
+
+func TestUndoBatchHandler(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	require.NoError(t, env.db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer env.db.Where("1 = 1").Delete(&ModificationHistory{})
+	for _, modification := range []ModificationHistory{
+		{DocumentID: 7, BatchID: "batch-1", ModField: "title", PreviousValue: "scan.pdf", NewValue: "Electricity bill"},
+		{DocumentID: 7, BatchID: "batch-1", ModField: "tags", PreviousValue: `["inbox"]`, NewValue: `["bills"]`},
+		{DocumentID: 7, BatchID: "batch-1", ModField: "correspondent", PreviousValue: "", NewValue: "ACME"},
+		{DocumentID: 7, BatchID: "batch-1", ModField: "created_date", PreviousValue: "2024-05-01", NewValue: "2024-05-02"},
+		{DocumentID: 7, BatchID: "batch-1", ModField: "content", PreviousValue: "old", NewValue: "new", Undone: true},
+		{DocumentID: 7, BatchID: "batch-2", ModField: "title", PreviousValue: "Bill", NewValue: "scan.pdf"},
+	} {
+		require.NoError(t, InsertModification(env.db, &modification))
+	}
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "bills"}, {"id": 2, "name": "inbox"}], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [{"id": 1, "name": "ACME"}], "next": null}`))
+	})
+	var patched map[string]any
+	env.setMockResponse("/api/documents/7/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &patched))
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 7, "title": "Electricity bill", "content": "new", "tags": [1], "correspondent": 1, "created_date": "2024-05-02"}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/undo-batch/:id", app.undoBatchHandler)
+	undo := func(batchID string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", "/api/undo-batch/"+batchID, nil))
+		return w
+	}
+
+	// All fields are reverted with one update, the content was undone before
+	w := undo("batch-1")
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"undone": 4}`, w.Body.String())
+	assert.Equal(t, map[string]any{
+		"title":         "scan.pdf",
+		"tags":          []any{float64(2)},
+		"correspondent": nil,
+		"created_date":  "2024-05-01",
+	}, patched)
+
+	batch, err := GetBatchModifications(env.db, "batch-1")
+	require.NoError(t, err)
+	for _, modification := range batch {
+		assert.True(t, modification.Undone, modification.ModField)
+	}
+	other, err := GetBatchModifications(env.db, "batch-2")
+	require.NoError(t, err)
+	require.Len(t, other, 1)
+	assert.False(t, other[0].Undone, "other batches are kept")
+
+	// The undo is recorded as a batch of its own
+	correspondents, _, err := GetPaginatedModifications(env.db, 1, 10, ModificationFilter{Field: "correspondent"})
+	require.NoError(t, err)
+	require.Len(t, correspondents, 2)
+	for _, modification := range correspondents {
+		if modification.BatchID != "batch-1" {
+			assert.Equal(t, "ACME", modification.PreviousValue)
+			assert.Empty(t, modification.NewValue)
+			assert.NotEmpty(t, modification.BatchID)
+			assert.Equal(t, ModificationOperationUndo, modification.Operation)
+			assert.Equal(t, batch[2].ID, modification.OriginID, "the undo refers to the undone modification")
+		}
+	}
+
+	assert.Equal(t, http.StatusBadRequest, undo("batch-1").Code, "already undone")
+	assert.Equal(t, http.StatusNotFound, undo("unknown").Code)
+}
+
+func TestRedoModificationHandler(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.teardown()
+
+	require.NoError(t, env.db.Where("1 = 1").Delete(&ModificationHistory{}).Error)
+	defer env.db.Where("1 = 1").Delete(&ModificationHistory{})
+	undone := ModificationHistory{DocumentID: 8, ModField: "title", PreviousValue: "scan.pdf", NewValue: "Lease", Undone: true, UndoneDate: "2024-05-02T10:00:00Z"}
+	require.NoError(t, InsertModification(env.db, &undone))
+	applied := ModificationHistory{DocumentID: 8, ModField: "created_date", PreviousValue: "2024-05-01", NewValue: "2024-05-02"}
+	require.NoError(t, InsertModification(env.db, &applied))
+
+	env.setMockResponse("/api/tags/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	env.setMockResponse("/api/correspondents/", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"results": [], "next": null}`))
+	})
+	var patched map[string]any
+	env.setMockResponse("/api/documents/8/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPatch {
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			require.NoError(t, json.Unmarshal(body, &patched))
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": 8, "title": "scan.pdf", "tags": [], "created_date": "2024-05-02"}`))
+	})
+
+	app := &App{Client: env.client, Database: env.db}
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/redo-modification/:id", app.redoModificationHandler)
+	redo := func(id uint) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("POST", fmt.Sprintf("/api/redo-modification/%d", id), nil))
+		return w
+	}
+
+	w := redo(undone.ID)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, map[string]any{"title": "Lease", "tags": []any{}}, patched)
+
+	redone, err := GetModification(env.db, undone.ID)
+	require.NoError(t, err)
+	assert.False(t, redone.Undone, "a redone modification can be undone again")
+	assert.Empty(t, redone.UndoneDate)
+
+	records, _, err := GetPaginatedModifications(env.db, 1, 10, ModificationFilter{Field: "title"})
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	for _, record := range records {
+		if record.ID != undone.ID {
+			assert.Equal(t, ModificationOperationRedo, record.Operation)
+			assert.Equal(t, undone.ID, record.OriginID)
+			assert.Equal(t, "Lease", record.NewValue)
+		}
+	}
+
+	assert.Equal(t, http.StatusBadRequest, redo(undone.ID).Code, "already applied again")
+	assert.Equal(t, http.StatusBadRequest, redo(applied.ID).Code, "never undone")
+	assert.Equal(t, http.StatusNotFound, redo(9999).Code)
+}
@@ -1,13 +1,17 @@
 import React from 'react';
 import { Route, BrowserRouter as Router, Routes } from 'react-router-dom';
 import Sidebar from './components/Sidebar';
+import Dashboard from './Dashboard';
 import DocumentProcessor from './DocumentProcessor';
 import ExperimentalOCR from './ExperimentalOCR'; // New component
 import History from './History';
 
+// The server sets the <base> element to BASE_PATH when serving the web UI under a sub-path
+const basename = new URL(document.querySelector('base')?.href ?? '/', window.location.href).pathname.replace(/\/$/, '');
+
 const App: React.FC = () => {
   return (
-    <Router>
+    <Router basename={basename}>
       <div className="flex h-screen flex-col">
         <div className="flex flex-1 overflow-hidden">
           <Sidebar onSelectPage={(page) => console.log(page)} />
@@ -17,6 +21,7 @@ const App: React.FC = () => {
                 <Route path="/" element={<DocumentProcessor />} />
                 <Route path="/experimental-ocr" element={<ExperimentalOCR />} />
                 <Route path="/history" element={<History />} />
+                <Route path="/dashboard" element={<Dashboard />} />
               </Routes>
             </div>
             <footer className="border-t-2 border-gray-200 bg-blue-50 p-5 text-center text-base text-gray-700 shadow-[0_-2px_10px_rgba(0,0,0,0.05)] dark:border-gray-700 dark:bg-gray-800 dark:text-gray-300 dark:shadow-[0_-2px_10px_rgba(0,0,0,0.2)]">
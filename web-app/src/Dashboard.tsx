@@ -0,0 +1,240 @@
+import React, { useEffect, useState } from 'react';
+
+interface DailyDocuments {
+  date: string;
+  documents: number;
+}
+
+interface TaskStats {
+  task: string;
+  calls: number;
+  failed: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost: number;
+  average_latency_ms: number;
+}
+
+interface UserStats {
+  user: string;
+  calls: number;
+  prompt_tokens: number;
+  completion_tokens: number;
+  cost: number;
+}
+
+interface JobStats {
+  kind: string;
+  completed: number;
+  failed: number;
+  unfinished: number;
+  pages: number;
+}
+
+interface Stats {
+  since: string;
+  documents_per_day: DailyDocuments[];
+  tasks: TaskStats[];
+  users: UserStats[];
+  jobs: JobStats[];
+  totals: {
+    documents_processed: number;
+    llm_calls: number;
+    llm_error_rate: number;
+    prompt_tokens: number;
+    completion_tokens: number;
+    cost: number;
+    average_latency_ms: number;
+    ocr_pages: number;
+    jobs_completed: number;
+    jobs_failed: number;
+    job_error_rate: number;
+    dead_lettered_documents: number;
+  };
+}
+
+const periods = [7, 30, 90];
+
+const formatPercent = (rate: number) => `${(rate * 100).toFixed(1)}%`;
+const formatLatency = (ms: number) => (ms >= 1000 ? `${(ms / 1000).toFixed(1)} s` : `${Math.round(ms)} ms`);
+
+const Dashboard: React.FC = () => {
+  const [days, setDays] = useState(30);
+  const [stats, setStats] = useState<Stats | null>(null);
+  const [error, setError] = useState<string | null>(null);
+
+  useEffect(() => {
+    const fetchStats = async () => {
+      try {
+        const response = await fetch(`api/stats?days=${days}`);
+        if (!response.ok) {
+          throw new Error('Failed to fetch statistics');
+        }
+        setStats(await response.json());
+        setError(null);
+      } catch (err) {
+        setError(err instanceof Error ? err.message : 'Unknown error occurred');
+      }
+    };
+
+    fetchStats();
+  }, [days]);
+
+  if (error) {
+    return (
+      <div className="text-red-500 dark:text-red-400 p-4 text-center">
+        Error: {error}
+      </div>
+    );
+  }
+
+  if (!stats) {
+    return (
+      <div className="flex justify-center items-center min-h-screen">
+        <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-blue-500" />
+      </div>
+    );
+  }
+
+  const { totals } = stats;
+  const cards = [
+    { title: 'Documents processed', value: totals.documents_processed.toLocaleString() },
+    { title: 'LLM requests', value: totals.llm_calls.toLocaleString() },
+    { title: 'Tokens', value: (totals.prompt_tokens + totals.completion_tokens).toLocaleString() },
+    { title: 'Cost', value: totals.cost.toFixed(2) },
+    { title: 'Average latency', value: formatLatency(totals.average_latency_ms) },
+    { title: 'OCR pages', value: totals.ocr_pages.toLocaleString() },
+    { title: 'LLM error rate', value: formatPercent(totals.llm_error_rate) },
+    { title: 'Job error rate', value: formatPercent(totals.job_error_rate) },
+    { title: 'Dead-lettered documents', value: totals.dead_lettered_documents.toLocaleString() },
+  ];
+  const maxDocuments = Math.max(1, ...stats.documents_per_day.map((day) => day.documents));
+
+  return (
+    <div className="container mx-auto px-4 py-8">
+      <div className="mb-6 flex items-center justify-between">
+        <h1 className="text-2xl font-bold text-gray-800 dark:text-gray-200">Dashboard</h1>
+        <div className="flex items-center space-x-2">
+          {periods.map((period) => (
+            <button
+              key={period}
+              onClick={() => setDays(period)}
+              className={`px-3 py-1 rounded-md ${
+                days === period
+                  ? 'bg-blue-500 text-white dark:bg-blue-600'
+                  : 'bg-gray-100 text-gray-600 hover:bg-gray-200 dark:bg-gray-800 dark:text-gray-300'
+              }`}
+            >
+              {period} days
+            </button>
+          ))}
+        </div>
+      </div>
+
+      <div className="mb-8 grid gap-4 sm:grid-cols-2 lg:grid-cols-3">
+        {cards.map((card) => (
+          <div key={card.title} className="rounded-lg bg-white p-4 shadow dark:bg-gray-800">
+            <div className="text-sm text-gray-500 dark:text-gray-400">{card.title}</div>
+            <div className="text-2xl font-semibold text-gray-800 dark:text-gray-200">{card.value}</div>
+          </div>
+        ))}
+      </div>
+
+      <h2 className="mb-3 text-lg font-semibold text-gray-800 dark:text-gray-200">Documents per day</h2>
+      {stats.documents_per_day.length === 0 ? (
+        <p className="mb-8 text-gray-500 dark:text-gray-400">No documents processed yet</p>
+      ) : (
+        <div className="mb-8 flex h-40 items-end space-x-1 rounded-lg bg-white p-4 shadow dark:bg-gray-800">
+          {stats.documents_per_day.map((day) => (
+            <div
+              key={day.date}
+              title={`${day.date}: ${day.documents}`}
+              className="flex-1 rounded-t bg-blue-500 dark:bg-blue-600"
+              style={{ height: `${(day.documents / maxDocuments) * 100}%` }}
+            />
+          ))}
+        </div>
+      )}
+
+      <h2 className="mb-3 text-lg font-semibold text-gray-800 dark:text-gray-200">Tasks</h2>
+      <div className="mb-8 overflow-x-auto rounded-lg bg-white shadow dark:bg-gray-800">
+        <table className="min-w-full text-left text-sm text-gray-700 dark:text-gray-300">
+          <thead className="border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="p-3">Task</th>
+              <th className="p-3">Requests</th>
+              <th className="p-3">Failed</th>
+              <th className="p-3">Tokens</th>
+              <th className="p-3">Cost</th>
+              <th className="p-3">Average latency</th>
+            </tr>
+          </thead>
+          <tbody>
+            {stats.tasks.map((task) => (
+              <tr key={task.task} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="p-3">{task.task}</td>
+                <td className="p-3">{task.calls}</td>
+                <td className="p-3">{task.failed}</td>
+                <td className="p-3">{(task.prompt_tokens + task.completion_tokens).toLocaleString()}</td>
+                <td className="p-3">{task.cost.toFixed(2)}</td>
+                <td className="p-3">{formatLatency(task.average_latency_ms)}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      </div>
+
+      <h2 className="mb-3 text-lg font-semibold text-gray-800 dark:text-gray-200">Users</h2>
+      <div className="mb-8 overflow-x-auto rounded-lg bg-white shadow dark:bg-gray-800">
+        <table className="min-w-full text-left text-sm text-gray-700 dark:text-gray-300">
+          <thead className="border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="p-3">User</th>
+              <th className="p-3">Requests</th>
+              <th className="p-3">Tokens</th>
+              <th className="p-3">Cost</th>
+            </tr>
+          </thead>
+          <tbody>
+            {stats.users.map((user) => (
+              <tr key={user.user} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="p-3">{user.user || 'Background processing'}</td>
+                <td className="p-3">{user.calls}</td>
+                <td className="p-3">{(user.prompt_tokens + user.completion_tokens).toLocaleString()}</td>
+                <td className="p-3">{user.cost.toFixed(2)}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      </div>
+
+      <h2 className="mb-3 text-lg font-semibold text-gray-800 dark:text-gray-200">Jobs</h2>
+      <div className="overflow-x-auto rounded-lg bg-white shadow dark:bg-gray-800">
+        <table className="min-w-full text-left text-sm text-gray-700 dark:text-gray-300">
+          <thead className="border-b border-gray-200 dark:border-gray-700">
+            <tr>
+              <th className="p-3">Kind</th>
+              <th className="p-3">Completed</th>
+              <th className="p-3">Failed</th>
+              <th className="p-3">Unfinished</th>
+              <th className="p-3">Pages</th>
+            </tr>
+          </thead>
+          <tbody>
+            {stats.jobs.map((job) => (
+              <tr key={job.kind} className="border-b border-gray-100 dark:border-gray-700">
+                <td className="p-3">{job.kind}</td>
+                <td className="p-3">{job.completed}</td>
+                <td className="p-3">{job.failed}</td>
+                <td className="p-3">{job.unfinished}</td>
+                <td className="p-3">{job.pages}</td>
+              </tr>
+            ))}
+          </tbody>
+        </table>
+      </div>
+    </div>
+  );
+};
+
+export default Dashboard;
@@ -5,6 +5,7 @@ import DocumentsToProcess from "./components/DocumentsToProcess";
 import NoDocuments from "./components/NoDocuments";
 import SuccessModal from "./components/SuccessModal";
 import SuggestionsReview from "./components/SuggestionsReview";
+import { waitForJob } from "./jobs";
 
 export interface Document {
   id: number;
@@ -32,6 +33,40 @@ export interface DocumentSuggestion {
   suggested_created_date?: string;
 }
 
+export interface FieldFeedback {
+  field: "title" | "tag" | "correspondent";
+  outcome: "accepted" | "edited" | "rejected";
+  suggested: string;
+  final?: string;
+}
+
+// Compares the applied values with the generated suggestions, for the accuracy of future suggestions
+const suggestionFeedback = (
+  generated: DocumentSuggestion,
+  applied: DocumentSuggestion
+): FieldFeedback[] => {
+  const feedback: FieldFeedback[] = [];
+  const compare = (field: "title" | "correspondent", suggested?: string, final?: string) => {
+    if (!suggested) {
+      return;
+    }
+    if (!final) {
+      feedback.push({ field, outcome: "rejected", suggested });
+    } else if (final === suggested) {
+      feedback.push({ field, outcome: "accepted", suggested });
+    } else {
+      feedback.push({ field, outcome: "edited", suggested, final });
+    }
+  };
+  compare("title", generated.suggested_title, applied.suggested_title);
+  compare("correspondent", generated.suggested_correspondent, applied.suggested_correspondent);
+  for (const tag of generated.suggested_tags ?? []) {
+    const kept = applied.suggested_tags?.includes(tag);
+    feedback.push({ field: "tag", outcome: kept ? "accepted" : "rejected", suggested: tag });
+  }
+  return feedback;
+};
+
 export interface TagOption {
   id: string;
   name: string;
@@ -40,6 +75,7 @@ export interface TagOption {
 const DocumentProcessor: React.FC = () => {
   const [documents, setDocuments] = useState<Document[]>([]);
   const [suggestions, setSuggestions] = useState<DocumentSuggestion[]>([]);
+  const [generatedSuggestions, setGeneratedSuggestions] = useState<DocumentSuggestion[]>([]);
   const [availableTags, setAvailableTags] = useState<TagOption[]>([]);
   const [loading, setLoading] = useState(true);
   const [processing, setProcessing] = useState(false);
@@ -56,9 +92,9 @@ const DocumentProcessor: React.FC = () => {
   const fetchInitialData = useCallback(async () => {
     try {
       const [filterTagRes, documentsRes, tagsRes] = await Promise.all([
-        axios.get<{ tag: string }>("/api/filter-tag"),
-        axios.get<Document[]>("/api/documents"),
-        axios.get<Record<string, number>>("/api/tags"),
+        axios.get<{ tag: string }>("api/filter-tag"),
+        axios.get<Document[]>("api/documents"),
+        axios.get<Record<string, number>>("api/tags"),
       ]);
 
       setFilterTag(filterTagRes.data.tag);
@@ -92,11 +128,18 @@ const DocumentProcessor: React.FC = () => {
         generate_created_date: generateCreatedDate,
       };
 
-      const { data } = await axios.post<DocumentSuggestion[]>(
-        "/api/generate-suggestions",
+      // Suggestions are generated by a job, as long runs would time out behind reverse proxies
+      const { data } = await axios.post<{ job_id: string }>(
+        "api/generate-suggestions",
         requestPayload
       );
-      setSuggestions(data);
+      const job = await waitForJob(data.job_id);
+      if (job.status === "failed") {
+        throw new Error(job.error);
+      }
+      const generated = JSON.parse(job.result ?? "[]") as DocumentSuggestion[];
+      setSuggestions(generated);
+      setGeneratedSuggestions(generated);
     } catch (err) {
       console.error("Error generating suggestions:", err);
       setError("Failed to generate suggestions.");
@@ -109,7 +152,8 @@ const DocumentProcessor: React.FC = () => {
     setUpdating(true);
     setError(null);
     try {
-      await axios.patch("/api/update-documents", suggestions);
+      await axios.patch("api/update-documents", suggestions);
+      sendFeedback();
       setIsSuccessModalOpen(true);
       setSuggestions([]);
     } catch (err) {
@@ -120,6 +164,19 @@ const DocumentProcessor: React.FC = () => {
     }
   };
 
+  // Feedback is best effort, failing to send it doesn't affect the update
+  const sendFeedback = () => {
+    for (const applied of suggestions) {
+      const generated = generatedSuggestions.find((doc) => doc.id === applied.id);
+      const fields = generated ? suggestionFeedback(generated, applied) : [];
+      if (fields.length > 0) {
+        axios
+          .post("api/feedback", { document_id: applied.id, fields })
+          .catch((err) => console.error("Error sending feedback:", err));
+      }
+    }
+  };
+
   const handleTagAddition = (docId: number, tag: TagOption) => {
     setSuggestions((prevSuggestions) =>
       prevSuggestions.map((doc) =>
@@ -179,7 +236,7 @@ const DocumentProcessor: React.FC = () => {
     setLoading(true);
     setError(null);
     try {
-      const { data } = await axios.get<Document[]>("/api/documents");
+      const { data } = await axios.get<Document[]>("api/documents");
       setDocuments(data);
     } catch (err) {
       console.error("Error reloading documents:", err);
@@ -194,7 +251,7 @@ const DocumentProcessor: React.FC = () => {
       const interval = setInterval(async () => {
         setError(null);
         try {
-          const { data } = await axios.get<Document[]>("/api/documents");
+          const { data } = await axios.get<Document[]>("api/documents");
           setDocuments(data);
         } catch (err) {
           console.error("Error reloading documents:", err);
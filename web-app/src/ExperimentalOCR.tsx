@@ -18,7 +18,7 @@ const ExperimentalOCR: React.FC = () => {
     if (!documentId) return;
 
     try {
-      const response = await axios.get<Document>(`/api/documents/${documentId}`);
+      const response = await axios.get<Document>(`api/documents/${documentId}`);
       setDocumentDetails(response.data);
     } catch (err) {
       console.error("Error fetching document details:", err);
@@ -38,7 +38,7 @@ const ExperimentalOCR: React.FC = () => {
       await fetchDocumentDetails(); // Fetch document details before submitting the job
 
       setStatus('Submitting OCR job...');
-      const response = await axios.post(`/api/documents/${documentId}/ocr`);
+      const response = await axios.post(`api/documents/${documentId}/ocr`);
       setJobId(response.data.job_id);
       setStatus('Job submitted. Processing...');
     } catch (err) {
@@ -47,23 +47,31 @@ const ExperimentalOCR: React.FC = () => {
     }
   };
 
-  const checkJobStatus = async () => {
+  // Shows the state of the job, it returns whether the job is finished
+  const applyJobStatus = (job: { status: string; pages_done: number; result?: string; error?: string }) => {
+    setPagesDone(job.pages_done); // Update pages done
+    if (job.status === 'completed') {
+      setOcrResult(job.result ?? '');
+      setStatus('OCR completed successfully.');
+      return true;
+    }
+    if (job.status === 'failed') {
+      setError(job.error ?? 'OCR failed.');
+      setStatus('OCR failed.');
+      return true;
+    }
+    setStatus(`Job status: ${job.status}. This may take a few minutes.`);
+    return false;
+  };
+
+  const checkJobStatus = async (poll: boolean) => {
     if (!jobId) return;
 
     try {
-      const response = await axios.get(`/api/jobs/ocr/${jobId}`);
-      const jobStatus = response.data.status;
-      setPagesDone(response.data.pages_done); // Update pages done
-      if (jobStatus === 'completed') {
-        setOcrResult(response.data.result);
-        setStatus('OCR completed successfully.');
-      } else if (jobStatus === 'failed') {
-        setError(response.data.error);
-        setStatus('OCR failed.');
-      } else {
-        setStatus(`Job status: ${jobStatus}. This may take a few minutes.`);
+      const response = await axios.get(`api/jobs/ocr/${jobId}`);
+      if (!applyJobStatus(response.data) && poll) {
         // Automatically check again after a delay
-        setTimeout(checkJobStatus, refreshInterval);
+        setTimeout(() => checkJobStatus(true), refreshInterval);
       }
     } catch (err) {
       console.error(err);
@@ -85,7 +93,7 @@ const ExperimentalOCR: React.FC = () => {
         suggested_content: ocrResult,
       };
 
-      await axios.patch("/api/update-documents", [requestPayload]);
+      await axios.patch("api/update-documents", [requestPayload]);
       setStatus('Content saved successfully.');
     } catch (err) {
       console.error("Error saving content:", err);
@@ -95,11 +103,35 @@ const ExperimentalOCR: React.FC = () => {
     }
   };
 
-  // Start checking job status when jobId is set
+  // Follow the job status over the event WebSocket when jobId is set, polling is the fallback if it disconnects
   useEffect(() => {
-    if (jobId) {
-      checkJobStatus();
-    }
+    if (!jobId) return;
+
+    let finished = false;
+    const url = new URL('api/ws?types=job', document.baseURI);
+    url.protocol = url.protocol === 'https:' ? 'wss:' : 'ws:';
+    const socket = new WebSocket(url);
+    socket.onopen = () => {
+      // Updates sent before the connection was open are missed, so the current status is fetched once
+      checkJobStatus(false);
+    };
+    socket.onmessage = (message) => {
+      const event = JSON.parse(message.data);
+      if (event.data?.job_id === jobId && applyJobStatus(event.data)) {
+        finished = true;
+        socket.close();
+      }
+    };
+    socket.onclose = () => {
+      if (!finished) {
+        finished = true;
+        checkJobStatus(true);
+      }
+    };
+    return () => {
+      finished = true;
+      socket.close();
+    };
     // eslint-disable-next-line react-hooks/exhaustive-deps
   }, [jobId]);
 
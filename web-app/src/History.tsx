@@ -10,10 +10,38 @@ interface ModificationHistory {
   NewValue: string;
   Undone: boolean;
   UndoneDate: string | null;
+  User?: string;
+  BatchID?: string;
+  Operation?: string;
+  OriginID?: number;
+  DocumentBefore?: string;
+  DocumentAfter?: string;
+  Archived?: boolean;
+  ArchivedDate?: string | null;
+}
+
+interface HistoryFilters {
+  documentId: string;
+  field: string;
+  from: string;
+  to: string;
+  undone: string;
+  archived: string;
+  search: string;
+}
+
+const emptyFilters: HistoryFilters = { documentId: '', field: '', from: '', to: '', undone: '', archived: '', search: '' };
+
+interface GenerationUsage {
+  models: string[];
+  requests: number;
+  tokens: number;
+  duration_ms: number;
 }
 
 interface PaginatedResponse {
   items: ModificationHistory[];
+  usage?: Record<string, GenerationUsage>;
   totalItems: number;
   totalPages: number;
   currentPage: number;
@@ -22,19 +50,22 @@ interface PaginatedResponse {
 
 const History: React.FC = () => {
   const [modifications, setModifications] = useState<ModificationHistory[]>([]);
+  const [usage, setUsage] = useState<Record<string, GenerationUsage>>({});
   const [loading, setLoading] = useState(true);
   const [error, setError] = useState<string | null>(null);
   const [paperlessUrl, setPaperlessUrl] = useState<string>('');
   const [currentPage, setCurrentPage] = useState(1);
   const [totalPages, setTotalPages] = useState(1);
   const [totalItems, setTotalItems] = useState(0);
+  const [filterInput, setFilterInput] = useState<HistoryFilters>(emptyFilters);
+  const [filters, setFilters] = useState<HistoryFilters>(emptyFilters);
   const pageSize = 20;
 
   // Get Paperless URL
   useEffect(() => {
     const fetchUrl = async () => {
       try {
-        const response = await fetch('/api/paperless-url');
+        const response = await fetch('api/paperless-url');
         if (!response.ok) {
           throw new Error('Failed to fetch public URL');
         }
@@ -48,22 +79,46 @@ const History: React.FC = () => {
     fetchUrl();
   }, []);
 
-  // Get modifications with pagination
+  // Get modifications with pagination and filters
   useEffect(() => {
-    fetchModifications(currentPage);
-  }, [currentPage]);
+    fetchModifications(currentPage, filters);
+  }, [currentPage, filters]);
+
+  const filterParams = (filters: HistoryFilters, params: URLSearchParams) => {
+    const values: [string, string][] = [
+      ['document_id', filters.documentId],
+      ['field', filters.field],
+      ['from', filters.from],
+      ['to', filters.to],
+      ['undone', filters.undone],
+      ['archived', filters.archived],
+      ['search', filters.search],
+    ];
+    values.forEach(([name, value]) => {
+      if (value.trim() !== '') {
+        params.set(name, value.trim());
+      }
+    });
+    return params;
+  };
 
-  const fetchModifications = async (page: number) => {
+  const exportUrl = (format: string) => `api/modifications/export?${filterParams(filters, new URLSearchParams({ format }))}`;
+
+  const fetchModifications = async (page: number, filters: HistoryFilters) => {
     setLoading(true);
     try {
-      const response = await fetch(`/api/modifications?page=${page}&pageSize=${pageSize}`);
+      const params = filterParams(filters, new URLSearchParams({ page: String(page), pageSize: String(pageSize) }));
+      const response = await fetch(`api/modifications?${params}`);
       if (!response.ok) {
-        throw new Error('Failed to fetch modifications');
+        const { error } = await response.json().catch(() => ({ error: null }));
+        throw new Error(error || 'Failed to fetch modifications');
       }
       const data: PaginatedResponse = await response.json();
       setModifications(data.items);
+      setUsage(data.usage || {});
       setTotalPages(data.totalPages);
       setTotalItems(data.totalItems);
+      setError(null);
     } catch (err) {
       setError(err instanceof Error ? err.message : 'Unknown error occurred');
     } finally {
@@ -71,9 +126,85 @@ const History: React.FC = () => {
     }
   };
 
+  const applyFilters = (event: React.FormEvent) => {
+    event.preventDefault();
+    setCurrentPage(1);
+    setFilters(filterInput);
+  };
+
+  const resetFilters = () => {
+    setFilterInput(emptyFilters);
+    setCurrentPage(1);
+    setFilters(emptyFilters);
+  };
+
+  const inputClassName =
+    'rounded-md border border-gray-300 bg-white px-2 py-1 text-sm text-gray-700 dark:border-gray-600 dark:bg-gray-800 dark:text-gray-200';
+
+  const handleRedo = async (id: number) => {
+    try {
+      const response = await fetch(`api/redo-modification/${id}`, {
+        method: 'POST',
+      });
+
+      if (!response.ok) {
+        throw new Error('Failed to redo modification');
+      }
+
+      setModifications(mods => mods.map(mod =>
+        mod.ID === id
+          ? { ...mod, Undone: false, UndoneDate: null }
+          : mod
+      ));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to redo modification');
+    }
+  };
+
+  const handleUndoBatch = async (batchId: string) => {
+    try {
+      const response = await fetch(`api/undo-batch/${encodeURIComponent(batchId)}`, {
+        method: 'POST',
+      });
+
+      if (!response.ok) {
+        throw new Error('Failed to undo the modifications');
+      }
+
+      const now = new Date().toISOString();
+
+      setModifications(mods => mods.map(mod =>
+        mod.BatchID === batchId && !mod.Undone
+          ? { ...mod, Undone: true, UndoneDate: now }
+          : mod
+      ));
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to undo the modifications');
+    }
+  };
+
+  const handleArchive = async (id: number, archived: boolean) => {
+    try {
+      const response = await fetch(`api/modifications/${archived ? 'archive' : 'restore'}`, {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ ids: [id] }),
+      });
+
+      if (!response.ok) {
+        throw new Error(archived ? 'Failed to archive modification' : 'Failed to restore modification');
+      }
+
+      // The modification may no longer match the filters
+      fetchModifications(currentPage, filters);
+    } catch (err) {
+      setError(err instanceof Error ? err.message : 'Failed to archive modification');
+    }
+  };
+
   const handleUndo = async (id: number) => {
     try {
-      const response = await fetch(`/api/undo-modification/${id}`, {
+      const response = await fetch(`api/undo-modification/${id}`, {
         method: 'POST',
       });
       
@@ -94,22 +225,6 @@ const History: React.FC = () => {
     }
   };
 
-  if (loading) {
-    return (
-      <div className="flex justify-center items-center min-h-screen">
-        <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-blue-500" />
-      </div>
-    );
-  }
-
-  if (error) {
-    return (
-      <div className="text-red-500 dark:text-red-400 p-4 text-center">
-        Error: {error}
-      </div>
-    );
-  }
-
   return (
     <div className="modification-history container mx-auto px-4 py-8">
       <h1 className="text-2xl font-bold text-gray-800 dark:text-gray-200">
@@ -118,7 +233,102 @@ const History: React.FC = () => {
       <div className="mb-6 text-sm text-gray-500 dark:text-gray-400">
         Note: when undoing tag changes, this will not re-add 'paperless-gpt-auto'
       </div>
-      {modifications.length === 0 ? (
+      <form onSubmit={applyFilters} className="mb-6 flex flex-wrap items-end gap-2">
+        <input
+          type="search"
+          placeholder="Search values and users"
+          value={filterInput.search}
+          onChange={(e) => setFilterInput({ ...filterInput, search: e.target.value })}
+          className={`${inputClassName} flex-1 min-w-[12rem]`}
+        />
+        <input
+          type="number"
+          min={1}
+          placeholder="Document ID"
+          value={filterInput.documentId}
+          onChange={(e) => setFilterInput({ ...filterInput, documentId: e.target.value })}
+          className={`${inputClassName} w-32`}
+        />
+        <input
+          type="text"
+          placeholder="Field, e.g. title"
+          value={filterInput.field}
+          onChange={(e) => setFilterInput({ ...filterInput, field: e.target.value })}
+          className={`${inputClassName} w-36`}
+        />
+        <label className="text-sm text-gray-500 dark:text-gray-400">
+          From
+          <input
+            type="date"
+            value={filterInput.from}
+            onChange={(e) => setFilterInput({ ...filterInput, from: e.target.value })}
+            className={`${inputClassName} ml-1`}
+          />
+        </label>
+        <label className="text-sm text-gray-500 dark:text-gray-400">
+          To
+          <input
+            type="date"
+            value={filterInput.to}
+            onChange={(e) => setFilterInput({ ...filterInput, to: e.target.value })}
+            className={`${inputClassName} ml-1`}
+          />
+        </label>
+        <select
+          value={filterInput.undone}
+          onChange={(e) => setFilterInput({ ...filterInput, undone: e.target.value })}
+          className={inputClassName}
+        >
+          <option value="">All</option>
+          <option value="false">Not undone</option>
+          <option value="true">Undone</option>
+        </select>
+        <select
+          value={filterInput.archived}
+          onChange={(e) => setFilterInput({ ...filterInput, archived: e.target.value })}
+          className={inputClassName}
+        >
+          <option value="">Hide archived</option>
+          <option value="true">Archived</option>
+          <option value="all">Include archived</option>
+        </select>
+        <button
+          type="submit"
+          className="px-3 py-1 rounded-md bg-blue-500 text-white hover:bg-blue-600 dark:bg-blue-600 dark:hover:bg-blue-700"
+        >
+          Filter
+        </button>
+        <button
+          type="button"
+          onClick={resetFilters}
+          className="px-3 py-1 rounded-md bg-gray-100 text-gray-600 hover:bg-gray-200 dark:bg-gray-800 dark:text-gray-300"
+        >
+          Reset
+        </button>
+        <a
+          href={exportUrl('csv')}
+          download
+          className="px-3 py-1 rounded-md bg-gray-100 text-gray-600 hover:bg-gray-200 dark:bg-gray-800 dark:text-gray-300"
+        >
+          Export CSV
+        </a>
+        <a
+          href={exportUrl('json')}
+          download
+          className="px-3 py-1 rounded-md bg-gray-100 text-gray-600 hover:bg-gray-200 dark:bg-gray-800 dark:text-gray-300"
+        >
+          Export JSON
+        </a>
+      </form>
+      {error ? (
+        <div className="text-red-500 dark:text-red-400 p-4 text-center">
+          Error: {error}
+        </div>
+      ) : loading ? (
+        <div className="flex justify-center py-8">
+          <div className="animate-spin rounded-full h-8 w-8 border-b-2 border-blue-500" />
+        </div>
+      ) : modifications.length === 0 ? (
         <p className="text-gray-500 dark:text-gray-400 text-center">
           No modifications found
         </p>
@@ -129,7 +339,11 @@ const History: React.FC = () => {
               <UndoCard
                 key={modification.ID}
                 {...modification}
+                Usage={modification.BatchID ? usage[modification.BatchID] : undefined}
                 onUndo={handleUndo}
+                onUndoBatch={handleUndoBatch}
+                onRedo={handleRedo}
+                onArchive={handleArchive}
                 paperlessUrl={paperlessUrl}
               />
             ))}
@@ -1,4 +1,4 @@
-import { mdiHistory, mdiHomeOutline, mdiTextBoxSearchOutline } from "@mdi/js";
+import { mdiChartBar, mdiHistory, mdiHomeOutline, mdiTextBoxSearchOutline } from "@mdi/js";
 import { Icon } from "@mdi/react";
 import axios from "axios";
 import React, { useCallback, useEffect, useState } from "react";
@@ -27,7 +27,7 @@ const Sidebar: React.FC<SidebarProps> = ({ onSelectPage }) => {
   const fetchOcrEnabled = useCallback(async () => {
     try {
       const res = await axios.get<{ enabled: boolean }>(
-        "/api/experimental/ocr"
+        "api/experimental/ocr"
       );
       setOcrEnabled(res.data.enabled);
     } catch (err) {
@@ -42,6 +42,7 @@ const Sidebar: React.FC<SidebarProps> = ({ onSelectPage }) => {
   const menuItems = [
     { name: "home", path: "/", icon: mdiHomeOutline, title: "Home" },
     { name: "history", path: "/history", icon: mdiHistory, title: "History" },
+    { name: "dashboard", path: "/dashboard", icon: mdiChartBar, title: "Dashboard" },
   ];
 
   // If OCR is enabled, add the OCR menu item
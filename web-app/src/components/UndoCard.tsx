@@ -1,5 +1,5 @@
 // UndoCard.tsx
-import React from 'react';
+import React, { useState } from 'react';
 import { Tooltip } from 'react-tooltip';
 
 interface ModificationProps {
@@ -11,7 +11,23 @@ interface ModificationProps {
   NewValue: string;
   Undone: boolean;
   UndoneDate: string | null;
+  User?: string;
+  BatchID?: string;
+  Operation?: string;
+  OriginID?: number;
+  DocumentBefore?: string;
+  DocumentAfter?: string;
+  Archived?: boolean;
+  Usage?: {
+    models: string[];
+    requests: number;
+    tokens: number;
+    duration_ms: number;
+  };
   onUndo: (id: number) => void;
+  onUndoBatch: (batchId: string) => void;
+  onRedo: (id: number) => void;
+  onArchive: (id: number, archived: boolean) => void;
   paperlessUrl: string;
 }
 
@@ -30,6 +46,30 @@ const formatDate = (dateString: string | null): string => {
   }
 };
 
+// Lists the fields of the document snapshots that differ before and after the update
+const snapshotChanges = (before?: string, after?: string): [string, string, string][] => {
+  if (!before || !after) return [];
+  try {
+    const previous = JSON.parse(before) as Record<string, unknown>;
+    const next = JSON.parse(after) as Record<string, unknown>;
+    return Object.keys({ ...previous, ...next })
+      .map((key): [string, string, string] => [key, JSON.stringify(previous[key] ?? null), JSON.stringify(next[key] ?? null)])
+      .filter(([, previousValue, nextValue]) => previousValue !== nextValue);
+  } catch {
+    return [];
+  }
+};
+
+// Describes the LLM and OCR requests that generated a change, e.g. "generated by gpt-4o-mini, 1,842 tokens, 3.2s"
+const formatUsage = (usage: NonNullable<ModificationProps['Usage']>): string => {
+  const parts = [`generated by ${usage.models.join(', ')}`];
+  if (usage.tokens > 0) {
+    parts.push(`${usage.tokens.toLocaleString('en-US')} tokens`);
+  }
+  parts.push(`${(usage.duration_ms / 1000).toFixed(1)}s`);
+  return parts.join(', ');
+};
+
 const buildPaperlessUrl = (paperlessUrl: string, documentId: number): string => {
   return `${paperlessUrl}/documents/${documentId}/details`;
 };
@@ -43,9 +83,23 @@ const UndoCard: React.FC<ModificationProps> = ({
   NewValue,
   Undone,
   UndoneDate,
+  User,
+  BatchID,
+  Operation,
+  OriginID,
+  DocumentBefore,
+  DocumentAfter,
+  Archived,
+  Usage,
   onUndo,
+  onUndoBatch,
+  onRedo,
+  onArchive,
   paperlessUrl,
 }) => {
+  const [showDocument, setShowDocument] = useState(false);
+  const documentChanges = snapshotChanges(DocumentBefore, DocumentAfter);
+
   const formatValue = (value: string, field: string) => {
     if (field === 'tags') {
       try {
@@ -83,6 +137,12 @@ const UndoCard: React.FC<ModificationProps> = ({
               <div className="text-sm text-gray-700 dark:text-gray-300">
                 {DateChanged && formatDate(DateChanged)}
               </div>
+              {User && (
+                <div className="text-xs text-gray-500 dark:text-gray-400">by {User}</div>
+              )}
+              {Usage && Usage.requests > 0 && (
+                <div className="text-xs text-gray-500 dark:text-gray-400">{formatUsage(Usage)}</div>
+              )}
             </div>
             <div className="">
               <a
@@ -107,6 +167,11 @@ const UndoCard: React.FC<ModificationProps> = ({
               <div className="text-sm text-gray-700 dark:text-gray-300">
                 {ModField}
               </div>
+              {Operation && OriginID ? (
+                <div className="text-xs text-gray-500 dark:text-gray-400">
+                  {Operation === 'undo' ? 'Undo' : 'Redo'} of #{OriginID}
+                </div>
+              ) : null}
             </div>
           </div>
           <div className="mt-3">
@@ -164,6 +229,29 @@ const UndoCard: React.FC<ModificationProps> = ({
             >
               {NewValue}
             </Tooltip>
+            {documentChanges.length > 0 && (
+              <div className="mt-2">
+                <button
+                  onClick={() => setShowDocument(!showDocument)}
+                  className="text-xs text-blue-500 hover:text-blue-600 dark:text-blue-400 dark:hover:text-blue-300 underline"
+                >
+                  {showDocument ? 'Hide document changes' : `Show document changes (${documentChanges.length})`}
+                </button>
+                {showDocument && (
+                  <table className="mt-2 w-full table-fixed text-xs text-gray-600 dark:text-gray-300">
+                    <tbody>
+                      {documentChanges.map(([field, previousValue, nextValue]) => (
+                        <tr key={field} className="align-top border-t border-gray-200 dark:border-gray-700">
+                          <td className="w-32 py-1 font-semibold">{field}</td>
+                          <td className="py-1 pr-2 break-words text-red-500 dark:text-red-400">{previousValue}</td>
+                          <td className="py-1 break-words text-green-500 dark:text-green-400">{nextValue}</td>
+                        </tr>
+                      ))}
+                    </tbody>
+                  </table>
+                )}
+              </div>
+            )}
           </div>
         </div>
         <div className="grid place-items-center"> {/* Button content */}
@@ -184,6 +272,31 @@ const UndoCard: React.FC<ModificationProps> = ({
               'Undo'
             )}
           </button>
+          {Undone && (
+            <button
+              onClick={() => onRedo(ID)}
+              title="Apply this change again"
+              className="mb-2 text-xs text-blue-500 hover:text-blue-600 dark:text-blue-400 dark:hover:text-blue-300 underline"
+            >
+              Redo
+            </button>
+          )}
+          {BatchID && !Undone && (
+            <button
+              onClick={() => onUndoBatch(BatchID)}
+              title="Undo all changes made to this document in the same run"
+              className="mb-2 text-xs text-blue-500 hover:text-blue-600 dark:text-blue-400 dark:hover:text-blue-300 underline"
+            >
+              Undo whole run
+            </button>
+          )}
+          <button
+            onClick={() => onArchive(ID, !Archived)}
+            title={Archived ? 'Show this change in the history again' : 'Hide this change from the history, it is kept for the audit trail'}
+            className="mb-2 text-xs text-gray-500 hover:text-gray-600 dark:text-gray-400 dark:hover:text-gray-300 underline"
+          >
+            {Archived ? 'Restore' : 'Archive'}
+          </button>
         </div>
       </div>
     </div>
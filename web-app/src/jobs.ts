@@ -0,0 +1,61 @@
+import axios from "axios";
+
+export interface Job {
+  job_id: string;
+  kind: string;
+  status: string;
+  pages_done: number;
+  result?: string;
+  error?: string;
+}
+
+const pollInterval = 1000; // Polling interval in milliseconds if the WebSocket is unavailable
+
+const isFinished = (job: Job) => job.status === "completed" || job.status === "failed";
+
+// waitForJob resolves with the job once it completed or failed. Updates are pushed over the event WebSocket,
+// the job is polled if the WebSocket can't connect or disconnects.
+export const waitForJob = (jobId: string): Promise<Job> =>
+  new Promise((resolve, reject) => {
+    let done = false;
+    const finish = (job: Job) => {
+      done = true;
+      socket.close();
+      resolve(job);
+    };
+
+    const fetchJob = async () => (await axios.get<Job>(`api/jobs/${jobId}`)).data;
+    const poll = async () => {
+      try {
+        const job = await fetchJob();
+        if (isFinished(job)) {
+          finish(job);
+        } else {
+          setTimeout(poll, pollInterval);
+        }
+      } catch (err) {
+        done = true;
+        reject(err);
+      }
+    };
+
+    const url = new URL("api/ws?types=job", document.baseURI);
+    url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+    const socket = new WebSocket(url);
+    socket.onopen = async () => {
+      // Updates sent before the connection was open are missed, so the current state is fetched once
+      try {
+        const job = await fetchJob();
+        if (!done && isFinished(job)) finish(job);
+      } catch (err) {
+        console.error(err);
+      }
+    };
+    socket.onmessage = (message) => {
+      const event = JSON.parse(message.data);
+      if (!done && event.data?.job_id === jobId && isFinished(event.data)) finish(event.data);
+    };
+    socket.onclose = () => {
+      if (!done) poll();
+    };
+  });
@@ -2,12 +2,15 @@ import react from '@vitejs/plugin-react-swc';
 import { defineConfig } from 'vite';
 
 export default defineConfig({
+    // Relative asset paths work under any BASE_PATH, the server injects the matching <base> element
+    base: './',
     plugins: [react()],
     server: {
         proxy: {
             '/api': {
                 target: 'http://localhost:8080', // Ihr Go-Webservice
                 changeOrigin: true,
+                ws: true, // Live updates of /api/ws
                 // rewrite: (path) => path.replace(/^\/api/, ''), // Entfernen Sie '/api' aus dem Pfad
             },
         },